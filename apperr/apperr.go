@@ -0,0 +1,204 @@
+// Package apperr is the error type services return from their handlers and
+// business logic.  Each error has a code, which decides its HTTP status, a
+// message that's safe to send to clients, and optionally field errors, an
+// internal detail and the error it wraps, which are only logged.
+//
+//	profile, err := store.Profile(ctx, id)
+//	if errors.Is(err, sql.ErrNoRows) {
+//		return apperr.Wrap(err, apperr.CodeNotFound, "Profile not found").WithDetail("profile %s", id)
+//	}
+//
+// respond.Error sends them as the canonical Vela error.
+package apperr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/seniorlink-vela/cs-common/client"
+)
+
+// Code is the kind of error, sent as the error_type.
+type Code string
+
+// The codes, which match the respond error types.
+const (
+	CodeValidation   Code = "validation_error"
+	CodeUnauthorized Code = "unauthorized"
+	CodeForbidden    Code = "forbidden"
+	CodeNotFound     Code = "not_found"
+	CodeConflict     Code = "conflict"
+	CodeRateLimited  Code = "rate_limited"
+	CodeUnavailable  Code = "service_unavailable"
+	CodeTimeout      Code = "timeout"
+	CodeUpstream     Code = "upstream_error"
+	CodeInternal     Code = "internal_error"
+)
+
+var statuses = map[Code]int{
+	CodeValidation:   http.StatusBadRequest,
+	CodeUnauthorized: http.StatusUnauthorized,
+	CodeForbidden:    http.StatusForbidden,
+	CodeNotFound:     http.StatusNotFound,
+	CodeConflict:     http.StatusConflict,
+	CodeRateLimited:  http.StatusTooManyRequests,
+	CodeUnavailable:  http.StatusServiceUnavailable,
+	CodeTimeout:      http.StatusGatewayTimeout,
+	CodeUpstream:     http.StatusBadGateway,
+	CodeInternal:     http.StatusInternalServerError,
+}
+
+// Status returns the HTTP status for the code.  Codes it doesn't know are
+// a 500.
+func (c Code) Status() int {
+	if status, ok := statuses[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Field is a problem with one field of a request.
+type Field struct {
+	Name    string
+	Message string
+}
+
+// Error is an application error.
+type Error struct {
+	Code Code
+	// Message is sent to clients, so it mustn't include internal details or
+	// PHI.
+	Message string
+	Fields  []Field
+	// Detail is for the logs only.
+	Detail string
+	// Err is the error this one wraps, if any.
+	Err error
+
+	// Overrides the code's status, for errors passed on from other APIs
+	status int
+}
+
+// New returns an error with the code and client-safe message.
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Newf is New with a formatted message.
+func Newf(code Code, format string, args ...interface{}) *Error {
+	return New(code, fmt.Sprintf(format, args...))
+}
+
+// Wrap returns an error with the code and message that wraps err, so
+// errors.Is and errors.As still see it.
+func Wrap(err error, code Code, message string) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+// WithField adds a field error, and returns the error.
+func (e *Error) WithField(name, message string) *Error {
+	e.Fields = append(e.Fields, Field{Name: name, Message: message})
+	return e
+}
+
+// WithDetail sets the internal detail, and returns the error.
+func (e *Error) WithDetail(format string, args ...interface{}) *Error {
+	e.Detail = fmt.Sprintf(format, args...)
+	return e
+}
+
+// Error includes the detail and the wrapped error, so it's for logs, not
+// clients.  Send SafeMessage instead.
+func (e *Error) Error() string {
+	parts := []string{string(e.Code)}
+	if e.Message != "" {
+		parts = append(parts, e.Message)
+	}
+	if e.Detail != "" {
+		parts = append(parts, e.Detail)
+	}
+	if e.Err != nil {
+		parts = append(parts, e.Err.Error())
+	}
+	return strings.Join(parts, ": ")
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Status returns the HTTP status for the error.
+func (e *Error) Status() int {
+	if e.status != 0 {
+		return e.status
+	}
+	return e.Code.Status()
+}
+
+// SafeMessage returns the message for clients, the status text when there
+// isn't one.
+func (e *Error) SafeMessage() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return http.StatusText(e.Status())
+}
+
+// CodeOf returns the code of the application error in err's chain, or
+// CodeInternal when there isn't one.
+func CodeOf(err error) Code {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return CodeInternal
+}
+
+// IsCode reports whether err's chain has an application error with the code.
+func IsCode(err error, code Code) bool {
+	var appErr *Error
+	return errors.As(err, &appErr) && appErr.Code == code
+}
+
+// From returns err as an application error.  Errors from other Vela APIs keep
+// their message, type and fields, deadlines become timeouts, and anything
+// else is wrapped as an internal error.  Nil stays nil.
+func From(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var appErr *Error
+	var clientErr client.HttpClientError
+	var clientErrPtr *client.HttpClientError
+	switch {
+	case errors.As(err, &appErr):
+		return appErr
+	case errors.As(err, &clientErrPtr) && clientErrPtr != nil:
+		return FromClientError(*clientErrPtr)
+	case errors.As(err, &clientErr):
+		return FromClientError(clientErr)
+	case errors.Is(err, context.DeadlineExceeded):
+		return Wrap(err, CodeTimeout, "")
+	}
+	return Wrap(err, CodeInternal, "")
+}
+
+// FromClientError returns an error from another Vela API as an application
+// error.  Its client errors are the caller's too, so keep their status, but
+// its server errors are ours to report as a 502.
+func FromClientError(err client.HttpClientError) *Error {
+	appErr := &Error{Code: Code(err.ErrorType), Message: err.Message, Err: err, status: err.StatusCode}
+	if appErr.Code == "" {
+		appErr.Code = CodeUpstream
+	}
+	for _, f := range err.Fields {
+		appErr.Fields = append(appErr.Fields, Field{Name: f.Name, Message: f.Message})
+	}
+	if appErr.status < 400 || appErr.status >= 500 {
+		appErr.status = http.StatusBadGateway
+	}
+	return appErr
+}
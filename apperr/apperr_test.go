@@ -0,0 +1,66 @@
+package apperr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/seniorlink-vela/cs-common/client"
+)
+
+func TestError(t *testing.T) {
+	cause := errors.New("sql: no rows in result set")
+	err := Wrap(cause, CodeNotFound, "Profile not found").WithDetail("profile %d", 12)
+
+	assert.Equal(t, "not_found: Profile not found: profile 12: sql: no rows in result set", err.Error())
+	assert.Equal(t, http.StatusNotFound, err.Status())
+	assert.Equal(t, "Profile not found", err.SafeMessage())
+	assert.ErrorIs(t, err, cause)
+
+	wrapped := fmt.Errorf("loading care team: %w", err)
+	assert.Equal(t, CodeNotFound, CodeOf(wrapped))
+	assert.True(t, IsCode(wrapped, CodeNotFound))
+	assert.False(t, IsCode(wrapped, CodeConflict))
+	assert.Equal(t, CodeInternal, CodeOf(cause))
+}
+
+func TestStatuses(t *testing.T) {
+	assert.Equal(t, http.StatusBadRequest, New(CodeValidation, "").Status())
+	assert.Equal(t, http.StatusTooManyRequests, New(CodeRateLimited, "").Status())
+	assert.Equal(t, http.StatusInternalServerError, New("made_up", "").Status())
+	assert.Equal(t, "Too Many Requests", New(CodeRateLimited, "").SafeMessage())
+}
+
+func TestFields(t *testing.T) {
+	err := Newf(CodeValidation, "%d fields are invalid", 2).
+		WithField("email", "This must be an email").
+		WithField("zip", "This must be 5 digits")
+	assert.Equal(t, "2 fields are invalid", err.Message)
+	assert.Equal(t, []Field{{"email", "This must be an email"}, {"zip", "This must be 5 digits"}}, err.Fields)
+}
+
+func TestFrom(t *testing.T) {
+	assert.Nil(t, From(nil))
+
+	appErr := New(CodeForbidden, "Not your care team")
+	assert.Same(t, appErr, From(fmt.Errorf("wrapped: %w", appErr)))
+
+	assert.Equal(t, CodeTimeout, From(context.DeadlineExceeded).Code)
+
+	internal := From(errors.New("boom"))
+	assert.Equal(t, CodeInternal, internal.Code)
+	assert.Equal(t, "Internal Server Error", internal.SafeMessage())
+
+	clientErr := From(&client.HttpClientError{StatusCode: 422, Message: "Bad zip", ErrorType: "validation_error", Fields: []client.HttpErrorField{{Name: "zip", Message: "Bad"}}})
+	assert.Equal(t, CodeValidation, clientErr.Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, clientErr.Status())
+	assert.Equal(t, []Field{{"zip", "Bad"}}, clientErr.Fields)
+
+	serverErr := From(client.HttpClientError{StatusCode: 503, Message: "Down"})
+	assert.Equal(t, CodeUpstream, serverErr.Code)
+	assert.Equal(t, http.StatusBadGateway, serverErr.Status())
+}
@@ -0,0 +1,289 @@
+// Package auth verifies Vela-issued JWTs and puts who they're for into the
+// request context, as the velacontext identity, token and claims.
+//
+//	verifier := auth.NewVerifier(auth.Config{
+//		JWKSURL:  "https://auth.vela.care/.well-known/jwks.json",
+//		Issuer:   "https://auth.vela.care/",
+//		Audience: []string{"care-team-api"},
+//	})
+//	handler = auth.Middleware(verifier)(auth.RequireRole("coordinator")(handler))
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+// DefaultClockSkew is how far the token's times may be off from ours.
+const DefaultClockSkew = time.Minute
+
+// The claims the identity is taken from, unless Config says otherwise.
+const (
+	DefaultOrganizationClaim = "organization_id"
+	DefaultPartnerClaim      = "partner_id"
+	DefaultRolesClaim        = "roles"
+)
+
+var (
+	// ErrMissingToken is returned when a request doesn't have a bearer token.
+	ErrMissingToken = errors.New("auth: missing bearer token")
+	// ErrInvalidToken is returned for a token that's malformed, or whose
+	// signature doesn't verify.
+	ErrInvalidToken = errors.New("auth: invalid token")
+	// ErrExpired is returned for a token that's expired, or isn't valid yet.
+	ErrExpired = errors.New("auth: token is expired")
+	// ErrInvalidClaims is returned for a token with the wrong issuer or
+	// audience.
+	ErrInvalidClaims = errors.New("auth: invalid token claims")
+)
+
+// Config says where the keys come from and what the tokens must say.
+type Config struct {
+	// JWKSURL is where the issuer's signing keys are published.
+	JWKSURL string
+	// Issuer is the required "iss" claim.  Empty allows any issuer.
+	Issuer string
+	// Audience lists the accepted "aud" values, any one of which the token
+	// must have.  Empty allows any audience.
+	Audience []string
+	// ClockSkew defaults to DefaultClockSkew.
+	ClockSkew time.Duration
+	// JWKSCacheTTL defaults to DefaultJWKSCacheTTL.
+	JWKSCacheTTL time.Duration
+	// HTTPClient fetches the keys, and defaults to a client with a
+	// DefaultJWKSTimeout timeout.
+	HTTPClient *http.Client
+
+	// The claims the identity is taken from, which default to the Default
+	// claims above.  The user ID is always the "sub" claim.
+	OrganizationClaim string
+	PartnerClaim      string
+	RolesClaim        string
+}
+
+// Verifier verifies tokens.  It's safe for concurrent use, and should be
+// shared, so the keys are only fetched once.
+type Verifier struct {
+	config Config
+	keys   *keySet
+	now    func() time.Time
+}
+
+// NewVerifier returns a verifier for the config.
+func NewVerifier(config Config) *Verifier {
+	if config.ClockSkew == 0 {
+		config.ClockSkew = DefaultClockSkew
+	}
+	if config.JWKSCacheTTL == 0 {
+		config.JWKSCacheTTL = DefaultJWKSCacheTTL
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = defaultJWKSClient
+	}
+	if config.OrganizationClaim == "" {
+		config.OrganizationClaim = DefaultOrganizationClaim
+	}
+	if config.PartnerClaim == "" {
+		config.PartnerClaim = DefaultPartnerClaim
+	}
+	if config.RolesClaim == "" {
+		config.RolesClaim = DefaultRolesClaim
+	}
+	v := &Verifier{config: config, now: time.Now}
+	v.keys = &keySet{url: config.JWKSURL, client: config.HTTPClient, ttl: config.JWKSCacheTTL, now: func() time.Time { return v.now() }}
+	return v
+}
+
+// The signing algorithms that are accepted.  "none" and the HMAC algorithms
+// never are, since the keys are public.
+var algorithms = map[string]struct {
+	hash crypto.Hash
+	ec   bool
+}{
+	"RS256": {crypto.SHA256, false},
+	"RS384": {crypto.SHA384, false},
+	"RS512": {crypto.SHA512, false},
+	"ES256": {crypto.SHA256, true},
+	"ES384": {crypto.SHA384, true},
+	"ES512": {crypto.SHA512, true},
+}
+
+// Verify checks the token's signature, times, issuer and audience, and
+// returns its claims.
+func (v *Verifier) Verify(ctx context.Context, token string) (velacontext.Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrInvalidToken
+	}
+	alg, ok := algorithms[header.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Algorithm)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	key, err := v.keys.key(ctx, header.KeyID)
+	if err != nil {
+		if errors.Is(err, ErrUnknownKey) {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+		}
+		return nil, err
+	}
+	hash := alg.hash.New()
+	hash.Write([]byte(parts[0] + "." + parts[1]))
+	if !verifySignature(key, alg.hash, alg.ec, hash.Sum(nil), signature) {
+		return nil, ErrInvalidToken
+	}
+
+	var claims velacontext.Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func verifySignature(key crypto.PublicKey, hash crypto.Hash, ec bool, digest, signature []byte) bool {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return !ec && rsa.VerifyPKCS1v15(key, hash, digest, signature) == nil
+	case *ecdsa.PublicKey:
+		// JWS EC signatures are r and s, each padded to the curve size
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if !ec || len(signature) != 2*size {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(key, digest, r, s)
+	}
+	return false
+}
+
+func (v *Verifier) checkClaims(claims velacontext.Claims) error {
+	now := v.now()
+	skew := v.config.ClockSkew
+	exp, ok := numericDate(claims["exp"])
+	if !ok {
+		return fmt.Errorf("%w: missing exp", ErrInvalidClaims)
+	}
+	if now.After(exp.Add(skew)) {
+		return ErrExpired
+	}
+	if nbf, ok := numericDate(claims["nbf"]); ok && now.Add(skew).Before(nbf) {
+		return ErrExpired
+	}
+	if v.config.Issuer != "" && claims.String("iss") != v.config.Issuer {
+		return fmt.Errorf("%w: wrong issuer", ErrInvalidClaims)
+	}
+	if len(v.config.Audience) > 0 && !audienceMatches(claims["aud"], v.config.Audience) {
+		return fmt.Errorf("%w: wrong audience", ErrInvalidClaims)
+	}
+	return nil
+}
+
+// Identity returns the identity the claims are for.
+func (v *Verifier) Identity(claims velacontext.Claims) velacontext.Identity {
+	return velacontext.Identity{
+		UserID:         claims.Subject(),
+		OrganizationID: int64Claim(claims[v.config.OrganizationClaim]),
+		PartnerID:      int64Claim(claims[v.config.PartnerClaim]),
+		Roles:          stringsClaim(claims[v.config.RolesClaim]),
+	}
+}
+
+// Authenticate verifies the token, and returns the context with it, its
+// claims and its identity.
+func (v *Verifier) Authenticate(ctx context.Context, token string) (context.Context, error) {
+	if token == "" {
+		return ctx, ErrMissingToken
+	}
+	claims, err := v.Verify(ctx, token)
+	if err != nil {
+		return ctx, err
+	}
+	ctx = velacontext.ContextWithToken(ctx, token)
+	ctx = velacontext.ContextWithClaims(ctx, claims)
+	return velacontext.ContextWithIdentity(ctx, v.Identity(claims)), nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func numericDate(value interface{}) (time.Time, bool) {
+	f, ok := value.(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(f), 0), true
+}
+
+// The "aud" claim can be a string or a list of them.
+func audienceMatches(value interface{}, accepted []string) bool {
+	for _, aud := range stringsClaim(value) {
+		for _, a := range accepted {
+			if aud == a {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IDs may be numbers or strings of them.
+func int64Claim(value interface{}) int64 {
+	switch v := value.(type) {
+	case float64:
+		return int64(v)
+	case string:
+		id, _ := strconv.ParseInt(v, 10, 64)
+		return id
+	}
+	return 0
+}
+
+// Lists may be JSON arrays or space separated strings, like "scope".
+func stringsClaim(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+var testNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+type testIssuer struct {
+	rsaKey  *rsa.PrivateKey
+	ecKey   *ecdsa.PrivateKey
+	server  *httptest.Server
+	fetches atomic.Int32
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	issuer := &testIssuer{rsaKey: rsaKey, ecKey: ecKey}
+	issuer.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		issuer.fetches.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+		}})
+	}))
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (ti *testIssuer) verifier() *Verifier {
+	v := NewVerifier(Config{JWKSURL: ti.server.URL, Issuer: "https://auth.vela.test/", Audience: []string{"care-api"}})
+	v.now = func() time.Time { return testNow }
+	return v
+}
+
+func (ti *testIssuer) token(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signed))
+	var signature []byte
+	switch alg {
+	case "RS256":
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, ti.rsaKey, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, ti.ecKey, digest[:])
+		require.NoError(t, err)
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + b64(signature)
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub":             "user-1",
+		"iss":             "https://auth.vela.test/",
+		"aud":             []string{"other-api", "care-api"},
+		"exp":             testNow.Add(time.Hour).Unix(),
+		"organization_id": 42,
+		"partner_id":      "7",
+		"roles":           []string{"caregiver", "coordinator"},
+	}
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func TestVerify(t *testing.T) {
+	issuer := newTestIssuer(t)
+	v := issuer.verifier()
+
+	for _, alg := range []struct{ alg, kid string }{{"RS256", "rsa"}, {"ES256", "ec"}} {
+		ctx, err := v.Authenticate(context.Background(), issuer.token(t, alg.alg, alg.kid, validClaims()))
+		require.NoError(t, err, alg.alg)
+		assert.Equal(t, velacontext.Identity{UserID: "user-1", OrganizationID: 42, PartnerID: 7, Roles: []string{"caregiver", "coordinator"}}, velacontext.GetContextIdentity(ctx))
+		assert.Equal(t, "user-1", velacontext.GetContextClaims(ctx).Subject())
+		assert.NotEmpty(t, velacontext.GetContextToken(ctx))
+	}
+	// The keys were cached
+	assert.Equal(t, int32(1), issuer.fetches.Load())
+}
+
+func TestVerifyFailures(t *testing.T) {
+	issuer := newTestIssuer(t)
+	v := issuer.verifier()
+
+	claims := func(change func(map[string]interface{})) map[string]interface{} {
+		c := validClaims()
+		change(c)
+		return c
+	}
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"malformed", "not.a-token", ErrInvalidToken},
+		{"none", b64([]byte(`{"alg":"none"}`)) + "." + b64([]byte(`{}`)) + ".", ErrInvalidToken},
+		{"wrong key", issuer.token(t, "RS256", "ec", validClaims()), ErrInvalidToken},
+		{"unknown key", issuer.token(t, "RS256", "rotated", validClaims()), ErrInvalidToken},
+		{"expired", issuer.token(t, "RS256", "rsa", claims(func(c map[string]interface{}) { c["exp"] = testNow.Add(-2 * time.Minute).Unix() })), ErrExpired},
+		{"not yet", issuer.token(t, "RS256", "rsa", claims(func(c map[string]interface{}) { c["nbf"] = testNow.Add(2 * time.Minute).Unix() })), ErrExpired},
+		{"no exp", issuer.token(t, "RS256", "rsa", claims(func(c map[string]interface{}) { delete(c, "exp") })), ErrInvalidClaims},
+		{"issuer", issuer.token(t, "RS256", "rsa", claims(func(c map[string]interface{}) { c["iss"] = "https://evil.test/" })), ErrInvalidClaims},
+		{"audience", issuer.token(t, "RS256", "rsa", claims(func(c map[string]interface{}) { c["aud"] = "other-api" })), ErrInvalidClaims},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := v.Verify(context.Background(), test.token)
+			assert.ErrorIs(t, err, test.err)
+		})
+	}
+
+	// Within the clock skew
+	_, err := v.Verify(context.Background(), issuer.token(t, "RS256", "rsa", claims(func(c map[string]interface{}) { c["exp"] = testNow.Add(-30 * time.Second).Unix() })))
+	assert.NoError(t, err)
+
+	_, err = v.Authenticate(context.Background(), "")
+	assert.ErrorIs(t, err, ErrMissingToken)
+}
+
+func TestJWKSRefresh(t *testing.T) {
+	issuer := newTestIssuer(t)
+	v := issuer.verifier()
+	ctx := context.Background()
+
+	_, err := v.Verify(ctx, issuer.token(t, "RS256", "rsa", validClaims()))
+	require.NoError(t, err)
+
+	// Unknown keys don't refetch right away
+	_, err = v.Verify(ctx, issuer.token(t, "RS256", "new", validClaims()))
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	assert.Equal(t, int32(1), issuer.fetches.Load())
+
+	testNow = testNow.Add(2 * time.Minute)
+	defer func() { testNow = testNow.Add(-2 * time.Minute) }()
+	_, err = v.Verify(ctx, issuer.token(t, "RS256", "new", validClaims()))
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	assert.Equal(t, int32(2), issuer.fetches.Load())
+}
+
+func TestJWKSFailureBackoff(t *testing.T) {
+	issuer := newTestIssuer(t)
+	v := issuer.verifier()
+	ctx := context.Background()
+	token := issuer.token(t, "RS256", "rsa", validClaims())
+	_, err := v.Verify(ctx, token)
+	require.NoError(t, err)
+
+	// Once the keys are stale and the endpoint is down, the old key is kept,
+	// and the failure stops refetches for a while
+	var failures atomic.Int32
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failures.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	v.keys.url = down.URL
+	testNow = testNow.Add(2 * time.Hour)
+	defer func() { testNow = testNow.Add(-2 * time.Hour) }()
+	token = issuer.token(t, "RS256", "rsa", validClaims())
+	for i := 0; i < 3; i++ {
+		_, err = v.Verify(ctx, token)
+		assert.NoError(t, err)
+	}
+	_, err = v.Verify(ctx, issuer.token(t, "RS256", "new", validClaims()))
+	assert.ErrorContains(t, err, "503")
+	assert.Equal(t, int32(1), failures.Load())
+}
+
+func TestJWKSFetchDoesntBlockLookups(t *testing.T) {
+	issuer := newTestIssuer(t)
+	v := issuer.verifier()
+	ctx := context.Background()
+	token := issuer.token(t, "RS256", "rsa", validClaims())
+	_, err := v.Verify(ctx, token)
+	require.NoError(t, err)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer hung.Close()
+	defer close(release)
+	v.keys.url = hung.URL
+	testNow = testNow.Add(2 * time.Minute)
+	defer func() { testNow = testNow.Add(-2 * time.Minute) }()
+
+	go v.Verify(ctx, issuer.token(t, "RS256", "new", validClaims()))
+	<-started
+	done := make(chan error)
+	go func() {
+		_, err := v.Verify(ctx, token)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("lookup waited on the fetch")
+	}
+}
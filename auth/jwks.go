@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+// DefaultJWKSCacheTTL is how long fetched keys are used before they're
+// fetched again.
+const DefaultJWKSCacheTTL = time.Hour
+
+// DefaultJWKSTimeout bounds each fetch of the keys when Config.HTTPClient
+// isn't set.
+const DefaultJWKSTimeout = 10 * time.Second
+
+// Keys aren't fetched again more often than this, whether the last attempt
+// worked or not, so a flood of forged tokens or a JWKS endpoint that's down
+// doesn't turn every request into a fetch.
+const minJWKSRefresh = time.Minute
+
+var defaultJWKSClient = &http.Client{Timeout: DefaultJWKSTimeout}
+
+// ErrUnknownKey is returned for a token signed with a key the JWKS doesn't
+// have.
+var ErrUnknownKey = errors.New("auth: unknown signing key")
+
+// jwk is one key of a JSON Web Key Set.  Only RSA and EC signing keys are
+// used.
+type jwk struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// keySet fetches and caches the keys from a JWKS URL.
+type keySet struct {
+	url    string
+	client *http.Client
+	ttl    time.Duration
+	now    func() time.Time
+
+	// Concurrent lookups share a fetch, which happens outside the lock
+	fetches singleflight.Group
+
+	lock      sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetched   time.Time
+	attempted time.Time
+	err       error
+}
+
+// key returns the public key with the ID, fetching the keys when they've
+// expired, or when the ID is new, unless they were fetched, or failed to be,
+// recently.
+func (ks *keySet) key(ctx context.Context, id string) (crypto.PublicKey, error) {
+	ks.lock.Lock()
+	now := ks.now()
+	key, ok := ks.keys[id]
+	stale := now.Sub(ks.fetched) >= ks.ttl
+	recent := now.Sub(ks.attempted) < minJWKSRefresh
+	lastErr := ks.err
+	ks.lock.Unlock()
+
+	switch {
+	case ok && (!stale || recent):
+		return key, nil
+	case recent && lastErr != nil:
+		return nil, lastErr
+	case recent:
+		return nil, ErrUnknownKey
+	}
+
+	result, err, _ := ks.fetches.Do(ks.url, func() (interface{}, error) {
+		// Other lookups are waiting on this fetch too, so it mustn't be
+		// cancelled along with the first one's request
+		keys, err := ks.fetch(context.WithoutCancel(ctx))
+		ks.lock.Lock()
+		defer ks.lock.Unlock()
+		ks.attempted, ks.err = ks.now(), err
+		if err != nil {
+			return nil, err
+		}
+		ks.keys, ks.fetched = keys, ks.attempted
+		return keys, nil
+	})
+	if err != nil {
+		if ok {
+			// Keep using the key we have rather than locking everyone out
+			velacontext.GetContextLogger(ctx).Warn("Unable to refresh JWKS", zap.String("url", ks.url), zap.Error(err))
+			return key, nil
+		}
+		return nil, err
+	}
+	if key, ok = result.(map[string]crypto.PublicKey)[id]; !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+func (ks *keySet) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("auth: fetching JWKS: %w", err)
+	}
+	response, err := ks.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("auth: fetching JWKS: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("auth: fetching JWKS: %s", response.Status)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("auth: decoding JWKS: %w", err)
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			velacontext.GetContextLogger(ctx).Warn("Skipping JWKS key", zap.String("kid", k.KeyID), zap.Error(err))
+			continue
+		}
+		keys[k.KeyID] = key
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("auth: RSA exponent is too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("auth: unsupported curve %q", k.Curve)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("auth: EC point isn't on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("auth: unsupported key type %q", k.KeyType)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("auth: invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
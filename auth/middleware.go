@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/context/lambdamiddleware"
+	"github.com/seniorlink-vela/cs-common/handlers/respond"
+)
+
+// ErrForbidden is returned when the identity doesn't have a required role.
+var ErrForbidden = errors.New("auth: forbidden")
+
+// BearerToken returns the token from an Authorization header, or an empty
+// string when it isn't a bearer token.
+func BearerToken(header string) string {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// Middleware returns middleware that authenticates each request's bearer
+// token, and responds with a 401 when it's missing or doesn't verify.  Put it
+// inside httpmiddleware.RequestContext, so failures are logged with the
+// request ID.
+func Middleware(v *Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, err := v.Authenticate(r.Context(), BearerToken(r.Header.Get("Authorization")))
+			if err != nil {
+				writeError(w, failure(r.Context(), err))
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireRole returns middleware that responds with a 403 unless the
+// context's identity has at least one of the roles.  Put it inside
+// Middleware.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := checkRoles(r.Context(), roles); err != nil {
+				writeError(w, failure(r.Context(), err))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ALBMiddleware authenticates the bearer token of ALB requests, and adds the
+// identity to the context the handler gets.  Put it inside
+// lambdamiddleware.ALBRequestContext, so failures are logged with the request
+// ID.
+func ALBMiddleware(v *Verifier) func(lambdamiddleware.ALBHandler) lambdamiddleware.ALBHandler {
+	return func(next lambdamiddleware.ALBHandler) lambdamiddleware.ALBHandler {
+		return func(ctx context.Context, req events.ALBTargetGroupRequest) (*events.ALBTargetGroupResponse, error) {
+			authCtx, err := v.Authenticate(ctx, BearerToken(lambdamiddleware.ALBHeader(req, "Authorization")))
+			if err != nil {
+				return albError(failure(ctx, err)), nil
+			}
+			return next(authCtx, req)
+		}
+	}
+}
+
+// ALBRequireRole is RequireRole for ALB handlers.
+func ALBRequireRole(roles ...string) func(lambdamiddleware.ALBHandler) lambdamiddleware.ALBHandler {
+	return func(next lambdamiddleware.ALBHandler) lambdamiddleware.ALBHandler {
+		return func(ctx context.Context, req events.ALBTargetGroupRequest) (*events.ALBTargetGroupResponse, error) {
+			if err := checkRoles(ctx, roles); err != nil {
+				return albError(failure(ctx, err)), nil
+			}
+			return next(ctx, req)
+		}
+	}
+}
+
+func checkRoles(ctx context.Context, roles []string) error {
+	identity, ok := velacontext.LookupIdentity(ctx)
+	if !ok {
+		return ErrMissingToken
+	}
+	for _, role := range roles {
+		if identity.HasRole(role) {
+			return nil
+		}
+	}
+	return ErrForbidden
+}
+
+// authError is a failed check, as it's sent.  The reason is logged rather
+// than sent, so callers can't probe what the checks are.
+type authError struct {
+	status int
+	body   respond.ErrorBody
+}
+
+func failure(ctx context.Context, err error) authError {
+	logger := velacontext.GetContextLogger(ctx)
+	switch {
+	case errors.Is(err, ErrForbidden):
+		logger.Info("Request forbidden", zap.Strings("roles", velacontext.GetContextRoles(ctx)))
+		return authError{http.StatusForbidden, respond.ErrorBody{Message: http.StatusText(http.StatusForbidden), ErrorType: respond.ErrorTypeForbidden}}
+	case errors.Is(err, ErrMissingToken), errors.Is(err, ErrInvalidToken), errors.Is(err, ErrExpired), errors.Is(err, ErrInvalidClaims):
+		logger.Info("Request unauthorized", zap.Error(err))
+		return authError{http.StatusUnauthorized, respond.ErrorBody{Message: http.StatusText(http.StatusUnauthorized), ErrorType: respond.ErrorTypeUnauthorized}}
+	}
+	// The keys couldn't be fetched
+	logger.Error("Unable to authenticate request", zap.Error(err))
+	return authError{http.StatusServiceUnavailable, respond.ErrorBody{Message: http.StatusText(http.StatusServiceUnavailable), ErrorType: respond.ErrorTypeUnavailable}}
+}
+
+func writeError(w http.ResponseWriter, e authError) {
+	if e.status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	respond.JSON(w, e.status, e.body)
+}
+
+func albError(e authError) *events.ALBTargetGroupResponse {
+	// The body always marshals
+	resp, _ := respond.ALB(e.status, e.body)
+	if e.status == http.StatusUnauthorized {
+		resp.Headers["WWW-Authenticate"] = "Bearer"
+	}
+	return resp
+}
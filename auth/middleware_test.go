@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+func TestMiddleware(t *testing.T) {
+	issuer := newTestIssuer(t)
+	token := issuer.token(t, "RS256", "rsa", validClaims())
+	handler := Middleware(issuer.verifier())(RequireRole("coordinator")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(velacontext.GetContextUserID(r.Context())))
+	})))
+
+	tests := []struct {
+		name   string
+		header string
+		status int
+	}{
+		{"authorized", "Bearer " + token, http.StatusOK},
+		{"missing", "", http.StatusUnauthorized},
+		{"basic", "Basic dXNlcjpwYXNz", http.StatusUnauthorized},
+		{"invalid", "Bearer " + token + "x", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.header != "" {
+				r.Header.Set("Authorization", test.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			assert.Equal(t, test.status, w.Code)
+			if test.status == http.StatusOK {
+				assert.Equal(t, "user-1", w.Body.String())
+			} else {
+				assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+				assert.JSONEq(t, `{"message":"Unauthorized","error_type":"unauthorized"}`, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	handler := RequireRole("admin", "coordinator")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r = r.WithContext(velacontext.ContextWithIdentity(r.Context(), velacontext.Identity{Roles: []string{"caregiver"}}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.JSONEq(t, `{"message":"Forbidden","error_type":"forbidden"}`, w.Body.String())
+
+	r = r.WithContext(velacontext.ContextWithIdentity(r.Context(), velacontext.Identity{Roles: []string{"coordinator"}}))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestALBMiddleware(t *testing.T) {
+	issuer := newTestIssuer(t)
+	handler := ALBMiddleware(issuer.verifier())(ALBRequireRole("caregiver")(func(ctx context.Context, req events.ALBTargetGroupRequest) (*events.ALBTargetGroupResponse, error) {
+		return &events.ALBTargetGroupResponse{StatusCode: http.StatusOK, Body: velacontext.GetContextUserID(ctx)}, nil
+	}))
+
+	resp, err := handler(context.Background(), events.ALBTargetGroupRequest{
+		MultiValueHeaders: map[string][]string{"authorization": {"Bearer " + issuer.token(t, "ES256", "ec", validClaims())}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "user-1", resp.Body)
+
+	resp, err = handler(context.Background(), events.ALBTargetGroupRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "Bearer", resp.Headers["WWW-Authenticate"])
+}
+
+func TestUnavailableJWKS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	issuer := newTestIssuer(t)
+	handler := Middleware(NewVerifier(Config{JWKSURL: server.URL}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+issuer.token(t, "RS256", "rsa", validClaims()))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
@@ -0,0 +1,165 @@
+// Package cache caches values in memory, Redis or DynamoDB, typed with
+// generics.
+//
+//	plans := cache.New[CarePlan](cache.NewRedis(client), cache.Options{Prefix: "care-plans", TTL: time.Minute})
+//	plan, err := plans.GetOrFill(ctx, id, func(ctx context.Context) (CarePlan, error) {
+//		return fetchCarePlan(ctx, id)
+//	})
+//
+// Values are stored as JSON, so any backend can hold any type, and callers
+// get their own copy.  Concurrent fills of the same key in a process share
+// one call.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+// The defaults for Options' zero fields.
+const (
+	DefaultTTL         = 5 * time.Minute
+	DefaultFillTimeout = 30 * time.Second
+)
+
+// Backend stores encoded values.  Get returns false for missing or expired
+// keys.
+type Backend interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// Options configure a Cache.
+type Options struct {
+	// Prefix is added to keys, so caches can share a backend.
+	Prefix string
+	// TTL is how long values are kept.
+	TTL time.Duration
+	// FillTimeout is how long GetOrFill's fill can take.  The fill is
+	// shared, so it isn't cancelled with the caller that started it.
+	FillTimeout time.Duration
+}
+
+// Cache holds values of one type.
+type Cache[T any] struct {
+	backend Backend
+	options Options
+	fills   singleflight.Group
+}
+
+// New returns a cache that keeps its values in the backend.
+func New[T any](backend Backend, options Options) *Cache[T] {
+	if options.TTL <= 0 {
+		options.TTL = DefaultTTL
+	}
+	if options.FillTimeout <= 0 {
+		options.FillTimeout = DefaultFillTimeout
+	}
+	return &Cache[T]{backend: backend, options: options}
+}
+
+func (c *Cache[T]) key(key string) string {
+	if c.options.Prefix == "" {
+		return key
+	}
+	return c.options.Prefix + ":" + key
+}
+
+// Get returns the key's value, and false when it isn't cached.
+func (c *Cache[T]) Get(ctx context.Context, key string) (T, bool, error) {
+	var value T
+	data, ok, err := c.backend.Get(ctx, c.key(key))
+	if err != nil || !ok {
+		return value, false, err
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return value, false, fmt.Errorf("cache: unable to decode %s: %w", key, err)
+	}
+	return value, true, nil
+}
+
+// Set caches the value for the TTL.
+func (c *Cache[T]) Set(ctx context.Context, key string, value T) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("cache: unable to encode %s: %w", key, err)
+	}
+	return c.backend.Set(ctx, c.key(key), data, c.options.TTL)
+}
+
+// Delete removes the key's value.
+func (c *Cache[T]) Delete(ctx context.Context, key string) error {
+	return c.backend.Delete(ctx, c.key(key))
+}
+
+// GetOrFill returns the key's value, calling fill and caching its value when
+// it isn't cached.  Concurrent calls for the same key wait for the first
+// one's fill, which runs with the first caller's context values but not its
+// cancellation, up to the FillTimeout, so one caller giving up doesn't fail
+// the others.  A caller whose context is done stops waiting.  The backend
+// failing is logged rather than returned, so the cache being down only
+// makes things slower; fill's error is returned, and nothing is cached.
+func (c *Cache[T]) GetOrFill(ctx context.Context, key string, fill func(ctx context.Context) (T, error)) (T, error) {
+	logger := velacontext.GetContextLogger(ctx)
+	value, ok, err := c.Get(ctx, key)
+	if err != nil {
+		logger.Warn("Unable to read from the cache", zap.String("cache_key", c.key(key)), zap.Error(err))
+	}
+	if ok {
+		return value, nil
+	}
+
+	fills := c.fills.DoChan(c.key(key), func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.options.FillTimeout)
+		defer cancel()
+		value, err := fill(ctx)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			logger.Warn("Unable to write to the cache", zap.String("cache_key", c.key(key)), zap.Error(fmt.Errorf("cache: unable to encode %s: %w", key, err)))
+			return filled[T]{value: value}, nil
+		}
+		if err := c.backend.Set(ctx, c.key(key), data, c.options.TTL); err != nil {
+			logger.Warn("Unable to write to the cache", zap.String("cache_key", c.key(key)), zap.Error(err))
+		}
+		return filled[T]{value: value, data: data}, nil
+	})
+	var zero T
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case result := <-fills:
+		if result.Err != nil {
+			return zero, result.Err
+		}
+		return result.Val.(filled[T]).copy(key)
+	}
+}
+
+// filled is a fill's value, and its encoding, for each caller that waited on
+// the fill to decode their own copy from.
+type filled[T any] struct {
+	value T
+	data  []byte
+}
+
+func (f filled[T]) copy(key string) (T, error) {
+	if f.data == nil {
+		return f.value, nil
+	}
+	var value T
+	if err := json.Unmarshal(f.data, &value); err != nil {
+		return value, fmt.Errorf("cache: unable to decode %s: %w", key, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,215 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type organization struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMemory(0)
+	orgs := New[organization](backend, Options{Prefix: "orgs"})
+
+	_, ok, err := orgs.Get(ctx, "1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, orgs.Set(ctx, "1", organization{ID: 1, Name: "Acme"}))
+	org, ok, err := orgs.Get(ctx, "1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, organization{ID: 1, Name: "Acme"}, org)
+	_, ok, _ = backend.Get(ctx, "orgs:1")
+	assert.True(t, ok)
+
+	require.NoError(t, orgs.Delete(ctx, "1"))
+	_, ok, _ = orgs.Get(ctx, "1")
+	assert.False(t, ok)
+
+	// Another type sharing the backend can't decode it
+	require.NoError(t, orgs.Set(ctx, "2", organization{ID: 2}))
+	_, _, err = New[int](backend, Options{Prefix: "orgs"}).Get(ctx, "2")
+	assert.ErrorContains(t, err, "cache: unable to decode 2")
+}
+
+func TestGetOrFill(t *testing.T) {
+	ctx := context.Background()
+	orgs := New[organization](NewMemory(0), Options{})
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fill := func(ctx context.Context) (organization, error) {
+		calls.Add(1)
+		<-release
+		return organization{ID: 1}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]organization, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			org, err := orgs.GetOrFill(ctx, "1", fill)
+			assert.NoError(t, err)
+			results[i] = org
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), calls.Load())
+	for _, org := range results {
+		assert.Equal(t, int64(1), org.ID)
+	}
+
+	// Cached now
+	org, err := orgs.GetOrFill(ctx, "1", func(context.Context) (organization, error) {
+		t.Error("filled again")
+		return organization{}, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), org.ID)
+}
+
+func TestGetOrFillError(t *testing.T) {
+	ctx := context.Background()
+	orgs := New[organization](NewMemory(0), Options{})
+	errFailed := errors.New("failed")
+	_, err := orgs.GetOrFill(ctx, "1", func(context.Context) (organization, error) {
+		return organization{ID: 1}, errFailed
+	})
+	assert.Equal(t, errFailed, err)
+	_, ok, _ := orgs.Get(ctx, "1")
+	assert.False(t, ok)
+}
+
+func TestGetOrFillFirstCallerCancels(t *testing.T) {
+	orgs := New[organization](NewMemory(0), Options{})
+	release := make(chan struct{})
+	started := make(chan struct{})
+	fill := func(ctx context.Context) (organization, error) {
+		close(started)
+		select {
+		case <-release:
+			return organization{ID: 1}, nil
+		case <-ctx.Done():
+			return organization{}, ctx.Err()
+		}
+	}
+
+	// The first caller gives up, but the fill it started carries on for
+	// the second
+	first, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() {
+		_, err := orgs.GetOrFill(first, "1", fill)
+		errs <- err
+	}()
+	<-started
+	cancel()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	org, err := orgs.GetOrFill(context.Background(), "1", fill)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), org.ID)
+}
+
+func TestGetOrFillTimeout(t *testing.T) {
+	orgs := New[organization](NewMemory(0), Options{FillTimeout: 10 * time.Millisecond})
+	_, err := orgs.GetOrFill(context.Background(), "1", func(ctx context.Context) (organization, error) {
+		<-ctx.Done()
+		return organization{}, ctx.Err()
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestGetOrFillCopies(t *testing.T) {
+	ctx := context.Background()
+	names := New[map[string]string](NewMemory(0), Options{})
+	release := make(chan struct{})
+	fill := func(context.Context) (map[string]string, error) {
+		<-release
+		return map[string]string{"1": "Vela"}, nil
+	}
+
+	// Callers that shared a fill can change their value without changing
+	// the others'
+	results := make(chan map[string]string, 2)
+	for range 2 {
+		go func() {
+			value, err := names.GetOrFill(ctx, "orgs", fill)
+			assert.NoError(t, err)
+			results <- value
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	a, b := <-results, <-results
+	a["1"] = "changed"
+	assert.Equal(t, "Vela", b["1"])
+
+	anything := New[any](NewMemory(0), Options{})
+	value, err := anything.GetOrFill(ctx, "nothing", func(context.Context) (any, error) {
+		return nil, nil
+	})
+	require.NoError(t, err)
+	assert.Nil(t, value)
+}
+
+type brokenBackend struct{}
+
+func (brokenBackend) Get(context.Context, string) ([]byte, bool, error) {
+	return nil, false, errors.New("down")
+}
+func (brokenBackend) Set(context.Context, string, []byte, time.Duration) error {
+	return errors.New("down")
+}
+func (brokenBackend) Delete(context.Context, string) error { return errors.New("down") }
+
+func TestGetOrFillBackendDown(t *testing.T) {
+	orgs := New[organization](brokenBackend{}, Options{})
+	org, err := orgs.GetOrFill(context.Background(), "1", func(context.Context) (organization, error) {
+		return organization{ID: 1}, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), org.ID)
+}
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(2)
+	now := time.Now()
+	m.now = func() time.Time { return now }
+
+	require.NoError(t, m.Set(ctx, "a", []byte("a"), time.Minute))
+	require.NoError(t, m.Set(ctx, "b", []byte("b"), time.Hour))
+	// Full, so the one closest to expiring goes
+	require.NoError(t, m.Set(ctx, "c", []byte("c"), time.Hour))
+	_, ok, _ := m.Get(ctx, "a")
+	assert.False(t, ok)
+	value, ok, _ := m.Get(ctx, "c")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("c"), value)
+
+	now = now.Add(2 * time.Hour)
+	_, ok, _ = m.Get(ctx, "b")
+	assert.False(t, ok)
+	require.NoError(t, m.Set(ctx, "d", []byte("d"), time.Hour))
+	assert.Len(t, m.entries, 1)
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBAPI is the part of the DynamoDB client the backend uses.
+// *dynamodb.Client implements it.
+type DynamoDBAPI interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+}
+
+var _ DynamoDBAPI = (*dynamodb.Client)(nil)
+
+// DynamoDB keeps values in a table, for services without Redis.  The
+// table's partition key is the string "key", and expires_at holds the
+// expiry in Unix seconds, so it can be the table's TTL attribute.  DynamoDB
+// deletes expired items lazily, so Get checks expires_at itself.
+type DynamoDB struct {
+	client DynamoDBAPI
+	table  string
+	now    func() time.Time
+}
+
+var _ Backend = (*DynamoDB)(nil)
+
+// NewDynamoDB returns a backend for the table.
+func NewDynamoDB(client DynamoDBAPI, table string) *DynamoDB {
+	return &DynamoDB{client: client, table: table, now: time.Now}
+}
+
+func (d *DynamoDB) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	out, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.table),
+		Key:       map[string]types.AttributeValue{"key": &types.AttributeValueMemberS{Value: key}},
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	value, ok := out.Item["value"].(*types.AttributeValueMemberB)
+	expiresAt, _ := out.Item["expires_at"].(*types.AttributeValueMemberN)
+	if !ok || expiresAt == nil {
+		return nil, false, nil
+	}
+	if seconds, err := strconv.ParseInt(expiresAt.Value, 10, 64); err != nil || seconds <= d.now().Unix() {
+		return nil, false, nil
+	}
+	return value.Value, true, nil
+}
+
+func (d *DynamoDB) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.table),
+		Item: map[string]types.AttributeValue{
+			"key":        &types.AttributeValueMemberS{Value: key},
+			"value":      &types.AttributeValueMemberB{Value: value},
+			"expires_at": &types.AttributeValueMemberN{Value: strconv.FormatInt(d.now().Add(ttl).Unix(), 10)},
+		},
+	})
+	return err
+}
+
+func (d *DynamoDB) Delete(ctx context.Context, key string) error {
+	_, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.table),
+		Key:       map[string]types.AttributeValue{"key": &types.AttributeValueMemberS{Value: key}},
+	})
+	return err
+}
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockDynamoDB struct {
+	items map[string]map[string]types.AttributeValue
+}
+
+func keyOf(key map[string]types.AttributeValue) string {
+	return key["key"].(*types.AttributeValueMemberS).Value
+}
+
+func (m *mockDynamoDB) GetItem(ctx context.Context, in *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: m.items[keyOf(in.Key)]}, nil
+}
+
+func (m *mockDynamoDB) PutItem(ctx context.Context, in *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	m.items[keyOf(in.Item)] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (m *mockDynamoDB) DeleteItem(ctx context.Context, in *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	delete(m.items, keyOf(in.Key))
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func TestDynamoDB(t *testing.T) {
+	ctx := context.Background()
+	client := &mockDynamoDB{items: map[string]map[string]types.AttributeValue{}}
+	backend := NewDynamoDB(client, "cache")
+	now := time.Now()
+	backend.now = func() time.Time { return now }
+
+	require.NoError(t, backend.Set(ctx, "key", []byte("value"), time.Minute))
+	value, ok, err := backend.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("value"), value)
+
+	// Expired, but not deleted yet
+	now = now.Add(2 * time.Minute)
+	_, ok, err = backend.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, backend.Delete(ctx, "key"))
+	assert.Empty(t, client.items)
+	_, ok, err = backend.Get(ctx, "missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultMaxEntries is the most values a Memory backend holds when
+// NewMemory's maxEntries is zero.
+const DefaultMaxEntries = 10000
+
+type entry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// Memory keeps values in the process.  When it's full, expired values are
+// dropped, then the ones closest to expiring.
+type Memory struct {
+	mu         sync.Mutex
+	entries    map[string]entry
+	maxEntries int
+	now        func() time.Time
+}
+
+var _ Backend = (*Memory)(nil)
+
+// NewMemory returns an empty memory backend holding up to maxEntries values.
+func NewMemory(maxEntries int) *Memory {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &Memory{entries: map[string]entry{}, maxEntries: maxEntries, now: time.Now}
+}
+
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok || !e.expiresAt.After(m.now()) {
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+func (m *Memory) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.entries[key]; !ok && len(m.entries) >= m.maxEntries {
+		m.evict()
+	}
+	m.entries[key] = entry{value: value, expiresAt: m.now().Add(ttl)}
+	return nil
+}
+
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
+
+// evict makes room for a value.
+func (m *Memory) evict() {
+	now := m.now()
+	var soonest string
+	for key, e := range m.entries {
+		if !e.expiresAt.After(now) {
+			delete(m.entries, key)
+			continue
+		}
+		if soonest == "" || e.expiresAt.Before(m.entries[soonest].expiresAt) {
+			soonest = key
+		}
+	}
+	if len(m.entries) >= m.maxEntries {
+		delete(m.entries, soonest)
+	}
+}
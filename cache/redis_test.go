@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	backend := NewRedis(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+
+	_, ok, err := backend.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, backend.Set(ctx, "key", []byte("value"), time.Minute))
+	value, ok, err := backend.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("value"), value)
+
+	server.FastForward(2 * time.Minute)
+	_, ok, err = backend.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, backend.Set(ctx, "key", []byte("value"), time.Minute))
+	require.NoError(t, backend.Delete(ctx, "key"))
+	assert.False(t, server.Exists("key"))
+
+	server.Close()
+	_, _, err = backend.Get(ctx, "key")
+	assert.Error(t, err)
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/seniorlink-vela/cs-common/metrics"
+)
+
+// The metrics the client records with metrics.Default.
+var (
+	requestsMetric = metrics.Definition{
+		Name: "client_requests",
+		Help: "Requests made to the Vela API.",
+		Tags: []string{"method", "status"},
+	}
+	requestDurationMetric = metrics.Definition{
+		Name: "client_request_duration_ms",
+		Help: "How long requests to the Vela API took.",
+		Unit: metrics.UnitMilliseconds,
+		Tags: []string{"method"},
+		// Milliseconds, rather than Prometheus' seconds
+		Buckets: []float64{10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000},
+	}
+	eventsReceivedMetric = metrics.Definition{
+		Name: "queue_events_received",
+		Help: "Events read from the event queue.",
+	}
+	watermarkMetric = metrics.Definition{
+		Name: "queue_watermark",
+		Help: "The last event queue index read.",
+	}
+)
+
+// metricsTransport counts and times each request.  The status is "error"
+// when there isn't a response.
+type metricsTransport struct {
+	next http.RoundTripper
+}
+
+func (t metricsTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	start := time.Now()
+	response, err := t.next.RoundTrip(request)
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(response.StatusCode)
+	}
+	provider := metrics.Default()
+	provider.Counter(requestsMetric).Inc(metrics.Tags{"method": request.Method, "status": status})
+	provider.Histogram(requestDurationMetric).Observe(metrics.Milliseconds(start), metrics.Tags{"method": request.Method})
+	return response, err
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/seniorlink-vela/cs-common/metrics"
+)
+
+func TestMetricsTransport(t *testing.T) {
+	var out bytes.Buffer
+	provider := metrics.NewEMF(metrics.EMFOptions{Namespace: "Vela/Test", Writer: &out})
+	metrics.SetDefault(provider)
+	defer metrics.SetDefault(nil)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: metricsTransport{next: http.DefaultTransport}}
+	response, err := client.Get(server.URL)
+	require.NoError(t, err)
+	response.Body.Close()
+	require.NoError(t, provider.Flush())
+
+	assert.Contains(t, out.String(), `"client_requests":1`)
+	assert.Contains(t, out.String(), `"status":"202"`)
+	assert.Contains(t, out.String(), `"client_request_duration_ms":[`)
+}
@@ -16,6 +16,11 @@ import (
 
 	"github.com/seniorlink-vela/cs-common/config"
 	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/metrics"
+	"github.com/seniorlink-vela/cs-common/optional"
+	"github.com/seniorlink-vela/cs-common/redact"
+	"github.com/seniorlink-vela/cs-common/timeutil"
+	"github.com/seniorlink-vela/cs-common/tracing"
 	"github.com/seniorlink-vela/cs-common/validation"
 )
 
@@ -46,6 +51,22 @@ type CaregiverCreate struct {
 	Primary bool
 }
 
+// The Authorization header for a call, using the context's access token when
+// the caller didn't pass one.
+func bearer(ctx context.Context, token string) string {
+	if token == "" {
+		token = velacontext.GetContextToken(ctx)
+	}
+	return fmt.Sprintf("Bearer %s", token)
+}
+
+// Passes the context's baggage on to the service being called.
+func addBaggage(ctx context.Context, request *http.Request) {
+	for k, v := range velacontext.Baggage(ctx) {
+		request.Header.Set(velacontext.BaggageHeaderPrefix+k, v)
+	}
+}
+
 func Init(maxIdle int, idleTimeout, clientTimeout time.Duration) {
 	clientTransport = &http.Transport{
 		DisableKeepAlives: true,
@@ -54,7 +75,7 @@ func Init(maxIdle int, idleTimeout, clientTimeout time.Duration) {
 	}
 	apiClient = &http.Client{
 		Timeout:   clientTimeout,
-		Transport: clientTransport,
+		Transport: tracing.Transport(retryTransport{next: metricsTransport{next: clientTransport}}),
 	}
 }
 
@@ -83,12 +104,12 @@ func (h HttpClientError) Error() string {
 
 type Profile struct {
 	ID                   string            `json:"id,omitempty"`
-	FirstName            *string           `json:"first_name,omitempty" validation:"required,max-length:255"`
-	MiddleName           *string           `json:"middle_name,omitempty" validation:"max-length:255"`
-	LastName             *string           `json:"last_name,omitempty" validation:"required,max-length:255"`
-	Username             *string           `json:"username,omitempty" validation:"required,max-length:255"`
-	Email                *string           `json:"email,omitempty" validation:"email,max-length:255,required"`
-	SecondEmail          *string           `json:"second_email,omitempty" validation:"email,max-length:255"`
+	FirstName            *string           `json:"first_name,omitempty" validation:"required(create),max-length:255" normalize:"trim,collapse-spaces"`
+	MiddleName           *string           `json:"middle_name,omitempty" validation:"max-length:255" normalize:"trim,collapse-spaces"`
+	LastName             *string           `json:"last_name,omitempty" validation:"required(create),max-length:255" normalize:"trim,collapse-spaces"`
+	Username             *string           `json:"username,omitempty" validation:"required(create),max-length:255" normalize:"trim"`
+	Email                *string           `json:"email,omitempty" validation:"email,max-length:255,required(create)" normalize:"trim,lower"`
+	SecondEmail          *string           `json:"second_email,omitempty" validation:"email,max-length:255" normalize:"trim,lower"`
 	AddressLine1         *string           `json:"address1,omitempty" validation:"max-length:255"`
 	AddressLine2         *string           `json:"address2,omitempty" validation:"max-length:255"`
 	City                 *string           `json:"city,omitempty" validation:"max-length:255"`
@@ -100,21 +121,21 @@ type Profile struct {
 	SecondaryPhoneNumber *string           `json:"secondary_phone_number,omitempty"`
 	SecondaryPhoneType   *string           `json:"secondary_phone_type,omitempty" validation:"values-insensitive:mobile|home|work|tablet|other"`
 	Locale               *string           `json:"locale,omitempty" validation:"max-length:255"`
-	TimeZone             *string           `json:"time_zone,omitempty"`
+	TimeZone             *string           `json:"time_zone,omitempty" validation:"timezone"`
 	Gender               *GenderOption     `json:"gender,omitempty" validation:"values:Female|Male|Transgender|Unspecififed"`
-	Birthday             *time.Time        `json:"birthday,omitempty"`
+	Birthday             *time.Time        `json:"birthday,omitempty" validation:"before:now"`
 	NeedsOnboarding      bool              `json:"needs_onboarding,omitempty"`
 	UserTypeID           *int              `json:"user_type_id"`
 	OrganizationID       *int              `json:"organization_id,omitempty"`
 	ExtendedProperties   map[string]string `json:"extended_properties,omitempty" pg:"extended_properties,hstore"`
 	AccessToken          string            `json:"-"`
-	Landing              string            `json:"landing" validation:"required"`
-	Program              string            `json:"program" validation:"required"`
+	Landing              string            `json:"landing" validation:"required(create)"`
+	Program              string            `json:"program" validation:"required(create)"`
 	Extensions           *[]*ExtensionData `json:"extensions,omitempty"`
 }
 
 type ExtensionData struct {
-	ID          int64                       `json:"extension_id" validate:"required"`
+	ID          int64                       `json:"extension_id" validation:"required"`
 	Name        string                      `json:"name"`
 	Description string                      `json:"description"`
 	Values      []*ObjectExtensionDataValue `json:"values"`
@@ -136,18 +157,49 @@ type ProfileResponse struct {
 	P Profile `json:"user_profile"`
 }
 
+// ContextWithLocalization adds the profile's locale and time zone to the
+// context, when it has them, so later formatting doesn't need the profile.
+// An unknown time zone is left out.
+func (p *Profile) ContextWithLocalization(ctx context.Context) context.Context {
+	if p.Locale != nil && *p.Locale != "" {
+		ctx = velacontext.ContextWithLocale(ctx, *p.Locale)
+	}
+	if p.TimeZone != nil && *p.TimeZone != "" {
+		ctx, _ = velacontext.ContextWithTimeZone(ctx, *p.TimeZone)
+	}
+	return ctx
+}
+
+// Age returns the profile's age in whole years as of now, counted in the
+// profile's own time zone when it has a valid one. It's false when there's no
+// birthday.
+func (p *Profile) Age(now time.Time) (int, bool) {
+	if p.Birthday == nil || p.Birthday.IsZero() {
+		return 0, false
+	}
+	birthday := *p.Birthday
+	if p.TimeZone != nil {
+		if loc, err := timeutil.LoadZone(*p.TimeZone); err == nil {
+			birthday = time.Date(birthday.Year(), birthday.Month(), birthday.Day(), 0, 0, 0, 0, loc)
+		}
+	}
+	return timeutil.Age(birthday, now), true
+}
+
+// Validate normalizes the profile in place, as its `normalize` tags say,
+// trimming names and lower casing emails, and then checks it for creating.
+// The program has to be in the current config.
 func (p *Profile) Validate() error {
 	var validationError = ErrorMap{}
-	_ = validation.ValidateStruct(*p, validationError)
+	_ = validation.NormalizeStruct(p)
+	_ = validation.ValidateStructForScenario(*p, validation.ScenarioCreate, validationError)
 
 	conf := config.Current()
 
-	if _, lOk := conf.Landing[p.Landing]; !lOk {
+	if _, err := conf.Program(p.Landing, p.Program); errors.Is(err, config.ErrProgramNotFound) {
+		validationError.AppendErrorField("program", "Invalid program passed")
+	} else if err != nil {
 		validationError.AppendErrorField("landing", "Invalid landing passed")
-	} else {
-		if _, pOk := conf.Landing[p.Landing].ProgramMap[p.Program]; !pOk {
-			validationError.AppendErrorField("program", "Invalid program passed")
-		}
 	}
 	if len(validationError) > 0 {
 		return validationError
@@ -155,6 +207,19 @@ func (p *Profile) Validate() error {
 	return nil
 }
 
+// ValidatePatch only checks the fields being changed, so unlike Validate
+// the names, landing, and program aren't required.  Like Validate, it
+// normalizes the profile in place first.
+func (p *Profile) ValidatePatch() error {
+	var validationError = ErrorMap{}
+	_ = validation.NormalizeStruct(p)
+	_ = validation.ValidateStructForScenario(*p, validation.ScenarioPatch, validationError)
+	if len(validationError) > 0 {
+		return validationError
+	}
+	return nil
+}
+
 type OAuthRequest struct {
 	Username string
 	Password string
@@ -179,13 +244,14 @@ func (o OAuthRequest) GetToken(ctx context.Context, baseURI string) (*OAuthRespo
 	defer func() {
 		go clientTransport.CloseIdleConnections()
 	}()
-	requestID := velacontext.GetContextRequestID(ctx)
+	ctx, requestID := velacontext.EnsureRequestID(ctx)
 	params := o.toParams()
 	tokenRequestURI := fmt.Sprintf("%s/authentication/token", baseURI)
 	b := strings.NewReader(params.Encode())
-	req, err := http.NewRequest("POST", tokenRequestURI, b)
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenRequestURI, b)
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Add("X-Vela-Request-Id", requestID)
+	addBaggage(ctx, req)
 	req.Close = true
 	if err != nil {
 		return nil, err
@@ -204,7 +270,7 @@ func (o OAuthRequest) GetToken(ctx context.Context, baseURI string) (*OAuthRespo
 			return nil, jsonErr
 		}
 		logger := velacontext.GetContextLogger(ctx)
-		logger.Info("OAuth error", zap.Any("response", errMap))
+		logger.Info("OAuth error", zap.Any("response", redact.Value(errMap)))
 		return nil, errors.New("Can't log in to oauth")
 	}
 	oresp := &OAuthResponse{}
@@ -220,10 +286,14 @@ func (p *Profile) CreateProfile(ctx context.Context) error {
 		go clientTransport.CloseIdleConnections()
 	}()
 	conf := config.Current()
-	requestID := velacontext.GetContextRequestID(ctx)
+	ctx, requestID := velacontext.EnsureRequestID(ctx)
 
-	orgID := conf.Landing[p.Landing].ProgramMap[p.Program].OrganizationID
-	userTypeID := conf.Landing[p.Landing].ProgramMap[p.Program].UserTypeID
+	program, err := conf.Program(p.Landing, p.Program)
+	if err != nil {
+		return err
+	}
+	orgID := program.OrganizationID
+	userTypeID := program.UserTypeID
 
 	p.OrganizationID = &orgID
 	p.UserTypeID = &userTypeID
@@ -233,10 +303,11 @@ func (p *Profile) CreateProfile(ctx context.Context) error {
 	}
 	url := fmt.Sprintf("%s/api/v1/admin/user-profiles", conf.Common.PublicBaseURI)
 	jsonValue, _ := json.Marshal(body)
-	request, _ := http.NewRequest("POST", url, bytes.NewBuffer(jsonValue))
+	request, _ := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonValue))
 	request.Header.Set("Content-Type", "application/json")
 	request.Header.Add("X-Vela-Request-Id", requestID)
-	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.AccessToken))
+	addBaggage(ctx, request)
+	request.Header.Set("Authorization", bearer(ctx, p.AccessToken))
 	response, err := apiClient.Do(request)
 	if err != nil || response == nil {
 		return err
@@ -248,7 +319,7 @@ func (p *Profile) CreateProfile(ctx context.Context) error {
 	}
 	if response.StatusCode != http.StatusOK {
 		logger := velacontext.GetContextLogger(ctx)
-		logger.Info("Create profile error", zap.Any("response", dat))
+		logger.Info("Create profile error", zap.Any("response", redact.Value(dat)))
 		var errResp HttpClientError
 		if err = json.Unmarshal(data, &errResp); err != nil {
 			return err
@@ -279,13 +350,14 @@ func (p *Profile) GetCareRoomID(ctx context.Context) (string, error) {
 		go clientTransport.CloseIdleConnections()
 	}()
 	conf := config.Current()
-	requestID := velacontext.GetContextRequestID(ctx)
+	ctx, requestID := velacontext.EnsureRequestID(ctx)
 
 	url := fmt.Sprintf("%s/api/v1/admin/care-teams/consumer/%s", conf.Common.PublicBaseURI, p.ID)
-	request, _ := http.NewRequest("GET", url, nil)
+	request, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
 	request.Header.Set("Content-Type", "application/json")
 	request.Header.Add("X-Vela-Request-Id", requestID)
-	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.AccessToken))
+	addBaggage(ctx, request)
+	request.Header.Set("Authorization", bearer(ctx, p.AccessToken))
 	response, err := apiClient.Do(request)
 	if err != nil || response == nil {
 		return "", err
@@ -319,7 +391,7 @@ func (p *Profile) AuthorizeCareRoom(ctx context.Context, careTeamID string) erro
 		go clientTransport.CloseIdleConnections()
 	}()
 	conf := config.Current()
-	requestID := velacontext.GetContextRequestID(ctx)
+	ctx, requestID := velacontext.EnsureRequestID(ctx)
 
 	url := fmt.Sprintf("%s/api/v1/admin/care-teams/%s/authorize", conf.Common.PublicBaseURI, careTeamID)
 
@@ -332,10 +404,11 @@ func (p *Profile) AuthorizeCareRoom(ctx context.Context, careTeamID string) erro
 	}
 	jsonValue, _ := json.Marshal(jsonMap)
 
-	request, rerr := http.NewRequest("POST", url, bytes.NewBuffer(jsonValue))
+	request, rerr := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonValue))
 	request.Header.Set("Content-Type", "application/json")
 	request.Header.Add("X-Vela-Request-Id", requestID)
-	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.AccessToken))
+	addBaggage(ctx, request)
+	request.Header.Set("Authorization", bearer(ctx, p.AccessToken))
 	response, err := apiClient.Do(request)
 	if rerr != nil || err != nil || response == nil {
 		return err
@@ -361,7 +434,7 @@ func (p *Profile) AddProfessionals(ctx context.Context, careTeamID string, proID
 		go clientTransport.CloseIdleConnections()
 	}()
 	conf := config.Current()
-	requestID := velacontext.GetContextRequestID(ctx)
+	ctx, requestID := velacontext.EnsureRequestID(ctx)
 
 	url := fmt.Sprintf("%s/api/v1/admin/care-teams/%s/member", conf.Common.PublicBaseURI, careTeamID)
 	newMemberTmpl := `{"member":{"user_id": "%s", "owner_type": "CareManager"}}`
@@ -369,10 +442,11 @@ func (p *Profile) AddProfessionals(ctx context.Context, careTeamID string, proID
 	for _, proID := range proIDs {
 		jsonStr := fmt.Sprintf(newMemberTmpl, proID)
 
-		request, rerr := http.NewRequest("POST", url, bytes.NewBuffer([]byte(jsonStr)))
+		request, rerr := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer([]byte(jsonStr)))
 		request.Header.Set("Content-Type", "application/json")
 		request.Header.Add("X-Vela-Request-Id", requestID)
-		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.AccessToken))
+		addBaggage(ctx, request)
+		request.Header.Set("Authorization", bearer(ctx, p.AccessToken))
 		response, err := apiClient.Do(request)
 		if rerr != nil || err != nil || response == nil {
 			return err
@@ -399,7 +473,7 @@ func (p *Profile) AddCareGiversToCareTeam(ctx context.Context, careTeamID string
 		go clientTransport.CloseIdleConnections()
 	}()
 	conf := config.Current()
-	requestID := velacontext.GetContextRequestID(ctx)
+	ctx, requestID := velacontext.EnsureRequestID(ctx)
 
 	url := fmt.Sprintf("%s/api/v1/admin/care-teams/%s/member", conf.Common.PublicBaseURI, careTeamID)
 	newMemberTmpl := `{"member":{"user_id": "%s", "owner_type": "Caregiver", "rank": %d}}`
@@ -411,13 +485,14 @@ func (p *Profile) AddCareGiversToCareTeam(ctx context.Context, careTeamID string
 		}
 		jsonStr := fmt.Sprintf(newMemberTmpl, cg.ID, rank)
 
-		request, rerr := http.NewRequest("POST", url, bytes.NewBuffer([]byte(jsonStr)))
+		request, rerr := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer([]byte(jsonStr)))
 		if rerr != nil {
 			return rerr
 		}
 		request.Header.Set("Content-Type", "application/json")
 		request.Header.Add("X-Vela-Request-Id", requestID)
-		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.AccessToken))
+		addBaggage(ctx, request)
+		request.Header.Set("Authorization", bearer(ctx, p.AccessToken))
 		response, err := apiClient.Do(request)
 		if rerr != nil || err != nil || response == nil {
 			return err
@@ -447,12 +522,13 @@ func (p *Profile) UserExistsForEmail(ctx context.Context, token string, email st
 		go clientTransport.CloseIdleConnections()
 	}()
 	conf := config.Current()
-	requestID := velacontext.GetContextRequestID(ctx)
+	ctx, requestID := velacontext.EnsureRequestID(ctx)
 	url := fmt.Sprintf("%s/api/v1/admin/user-profiles/by-reference/email/%s", conf.Common.PublicBaseURI, email)
-	request, _ := http.NewRequest("GET", url, nil)
+	request, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
 	request.Header.Set("Content-Type", "application/json")
 	request.Header.Add("X-Vela-Request-Id", requestID)
-	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	addBaggage(ctx, request)
+	request.Header.Set("Authorization", bearer(ctx, token))
 	response, err := apiClient.Do(request)
 	if err != nil || response == nil {
 		return false, err
@@ -491,12 +567,13 @@ func (p *Profile) GetByID(ctx context.Context, token string, ID string) (bool, e
 		go clientTransport.CloseIdleConnections()
 	}()
 	conf := config.Current()
-	requestID := velacontext.GetContextRequestID(ctx)
+	ctx, requestID := velacontext.EnsureRequestID(ctx)
 	url := fmt.Sprintf("%s/api/v1/admin/user-profiles/%s", conf.Common.PublicBaseURI, ID)
-	request, _ := http.NewRequest("GET", url, nil)
+	request, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
 	request.Header.Set("Content-Type", "application/json")
 	request.Header.Add("X-Vela-Request-Id", requestID)
-	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	addBaggage(ctx, request)
+	request.Header.Set("Authorization", bearer(ctx, token))
 	response, err := apiClient.Do(request)
 	if err != nil || response == nil {
 		return false, err
@@ -510,7 +587,7 @@ func (p *Profile) GetByID(ctx context.Context, token string, ID string) (bool, e
 
 	if response.StatusCode != http.StatusOK {
 		logger := velacontext.GetContextLogger(ctx)
-		logger.Info("Get profile error", zap.Any("response", data))
+		logger.Info("Get profile error", zap.Any("response", redact.Value(data)))
 		var errResp HttpClientError
 		if err = json.Unmarshal(data, &errResp); err != nil {
 			return false, err
@@ -531,42 +608,124 @@ func (p *Profile) GetByID(ctx context.Context, token string, ID string) (bool, e
 }
 
 func (p *Profile) PatchProfile(ctx context.Context, token string) error {
-	defer func() {
-		go clientTransport.CloseIdleConnections()
-	}()
-	conf := config.Current()
-	requestID := velacontext.GetContextRequestID(ctx)
-
-	body := map[string]Profile{
-		"user_profile": *p,
-	}
 	if len(p.ID) < 1 {
 		return errors.New("No ID to update")
 	}
 	if len(token) > 0 {
 		p.AccessToken = token
 	}
-	url := fmt.Sprintf("%s/api/v1/admin/user-profiles/%s", conf.Common.PublicBaseURI, p.ID)
+	consumerID, err := patchProfile(ctx, p.AccessToken, p.ID, *p)
+	if err != nil {
+		return err
+	}
+	p.ID = consumerID
+	return nil
+}
+
+// ProfilePatch changes only the fields that are set, and clears the ones
+// that are null, which a Profile can't say, since its nil fields are left
+// out.  Send it with PatchProfileFields.
+type ProfilePatch struct {
+	FirstName            optional.Optional[string]       `json:"first_name,omitzero" validation:"not-null,max-length:255" normalize:"trim,collapse-spaces"`
+	MiddleName           optional.Optional[string]       `json:"middle_name,omitzero" validation:"max-length:255" normalize:"trim,collapse-spaces"`
+	LastName             optional.Optional[string]       `json:"last_name,omitzero" validation:"not-null,max-length:255" normalize:"trim,collapse-spaces"`
+	Email                optional.Optional[string]       `json:"email,omitzero" validation:"email,max-length:255" normalize:"trim,lower"`
+	SecondEmail          optional.Optional[string]       `json:"second_email,omitzero" validation:"email,max-length:255" normalize:"trim,lower"`
+	AddressLine1         optional.Optional[string]       `json:"address1,omitzero" validation:"max-length:255"`
+	AddressLine2         optional.Optional[string]       `json:"address2,omitzero" validation:"max-length:255"`
+	City                 optional.Optional[string]       `json:"city,omitzero" validation:"max-length:255"`
+	State                optional.Optional[string]       `json:"state,omitzero" validation:"max-length:255"`
+	ZipCode              optional.Optional[string]       `json:"zip_code,omitzero" validation:"max-length:255"`
+	Country              optional.Optional[string]       `json:"country,omitzero" validation:"max-length:255"`
+	PrimaryPhoneNumber   optional.Optional[string]       `json:"primary_phone_number,omitzero"`
+	PrimaryPhoneType     optional.Optional[string]       `json:"primary_phone_type,omitzero" validation:"values-insensitive:mobile|home|work|tablet|other"`
+	SecondaryPhoneNumber optional.Optional[string]       `json:"secondary_phone_number,omitzero"`
+	SecondaryPhoneType   optional.Optional[string]       `json:"secondary_phone_type,omitzero" validation:"values-insensitive:mobile|home|work|tablet|other"`
+	Locale               optional.Optional[string]       `json:"locale,omitzero" validation:"max-length:255"`
+	TimeZone             optional.Optional[string]       `json:"time_zone,omitzero" validation:"timezone"`
+	Gender               optional.Optional[GenderOption] `json:"gender,omitzero" validation:"values:Female|Male|Transgender|Unspecified"`
+	Birthday             optional.Optional[time.Time]    `json:"birthday,omitzero" validation:"before:now"`
+}
+
+// Validate normalizes and checks the patch.  The names can't be cleared, so
+// they're not-null: they can be left out, but not sent empty or null.
+func (pp *ProfilePatch) Validate() error {
+	var validationError = ErrorMap{}
+	_ = validation.NormalizeStruct(pp)
+	_ = validation.ValidateStruct(*pp, validationError)
+	if len(validationError) > 0 {
+		return validationError
+	}
+	return nil
+}
+
+// Apply makes the patch's changes to a profile, the way the API will.
+func (pp *ProfilePatch) Apply(p *Profile) {
+	pp.FirstName.ApplyTo(&p.FirstName)
+	pp.MiddleName.ApplyTo(&p.MiddleName)
+	pp.LastName.ApplyTo(&p.LastName)
+	pp.Email.ApplyTo(&p.Email)
+	pp.SecondEmail.ApplyTo(&p.SecondEmail)
+	pp.AddressLine1.ApplyTo(&p.AddressLine1)
+	pp.AddressLine2.ApplyTo(&p.AddressLine2)
+	pp.City.ApplyTo(&p.City)
+	pp.State.ApplyTo(&p.State)
+	pp.ZipCode.ApplyTo(&p.ZipCode)
+	pp.Country.ApplyTo(&p.Country)
+	pp.PrimaryPhoneNumber.ApplyTo(&p.PrimaryPhoneNumber)
+	pp.PrimaryPhoneType.ApplyTo(&p.PrimaryPhoneType)
+	pp.SecondaryPhoneNumber.ApplyTo(&p.SecondaryPhoneNumber)
+	pp.SecondaryPhoneType.ApplyTo(&p.SecondaryPhoneType)
+	pp.Locale.ApplyTo(&p.Locale)
+	pp.TimeZone.ApplyTo(&p.TimeZone)
+	pp.Gender.ApplyTo(&p.Gender)
+	pp.Birthday.ApplyTo(&p.Birthday)
+}
+
+// PatchProfileFields sends the patch for the profile with the ID.
+func PatchProfileFields(ctx context.Context, token, id string, patch ProfilePatch) error {
+	if len(id) < 1 {
+		return errors.New("No ID to update")
+	}
+	_, err := patchProfile(ctx, token, id, patch)
+	return err
+}
+
+// patchProfile sends the body as the user_profile, and returns the ID from
+// the response.
+func patchProfile(ctx context.Context, token, id string, profile interface{}) (string, error) {
+	defer func() {
+		go clientTransport.CloseIdleConnections()
+	}()
+	conf := config.Current()
+	ctx, requestID := velacontext.EnsureRequestID(ctx)
+
+	body := map[string]interface{}{
+		"user_profile": profile,
+	}
+	url := fmt.Sprintf("%s/api/v1/admin/user-profiles/%s", conf.Common.PublicBaseURI, id)
 	jsonValue, _ := json.Marshal(body)
-	request, _ := http.NewRequest("PATCH", url, bytes.NewBuffer(jsonValue))
+	request, _ := http.NewRequestWithContext(ctx, "PATCH", url, bytes.NewBuffer(jsonValue))
 	request.Header.Set("Content-Type", "application/json")
 	request.Header.Add("X-Vela-Request-Id", requestID)
-	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.AccessToken))
+	addBaggage(ctx, request)
+	request.Header.Set("Authorization", bearer(ctx, token))
 	response, err := apiClient.Do(request)
 	if err != nil || response == nil {
-		return err
+		return "", err
 	}
+	defer response.Body.Close()
 	var dat map[string]interface{}
 	data, _ := ioutil.ReadAll(response.Body)
 	if err = json.Unmarshal(data, &dat); err != nil {
-		return err
+		return "", err
 	}
 	if response.StatusCode != http.StatusOK {
 		logger := velacontext.GetContextLogger(ctx)
-		logger.Info("Patch profile error", zap.Any("response", dat))
+		logger.Info("Patch profile error", zap.Any("response", redact.Value(dat)))
 		var errResp HttpClientError
 		if err = json.Unmarshal(data, &errResp); err != nil {
-			return err
+			return "", err
 		}
 		if errResp.Fields != nil && len(errResp.Fields) > 0 {
 			errMap := ErrorMap{}
@@ -574,18 +733,17 @@ func (p *Profile) PatchProfile(ctx context.Context, token string) error {
 				fn := strings.Split(f.Name, ":")
 				errMap.AppendErrorField(fn[len(fn)-1], f.Message)
 			}
-			return errMap
+			return "", errMap
 		}
 		errResp.Path = url
-		return errResp
+		return "", errResp
 	}
 	inner, _ := dat["user_profile"].(map[string]interface{})
 	consumerID, cidok := inner["id"].(string)
 	if !cidok || len(consumerID) == 0 {
-		return errors.New("Failed to aquire consumer ID")
+		return "", errors.New("Failed to aquire consumer ID")
 	}
-	p.ID = consumerID
-	return nil
+	return consumerID, nil
 }
 
 type EventQueue struct {
@@ -645,12 +803,13 @@ func GetQueue(ctx context.Context, token string) (*EventQueue, error) {
 		go clientTransport.CloseIdleConnections()
 	}()
 	conf := config.Current()
-	requestID := velacontext.GetContextRequestID(ctx)
+	ctx, requestID := velacontext.EnsureRequestID(ctx)
 	url := fmt.Sprintf("%s/api/v1/events/queue", conf.Common.PublicBaseURI)
-	request, _ := http.NewRequest("GET", url, nil)
+	request, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
 	request.Header.Set("Content-Type", "application/json")
 	request.Header.Add("X-Vela-Request-Id", requestID)
-	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	addBaggage(ctx, request)
+	request.Header.Set("Authorization", bearer(ctx, token))
 	response, err := apiClient.Do(request)
 	if err != nil || response == nil {
 		return nil, err
@@ -660,7 +819,7 @@ func GetQueue(ctx context.Context, token string) (*EventQueue, error) {
 
 	if response.StatusCode != http.StatusOK {
 		logger := velacontext.GetContextLogger(ctx)
-		logger.Info("Get queue error", zap.Any("response", data))
+		logger.Info("Get queue error", zap.Any("response", redact.Value(data)))
 		var errResp HttpClientError
 		if err = json.Unmarshal(data, &errResp); err != nil {
 			return nil, err
@@ -684,7 +843,7 @@ func GetEventsForQueue(ctx context.Context, token string, maxRecords *int64, slu
 		go clientTransport.CloseIdleConnections()
 	}()
 	conf := config.Current()
-	requestID := velacontext.GetContextRequestID(ctx)
+	ctx, requestID := velacontext.EnsureRequestID(ctx)
 	url := fmt.Sprintf("%s/api/v1/events/queue/events", conf.Common.PublicBaseURI)
 	foundMax := false
 	if maxRecords != nil {
@@ -700,10 +859,11 @@ func GetEventsForQueue(ctx context.Context, token string, maxRecords *int64, slu
 		}
 		url = fmt.Sprintf("%s%s%s", url, separator, slugParam)
 	}
-	request, _ := http.NewRequest("GET", url, nil)
+	request, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
 	request.Header.Set("Content-Type", "application/json")
 	request.Header.Add("X-Vela-Request-Id", requestID)
-	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	addBaggage(ctx, request)
+	request.Header.Set("Authorization", bearer(ctx, token))
 	response, err := apiClient.Do(request)
 	if err != nil || response == nil {
 		return nil, 0, err
@@ -713,7 +873,7 @@ func GetEventsForQueue(ctx context.Context, token string, maxRecords *int64, slu
 
 	if response.StatusCode != http.StatusOK {
 		logger := velacontext.GetContextLogger(ctx)
-		logger.Info("GetEvents error", zap.Any("response", data))
+		logger.Info("GetEvents error", zap.Any("response", redact.Value(data)))
 		var errResp HttpClientError
 		if err = json.Unmarshal(data, &errResp); err != nil {
 			return nil, 0, err
@@ -728,6 +888,7 @@ func GetEventsForQueue(ctx context.Context, token string, maxRecords *int64, slu
 		return nil, 0, err
 	}
 
+	metrics.Default().Counter(eventsReceivedMetric).Add(float64(len(er.Events)), nil)
 	return er.Events, er.LastReadIndex, nil
 
 }
@@ -739,17 +900,18 @@ func SetWatermarkForQueue(ctx context.Context, token string, watermark int64) er
 		go clientTransport.CloseIdleConnections()
 	}()
 	conf := config.Current()
-	requestID := velacontext.GetContextRequestID(ctx)
+	ctx, requestID := velacontext.EnsureRequestID(ctx)
 	url := fmt.Sprintf("%s/api/v1/events/queue/watermark", conf.Common.PublicBaseURI)
 	w := Watermark{
 		LastReadIndex: watermark,
 	}
 
 	jsonValue, _ := json.Marshal(w)
-	request, _ := http.NewRequest("PUT", url, bytes.NewBuffer(jsonValue))
+	request, _ := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewBuffer(jsonValue))
 	request.Header.Set("Content-Type", "application/json")
 	request.Header.Add("X-Vela-Request-Id", requestID)
-	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	addBaggage(ctx, request)
+	request.Header.Set("Authorization", bearer(ctx, token))
 	response, err := apiClient.Do(request)
 	if err != nil || response == nil {
 		return err
@@ -761,7 +923,7 @@ func SetWatermarkForQueue(ctx context.Context, token string, watermark int64) er
 	}
 	if response.StatusCode != http.StatusOK {
 		logger := velacontext.GetContextLogger(ctx)
-		logger.Info("Setting Watermark error", zap.Any("response", dat))
+		logger.Info("Setting Watermark error", zap.Any("response", redact.Value(dat)))
 		var errResp HttpClientError
 		if err = json.Unmarshal(data, &errResp); err != nil {
 			return err
@@ -777,5 +939,6 @@ func SetWatermarkForQueue(ctx context.Context, token string, watermark int64) er
 		errResp.Path = url
 		return errResp
 	}
+	metrics.Default().Gauge(watermarkMetric).Set(float64(watermark), nil)
 	return nil
 }
@@ -1,9 +1,20 @@
 package client
 
 import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/seniorlink-vela/cs-common/config"
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/optional"
 )
 
 func TestOAuthRequestToParams(t *testing.T) {
@@ -18,3 +29,139 @@ func TestOAuthRequestToParams(t *testing.T) {
 	assert.Equal(t, o.Password, p.Get("password"))
 	assert.Equal(t, o.ClientID, p.Get("client_id"))
 }
+
+func TestBearer(t *testing.T) {
+	ctx := velacontext.ContextWithToken(context.Background(), "from-context")
+	assert.Equal(t, "Bearer passed", bearer(ctx, "passed"))
+	assert.Equal(t, "Bearer from-context", bearer(ctx, ""))
+}
+
+func TestAddBaggage(t *testing.T) {
+	ctx := velacontext.SetBaggage(context.Background(), "variant", "b")
+	request, _ := http.NewRequest("GET", "https://example.com", nil)
+	addBaggage(ctx, request)
+	assert.Equal(t, "b", request.Header.Get("X-Vela-Baggage-Variant"))
+}
+
+func TestProfileContextWithLocalization(t *testing.T) {
+	locale, zone := "es-US", "America/Denver"
+	p := Profile{Locale: &locale, TimeZone: &zone}
+	ctx := p.ContextWithLocalization(context.Background())
+	assert.Equal(t, "es-US", velacontext.GetContextLocale(ctx))
+	assert.Equal(t, "America/Denver", velacontext.GetContextLocation(ctx).String())
+
+	ctx = (&Profile{}).ContextWithLocalization(context.Background())
+	_, ok := velacontext.LookupLocale(ctx)
+	assert.False(t, ok)
+}
+
+func TestProfileAge(t *testing.T) {
+	zone := "Pacific/Honolulu"
+	birthday := time.Date(1950, time.June, 2, 0, 0, 0, 0, time.UTC)
+	p := Profile{Birthday: &birthday, TimeZone: &zone}
+	// Already the 2nd in UTC, but still the 1st in Honolulu
+	age, ok := p.Age(time.Date(2024, time.June, 2, 5, 0, 0, 0, time.UTC))
+	assert.True(t, ok)
+	assert.Equal(t, 73, age)
+
+	_, ok = (&Profile{}).Age(time.Now())
+	assert.False(t, ok)
+}
+
+func TestProfileValidatePatch(t *testing.T) {
+	email := "bad-email"
+	p := Profile{Email: &email}
+	err := p.ValidatePatch()
+
+	em, ok := err.(ErrorMap)
+	assert.True(t, ok)
+	assert.Len(t, em, 1)
+	assert.Contains(t, em, "email")
+
+	email = "jlebowski@example.com"
+	assert.NoError(t, p.ValidatePatch())
+}
+
+func TestProfileValidatePatchNormalizes(t *testing.T) {
+	email := "  JLebowski@Example.com "
+	name := " Jeffrey  "
+	p := Profile{Email: &email, FirstName: &name}
+
+	assert.NoError(t, p.ValidatePatch())
+	assert.Equal(t, "jlebowski@example.com", *p.Email)
+	assert.Equal(t, "Jeffrey", *p.FirstName)
+}
+
+func TestProfilePatch(t *testing.T) {
+	var patch ProfilePatch
+	require.NoError(t, json.Unmarshal([]byte(`{"middle_name": null, "email": " Walter@Example.com", "time_zone": "America/Chicago"}`), &patch))
+	assert.NoError(t, patch.Validate())
+
+	middle, email := "Lee", "walter@old.example.com"
+	p := Profile{MiddleName: &middle, Email: &email}
+	patch.Apply(&p)
+	assert.Nil(t, p.MiddleName)
+	assert.Equal(t, "walter@example.com", *p.Email)
+	assert.Equal(t, "America/Chicago", *p.TimeZone)
+	assert.Nil(t, p.FirstName)
+
+	// The names can be left out, but not cleared
+	patch = ProfilePatch{FirstName: optional.Null[string](), Email: optional.Of("walter")}
+	em, ok := patch.Validate().(ErrorMap)
+	require.True(t, ok)
+	assert.Contains(t, em, "first_name")
+	assert.Contains(t, em, "email")
+	assert.NotContains(t, em, "last_name")
+
+	patch = ProfilePatch{Gender: optional.Of(GenderUnspecified)}
+	assert.NoError(t, patch.Validate())
+}
+
+func TestPatchProfileFields(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		assert.Equal(t, "/api/v1/admin/user-profiles/p-1", r.URL.Path)
+		assert.Equal(t, "Bearer abides", r.Header.Get("Authorization"))
+		w.Write([]byte(`{"user_profile": {"id": "p-1"}}`))
+	}))
+	defer server.Close()
+	config.Set(&config.Config{Common: config.CommonConfig{PublicBaseURI: server.URL}})
+	defer config.Reset()
+	Init(1, time.Second, time.Second)
+
+	patch := ProfilePatch{MiddleName: optional.Null[string](), City: optional.Of("Los Angeles")}
+	require.NoError(t, PatchProfileFields(context.Background(), "abides", "p-1", patch))
+	assert.JSONEq(t, `{"user_profile": {"middle_name": null, "city": "Los Angeles"}}`, body)
+}
+
+func TestProfileValidate(t *testing.T) {
+	config.Set(&config.Config{
+		Landing: map[string]*config.LandingConfig{
+			"test-sample": {
+				ProgramMap: map[string]config.Program{
+					"test-program": {OrganizationName: "test-org"},
+				},
+			},
+		},
+	})
+	defer config.Reset()
+
+	first, last, username, email := "Jeffrey", "Lebowski", "dude", "jlebowski@example.com"
+	p := Profile{
+		FirstName: &first,
+		LastName:  &last,
+		Username:  &username,
+		Email:     &email,
+		Landing:   "test-sample",
+		Program:   "test-program",
+	}
+	assert.NoError(t, p.Validate())
+
+	p.Program = "other-program"
+	err := p.Validate()
+	em, ok := err.(ErrorMap)
+	assert.True(t, ok)
+	assert.Equal(t, ErrorMap{"program": "Invalid program passed"}, em)
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/seniorlink-vela/cs-common/retry"
+)
+
+// Stands in for a response that's worth retrying, inside retry.Do.
+var errRetryableStatus = errors.New("client: retryable status")
+
+// retryTransport retries idempotent requests that fail on the network, or
+// get a 429, 502, 503 or 504.  When the attempts run out, the last response
+// is returned as it is.  It's the only retrying the client does, so
+// callers shouldn't retry its requests as well.
+type retryTransport struct {
+	next   http.RoundTripper
+	policy retry.Policy
+}
+
+func (t retryTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if !idempotent(request.Method) || (request.Body != nil && request.Body != http.NoBody && request.GetBody == nil) {
+		return t.next.RoundTrip(request)
+	}
+	policy := t.policy
+	policy.Retryable = func(err error) bool {
+		return errors.Is(err, errRetryableStatus) || retry.Network(err)
+	}
+
+	var last *http.Response
+	attempts := 0
+	err := retry.Do(request.Context(), policy, func(ctx context.Context) error {
+		discard(last)
+		last = nil
+		attempt := request
+		if attempts++; attempts > 1 && request.GetBody != nil {
+			body, err := request.GetBody()
+			if err != nil {
+				return retry.Permanent(err)
+			}
+			attempt = request.Clone(ctx)
+			attempt.Body = body
+		}
+		response, err := t.next.RoundTrip(attempt)
+		if err != nil {
+			return err
+		}
+		last = response
+		if retry.RetryableStatus(response.StatusCode) {
+			return errRetryableStatus
+		}
+		return nil
+	})
+	if err == nil || errors.Is(err, errRetryableStatus) {
+		return last, nil
+	}
+	discard(last)
+	return nil, err
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// Reads what's left of a response that won't be used, so its connection can
+// be reused.
+func discard(response *http.Response) {
+	if response != nil {
+		_, _ = io.Copy(io.Discard, response.Body)
+		response.Body.Close()
+	}
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/seniorlink-vela/cs-common/retry"
+)
+
+func TestRetryTransport(t *testing.T) {
+	var calls int
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: retryTransport{next: http.DefaultTransport, policy: retry.Policy{InitialDelay: time.Millisecond}}}
+
+	request, _ := http.NewRequest(http.MethodPut, server.URL, bytes.NewBufferString(`{"last_read_index":7}`))
+	response, err := client.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []string{`{"last_read_index":7}`, `{"last_read_index":7}`, `{"last_read_index":7}`}, bodies, "the body is sent again")
+
+	// POSTs aren't retried
+	calls = 0
+	response, err = client.Post(server.URL, "application/json", bytes.NewBufferString(`{}`))
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryTransportLastResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("still down"))
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: retryTransport{next: http.DefaultTransport, policy: retry.Policy{InitialDelay: time.Millisecond}}}
+
+	response, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusBadGateway, response.StatusCode)
+	body, _ := io.ReadAll(response.Body)
+	assert.Equal(t, "still down", string(body))
+}
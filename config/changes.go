@@ -0,0 +1,190 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Change is a single setting that differs between two configs.
+type Change struct {
+	// Path names the setting the same way as the parameter store, e.g.
+	// `common/public_base_uri` or `landing/foo/programs/bar/organization_id`.
+	Path string
+	// Old and New are the values before and after, nil when the setting was
+	// added or removed.  Secrets are masked, the same as Sanitized.
+	Old interface{}
+	New interface{}
+}
+
+// ChangesFunc is called with every setting that changed in a reload.
+type ChangesFunc func(old, new *Config, changes []Change)
+
+var (
+	changeSubscribers     = map[int]ChangesFunc{}
+	changeSubscribersNext int
+	changeSubscribersLock sync.RWMutex
+)
+
+// OnChange subscribes to config reloads, from the parameter store or a file,
+// with the settings that changed, so services can react to the ones they care
+// about, e.g. rebuilding an HTTP client when the public base URI changes.
+// Subscribers are only called when something changed.  The returned function
+// unsubscribes.
+func OnChange(fn ChangesFunc) func() {
+	changeSubscribersLock.Lock()
+	defer changeSubscribersLock.Unlock()
+	id := changeSubscribersNext
+	changeSubscribersNext++
+	changeSubscribers[id] = fn
+	return func() {
+		changeSubscribersLock.Lock()
+		defer changeSubscribersLock.Unlock()
+		delete(changeSubscribers, id)
+	}
+}
+
+// HasChange returns whether any change is at, or under, the path, e.g.
+// `HasChange(changes, "landing/foo")` is true for any change to that landing.
+func HasChange(changes []Change, path string) bool {
+	for _, c := range changes {
+		if c.Path == path || strings.HasPrefix(c.Path, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Lets the subscribers know what changed between two configs.
+func notifySubscribers(old, new *Config) {
+	changeSubscribersLock.RLock()
+	ids := make([]int, 0, len(changeSubscribers))
+	for id := range changeSubscribers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	subscribers := make([]ChangesFunc, 0, len(ids))
+	for _, id := range ids {
+		subscribers = append(subscribers, changeSubscribers[id])
+	}
+	changeSubscribersLock.RUnlock()
+	if len(subscribers) == 0 {
+		return
+	}
+
+	changes := Diff(old, new)
+	if len(changes) == 0 {
+		return
+	}
+	for _, fn := range subscribers {
+		fn(old, new, changes)
+	}
+}
+
+// Diff lists the settings that differ between two configs, including in
+// registered sections, sorted by path.
+func Diff(old, new *Config) []Change {
+	if old == nil {
+		old = &Config{}
+	}
+	if new == nil {
+		new = &Config{}
+	}
+	changes := []Change{}
+	diffValues(reflect.ValueOf(*old), reflect.ValueOf(*new), nil, false, &changes)
+
+	names := map[string]bool{}
+	for name := range old.sections {
+		names[name] = true
+	}
+	for name := range new.sections {
+		names[name] = true
+	}
+	for name := range names {
+		diffValues(reflect.ValueOf(old.sections[name]), reflect.ValueOf(new.sections[name]), []string{name}, false, &changes)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+func diffValues(old, new reflect.Value, path []string, secret bool, changes *[]Change) {
+	if !old.IsValid() || !new.IsValid() {
+		if old.IsValid() || new.IsValid() {
+			*changes = append(*changes, Change{Path: strings.Join(path, "/"), Old: changeValue(old, secret), New: changeValue(new, secret)})
+		}
+		return
+	}
+	switch old.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if old.IsNil() || new.IsNil() {
+			if old.IsNil() != new.IsNil() {
+				*changes = append(*changes, Change{Path: strings.Join(path, "/"), Old: changeValue(old, secret), New: changeValue(new, secret)})
+			}
+			return
+		}
+		diffValues(old.Elem(), new.Elem(), path, secret, changes)
+		return
+	case reflect.Struct:
+		if old.Type() != new.Type() {
+			break
+		}
+		for i := 0; i < old.NumField(); i++ {
+			f := old.Type().Field(i)
+			name := diffFieldName(f)
+			if name == "" {
+				continue
+			}
+			diffValues(old.Field(i), new.Field(i), append(path[:len(path):len(path)], name), secret || isSensitiveField(f), changes)
+		}
+		return
+	case reflect.Map:
+		if old.Type() != new.Type() || old.Type().Key().Kind() != reflect.String {
+			break
+		}
+		keys := map[string]bool{}
+		for _, k := range old.MapKeys() {
+			keys[k.String()] = true
+		}
+		for _, k := range new.MapKeys() {
+			keys[k.String()] = true
+		}
+		for k := range keys {
+			kv := reflect.ValueOf(k).Convert(old.Type().Key())
+			diffValues(old.MapIndex(kv), new.MapIndex(kv), append(path[:len(path):len(path)], k), secret, changes)
+		}
+		return
+	}
+	if !reflect.DeepEqual(old.Interface(), new.Interface()) {
+		*changes = append(*changes, Change{Path: strings.Join(path, "/"), Old: changeValue(old, secret), New: changeValue(new, secret)})
+	}
+}
+
+// Names fields by their JSON name, falling back to the parameter name.  Fields
+// that only hold the raw form of another, like the programs JSON, are skipped.
+func diffFieldName(f reflect.StructField) string {
+	if f.PkgPath != "" {
+		return ""
+	}
+	if tag := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]; tag == "-" {
+		return ""
+	} else if tag != "" {
+		return tag
+	}
+	if tag := strings.SplitN(f.Tag.Get("mapstructure"), ",", 2)[0]; tag != "" && tag != "-" {
+		return tag
+	}
+	return f.Name
+}
+
+func changeValue(v reflect.Value, secret bool) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return nil
+	}
+	return sanitizeValue(v, secret).Interface()
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	old, err := configFromParams(map[string]string{
+		"common/public_base_uri":        "https://old.local",
+		"landing/test-sample/client_id": "oauth.client.id",
+		"landing/test-sample/password":  "therug",
+		"landing/test-sample/programs":  `[{"organization_name": "test-org", "organization_id": 987}]`,
+		"landing/removed/client_id":     "removed.client.id",
+	})
+	require.NoError(t, err)
+	new, err := configFromParams(map[string]string{
+		"common/public_base_uri":        "https://new.local",
+		"landing/test-sample/client_id": "oauth.client.id",
+		"landing/test-sample/password":  "thenewrug",
+		"landing/test-sample/programs":  `[{"organization_name": "test-org", "organization_id": 988}]`,
+		"landing/added/password":        "secret",
+	})
+	require.NoError(t, err)
+
+	changes := Diff(old, new)
+	require.Len(t, changes, 5)
+	assert.Equal(t, Change{Path: "common/public_base_uri", Old: "https://old.local", New: "https://new.local"}, changes[0])
+	assert.Equal(t, "landing/added", changes[1].Path)
+	assert.Nil(t, changes[1].Old)
+	assert.Equal(t, "********", changes[1].New.(*LandingConfig).Password)
+	assert.Equal(t, "landing/removed", changes[2].Path)
+	assert.Nil(t, changes[2].New)
+	assert.Equal(t, Change{Path: "landing/test-sample/password", Old: "********", New: "********"}, changes[3])
+	assert.Equal(t, Change{Path: "landing/test-sample/programs/test-org/organization_id", Old: 987, New: 988}, changes[4])
+
+	assert.True(t, HasChange(changes, "landing/test-sample"))
+	assert.True(t, HasChange(changes, "common/public_base_uri"))
+	assert.False(t, HasChange(changes, "landing/test"))
+	assert.False(t, HasChange(changes, "common/redirects"))
+
+	assert.Empty(t, Diff(old, old))
+	assert.Len(t, Diff(nil, old), 4)
+}
+
+func TestDiffSections(t *testing.T) {
+	registerTestSection(t, "scheduler", &schedulerSettings{})
+	old, err := configFromParams(map[string]string{"scheduler/batch_size": "25"})
+	require.NoError(t, err)
+	new, err := configFromParams(map[string]string{"scheduler/batch_size": "50", "scheduler/enabled": "true"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []Change{
+		{Path: "scheduler/batch_size", Old: 25, New: 50},
+		{Path: "scheduler/enabled", Old: false, New: true},
+	}, Diff(old, new))
+}
+
+func TestOnChange(t *testing.T) {
+	fake := useFakeSSM(t, testParams())
+	LoadConfigFromParamStore("us-east-1", "/cs-common/", configTestLogger())
+	defer Reset()
+
+	var received [][]Change
+	unsubscribe := OnChange(func(old, new *Config, changes []Change) {
+		received = append(received, changes)
+	})
+
+	fake.set("common/public_base_uri", "https://new.local")
+	params, _, err := readParams(t.Context(), fake, "/cs-common/", ParamStoreOptions{})
+	require.NoError(t, err)
+	require.NoError(t, applyParams(params, nil))
+	require.Len(t, received, 1)
+	assert.Equal(t, []Change{{Path: "common/public_base_uri", Old: "https://app.dev.alwaysreach.net/public", New: "https://new.local"}}, received[0])
+
+	unsubscribe()
+	fake.set("common/public_base_uri", "https://newer.local")
+	params, _, err = readParams(t.Context(), fake, "/cs-common/", ParamStoreOptions{})
+	require.NoError(t, err)
+	require.NoError(t, applyParams(params, nil))
+	assert.Len(t, received, 1)
+}
@@ -1,74 +1,139 @@
 package config
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/awserr"
-	awssession "github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/ssm"
-	"github.com/mitchellh/mapstructure"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/smithy-go"
 	"go.uber.org/zap"
 )
 
-var config *Config
+// Reloads swap the config while requests are reading it, so it's only
+// accessed atomically.
+var config atomic.Pointer[Config]
 
+// The parameters behind the current config, so reloads can tell what changed.
+var loadedParams atomic.Pointer[map[string]string]
+
+// Creates the SSM client used to read parameters, replaced in tests.
+var newSSMClient = defaultSSMClient
+
+// Clients by region, so each load doesn't load the AWS config again.
+var ssmClients sync.Map
+
+func defaultSSMClient(ctx context.Context, region string) (SSMAPI, error) {
+	if svc, ok := ssmClients.Load(region); ok {
+		return svc.(SSMAPI), nil
+	}
+	// Throttling is retried by readParams, with its own backoff
+	cfg, err := awsconfig.LoadDefaultConfig(ctx,
+		awsconfig.WithRegion(region),
+		awsconfig.WithRetryer(func() aws.Retryer { return aws.NopRetryer{} }),
+	)
+	if err != nil {
+		return nil, err
+	}
+	svc, _ := ssmClients.LoadOrStore(region, ssm.NewFromConfig(cfg))
+	return svc.(SSMAPI), nil
+}
+
+// Current returns a snapshot of the config.  Reloads replace the config
+// rather than modifying it, so call this once per request and use the
+// returned value throughout, to get a consistent view.
 func Current() *Config {
-	return config
+	return config.Load()
+}
+
+// Set replaces the current config, mostly for tests that need a known config
+// without loading one.
+func Set(c *Config) {
+	storeConfig(c)
+}
+
+// Reset clears the current config, and anything remembered from loading it.
+func Reset() {
+	config.Store(nil)
+	loadedParams.Store(nil)
+	checkedAt.Store(0)
+}
+
+// When the config was last loaded, or a reload found it unchanged, in Unix
+// nanoseconds.
+var checkedAt atomic.Int64
+
+func storeConfig(c *Config) {
+	config.Store(c)
+	markChecked()
+}
+
+func markChecked() {
+	checkedAt.Store(time.Now().UnixNano())
+}
+
+// LastChecked returns when the config was last loaded, or a reload found it
+// unchanged, so health checks can tell when reloads have been failing.  It's
+// zero before the config's been loaded.
+func LastChecked() time.Time {
+	if ns := checkedAt.Load(); ns != 0 {
+		return time.Unix(0, ns)
+	}
+	return time.Time{}
 }
 
 type Program struct {
-	OrganizationName    string   `json:"organization_name"`
-	OrganizationID      int      `json:"organization_id"`
-	UserTypeID          int      `json:"user_type_id"`
-	CaregiverUserTypeID int      `json:"caregiver_user_type_id"`
-	ProIDs              []string `json:"pro_ids"`
+	OrganizationName    string   `mapstructure:"organization_name" json:"organization_name"`
+	OrganizationID      int      `mapstructure:"organization_id" json:"organization_id"`
+	UserTypeID          int      `mapstructure:"user_type_id" json:"user_type_id"`
+	CaregiverUserTypeID int      `mapstructure:"caregiver_user_type_id" json:"caregiver_user_type_id"`
+	ProIDs              []string `mapstructure:"pro_ids" json:"pro_ids"`
 }
 
 type LandingConfig struct {
 	ClientID    string             `mapstructure:"client_id" json:"client_id"`
 	Username    string             `mapstructure:"username" json:"username"`
-	Password    string             `mapstructure:"password" json:"password"`
+	Password    string             `mapstructure:"password" json:"password" sensitive:"true"`
 	ProgramsRaw string             `mapstructure:"programs" json:"-"`
-	ProgramMap  map[string]Program `json:"programs"`
+	ProgramMap  map[string]Program `mapstructure:"-" json:"programs"`
 }
 
 type CommonConfig struct {
 	PublicBaseURI string            `mapstructure:"public_base_uri" json:"public_base_uri"`
-	Redirects     map[string]string `mapstructure:"redirects"`
+	Redirects     map[string]string `mapstructure:"redirects" json:"redirects"`
 }
 
 type Config struct {
+	// Version is the layout the config was migrated to, CurrentConfigVersion
+	// once loaded.
+	Version int                       `mapstructure:"config_version" json:"config_version"`
 	Common  CommonConfig              `mapstructure:"common" json:"common"`
 	Landing map[string]*LandingConfig `mapstructure:"landing" json:"landing"`
+
+	// Registered sections, by name
+	sections map[string]interface{}
+	// Problems found while loading, such as deprecated keys
+	warnings []string
 }
 
 func LoadConfigFromParamStore(region, path string, logger *zap.Logger) {
-	session, _ := awssession.NewSession(&aws.Config{Region: aws.String(region)})
-	svc := ssm.New(session)
-
-	in := &ssm.GetParametersByPathInput{}
-	in.SetPath(path)
-	in.SetWithDecryption(true)
-	in.SetRecursive(true)
-
-	config = &Config{}
-
-	pm := make(map[string]string)
-	err := svc.GetParametersByPathPages(in, func(params *ssm.GetParametersByPathOutput, lastPage bool) bool {
-		for _, p := range params.Parameters {
-			pm[strings.TrimPrefix(*p.Name, path)] = *p.Value
-		}
-		return !lastPage
-	})
+	warnings, err := LoadConfigFromParamStoreContext(context.Background(), path, ParamStoreOptions{Region: region})
 	if err != nil {
-		if awsErr, ok := err.(awserr.Error); ok {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) {
 			logger.Fatal(
 				"AWS error",
-				zap.String("code", awsErr.Code()),
-				zap.String("message", awsErr.Message()),
+				zap.String("code", apiErr.ErrorCode()),
+				zap.String("message", apiErr.ErrorMessage()),
 			)
 		} else {
 			logger.Fatal(
@@ -77,59 +142,109 @@ func LoadConfigFromParamStore(region, path string, logger *zap.Logger) {
 			)
 		}
 		return
-	} else {
-		cm := map[string]map[string]interface{}{}
-		for k, v := range pm {
-			ks := strings.Split(k, "/")
-			if _, ok := cm[ks[0]]; !ok {
-				cm[ks[0]] = map[string]interface{}{}
-			}
-			m := cm[ks[0]]
-
-			var i int
-			for i = 1; i < len(ks)-1; i++ {
-				if _, ok := m[ks[i]]; !ok {
-					m[ks[i]] = map[string]interface{}{}
-				}
-				m = m[ks[i]].(map[string]interface{})
+	}
+	for _, w := range warnings {
+		logger.Warn("Config partially loaded", zap.String("warning", w))
+	}
+}
+
+// Builds the config from flat parameter names, e.g. `landing/foo/client_id`.
+// Values can also be JSON, or comma separated lists from StringList
+// parameters, where the config expects an object or list.
+func configFromParams(pm map[string]string) (*Config, error) {
+	c := &Config{}
+	tree, err := treeFromParams(pm)
+	if err != nil {
+		return nil, err
+	}
+	if c.warnings, err = migrateTree(tree); err != nil {
+		return nil, err
+	}
+	expandJSONValues(tree, reflect.TypeOf(Config{}))
+	programs := extractPrograms(tree)
+	if err := decode(tree, c); err != nil {
+		return nil, err
+	}
+	if err := decodeSections(c, tree); err != nil {
+		return nil, err
+	}
+	for name, p := range programs {
+		if l := c.Landing[name]; l != nil {
+			if err := decodePrograms(p, l); err != nil {
+				return nil, fmt.Errorf("landing %s programs: %w", name, err)
 			}
-			m[ks[i]] = v
 		}
-		mapstructure.Decode(cm, config)
-		for _, l := range config.Landing {
-
-			if l.ProgramsRaw != "" {
-				l.ProgramMap = map[string]Program{}
-				programs := []Program{}
-				err := json.Unmarshal([]byte(l.ProgramsRaw), &programs)
-				if err != nil {
-					logger.Fatal(
-						"System error, bad programs json",
-						zap.Error(err),
-					)
-				}
-				for _, p := range programs {
-					l.ProgramMap[p.OrganizationName] = p
-				}
+	}
+	for _, l := range c.Landing {
+		if l.ProgramsRaw != "" {
+			pm, err := parsePrograms(l.ProgramsRaw)
+			if err != nil {
+				return nil, err
 			}
+			l.ProgramMap = pm
 		}
 	}
+	return c, nil
 }
 
+// Programs are usually a list, keyed by organization name, but JSON config
+// files have them as an object already keyed by name.
+func parsePrograms(raw string) (map[string]Program, error) {
+	pm := map[string]Program{}
+	if strings.HasPrefix(strings.TrimSpace(raw), "{") {
+		if err := json.Unmarshal([]byte(raw), &pm); err != nil {
+			return nil, err
+		}
+		return pm, nil
+	}
+	programs := []Program{}
+	if err := json.Unmarshal([]byte(raw), &programs); err != nil {
+		return nil, err
+	}
+	for _, p := range programs {
+		pm[p.OrganizationName] = p
+	}
+	return pm, nil
+}
+
+// LoadConfigFromJSON reads the config from a JSON file.  An `environments`
+// block in the file can hold overlays for each environment, selected by the
+// ENV environment variable.  Files from older versions of the config are
+// migrated, with a warning logged for each deprecated key.
 func LoadConfigFromJSON(path string, logger *zap.Logger) {
-	config = &Config{}
-	d, err := ioutil.ReadFile(path)
+	// Decoded the same way WatchJSONFile reloads it, so the file reads the
+	// same either way, and a watcher can tell what changed
+	params, err := JSONSource(path).Params()
 	if err != nil {
 		logger.Fatal(
 			"Config read error",
 			zap.Error(err),
 		)
 	}
-	err = json.Unmarshal(d, config)
+	c, err := configFromParams(params)
 	if err != nil {
 		logger.Fatal(
 			"Config parse error",
 			zap.Error(err),
 		)
 	}
+	for _, w := range c.Warnings() {
+		logger.Warn("Deprecated config", zap.String("warning", w))
+	}
+	loadedParams.Store(&params)
+	storeConfig(c)
+}
+
+// Reads a JSON config file, with the overlay for the current environment
+// applied.
+func readJSONTree(path string) (map[string]interface{}, error) {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tree := map[string]interface{}{}
+	if err := json.Unmarshal(d, &tree); err != nil {
+		return nil, err
+	}
+	return applyEnvironment(tree)
 }
@@ -7,6 +7,7 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -66,3 +67,22 @@ func configTestLogger() *zap.Logger {
 	logger = newLogger.Named("cs-common")
 	return logger
 }
+
+func TestSetAndReset(t *testing.T) {
+	c := &Config{Common: CommonConfig{PublicBaseURI: "https://example.local"}}
+	Set(c)
+	assert.Equal(t, c, Current())
+
+	Reset()
+	assert.Nil(t, Current())
+}
+
+func TestLastChecked(t *testing.T) {
+	defer Reset()
+	Reset()
+	assert.True(t, LastChecked().IsZero())
+
+	before := time.Now()
+	Set(&Config{})
+	assert.False(t, LastChecked().Before(before))
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// ByteSize is a number of bytes, decoded from sizes like `512KB` or `10MB`.
+// Units are powers of 1024, and `KiB` style units are accepted as well.
+type ByteSize int64
+
+const (
+	Byte     ByteSize = 1
+	Kilobyte          = 1024 * Byte
+	Megabyte          = 1024 * Kilobyte
+	Gigabyte          = 1024 * Megabyte
+	Terabyte          = 1024 * Gigabyte
+)
+
+var byteSizeUnits = map[string]ByteSize{
+	"":    Byte,
+	"B":   Byte,
+	"K":   Kilobyte,
+	"KB":  Kilobyte,
+	"KIB": Kilobyte,
+	"M":   Megabyte,
+	"MB":  Megabyte,
+	"MIB": Megabyte,
+	"G":   Gigabyte,
+	"GB":  Gigabyte,
+	"GIB": Gigabyte,
+	"T":   Terabyte,
+	"TB":  Terabyte,
+	"TIB": Terabyte,
+}
+
+// ParseByteSize parses a size like `10MB`, `1.5GB` or `100`, which is bytes.
+func ParseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	unit, ok := byteSizeUnits[strings.ToUpper(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	return ByteSize(n * float64(unit)), nil
+}
+
+// Converts the strings that come out of the parameter store into durations
+// (`30s`), byte sizes (`10MB`), URLs and lists (`a,b,c`).
+func decodeHook() mapstructure.DecodeHookFunc {
+	return mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		stringToByteSizeHook,
+		stringToURLHook,
+		stringToSliceHook,
+	)
+}
+
+func stringToByteSizeHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to != reflect.TypeOf(ByteSize(0)) {
+		return data, nil
+	}
+	return ParseByteSize(data.(string))
+}
+
+func stringToURLHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to != reflect.TypeOf(url.URL{}) {
+		return data, nil
+	}
+	u, err := url.Parse(data.(string))
+	if err != nil {
+		return nil, err
+	}
+	return *u, nil
+}
+
+func stringToSliceHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to.Kind() != reflect.Slice {
+		return data, nil
+	}
+	s := strings.TrimSpace(data.(string))
+	if s == "" {
+		return []string{}, nil
+	}
+	parts := strings.Split(s, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts, nil
+}
+
+// Decodes part of the config tree, converting strings to the field types.
+func decode(input, result interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       decodeHook(),
+		WeaklyTypedInput: true,
+		Result:           result,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(input)
+}
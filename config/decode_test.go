@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type clientSettings struct {
+	Timeout     time.Duration `mapstructure:"timeout"`
+	MaxBody     ByteSize      `mapstructure:"max_body"`
+	Endpoint    *url.URL      `mapstructure:"endpoint"`
+	Callback    url.URL       `mapstructure:"callback"`
+	AllowedIPs  []string      `mapstructure:"allowed_ips"`
+	RetryDelays []int         `mapstructure:"retry_delays"`
+}
+
+func TestParseByteSize(t *testing.T) {
+	cases := map[string]ByteSize{
+		"100":    100,
+		"100B":   100,
+		"512KB":  512 * Kilobyte,
+		"10MB":   10 * Megabyte,
+		"10 mb":  10 * Megabyte,
+		"10MiB":  10 * Megabyte,
+		"1.5GB":  Gigabyte + 512*Megabyte,
+		"2T":     2 * Terabyte,
+		" 1KB  ": Kilobyte,
+	}
+	for s, expected := range cases {
+		size, err := ParseByteSize(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, size, s)
+	}
+	for _, s := range []string{"", "MB", "10XB", "1.2.3MB", "-1KB"} {
+		_, err := ParseByteSize(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestDecodeHooks(t *testing.T) {
+	settings := &clientSettings{}
+	registerTestSection(t, "client", settings)
+
+	_, err := configFromParams(map[string]string{
+		"client/timeout":      "1m30s",
+		"client/max_body":     "10MB",
+		"client/endpoint":     "https://api.local/v1",
+		"client/callback":     "https://app.local/callback",
+		"client/allowed_ips":  "10.0.0.1, 10.0.0.2,10.0.0.3",
+		"client/retry_delays": "1,2,4",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, settings.Timeout)
+	assert.Equal(t, 10*Megabyte, settings.MaxBody)
+	require.NotNil(t, settings.Endpoint)
+	assert.Equal(t, "api.local", settings.Endpoint.Host)
+	assert.Equal(t, "/v1", settings.Endpoint.Path)
+	assert.Equal(t, "https://app.local/callback", settings.Callback.String())
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, settings.AllowedIPs)
+	assert.Equal(t, []int{1, 2, 4}, settings.RetryDelays)
+
+	for param, value := range map[string]string{
+		"client/timeout":  "soon",
+		"client/max_body": "big",
+		"client/endpoint": "://nope",
+	} {
+		_, err := configFromParams(map[string]string{param: value})
+		assert.Error(t, err, fmt.Sprintf("%s=%s", param, value))
+	}
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// LoadConfigFromEnv builds the config from environment variables starting with
+// the prefix, e.g. with a prefix of `CS_`, `CS_COMMON_PUBLIC_BASE_URI` sets
+// `common.public_base_uri` and `CS_LANDING_FOO_CLIENT_ID` sets the client ID of
+// the `foo` landing.  Names are matched against the config structure, map keys
+// such as landing names are lower cased, and underscores in them become hyphens.
+func LoadConfigFromEnv(prefix string, logger *zap.Logger) {
+	c, err := configFromParams(paramsFromEnv(prefix, os.Environ()))
+	if err != nil {
+		logger.Fatal(
+			"Unable to load config from the environment",
+			zap.Error(err),
+		)
+	}
+	for _, w := range c.Warnings() {
+		logger.Warn("Deprecated config", zap.String("warning", w))
+	}
+	storeConfig(c)
+}
+
+// Maps environment variables to the same parameter names the parameter store
+// uses, e.g. `landing/foo/client_id`.  Variables that don't match anything in
+// the config are ignored.
+func paramsFromEnv(prefix string, environ []string) map[string]string {
+	pm := map[string]string{}
+	configType := reflect.TypeOf(Config{})
+	for _, kv := range environ {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], prefix) {
+			continue
+		}
+		name := strings.ToUpper(strings.TrimPrefix(parts[0], prefix))
+		if path, ok := matchEnvPath(strings.Split(name, "_"), configType); ok {
+			pm[strings.Join(path, "/")] = parts[1]
+		}
+	}
+	return pm
+}
+
+// Walks the config type, consuming the words of an environment variable name,
+// and returns the parameter path they match.
+func matchEnvPath(words []string, t reflect.Type) ([]string, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		for _, f := range treeFields(t) {
+			fieldWords := strings.Split(strings.ToUpper(f.name), "_")
+			if !hasWordPrefix(words, fieldWords) {
+				continue
+			}
+			if path, ok := matchEnvPath(words[len(fieldWords):], f.typ); ok {
+				return append([]string{f.name}, path...), true
+			}
+		}
+		return nil, false
+	case reflect.Map:
+		// The key can be any number of words, so try the shortest first
+		for i := 1; i <= len(words); i++ {
+			if path, ok := matchEnvPath(words[i:], t.Elem()); ok {
+				return append([]string{envMapKey(words[:i])}, path...), true
+			}
+		}
+		return nil, false
+	default:
+		// Anything else is a value, so all of the words should be used up
+		return nil, len(words) == 0
+	}
+}
+
+func hasWordPrefix(words, prefix []string) bool {
+	if len(prefix) > len(words) {
+		return false
+	}
+	for i := range prefix {
+		if words[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func envMapKey(words []string) string {
+	return strings.ToLower(strings.Join(words, "-"))
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParamsFromEnv(t *testing.T) {
+	environ := []string{
+		"CS_COMMON_PUBLIC_BASE_URI=https://app.dev.alwaysreach.net/public",
+		"CS_COMMON_REDIRECTS_OLD_HOME=https://example.local/home",
+		"CS_LANDING_TEST_SAMPLE_CLIENT_ID=oauth.client.id",
+		"CS_LANDING_TEST_SAMPLE_PASSWORD=the=rug",
+		"CS_LANDING_CLIENT_CLIENT_ID=client.client.id",
+		`CS_LANDING_TEST_SAMPLE_PROGRAMS=[{"organization_name": "test-org", "organization_id": 987}]`,
+		"CS_LANDING_NOT_A_FIELD=ignored",
+		"CS_UNKNOWN=ignored",
+		"OTHER_COMMON_PUBLIC_BASE_URI=ignored",
+		"PATH=/usr/bin",
+	}
+	pm := paramsFromEnv("CS_", environ)
+	assert.Equal(t, map[string]string{
+		"common/public_base_uri":        "https://app.dev.alwaysreach.net/public",
+		"common/redirects/old-home":     "https://example.local/home",
+		"landing/test-sample/client_id": "oauth.client.id",
+		"landing/test-sample/password":  "the=rug",
+		"landing/client/client_id":      "client.client.id",
+		"landing/test-sample/programs":  `[{"organization_name": "test-org", "organization_id": 987}]`,
+	}, pm)
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	t.Setenv("CS_COMMON_COMMON_PUBLIC_BASE_URI", "https://app.dev.alwaysreach.net/public")
+	t.Setenv("CS_COMMON_LANDING_TEST_SAMPLE_USERNAME", "apidude")
+	t.Setenv("CS_COMMON_LANDING_TEST_SAMPLE_PROGRAMS", `[{"organization_name": "test-org", "organization_id": 987}]`)
+	defer Reset()
+
+	LoadConfigFromEnv("CS_COMMON_", configTestLogger())
+
+	c := Current()
+	require.NotNil(t, c)
+	assert.Equal(t, "https://app.dev.alwaysreach.net/public", c.Common.PublicBaseURI)
+	require.NotNil(t, c.Landing["test-sample"])
+	assert.Equal(t, "apidude", c.Landing["test-sample"].Username)
+	assert.Equal(t, 987, c.Landing["test-sample"].ProgramMap["test-org"].OrganizationID)
+}
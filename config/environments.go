@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// EnvironmentVariable names the environment variable that selects which
+// overlay in a config file's `environments` block is used, e.g. `dev`.
+var EnvironmentVariable = "ENV"
+
+// The block in a config file holding the per environment overlays.
+const environmentsKey = "environments"
+
+// Merges the overlay for the current environment over the rest of a config
+// file, so one file can serve every environment:
+//
+//	{
+//	  "common": {"public_base_uri": "https://app.alwaysreach.net/public"},
+//	  "environments": {
+//	    "dev": {"common": {"public_base_uri": "https://app.dev.alwaysreach.net/public"}}
+//	  }
+//	}
+//
+// Objects are merged key by key, and anything else in the overlay replaces the
+// base value.  Without a matching overlay, the base is used as is.
+func applyEnvironment(tree map[string]interface{}) (map[string]interface{}, error) {
+	raw, ok := tree[environmentsKey]
+	if !ok {
+		return tree, nil
+	}
+	environments, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s should be an object", environmentsKey)
+	}
+	base := make(map[string]interface{}, len(tree))
+	for k, v := range tree {
+		if k != environmentsKey {
+			base[k] = v
+		}
+	}
+	env := os.Getenv(EnvironmentVariable)
+	if env == "" {
+		return base, nil
+	}
+	overlay, ok := environments[env]
+	if !ok {
+		return base, nil
+	}
+	o, ok := overlay.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s/%s should be an object", environmentsKey, env)
+	}
+	return mergeTrees(base, o), nil
+}
+
+func mergeTrees(base, overlay map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overlay {
+		bm, baseIsMap := merged[k].(map[string]interface{})
+		om, overlayIsMap := v.(map[string]interface{})
+		if baseIsMap && overlayIsMap {
+			merged[k] = mergeTrees(bm, om)
+		} else {
+			merged[k] = v
+		}
+	}
+	return merged
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigFromJSONEnvironments(t *testing.T) {
+	defer Reset()
+	path := fmt.Sprintf("%s/config/environments.json", testDataDir)
+
+	t.Setenv("ENV", "dev")
+	LoadConfigFromJSON(path, configTestLogger())
+	c := Current()
+	require.NotNil(t, c)
+	assert.Equal(t, "https://app.dev.alwaysreach.net/public", c.Common.PublicBaseURI)
+	require.NotNil(t, c.Landing["test-sample"])
+	assert.Equal(t, "oauth.client.id", c.Landing["test-sample"].ClientID)
+	assert.Equal(t, "apidude", c.Landing["test-sample"].Username)
+	assert.Equal(t, "devrug", c.Landing["test-sample"].Password)
+	require.NotNil(t, c.Landing["dev-only"])
+	assert.Equal(t, "dev.client.id", c.Landing["dev-only"].ClientID)
+
+	t.Setenv("ENV", "stage")
+	LoadConfigFromJSON(path, configTestLogger())
+	c = Current()
+	assert.Equal(t, "https://app.stage.alwaysreach.net/public", c.Common.PublicBaseURI)
+	assert.Equal(t, "therug", c.Landing["test-sample"].Password)
+	assert.Nil(t, c.Landing["dev-only"])
+
+	// Without an overlay, the base is used
+	t.Setenv("ENV", "prod")
+	LoadConfigFromJSON(path, configTestLogger())
+	assert.Equal(t, "https://app.alwaysreach.net/public", Current().Common.PublicBaseURI)
+}
+
+func TestJSONSourceEnvironments(t *testing.T) {
+	t.Setenv("ENV", "dev")
+	pm, err := JSONSource(fmt.Sprintf("%s/config/environments.json", testDataDir)).Params()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"common/public_base_uri":        "https://app.dev.alwaysreach.net/public",
+		"landing/test-sample/client_id": "oauth.client.id",
+		"landing/test-sample/username":  "apidude",
+		"landing/test-sample/password":  "devrug",
+		"landing/dev-only/client_id":    "dev.client.id",
+	}, pm)
+}
+
+func TestApplyEnvironmentErrors(t *testing.T) {
+	t.Setenv("ENV", "dev")
+	_, err := applyEnvironment(map[string]interface{}{"environments": "dev"})
+	assert.EqualError(t, err, "environments should be an object")
+	_, err = applyEnvironment(map[string]interface{}{"environments": map[string]interface{}{"dev": 1}})
+	assert.EqualError(t, err, "environments/dev should be an object")
+}
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Source supplies config values as flat parameter names, e.g.
+// `landing/foo/client_id`, the same as the parameter store uses.
+type Source interface {
+	// Name identifies the source when reporting where a value came from.
+	Name() string
+	Params() (map[string]string, error)
+}
+
+type jsonSource struct {
+	path string
+}
+
+// JSONSource reads a JSON config file, in the same format as LoadConfigFromJSON.
+func JSONSource(path string) Source {
+	return jsonSource{path: path}
+}
+
+func (s jsonSource) Name() string {
+	return "json:" + s.path
+}
+
+func (s jsonSource) Params() (map[string]string, error) {
+	tree, err := readJSONTree(s.path)
+	if err != nil {
+		return nil, err
+	}
+	pm := map[string]string{}
+	if err := flattenJSON(tree, reflect.TypeOf(Config{}), nil, pm); err != nil {
+		return nil, err
+	}
+	return pm, nil
+}
+
+type paramStoreSource struct {
+	region string
+	path   string
+}
+
+// ParamStoreSource reads every parameter under the path in the SSM parameter
+// store.
+func ParamStoreSource(region, path string) Source {
+	return paramStoreSource{region: region, path: path}
+}
+
+func (s paramStoreSource) Name() string {
+	return "ssm:" + s.path
+}
+
+func (s paramStoreSource) Params() (map[string]string, error) {
+	return readParamStore(s.region, s.path)
+}
+
+type envSource struct {
+	prefix string
+}
+
+// EnvSource reads environment variables starting with the prefix, mapped the
+// same way as LoadConfigFromEnv.
+func EnvSource(prefix string) Source {
+	return envSource{prefix: prefix}
+}
+
+func (s envSource) Name() string {
+	return "env:" + s.prefix
+}
+
+func (s envSource) Params() (map[string]string, error) {
+	return paramsFromEnv(s.prefix, os.Environ()), nil
+}
+
+// Loader builds the config from several sources, with later sources taking
+// precedence over earlier ones.  Sources are merged parameter by parameter,
+// so an environment variable can override a single landing's password while
+// the rest of the landing comes from the parameter store.
+//
+//	c, err := config.NewLoader().
+//		With(config.JSONSource("defaults.json")).
+//		With(config.ParamStoreSource("us-east-1", "/cs-common/")).
+//		With(config.EnvSource("CS_")).
+//		Load()
+type Loader struct {
+	sources []Source
+	origins map[string]string
+}
+
+func NewLoader() *Loader {
+	return &Loader{}
+}
+
+// With adds a source, overriding any added before it.
+func (l *Loader) With(s Source) *Loader {
+	l.sources = append(l.sources, s)
+	return l
+}
+
+// Load reads every source, merges them and makes the result the current
+// config.  The current config is left alone when any source fails.
+func (l *Loader) Load() (*Config, error) {
+	params := map[string]string{}
+	origins := map[string]string{}
+	for _, s := range l.sources {
+		pm, err := s.Params()
+		if err != nil {
+			return nil, fmt.Errorf("config source %s: %w", s.Name(), err)
+		}
+		for k, v := range pm {
+			params[k] = v
+			origins[k] = s.Name()
+		}
+	}
+	c, err := configFromParams(params)
+	if err != nil {
+		return nil, err
+	}
+	l.origins = origins
+	loadedParams.Store(&params)
+	storeConfig(c)
+	return c, nil
+}
+
+// Origin returns the name of the source that supplied a parameter in the last
+// load, or an empty string when no source did.
+func (l *Loader) Origin(param string) string {
+	return l.origins[param]
+}
+
+// Origins returns the source of every parameter from the last load.
+func (l *Loader) Origins() map[string]string {
+	origins := make(map[string]string, len(l.origins))
+	for k, v := range l.origins {
+		origins[k] = v
+	}
+	return origins
+}
+
+// Flattens a JSON config tree into parameter names, following the config
+// structure.  Values that are strings in the config, but aren't in the JSON,
+// such as the programs, are kept as JSON.
+func flattenJSON(v interface{}, t reflect.Type, path []string, pm map[string]string) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if v == nil {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s should be an object", strings.Join(path, "/"))
+		}
+		for _, f := range treeFields(t) {
+			if fv, ok := m[f.name]; ok {
+				if err := flattenJSON(fv, f.typ, append(path, f.name), pm); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Map:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s should be an object", strings.Join(path, "/"))
+		}
+		for k, mv := range m {
+			if err := flattenJSON(mv, t.Elem(), append(path, k), pm); err != nil {
+				return err
+			}
+		}
+	default:
+		if s, ok := v.(string); ok {
+			pm[strings.Join(path, "/")] = s
+			return nil
+		}
+		d, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		pm[strings.Join(path, "/")] = string(d)
+	}
+	return nil
+}
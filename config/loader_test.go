@@ -0,0 +1,75 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticSource struct {
+	name   string
+	params map[string]string
+	err    error
+}
+
+func (s staticSource) Name() string {
+	return s.name
+}
+
+func (s staticSource) Params() (map[string]string, error) {
+	return s.params, s.err
+}
+
+func TestJSONSource(t *testing.T) {
+	pm, err := JSONSource(fmt.Sprintf("%s/config/test.json", testDataDir)).Params()
+	require.NoError(t, err)
+	assert.Equal(t, "https://app.dev.alwaysreach.net/public", pm["common/public_base_uri"])
+	assert.Equal(t, "oauth.client.id", pm["landing/test-sample/client_id"])
+	assert.Contains(t, pm["landing/test-sample/programs"], `"test-program"`)
+}
+
+func TestLoaderPrecedence(t *testing.T) {
+	defer Reset()
+	path := fmt.Sprintf("%s/config/test.json", testDataDir)
+	t.Setenv("CS_LANDING_TEST_SAMPLE_PASSWORD", "fromenv")
+
+	l := NewLoader().
+		With(JSONSource(path)).
+		With(staticSource{name: "ssm", params: map[string]string{
+			"landing/test-sample/username": "fromssm",
+			"landing/test-sample/password": "fromssm",
+		}}).
+		With(EnvSource("CS_"))
+	c, err := l.Load()
+	require.NoError(t, err)
+	assert.Equal(t, c, Current())
+
+	landing := c.Landing["test-sample"]
+	require.NotNil(t, landing)
+	assert.Equal(t, "oauth.client.id", landing.ClientID)
+	assert.Equal(t, "fromssm", landing.Username)
+	assert.Equal(t, "fromenv", landing.Password)
+	// The JSON programs keep their names
+	assert.Equal(t, 987, landing.ProgramMap["test-program"].OrganizationID)
+
+	assert.Equal(t, "json:"+path, l.Origin("landing/test-sample/client_id"))
+	assert.Equal(t, "ssm", l.Origin("landing/test-sample/username"))
+	assert.Equal(t, "env:CS_", l.Origin("landing/test-sample/password"))
+	assert.Equal(t, "", l.Origin("landing/missing/password"))
+	assert.Len(t, l.Origins(), 5)
+}
+
+func TestLoaderSourceError(t *testing.T) {
+	defer Reset()
+	c := &Config{}
+	Set(c)
+
+	_, err := NewLoader().
+		With(staticSource{name: "broken", err: errors.New("boom")}).
+		Load()
+	assert.EqualError(t, err, "config source broken: boom")
+	assert.Equal(t, c, Current())
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNotLoaded       = errors.New("config not loaded")
+	ErrLandingNotFound = errors.New("landing not found")
+	ErrProgramNotFound = errors.New("program not found")
+)
+
+// The most names suggested for a typo.
+const maxLookupSuggestions = 3
+
+// LookupError describes a landing or program that isn't in the config, with
+// the closest names that are, to help spot typos.
+type LookupError struct {
+	// Kind is `landing` or `program`.
+	Kind        string
+	Name        string
+	Landing     string
+	Suggestions []string
+	err         error
+}
+
+func (e *LookupError) Error() string {
+	msg := fmt.Sprintf("unknown %s %q", e.Kind, e.Name)
+	if e.Kind == "program" {
+		msg += fmt.Sprintf(" in landing %q", e.Landing)
+	}
+	if len(e.Suggestions) > 0 {
+		quoted := make([]string, len(e.Suggestions))
+		for i, s := range e.Suggestions {
+			quoted[i] = strconv.Quote(s)
+		}
+		msg += fmt.Sprintf(", did you mean %s?", strings.Join(quoted, " or "))
+	}
+	return msg
+}
+
+func (e *LookupError) Unwrap() error {
+	return e.err
+}
+
+// GetLanding returns the named landing, or an error that wraps
+// ErrLandingNotFound, with suggestions for similar names.  It's safe to call on
+// a nil config.
+func (c *Config) GetLanding(name string) (*LandingConfig, error) {
+	if c == nil {
+		return nil, ErrNotLoaded
+	}
+	if l := c.Landing[name]; l != nil {
+		return l, nil
+	}
+	names := make([]string, 0, len(c.Landing))
+	for n, l := range c.Landing {
+		if l != nil {
+			names = append(names, n)
+		}
+	}
+	return nil, &LookupError{
+		Kind:        "landing",
+		Name:        name,
+		Suggestions: suggestNames(name, names),
+		err:         ErrLandingNotFound,
+	}
+}
+
+// Program returns a copy of a landing's program, or an error that wraps
+// ErrLandingNotFound or ErrProgramNotFound, with suggestions for similar
+// names.  It's safe to call on a nil config.
+func (c *Config) Program(landing, program string) (*Program, error) {
+	l, err := c.GetLanding(landing)
+	if err != nil {
+		return nil, err
+	}
+	if p, ok := l.ProgramMap[program]; ok {
+		return &p, nil
+	}
+	names := make([]string, 0, len(l.ProgramMap))
+	for n := range l.ProgramMap {
+		names = append(names, n)
+	}
+	return nil, &LookupError{
+		Kind:        "program",
+		Name:        program,
+		Landing:     landing,
+		Suggestions: suggestNames(program, names),
+		err:         ErrProgramNotFound,
+	}
+}
+
+// Finds the names closest to one that wasn't found, ignoring any that are too
+// different to be a typo.
+func suggestNames(name string, names []string) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+	limit := len(name) / 4
+	if limit < 2 {
+		limit = 2
+	}
+	candidates := []candidate{}
+	for _, n := range names {
+		d := editDistance(strings.ToLower(name), strings.ToLower(n))
+		if d <= limit {
+			candidates = append(candidates, candidate{name: n, distance: d})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].name < candidates[j].name
+	})
+	suggestions := []string{}
+	for i := 0; i < len(candidates) && i < maxLookupSuggestions; i++ {
+		suggestions = append(suggestions, candidates[i].name)
+	}
+	return suggestions
+}
+
+// Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		cur := make([]int, len(br)+1)
+		cur[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(br)]
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lookupTestConfig() *Config {
+	return &Config{
+		Landing: map[string]*LandingConfig{
+			"test-sample": {
+				ClientID: "oauth.client.id",
+				ProgramMap: map[string]Program{
+					"test-program":  {OrganizationName: "test-org", OrganizationID: 987},
+					"other-program": {OrganizationName: "other-org", OrganizationID: 654},
+				},
+			},
+			"test-simple": {ClientID: "simple.client.id"},
+			"unrelated":   {ClientID: "unrelated.client.id"},
+			"broken":      nil,
+		},
+	}
+}
+
+func TestGetLanding(t *testing.T) {
+	c := lookupTestConfig()
+
+	l, err := c.GetLanding("test-sample")
+	require.NoError(t, err)
+	assert.Equal(t, "oauth.client.id", l.ClientID)
+
+	_, err = c.GetLanding("test-sampl")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrLandingNotFound))
+	assert.EqualError(t, err, `unknown landing "test-sampl", did you mean "test-sample" or "test-simple"?`)
+
+	_, err = c.GetLanding("nothing-like-it")
+	assert.EqualError(t, err, `unknown landing "nothing-like-it"`)
+
+	// Nil landings are treated as missing, and never suggested
+	_, err = c.GetLanding("broken")
+	assert.True(t, errors.Is(err, ErrLandingNotFound))
+	_, err = c.GetLanding("broke")
+	assert.EqualError(t, err, `unknown landing "broke"`)
+
+	var nilConfig *Config
+	_, err = nilConfig.GetLanding("test-sample")
+	assert.Equal(t, ErrNotLoaded, err)
+}
+
+func TestProgram(t *testing.T) {
+	c := lookupTestConfig()
+
+	p, err := c.Program("test-sample", "test-program")
+	require.NoError(t, err)
+	assert.Equal(t, 987, p.OrganizationID)
+
+	// A copy is returned
+	p.OrganizationID = 1
+	assert.Equal(t, 987, c.Landing["test-sample"].ProgramMap["test-program"].OrganizationID)
+
+	_, err = c.Program("test-sample", "Test-Program")
+	assert.True(t, errors.Is(err, ErrProgramNotFound))
+	assert.EqualError(t, err, `unknown program "Test-Program" in landing "test-sample", did you mean "test-program"?`)
+	var lookupErr *LookupError
+	require.ErrorAs(t, err, &lookupErr)
+	assert.Equal(t, "program", lookupErr.Kind)
+
+	_, err = c.Program("test-simple", "test-program")
+	assert.EqualError(t, err, `unknown program "test-program" in landing "test-simple"`)
+
+	_, err = c.Program("missing", "test-program")
+	assert.True(t, errors.Is(err, ErrLandingNotFound))
+
+	var nilConfig *Config
+	_, err = nilConfig.Program("test-sample", "test-program")
+	assert.Equal(t, ErrNotLoaded, err)
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("same", "same"))
+	assert.Equal(t, 1, editDistance("sample", "sampl"))
+	assert.Equal(t, 2, editDistance("sample", "sampel"))
+	assert.Equal(t, 3, editDistance("kitten", "sitting"))
+	assert.Equal(t, 4, editDistance("", "four"))
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CurrentConfigVersion is the layout this package decodes.  Configs without a
+// `config_version` are taken to be version 1, and are migrated when loaded.
+const CurrentConfigVersion = 2
+
+const configVersionKey = "config_version"
+
+// MigrationFunc upgrades a config tree from one version to the next, in place.
+// Deprecated keys it finds are returned as warnings, so they can be fixed at
+// the source.
+type MigrationFunc func(tree map[string]interface{}) (warnings []string, err error)
+
+var (
+	migrations     = map[int]MigrationFunc{1: migrateProgramsToObjects}
+	migrationsLock sync.RWMutex
+)
+
+// RegisterMigration adds the migration from a version of the config to the
+// next, e.g. for the keys of a registered section.  Only one migration can be
+// registered for each version, and this is meant to be called during service
+// start up, before the config is loaded.
+func RegisterMigration(from int, fn MigrationFunc) {
+	migrationsLock.Lock()
+	defer migrationsLock.Unlock()
+	if _, ok := migrations[from]; ok {
+		panic(fmt.Sprintf("config: migration from version %d is already registered", from))
+	}
+	migrations[from] = fn
+}
+
+// Warnings returns the problems found while loading the config that didn't
+// stop it loading, such as deprecated keys that were migrated.
+func (c *Config) Warnings() []string {
+	return c.warnings
+}
+
+// Upgrades a config tree to the current version, returning warnings for any
+// deprecated keys.  Trees newer than this package understands are rejected,
+// rather than being decoded wrongly.
+func migrateTree(tree map[string]interface{}) ([]string, error) {
+	version, err := treeVersion(tree)
+	if err != nil {
+		return nil, err
+	}
+	if version > CurrentConfigVersion {
+		return nil, fmt.Errorf("config version %d is newer than the supported version %d", version, CurrentConfigVersion)
+	}
+	migrationsLock.RLock()
+	defer migrationsLock.RUnlock()
+	warnings := []string{}
+	for ; version < CurrentConfigVersion; version++ {
+		fn, ok := migrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration from config version %d", version)
+		}
+		w, err := fn(tree)
+		if err != nil {
+			return nil, fmt.Errorf("migrating config from version %d: %w", version, err)
+		}
+		warnings = append(warnings, w...)
+	}
+	tree[configVersionKey] = CurrentConfigVersion
+	return warnings, nil
+}
+
+// The version is a string in the parameter store, and a number in JSON.
+func treeVersion(tree map[string]interface{}) (int, error) {
+	switch v := tree[configVersionKey].(type) {
+	case nil:
+		return 1, nil
+	case int:
+		return v, nil
+	case float64:
+		return int(v), nil
+	case string:
+		version, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return 0, fmt.Errorf("%s %q isn't a number", configVersionKey, v)
+		}
+		return version, nil
+	}
+	return 0, fmt.Errorf("%s should be a number", configVersionKey)
+}
+
+// Version 1 had programs as a JSON string holding a list, keyed by
+// organization name when loaded.  Version 2 has them as an object keyed by
+// name, the same as JSON config files.
+func migrateProgramsToObjects(tree map[string]interface{}) ([]string, error) {
+	landings, _ := tree["landing"].(map[string]interface{})
+	names := make([]string, 0, len(landings))
+	for name := range landings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	warnings := []string{}
+	for _, name := range names {
+		landing, ok := landings[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var list []interface{}
+		switch p := landing["programs"].(type) {
+		case string:
+			if !looksLikeJSON(p) {
+				continue
+			}
+			var parsed interface{}
+			if err := json.Unmarshal([]byte(p), &parsed); err != nil {
+				return nil, fmt.Errorf("landing %s programs: %w", name, err)
+			}
+			landing["programs"] = parsed
+			warnings = append(warnings, fmt.Sprintf("landing/%s/programs is a JSON string, which is deprecated, use an object keyed by organization name", name))
+			list, _ = parsed.([]interface{})
+		case []interface{}:
+			list = p
+			warnings = append(warnings, fmt.Sprintf("landing/%s/programs is a list, which is deprecated, use an object keyed by organization name", name))
+		}
+		if list == nil {
+			continue
+		}
+		programs := make(map[string]interface{}, len(list))
+		for _, item := range list {
+			program, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("landing %s programs should be a list of objects", name)
+			}
+			orgName, _ := program["organization_name"].(string)
+			programs[orgName] = program
+		}
+		landing["programs"] = programs
+	}
+	return warnings, nil
+}
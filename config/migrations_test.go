@@ -0,0 +1,78 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateProgramsToObjects(t *testing.T) {
+	c, err := configFromParams(map[string]string{
+		"landing/test-sample/client_id": "oauth.client.id",
+		"landing/test-sample/programs":  `[{"organization_name": "test-org", "organization_id": 987}]`,
+		"landing/other/programs":        `{"other-org": {"organization_name": "other-org", "organization_id": 654}}`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, CurrentConfigVersion, c.Version)
+	assert.Equal(t, 987, c.Landing["test-sample"].ProgramMap["test-org"].OrganizationID)
+	assert.Equal(t, 654, c.Landing["other"].ProgramMap["other-org"].OrganizationID)
+	assert.Empty(t, c.Landing["test-sample"].ProgramsRaw)
+	assert.Equal(t, []string{
+		"landing/other/programs is a JSON string, which is deprecated, use an object keyed by organization name",
+		"landing/test-sample/programs is a JSON string, which is deprecated, use an object keyed by organization name",
+	}, c.Warnings())
+}
+
+func TestMigrateCurrentVersion(t *testing.T) {
+	c, err := configFromParams(map[string]string{
+		"config_version": "2",
+		"landing/test-sample/programs/test-org/organization_id": "987",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 987, c.Landing["test-sample"].ProgramMap["test-org"].OrganizationID)
+	assert.Empty(t, c.Warnings())
+}
+
+func TestMigrateVersionErrors(t *testing.T) {
+	_, err := configFromParams(map[string]string{"config_version": "3"})
+	assert.EqualError(t, err, "config version 3 is newer than the supported version 2")
+
+	_, err = configFromParams(map[string]string{"config_version": "two"})
+	assert.EqualError(t, err, `config_version "two" isn't a number`)
+
+	_, err = configFromParams(map[string]string{"config_version": "0"})
+	assert.EqualError(t, err, "no migration from config version 0")
+
+	_, err = configFromParams(map[string]string{"landing/test-sample/programs": `["test-org"]`})
+	assert.EqualError(t, err, "migrating config from version 1: landing test-sample programs should be a list of objects")
+}
+
+func TestRegisterMigration(t *testing.T) {
+	assert.Panics(t, func() {
+		RegisterMigration(1, func(tree map[string]interface{}) ([]string, error) { return nil, nil })
+	})
+
+	RegisterMigration(0, func(tree map[string]interface{}) ([]string, error) {
+		common, _ := tree["common"].(map[string]interface{})
+		if uri, ok := common["base_uri"]; ok {
+			common["public_base_uri"] = uri
+			delete(common, "base_uri")
+			return []string{"common/base_uri is deprecated, use common/public_base_uri"}, nil
+		}
+		return nil, nil
+	})
+	t.Cleanup(func() {
+		migrationsLock.Lock()
+		defer migrationsLock.Unlock()
+		delete(migrations, 0)
+	})
+
+	c, err := configFromParams(map[string]string{
+		"config_version":  "0",
+		"common/base_uri": "https://example.local",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.local", c.Common.PublicBaseURI)
+	assert.Equal(t, []string{"common/base_uri is deprecated, use common/public_base_uri"}, c.Warnings())
+}
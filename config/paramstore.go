@@ -0,0 +1,199 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+
+	"github.com/seniorlink-vela/cs-common/cache"
+	"github.com/seniorlink-vela/cs-common/retry"
+)
+
+const (
+	defaultParamRetries    = 3
+	defaultParamRetryDelay = 100 * time.Millisecond
+	maxParamRetryDelay     = 5 * time.Second
+)
+
+// SSMAPI is the part of the SSM client used to read and write parameters, so
+// a fake can be used in tests.  *ssm.Client implements it.
+type SSMAPI interface {
+	GetParametersByPath(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error)
+	PutParameter(ctx context.Context, params *ssm.PutParameterInput, optFns ...func(*ssm.Options)) (*ssm.PutParameterOutput, error)
+}
+
+var _ SSMAPI = (*ssm.Client)(nil)
+
+// ParamStoreOptions controls how parameters are read from the SSM parameter
+// store.
+type ParamStoreOptions struct {
+	// Region is used to create a client when Client is nil.
+	Region string
+	// Client is used instead of creating one, e.g. to share the AWS config with
+	// the rest of the service.
+	Client SSMAPI
+	// MaxRetries is how many times a throttled request is retried, 3 when zero.
+	// Use a negative number to disable retries.
+	MaxRetries int
+	// RetryDelay is the wait before the first retry, 100ms when zero.  It
+	// doubles for each retry after that, up to 5 seconds, less some jitter.
+	RetryDelay time.Duration
+	// MaxPages limits how many pages of parameters are read, with no limit when
+	// zero.  Stopping early is reported as a warning.
+	MaxPages int
+	// Overwrite replaces existing parameters when writing.  Without it, writing
+	// fails at the first parameter that already exists.
+	Overwrite bool
+	// KMSKeyID is the KMS key used to encrypt secrets when writing, the account's
+	// default SSM key when empty.
+	KMSKeyID string
+	// Cache keeps the parameters LoadConfigFromParamStoreContext reads, keyed
+	// by path, so a fleet of Lambdas starting at once doesn't get throttled
+	// by SSM.  Its TTL is how stale a new instance's config can be.  The
+	// parameters include decrypted secrets, so only use an encrypted backend
+	// that's no more widely readable than the parameters.
+	Cache *cache.Cache[CachedParams]
+}
+
+// CachedParams are the parameters under a path, and the warnings reading
+// them, as ParamStoreOptions.Cache keeps them.
+type CachedParams struct {
+	Params   map[string]string `json:"params"`
+	Warnings []string          `json:"warnings,omitempty"`
+}
+
+func (o ParamStoreOptions) client(ctx context.Context) (SSMAPI, error) {
+	if o.Client != nil {
+		return o.Client, nil
+	}
+	return newSSMClient(ctx, o.Region)
+}
+
+// LoadConfigFromParamStoreContext reads every parameter under the path and
+// makes it the current config.  Unlike LoadConfigFromParamStore, errors are
+// returned rather than being fatal, and the current config is left alone when
+// loading fails.  Problems that didn't stop the load, such as reaching the page
+// limit, parameters without a value or deprecated keys, are returned as
+// warnings.
+func LoadConfigFromParamStoreContext(ctx context.Context, path string, opts ParamStoreOptions) ([]string, error) {
+	svc, err := opts.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	params, warnings, err := readCachedParams(ctx, svc, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	c, err := configFromParams(params)
+	if err != nil {
+		return warnings, err
+	}
+	loadedParams.Store(&params)
+	storeConfig(c)
+	return append(warnings, c.Warnings()...), nil
+}
+
+// Reads every parameter under the path, keyed by name with the path removed.
+func readParamStore(region, path string) (map[string]string, error) {
+	opts := ParamStoreOptions{Region: region}
+	svc, err := opts.client(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	params, _, err := readParams(context.Background(), svc, path, opts)
+	return params, err
+}
+
+// Reads the parameters through the cache, when there is one.  The warnings
+// reading them are cached with them.
+func readCachedParams(ctx context.Context, svc SSMAPI, path string, opts ParamStoreOptions) (map[string]string, []string, error) {
+	if opts.Cache == nil {
+		return readParams(ctx, svc, path, opts)
+	}
+	cached, err := opts.Cache.GetOrFill(ctx, path, func(ctx context.Context) (CachedParams, error) {
+		params, warnings, err := readParams(ctx, svc, path, opts)
+		return CachedParams{Params: params, Warnings: warnings}, err
+	})
+	return cached.Params, cached.Warnings, err
+}
+
+func readParams(ctx context.Context, svc SSMAPI, path string, opts ParamStoreOptions) (map[string]string, []string, error) {
+	in := &ssm.GetParametersByPathInput{
+		Path:           aws.String(path),
+		WithDecryption: aws.Bool(true),
+		Recursive:      aws.Bool(true),
+	}
+
+	pm := make(map[string]string)
+	warnings := []string{}
+	for page := 1; ; page++ {
+		out, err := getParamsPage(ctx, svc, in, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, p := range out.Parameters {
+			if p.Name == nil || p.Value == nil {
+				warnings = append(warnings, fmt.Sprintf("parameter %s has no value", stringOrEmpty(p.Name)))
+				continue
+			}
+			pm[strings.TrimPrefix(*p.Name, path)] = *p.Value
+		}
+		if out.NextToken == nil || *out.NextToken == "" {
+			return pm, warnings, nil
+		}
+		if opts.MaxPages > 0 && page >= opts.MaxPages {
+			warnings = append(warnings, fmt.Sprintf("stopped after %d pages, later parameters were not loaded", page))
+			return pm, warnings, nil
+		}
+		in.NextToken = out.NextToken
+	}
+}
+
+// Gets a page of parameters, backing off and retrying when throttled.
+func getParamsPage(ctx context.Context, svc SSMAPI, in *ssm.GetParametersByPathInput, opts ParamStoreOptions) (*ssm.GetParametersByPathOutput, error) {
+	var out *ssm.GetParametersByPathOutput
+	err := withRetries(ctx, opts, func() error {
+		var err error
+		out, err = svc.GetParametersByPath(ctx, in, singleAttempt)
+		return err
+	})
+	return out, err
+}
+
+// Runs an SSM call, backing off and retrying when it's throttled.  The call
+// should pass singleAttempt, so the SDK doesn't retry as well.
+func withRetries(ctx context.Context, opts ParamStoreOptions, call func() error) error {
+	policy := retry.Policy{
+		MaxAttempts:  opts.MaxRetries + 1,
+		InitialDelay: opts.RetryDelay,
+		MaxDelay:     maxParamRetryDelay,
+		Retryable:    retry.AWSThrottle,
+	}
+	switch {
+	case opts.MaxRetries == 0:
+		policy.MaxAttempts = defaultParamRetries + 1
+	case opts.MaxRetries < 0:
+		policy.MaxAttempts = 1
+	}
+	if policy.InitialDelay == 0 {
+		policy.InitialDelay = defaultParamRetryDelay
+	}
+	return retry.Do(ctx, policy, func(context.Context) error {
+		return call()
+	})
+}
+
+func singleAttempt(o *ssm.Options) {
+	o.RetryMaxAttempts = 1
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/seniorlink-vela/cs-common/cache"
+)
+
+// Warned about for the programs in testParams, which use the version 1 layout.
+const programsDeprecation = "landing/test-sample/programs is a JSON string, which is deprecated, use an object keyed by organization name"
+
+func TestLoadConfigFromParamStoreContext(t *testing.T) {
+	defer Reset()
+	fake := &fakeSSM{params: testParams()}
+
+	warnings, err := LoadConfigFromParamStoreContext(context.Background(), "/cs-common/", ParamStoreOptions{Client: fake})
+	require.NoError(t, err)
+	assert.Equal(t, []string{programsDeprecation}, warnings)
+	assert.Equal(t, 3, fake.calls)
+
+	c := Current()
+	require.NotNil(t, c)
+	assert.Equal(t, "therug", c.Landing["test-sample"].Password)
+}
+
+func TestLoadConfigFromParamStoreContextCache(t *testing.T) {
+	defer Reset()
+	fake := &fakeSSM{params: testParams()}
+	opts := ParamStoreOptions{Client: fake, Cache: cache.New[CachedParams](cache.NewMemory(0), cache.Options{TTL: time.Minute})}
+
+	first, err := LoadConfigFromParamStoreContext(context.Background(), "/cs-common/", opts)
+	require.NoError(t, err)
+	Reset()
+	warnings, err := LoadConfigFromParamStoreContext(context.Background(), "/cs-common/", opts)
+	require.NoError(t, err)
+	// The same warnings, though SSM wasn't read
+	assert.Equal(t, first, warnings)
+	assert.Equal(t, 3, fake.calls)
+	assert.Equal(t, "therug", Current().Landing["test-sample"].Password)
+}
+
+func TestLoadConfigFromParamStoreContextRetriesThrottling(t *testing.T) {
+	defer Reset()
+	fake := &fakeSSM{params: testParams(), throttle: 2}
+
+	_, err := LoadConfigFromParamStoreContext(context.Background(), "/cs-common/", ParamStoreOptions{
+		Client:     fake,
+		RetryDelay: time.Millisecond,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 5, fake.calls)
+	assert.Equal(t, 1, fake.options.RetryMaxAttempts, "the SDK doesn't retry as well")
+	assert.NotNil(t, Current())
+}
+
+func TestLoadConfigFromParamStoreContextGivesUp(t *testing.T) {
+	defer Reset()
+	fake := &fakeSSM{params: testParams(), throttle: 10}
+
+	_, err := LoadConfigFromParamStoreContext(context.Background(), "/cs-common/", ParamStoreOptions{
+		Client:     fake,
+		MaxRetries: 2,
+		RetryDelay: time.Millisecond,
+	})
+	var apiErr smithy.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "ThrottlingException", apiErr.ErrorCode())
+	assert.Equal(t, 3, fake.calls)
+	assert.Nil(t, Current())
+
+	// Without retries
+	fake.calls = 0
+	_, err = LoadConfigFromParamStoreContext(context.Background(), "/cs-common/", ParamStoreOptions{
+		Client:     fake,
+		MaxRetries: -1,
+	})
+	require.Error(t, err)
+	assert.Equal(t, 1, fake.calls)
+}
+
+func TestLoadConfigFromParamStoreContextCancelled(t *testing.T) {
+	fake := &fakeSSM{params: testParams(), throttle: 10}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := LoadConfigFromParamStoreContext(ctx, "/cs-common/", ParamStoreOptions{
+		Client:     fake,
+		RetryDelay: time.Hour,
+	})
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestLoadConfigFromParamStoreContextMaxPages(t *testing.T) {
+	defer Reset()
+	fake := &fakeSSM{params: testParams()}
+
+	warnings, err := LoadConfigFromParamStoreContext(context.Background(), "/cs-common/", ParamStoreOptions{
+		Client:   fake,
+		MaxPages: 2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"stopped after 2 pages, later parameters were not loaded", programsDeprecation}, warnings)
+	assert.Len(t, *loadedParams.Load(), 4)
+}
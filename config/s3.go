@@ -0,0 +1,184 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+// S3API is the part of the S3 client used to read config objects.
+// *s3.Client implements it.
+type S3API interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// KMSAPI is the part of the KMS client used to decrypt config objects.
+// *kms.Client implements it.
+type KMSAPI interface {
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+var (
+	_ S3API  = (*s3.Client)(nil)
+	_ KMSAPI = (*kms.Client)(nil)
+)
+
+// Creates the S3 client used to read config objects, replaced in tests.
+var newS3Client = defaultS3Client
+
+// Clients by region, so each load doesn't load the AWS config again.
+var s3Clients sync.Map
+
+func defaultS3Client(ctx context.Context, region string) (S3API, error) {
+	if svc, ok := s3Clients.Load(region); ok {
+		return svc.(S3API), nil
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(region))
+	if err != nil {
+		return nil, err
+	}
+	svc, _ := s3Clients.LoadOrStore(region, s3.NewFromConfig(cfg))
+	return svc.(S3API), nil
+}
+
+// S3Options controls how the config is read from S3.
+type S3Options struct {
+	// Region is used to create a client when Client is nil.
+	Region string
+	// Client is used instead of creating one.
+	Client S3API
+	// KMS decrypts the object when it was encrypted with KMS before being
+	// uploaded, i.e. the object is the ciphertext from a KMS Encrypt call.
+	// Objects using S3's own KMS encryption are decrypted by S3, and don't need
+	// this.
+	KMS KMSAPI
+	// RefreshInterval is how often the object is checked for changes, with no
+	// refreshing when zero.  It's only downloaded again when its ETag changes.
+	RefreshInterval time.Duration
+	// OnChange, when not nil, is called after a refresh swaps in a new config,
+	// along with the registered callbacks.
+	OnChange ChangeFunc
+}
+
+// LoadConfigFromS3 reads the config from a JSON object in S3, in the same
+// format as LoadConfigFromJSON, for configs too large for the parameter store,
+// e.g. large program mappings.  When a refresh interval is set, the object is
+// checked until the context is done, and changes are swapped in like
+// WatchParamStore.  Failed refreshes are logged with the context logger, and
+// the current config is kept.
+func LoadConfigFromS3(ctx context.Context, bucket, key string, opts S3Options) error {
+	svc := opts.Client
+	if svc == nil {
+		var err error
+		if svc, err = newS3Client(ctx, opts.Region); err != nil {
+			return err
+		}
+	}
+	obj := &s3Object{svc: svc, kms: opts.KMS, bucket: bucket, key: key}
+	params, _, err := obj.read(ctx)
+	if err != nil {
+		return err
+	}
+	c, err := configFromParams(params)
+	if err != nil {
+		return err
+	}
+	loadedParams.Store(&params)
+	storeConfig(c)
+
+	if opts.RefreshInterval > 0 {
+		go obj.refresh(ctx, opts.RefreshInterval, opts.OnChange)
+	}
+	return nil
+}
+
+type s3Object struct {
+	svc    S3API
+	kms    KMSAPI
+	bucket string
+	key    string
+	etag   string
+}
+
+// Reads the object's parameters, unless its ETag hasn't changed since the
+// last read.
+func (o *s3Object) read(ctx context.Context) (map[string]string, bool, error) {
+	in := &s3.GetObjectInput{
+		Bucket: aws.String(o.bucket),
+		Key:    aws.String(o.key),
+	}
+	if o.etag != "" {
+		in.IfNoneMatch = aws.String(o.etag)
+	}
+	out, err := o.svc.GetObject(ctx, in)
+	if err != nil {
+		var respErr *awshttp.ResponseError
+		if errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotModified {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	defer out.Body.Close()
+	d, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	if o.kms != nil {
+		dec, err := o.kms.Decrypt(ctx, &kms.DecryptInput{CiphertextBlob: d})
+		if err != nil {
+			return nil, false, err
+		}
+		d = dec.Plaintext
+	}
+
+	tree := map[string]interface{}{}
+	if err := json.Unmarshal(d, &tree); err != nil {
+		return nil, false, err
+	}
+	if tree, err = applyEnvironment(tree); err != nil {
+		return nil, false, err
+	}
+	params := map[string]string{}
+	if err := flattenJSON(tree, reflect.TypeOf(Config{}), nil, params); err != nil {
+		return nil, false, err
+	}
+	o.etag = aws.ToString(out.ETag)
+	return params, true, nil
+}
+
+func (o *s3Object) refresh(ctx context.Context, interval time.Duration, onChange ChangeFunc) {
+	logger := velacontext.GetContextLogger(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		params, changed, err := o.read(ctx)
+		if err == nil && changed {
+			err = applyParams(params, onChange)
+		} else if err == nil {
+			markChecked()
+		}
+		if err != nil {
+			logger.Error("Config refresh from S3 failed", zap.String("bucket", o.bucket), zap.String("key", o.key), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Serves a single object, honoring If-None-Match like S3 does.
+type fakeS3 struct {
+	sync.Mutex
+	body  string
+	etag  string
+	gets  int
+	reads int
+}
+
+func (f *fakeS3) put(body, etag string) {
+	f.Lock()
+	defer f.Unlock()
+	f.body = body
+	f.etag = etag
+}
+
+func (f *fakeS3) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.gets++
+	if aws.ToString(in.IfNoneMatch) == f.etag {
+		return nil, &awshttp.ResponseError{
+			ResponseError: &smithyhttp.ResponseError{
+				Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusNotModified}},
+				Err:      errors.New("not modified"),
+			},
+		}
+	}
+	f.reads++
+	return &s3.GetObjectOutput{
+		Body: io.NopCloser(bytes.NewBufferString(f.body)),
+		ETag: aws.String(f.etag),
+	}, nil
+}
+
+// "Decrypts" by reversing the bytes.
+type fakeKMS struct{}
+
+func (fakeKMS) Decrypt(ctx context.Context, in *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	return &kms.DecryptOutput{Plaintext: reverse(in.CiphertextBlob)}, nil
+}
+
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
+
+const s3ConfigJSON = `{
+  "common": {"public_base_uri": "https://example.local"},
+  "landing": {"test-sample": {
+    "client_id": "oauth.client.id",
+    "programs": {"test-program": {"organization_name": "test-org", "organization_id": 987}}
+  }}
+}`
+
+func TestLoadConfigFromS3(t *testing.T) {
+	defer Reset()
+	fake := &fakeS3{body: s3ConfigJSON, etag: `"v1"`}
+	require.NoError(t, LoadConfigFromS3(context.Background(), "bucket", "config.json", S3Options{Client: fake}))
+
+	c := Current()
+	require.NotNil(t, c)
+	assert.Equal(t, "https://example.local", c.Common.PublicBaseURI)
+	assert.Equal(t, 987, c.Landing["test-sample"].ProgramMap["test-program"].OrganizationID)
+}
+
+func TestLoadConfigFromS3Encrypted(t *testing.T) {
+	defer Reset()
+	fake := &fakeS3{body: string(reverse([]byte(s3ConfigJSON))), etag: `"v1"`}
+	require.NoError(t, LoadConfigFromS3(context.Background(), "bucket", "config.json", S3Options{Client: fake, KMS: fakeKMS{}}))
+	assert.Equal(t, "https://example.local", Current().Common.PublicBaseURI)
+
+	// Without KMS the ciphertext doesn't parse
+	Reset()
+	assert.Error(t, LoadConfigFromS3(context.Background(), "bucket", "config.json", S3Options{Client: fake}))
+	assert.Nil(t, Current())
+}
+
+func TestLoadConfigFromS3Refresh(t *testing.T) {
+	defer Reset()
+	fake := &fakeS3{body: s3ConfigJSON, etag: `"v1"`}
+	changes := make(chan ParamDiff, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, LoadConfigFromS3(ctx, "bucket", "config.json", S3Options{
+		Client:          fake,
+		RefreshInterval: 10 * time.Millisecond,
+		OnChange: func(old, new *Config, diff ParamDiff) {
+			changes <- diff
+		},
+	}))
+
+	// Unchanged objects aren't downloaded again
+	time.Sleep(50 * time.Millisecond)
+	fake.Lock()
+	assert.Greater(t, fake.gets, 1)
+	assert.Equal(t, 1, fake.reads)
+	fake.Unlock()
+	assert.Empty(t, changes)
+
+	fake.put(`{"common": {"public_base_uri": "https://new.local"}}`, `"v2"`)
+	select {
+	case diff := <-changes:
+		assert.Equal(t, []string{"common/public_base_uri"}, diff.Changed)
+		assert.Equal(t, []string{"landing/test-sample/client_id", "landing/test-sample/programs"}, diff.Removed)
+	case <-time.After(time.Second):
+		t.Fatal("Config was not refreshed")
+	}
+	assert.Equal(t, "https://new.local", Current().Common.PublicBaseURI)
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// What secrets are replaced with.  Empty values are left empty, so it's still
+// clear what isn't set.
+const redactedValue = "********"
+
+// Parts of field names that mark a secret, matched case insensitively.
+var secretFieldNames = []string{"password", "secret", "token", "apikey", "api_key", "privatekey", "private_key"}
+
+// Sanitized returns a copy of the config with passwords, client secrets and
+// tokens masked, including in registered sections, so it can be logged or
+// shown to admins.  Fields are masked when they're tagged `sensitive:"true"`,
+// or their names say they're secret.
+func (c *Config) Sanitized() *Config {
+	if c == nil {
+		return nil
+	}
+	s := sanitizeValue(reflect.ValueOf(c).Elem(), false).Addr().Interface().(*Config)
+	s.warnings = append([]string(nil), c.warnings...)
+	s.sections = nil
+	if c.sections != nil {
+		s.sections = make(map[string]interface{}, len(c.sections))
+		for name, section := range c.sections {
+			v := reflect.New(reflect.TypeOf(section).Elem())
+			v.Elem().Set(sanitizeValue(reflect.ValueOf(section).Elem(), false))
+			s.sections[name] = v.Interface()
+		}
+	}
+	return s
+}
+
+// DumpJSON writes the current config as JSON, sanitized, with the registered
+// sections alongside the common and landing settings.  Fields are named by
+// their config keys, the same as Diff names them.
+func DumpJSON(w io.Writer) error {
+	c := Current().Sanitized()
+	if c == nil {
+		_, err := io.WriteString(w, "null\n")
+		return err
+	}
+	dump := dumpValue(reflect.ValueOf(c).Elem()).(map[string]interface{})
+	for name, section := range c.sections {
+		dump[name] = dumpValue(reflect.ValueOf(section))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(dump)
+}
+
+func redact(s string) string {
+	if s == "" {
+		return s
+	}
+	return redactedValue
+}
+
+// Whether the field holds a secret, by its tag or its name.
+func isSensitiveField(f reflect.StructField) bool {
+	return f.Tag.Get("sensitive") == "true" || isSecretField(f.Name)
+}
+
+func isSecretField(name string) bool {
+	name = strings.ToLower(name)
+	for _, secret := range secretFieldNames {
+		if strings.Contains(name, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// Copies a value, masking strings that are in, or under, a secret field.
+func sanitizeValue(v reflect.Value, secret bool) reflect.Value {
+	out := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.String:
+		if secret {
+			out.SetString(redact(v.String()))
+		} else {
+			out.Set(v)
+		}
+	case reflect.Struct:
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			out.Field(i).Set(sanitizeValue(v.Field(i), secret || isSensitiveField(f)))
+		}
+	case reflect.Ptr:
+		if !v.IsNil() {
+			p := reflect.New(v.Type().Elem())
+			p.Elem().Set(sanitizeValue(v.Elem(), secret))
+			out.Set(p)
+		}
+	case reflect.Map:
+		if !v.IsNil() {
+			out.Set(reflect.MakeMapWithSize(v.Type(), v.Len()))
+			iter := v.MapRange()
+			for iter.Next() {
+				out.SetMapIndex(iter.Key(), sanitizeValue(iter.Value(), secret))
+			}
+		}
+	case reflect.Slice:
+		if !v.IsNil() {
+			out.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+			for i := 0; i < v.Len(); i++ {
+				out.Index(i).Set(sanitizeValue(v.Index(i), secret))
+			}
+		}
+	default:
+		out.Set(v)
+	}
+	return out
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Converts a value to maps keyed by config keys, rather than the Go names
+// encoding/json would use for fields with only a mapstructure tag.  Values
+// that marshal themselves, like times, are left to encoding/json.
+func dumpValue(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType) {
+		return v.Interface()
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return dumpValue(v.Elem())
+	case reflect.Struct:
+		m := map[string]interface{}{}
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if name := diffFieldName(f); name != "" {
+				m[name] = dumpValue(v.Field(i))
+			}
+		}
+		return m
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(iter.Key().Interface())] = dumpValue(iter.Value())
+		}
+		return m
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = dumpValue(v.Index(i))
+		}
+		return list
+	default:
+		return v.Interface()
+	}
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type apiSettings struct {
+	URL       string            `mapstructure:"url"`
+	APIKey    string            `mapstructure:"api_key"`
+	Secrets   map[string]string `mapstructure:"secrets"`
+	Timeout   int               `mapstructure:"timeout"`
+	AuthToken string            `mapstructure:"auth_token"`
+}
+
+func TestSanitized(t *testing.T) {
+	settings := &apiSettings{}
+	registerTestSection(t, "api", settings)
+	c, err := configFromParams(map[string]string{
+		"common/public_base_uri":        "https://example.local",
+		"landing/test-sample/client_id": "oauth.client.id",
+		"landing/test-sample/password":  "therug",
+		"landing/test-sample/programs":  `[{"organization_name": "test-org", "organization_id": 987}]`,
+		"landing/no-password/client_id": "other.client.id",
+		"api/url":                       "https://api.local",
+		"api/api_key":                   "key",
+		"api/secrets/webhook":           "hook",
+		"api/timeout":                   "30",
+	})
+	require.NoError(t, err)
+
+	s := c.Sanitized()
+	assert.Equal(t, "https://example.local", s.Common.PublicBaseURI)
+	assert.Equal(t, "oauth.client.id", s.Landing["test-sample"].ClientID)
+	assert.Equal(t, "********", s.Landing["test-sample"].Password)
+	assert.Equal(t, "", s.Landing["no-password"].Password)
+	assert.Equal(t, 987, s.Landing["test-sample"].ProgramMap["test-org"].OrganizationID)
+	assert.Equal(t, &apiSettings{
+		URL:     "https://api.local",
+		APIKey:  "********",
+		Secrets: map[string]string{"webhook": "********"},
+		Timeout: 30,
+	}, s.Section("api"))
+
+	// The original is untouched
+	assert.Equal(t, "therug", c.Landing["test-sample"].Password)
+	assert.Equal(t, "key", c.Section("api").(*apiSettings).APIKey)
+	assert.Equal(t, "hook", c.Section("api").(*apiSettings).Secrets["webhook"])
+
+	var nilConfig *Config
+	assert.Nil(t, nilConfig.Sanitized())
+}
+
+func TestSanitizedByTag(t *testing.T) {
+	type vendor struct {
+		Endpoint string `mapstructure:"endpoint"`
+		DSN      string `mapstructure:"dsn" sensitive:"true"`
+	}
+	settings := &vendor{}
+	registerTestSection(t, "vendor", settings)
+	c, err := configFromParams(map[string]string{
+		"landing/test-sample/programs": `[{"organization_name": "test-org"}]`,
+		"vendor/endpoint":              "https://vendor.local",
+		"vendor/dsn":                   "postgres://u:p@db/x",
+	})
+	require.NoError(t, err)
+
+	s := c.Sanitized()
+	assert.Equal(t, &vendor{Endpoint: "https://vendor.local", DSN: "********"}, s.Section("vendor"))
+	assert.Equal(t, c.Warnings(), s.Warnings())
+	assert.NotEmpty(t, s.Warnings())
+}
+
+func TestDumpJSON(t *testing.T) {
+	defer Reset()
+	settings := &apiSettings{}
+	registerTestSection(t, "api", settings)
+	c, err := configFromParams(map[string]string{
+		"common/public_base_uri":       "https://example.local",
+		"landing/test-sample/password": "therug",
+		"api/api_key":                  "key",
+	})
+	require.NoError(t, err)
+	Set(c)
+
+	var buf bytes.Buffer
+	require.NoError(t, DumpJSON(&buf))
+	assert.NotContains(t, buf.String(), "therug")
+	assert.NotContains(t, buf.String(), `"key"`)
+
+	dump := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &dump))
+	assert.Equal(t, float64(CurrentConfigVersion), dump["config_version"])
+	assert.Equal(t, "https://example.local", dump["common"].(map[string]interface{})["public_base_uri"])
+	landing := dump["landing"].(map[string]interface{})
+	assert.Equal(t, "********", landing["test-sample"].(map[string]interface{})["password"])
+	// Sections are keyed the same as the config
+	assert.Equal(t, "********", dump["api"].(map[string]interface{})["api_key"])
+	assert.NotContains(t, landing["test-sample"], "ProgramsRaw")
+
+	Reset()
+	buf.Reset()
+	require.NoError(t, DumpJSON(&buf))
+	assert.Equal(t, "null\n", buf.String())
+}
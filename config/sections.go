@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	sectionTypes   = map[string]reflect.Type{}
+	sectionTargets = map[string]interface{}{}
+	// The sections whose targets have been filled, which only happens once
+	sectionFilled = map[string]bool{}
+	sectionsLock  sync.RWMutex
+)
+
+// RegisterSection adds a service's own settings to the config.  Everything
+// under the name, e.g. `scheduler/batch_size` in the parameter store or the
+// `scheduler` object in a JSON config, is decoded into the target, which must
+// be a pointer to a struct.  Fields are matched with `mapstructure` tags, and
+// strings are converted to numbers, booleans, durations, byte sizes, URLs and
+// comma separated lists as needed.
+//
+// The target is only filled in by the first load, for start up code, since
+// other goroutines may be reading it by the time the config is reloaded.
+// Code that should see reloads uses CurrentSection or Current().Section(name)
+// instead, which return a copy that belongs to that config snapshot.  This is
+// meant to be called during service start up, before the config is loaded.
+func RegisterSection(name string, target interface{}) {
+	t := reflect.TypeOf(target)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("config: section %s must be a pointer to a struct", name))
+	}
+	for _, f := range treeFields(reflect.TypeOf(Config{})) {
+		if f.name == name {
+			panic(fmt.Sprintf("config: section %s is already part of the config", name))
+		}
+	}
+	sectionsLock.Lock()
+	defer sectionsLock.Unlock()
+	sectionTypes[name] = t.Elem()
+	sectionTargets[name] = target
+}
+
+// Section returns the decoded copy of a registered section, as a pointer to
+// the registered type, or nil when the section isn't registered.
+func (c *Config) Section(name string) interface{} {
+	return c.sections[name]
+}
+
+// CurrentSection returns the current config's copy of a registered section,
+// or nil when there's no config yet, or T isn't the section's type.
+//
+//	settings := config.CurrentSection[scheduler.Settings]("scheduler")
+func CurrentSection[T any](name string) *T {
+	c := Current()
+	if c == nil {
+		return nil
+	}
+	section, _ := c.Section(name).(*T)
+	return section
+}
+
+// Decodes every registered section from the config tree.
+func decodeSections(c *Config, tree map[string]interface{}) error {
+	sectionsLock.Lock()
+	defer sectionsLock.Unlock()
+	if len(sectionTypes) == 0 {
+		return nil
+	}
+	c.sections = make(map[string]interface{}, len(sectionTypes))
+	for name, t := range sectionTypes {
+		v := reflect.New(t)
+		if err := decode(tree[name], v.Interface()); err != nil {
+			return fmt.Errorf("config section %s: %w", name, err)
+		}
+		c.sections[name] = v.Interface()
+	}
+	for name, section := range c.sections {
+		if !sectionFilled[name] {
+			reflect.ValueOf(sectionTargets[name]).Elem().Set(reflect.ValueOf(section).Elem())
+			sectionFilled[name] = true
+		}
+	}
+	return nil
+}
+
+// A field of the config tree, by its parameter name.
+type treeField struct {
+	name string
+	typ  reflect.Type
+}
+
+// Lists the fields of a struct in the config tree.  The top level of the
+// config also includes the registered sections.
+func treeFields(t reflect.Type) []treeField {
+	fields := []treeField{}
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.SplitN(t.Field(i).Tag.Get("mapstructure"), ",", 2)[0]
+		if name != "" && name != "-" {
+			fields = append(fields, treeField{name: name, typ: t.Field(i).Type})
+		}
+	}
+	if t == reflect.TypeOf(Config{}) {
+		sectionsLock.RLock()
+		defer sectionsLock.RUnlock()
+		for name, st := range sectionTypes {
+			fields = append(fields, treeField{name: name, typ: st})
+		}
+	}
+	return fields
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type schedulerSettings struct {
+	BatchSize int    `mapstructure:"batch_size"`
+	Enabled   bool   `mapstructure:"enabled"`
+	QueueURL  string `mapstructure:"queue_url"`
+}
+
+func registerTestSection(t *testing.T, name string, target interface{}) {
+	RegisterSection(name, target)
+	t.Cleanup(func() {
+		sectionsLock.Lock()
+		defer sectionsLock.Unlock()
+		delete(sectionTypes, name)
+		delete(sectionTargets, name)
+		delete(sectionFilled, name)
+	})
+}
+
+func TestRegisterSectionFromParams(t *testing.T) {
+	settings := &schedulerSettings{}
+	registerTestSection(t, "scheduler", settings)
+
+	c, err := configFromParams(map[string]string{
+		"common/public_base_uri": "https://example.local",
+		"scheduler/batch_size":   "25",
+		"scheduler/enabled":      "true",
+		"scheduler/queue_url":    "https://sqs.local/queue",
+	})
+	require.NoError(t, err)
+	expected := schedulerSettings{BatchSize: 25, Enabled: true, QueueURL: "https://sqs.local/queue"}
+	assert.Equal(t, expected, *settings)
+	assert.Equal(t, &expected, c.Section("scheduler"))
+	assert.Nil(t, c.Section("missing"))
+
+	_, err = configFromParams(map[string]string{"scheduler/batch_size": "lots"})
+	assert.Error(t, err)
+
+	// Later loads leave the target alone, since it may be being read
+	c, err = configFromParams(map[string]string{"scheduler/batch_size": "50"})
+	require.NoError(t, err)
+	assert.Equal(t, expected, *settings)
+	assert.Equal(t, &schedulerSettings{BatchSize: 50}, c.Section("scheduler"))
+}
+
+func TestRegisterSectionFromJSONAndEnv(t *testing.T) {
+	defer Reset()
+	settings := &schedulerSettings{}
+	registerTestSection(t, "scheduler", settings)
+	t.Setenv("CS_SCHEDULER_QUEUE_URL", "https://sqs.local/env")
+
+	_, err := NewLoader().
+		With(JSONSource(fmt.Sprintf("%s/config/sections.json", testDataDir))).
+		With(EnvSource("CS_")).
+		Load()
+	require.NoError(t, err)
+	assert.Equal(t, schedulerSettings{BatchSize: 50, Enabled: true, QueueURL: "https://sqs.local/env"}, *settings)
+
+	LoadConfigFromJSON(fmt.Sprintf("%s/config/sections.json", testDataDir), configTestLogger())
+	assert.Equal(t, &schedulerSettings{BatchSize: 50, Enabled: true}, Current().Section("scheduler"))
+	assert.Equal(t, &schedulerSettings{BatchSize: 50, Enabled: true}, CurrentSection[schedulerSettings]("scheduler"))
+	assert.Nil(t, CurrentSection[apiSettings]("scheduler"))
+}
+
+func TestRegisterSectionPanics(t *testing.T) {
+	assert.Panics(t, func() { RegisterSection("scheduler", schedulerSettings{}) })
+	assert.Panics(t, func() { RegisterSection("landing", &schedulerSettings{}) })
+}
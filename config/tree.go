@@ -0,0 +1,147 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Builds the config tree from flat parameter names, e.g. `landing/foo/client_id`.
+// A parameter can also hold a JSON object for a whole node, e.g. `landing/foo`,
+// with more specific parameters taking precedence over its keys.
+func treeFromParams(pm map[string]string) (map[string]interface{}, error) {
+	// Sorted so a node's parameter comes before those under it
+	names := make([]string, 0, len(pm))
+	for name := range pm {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tree := map[string]interface{}{}
+	for _, name := range names {
+		ks := strings.Split(name, "/")
+		m := tree
+		for _, k := range ks[:len(ks)-1] {
+			switch child := m[k].(type) {
+			case nil:
+				next := map[string]interface{}{}
+				m[k] = next
+				m = next
+			case map[string]interface{}:
+				m = child
+			case string:
+				next, ok := jsonObject(child)
+				if !ok {
+					return nil, fmt.Errorf("parameter %s is under %s, which isn't a JSON object", name, k)
+				}
+				m[k] = next
+				m = next
+			default:
+				// A number, bool or list from a JSON object above
+				return nil, fmt.Errorf("parameter %s is under %s, which isn't a JSON object", name, k)
+			}
+		}
+		m[ks[len(ks)-1]] = pm[name]
+	}
+	return tree, nil
+}
+
+// Parses parameter values holding JSON objects or arrays, where the config
+// expects an object or list rather than a string.  Lists that aren't JSON are
+// left for the decoder, which splits them on commas, the same as StringList
+// parameters.
+func expandJSONValues(tree map[string]interface{}, t reflect.Type) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		for _, f := range treeFields(t) {
+			if v, ok := tree[f.name]; ok {
+				tree[f.name] = expandJSONValue(v, f.typ)
+			}
+		}
+	case reflect.Map:
+		for k, v := range tree {
+			tree[k] = expandJSONValue(v, t.Elem())
+		}
+	}
+}
+
+func expandJSONValue(v interface{}, t reflect.Type) interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if s, ok := v.(string); ok {
+		if t.Kind() == reflect.String || !looksLikeJSON(s) {
+			return v
+		}
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(s), &parsed); err != nil {
+			// Left for the decoder to report
+			return v
+		}
+		v = parsed
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		expandJSONValues(m, t)
+	}
+	return v
+}
+
+// Programs are decoded separately when they're a tree or parsed JSON, rather
+// than a JSON string, since they aren't keyed the same way as the rest of the
+// config.
+func extractPrograms(tree map[string]interface{}) map[string]interface{} {
+	programs := map[string]interface{}{}
+	landings, _ := tree["landing"].(map[string]interface{})
+	for name, l := range landings {
+		landing, ok := l.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if p, ok := landing["programs"]; ok {
+			if _, isString := p.(string); !isString {
+				programs[name] = p
+				delete(landing, "programs")
+			}
+		}
+	}
+	return programs
+}
+
+// Decodes programs given as an object keyed by name, or a list keyed by
+// organization name.
+func decodePrograms(p interface{}, l *LandingConfig) error {
+	if list, ok := p.([]interface{}); ok {
+		programs := []Program{}
+		if err := decode(list, &programs); err != nil {
+			return err
+		}
+		l.ProgramMap = map[string]Program{}
+		for _, program := range programs {
+			l.ProgramMap[program.OrganizationName] = program
+		}
+		return nil
+	}
+	l.ProgramMap = map[string]Program{}
+	return decode(p, &l.ProgramMap)
+}
+
+func looksLikeJSON(s string) bool {
+	s = strings.TrimSpace(s)
+	return strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[")
+}
+
+func jsonObject(s string) (map[string]interface{}, bool) {
+	if !looksLikeJSON(s) {
+		return nil, false
+	}
+	m := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		return nil, false
+	}
+	return m, true
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type listSettings struct {
+	Hosts   []string          `mapstructure:"hosts"`
+	Ports   []int             `mapstructure:"ports"`
+	Headers map[string]string `mapstructure:"headers"`
+	Name    string            `mapstructure:"name"`
+}
+
+func TestConfigFromParamsStringLists(t *testing.T) {
+	settings := &listSettings{}
+	registerTestSection(t, "lists", settings)
+
+	_, err := configFromParams(map[string]string{
+		"lists/hosts":   "a.local,b.local",
+		"lists/ports":   "[80, 443]",
+		"lists/headers": `{"X-Team": "care"}`,
+		"lists/name":    `{"not": "parsed"}`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.local", "b.local"}, settings.Hosts)
+	assert.Equal(t, []int{80, 443}, settings.Ports)
+	assert.Equal(t, map[string]string{"X-Team": "care"}, settings.Headers)
+	assert.Equal(t, `{"not": "parsed"}`, settings.Name)
+}
+
+func TestConfigFromParamsJSONNodes(t *testing.T) {
+	c, err := configFromParams(map[string]string{
+		"landing/test-sample":          `{"client_id": "oauth.client.id", "password": "therug", "programs": [{"organization_name": "test-org", "organization_id": 987}]}`,
+		"landing/test-sample/password": "thenewrug",
+		"landing/other":                `{"client_id": "other.client.id", "programs": {"other-program": {"organization_name": "other-org", "user_type_id": 3}}}`,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, c.Landing["test-sample"])
+	assert.Equal(t, "oauth.client.id", c.Landing["test-sample"].ClientID)
+	assert.Equal(t, "thenewrug", c.Landing["test-sample"].Password)
+	assert.Equal(t, 987, c.Landing["test-sample"].ProgramMap["test-org"].OrganizationID)
+	assert.Equal(t, 3, c.Landing["other"].ProgramMap["other-program"].UserTypeID)
+}
+
+func TestConfigFromParamsProgramTree(t *testing.T) {
+	c, err := configFromParams(map[string]string{
+		"landing/test-sample/client_id":                               "oauth.client.id",
+		"landing/test-sample/programs/test-program/organization_name": "test-org",
+		"landing/test-sample/programs/test-program/organization_id":   "987",
+		"landing/test-sample/programs/test-program/pro_ids":           "pro1,pro2",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Program{
+		OrganizationName: "test-org",
+		OrganizationID:   987,
+		ProIDs:           []string{"pro1", "pro2"},
+	}, c.Landing["test-sample"].ProgramMap["test-program"])
+}
+
+func TestConfigFromParamsConflicts(t *testing.T) {
+	_, err := configFromParams(map[string]string{
+		"common/public_base_uri":       "https://example.local",
+		"common/public_base_uri/extra": "nope",
+	})
+	assert.EqualError(t, err, "parameter common/public_base_uri/extra is under public_base_uri, which isn't a JSON object")
+
+	_, err = configFromParams(map[string]string{
+		"landing/test-sample/programs/test-program/organization_id": "lots",
+	})
+	assert.Error(t, err)
+
+	// Values from a JSON object can't have parameters under them either
+	for _, value := range []string{`5`, `true`, `["a"]`} {
+		_, err = treeFromParams(map[string]string{
+			"common":                `{"port": ` + value + `}`,
+			"common/port/secondary": "6",
+		})
+		assert.EqualError(t, err, "parameter common/port/secondary is under port, which isn't a JSON object", value)
+	}
+}
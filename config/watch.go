@@ -0,0 +1,137 @@
+package config
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+// ParamDiff lists the parameter names that changed between two reads of the
+// parameter store.  Values are deliberately left out, since many of them
+// are secrets.
+type ParamDiff struct {
+	Added   []string
+	Changed []string
+	Removed []string
+}
+
+func (d ParamDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Removed) == 0
+}
+
+// ChangeFunc is called after a reload swaps in a new config.
+type ChangeFunc func(old, new *Config, diff ParamDiff)
+
+var (
+	changeCallbacks     []ChangeFunc
+	changeCallbacksLock sync.RWMutex
+)
+
+// RegisterChangeCallback adds a callback that is run for every config reload.
+func RegisterChangeCallback(fn ChangeFunc) {
+	changeCallbacksLock.Lock()
+	defer changeCallbacksLock.Unlock()
+	changeCallbacks = append(changeCallbacks, fn)
+}
+
+// WatchParamStore re-reads the parameter store every interval, and swaps in
+// the new config when anything has changed.  The registered callbacks, and
+// onChange if it isn't nil, are then run with the differences.  Watching stops
+// when the context is done.  Failed reloads are logged with the context logger,
+// and the current config is kept.  An error is only returned when the SSM
+// client can't be created.
+func WatchParamStore(ctx context.Context, region, path string, interval time.Duration, onChange ChangeFunc) error {
+	svc, err := newSSMClient(ctx, region)
+	if err != nil {
+		return err
+	}
+	go func() {
+		logger := velacontext.GetContextLogger(ctx)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			params, warnings, err := readParams(ctx, svc, path, ParamStoreOptions{})
+			if err != nil {
+				logger.Error("Config reload failed", zap.Error(err))
+				continue
+			}
+			for _, w := range warnings {
+				logger.Warn("Config partially reloaded", zap.String("warning", w))
+			}
+			if err := applyParams(params, onChange); err != nil {
+				logger.Error("Config reload failed, bad config", zap.Error(err))
+			}
+		}
+	}()
+	return nil
+}
+
+// Builds the config from freshly read parameters, and swaps it in when they've
+// changed.  The current config is kept when the new one can't be built.
+func applyParams(params map[string]string, onChange ChangeFunc) error {
+	var last map[string]string
+	if lp := loadedParams.Load(); lp != nil {
+		last = *lp
+	}
+	diff := diffParams(last, params)
+	if Current() != nil && diff.IsEmpty() {
+		markChecked()
+		return nil
+	}
+	c, err := configFromParams(params)
+	if err != nil {
+		return err
+	}
+	loadedParams.Store(&params)
+	swapConfig(c, diff, onChange)
+	return nil
+}
+
+// Stores the new config, and lets everyone know about it.
+func swapConfig(c *Config, diff ParamDiff, onChange ChangeFunc) {
+	old := Current()
+	storeConfig(c)
+
+	changeCallbacksLock.RLock()
+	callbacks := append([]ChangeFunc{}, changeCallbacks...)
+	changeCallbacksLock.RUnlock()
+	if onChange != nil {
+		callbacks = append(callbacks, onChange)
+	}
+	for _, fn := range callbacks {
+		fn(old, c, diff)
+	}
+	notifySubscribers(old, c)
+}
+
+func diffParams(old, new map[string]string) ParamDiff {
+	diff := ParamDiff{}
+	for k, v := range new {
+		ov, ok := old[k]
+		if !ok {
+			diff.Added = append(diff.Added, k)
+		} else if ov != v {
+			diff.Changed = append(diff.Changed, k)
+		}
+	}
+	for k := range old {
+		if _, ok := new[k]; !ok {
+			diff.Removed = append(diff.Removed, k)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Changed)
+	sort.Strings(diff.Removed)
+	return diff
+}
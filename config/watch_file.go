@@ -0,0 +1,86 @@
+package config
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+// Editors and config agents often write a file in several steps, so reloads
+// wait for the writes to settle.
+var fileReloadDelay = 100 * time.Millisecond
+
+// WatchJSONFile reloads a JSON config file, in the same format as
+// LoadConfigFromJSON, whenever it changes.  Like WatchParamStore, the new
+// config is only swapped in when it parses and decodes, and the registered
+// callbacks, and onChange if it isn't nil, are then run with the differences.
+// The directory is watched rather than the file, so files that are replaced,
+// e.g. by renaming a new file over them or swapping a mounted volume's
+// symlink, are still picked up.  Watching stops when the context is done, and
+// failed reloads are logged with the context logger.
+func WatchJSONFile(ctx context.Context, path string, onChange ChangeFunc) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return err
+	}
+	delay := fileReloadDelay
+	go func() {
+		defer watcher.Close()
+		logger := velacontext.GetContextLogger(ctx)
+		reload := time.NewTimer(delay)
+		reload.Stop()
+		defer reload.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Error("Config file watch failed", zap.Error(err))
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if isConfigFileEvent(event, path) {
+					reload.Reset(delay)
+				}
+			case <-reload.C:
+				params, err := JSONSource(path).Params()
+				if err == nil {
+					err = applyParams(params, onChange)
+				}
+				if err != nil {
+					logger.Error("Config reload failed", zap.String("path", path), zap.Error(err))
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Whether an event in the config file's directory could have changed it.
+// Kubernetes style mounts swap a `..data` symlink rather than touching the
+// file itself.
+func isConfigFileEvent(event fsnotify.Event, path string) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	name := filepath.Clean(event.Name)
+	return name == path || filepath.Base(name) == "..data"
+}
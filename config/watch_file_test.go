@@ -0,0 +1,82 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const watchedJSON = `{
+  "common": {"public_base_uri": "https://example.local"},
+  "landing": {"test-sample": {"client_id": "oauth.client.id", "password": "%s"}}
+}`
+
+func writeConfigFile(t *testing.T, path, password string) {
+	// Written elsewhere and renamed over the config, like most tools do
+	tmp := path + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, []byte(fmt.Sprintf(watchedJSON, password)), 0600))
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+func TestWatchJSONFile(t *testing.T) {
+	defer Reset()
+	fileReloadDelay = 10 * time.Millisecond
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeConfigFile(t, path, "therug")
+	LoadConfigFromJSON(path, configTestLogger())
+	original := Current()
+
+	changes := make(chan ParamDiff, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, WatchJSONFile(ctx, path, func(old, new *Config, diff ParamDiff) {
+		assert.Equal(t, original, old)
+		changes <- diff
+	}))
+
+	// Other files in the directory are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(path), "other.json"), []byte("{}"), 0600))
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, changes)
+
+	writeConfigFile(t, path, "thenewrug")
+	select {
+	case diff := <-changes:
+		assert.Equal(t, []string{"landing/test-sample/password"}, diff.Changed)
+		assert.Empty(t, diff.Added)
+		assert.Empty(t, diff.Removed)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Config was not reloaded")
+	}
+	assert.Equal(t, "thenewrug", Current().Landing["test-sample"].Password)
+}
+
+func TestWatchJSONFileKeepsConfigOnError(t *testing.T) {
+	defer Reset()
+	fileReloadDelay = 10 * time.Millisecond
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeConfigFile(t, path, "therug")
+	LoadConfigFromJSON(path, configTestLogger())
+	original := Current()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, WatchJSONFile(ctx, path, func(old, new *Config, diff ParamDiff) {
+		t.Error("Config should not have been swapped")
+	}))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"common": `), 0600))
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, original, Current())
+}
+
+func TestWatchJSONFileMissingDirectory(t *testing.T) {
+	err := WatchJSONFile(context.Background(), filepath.Join(t.TempDir(), "missing", "config.json"), nil)
+	assert.Error(t, err)
+}
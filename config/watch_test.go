@@ -0,0 +1,188 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Serves parameters from memory, in pages of two to exercise paging.
+type fakeSSM struct {
+	sync.Mutex
+	params   map[string]string
+	err      error
+	throttle int
+	calls    int
+	puts     []*ssm.PutParameterInput
+	// The options GetParametersByPath was last called with
+	options ssm.Options
+}
+
+func (f *fakeSSM) set(name, value string) {
+	f.Lock()
+	defer f.Unlock()
+	f.params[name] = value
+}
+
+func (f *fakeSSM) GetParametersByPath(ctx context.Context, in *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.calls++
+	for _, fn := range optFns {
+		fn(&f.options)
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.throttle > 0 {
+		f.throttle--
+		return nil, &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded"}
+	}
+	names := make([]string, 0, len(f.params))
+	for k := range f.params {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	start := 0
+	if in.NextToken != nil {
+		start, _ = strconv.Atoi(*in.NextToken)
+	}
+	page := &ssm.GetParametersByPathOutput{}
+	for i := start; i < len(names) && i < start+2; i++ {
+		page.Parameters = append(page.Parameters, types.Parameter{
+			Name:  aws.String(*in.Path + names[i]),
+			Value: aws.String(f.params[names[i]]),
+		})
+	}
+	if start+2 < len(names) {
+		page.NextToken = aws.String(strconv.Itoa(start + 2))
+	}
+	return page, nil
+}
+
+func (f *fakeSSM) PutParameter(ctx context.Context, in *ssm.PutParameterInput, optFns ...func(*ssm.Options)) (*ssm.PutParameterOutput, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.calls++
+	if f.throttle > 0 {
+		f.throttle--
+		return nil, &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded"}
+	}
+	for _, put := range f.puts {
+		if *put.Name == *in.Name && !aws.ToBool(in.Overwrite) {
+			return nil, &smithy.GenericAPIError{Code: "ParameterAlreadyExists", Message: "The parameter already exists."}
+		}
+	}
+	f.puts = append(f.puts, in)
+	return &ssm.PutParameterOutput{}, nil
+}
+
+func useFakeSSM(t *testing.T, params map[string]string) *fakeSSM {
+	fake := &fakeSSM{params: params}
+	newSSMClient = func(ctx context.Context, region string) (SSMAPI, error) {
+		return fake, nil
+	}
+	t.Cleanup(func() {
+		newSSMClient = defaultSSMClient
+	})
+	return fake
+}
+
+func testParams() map[string]string {
+	return map[string]string{
+		"common/public_base_uri":        "https://app.dev.alwaysreach.net/public",
+		"landing/test-sample/client_id": "oauth.client.id",
+		"landing/test-sample/username":  "apidude",
+		"landing/test-sample/password":  "therug",
+		"landing/test-sample/programs":  `[{"organization_name": "test-org", "organization_id": 987}]`,
+	}
+}
+
+func TestLoadConfigFromParamStore(t *testing.T) {
+	useFakeSSM(t, testParams())
+	LoadConfigFromParamStore("us-east-1", "/cs-common/", configTestLogger())
+
+	c := Current()
+	require.NotNil(t, c)
+	assert.Equal(t, "https://app.dev.alwaysreach.net/public", c.Common.PublicBaseURI)
+	require.NotNil(t, c.Landing["test-sample"])
+	assert.Equal(t, "therug", c.Landing["test-sample"].Password)
+	assert.Equal(t, 987, c.Landing["test-sample"].ProgramMap["test-org"].OrganizationID)
+}
+
+func TestWatchParamStore(t *testing.T) {
+	fake := useFakeSSM(t, testParams())
+	LoadConfigFromParamStore("us-east-1", "/cs-common/", configTestLogger())
+	original := Current()
+
+	registered := make(chan ParamDiff, 10)
+	RegisterChangeCallback(func(old, new *Config, diff ParamDiff) {
+		registered <- diff
+	})
+	changes := make(chan *Config, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := WatchParamStore(ctx, "us-east-1", "/cs-common/", 10*time.Millisecond, func(old, new *Config, diff ParamDiff) {
+		assert.Equal(t, original, old)
+		changes <- new
+	})
+	require.NoError(t, err)
+
+	// Nothing changes until the parameters do
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, original, Current())
+	assert.Empty(t, changes)
+
+	fake.set("landing/test-sample/password", "thenewrug")
+	fake.set("landing/other/client_id", "other.client.id")
+
+	select {
+	case c := <-changes:
+		assert.Equal(t, "thenewrug", c.Landing["test-sample"].Password)
+		assert.Equal(t, c, Current())
+	case <-time.After(time.Second):
+		t.Fatal("Config was not reloaded")
+	}
+	diff := <-registered
+	assert.Equal(t, []string{"landing/other/client_id"}, diff.Added)
+	assert.Equal(t, []string{"landing/test-sample/password"}, diff.Changed)
+	assert.Empty(t, diff.Removed)
+}
+
+func TestWatchParamStoreKeepsConfigOnError(t *testing.T) {
+	fake := useFakeSSM(t, testParams())
+	LoadConfigFromParamStore("us-east-1", "/cs-common/", configTestLogger())
+	original := Current()
+
+	fake.Lock()
+	fake.err = errors.New("throttled")
+	fake.Unlock()
+	fake.set("landing/test-sample/programs", "not json")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := WatchParamStore(ctx, "us-east-1", "/cs-common/", 10*time.Millisecond, func(old, new *Config, diff ParamDiff) {
+		t.Error("Config should not have been swapped")
+	})
+	require.NoError(t, err)
+	time.Sleep(30 * time.Millisecond)
+
+	fake.Lock()
+	fake.err = nil
+	fake.Unlock()
+	time.Sleep(30 * time.Millisecond)
+	cancel()
+
+	assert.Equal(t, original, Current())
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// WriteToParamStore writes a config to the parameter store under the path, in
+// the same layout the loaders read, e.g. `<path>landing/foo/client_id`.
+// Passwords, secrets and tokens are written as SecureStrings, and everything
+// else as Strings.  This is meant for bootstrapping new environments from a
+// reviewed JSON file:
+//
+//	c, err := config.NewLoader().With(config.JSONSource("stage.json")).Load()
+//	...
+//	err = config.WriteToParamStore(ctx, "/cs-common/", c, config.ParamStoreOptions{Region: "us-east-1"})
+//
+// Parameters are written in name order, and writing stops at the first one
+// that fails.
+func WriteToParamStore(ctx context.Context, path string, cfg *Config, opts ParamStoreOptions) error {
+	svc, err := opts.client(ctx)
+	if err != nil {
+		return err
+	}
+	params := paramsFromConfig(cfg)
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		in := &ssm.PutParameterInput{
+			Name:      aws.String(path + name),
+			Value:     aws.String(params[name]),
+			Type:      types.ParameterTypeString,
+			Overwrite: aws.Bool(opts.Overwrite),
+		}
+		if isSecretField(name[strings.LastIndex(name, "/")+1:]) {
+			in.Type = types.ParameterTypeSecureString
+			if opts.KMSKeyID != "" {
+				in.KeyId = aws.String(opts.KMSKeyID)
+			}
+		}
+		err := withRetries(ctx, opts, func() error {
+			_, err := svc.PutParameter(ctx, in, singleAttempt)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("writing parameter %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Flattens a config into parameter names, the reverse of configFromParams.
+// Empty strings are left out, since the parameter store doesn't allow them.
+func paramsFromConfig(c *Config) map[string]string {
+	pm := map[string]string{}
+	if c == nil {
+		return pm
+	}
+	flattenValue(reflect.ValueOf(*c), nil, pm)
+	// Written in the current layout, whatever version it was loaded from
+	pm[configVersionKey] = strconv.Itoa(CurrentConfigVersion)
+	for name, l := range c.Landing {
+		// Programs only loaded from JSON need writing as JSON
+		if l != nil && l.ProgramsRaw == "" && len(l.ProgramMap) > 0 {
+			d, _ := json.Marshal(l.ProgramMap)
+			pm["landing/"+name+"/programs"] = string(d)
+		}
+	}
+	for name, section := range c.sections {
+		flattenValue(reflect.ValueOf(section), []string{name}, pm)
+	}
+	return pm
+}
+
+func flattenValue(v reflect.Value, path []string, pm map[string]string) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	name := strings.Join(path, "/")
+	switch val := v.Interface().(type) {
+	case time.Duration:
+		pm[name] = val.String()
+		return
+	case ByteSize:
+		pm[name] = strconv.FormatInt(int64(val), 10)
+		return
+	case url.URL:
+		if s := val.String(); s != "" {
+			pm[name] = s
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			fieldName := strings.SplitN(f.Tag.Get("mapstructure"), ",", 2)[0]
+			if fieldName == "-" {
+				continue
+			}
+			if fieldName == "" {
+				fieldName = f.Name
+			}
+			flattenValue(v.Field(i), append(path[:len(path):len(path)], fieldName), pm)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			flattenValue(iter.Value(), append(path[:len(path):len(path)], fmt.Sprint(iter.Key().Interface())), pm)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			return
+		}
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		pm[name] = strings.Join(items, ",")
+	case reflect.String:
+		if v.String() != "" {
+			pm[name] = v.String()
+		}
+	default:
+		pm[name] = fmt.Sprint(v.Interface())
+	}
+}
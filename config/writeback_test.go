@@ -0,0 +1,90 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bootstrapSettings struct {
+	Timeout  time.Duration `mapstructure:"timeout"`
+	MaxBody  ByteSize      `mapstructure:"max_body"`
+	Endpoint *url.URL      `mapstructure:"endpoint"`
+	Hosts    []string      `mapstructure:"hosts"`
+	Enabled  bool          `mapstructure:"enabled"`
+	APIToken string        `mapstructure:"api_token"`
+	Unset    string        `mapstructure:"unset"`
+}
+
+func TestParamsFromConfigRoundTrip(t *testing.T) {
+	registerTestSection(t, "bootstrap", &bootstrapSettings{})
+	params := map[string]string{
+		"config_version":                "2",
+		"common/public_base_uri":        "https://example.local",
+		"common/redirects/old":          "https://example.local/new",
+		"landing/test-sample/client_id": "oauth.client.id",
+		"landing/test-sample/password":  "therug",
+		"landing/test-sample/programs":  `[{"organization_name": "test-org", "organization_id": 987}]`,
+		"bootstrap/timeout":             "1m30s",
+		"bootstrap/max_body":            "1024",
+		"bootstrap/endpoint":            "https://api.local/v1",
+		"bootstrap/hosts":               "a,b",
+		"bootstrap/enabled":             "true",
+		"bootstrap/api_token":           "token",
+	}
+	c, err := configFromParams(params)
+	require.NoError(t, err)
+	assert.Equal(t, params, paramsFromConfig(c))
+}
+
+func TestParamsFromConfigJSONPrograms(t *testing.T) {
+	defer Reset()
+	LoadConfigFromJSON(fmt.Sprintf("%s/config/test.json", testDataDir), configTestLogger())
+
+	pm := paramsFromConfig(Current())
+	c, err := configFromParams(pm)
+	require.NoError(t, err)
+	assert.Equal(t, Current().Landing["test-sample"].ProgramMap, c.Landing["test-sample"].ProgramMap)
+	assert.Empty(t, paramsFromConfig(nil))
+}
+
+func TestWriteToParamStore(t *testing.T) {
+	fake := &fakeSSM{throttle: 1}
+	c := &Config{
+		Common: CommonConfig{PublicBaseURI: "https://example.local"},
+		Landing: map[string]*LandingConfig{
+			"test-sample": {ClientID: "oauth.client.id", Password: "therug"},
+		},
+	}
+	err := WriteToParamStore(context.Background(), "/cs-common/", c, ParamStoreOptions{
+		Client:     fake,
+		KMSKeyID:   "alias/config",
+		RetryDelay: time.Millisecond,
+	})
+	require.NoError(t, err)
+	require.Len(t, fake.puts, 4)
+
+	assert.Equal(t, "/cs-common/common/public_base_uri", *fake.puts[0].Name)
+	assert.Equal(t, types.ParameterTypeString, fake.puts[0].Type)
+	assert.Nil(t, fake.puts[0].KeyId)
+	assert.Equal(t, "/cs-common/config_version", *fake.puts[1].Name)
+	assert.Equal(t, "2", *fake.puts[1].Value)
+	assert.Equal(t, "/cs-common/landing/test-sample/client_id", *fake.puts[2].Name)
+	assert.Equal(t, "/cs-common/landing/test-sample/password", *fake.puts[3].Name)
+	assert.Equal(t, "therug", *fake.puts[3].Value)
+	assert.Equal(t, types.ParameterTypeSecureString, fake.puts[3].Type)
+	assert.Equal(t, "alias/config", aws.ToString(fake.puts[3].KeyId))
+
+	// Existing parameters are only replaced when asked
+	err = WriteToParamStore(context.Background(), "/cs-common/", c, ParamStoreOptions{Client: fake})
+	assert.EqualError(t, err, "writing parameter common/public_base_uri: api error ParameterAlreadyExists: The parameter already exists.")
+	err = WriteToParamStore(context.Background(), "/cs-common/", c, ParamStoreOptions{Client: fake, Overwrite: true})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,168 @@
+package context
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type (
+	auditActorKey struct{}
+	auditLogKey   struct{}
+)
+
+// AuditActor is who performed an audited action.
+type AuditActor struct {
+	ID             string `json:"id"`
+	Type           string `json:"type"`
+	OrganizationID int64  `json:"organization_id,omitempty"`
+}
+
+// AuditEvent is a single entry in the audit trail.
+type AuditEvent struct {
+	Time      time.Time              `json:"time"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Actor     AuditActor             `json:"actor"`
+	Action    string                 `json:"action"`
+	Target    string                 `json:"target"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// AuditSink is where audit events are written when they're flushed.
+type AuditSink interface {
+	WriteAuditEvents(ctx context.Context, events []AuditEvent) error
+}
+
+// AuditSinkFunc adapts a function to an AuditSink.
+type AuditSinkFunc func(ctx context.Context, events []AuditEvent) error
+
+func (f AuditSinkFunc) WriteAuditEvents(ctx context.Context, events []AuditEvent) error {
+	return f(ctx, events)
+}
+
+// LogAuditSink writes audit events to the context's logger, one line each.
+type LogAuditSink struct{}
+
+func (LogAuditSink) WriteAuditEvents(ctx context.Context, events []AuditEvent) error {
+	logger := GetContextLogger(ctx)
+	for _, event := range events {
+		logger.Info("Audit event", zap.Any("audit", event))
+	}
+	return nil
+}
+
+// The sink events are flushed to, the log until SetAuditSink installs another.
+var auditSink atomic.Value
+
+func init() {
+	auditSink.Store(auditSinkHolder{LogAuditSink{}})
+}
+
+// atomic.Value needs the same concrete type every time.
+type auditSinkHolder struct{ AuditSink }
+
+// SetAuditSink installs the sink audit events are flushed to.  Passing nil
+// restores the log sink.
+func SetAuditSink(sink AuditSink) {
+	if sink == nil {
+		sink = LogAuditSink{}
+	}
+	auditSink.Store(auditSinkHolder{sink})
+}
+
+// The events recorded for a request, waiting to be flushed.
+type auditLog struct {
+	mu     sync.Mutex
+	events []AuditEvent
+}
+
+// WithAuditActor adds the actor audited events are recorded for to the
+// context.  Without one, the actor is the context's identity.
+func WithAuditActor(ctx context.Context, actor AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// GetContextAuditActor returns the actor audit events are recorded for: the
+// one from WithAuditActor, or else a user made from the context's identity.
+func GetContextAuditActor(ctx context.Context) AuditActor {
+	if ctx == nil {
+		return AuditActor{}
+	}
+	if actor, ok := ctx.Value(auditActorKey{}).(AuditActor); ok {
+		return actor
+	}
+	if identity, ok := LookupIdentity(ctx); ok {
+		return AuditActor{ID: identity.UserID, Type: "user", OrganizationID: identity.OrganizationID}
+	}
+	return AuditActor{}
+}
+
+// StartAudit returns a context that buffers the events recorded with it until
+// FlushAudit is called, usually at the end of the request.
+func StartAudit(ctx context.Context) context.Context {
+	return context.WithValue(ctx, auditLogKey{}, &auditLog{})
+}
+
+// RecordAuditEvent records that the context's actor performed the action on
+// the target.  The event is buffered when StartAudit was called on the
+// context, and written to the sink straight away when it wasn't, so it's
+// never lost.
+func RecordAuditEvent(ctx context.Context, action, target string, details map[string]interface{}) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	event := AuditEvent{
+		Time:      time.Now().UTC(),
+		RequestID: GetContextRequestID(ctx),
+		Actor:     GetContextAuditActor(ctx),
+		Action:    action,
+		Target:    target,
+		Details:   details,
+	}
+	if log, ok := ctx.Value(auditLogKey{}).(*auditLog); ok {
+		log.mu.Lock()
+		log.events = append(log.events, event)
+		log.mu.Unlock()
+		return nil
+	}
+	return writeAuditEvents(ctx, []AuditEvent{event})
+}
+
+// Writes the events to the sink.  When the sink fails they're written to the
+// log instead, so the audit trail can still be pieced together from it, and
+// the error is returned.
+func writeAuditEvents(ctx context.Context, events []AuditEvent) error {
+	sink := auditSink.Load().(auditSinkHolder).AuditSink
+	err := sink.WriteAuditEvents(ctx, events)
+	if err != nil {
+		GetContextLogger(ctx).Error("Can't write audit events", zap.Error(err), zap.Int("count", len(events)))
+		if _, ok := sink.(LogAuditSink); !ok {
+			_ = LogAuditSink{}.WriteAuditEvents(ctx, events)
+		}
+	}
+	return err
+}
+
+// FlushAudit writes the events buffered since StartAudit to the sink.  The
+// buffer is emptied even when the sink fails, in which case the events are
+// logged in full and the error returned, so it's safe to defer.
+func FlushAudit(ctx context.Context) error {
+	if ctx == nil {
+		return nil
+	}
+	log, ok := ctx.Value(auditLogKey{}).(*auditLog)
+	if !ok {
+		return nil
+	}
+	log.mu.Lock()
+	events := log.events
+	log.events = nil
+	log.mu.Unlock()
+	if len(events) == 0 {
+		return nil
+	}
+	return writeAuditEvents(Detach(ctx), events)
+}
@@ -0,0 +1,93 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type recordingSink struct {
+	batches [][]AuditEvent
+	err     error
+}
+
+func (s *recordingSink) WriteAuditEvents(ctx context.Context, events []AuditEvent) error {
+	s.batches = append(s.batches, events)
+	return s.err
+}
+
+func TestAuditBuffered(t *testing.T) {
+	sink := &recordingSink{}
+	SetAuditSink(sink)
+	defer SetAuditSink(nil)
+
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	ctx = ContextWithIdentity(ctx, Identity{UserID: "user-1", OrganizationID: 7})
+	ctx = StartAudit(ctx)
+
+	require.NoError(t, RecordAuditEvent(ctx, "profile.read", "profile/42", nil))
+	require.NoError(t, RecordAuditEvent(WithAuditActor(ctx, AuditActor{ID: "sync", Type: "service"}), "profile.update", "profile/42", map[string]interface{}{"field": "email"}))
+	assert.Empty(t, sink.batches)
+
+	require.NoError(t, FlushAudit(ctx))
+	require.Len(t, sink.batches, 1)
+	events := sink.batches[0]
+	require.Len(t, events, 2)
+	assert.Equal(t, "req-1", events[0].RequestID)
+	assert.Equal(t, AuditActor{ID: "user-1", Type: "user", OrganizationID: 7}, events[0].Actor)
+	assert.Equal(t, "profile.read", events[0].Action)
+	assert.Equal(t, AuditActor{ID: "sync", Type: "service"}, events[1].Actor)
+	assert.Equal(t, "email", events[1].Details["field"])
+
+	// Nothing left to flush
+	require.NoError(t, FlushAudit(ctx))
+	assert.Len(t, sink.batches, 1)
+}
+
+func TestAuditUnbuffered(t *testing.T) {
+	sink := &recordingSink{err: errors.New("queue down")}
+	SetAuditSink(sink)
+	defer SetAuditSink(nil)
+
+	err := RecordAuditEvent(context.Background(), "profile.read", "profile/42", nil)
+	assert.Error(t, err)
+	require.Len(t, sink.batches, 1)
+	assert.NoError(t, FlushAudit(context.Background()))
+}
+
+func TestAuditFlushFailure(t *testing.T) {
+	sink := &recordingSink{err: errors.New("queue down")}
+	SetAuditSink(sink)
+	defer SetAuditSink(nil)
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	ctx := StartAudit(ContextWithLogger(context.Background(), zap.New(core)))
+	require.NoError(t, RecordAuditEvent(ctx, "profile.read", "profile/42", nil))
+	assert.Error(t, FlushAudit(ctx))
+	assert.Equal(t, 1, logs.FilterMessage("Can't write audit events").Len())
+
+	// The events are logged in full instead
+	logged := logs.FilterMessage("Audit event").All()
+	require.Len(t, logged, 1)
+	event, ok := logged[0].ContextMap()["audit"].(AuditEvent)
+	require.True(t, ok)
+	assert.Equal(t, "profile.read", event.Action)
+	assert.Equal(t, "profile/42", event.Target)
+
+	// And so are unbuffered ones
+	assert.Error(t, RecordAuditEvent(ContextWithLogger(context.Background(), zap.New(core)), "profile.update", "profile/42", nil))
+	assert.Equal(t, 2, logs.FilterMessage("Audit event").Len())
+}
+
+func TestLogAuditSink(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	ctx := ContextWithLogger(context.Background(), zap.New(core))
+	require.NoError(t, RecordAuditEvent(ctx, "profile.read", "profile/42", nil))
+	assert.Equal(t, 1, logs.FilterMessage("Audit event").Len())
+}
@@ -0,0 +1,113 @@
+// Package auditsink has velacontext.AuditSink implementations that send audit
+// events to an SQS queue or an HTTP API, for services that can't rely on the
+// log alone for their audit trail.
+package auditsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+// SQS accepts at most this many messages in a batch.
+const maxSQSBatch = 10
+
+// DefaultHTTPTimeout bounds each post when HTTPSink.Client isn't set, so a
+// hung audit API can't hold up the requests flushing to it.
+const DefaultHTTPTimeout = 10 * time.Second
+
+var defaultHTTPClient = &http.Client{Timeout: DefaultHTTPTimeout}
+
+// SQSAPI is the part of the SQS client used to send audit events.
+// *sqs.Client implements it.
+type SQSAPI interface {
+	SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error)
+}
+
+var _ SQSAPI = (*sqs.Client)(nil)
+
+// SQSSink sends each audit event to a queue as a JSON message.
+type SQSSink struct {
+	Client   SQSAPI
+	QueueURL string
+}
+
+func (s *SQSSink) WriteAuditEvents(ctx context.Context, events []velacontext.AuditEvent) error {
+	for start := 0; start < len(events); start += maxSQSBatch {
+		end := start + maxSQSBatch
+		if end > len(events) {
+			end = len(events)
+		}
+		entries := make([]types.SendMessageBatchRequestEntry, 0, end-start)
+		for i, event := range events[start:end] {
+			body, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, types.SendMessageBatchRequestEntry{
+				Id:          aws.String(strconv.Itoa(start + i)),
+				MessageBody: aws.String(string(body)),
+			})
+		}
+		out, err := s.Client.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+			QueueUrl: aws.String(s.QueueURL),
+			Entries:  entries,
+		})
+		if err != nil {
+			return err
+		}
+		if len(out.Failed) > 0 {
+			return fmt.Errorf("auditsink: %d of %d audit events weren't queued: %s", len(out.Failed), len(entries), aws.ToString(out.Failed[0].Message))
+		}
+	}
+	return nil
+}
+
+// HTTPSink posts the audit events to an API as a JSON array, passing on the
+// request ID and access token from the context.
+type HTTPSink struct {
+	URL string
+	// Client defaults to one with a DefaultHTTPTimeout timeout.
+	Client *http.Client
+}
+
+func (s *HTTPSink) WriteAuditEvents(ctx context.Context, events []velacontext.AuditEvent) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, "POST", s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if requestID, ok := velacontext.LookupRequestID(ctx); ok {
+		request.Header.Set("X-Vela-Request-Id", requestID)
+	}
+	if token, ok := velacontext.LookupToken(ctx); ok {
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+	client := s.Client
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("auditsink: posting audit events to %s: %s", s.URL, response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package auditsink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+type mockSQS struct {
+	inputs []*sqs.SendMessageBatchInput
+	failed []types.BatchResultErrorEntry
+}
+
+func (m *mockSQS) SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+	m.inputs = append(m.inputs, params)
+	return &sqs.SendMessageBatchOutput{Failed: m.failed}, nil
+}
+
+func events(n int) []velacontext.AuditEvent {
+	events := make([]velacontext.AuditEvent, n)
+	for i := range events {
+		events[i] = velacontext.AuditEvent{Action: "profile.read", Target: "profile/42"}
+	}
+	return events
+}
+
+func TestSQSSink(t *testing.T) {
+	client := &mockSQS{}
+	sink := &SQSSink{Client: client, QueueURL: "https://sqs.example.com/audit"}
+	require.NoError(t, sink.WriteAuditEvents(context.Background(), events(12)))
+
+	require.Len(t, client.inputs, 2)
+	assert.Len(t, client.inputs[0].Entries, 10)
+	assert.Len(t, client.inputs[1].Entries, 2)
+	assert.Equal(t, "https://sqs.example.com/audit", aws.ToString(client.inputs[0].QueueUrl))
+	assert.Equal(t, "11", aws.ToString(client.inputs[1].Entries[1].Id))
+
+	var event velacontext.AuditEvent
+	require.NoError(t, json.Unmarshal([]byte(aws.ToString(client.inputs[0].Entries[0].MessageBody)), &event))
+	assert.Equal(t, "profile.read", event.Action)
+
+	client.failed = []types.BatchResultErrorEntry{{Id: aws.String("0"), Message: aws.String("too big")}}
+	assert.Error(t, sink.WriteAuditEvents(context.Background(), events(1)))
+}
+
+func TestHTTPSink(t *testing.T) {
+	var got []velacontext.AuditEvent
+	var requestID, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID = r.Header.Get("X-Vela-Request-Id")
+		auth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	ctx := velacontext.ContextWithRequestID(context.Background(), "req-1")
+	ctx = velacontext.ContextWithToken(ctx, "token")
+	sink := &HTTPSink{URL: server.URL}
+	require.NoError(t, sink.WriteAuditEvents(ctx, events(2)))
+	assert.Len(t, got, 2)
+	assert.Equal(t, "req-1", requestID)
+	assert.Equal(t, "Bearer token", auth)
+
+	sink.URL = server.URL + "/missing"
+	server.Config.Handler = http.NotFoundHandler()
+	assert.Error(t, sink.WriteAuditEvents(ctx, events(1)))
+}
@@ -0,0 +1,72 @@
+package context
+
+import (
+	"context"
+)
+
+type (
+	tokenKey  struct{}
+	claimsKey struct{}
+)
+
+// Claims are the claims of the request's access token, as decoded from its
+// JWT payload.
+type Claims map[string]interface{}
+
+// String returns the claim as a string, or an empty string when it's missing
+// or isn't one.
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Subject returns the token's "sub" claim.
+func (c Claims) Subject() string {
+	return c.String("sub")
+}
+
+// ContextWithToken adds the request's access token to the context, so calls
+// made for the request can pass it on.
+func ContextWithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey{}, token)
+}
+
+// GetContextToken returns the context's access token, or an empty string when
+// it doesn't have one.
+func GetContextToken(ctx context.Context) (token string) {
+	token, _ = LookupToken(ctx)
+	return
+}
+
+// LookupToken returns the context's access token, and whether it has one.
+// It's safe to call with a nil context.
+func LookupToken(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	token, ok := ctx.Value(tokenKey{}).(string)
+	return token, ok && token != ""
+}
+
+// ContextWithClaims adds the claims of the request's access token to the
+// context.  Only add claims from a token that's been verified.
+func ContextWithClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// GetContextClaims returns the context's token claims, or nil when it doesn't
+// have any.
+func GetContextClaims(ctx context.Context) (claims Claims) {
+	claims, _ = LookupClaims(ctx)
+	return
+}
+
+// LookupClaims returns the context's token claims, and whether it has them.
+// It's safe to call with a nil context.
+func LookupClaims(ctx context.Context) (Claims, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	claims, ok := ctx.Value(claimsKey{}).(Claims)
+	return claims, ok && claims != nil
+}
@@ -0,0 +1,31 @@
+package context
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToken(t *testing.T) {
+	ctx := ContextWithToken(context.Background(), "abc.def.ghi")
+	assert.Equal(t, "abc.def.ghi", GetContextToken(ctx))
+
+	_, ok := LookupToken(ContextWithToken(context.Background(), ""))
+	assert.False(t, ok)
+	_, ok = LookupToken(nil)
+	assert.False(t, ok)
+}
+
+func TestClaims(t *testing.T) {
+	ctx := ContextWithClaims(context.Background(), Claims{"sub": "user-1", "exp": float64(1700000000)})
+	claims, ok := LookupClaims(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "user-1", claims.Subject())
+	assert.Empty(t, claims.String("exp"))
+	assert.Empty(t, claims.String("missing"))
+
+	assert.Nil(t, GetContextClaims(context.Background()))
+	_, ok = LookupClaims(nil)
+	assert.False(t, ok)
+}
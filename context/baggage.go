@@ -0,0 +1,55 @@
+package context
+
+import (
+	"context"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// BaggageHeaderPrefix starts the names of the headers baggage is passed
+// between services in, one header per entry.
+const BaggageHeaderPrefix = "X-Vela-Baggage-"
+
+type baggageKey struct{}
+
+// SetBaggage returns a context with the baggage entry added, replacing any
+// with the same key.  Baggage is passed on to other Vela services by the
+// client package and included in the context's logger, so keep it small and
+// free of PHI.  Keys are lower cased, since they travel in header names.
+func SetBaggage(ctx context.Context, key, value string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	existing := Baggage(ctx)
+	baggage := make(map[string]string, len(existing)+1)
+	for k, v := range existing {
+		baggage[k] = v
+	}
+	baggage[strings.ToLower(key)] = value
+	return context.WithValue(ctx, baggageKey{}, baggage)
+}
+
+// Baggage returns the context's baggage.  The map is shared by every context
+// derived from the one it was set on, so don't change it.
+func Baggage(ctx context.Context) map[string]string {
+	if ctx == nil {
+		return nil
+	}
+	baggage, _ := ctx.Value(baggageKey{}).(map[string]string)
+	return baggage
+}
+
+// GetBaggage returns a baggage entry, or an empty string when there isn't one.
+func GetBaggage(ctx context.Context, key string) string {
+	return Baggage(ctx)[strings.ToLower(key)]
+}
+
+// The baggage as a logging field, or none when there isn't any.
+func baggageFields(ctx context.Context) []zap.Field {
+	baggage := Baggage(ctx)
+	if len(baggage) == 0 {
+		return nil
+	}
+	return []zap.Field{zap.Any("baggage", baggage)}
+}
@@ -0,0 +1,34 @@
+package context
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestBaggage(t *testing.T) {
+	assert.Empty(t, Baggage(context.Background()))
+	assert.Empty(t, Baggage(nil))
+
+	parent := SetBaggage(context.Background(), "Checkout-Variant", "b")
+	child := SetBaggage(parent, "region", "east")
+	assert.Equal(t, map[string]string{"checkout-variant": "b"}, Baggage(parent))
+	assert.Equal(t, map[string]string{"checkout-variant": "b", "region": "east"}, Baggage(child))
+	assert.Equal(t, "b", GetBaggage(child, "CHECKOUT-VARIANT"))
+	assert.Empty(t, GetBaggage(child, "missing"))
+}
+
+func TestLoggerIncludesBaggage(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	ctx := ContextWithLogger(context.Background(), zap.New(core))
+	ctx = SetBaggage(ctx, "variant", "b")
+
+	GetContextLogger(ctx).Info("hello")
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, map[string]string{"variant": "b"}, logs.All()[0].ContextMap()["baggage"])
+}
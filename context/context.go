@@ -2,33 +2,135 @@ package context
 
 import (
 	"context"
+	"sync/atomic"
 
 	"go.uber.org/zap"
 )
 
-const (
-	requestIDKey = "request-id"
-	loggerKey    = "logger"
+// Keys are unexported types, so values set here can't collide with other
+// packages using the same names.
+type (
+	requestIDKey    struct{}
+	loggerKey       struct{}
+	loggerFieldsKey struct{}
 )
 
+// The logger GetContextLogger falls back to, a no-op one until
+// SetFallbackLogger installs another.
+var fallbackLogger atomic.Pointer[zap.Logger]
+
+func init() {
+	fallbackLogger.Store(zap.NewNop())
+}
+
+// SetFallbackLogger installs the logger GetContextLogger returns for contexts
+// without one, such as the service's root logger.  Passing nil restores the
+// no-op logger.
+func SetFallbackLogger(logger *zap.Logger) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	fallbackLogger.Store(logger)
+}
+
+// FallbackLogger returns the logger GetContextLogger uses for contexts
+// without one.
+func FallbackLogger() *zap.Logger {
+	return fallbackLogger.Load()
+}
+
+// GetContextLogger returns the context's logger, or the fallback logger when
+// it doesn't have one, so it's never nil.  When the context has an identity,
+// the logger includes its IDs, and when it's being traced, the trace and span
+// IDs.  Then come any baggage and fields from WithLoggerFields.
 func GetContextLogger(ctx context.Context) (logger *zap.Logger) {
-	if val := ctx.Value(loggerKey); val != nil {
-		logger, _ = val.(*zap.Logger)
+	logger, ok := LookupLogger(ctx)
+	if !ok {
+		logger = FallbackLogger()
+	}
+	if identity, ok := LookupIdentity(ctx); ok {
+		logger = logger.With(identity.fields()...)
+	}
+	if fields := traceFields(ctx); len(fields) > 0 {
+		logger = logger.With(fields...)
+	}
+	if fields := baggageFields(ctx); len(fields) > 0 {
+		logger = logger.With(fields...)
+	}
+	if fields := loggerFields(ctx); len(fields) > 0 {
+		logger = logger.With(fields...)
 	}
 	return
 }
 
-func GetContextRequestID(ctx context.Context) (requestID string) {
-	if val := ctx.Value(requestIDKey); val != nil {
-		requestID, _ = val.(string)
+// WithLoggerFields returns a context whose logger from GetContextLogger
+// includes the fields, on top of any added before.  The first call also adds
+// the request ID, so every line logged for the request can be correlated.  The
+// identity's IDs are always included, they don't need to be added here.
+func WithLoggerFields(ctx context.Context, fields ...zap.Field) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	existing := loggerFields(ctx)
+	combined := make([]zap.Field, 0, len(existing)+len(fields)+1)
+	combined = append(combined, existing...)
+	if len(existing) == 0 {
+		if requestID, ok := LookupRequestID(ctx); ok {
+			combined = append(combined, zap.String("request_id", requestID))
+		}
 	}
+	combined = append(combined, fields...)
+	return context.WithValue(ctx, loggerFieldsKey{}, combined)
+}
+
+func loggerFields(ctx context.Context) []zap.Field {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(loggerFieldsKey{}).([]zap.Field)
+	return fields
+}
+
+func GetContextRequestID(ctx context.Context) (requestID string) {
+	requestID, _ = LookupRequestID(ctx)
 	return
 }
 
+// LookupLogger returns the context's logger, and whether it has one.  It's
+// safe to call with a nil context.
+func LookupLogger(ctx context.Context) (*zap.Logger, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	logger, ok := ctx.Value(loggerKey{}).(*zap.Logger)
+	return logger, ok && logger != nil
+}
+
+// LookupRequestID returns the context's request ID, and whether it has one.
+// It's safe to call with a nil context.
+func LookupRequestID(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	requestID, ok := ctx.Value(requestIDKey{}).(string)
+	return requestID, ok && requestID != ""
+}
+
 func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
-	return context.WithValue(ctx, requestIDKey, requestID)
+	return context.WithValue(ctx, requestIDKey{}, requestID)
 }
 
 func ContextWithLogger(ctx context.Context, logger *zap.Logger) context.Context {
-	return context.WithValue(ctx, loggerKey, logger)
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// Detach returns a context with the same values, such as the logger and
+// request ID, but without the deadline or cancellation.  Use it for work that
+// has to outlive the request, like flushing audit records after the response
+// is sent, so the values are still there once the request's context is done.
+func Detach(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return context.WithoutCancel(ctx)
 }
@@ -0,0 +1,106 @@
+package context
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRequestID(t *testing.T) {
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	assert.Equal(t, "req-1", GetContextRequestID(ctx))
+	id, ok := LookupRequestID(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "req-1", id)
+
+	// Plain string keys don't reach the typed ones
+	ctx = context.WithValue(context.Background(), "request-id", "req-2")
+	assert.Empty(t, GetContextRequestID(ctx))
+	_, ok = LookupRequestID(ctx)
+	assert.False(t, ok)
+
+	_, ok = LookupRequestID(nil)
+	assert.False(t, ok)
+}
+
+func TestLogger(t *testing.T) {
+	logger := zap.NewNop()
+	ctx := ContextWithLogger(context.Background(), logger)
+	assert.Equal(t, logger, GetContextLogger(ctx))
+	l, ok := LookupLogger(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, logger, l)
+
+	_, ok = LookupLogger(ContextWithLogger(context.Background(), nil))
+	assert.False(t, ok)
+	assert.NotNil(t, GetContextLogger(context.Background()))
+	assert.NotNil(t, GetContextLogger(nil))
+}
+
+func TestFallbackLogger(t *testing.T) {
+	defer SetFallbackLogger(nil)
+
+	fallback := zap.NewExample()
+	SetFallbackLogger(fallback)
+	assert.Equal(t, fallback, GetContextLogger(context.Background()))
+	assert.Equal(t, fallback, FallbackLogger())
+
+	// The context's own logger still wins
+	logger := zap.NewNop()
+	assert.Equal(t, logger, GetContextLogger(ContextWithLogger(context.Background(), logger)))
+
+	SetFallbackLogger(nil)
+	assert.NotNil(t, FallbackLogger())
+	assert.NotEqual(t, fallback, FallbackLogger())
+}
+
+func TestWithLoggerFields(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	ctx := ContextWithLogger(context.Background(), zap.New(core))
+	ctx = ContextWithRequestID(ctx, "req-1")
+	ctx = ContextWithIdentity(ctx, Identity{UserID: "user-1", OrganizationID: 12})
+
+	ctx = WithLoggerFields(ctx, zap.String("step", "one"))
+	child := WithLoggerFields(ctx, zap.Int("attempt", 2))
+	GetContextLogger(ctx).Info("parent")
+	GetContextLogger(child).Info("child")
+
+	require.Equal(t, 2, logs.Len())
+	assert.Equal(t, map[string]interface{}{
+		"request_id":      "req-1",
+		"user_id":         "user-1",
+		"organization_id": int64(12),
+		"step":            "one",
+	}, logs.All()[0].ContextMap())
+	assert.Equal(t, map[string]interface{}{
+		"request_id":      "req-1",
+		"user_id":         "user-1",
+		"organization_id": int64(12),
+		"step":            "one",
+		"attempt":         int64(2),
+	}, logs.All()[1].ContextMap())
+	assert.Len(t, logs.All()[1].Context, 5)
+
+	assert.NotNil(t, WithLoggerFields(nil, zap.String("a", "b")))
+}
+
+func TestDetach(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	ctx = ContextWithRequestID(ctx, "req-1")
+	<-ctx.Done()
+
+	detached := Detach(ctx)
+	assert.NoError(t, detached.Err())
+	_, hasDeadline := detached.Deadline()
+	assert.False(t, hasDeadline)
+	assert.Equal(t, "req-1", GetContextRequestID(detached))
+
+	assert.NotNil(t, Detach(nil))
+}
@@ -0,0 +1,125 @@
+// Package contexttest builds contexts with the velacontext values already
+// set, for handler tests:
+//
+//	ctx, logs := contexttest.NewTestContext(t, contexttest.WithRequestID("abc"), contexttest.WithObservedLogger())
+//	handle(ctx)
+//	assert.Equal(t, 1, logs.FilterField(zap.String("request_id", "abc")).Len())
+package contexttest
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+type builder struct {
+	t    testing.TB
+	ctx  context.Context
+	logs *observer.ObservedLogs
+}
+
+// Option sets a value on the test context.
+type Option func(*builder)
+
+// NewTestContext returns a context with the options applied, in order, and
+// the observed logs when WithObservedLogger is one of them.  Otherwise the
+// context logs to the test's output.  The context is cancelled when the test
+// finishes.
+func NewTestContext(t testing.TB, opts ...Option) (context.Context, *observer.ObservedLogs) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	b := &builder{
+		t:   t,
+		ctx: velacontext.ContextWithLogger(ctx, zaptest.NewLogger(t)),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b.ctx, b.logs
+}
+
+// WithRequestID sets the request ID.
+func WithRequestID(requestID string) Option {
+	return func(b *builder) {
+		b.ctx = velacontext.ContextWithRequestID(b.ctx, requestID)
+	}
+}
+
+// WithObservedLogger replaces the logger with one that records everything
+// logged at debug level and up, for NewTestContext to return.
+func WithObservedLogger() Option {
+	return func(b *builder) {
+		core, logs := observer.New(zapcore.DebugLevel)
+		b.ctx = velacontext.ContextWithLogger(b.ctx, zap.New(core))
+		b.logs = logs
+	}
+}
+
+// WithLoggerFields adds fields to the context's logger, along with the request
+// ID when it's been set.
+func WithLoggerFields(fields ...zap.Field) Option {
+	return func(b *builder) {
+		b.ctx = velacontext.WithLoggerFields(b.ctx, fields...)
+	}
+}
+
+// WithIdentity sets the identity.
+func WithIdentity(identity velacontext.Identity) Option {
+	return func(b *builder) {
+		b.ctx = velacontext.ContextWithIdentity(b.ctx, identity)
+	}
+}
+
+// WithToken sets the access token.
+func WithToken(token string) Option {
+	return func(b *builder) {
+		b.ctx = velacontext.ContextWithToken(b.ctx, token)
+	}
+}
+
+// WithClaims sets the access token's claims.
+func WithClaims(claims velacontext.Claims) Option {
+	return func(b *builder) {
+		b.ctx = velacontext.ContextWithClaims(b.ctx, claims)
+	}
+}
+
+// WithLocale sets the locale.
+func WithLocale(locale string) Option {
+	return func(b *builder) {
+		b.ctx = velacontext.ContextWithLocale(b.ctx, locale)
+	}
+}
+
+// WithTimeZone sets the time zone by its IANA name, failing the test when it
+// isn't one.
+func WithTimeZone(name string) Option {
+	return func(b *builder) {
+		ctx, err := velacontext.ContextWithTimeZone(b.ctx, name)
+		if err != nil {
+			b.t.Fatalf("contexttest: %v", err)
+		}
+		b.ctx = ctx
+	}
+}
+
+// WithBaggage adds a baggage entry.
+func WithBaggage(key, value string) Option {
+	return func(b *builder) {
+		b.ctx = velacontext.SetBaggage(b.ctx, key, value)
+	}
+}
+
+// WithAudit starts buffering audit events, as the audit middleware does.
+func WithAudit() Option {
+	return func(b *builder) {
+		b.ctx = velacontext.StartAudit(b.ctx)
+	}
+}
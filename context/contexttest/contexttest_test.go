@@ -0,0 +1,47 @@
+package contexttest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+func TestNewTestContext(t *testing.T) {
+	ctx, logs := NewTestContext(t,
+		WithRequestID("abc"),
+		WithObservedLogger(),
+		WithLoggerFields(zap.String("handler", "profiles")),
+		WithIdentity(velacontext.Identity{UserID: "user-1"}),
+		WithToken("token"),
+		WithLocale("es-US"),
+		WithTimeZone("America/Chicago"),
+		WithBaggage("variant", "b"),
+	)
+	require.NotNil(t, logs)
+
+	assert.Equal(t, "abc", velacontext.GetContextRequestID(ctx))
+	assert.Equal(t, "user-1", velacontext.GetContextUserID(ctx))
+	assert.Equal(t, "token", velacontext.GetContextToken(ctx))
+	assert.Equal(t, "es-US", velacontext.GetContextLocale(ctx))
+	assert.Equal(t, "America/Chicago", velacontext.GetContextLocation(ctx).String())
+	assert.Equal(t, "b", velacontext.GetBaggage(ctx, "variant"))
+
+	velacontext.GetContextLogger(ctx).Debug("hello")
+	require.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, "abc", fields["request_id"])
+	assert.Equal(t, "profiles", fields["handler"])
+	assert.Equal(t, "user-1", fields["user_id"])
+}
+
+func TestNewTestContextDefaults(t *testing.T) {
+	ctx, logs := NewTestContext(t)
+	assert.Nil(t, logs)
+	_, ok := velacontext.LookupLogger(ctx)
+	assert.True(t, ok)
+	assert.NoError(t, ctx.Err())
+}
@@ -0,0 +1,52 @@
+package context
+
+import (
+	"context"
+	"time"
+)
+
+// WithTimeoutIfNone gives the context a timeout of d, unless it already has a
+// deadline, which is kept whether it's sooner or later.  The cancel function
+// must be called either way.
+func WithTimeoutIfNone(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// RemainingBudget returns how long is left before the context's deadline, and
+// whether it has one.  It's zero or less once the deadline has passed.  In a
+// Lambda function, the deadline is when the invocation times out.
+func RemainingBudget(ctx context.Context) (time.Duration, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	return time.Until(deadline), true
+}
+
+// WithReserve returns a context for an outbound call whose deadline is reserve
+// before the context's own, leaving that long to handle a slow or failed call
+// and still respond.  When there's no deadline, the context just gets a cancel
+// function.  When less than reserve is left, the returned context is already
+// done.  The cancel function must be called either way.
+//
+//	callCtx, cancel := velacontext.WithReserve(ctx, 500*time.Millisecond)
+//	defer cancel()
+func WithReserve(ctx context.Context, reserve time.Duration) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, deadline.Add(-reserve))
+}
@@ -0,0 +1,60 @@
+package context
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTimeoutIfNone(t *testing.T) {
+	ctx, cancel := WithTimeoutIfNone(context.Background(), time.Minute)
+	defer cancel()
+	remaining, ok := RemainingBudget(ctx)
+	assert.True(t, ok)
+	assert.InDelta(t, time.Minute, remaining, float64(time.Second))
+
+	// An existing deadline is kept, even a later one
+	parent, cancelParent := context.WithTimeout(context.Background(), time.Hour)
+	defer cancelParent()
+	ctx, cancel = WithTimeoutIfNone(parent, time.Minute)
+	defer cancel()
+	remaining, _ = RemainingBudget(ctx)
+	assert.Greater(t, remaining, 59*time.Minute)
+}
+
+func TestRemainingBudget(t *testing.T) {
+	_, ok := RemainingBudget(context.Background())
+	assert.False(t, ok)
+	_, ok = RemainingBudget(nil)
+	assert.False(t, ok)
+
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	remaining, ok := RemainingBudget(ctx)
+	assert.True(t, ok)
+	assert.LessOrEqual(t, remaining, time.Duration(0))
+}
+
+func TestWithReserve(t *testing.T) {
+	parent, cancelParent := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelParent()
+	parentDeadline, _ := parent.Deadline()
+
+	ctx, cancel := WithReserve(parent, 2*time.Second)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.Equal(t, parentDeadline.Add(-2*time.Second), deadline)
+
+	// Not enough left, so the call's context is already done
+	ctx, cancel = WithReserve(parent, time.Minute)
+	defer cancel()
+	assert.Error(t, ctx.Err())
+
+	ctx, cancel = WithReserve(context.Background(), time.Second)
+	defer cancel()
+	_, ok = ctx.Deadline()
+	assert.False(t, ok)
+}
@@ -0,0 +1,158 @@
+// Package httpmiddleware wires the velacontext values into net/http requests,
+// so every service sets up the request ID and logger the same way.
+package httpmiddleware
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+// RequestIDHeader carries the request ID between Vela services.
+const RequestIDHeader = "X-Vela-Request-Id"
+
+// TimeZoneHeader carries the end user's IANA time zone, for clients that know
+// it before the profile's been loaded.
+const TimeZoneHeader = "X-Vela-Time-Zone"
+
+// Longer incoming request IDs are replaced, so a caller can't stuff every log
+// line with whatever it likes.
+const maxRequestIDLength = 128
+
+// Incoming baggage is logged and passed on to other services, so callers only
+// get to send so much of it.  Entries past these limits are dropped.
+const (
+	maxBaggageEntries     = 16
+	maxBaggageKeyLength   = 64
+	maxBaggageValueLength = 256
+)
+
+// RequestIDFromHeader returns the request ID from an incoming
+// X-Vela-Request-Id header, or a new one when it's missing or too long.
+func RequestIDFromHeader(value string) string {
+	if value == "" || len(value) > maxRequestIDLength {
+		return velacontext.NewRequestID()
+	}
+	return value
+}
+
+// RequestContext returns middleware that adds a request ID and logger to each
+// request's context.  The ID is taken from the X-Vela-Request-Id header, or
+// generated when there isn't one, and is echoed on the response.  The logger is
+// the given one named name, or the fallback logger when it's nil, and it
+// includes the request ID, method and path.
+func RequestContext(logger *zap.Logger, name string) func(http.Handler) http.Handler {
+	if logger == nil {
+		logger = velacontext.FallbackLogger()
+	}
+	if name != "" {
+		logger = logger.Named(name)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := RequestIDFromHeader(r.Header.Get(RequestIDHeader))
+			w.Header().Set(RequestIDHeader, requestID)
+
+			ctx := velacontext.ContextWithRequestID(r.Context(), requestID)
+			ctx = velacontext.ContextWithLogger(ctx, logger)
+			ctx = BaggageFromHeader(ctx, r.Header)
+			ctx = velacontext.WithLoggerFields(ctx,
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+			)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// BaggageFromHeader adds the baggage in the X-Vela-Baggage-* headers of an
+// incoming request to the context.  Only the first 16 entries, in key order,
+// are kept, and entries with keys over 64 bytes or values over 256 are
+// dropped, since a caller could otherwise fill every log line and outgoing
+// request with whatever it likes.
+func BaggageFromHeader(ctx context.Context, header http.Header) context.Context {
+	var keys []string
+	values := map[string]string{}
+	for name, v := range header {
+		name = http.CanonicalHeaderKey(name)
+		key := strings.TrimPrefix(name, velacontext.BaggageHeaderPrefix)
+		if key == name || key == "" || len(v) == 0 {
+			continue
+		}
+		if len(key) > maxBaggageKeyLength || len(v[0]) > maxBaggageValueLength {
+			continue
+		}
+		keys = append(keys, key)
+		values[key] = v[0]
+	}
+	sort.Strings(keys)
+	if len(keys) > maxBaggageEntries {
+		keys = keys[:maxBaggageEntries]
+	}
+	for _, key := range keys {
+		ctx = velacontext.SetBaggage(ctx, key, values[key])
+	}
+	return ctx
+}
+
+// Localization returns middleware that adds the end user's locale, from the
+// first language in the Accept-Language header, and time zone, from the
+// X-Vela-Time-Zone header, to each request's context.  Headers that are
+// missing or unusable are ignored.
+func Localization() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if locale := AcceptLanguageLocale(r.Header.Get("Accept-Language")); locale != "" {
+				ctx = velacontext.ContextWithLocale(ctx, locale)
+			}
+			if zone := r.Header.Get(TimeZoneHeader); zone != "" {
+				ctx, _ = velacontext.ContextWithTimeZone(ctx, zone)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// AcceptLanguageLocale returns the first language in an Accept-Language
+// header, such as "en-US" for "en-US,en;q=0.9", or an empty string when there
+// isn't one.
+func AcceptLanguageLocale(header string) string {
+	first := strings.SplitN(header, ",", 2)[0]
+	first = strings.TrimSpace(strings.SplitN(first, ";", 2)[0])
+	if first == "*" {
+		return ""
+	}
+	return first
+}
+
+// Recover returns middleware that recovers panics in the handlers it wraps,
+// logs them with velacontext.Recover, and responds with a 500.  Put it inside
+// RequestContext, so the log includes the request ID.
+func Recover() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer velacontext.Recover(r.Context(), func(err error) {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			})
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Audit returns middleware that buffers the audit events recorded during each
+// request, and flushes them to the audit sink once the handler returns.
+func Audit() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := velacontext.StartAudit(r.Context())
+			defer velacontext.FlushAudit(ctx)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package httpmiddleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+func TestRequestContext(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	var seen string
+	handler := RequestContext(zap.New(core), "api")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = velacontext.GetContextRequestID(r.Context())
+		velacontext.GetContextLogger(r.Context()).Info("handling")
+	}))
+
+	req := httptest.NewRequest("GET", "/profiles/1", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, "req-1", seen)
+	assert.Equal(t, "req-1", rec.Header().Get(RequestIDHeader))
+	require.Equal(t, 1, logs.Len())
+	entry := logs.All()[0]
+	assert.Equal(t, "api", entry.LoggerName)
+	assert.Equal(t, map[string]interface{}{
+		"request_id": "req-1",
+		"method":     "GET",
+		"path":       "/profiles/1",
+	}, entry.ContextMap())
+}
+
+func TestRequestContextBaggage(t *testing.T) {
+	var baggage map[string]string
+	handler := RequestContext(nil, "")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		baggage = velacontext.Baggage(r.Context())
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Vela-Baggage-Checkout-Variant", "b")
+	req.Header.Set("X-Other", "ignored")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, map[string]string{"checkout-variant": "b"}, baggage)
+}
+
+func TestBaggageFromHeader(t *testing.T) {
+	header := http.Header{}
+	for i := 0; i < 20; i++ {
+		header.Set(fmt.Sprintf("X-Vela-Baggage-K%02d", i), "v")
+	}
+	header.Set("X-Vela-Baggage-A"+strings.Repeat("k", 64), "v")
+	header.Set("X-Vela-Baggage-B", strings.Repeat("v", 257))
+
+	baggage := velacontext.Baggage(BaggageFromHeader(context.Background(), header))
+	assert.Len(t, baggage, 16)
+	assert.Equal(t, "v", baggage["k00"])
+	assert.Equal(t, "v", baggage["k15"])
+	assert.NotContains(t, baggage, "k16")
+	assert.NotContains(t, baggage, "b")
+}
+
+func TestRequestContextGeneratesID(t *testing.T) {
+	var seen string
+	handler := RequestContext(nil, "")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = velacontext.GetContextRequestID(r.Context())
+		assert.NotNil(t, velacontext.GetContextLogger(r.Context()))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	assert.NotEmpty(t, seen)
+	assert.Equal(t, seen, rec.Header().Get(RequestIDHeader))
+
+	// Oversized IDs are replaced
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, strings.Repeat("x", 200))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Len(t, seen, 36)
+}
+
+func TestLocalization(t *testing.T) {
+	var locale, zone string
+	handler := Localization()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale = velacontext.GetContextLocale(r.Context())
+		zone = velacontext.GetContextLocation(r.Context()).String()
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Language", "es-US;q=1.0, en;q=0.8")
+	req.Header.Set(TimeZoneHeader, "America/New_York")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "es-US", locale)
+	assert.Equal(t, "America/New_York", zone)
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Language", "*")
+	req.Header.Set(TimeZoneHeader, "Nowhere/Special")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Empty(t, locale)
+	assert.Equal(t, "UTC", zone)
+}
+
+func TestRecover(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	handler := RequestContext(zap.New(core), "")(Recover()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, rec.Header().Get(RequestIDHeader), logs.All()[0].ContextMap()["request_id"])
+}
+
+func TestAudit(t *testing.T) {
+	var flushed []velacontext.AuditEvent
+	velacontext.SetAuditSink(velacontext.AuditSinkFunc(func(ctx context.Context, events []velacontext.AuditEvent) error {
+		flushed = append(flushed, events...)
+		return nil
+	}))
+	defer velacontext.SetAuditSink(nil)
+
+	handler := Audit()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = velacontext.RecordAuditEvent(r.Context(), "profile.read", "profile/42", nil)
+		_ = velacontext.RecordAuditEvent(r.Context(), "profile.update", "profile/42", nil)
+		assert.Empty(t, flushed)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	assert.Len(t, flushed, 2)
+}
@@ -0,0 +1,83 @@
+package context
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type identityKey struct{}
+
+// Identity is who a request is being made for, usually taken from the claims
+// of its token, so downstream code can make authorization decisions.
+type Identity struct {
+	UserID         string
+	OrganizationID int64
+	PartnerID      int64
+	Roles          []string
+}
+
+// HasRole reports whether the identity has the role.
+func (i Identity) HasRole(role string) bool {
+	for _, r := range i.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// The identity as logging fields, leaving out anything that isn't set.  Roles
+// are left out, they're too noisy for every log line.
+func (i Identity) fields() []zap.Field {
+	fields := []zap.Field{}
+	if i.UserID != "" {
+		fields = append(fields, zap.String("user_id", i.UserID))
+	}
+	if i.OrganizationID != 0 {
+		fields = append(fields, zap.Int64("organization_id", i.OrganizationID))
+	}
+	if i.PartnerID != 0 {
+		fields = append(fields, zap.Int64("partner_id", i.PartnerID))
+	}
+	return fields
+}
+
+// ContextWithIdentity adds the identity to the context.  The logger from
+// GetContextLogger then includes its user, organization and partner IDs.
+func ContextWithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// GetContextIdentity returns the context's identity, or an empty one when it
+// doesn't have one.
+func GetContextIdentity(ctx context.Context) (identity Identity) {
+	identity, _ = LookupIdentity(ctx)
+	return
+}
+
+// LookupIdentity returns the context's identity, and whether it has one.  It's
+// safe to call with a nil context.
+func LookupIdentity(ctx context.Context) (Identity, bool) {
+	if ctx == nil {
+		return Identity{}, false
+	}
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
+
+func GetContextUserID(ctx context.Context) string {
+	return GetContextIdentity(ctx).UserID
+}
+
+func GetContextOrganizationID(ctx context.Context) int64 {
+	return GetContextIdentity(ctx).OrganizationID
+}
+
+func GetContextPartnerID(ctx context.Context) int64 {
+	return GetContextIdentity(ctx).PartnerID
+}
+
+func GetContextRoles(ctx context.Context) []string {
+	return GetContextIdentity(ctx).Roles
+}
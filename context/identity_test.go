@@ -0,0 +1,50 @@
+package context
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestIdentity(t *testing.T) {
+	identity := Identity{UserID: "user-1", OrganizationID: 12, PartnerID: 34, Roles: []string{"admin", "caregiver"}}
+	ctx := ContextWithIdentity(context.Background(), identity)
+
+	assert.Equal(t, identity, GetContextIdentity(ctx))
+	assert.Equal(t, "user-1", GetContextUserID(ctx))
+	assert.Equal(t, int64(12), GetContextOrganizationID(ctx))
+	assert.Equal(t, int64(34), GetContextPartnerID(ctx))
+	assert.Equal(t, []string{"admin", "caregiver"}, GetContextRoles(ctx))
+	assert.True(t, identity.HasRole("admin"))
+	assert.False(t, identity.HasRole("partner"))
+
+	_, ok := LookupIdentity(context.Background())
+	assert.False(t, ok)
+	assert.Equal(t, Identity{}, GetContextIdentity(context.Background()))
+}
+
+func TestLoggerIncludesIdentity(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	ctx := ContextWithLogger(context.Background(), zap.New(core))
+	ctx = ContextWithIdentity(ctx, Identity{UserID: "user-1", OrganizationID: 12})
+
+	GetContextLogger(ctx).Info("hello")
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, map[string]interface{}{
+		"user_id":         "user-1",
+		"organization_id": int64(12),
+	}, logs.All()[0].ContextMap())
+
+	// The fallback logger gets the identity fields too
+	fallbackCore, fallbackLogs := observer.New(zapcore.InfoLevel)
+	SetFallbackLogger(zap.New(fallbackCore))
+	defer SetFallbackLogger(nil)
+	GetContextLogger(ContextWithIdentity(context.Background(), Identity{UserID: "user-2"})).Info("hello")
+	require.Equal(t, 1, fallbackLogs.Len())
+	assert.Equal(t, "user-2", fallbackLogs.All()[0].ContextMap()["user_id"])
+}
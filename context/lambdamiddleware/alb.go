@@ -0,0 +1,63 @@
+package lambdamiddleware
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ALBRawQuery rebuilds the raw query string of an ALB request.  ALB passes the
+// parameters on still URL encoded, so they're joined as they are, in sorted
+// order, from the multi-value parameters when the target group uses them.
+func ALBRawQuery(req events.ALBTargetGroupRequest) string {
+	var params []string
+	if len(req.MultiValueQueryStringParameters) > 0 {
+		for k, values := range req.MultiValueQueryStringParameters {
+			for _, v := range values {
+				params = append(params, k+"="+v)
+			}
+		}
+	} else {
+		for k, v := range req.QueryStringParameters {
+			params = append(params, k+"="+v)
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// ALBHeader returns the first value of an ALB request's header, from the
+// multi-value headers when the target group uses them.  ALB lower cases the
+// names, so they're matched without regard to case.
+func ALBHeader(req events.ALBTargetGroupRequest, name string) string {
+	return header(req.Headers, req.MultiValueHeaders, name)
+}
+
+// ALBQuery returns the decoded query parameters of an ALB request.  Keys and
+// values that aren't valid escapes are kept as they came.
+func ALBQuery(req events.ALBTargetGroupRequest) url.Values {
+	query := url.Values{}
+	add := func(k, v string) {
+		if unescaped, err := url.QueryUnescape(k); err == nil {
+			k = unescaped
+		}
+		if unescaped, err := url.QueryUnescape(v); err == nil {
+			v = unescaped
+		}
+		query.Add(k, v)
+	}
+	if len(req.MultiValueQueryStringParameters) > 0 {
+		for k, values := range req.MultiValueQueryStringParameters {
+			for _, v := range values {
+				add(k, v)
+			}
+		}
+		return query
+	}
+	for k, v := range req.QueryStringParameters {
+		add(k, v)
+	}
+	return query
+}
@@ -0,0 +1,40 @@
+package lambdamiddleware
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestALBRawQuery(t *testing.T) {
+	assert.Equal(t, "", ALBRawQuery(events.ALBTargetGroupRequest{}))
+	assert.Equal(t, "a=b%20c&q=x%26y", ALBRawQuery(events.ALBTargetGroupRequest{
+		QueryStringParameters: map[string]string{"q": "x%26y", "a": "b%20c"},
+	}))
+	assert.Equal(t, "tag=a&tag=b%2Fc", ALBRawQuery(events.ALBTargetGroupRequest{
+		QueryStringParameters:           map[string]string{"ignored": "1"},
+		MultiValueQueryStringParameters: map[string][]string{"tag": {"b%2Fc", "a"}},
+	}))
+}
+
+func TestALBQuery(t *testing.T) {
+	assert.Equal(t, url.Values{}, ALBQuery(events.ALBTargetGroupRequest{}))
+	assert.Equal(t, url.Values{"q": {"x&y"}, "name": {"Ada Lovelace"}, "bad": {"%zz"}}, ALBQuery(events.ALBTargetGroupRequest{
+		QueryStringParameters: map[string]string{"q": "x%26y", "name": "Ada+Lovelace", "bad": "%zz"},
+	}))
+	assert.Equal(t, url.Values{"tag": {"a", "b/c"}}, ALBQuery(events.ALBTargetGroupRequest{
+		MultiValueQueryStringParameters: map[string][]string{"tag": {"a", "b%2Fc"}},
+	}))
+}
+
+func TestALBHeader(t *testing.T) {
+	assert.Equal(t, "", ALBHeader(events.ALBTargetGroupRequest{}, "X-Signature"))
+	assert.Equal(t, "a", ALBHeader(events.ALBTargetGroupRequest{
+		Headers: map[string]string{"x-signature": "a"},
+	}, "X-Signature"))
+	assert.Equal(t, "b", ALBHeader(events.ALBTargetGroupRequest{
+		MultiValueHeaders: map[string][]string{"x-signature": {"b", "c"}},
+	}, "X-Signature"))
+}
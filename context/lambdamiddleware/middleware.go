@@ -0,0 +1,254 @@
+// Package lambdamiddleware wires the velacontext values into Lambda handlers
+// for ALB and API Gateway events.  It's the Lambda counterpart of
+// httpmiddleware.
+package lambdamiddleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/context/httpmiddleware"
+)
+
+// TraceIDHeader is the header ALB and API Gateway put the AWS trace ID in.
+const TraceIDHeader = "X-Amzn-Trace-Id"
+
+type (
+	// ALBHandler handles requests from an ALB target group.
+	ALBHandler func(context.Context, events.ALBTargetGroupRequest) (*events.ALBTargetGroupResponse, error)
+	// APIGatewayHandler handles API Gateway REST API (v1) proxy requests.
+	APIGatewayHandler func(context.Context, events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error)
+	// APIGatewayV2Handler handles API Gateway HTTP API (v2) requests.
+	APIGatewayV2Handler func(context.Context, events.APIGatewayV2HTTPRequest) (*events.APIGatewayV2HTTPResponse, error)
+)
+
+// The values taken from a request, whatever kind of event it came in.
+type request struct {
+	requestID string
+	traceID   string
+	sourceIP  string
+	method    string
+	path      string
+}
+
+func (r request) context(ctx context.Context, logger *zap.Logger) context.Context {
+	ctx = velacontext.ContextWithRequestID(ctx, r.requestID)
+	ctx = velacontext.ContextWithAmznTraceID(ctx, r.traceID)
+	ctx = velacontext.ContextWithSourceIP(ctx, r.sourceIP)
+	ctx = velacontext.ContextWithLogger(ctx, logger)
+	fields := []zap.Field{
+		zap.String("method", r.method),
+		zap.String("path", r.path),
+	}
+	if r.traceID != "" {
+		fields = append(fields, zap.String("amzn_trace_id", r.traceID))
+	}
+	if r.sourceIP != "" {
+		fields = append(fields, zap.String("source_ip", r.sourceIP))
+	}
+	return velacontext.WithLoggerFields(ctx, fields...)
+}
+
+func namedLogger(logger *zap.Logger, name string) *zap.Logger {
+	if logger == nil {
+		logger = velacontext.FallbackLogger()
+	}
+	if name != "" {
+		logger = logger.Named(name)
+	}
+	return logger
+}
+
+// ALBRequestContext returns middleware that adds the request ID, AWS trace ID,
+// source IP and a logger to the context of each ALB request, the same way
+// httpmiddleware.RequestContext does, and echoes the request ID on the
+// response.  The source IP comes from X-Forwarded-For, see ForwardedFor.
+func ALBRequestContext(logger *zap.Logger, name string) func(ALBHandler) ALBHandler {
+	logger = namedLogger(logger, name)
+	return func(next ALBHandler) ALBHandler {
+		return func(ctx context.Context, req events.ALBTargetGroupRequest) (*events.ALBTargetGroupResponse, error) {
+			r := request{
+				requestID: httpmiddleware.RequestIDFromHeader(header(req.Headers, req.MultiValueHeaders, httpmiddleware.RequestIDHeader)),
+				traceID:   header(req.Headers, req.MultiValueHeaders, TraceIDHeader),
+				sourceIP:  ForwardedFor(joinedHeader(req.Headers, req.MultiValueHeaders, "X-Forwarded-For")),
+				method:    req.HTTPMethod,
+				path:      req.Path,
+			}
+			resp, err := next(r.context(ctx, logger), req)
+			if resp != nil {
+				resp.Headers, resp.MultiValueHeaders = setHeader(resp.Headers, resp.MultiValueHeaders, r.requestID)
+			}
+			return resp, err
+		}
+	}
+}
+
+// APIGatewayRequestContext is ALBRequestContext for API Gateway REST API (v1)
+// proxy requests.  The source IP comes from the request context's identity.
+func APIGatewayRequestContext(logger *zap.Logger, name string) func(APIGatewayHandler) APIGatewayHandler {
+	logger = namedLogger(logger, name)
+	return func(next APIGatewayHandler) APIGatewayHandler {
+		return func(ctx context.Context, req events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+			r := request{
+				requestID: httpmiddleware.RequestIDFromHeader(header(req.Headers, req.MultiValueHeaders, httpmiddleware.RequestIDHeader)),
+				traceID:   header(req.Headers, req.MultiValueHeaders, TraceIDHeader),
+				sourceIP:  req.RequestContext.Identity.SourceIP,
+				method:    req.HTTPMethod,
+				path:      req.Path,
+			}
+			resp, err := next(r.context(ctx, logger), req)
+			if resp != nil {
+				resp.Headers, resp.MultiValueHeaders = setHeader(resp.Headers, resp.MultiValueHeaders, r.requestID)
+			}
+			return resp, err
+		}
+	}
+}
+
+// APIGatewayV2RequestContext is ALBRequestContext for API Gateway HTTP API
+// (v2) requests.  The source IP comes from the request context.
+func APIGatewayV2RequestContext(logger *zap.Logger, name string) func(APIGatewayV2Handler) APIGatewayV2Handler {
+	logger = namedLogger(logger, name)
+	return func(next APIGatewayV2Handler) APIGatewayV2Handler {
+		return func(ctx context.Context, req events.APIGatewayV2HTTPRequest) (*events.APIGatewayV2HTTPResponse, error) {
+			r := request{
+				requestID: httpmiddleware.RequestIDFromHeader(header(req.Headers, nil, httpmiddleware.RequestIDHeader)),
+				traceID:   header(req.Headers, nil, TraceIDHeader),
+				sourceIP:  req.RequestContext.HTTP.SourceIP,
+				method:    req.RequestContext.HTTP.Method,
+				path:      req.RawPath,
+			}
+			resp, err := next(r.context(ctx, logger), req)
+			if resp != nil {
+				resp.Headers, resp.MultiValueHeaders = setHeader(resp.Headers, resp.MultiValueHeaders, r.requestID)
+			}
+			return resp, err
+		}
+	}
+}
+
+// Looks a header up without regard to case, since ALB lower cases them and
+// API Gateway passes them on as sent.
+func header(headers map[string]string, multiValueHeaders map[string][]string, name string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	for k, v := range multiValueHeaders {
+		if strings.EqualFold(k, name) && len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// Adds the request ID header to the response, in the multi-value headers
+// when the handler used them, since ALB ignores the others then.
+func setHeader(headers map[string]string, multiValueHeaders map[string][]string, requestID string) (map[string]string, map[string][]string) {
+	if len(multiValueHeaders) > 0 {
+		multiValueHeaders[http.CanonicalHeaderKey(httpmiddleware.RequestIDHeader)] = []string{requestID}
+		return headers, multiValueHeaders
+	}
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	headers[httpmiddleware.RequestIDHeader] = requestID
+	return headers, multiValueHeaders
+}
+
+// Like header, but joins all the values of a multi-value header, which a
+// client can send more than once.
+func joinedHeader(headers map[string]string, multiValueHeaders map[string][]string, name string) string {
+	for k, v := range multiValueHeaders {
+		if strings.EqualFold(k, name) && len(v) > 0 {
+			return strings.Join(v, ",")
+		}
+	}
+	return header(headers, nil, name)
+}
+
+// TrustedProxies is the number of proxies in front of the ALB, CloudFront
+// say, that append the address they got the request from to X-Forwarded-For.
+// Set it at start up, before handling any requests.
+var TrustedProxies = 0
+
+// ForwardedFor picks the client's address out of an X-Forwarded-For header.
+// Clients can send the header with whatever they like in it, and the ALB
+// appends the address it got the request from, so only the entries on the
+// right can be trusted: the client's is the last one, or the one before the
+// TrustedProxies' own.  When there are fewer entries than that, it's the
+// first.
+func ForwardedFor(value string) string {
+	if value == "" {
+		return ""
+	}
+	entries := strings.Split(value, ",")
+	i := len(entries) - 1 - TrustedProxies
+	if i < 0 {
+		i = 0
+	}
+	return strings.TrimSpace(entries[i])
+}
+
+// ALBRecover returns middleware that recovers panics in the ALB handlers it
+// wraps, logs them with velacontext.Recover, and responds with a 500.  Put it
+// inside ALBRequestContext, so the log includes the request ID.
+func ALBRecover() func(ALBHandler) ALBHandler {
+	return func(next ALBHandler) ALBHandler {
+		return func(ctx context.Context, req events.ALBTargetGroupRequest) (resp *events.ALBTargetGroupResponse, err error) {
+			defer velacontext.Recover(ctx, func(error) {
+				resp, err = &events.ALBTargetGroupResponse{
+					StatusCode:        http.StatusInternalServerError,
+					StatusDescription: statusDescription(http.StatusInternalServerError),
+					Headers:           map[string]string{"Content-Type": "text/plain"},
+					Body:              http.StatusText(http.StatusInternalServerError),
+				}, nil
+			})
+			return next(ctx, req)
+		}
+	}
+}
+
+// APIGatewayRecover is ALBRecover for API Gateway REST API (v1) handlers.
+func APIGatewayRecover() func(APIGatewayHandler) APIGatewayHandler {
+	return func(next APIGatewayHandler) APIGatewayHandler {
+		return func(ctx context.Context, req events.APIGatewayProxyRequest) (resp *events.APIGatewayProxyResponse, err error) {
+			defer velacontext.Recover(ctx, func(error) {
+				resp, err = &events.APIGatewayProxyResponse{
+					StatusCode: http.StatusInternalServerError,
+					Headers:    map[string]string{"Content-Type": "text/plain"},
+					Body:       http.StatusText(http.StatusInternalServerError),
+				}, nil
+			})
+			return next(ctx, req)
+		}
+	}
+}
+
+// APIGatewayV2Recover is ALBRecover for API Gateway HTTP API (v2) handlers.
+func APIGatewayV2Recover() func(APIGatewayV2Handler) APIGatewayV2Handler {
+	return func(next APIGatewayV2Handler) APIGatewayV2Handler {
+		return func(ctx context.Context, req events.APIGatewayV2HTTPRequest) (resp *events.APIGatewayV2HTTPResponse, err error) {
+			defer velacontext.Recover(ctx, func(error) {
+				resp, err = &events.APIGatewayV2HTTPResponse{
+					StatusCode: http.StatusInternalServerError,
+					Headers:    map[string]string{"Content-Type": "text/plain"},
+					Body:       http.StatusText(http.StatusInternalServerError),
+				}, nil
+			})
+			return next(ctx, req)
+		}
+	}
+}
+
+// ALB responses need a status description, like "500 Internal Server Error".
+func statusDescription(status int) string {
+	return fmt.Sprintf("%d %s", status, http.StatusText(status))
+}
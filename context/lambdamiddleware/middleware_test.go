@@ -0,0 +1,157 @@
+package lambdamiddleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+func TestALBRequestContext(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	handler := ALBRequestContext(zap.New(core), "alb")(func(ctx context.Context, req events.ALBTargetGroupRequest) (*events.ALBTargetGroupResponse, error) {
+		assert.Equal(t, "req-1", velacontext.GetContextRequestID(ctx))
+		assert.Equal(t, "Root=1-abc", velacontext.GetContextAmznTraceID(ctx))
+		assert.Equal(t, "203.0.113.7", velacontext.GetContextSourceIP(ctx))
+		velacontext.GetContextLogger(ctx).Info("handling")
+		return &events.ALBTargetGroupResponse{StatusCode: 200}, nil
+	})
+
+	resp, err := handler(context.Background(), events.ALBTargetGroupRequest{
+		HTTPMethod: "GET",
+		Path:       "/home",
+		Headers: map[string]string{
+			"x-vela-request-id": "req-1",
+			"x-amzn-trace-id":   "Root=1-abc",
+			"x-forwarded-for":   "10.0.0.1, 203.0.113.7",
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "req-1", resp.Headers["X-Vela-Request-Id"])
+
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, "alb", logs.All()[0].LoggerName)
+	assert.Equal(t, map[string]interface{}{
+		"request_id":    "req-1",
+		"method":        "GET",
+		"path":          "/home",
+		"amzn_trace_id": "Root=1-abc",
+		"source_ip":     "203.0.113.7",
+	}, logs.All()[0].ContextMap())
+}
+
+func TestForwardedFor(t *testing.T) {
+	assert.Equal(t, "", ForwardedFor(""))
+	assert.Equal(t, "203.0.113.7", ForwardedFor("203.0.113.7"))
+	assert.Equal(t, "203.0.113.7", ForwardedFor("6.6.6.6, 203.0.113.7"))
+
+	TrustedProxies = 1
+	defer func() { TrustedProxies = 0 }()
+	assert.Equal(t, "203.0.113.7", ForwardedFor("6.6.6.6, 203.0.113.7, 130.176.0.1"))
+	assert.Equal(t, "130.176.0.1", ForwardedFor("130.176.0.1"))
+}
+
+func TestALBRequestContextMultiValue(t *testing.T) {
+	var requestID, sourceIP string
+	handler := ALBRequestContext(nil, "")(func(ctx context.Context, req events.ALBTargetGroupRequest) (*events.ALBTargetGroupResponse, error) {
+		requestID = velacontext.GetContextRequestID(ctx)
+		sourceIP = velacontext.GetContextSourceIP(ctx)
+		return &events.ALBTargetGroupResponse{
+			StatusCode:        200,
+			MultiValueHeaders: map[string][]string{"Content-Type": {"text/html"}},
+		}, nil
+	})
+
+	resp, err := handler(context.Background(), events.ALBTargetGroupRequest{
+		MultiValueHeaders: map[string][]string{"x-forwarded-for": {"6.6.6.6", "198.51.100.2"}},
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, requestID)
+	assert.Equal(t, "198.51.100.2", sourceIP)
+	assert.Equal(t, []string{requestID}, resp.MultiValueHeaders["X-Vela-Request-Id"])
+	assert.Empty(t, resp.Headers)
+
+	// A nil response is passed through
+	handler = ALBRequestContext(nil, "")(func(ctx context.Context, req events.ALBTargetGroupRequest) (*events.ALBTargetGroupResponse, error) {
+		return nil, nil
+	})
+	resp, err = handler(context.Background(), events.ALBTargetGroupRequest{})
+	assert.NoError(t, err)
+	assert.Nil(t, resp)
+}
+
+func TestAPIGatewayRequestContext(t *testing.T) {
+	handler := APIGatewayRequestContext(nil, "")(func(ctx context.Context, req events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		assert.Equal(t, "req-2", velacontext.GetContextRequestID(ctx))
+		assert.Equal(t, "198.51.100.9", velacontext.GetContextSourceIP(ctx))
+		return &events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	})
+
+	req := events.APIGatewayProxyRequest{
+		HTTPMethod: "POST",
+		Path:       "/profiles",
+		Headers:    map[string]string{"X-Vela-Request-Id": "req-2"},
+	}
+	req.RequestContext.Identity.SourceIP = "198.51.100.9"
+	resp, err := handler(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "req-2", resp.Headers["X-Vela-Request-Id"])
+}
+
+func TestAPIGatewayV2RequestContext(t *testing.T) {
+	handler := APIGatewayV2RequestContext(nil, "")(func(ctx context.Context, req events.APIGatewayV2HTTPRequest) (*events.APIGatewayV2HTTPResponse, error) {
+		assert.Equal(t, "req-3", velacontext.GetContextRequestID(ctx))
+		assert.Equal(t, "Root=1-def", velacontext.GetContextAmznTraceID(ctx))
+		assert.Equal(t, "192.0.2.4", velacontext.GetContextSourceIP(ctx))
+		return &events.APIGatewayV2HTTPResponse{StatusCode: 200}, nil
+	})
+
+	req := events.APIGatewayV2HTTPRequest{
+		RawPath: "/profiles",
+		Headers: map[string]string{"x-vela-request-id": "req-3", "x-amzn-trace-id": "Root=1-def"},
+	}
+	req.RequestContext.HTTP.SourceIP = "192.0.2.4"
+	resp, err := handler(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "req-3", resp.Headers["X-Vela-Request-Id"])
+}
+
+func TestALBRecover(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	handler := ALBRequestContext(zap.New(core), "")(ALBRecover()(func(ctx context.Context, req events.ALBTargetGroupRequest) (*events.ALBTargetGroupResponse, error) {
+		panic("boom")
+	}))
+
+	resp, err := handler(context.Background(), events.ALBTargetGroupRequest{
+		Headers: map[string]string{"x-vela-request-id": "req-1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 500, resp.StatusCode)
+	assert.Equal(t, "500 Internal Server Error", resp.StatusDescription)
+	assert.Equal(t, "req-1", resp.Headers["X-Vela-Request-Id"])
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, "req-1", logs.All()[0].ContextMap()["request_id"])
+}
+
+func TestAPIGatewayRecover(t *testing.T) {
+	v1 := APIGatewayRecover()(func(ctx context.Context, req events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		panic("boom")
+	})
+	resp, err := v1(context.Background(), events.APIGatewayProxyRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, 500, resp.StatusCode)
+
+	v2 := APIGatewayV2Recover()(func(ctx context.Context, req events.APIGatewayV2HTTPRequest) (*events.APIGatewayV2HTTPResponse, error) {
+		return &events.APIGatewayV2HTTPResponse{StatusCode: 204}, nil
+	})
+	resp2, err := v2(context.Background(), events.APIGatewayV2HTTPRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, 204, resp2.StatusCode)
+}
@@ -0,0 +1,72 @@
+package context
+
+import (
+	"context"
+	"time"
+
+	"github.com/seniorlink-vela/cs-common/timeutil"
+)
+
+type (
+	localeKey   struct{}
+	locationKey struct{}
+)
+
+// ContextWithLocale adds the end user's locale, such as "en-US", to the
+// context.
+func ContextWithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// GetContextLocale returns the context's locale, or an empty string when it
+// doesn't have one.
+func GetContextLocale(ctx context.Context) (locale string) {
+	locale, _ = LookupLocale(ctx)
+	return
+}
+
+// LookupLocale returns the context's locale, and whether it has one.  It's
+// safe to call with a nil context.
+func LookupLocale(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	locale, ok := ctx.Value(localeKey{}).(string)
+	return locale, ok && locale != ""
+}
+
+// ContextWithLocation adds the end user's time zone to the context.
+func ContextWithLocation(ctx context.Context, location *time.Location) context.Context {
+	return context.WithValue(ctx, locationKey{}, location)
+}
+
+// ContextWithTimeZone adds the end user's time zone to the context by its
+// IANA name, such as "America/New_York".  The context is returned unchanged
+// along with the error when the name isn't a time zone timeutil.LoadZone
+// accepts, so "" and "Local" can't make the server's zone the user's.
+func ContextWithTimeZone(ctx context.Context, name string) (context.Context, error) {
+	location, err := timeutil.LoadZone(name)
+	if err != nil {
+		return ctx, err
+	}
+	return ContextWithLocation(ctx, location), nil
+}
+
+// GetContextLocation returns the context's time zone, or UTC when it doesn't
+// have one, so times can always be converted with it.
+func GetContextLocation(ctx context.Context) *time.Location {
+	if location, ok := LookupLocation(ctx); ok {
+		return location
+	}
+	return time.UTC
+}
+
+// LookupLocation returns the context's time zone, and whether it has one.
+// It's safe to call with a nil context.
+func LookupLocation(ctx context.Context) (*time.Location, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	location, ok := ctx.Value(locationKey{}).(*time.Location)
+	return location, ok && location != nil
+}
@@ -0,0 +1,36 @@
+package context
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocale(t *testing.T) {
+	ctx := ContextWithLocale(context.Background(), "es-US")
+	assert.Equal(t, "es-US", GetContextLocale(ctx))
+	_, ok := LookupLocale(context.Background())
+	assert.False(t, ok)
+	_, ok = LookupLocale(nil)
+	assert.False(t, ok)
+}
+
+func TestTimeZone(t *testing.T) {
+	assert.Equal(t, time.UTC, GetContextLocation(context.Background()))
+
+	ctx, err := ContextWithTimeZone(context.Background(), "America/Chicago")
+	require.NoError(t, err)
+	assert.Equal(t, "America/Chicago", GetContextLocation(ctx).String())
+
+	for _, name := range []string{"Mars/Olympus_Mons", "Local", ""} {
+		same, err := ContextWithTimeZone(ctx, name)
+		assert.Error(t, err, name)
+		assert.Equal(t, ctx, same, name)
+	}
+
+	_, ok := LookupLocation(nil)
+	assert.False(t, ok)
+}
@@ -0,0 +1,42 @@
+package context
+
+import (
+	"context"
+)
+
+type (
+	amznTraceIDKey struct{}
+	sourceIPKey    struct{}
+)
+
+// ContextWithAmznTraceID adds the request's AWS trace ID, from its
+// X-Amzn-Trace-Id header, to the context.
+func ContextWithAmznTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, amznTraceIDKey{}, traceID)
+}
+
+// GetContextAmznTraceID returns the context's AWS trace ID, or an empty string
+// when it doesn't have one.
+func GetContextAmznTraceID(ctx context.Context) (traceID string) {
+	if ctx == nil {
+		return
+	}
+	traceID, _ = ctx.Value(amznTraceIDKey{}).(string)
+	return
+}
+
+// ContextWithSourceIP adds the IP address the request came from to the
+// context.
+func ContextWithSourceIP(ctx context.Context, sourceIP string) context.Context {
+	return context.WithValue(ctx, sourceIPKey{}, sourceIP)
+}
+
+// GetContextSourceIP returns the IP address the context's request came from,
+// or an empty string when it isn't known.
+func GetContextSourceIP(ctx context.Context) (sourceIP string) {
+	if ctx == nil {
+		return
+	}
+	sourceIP, _ = ctx.Value(sourceIPKey{}).(string)
+	return
+}
@@ -0,0 +1,59 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+var panics atomic.Int64
+
+// PanicError is a recovered panic.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", p.Value)
+}
+
+// Unwrap returns the panic's value when it was an error.
+func (p *PanicError) Unwrap() error {
+	err, _ := p.Value.(error)
+	return err
+}
+
+// Recover recovers a panic, logs it and its stack trace with the context's
+// logger, counts it, and passes it to onPanic as a *PanicError.  It has to be
+// deferred directly:
+//
+//	defer velacontext.Recover(ctx, func(err error) { resp, retErr = nil, err })
+//
+// onPanic can be nil, and isn't called when there's no panic.
+func Recover(ctx context.Context, onPanic func(err error)) {
+	value := recover()
+	if value == nil {
+		return
+	}
+	err := &PanicError{Value: value, Stack: debug.Stack()}
+	panics.Add(1)
+	fields := []zap.Field{zap.Any("panic", value), zap.ByteString("stack", err.Stack)}
+	// WithLoggerFields already added the request ID when there are any
+	if requestID, ok := LookupRequestID(ctx); ok && len(loggerFields(ctx)) == 0 {
+		fields = append(fields, zap.String("request_id", requestID))
+	}
+	GetContextLogger(ctx).Error("Recovered from panic", fields...)
+	if onPanic != nil {
+		onPanic(err)
+	}
+}
+
+// PanicCount returns how many panics Recover has recovered since the process
+// started, for reporting as a metric.
+func PanicCount() int64 {
+	return panics.Load()
+}
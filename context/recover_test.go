@@ -0,0 +1,51 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRecover(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	ctx := ContextWithLogger(context.Background(), zap.New(core))
+	ctx = ContextWithRequestID(ctx, "req-1")
+	before := PanicCount()
+
+	cause := errors.New("boom")
+	var recovered error
+	func() {
+		defer Recover(ctx, func(err error) { recovered = err })
+		panic(cause)
+	}()
+
+	var panicErr *PanicError
+	require.True(t, errors.As(recovered, &panicErr))
+	assert.ErrorIs(t, recovered, cause)
+	assert.Contains(t, string(panicErr.Stack), "TestRecover")
+	assert.Equal(t, before+1, PanicCount())
+
+	require.Equal(t, 1, logs.Len())
+	entry := logs.All()[0]
+	assert.Equal(t, zapcore.ErrorLevel, entry.Level)
+	assert.Equal(t, "req-1", entry.ContextMap()["request_id"])
+
+	// No panic, no call
+	called := false
+	func() {
+		defer Recover(ctx, func(err error) { called = true })
+	}()
+	assert.False(t, called)
+
+	// A nil onPanic still recovers
+	assert.NotPanics(t, func() {
+		defer Recover(ctx, nil)
+		panic("boom")
+	})
+}
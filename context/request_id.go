@@ -0,0 +1,32 @@
+package context
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+)
+
+// NewRequestID returns a new random (version 4) UUID to use as a request ID.
+func NewRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("velacontext: can't read random bytes for a request ID: %v", err))
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// EnsureRequestID returns the context's request ID, generating one with
+// NewRequestID and adding it to the context when it doesn't have one.  Use the
+// returned context from then on so the ID stays the same.
+func EnsureRequestID(ctx context.Context) (context.Context, string) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if requestID, ok := LookupRequestID(ctx); ok {
+		return ctx, requestID
+	}
+	requestID := NewRequestID()
+	return ContextWithRequestID(ctx, requestID), requestID
+}
@@ -0,0 +1,37 @@
+package context
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var uuidV4 = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewRequestID(t *testing.T) {
+	a, b := NewRequestID(), NewRequestID()
+	assert.Regexp(t, uuidV4, a)
+	assert.Regexp(t, uuidV4, b)
+	assert.NotEqual(t, a, b)
+}
+
+func TestEnsureRequestID(t *testing.T) {
+	ctx, id := EnsureRequestID(context.Background())
+	assert.Regexp(t, uuidV4, id)
+	assert.Equal(t, id, GetContextRequestID(ctx))
+
+	// An existing ID is kept
+	same, again := EnsureRequestID(ctx)
+	assert.Equal(t, id, again)
+	assert.Equal(t, ctx, same)
+
+	ctx, id = EnsureRequestID(ContextWithRequestID(context.Background(), "req-1"))
+	assert.Equal(t, "req-1", id)
+	assert.Equal(t, "req-1", GetContextRequestID(ctx))
+
+	ctx, id = EnsureRequestID(nil)
+	assert.NotNil(t, ctx)
+	assert.Regexp(t, uuidV4, id)
+}
@@ -0,0 +1,77 @@
+package context
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// The name spans started with StartSpan are recorded under.
+const tracerName = "github.com/seniorlink-vela/cs-common"
+
+// ContextWithSpanContext adds an OpenTelemetry span context to the context,
+// such as one extracted from an incoming request, so spans started from it
+// join the caller's trace.
+func ContextWithSpanContext(ctx context.Context, spanContext trace.SpanContext) context.Context {
+	return trace.ContextWithSpanContext(ctx, spanContext)
+}
+
+// LookupSpanContext returns the context's OpenTelemetry span context, and
+// whether it has a valid one.  It's safe to call with a nil context.
+func LookupSpanContext(ctx context.Context) (trace.SpanContext, bool) {
+	if ctx == nil {
+		return trace.SpanContext{}, false
+	}
+	spanContext := trace.SpanContextFromContext(ctx)
+	return spanContext, spanContext.IsValid()
+}
+
+// GetContextTraceID returns the context's OpenTelemetry trace ID, or an empty
+// string when it isn't being traced.
+func GetContextTraceID(ctx context.Context) string {
+	spanContext, ok := LookupSpanContext(ctx)
+	if !ok {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// GetContextSpanID returns the context's OpenTelemetry span ID, or an empty
+// string when it isn't being traced.
+func GetContextSpanID(ctx context.Context) string {
+	spanContext, ok := LookupSpanContext(ctx)
+	if !ok {
+		return ""
+	}
+	return spanContext.SpanID().String()
+}
+
+// StartSpan starts a span with the global tracer provider, tagged with the
+// request ID when the context has one.  End the span when the work is done:
+//
+//	ctx, span := velacontext.StartSpan(ctx, "client.CreateProfile")
+//	defer span.End()
+func StartSpan(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if requestID, ok := LookupRequestID(ctx); ok {
+		opts = append(opts, trace.WithAttributes(attribute.String("vela.request_id", requestID)))
+	}
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// The trace and span IDs as logging fields, or none when there's no trace.
+func traceFields(ctx context.Context) []zap.Field {
+	spanContext, ok := LookupSpanContext(ctx)
+	if !ok {
+		return nil
+	}
+	return []zap.Field{
+		zap.String("trace_id", spanContext.TraceID().String()),
+		zap.String("span_id", spanContext.SpanID().String()),
+	}
+}
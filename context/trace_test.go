@@ -0,0 +1,57 @@
+package context
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func testSpanContext() trace.SpanContext {
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10},
+		SpanID:     trace.SpanID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+		TraceFlags: trace.FlagsSampled,
+	})
+}
+
+func TestSpanContext(t *testing.T) {
+	_, ok := LookupSpanContext(context.Background())
+	assert.False(t, ok)
+	assert.Empty(t, GetContextTraceID(nil))
+	assert.Empty(t, GetContextSpanID(context.Background()))
+
+	ctx := ContextWithSpanContext(context.Background(), testSpanContext())
+	assert.Equal(t, "0102030405060708090a0b0c0d0e0f10", GetContextTraceID(ctx))
+	assert.Equal(t, "0102030405060708", GetContextSpanID(ctx))
+}
+
+func TestLoggerIncludesTrace(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	ctx := ContextWithLogger(context.Background(), zap.New(core))
+	ctx = ContextWithSpanContext(ctx, testSpanContext())
+
+	GetContextLogger(ctx).Info("hello")
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, map[string]interface{}{
+		"trace_id": "0102030405060708090a0b0c0d0e0f10",
+		"span_id":  "0102030405060708",
+	}, logs.All()[0].ContextMap())
+}
+
+func TestStartSpan(t *testing.T) {
+	// With the default no-op provider, the span carries on the parent's trace
+	parent := ContextWithSpanContext(ContextWithRequestID(context.Background(), "req-1"), testSpanContext())
+	ctx, span := StartSpan(parent, "work")
+	defer span.End()
+	assert.Equal(t, GetContextTraceID(parent), GetContextTraceID(ctx))
+
+	ctx, span = StartSpan(nil, "work")
+	defer span.End()
+	assert.NotNil(t, ctx)
+}
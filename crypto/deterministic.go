@@ -0,0 +1,176 @@
+package crypto
+
+import (
+	"context"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+// Deterministic encrypts the same plaintext, with the same additional data
+// and key, to the same ciphertext, so an encrypted column can be searched by
+// encrypting the value looked for.  That shows which rows have the same
+// value, so only use it for fields that are looked up, like medical record
+// numbers, and not for low cardinality fields like a diagnosis, where
+// counting gives values away.
+//
+// The nonce is an HMAC of the plaintext, so only identical plaintexts share
+// one.  Keys have IDs, which go in the header, so old values decrypt after
+// the current key changes; until they're re-encrypted, look them up with
+// SearchValues.
+type Deterministic struct {
+	keys    map[string]deterministicKey
+	current string
+}
+
+type deterministicKey struct {
+	id  string
+	enc []byte
+	mac []byte
+}
+
+// NewDeterministic returns a Deterministic with 32 byte keys by ID,
+// encrypting with the current one.  IDs are up to 255 bytes, and short ones
+// keep ciphertexts short.
+func NewDeterministic(keys map[string][]byte, current string) (*Deterministic, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("crypto: there's no key %q", current)
+	}
+	d := &Deterministic{keys: make(map[string]deterministicKey, len(keys)), current: current}
+	for id, key := range keys {
+		if len(id) == 0 || len(id) > 255 {
+			return nil, fmt.Errorf("crypto: key ID %q has to be 1 to 255 bytes", id)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("crypto: key %q has to be 32 bytes", id)
+		}
+		// Separate keys for the cipher and the nonces
+		enc, err := hkdf.Key(sha256.New, key, nil, "cs-common deterministic encryption", 32)
+		if err != nil {
+			return nil, err
+		}
+		mac, err := hkdf.Key(sha256.New, key, nil, "cs-common deterministic nonce", 32)
+		if err != nil {
+			return nil, err
+		}
+		d.keys[id] = deterministicKey{id: id, enc: enc, mac: mac}
+	}
+	return d, nil
+}
+
+// LoadDeterministic decrypts the keys with KMS, for keys stored encrypted in
+// config, like the CiphertextBlob of a GenerateDataKey call.
+func LoadDeterministic(ctx context.Context, client KMSAPI, encryptedKeys map[string][]byte, encryptionContext map[string]string, current string) (*Deterministic, error) {
+	keys := make(map[string][]byte, len(encryptedKeys))
+	for id, encrypted := range encryptedKeys {
+		out, err := client.Decrypt(ctx, &kms.DecryptInput{CiphertextBlob: encrypted, EncryptionContext: encryptionContext})
+		if err != nil {
+			return nil, fmt.Errorf("crypto: unable to decrypt key %q: %w", id, err)
+		}
+		keys[id] = out.Plaintext
+	}
+	return NewDeterministic(keys, current)
+}
+
+// Encrypt encrypts the plaintext with the current key.  The ciphertext is
+//
+//	version | mode | key ID length | key ID | nonce | sealed plaintext
+func (d *Deterministic) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
+	return d.encrypt(d.keys[d.current], plaintext, additionalData)
+}
+
+func (d *Deterministic) encrypt(key deterministicKey, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key.enc)
+	if err != nil {
+		return nil, err
+	}
+	header := append([]byte{formatVersion, modeDeterministic, byte(len(key.id))}, key.id...)
+
+	mac := hmac.New(sha256.New, key.mac)
+	// Lengths first, so the plaintext and additional data can't be split
+	// differently to get the same nonce
+	mac.Write(lengthPrefixed(additionalData))
+	mac.Write(plaintext)
+	nonce := mac.Sum(nil)[:aead.NonceSize()]
+
+	sealed := aead.Seal(nonce, nonce, plaintext, append(header[:len(header):len(header)], additionalData...))
+	return append(header, sealed...), nil
+}
+
+func lengthPrefixed(b []byte) []byte {
+	return append([]byte(fmt.Sprintf("%d:", len(b))), b...)
+}
+
+// Decrypt decrypts a value from Encrypt, with the same additional data,
+// using the key its header names.
+func (d *Deterministic) Decrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < 3 || ciphertext[0] != formatVersion || ciphertext[1] != modeDeterministic {
+		return nil, ErrInvalidCiphertext
+	}
+	idLen := int(ciphertext[2])
+	if len(ciphertext) < 3+idLen {
+		return nil, ErrInvalidCiphertext
+	}
+	header, sealed := ciphertext[:3+idLen], ciphertext[3+idLen:]
+	key, ok := d.keys[string(header[3:])]
+	if !ok {
+		return nil, fmt.Errorf("crypto: unknown key %q: %w", header[3:], ErrInvalidCiphertext)
+	}
+	aead, err := newAEAD(key.enc)
+	if err != nil {
+		return nil, err
+	}
+	return open(aead, sealed, append(header[:len(header):len(header)], additionalData...))
+}
+
+// SearchValues returns the plaintext encrypted with each key, current first,
+// for finding values encrypted before the current key changed:
+//
+//	WHERE mrn = ANY($1)
+func (d *Deterministic) SearchValues(plaintext, additionalData []byte) ([][]byte, error) {
+	ids := make([]string, 0, len(d.keys))
+	for id := range d.keys {
+		if id != d.current {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	ids = append([]string{d.current}, ids...)
+
+	values := make([][]byte, 0, len(ids))
+	for _, id := range ids {
+		value, err := d.encrypt(d.keys[id], plaintext, additionalData)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// EncryptString encrypts a string, returning base64 for text columns.
+func (d *Deterministic) EncryptString(plaintext, additionalData string) (string, error) {
+	ciphertext, err := d.Encrypt([]byte(plaintext), []byte(additionalData))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// DecryptString decrypts a value from EncryptString.
+func (d *Deterministic) DecryptString(ciphertext, additionalData string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	plaintext, err := d.Decrypt(decoded, []byte(additionalData))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
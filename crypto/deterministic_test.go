@@ -0,0 +1,93 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestDeterministic(t *testing.T) {
+	d, err := NewDeterministic(map[string][]byte{"k1": testKey(1)}, "k1")
+	require.NoError(t, err)
+
+	a, err := d.Encrypt([]byte("MRN-0042"), []byte("profiles.mrn"))
+	require.NoError(t, err)
+	b, err := d.Encrypt([]byte("MRN-0042"), []byte("profiles.mrn"))
+	require.NoError(t, err)
+	assert.Equal(t, a, b)
+
+	other, err := d.Encrypt([]byte("MRN-0043"), []byte("profiles.mrn"))
+	require.NoError(t, err)
+	assert.NotEqual(t, a, other)
+	column, err := d.Encrypt([]byte("MRN-0042"), []byte("members.mrn"))
+	require.NoError(t, err)
+	assert.NotEqual(t, a, column)
+
+	plaintext, err := d.Decrypt(a, []byte("profiles.mrn"))
+	require.NoError(t, err)
+	assert.Equal(t, "MRN-0042", string(plaintext))
+	_, err = d.Decrypt(a, []byte("members.mrn"))
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	// Decrypting doesn't change the ciphertext
+	assert.Equal(t, a, b)
+}
+
+func TestDeterministicRotation(t *testing.T) {
+	old, err := NewDeterministic(map[string][]byte{"k1": testKey(1)}, "k1")
+	require.NoError(t, err)
+	before, err := old.EncryptString("MRN-0042", "profiles.mrn")
+	require.NoError(t, err)
+
+	d, err := NewDeterministic(map[string][]byte{"k1": testKey(1), "k2": testKey(2)}, "k2")
+	require.NoError(t, err)
+	plaintext, err := d.DecryptString(before, "profiles.mrn")
+	require.NoError(t, err)
+	assert.Equal(t, "MRN-0042", plaintext)
+
+	values, err := d.SearchValues([]byte("MRN-0042"), []byte("profiles.mrn"))
+	require.NoError(t, err)
+	require.Len(t, values, 2)
+	current, _ := d.Encrypt([]byte("MRN-0042"), []byte("profiles.mrn"))
+	assert.Equal(t, current, values[0])
+	beforeBytes, _ := old.Encrypt([]byte("MRN-0042"), []byte("profiles.mrn"))
+	assert.Equal(t, beforeBytes, values[1])
+
+	newer, _ := NewDeterministic(map[string][]byte{"k2": testKey(2)}, "k2")
+	_, err = newer.DecryptString(before, "profiles.mrn")
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+}
+
+func TestNewDeterministic(t *testing.T) {
+	_, err := NewDeterministic(map[string][]byte{"k1": testKey(1)}, "k2")
+	assert.Error(t, err)
+	_, err = NewDeterministic(map[string][]byte{"k1": []byte("short")}, "k1")
+	assert.Error(t, err)
+}
+
+func TestLoadDeterministic(t *testing.T) {
+	client := newFakeKMS()
+	ctx := context.Background()
+	e := NewEnvelope(client, "alias/search", EnvelopeOptions{})
+	// A data key to store in config
+	key, err := e.dataKey(ctx)
+	require.NoError(t, err)
+
+	d, err := LoadDeterministic(ctx, client, map[string][]byte{"k1": key.encrypted}, nil, "k1")
+	require.NoError(t, err)
+	direct, err := NewDeterministic(map[string][]byte{"k1": client.keys[string(key.encrypted)]}, "k1")
+	require.NoError(t, err)
+	a, _ := d.Encrypt([]byte("x"), nil)
+	b, _ := direct.Encrypt([]byte("x"), nil)
+	assert.Equal(t, a, b)
+
+	_, err = LoadDeterministic(ctx, client, map[string][]byte{"k1": []byte("bogus")}, nil, "k1")
+	assert.ErrorContains(t, err, `unable to decrypt key "k1"`)
+}
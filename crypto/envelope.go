@@ -0,0 +1,245 @@
+// Package crypto encrypts PHI before it's stored, like columns holding
+// diagnoses or social security numbers.
+//
+// Envelope encrypts each value with AES-GCM under a data key from KMS, and
+// stores the data key, encrypted by KMS, in the value's header, so values
+// encrypted before a key is rotated, or under a different key, still
+// decrypt:
+//
+//	envelope := crypto.NewEnvelope(kmsClient, keyARN, crypto.EnvelopeOptions{})
+//	ciphertext, err := envelope.Encrypt(ctx, []byte(diagnosis), []byte("care_plans.diagnosis"))
+//
+// The additional data, usually the column, has to be the same to decrypt,
+// so a value can't be copied into another column and decrypted there.
+//
+// Deterministic encrypts the same plaintext to the same ciphertext, for
+// fields that are looked up by value; see its docs for what that costs.
+package crypto
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+
+	"github.com/seniorlink-vela/cs-common/cache"
+)
+
+// The defaults for EnvelopeOptions' zero fields.
+const (
+	DefaultDataKeyMaxAge  = 5 * time.Minute
+	DefaultDataKeyMaxUses = 10000
+	DefaultDataKeyCache   = 1000
+)
+
+// The header's version byte, and the modes that follow it.
+const (
+	formatVersion     byte = 1
+	modeEnvelope      byte = 1
+	modeDeterministic byte = 2
+)
+
+// ErrInvalidCiphertext is returned for values that weren't encrypted by this
+// package, or were changed since, or are decrypted with different
+// additional data.
+var ErrInvalidCiphertext = errors.New("crypto: invalid ciphertext")
+
+// KMSAPI is the part of the KMS client used for data keys.  *kms.Client
+// implements it.
+type KMSAPI interface {
+	GenerateDataKey(ctx context.Context, params *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error)
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+var _ KMSAPI = (*kms.Client)(nil)
+
+// EnvelopeOptions configures an Envelope.
+type EnvelopeOptions struct {
+	// EncryptionContext is sent to KMS with the data keys, and shows in
+	// CloudTrail.  It has to be the same to decrypt.
+	EncryptionContext map[string]string
+	// A data key is used for up to DataKeyMaxAge or DataKeyMaxUses values,
+	// whichever comes first, rather than calling KMS for every value.
+	// They default to DefaultDataKeyMaxAge and DefaultDataKeyMaxUses.
+	DataKeyMaxAge  time.Duration
+	DataKeyMaxUses int
+	// DataKeyCache is how many decrypted data keys are kept in memory, for
+	// reading back values, and defaults to DefaultDataKeyCache.
+	DataKeyCache int
+}
+
+type dataKey struct {
+	aead      cipher.AEAD
+	encrypted []byte
+	created   time.Time
+	uses      int
+}
+
+// Envelope encrypts values under KMS data keys.
+type Envelope struct {
+	client  KMSAPI
+	keyID   string
+	options EnvelopeOptions
+
+	mu      sync.Mutex
+	current *dataKey
+	// plaintext data keys, by a hash of the encrypted key.  They're kept in
+	// memory only: never put them in a shared backend.
+	keys *cache.Cache[[]byte]
+}
+
+// NewEnvelope returns an Envelope that encrypts with data keys under the KMS
+// key, which can be an ID, ARN or alias.
+func NewEnvelope(client KMSAPI, keyID string, options EnvelopeOptions) *Envelope {
+	if options.DataKeyMaxAge <= 0 {
+		options.DataKeyMaxAge = DefaultDataKeyMaxAge
+	}
+	if options.DataKeyMaxUses <= 0 {
+		options.DataKeyMaxUses = DefaultDataKeyMaxUses
+	}
+	if options.DataKeyCache <= 0 {
+		options.DataKeyCache = DefaultDataKeyCache
+	}
+	return &Envelope{
+		client:  client,
+		keyID:   keyID,
+		options: options,
+		keys:    cache.New[[]byte](cache.NewMemory(options.DataKeyCache), cache.Options{TTL: options.DataKeyMaxAge}),
+	}
+}
+
+// Encrypt encrypts the plaintext.  The ciphertext is
+//
+//	version | mode | encrypted data key length (2 bytes) | encrypted data key | nonce | sealed plaintext
+func (e *Envelope) Encrypt(ctx context.Context, plaintext, additionalData []byte) ([]byte, error) {
+	key, err := e.dataKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 0, 4+len(key.encrypted))
+	header = append(header, formatVersion, modeEnvelope)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(key.encrypted)))
+	header = append(header, key.encrypted...)
+
+	nonce := make([]byte, key.aead.NonceSize(), key.aead.NonceSize()+len(plaintext)+key.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	// The header is authenticated too, so it can't be swapped
+	sealed := key.aead.Seal(nonce, nonce, plaintext, append(header[:len(header):len(header)], additionalData...))
+	return append(header, sealed...), nil
+}
+
+// dataKey returns the current data key, generating a new one when it's been
+// used enough.
+func (e *Envelope) dataKey(ctx context.Context) (*dataKey, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if key := e.current; key != nil && key.uses < e.options.DataKeyMaxUses && time.Since(key.created) < e.options.DataKeyMaxAge {
+		key.uses++
+		return key, nil
+	}
+	out, err := e.client.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+		KeyId:             aws.String(e.keyID),
+		KeySpec:           types.DataKeySpecAes256,
+		EncryptionContext: e.options.EncryptionContext,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("crypto: unable to generate a data key: %w", err)
+	}
+	if len(out.CiphertextBlob) > 0xffff {
+		return nil, errors.New("crypto: the encrypted data key is too long")
+	}
+	aead, err := newAEAD(out.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+	e.current = &dataKey{aead: aead, encrypted: out.CiphertextBlob, created: time.Now(), uses: 1}
+	return e.current, nil
+}
+
+// Decrypt decrypts a value from Encrypt, with the same additional data.
+func (e *Envelope) Decrypt(ctx context.Context, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < 4 || ciphertext[0] != formatVersion || ciphertext[1] != modeEnvelope {
+		return nil, ErrInvalidCiphertext
+	}
+	keyLen := int(binary.BigEndian.Uint16(ciphertext[2:4]))
+	if len(ciphertext) < 4+keyLen {
+		return nil, ErrInvalidCiphertext
+	}
+	header, sealed := ciphertext[:4+keyLen], ciphertext[4+keyLen:]
+	encrypted := header[4:]
+
+	hash := sha256.Sum256(encrypted)
+	plaintextKey, err := e.keys.GetOrFill(ctx, base64.RawStdEncoding.EncodeToString(hash[:]), func(ctx context.Context) ([]byte, error) {
+		out, err := e.client.Decrypt(ctx, &kms.DecryptInput{
+			CiphertextBlob:    encrypted,
+			EncryptionContext: e.options.EncryptionContext,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("crypto: unable to decrypt the data key: %w", err)
+		}
+		return out.Plaintext, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(plaintextKey)
+	if err != nil {
+		return nil, err
+	}
+	return open(aead, sealed, append(header[:len(header):len(header)], additionalData...))
+}
+
+// EncryptString encrypts a string, returning base64 for text columns.
+func (e *Envelope) EncryptString(ctx context.Context, plaintext, additionalData string) (string, error) {
+	ciphertext, err := e.Encrypt(ctx, []byte(plaintext), []byte(additionalData))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// DecryptString decrypts a value from EncryptString.
+func (e *Envelope) DecryptString(ctx context.Context, ciphertext, additionalData string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	plaintext, err := e.Decrypt(ctx, decoded, []byte(additionalData))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: invalid data key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// open opens nonce | sealed.
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrInvalidCiphertext
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additionalData)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,119 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKMS wraps data keys by remembering them.
+type fakeKMS struct {
+	mu        sync.Mutex
+	keys      map[string][]byte
+	generated int
+	decrypted int
+}
+
+func newFakeKMS() *fakeKMS {
+	return &fakeKMS{keys: map[string][]byte{}}
+}
+
+func (f *fakeKMS) GenerateDataKey(_ context.Context, in *kms.GenerateDataKeyInput, _ ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.generated++
+	plaintext := make([]byte, 32)
+	rand.Read(plaintext)
+	blob := fmt.Sprintf("%s/%s/%d", aws.ToString(in.KeyId), in.EncryptionContext["service"], f.generated)
+	f.keys[blob] = plaintext
+	return &kms.GenerateDataKeyOutput{CiphertextBlob: []byte(blob), Plaintext: plaintext, KeyId: in.KeyId}, nil
+}
+
+func (f *fakeKMS) Decrypt(_ context.Context, in *kms.DecryptInput, _ ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.decrypted++
+	plaintext, ok := f.keys[string(in.CiphertextBlob)]
+	if !ok || !bytes.Contains(in.CiphertextBlob, []byte("/"+in.EncryptionContext["service"]+"/")) {
+		return nil, errors.New("InvalidCiphertextException")
+	}
+	return &kms.DecryptOutput{Plaintext: plaintext}, nil
+}
+
+func TestEnvelope(t *testing.T) {
+	client := newFakeKMS()
+	e := NewEnvelope(client, "alias/phi", EnvelopeOptions{EncryptionContext: map[string]string{"service": "careplans"}})
+	ctx := context.Background()
+
+	ciphertext, err := e.Encrypt(ctx, []byte("type 2 diabetes"), []byte("care_plans.diagnosis"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(ciphertext), "diabetes")
+	again, err := e.Encrypt(ctx, []byte("type 2 diabetes"), []byte("care_plans.diagnosis"))
+	require.NoError(t, err)
+	assert.NotEqual(t, ciphertext, again)
+	assert.Equal(t, 1, client.generated)
+
+	// Another instance, without the data key, decrypts it with KMS
+	other := NewEnvelope(client, "alias/phi", EnvelopeOptions{EncryptionContext: map[string]string{"service": "careplans"}})
+	for _, c := range [][]byte{ciphertext, again, ciphertext} {
+		plaintext, err := other.Decrypt(ctx, c, []byte("care_plans.diagnosis"))
+		require.NoError(t, err)
+		assert.Equal(t, "type 2 diabetes", string(plaintext))
+	}
+	assert.Equal(t, 1, client.decrypted)
+
+	_, err = other.Decrypt(ctx, ciphertext, []byte("profiles.notes"))
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+	tampered := bytes.Clone(ciphertext)
+	tampered[len(tampered)-1] ^= 1
+	_, err = other.Decrypt(ctx, tampered, []byte("care_plans.diagnosis"))
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+	_, err = other.Decrypt(ctx, []byte("plaintext"), nil)
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	wrongContext := NewEnvelope(client, "alias/phi", EnvelopeOptions{EncryptionContext: map[string]string{"service": "billing"}})
+	_, err = wrongContext.Decrypt(ctx, ciphertext, []byte("care_plans.diagnosis"))
+	assert.ErrorContains(t, err, "unable to decrypt the data key")
+}
+
+func TestEnvelopeDataKeyRotation(t *testing.T) {
+	client := newFakeKMS()
+	e := NewEnvelope(client, "alias/phi", EnvelopeOptions{DataKeyMaxUses: 2, DataKeyMaxAge: time.Hour})
+	ctx := context.Background()
+	var ciphertexts [][]byte
+	for range 5 {
+		c, err := e.Encrypt(ctx, []byte("x"), nil)
+		require.NoError(t, err)
+		ciphertexts = append(ciphertexts, c)
+	}
+	assert.Equal(t, 3, client.generated)
+
+	// Values under the old KMS key still decrypt after moving to a new one
+	rotated := NewEnvelope(client, "alias/phi-2025", EnvelopeOptions{})
+	for _, c := range ciphertexts {
+		plaintext, err := rotated.Decrypt(ctx, c, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "x", string(plaintext))
+	}
+}
+
+func TestEnvelopeStrings(t *testing.T) {
+	e := NewEnvelope(newFakeKMS(), "alias/phi", EnvelopeOptions{})
+	ciphertext, err := e.EncryptString(context.Background(), "123-45-6789", "profiles.ssn")
+	require.NoError(t, err)
+	plaintext, err := e.DecryptString(context.Background(), ciphertext, "profiles.ssn")
+	require.NoError(t, err)
+	assert.Equal(t, "123-45-6789", plaintext)
+	_, err = e.DecryptString(context.Background(), "not base64!", "profiles.ssn")
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+}
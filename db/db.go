@@ -0,0 +1,155 @@
+// Package db connects to Postgres with a pgx pool configured from the config
+// package, and logs queries with the context's logger, so they carry the
+// request ID.
+//
+//	var settings db.Config
+//	config.RegisterSection("database", &settings)
+//	...
+//	pool, err := db.Open(ctx, settings)
+//	health.Register(health.Check{Name: "postgres", Kind: health.Readiness, Func: pool.HealthCheck()})
+package db
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/seniorlink-vela/cs-common/health"
+)
+
+// The defaults for Config's zero fields.
+const (
+	DefaultPort            = 5432
+	DefaultSSLMode         = "require"
+	DefaultMaxConns        = 10
+	DefaultMaxConnLifetime = 30 * time.Minute
+	DefaultMaxConnIdleTime = 5 * time.Minute
+	DefaultConnectTimeout  = 5 * time.Second
+	DefaultSlowQuery       = 500 * time.Millisecond
+)
+
+// Config is the database's settings, for registering as a config section.
+// Either set URL, or the host and the rest.
+type Config struct {
+	URL      string `mapstructure:"url" json:"url"`
+	Host     string `mapstructure:"host" json:"host"`
+	Port     int    `mapstructure:"port" json:"port"`
+	Name     string `mapstructure:"name" json:"name"`
+	User     string `mapstructure:"user" json:"user"`
+	Password string `mapstructure:"password" json:"password"`
+	SSLMode  string `mapstructure:"sslmode" json:"sslmode"`
+
+	MaxConns        int32         `mapstructure:"max_conns" json:"max_conns"`
+	MinConns        int32         `mapstructure:"min_conns" json:"min_conns"`
+	MaxConnLifetime time.Duration `mapstructure:"max_conn_lifetime" json:"max_conn_lifetime"`
+	MaxConnIdleTime time.Duration `mapstructure:"max_conn_idle_time" json:"max_conn_idle_time"`
+	ConnectTimeout  time.Duration `mapstructure:"connect_timeout" json:"connect_timeout"`
+	// StatementTimeout cancels statements that run longer, in the
+	// database.  Zero leaves the database's setting.
+	StatementTimeout time.Duration `mapstructure:"statement_timeout" json:"statement_timeout"`
+	// SlowQuery is how long a query takes before it's logged as a
+	// warning.
+	SlowQuery       time.Duration `mapstructure:"slow_query" json:"slow_query"`
+	ApplicationName string        `mapstructure:"application_name" json:"application_name"`
+}
+
+// connString returns the URL, or one built from the host and the rest.
+func (c Config) connString() string {
+	if c.URL != "" {
+		return c.URL
+	}
+	port := c.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+	sslMode := c.SSLMode
+	if sslMode == "" {
+		sslMode = DefaultSSLMode
+	}
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     net.JoinHostPort(c.Host, strconv.Itoa(port)),
+		Path:     "/" + c.Name,
+		RawQuery: url.Values{"sslmode": {sslMode}}.Encode(),
+	}
+	return u.String()
+}
+
+// PoolConfig returns the pgx pool config for the settings, with query
+// logging.  Use it to change what Config doesn't cover before calling
+// OpenPool.
+func (c Config) PoolConfig(logger *zap.Logger) (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(c.connString())
+	if err != nil {
+		// The error includes the connection string, password and all
+		return nil, fmt.Errorf("db: invalid connection settings")
+	}
+	poolConfig.MaxConns = orDefault(c.MaxConns, DefaultMaxConns)
+	poolConfig.MinConns = c.MinConns
+	poolConfig.MaxConnLifetime = orDefault(c.MaxConnLifetime, DefaultMaxConnLifetime)
+	poolConfig.MaxConnIdleTime = orDefault(c.MaxConnIdleTime, DefaultMaxConnIdleTime)
+	poolConfig.ConnConfig.ConnectTimeout = orDefault(c.ConnectTimeout, DefaultConnectTimeout)
+	if c.StatementTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(c.StatementTimeout.Milliseconds(), 10)
+	}
+	if c.ApplicationName != "" {
+		poolConfig.ConnConfig.RuntimeParams["application_name"] = c.ApplicationName
+	}
+	poolConfig.ConnConfig.Tracer = &Tracer{SlowQuery: orDefault(c.SlowQuery, DefaultSlowQuery), Logger: logger}
+	return poolConfig, nil
+}
+
+func orDefault[T int32 | time.Duration](value, def T) T {
+	if value <= 0 {
+		return def
+	}
+	return value
+}
+
+// DB is a connection pool.
+type DB struct {
+	*pgxpool.Pool
+}
+
+// Open connects to the database, logging queries with the context's logger,
+// or the fallback logger when there isn't one.
+func Open(ctx context.Context, c Config) (*DB, error) {
+	poolConfig, err := c.PoolConfig(nil)
+	if err != nil {
+		return nil, err
+	}
+	return OpenPool(ctx, poolConfig)
+}
+
+// OpenPool connects to the database with the pgx pool config.
+func OpenPool(ctx context.Context, poolConfig *pgxpool.Config) (*DB, error) {
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("db: unable to connect: %w", err)
+	}
+	return &DB{Pool: pool}, nil
+}
+
+// Pinger is the part of a pool the health check uses.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// HealthCheck checks the database answers.
+func (db *DB) HealthCheck() health.CheckFunc {
+	return PingCheck(db)
+}
+
+// PingCheck checks that the pinger can reach the database.
+func PingCheck(p Pinger) health.CheckFunc {
+	return func(ctx context.Context) error {
+		return p.Ping(ctx)
+	}
+}
@@ -0,0 +1,327 @@
+// Package migrate applies a service's SQL migrations on startup, holding a
+// Postgres advisory lock so only one instance applies them at a time.
+//
+// Migrations are files named <version>_<name>.sql, usually embedded:
+//
+//	//go:embed migrations/*.sql
+//	var migrations embed.FS
+//
+//	var settings migrate.Options
+//	config.RegisterSection("migrations", &settings)
+//	...
+//	sub, _ := fs.Sub(migrations, "migrations")
+//	m, err := migrate.New(sub, settings)
+//	err = m.Up(ctx, pool)
+//	health.Register(health.Check{Name: "schema", Kind: health.Readiness, Func: m.HealthCheck()})
+//
+// Each migration runs in its own transaction, with its version recorded in
+// the same transaction.  There are no down migrations: fix mistakes with
+// another migration.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/db"
+	"github.com/seniorlink-vela/cs-common/health"
+)
+
+// The defaults for Options' zero fields.
+const (
+	DefaultTable           = "schema_migrations"
+	DefaultRefreshInterval = time.Minute
+)
+
+// ErrNotApplied is the health check's error before Up succeeds.
+var ErrNotApplied = errors.New("migrations haven't been applied")
+
+// Options are the migration settings, for registering as a config section.
+type Options struct {
+	// Table defaults to DefaultTable.  It may include a schema.
+	Table string `mapstructure:"table" json:"table"`
+	// LockKey is the advisory lock held while migrating.  It defaults to
+	// a hash of the table name, so services sharing a database with
+	// different tables don't wait on each other.
+	LockKey int64 `mapstructure:"lock_key" json:"lock_key"`
+	// Disabled skips applying migrations in Up, for services that run
+	// them as a separate step.  The health check still checks the schema
+	// is current, reading it again from Up's pool when it's older than
+	// RefreshInterval, since the step can run while the service is up.
+	Disabled bool `mapstructure:"disabled" json:"disabled"`
+	// RefreshInterval defaults to DefaultRefreshInterval.
+	RefreshInterval time.Duration `mapstructure:"refresh_interval" json:"refresh_interval"`
+}
+
+// Migration is one SQL file.
+type Migration struct {
+	Version int64
+	Name    string
+	SQL     string
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_([^.]+)\.sql$`)
+
+// Load reads the migrations in the root of fsys, in version order.  Files
+// that aren't named <version>_<name>.sql are ignored.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("migrate: unable to read migrations: %w", err)
+	}
+	var migrations []Migration
+	seen := map[int64]string{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: invalid version in %s: %w", entry.Name(), err)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrate: %s and %s have the same version", other, entry.Name())
+		}
+		seen[version] = entry.Name()
+		sql, err := fs.ReadFile(fsys, path.Clean(entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("migrate: unable to read %s: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: match[2], SQL: string(sql)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Conn is the part of a connection the migrator uses.  Advisory locks
+// belong to a session, so it's a connection rather than a pool.
+type Conn interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// Migrator applies a set of migrations.
+type Migrator struct {
+	migrations []Migration
+	options    Options
+
+	mu      sync.Mutex
+	applied []int64
+	checked bool
+	// When Disabled, where the health check reads the applied versions
+	// again, and when it last did.
+	refreshFrom Conn
+	refreshed   time.Time
+}
+
+// New loads the migrations in fsys.
+func New(fsys fs.FS, options Options) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	if options.Table == "" {
+		options.Table = DefaultTable
+	}
+	if options.RefreshInterval <= 0 {
+		options.RefreshInterval = DefaultRefreshInterval
+	}
+	if options.LockKey == 0 {
+		h := fnv.New64a()
+		h.Write([]byte(options.Table))
+		options.LockKey = int64(h.Sum64())
+	}
+	return &Migrator{migrations: migrations, options: options}, nil
+}
+
+// Up applies the pending migrations on a connection from the pool, or, when
+// the options disable that, only reads which are applied, and keeps the pool
+// for the health check to read them again.
+func (m *Migrator) Up(ctx context.Context, pool *db.DB) error {
+	if m.options.Disabled {
+		m.mu.Lock()
+		m.refreshFrom = pool
+		m.mu.Unlock()
+		return m.Refresh(ctx, pool)
+	}
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("migrate: unable to connect: %w", err)
+	}
+	defer conn.Release()
+	return m.Apply(ctx, conn)
+}
+
+// Apply applies the pending migrations on the connection.
+func (m *Migrator) Apply(ctx context.Context, conn Conn) error {
+	logger := velacontext.GetContextLogger(ctx)
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", m.options.LockKey); err != nil {
+		return fmt.Errorf("migrate: unable to lock: %w", err)
+	}
+	defer func() {
+		// The context may be done, and the lock has to go regardless
+		if _, err := conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", m.options.LockKey); err != nil {
+			logger.Warn("Unable to unlock migrations", zap.Error(err))
+		}
+	}()
+
+	table := pgx.Identifier(splitTable(m.options.Table)).Sanitize()
+	if _, err := conn.Exec(ctx, "CREATE TABLE IF NOT EXISTS "+table+" (version bigint PRIMARY KEY, name text NOT NULL, applied_at timestamptz NOT NULL DEFAULT now())"); err != nil {
+		return fmt.Errorf("migrate: unable to create %s: %w", m.options.Table, err)
+	}
+	applied, err := m.read(ctx, conn)
+	if err != nil {
+		return err
+	}
+	done := make(map[int64]bool, len(applied))
+	for _, version := range applied {
+		done[version] = true
+	}
+
+	for _, migration := range m.migrations {
+		if done[migration.Version] {
+			continue
+		}
+		start := time.Now()
+		if err := apply(ctx, conn, table, migration); err != nil {
+			return fmt.Errorf("migrate: %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		logger.Info("Applied migration", zap.Int64("version", migration.Version), zap.String("name", migration.Name), zap.Duration("duration", time.Since(start)))
+		applied = append(applied, migration.Version)
+		m.record(applied)
+	}
+	m.record(applied)
+	return nil
+}
+
+func apply(ctx context.Context, conn Conn, table string, migration Migration) (err error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback(context.WithoutCancel(ctx))
+		}
+	}()
+	if _, err = tx.Exec(ctx, migration.SQL); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, "INSERT INTO "+table+" (version, name) VALUES ($1, $2)", migration.Version, migration.Name); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Refresh reads which migrations are applied, without applying any.
+func (m *Migrator) Refresh(ctx context.Context, conn Conn) error {
+	applied, err := m.read(ctx, conn)
+	if err != nil {
+		return err
+	}
+	m.record(applied)
+	return nil
+}
+
+func (m *Migrator) read(ctx context.Context, conn Conn) ([]int64, error) {
+	table := pgx.Identifier(splitTable(m.options.Table)).Sanitize()
+	var applied []int64
+	if err := conn.QueryRow(ctx, "SELECT coalesce(array_agg(version ORDER BY version), '{}') FROM "+table).Scan(&applied); err != nil {
+		return nil, fmt.Errorf("migrate: unable to read %s: %w", m.options.Table, err)
+	}
+	return applied, nil
+}
+
+func (m *Migrator) record(applied []int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.applied = append([]int64(nil), applied...)
+	sort.Slice(m.applied, func(i, j int) bool { return m.applied[i] < m.applied[j] })
+	m.checked = true
+	m.refreshed = time.Now()
+}
+
+// Applied is the versions applied when they were last read, in order.
+func (m *Migrator) Applied() []int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]int64(nil), m.applied...)
+}
+
+// Pending is the migrations that weren't applied when they were last read.
+func (m *Migrator) Pending() []Migration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	done := make(map[int64]bool, len(m.applied))
+	for _, version := range m.applied {
+		done[version] = true
+	}
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if !done[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	return pending
+}
+
+// HealthCheck fails until Up has run and left nothing pending, saying which
+// version the schema is at.  When migrations are disabled, it reads which
+// are applied again every RefreshInterval.
+func (m *Migrator) HealthCheck() health.CheckFunc {
+	return func(ctx context.Context) error {
+		m.mu.Lock()
+		conn := m.refreshFrom
+		stale := time.Since(m.refreshed) >= m.options.RefreshInterval
+		m.mu.Unlock()
+		if conn != nil && stale {
+			if err := m.Refresh(ctx, conn); err != nil {
+				return err
+			}
+		}
+
+		m.mu.Lock()
+		checked := m.checked
+		m.mu.Unlock()
+		if !checked {
+			return ErrNotApplied
+		}
+		if pending := m.Pending(); len(pending) > 0 {
+			return fmt.Errorf("schema is at version %d, with %d migrations pending from %d", m.version(), len(pending), pending[0].Version)
+		}
+		return nil
+	}
+}
+
+func (m *Migrator) version() int64 {
+	applied := m.Applied()
+	if len(applied) == 0 {
+		return 0
+	}
+	return applied[len(applied)-1]
+}
+
+// splitTable splits a schema from the table name.
+func splitTable(table string) []string {
+	if i := strings.LastIndexByte(table, '.'); i >= 0 {
+		return []string{table[:i], table[i+1:]}
+	}
+	return []string{table}
+}
@@ -0,0 +1,180 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var migrations = fstest.MapFS{
+	"0002_add_care_plans.sql":   {Data: []byte("CREATE TABLE care_plans (id uuid PRIMARY KEY)")},
+	"0001_create_profiles.sql":  {Data: []byte("CREATE TABLE profiles (id uuid PRIMARY KEY)")},
+	"0003_add_plan_goals.sql":   {Data: []byte("ALTER TABLE care_plans ADD COLUMN goals jsonb")},
+	"README.md":                 {Data: []byte("Migrations")},
+	"archive/0001_old_idea.sql": {Data: []byte("DROP TABLE profiles")},
+}
+
+func TestLoad(t *testing.T) {
+	loaded, err := Load(migrations)
+	require.NoError(t, err)
+	require.Len(t, loaded, 3)
+	assert.Equal(t, int64(1), loaded[0].Version)
+	assert.Equal(t, "create_profiles", loaded[0].Name)
+	assert.Equal(t, "CREATE TABLE profiles (id uuid PRIMARY KEY)", loaded[0].SQL)
+	assert.Equal(t, int64(3), loaded[2].Version)
+
+	_, err = Load(fstest.MapFS{"1_a.sql": {}, "01_b.sql": {}})
+	assert.ErrorContains(t, err, "same version")
+}
+
+// fakeConn is a database where the migrations table holds applied, and
+// statements containing fail fail.
+type fakeConn struct {
+	mu        sync.Mutex
+	applied   []int64
+	fail      string
+	statement []string
+}
+
+func (c *fakeConn) exec(sql string, args ...any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statement = append(c.statement, sql)
+	if c.fail != "" && strings.Contains(sql, c.fail) {
+		return errors.New("syntax error")
+	}
+	if strings.HasPrefix(sql, "INSERT INTO") {
+		c.applied = append(c.applied, args[0].(int64))
+	}
+	return nil
+}
+
+func (c *fakeConn) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, c.exec(sql, args...)
+}
+
+func (c *fakeConn) QueryRow(_ context.Context, sql string, _ ...any) pgx.Row {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statement = append(c.statement, sql)
+	return row{applied: append([]int64(nil), c.applied...)}
+}
+
+func (c *fakeConn) Begin(context.Context) (pgx.Tx, error) {
+	return &fakeTx{conn: c}, nil
+}
+
+type row struct{ applied []int64 }
+
+func (r row) Scan(dest ...any) error {
+	*dest[0].(*[]int64) = r.applied
+	return nil
+}
+
+// fakeTx applies its statements on commit.
+type fakeTx struct {
+	pgx.Tx
+	conn    *fakeConn
+	pending [][]any
+}
+
+func (tx *fakeTx) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	if tx.conn.fail != "" && strings.Contains(sql, tx.conn.fail) {
+		return pgconn.CommandTag{}, errors.New("syntax error")
+	}
+	tx.pending = append(tx.pending, append([]any{sql}, args...))
+	return pgconn.CommandTag{}, nil
+}
+
+func (tx *fakeTx) Commit(context.Context) error {
+	for _, statement := range tx.pending {
+		if err := tx.conn.exec(statement[0].(string), statement[1:]...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tx *fakeTx) Rollback(context.Context) error { return nil }
+
+func TestApply(t *testing.T) {
+	m, err := New(migrations, Options{})
+	require.NoError(t, err)
+	assert.ErrorIs(t, m.HealthCheck()(context.Background()), ErrNotApplied)
+
+	conn := &fakeConn{applied: []int64{1}}
+	require.NoError(t, m.Apply(context.Background(), conn))
+
+	assert.Equal(t, []int64{1, 2, 3}, conn.applied)
+	assert.Equal(t, []int64{1, 2, 3}, m.Applied())
+	assert.Empty(t, m.Pending())
+	assert.NoError(t, m.HealthCheck()(context.Background()))
+	assert.True(t, strings.HasPrefix(conn.statement[0], "SELECT pg_advisory_lock"))
+	assert.Contains(t, conn.statement[1], `CREATE TABLE IF NOT EXISTS "schema_migrations"`)
+	assert.True(t, strings.HasPrefix(conn.statement[len(conn.statement)-1], "SELECT pg_advisory_unlock"))
+	assert.NotContains(t, strings.Join(conn.statement, "\n"), "CREATE TABLE profiles")
+
+	// Nothing to do the second time
+	conn.statement = nil
+	require.NoError(t, m.Apply(context.Background(), conn))
+	assert.Len(t, conn.statement, 4)
+}
+
+func TestApplyFailure(t *testing.T) {
+	m, err := New(migrations, Options{Table: "careplans.migrations"})
+	require.NoError(t, err)
+
+	conn := &fakeConn{fail: "ALTER TABLE"}
+	err = m.Apply(context.Background(), conn)
+	assert.ErrorContains(t, err, "3_add_plan_goals failed")
+	assert.Equal(t, []int64{1, 2}, conn.applied)
+	assert.Contains(t, conn.statement[1], `"careplans"."migrations"`)
+	assert.True(t, strings.HasPrefix(conn.statement[len(conn.statement)-1], "SELECT pg_advisory_unlock"))
+
+	require.Len(t, m.Pending(), 1)
+	assert.EqualError(t, m.HealthCheck()(context.Background()), "schema is at version 2, with 1 migrations pending from 3")
+}
+
+func TestRefresh(t *testing.T) {
+	m, err := New(migrations, Options{Disabled: true})
+	require.NoError(t, err)
+
+	conn := &fakeConn{applied: []int64{1, 2, 3}}
+	require.NoError(t, m.Refresh(context.Background(), conn))
+	assert.Len(t, conn.statement, 1)
+	assert.NoError(t, m.HealthCheck()(context.Background()))
+}
+
+func TestHealthCheckRefreshes(t *testing.T) {
+	m, err := New(migrations, Options{Disabled: true})
+	require.NoError(t, err)
+	conn := &fakeConn{applied: []int64{1, 2}}
+	m.refreshFrom = conn
+	require.NoError(t, m.Refresh(context.Background(), conn))
+	assert.EqualError(t, m.HealthCheck()(context.Background()), "schema is at version 2, with 1 migrations pending from 3")
+
+	// The migration step ran, and the check only notices once the last
+	// read is stale
+	conn.applied = []int64{1, 2, 3}
+	assert.Error(t, m.HealthCheck()(context.Background()))
+	assert.Len(t, conn.statement, 1)
+	m.refreshed = m.refreshed.Add(-DefaultRefreshInterval)
+	assert.NoError(t, m.HealthCheck()(context.Background()))
+	assert.Len(t, conn.statement, 2)
+}
+
+func TestLockKey(t *testing.T) {
+	a, _ := New(migrations, Options{Table: "a"})
+	b, _ := New(migrations, Options{Table: "b"})
+	c, _ := New(migrations, Options{Table: "a", LockKey: 42})
+	assert.NotEqual(t, a.options.LockKey, b.options.LockKey)
+	assert.Equal(t, int64(42), c.options.LockKey)
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/metrics"
+)
+
+// The metrics the tracer records with metrics.Default.
+var queryDurationMetric = metrics.Definition{
+	Name: "db_query_duration_ms",
+	Help: "How long database queries took.",
+	Unit: metrics.UnitMilliseconds,
+	Tags: []string{"status"},
+	// Milliseconds, rather than Prometheus' seconds
+	Buckets: []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500},
+}
+
+// Tracer logs queries with the context's logger: at debug level normally,
+// as a warning when they're slow, and as an error when they fail.  Query
+// arguments aren't logged, since they're often personal information.
+type Tracer struct {
+	SlowQuery time.Duration
+	// Logger is used when the context doesn't have one.
+	Logger *zap.Logger
+}
+
+var _ pgx.QueryTracer = (*Tracer)(nil)
+
+type queryKey struct{}
+
+type query struct {
+	sql   string
+	start time.Time
+}
+
+func (t *Tracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryKey{}, query{sql: data.SQL, start: time.Now()})
+}
+
+func (t *Tracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	q, ok := ctx.Value(queryKey{}).(query)
+	if !ok {
+		return
+	}
+	elapsed := time.Since(q.start)
+	status := "ok"
+	if data.Err != nil {
+		status = "error"
+	}
+	metrics.Default().Histogram(queryDurationMetric).Observe(float64(elapsed)/float64(time.Millisecond), metrics.Tags{"status": status})
+
+	logger := t.logger(ctx)
+	fields := []zap.Field{
+		zap.String("sql", compact(q.sql)),
+		zap.Duration("duration", elapsed),
+	}
+	switch {
+	case data.Err != nil:
+		logger.Error("Query failed", append(fields, zap.Error(data.Err))...)
+	case t.SlowQuery > 0 && elapsed >= t.SlowQuery:
+		logger.Warn("Slow query", append(fields, zap.Int64("rows", data.CommandTag.RowsAffected()))...)
+	default:
+		logger.Debug("Query", append(fields, zap.Int64("rows", data.CommandTag.RowsAffected()))...)
+	}
+}
+
+func (t *Tracer) logger(ctx context.Context) *zap.Logger {
+	if _, ok := velacontext.LookupLogger(ctx); ok || t.Logger == nil {
+		return velacontext.GetContextLogger(ctx)
+	}
+	return t.Logger
+}
+
+// compact puts the query on one line.
+func compact(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
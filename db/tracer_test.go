@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+func trace(ctx context.Context, tracer *Tracer, sql string, wait time.Duration, err error) {
+	ctx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: sql, Args: []any{"Jane Doe"}})
+	time.Sleep(wait)
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 1"), Err: err})
+}
+
+func TestTracer(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	ctx := velacontext.ContextWithLogger(context.Background(), zap.New(core).With(zap.String("request_id", "req-1")))
+	tracer := &Tracer{SlowQuery: 20 * time.Millisecond}
+
+	trace(ctx, tracer, "SELECT *\n\tFROM profiles\n\tWHERE name = $1", 0, nil)
+	trace(ctx, tracer, "SELECT pg_sleep(1)", 25*time.Millisecond, nil)
+	trace(ctx, tracer, "SELECT nope", 0, errors.New("column does not exist"))
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 3)
+	assert.Equal(t, zapcore.DebugLevel, entries[0].Level)
+	assert.Equal(t, "SELECT * FROM profiles WHERE name = $1", entries[0].ContextMap()["sql"])
+	assert.Equal(t, "req-1", entries[0].ContextMap()["request_id"])
+	assert.Equal(t, int64(1), entries[0].ContextMap()["rows"])
+	assert.Equal(t, zapcore.WarnLevel, entries[1].Level)
+	assert.Equal(t, zapcore.ErrorLevel, entries[2].Level)
+	assert.Equal(t, "column does not exist", entries[2].ContextMap()["error"])
+	for _, entry := range entries {
+		assert.NotContains(t, entry.ContextMap(), "args")
+	}
+}
+
+func TestTracerLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	trace(context.Background(), &Tracer{Logger: zap.New(core)}, "SELECT 1", 0, nil)
+	assert.Equal(t, 1, logs.Len())
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Hstore converts a map, like Profile.ExtendedProperties, to an hstore
+// value.
+func Hstore(m map[string]string) pgtype.Hstore {
+	if m == nil {
+		return nil
+	}
+	h := make(pgtype.Hstore, len(m))
+	for k, v := range m {
+		h[k] = &v
+	}
+	return h
+}
+
+// HstoreMap converts an hstore value to a map.  NULL values become empty
+// strings.
+func HstoreMap(h pgtype.Hstore) map[string]string {
+	if h == nil {
+		return nil
+	}
+	m := make(map[string]string, len(h))
+	for k, v := range h {
+		if v != nil {
+			m[k] = *v
+		} else {
+			m[k] = ""
+		}
+	}
+	return m
+}
+
+// JSONB is a json or jsonb column holding a T.  NULL is the zero T, and
+// Valid false.
+type JSONB[T any] struct {
+	V     T
+	Valid bool
+}
+
+// NewJSONB returns a valid JSONB holding v.
+func NewJSONB[T any](v T) JSONB[T] {
+	return JSONB[T]{V: v, Valid: true}
+}
+
+// Scan implements sql.Scanner.
+func (j *JSONB[T]) Scan(src any) error {
+	var zero T
+	j.V, j.Valid = zero, false
+	var data []byte
+	switch src := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		data = src
+	case string:
+		data = []byte(src)
+	default:
+		return fmt.Errorf("db: can't scan %T into JSONB", src)
+	}
+	if err := json.Unmarshal(data, &j.V); err != nil {
+		return err
+	}
+	j.Valid = true
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (j JSONB[T]) Value() (driver.Value, error) {
+	if !j.Valid {
+		return nil, nil
+	}
+	data, err := json.Marshal(j.V)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
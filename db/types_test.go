@@ -0,0 +1,46 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHstore(t *testing.T) {
+	props := map[string]string{"language": "es", "pronouns": ""}
+	h := Hstore(props)
+	require.Len(t, h, 2)
+	assert.Equal(t, "es", *h["language"])
+	assert.Equal(t, props, HstoreMap(h))
+
+	h["missing"] = nil
+	assert.Equal(t, "", HstoreMap(h)["missing"])
+	assert.Nil(t, Hstore(nil))
+	assert.Nil(t, HstoreMap(nil))
+}
+
+type carePlan struct {
+	Goals []string `json:"goals"`
+}
+
+func TestJSONB(t *testing.T) {
+	value, err := NewJSONB(carePlan{Goals: []string{"walk daily"}}).Value()
+	require.NoError(t, err)
+	assert.Equal(t, `{"goals":["walk daily"]}`, value)
+
+	var j JSONB[carePlan]
+	require.NoError(t, j.Scan([]byte(`{"goals":["hydrate"]}`)))
+	assert.True(t, j.Valid)
+	assert.Equal(t, []string{"hydrate"}, j.V.Goals)
+
+	require.NoError(t, j.Scan(nil))
+	assert.False(t, j.Valid)
+	assert.Empty(t, j.V.Goals)
+	value, err = j.Value()
+	require.NoError(t, err)
+	assert.Nil(t, value)
+
+	assert.Error(t, j.Scan(42))
+	assert.Error(t, j.Scan(`{`))
+}
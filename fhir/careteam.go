@@ -0,0 +1,203 @@
+package fhir
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/seniorlink-vela/cs-common/client"
+)
+
+// The care team roles, which are the member owner types the API uses.
+const (
+	RoleCareManager = "CareManager"
+	RoleCaregiver   = "Caregiver"
+)
+
+// Team is a consumer's care team.  The API only deals in IDs, so services put
+// it together from the profiles they've fetched.
+type Team struct {
+	ID       string
+	Name     string
+	Consumer client.Profile
+	Members  []TeamMember
+}
+
+// TeamMember is someone on a care team.
+type TeamMember struct {
+	Profile client.Profile
+	Role    string
+	// Primary is for the primary caregiver, who the API ranks 0
+	Primary bool
+}
+
+// NewCareTeam returns the team as a CareTeam, which refers to the people on
+// it by ID.  Care managers are Practitioners, and everyone else is a
+// RelatedPerson.
+func NewCareTeam(team Team) CareTeam {
+	ct := CareTeam{
+		ResourceType: TypeCareTeam,
+		ID:           team.ID,
+		Status:       "active",
+		Name:         team.Name,
+		Subject:      NewReference(TypePatient, team.Consumer.ID),
+	}
+	if team.ID != "" {
+		ct.Identifier = []Identifier{{System: SystemCareTeam, Value: team.ID}}
+	}
+	for _, m := range team.Members {
+		participant := CareTeamParticipant{
+			Role:   []CodeableConcept{role(m.Role)},
+			Member: NewReference(memberType(m.Role), m.Profile.ID),
+		}
+		if m.Primary {
+			primary := true
+			participant.Extension = []Extension{{URL: ExtensionPrimaryCaregiver, ValueBoolean: &primary}}
+		}
+		ct.Participant = append(ct.Participant, participant)
+	}
+	return ct
+}
+
+// TeamFromCareTeam is the reverse of NewCareTeam, but the profiles only have
+// their IDs.  Use TeamFromBundle to get the rest.  Participants that aren't
+// people, like Organizations, are skipped.
+func TeamFromCareTeam(ct CareTeam) (Team, error) {
+	if ct.ResourceType != TypeCareTeam {
+		return Team{}, fmt.Errorf("%w: %q isn't a %s", ErrWrongResource, ct.ResourceType, TypeCareTeam)
+	}
+	subjectType, consumerID, ok := ct.Subject.Target()
+	if !ok || subjectType != TypePatient {
+		return Team{}, fmt.Errorf("fhir: care team subject %q isn't a Patient", ct.Subject.Reference)
+	}
+	team := Team{ID: ct.ID, Name: ct.Name, Consumer: client.Profile{ID: consumerID}}
+	for _, id := range ct.Identifier {
+		if team.ID == "" && id.System == SystemCareTeam {
+			team.ID = id.Value
+		}
+	}
+	for _, participant := range ct.Participant {
+		memberType, memberID, ok := participant.Member.Target()
+		if !ok {
+			return Team{}, fmt.Errorf("fhir: invalid care team member %q", participant.Member.Reference)
+		}
+		if memberType != TypePractitioner && memberType != TypeRelatedPerson {
+			continue
+		}
+		member := TeamMember{Profile: client.Profile{ID: memberID}, Role: RoleCaregiver}
+		if memberType == TypePractitioner {
+			member.Role = RoleCareManager
+		}
+		for _, r := range participant.Role {
+			if code, ok := r.code(SystemCareTeamRole); ok {
+				member.Role = code
+				break
+			}
+		}
+		if e, ok := findExtension(participant.Extension, ExtensionPrimaryCaregiver); ok {
+			member.Primary = e.ValueBoolean != nil && *e.ValueBoolean
+		}
+		team.Members = append(team.Members, member)
+	}
+	return team, nil
+}
+
+// NewBundle returns a collection Bundle of the team's CareTeam, the consumer's
+// Patient, and the members.
+func NewBundle(team Team) (Bundle, error) {
+	resources := []interface{}{NewCareTeam(team), NewPatient(&team.Consumer)}
+	for i := range team.Members {
+		m := &team.Members[i]
+		if memberType(m.Role) == TypePractitioner {
+			resources = append(resources, NewPractitioner(&m.Profile))
+		} else {
+			rp := NewRelatedPerson(&m.Profile, team.Consumer.ID)
+			rp.Relationship = []CodeableConcept{role(m.Role)}
+			resources = append(resources, rp)
+		}
+	}
+	bundle := Bundle{ResourceType: TypeBundle, Type: "collection"}
+	for _, r := range resources {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return Bundle{}, fmt.Errorf("fhir: marshalling bundle: %w", err)
+		}
+		bundle.Entry = append(bundle.Entry, BundleEntry{Resource: data})
+	}
+	return bundle, nil
+}
+
+// TeamFromBundle is the reverse of NewBundle.  It reads the first CareTeam,
+// and fills in the profiles of the people in the Bundle.  Anyone who isn't
+// only has their ID.
+func TeamFromBundle(bundle Bundle) (Team, error) {
+	if bundle.ResourceType != TypeBundle {
+		return Team{}, fmt.Errorf("%w: %q isn't a %s", ErrWrongResource, bundle.ResourceType, TypeBundle)
+	}
+	var careTeam *CareTeam
+	profiles := map[string]client.Profile{}
+	for _, entry := range bundle.Entry {
+		var (
+			p   client.Profile
+			err error
+		)
+		resourceType := entry.ResourceType()
+		switch resourceType {
+		case TypeCareTeam:
+			if careTeam == nil {
+				careTeam = &CareTeam{}
+				err = json.Unmarshal(entry.Resource, careTeam)
+			}
+		case TypePatient:
+			var pt Patient
+			if err = json.Unmarshal(entry.Resource, &pt); err == nil {
+				p, err = ProfileFromPatient(pt)
+			}
+		case TypeRelatedPerson:
+			var rp RelatedPerson
+			if err = json.Unmarshal(entry.Resource, &rp); err == nil {
+				p, err = ProfileFromRelatedPerson(rp)
+			}
+		case TypePractitioner:
+			var pr Practitioner
+			if err = json.Unmarshal(entry.Resource, &pr); err == nil {
+				p, err = ProfileFromPractitioner(pr)
+			}
+		}
+		if err != nil {
+			return Team{}, fmt.Errorf("fhir: reading %s: %w", resourceType, err)
+		}
+		if p.ID != "" {
+			profiles[resourceType+"/"+p.ID] = p
+		}
+	}
+	if careTeam == nil {
+		return Team{}, errors.New("fhir: bundle has no CareTeam")
+	}
+
+	team, err := TeamFromCareTeam(*careTeam)
+	if err != nil {
+		return Team{}, err
+	}
+	if p, ok := profiles[careTeam.Subject.Reference]; ok {
+		team.Consumer = p
+	}
+	for i := range team.Members {
+		m := &team.Members[i]
+		if p, ok := profiles[memberType(m.Role)+"/"+m.Profile.ID]; ok {
+			m.Profile = p
+		}
+	}
+	return team, nil
+}
+
+func memberType(role string) string {
+	if role == RoleCareManager {
+		return TypePractitioner
+	}
+	return TypeRelatedPerson
+}
+
+func role(code string) CodeableConcept {
+	return CodeableConcept{Coding: []Coding{{System: SystemCareTeamRole, Code: code}}}
+}
@@ -0,0 +1,91 @@
+package fhir
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/seniorlink-vela/cs-common/client"
+)
+
+func testTeam() Team {
+	consumer := testProfile()
+	return Team{
+		ID:       "42",
+		Name:     "The Dude's team",
+		Consumer: consumer,
+		Members: []TeamMember{
+			{Profile: client.Profile{ID: "cm-1", FirstName: str("Maude"), LastName: str("Lebowski")}, Role: RoleCareManager},
+			{Profile: client.Profile{ID: "cg-1", FirstName: str("Walter"), LastName: str("Sobchak")}, Role: RoleCaregiver, Primary: true},
+			{Profile: client.Profile{ID: "cg-2", FirstName: str("Donny")}, Role: RoleCaregiver},
+		},
+	}
+}
+
+func TestCareTeam(t *testing.T) {
+	ct := NewCareTeam(testTeam())
+	data, err := json.Marshal(ct.Participant[1])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"extension": [{"url": "`+ExtensionPrimaryCaregiver+`", "valueBoolean": true}],
+		"role": [{"coding": [{"system": "`+SystemCareTeamRole+`", "code": "Caregiver"}]}],
+		"member": {"reference": "RelatedPerson/cg-1"}
+	}`, string(data))
+	assert.Equal(t, "Patient/p-1", ct.Subject.Reference)
+	assert.Equal(t, "Practitioner/cm-1", ct.Participant[0].Member.Reference)
+
+	team, err := TeamFromCareTeam(ct)
+	require.NoError(t, err)
+	assert.Equal(t, "p-1", team.Consumer.ID)
+	assert.Equal(t, []TeamMember{
+		{Profile: client.Profile{ID: "cm-1"}, Role: RoleCareManager},
+		{Profile: client.Profile{ID: "cg-1"}, Role: RoleCaregiver, Primary: true},
+		{Profile: client.Profile{ID: "cg-2"}, Role: RoleCaregiver},
+	}, team.Members)
+
+	ct.Subject = Reference{Reference: "Group/1"}
+	_, err = TeamFromCareTeam(ct)
+	assert.Error(t, err)
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	team := testTeam()
+	bundle, err := NewBundle(team)
+	require.NoError(t, err)
+	require.Len(t, bundle.Entry, 5)
+	assert.Equal(t, "CareTeam", bundle.Entry[0].ResourceType())
+	assert.Equal(t, "Practitioner", bundle.Entry[2].ResourceType())
+
+	data, err := json.Marshal(bundle)
+	require.NoError(t, err)
+	var decoded Bundle
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	back, err := TeamFromBundle(decoded)
+	require.NoError(t, err)
+	assert.Equal(t, team, back)
+}
+
+func TestTeamFromBundleWithoutPeople(t *testing.T) {
+	// Participants that aren't people are skipped, and missing people only
+	// have their IDs
+	ct := CareTeam{
+		ResourceType: "CareTeam",
+		Subject:      NewReference("Patient", "p-1"),
+		Identifier:   []Identifier{{System: SystemCareTeam, Value: "42"}},
+		Participant: []CareTeamParticipant{
+			{Member: NewReference("Organization", "payer")},
+			{Member: NewReference("RelatedPerson", "cg-1")},
+		},
+	}
+	data, _ := json.Marshal(ct)
+	team, err := TeamFromBundle(Bundle{ResourceType: "Bundle", Type: "collection", Entry: []BundleEntry{{Resource: data}}})
+	require.NoError(t, err)
+	assert.Equal(t, "42", team.ID)
+	assert.Equal(t, []TeamMember{{Profile: client.Profile{ID: "cg-1"}, Role: RoleCaregiver}}, team.Members)
+
+	_, err = TeamFromBundle(Bundle{ResourceType: "Bundle"})
+	assert.Error(t, err)
+}
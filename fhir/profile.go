@@ -0,0 +1,395 @@
+package fhir
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/seniorlink-vela/cs-common/client"
+)
+
+// The resource types.
+const (
+	TypePatient       = "Patient"
+	TypeRelatedPerson = "RelatedPerson"
+	TypePractitioner  = "Practitioner"
+	TypeCareTeam      = "CareTeam"
+	TypeBundle        = "Bundle"
+)
+
+const dateLayout = "2006-01-02"
+
+// FHIR's genders.  Ours that it doesn't have are other or unknown, with the
+// original kept in ExtensionGender.
+var (
+	genderToFHIR = map[client.GenderOption]string{
+		client.GenderFemale:      "female",
+		client.GenderMale:        "male",
+		client.GenderTransgender: "other",
+		client.GenderUnspecified: "unknown",
+	}
+	genderFromFHIR = map[string]client.GenderOption{
+		"female":  client.GenderFemale,
+		"male":    client.GenderMale,
+		"unknown": client.GenderUnspecified,
+	}
+)
+
+// Our phone types that FHIR has a use for.  The others are kept in
+// ExtensionPhoneType.
+var phoneUses = map[string]string{"mobile": "mobile", "home": "home", "work": "work"}
+
+// NewPatient returns a consumer's profile as a Patient.
+func NewPatient(p *client.Profile) Patient {
+	return Patient{ResourceType: TypePatient, Person: newPerson(p), Communication: communication(p)}
+}
+
+// ProfileFromPatient is the reverse of NewPatient.
+func ProfileFromPatient(pt Patient) (client.Profile, error) {
+	if pt.ResourceType != TypePatient {
+		return client.Profile{}, fmt.Errorf("%w: %q isn't a %s", ErrWrongResource, pt.ResourceType, TypePatient)
+	}
+	p, err := pt.Person.profile()
+	if err != nil {
+		return client.Profile{}, err
+	}
+	p.Locale = preferredLanguage(pt.Communication)
+	return p, nil
+}
+
+// NewRelatedPerson returns a caregiver's profile as a RelatedPerson of the
+// consumer with the ID.
+func NewRelatedPerson(p *client.Profile, consumerID string) RelatedPerson {
+	return RelatedPerson{
+		ResourceType:  TypeRelatedPerson,
+		Person:        newPerson(p),
+		Patient:       NewReference(TypePatient, consumerID),
+		Relationship:  []CodeableConcept{role(RoleCaregiver)},
+		Communication: communication(p),
+	}
+}
+
+// ProfileFromRelatedPerson is the reverse of NewRelatedPerson.  The
+// consumer's ID is in the Patient reference.
+func ProfileFromRelatedPerson(rp RelatedPerson) (client.Profile, error) {
+	if rp.ResourceType != TypeRelatedPerson {
+		return client.Profile{}, fmt.Errorf("%w: %q isn't a %s", ErrWrongResource, rp.ResourceType, TypeRelatedPerson)
+	}
+	p, err := rp.Person.profile()
+	if err != nil {
+		return client.Profile{}, err
+	}
+	p.Locale = preferredLanguage(rp.Communication)
+	return p, nil
+}
+
+// NewPractitioner returns a professional's profile as a Practitioner.
+func NewPractitioner(p *client.Profile) Practitioner {
+	pr := Practitioner{ResourceType: TypePractitioner, Person: newPerson(p)}
+	if p.Locale != nil && *p.Locale != "" {
+		pr.Communication = []CodeableConcept{language(*p.Locale)}
+	}
+	return pr
+}
+
+// ProfileFromPractitioner is the reverse of NewPractitioner.
+func ProfileFromPractitioner(pr Practitioner) (client.Profile, error) {
+	if pr.ResourceType != TypePractitioner {
+		return client.Profile{}, fmt.Errorf("%w: %q isn't a %s", ErrWrongResource, pr.ResourceType, TypePractitioner)
+	}
+	p, err := pr.Person.profile()
+	if err != nil {
+		return client.Profile{}, err
+	}
+	for _, c := range pr.Communication {
+		if locale, ok := c.code(SystemLanguage); ok {
+			p.Locale = &locale
+			break
+		}
+	}
+	return p, nil
+}
+
+// newPerson maps the fields every kind of person has.  Profile extensions
+// (the ExtensionData) aren't mapped, since their values are free form.
+func newPerson(p *client.Profile) Person {
+	person := Person{ID: p.ID}
+	if p.ID != "" {
+		person.Identifier = append(person.Identifier, Identifier{System: SystemProfileID, Value: p.ID})
+	}
+	if s := deref(p.Username); s != "" {
+		person.Identifier = append(person.Identifier, Identifier{System: SystemUsername, Value: s})
+	}
+
+	name := HumanName{Use: "official", Family: deref(p.LastName)}
+	for _, given := range []*string{p.FirstName, p.MiddleName} {
+		if s := deref(given); s != "" {
+			name.Given = append(name.Given, s)
+		}
+	}
+	if name.Family != "" || len(name.Given) > 0 {
+		person.Name = []HumanName{name}
+	}
+
+	for i, email := range []*string{p.Email, p.SecondEmail} {
+		if s := deref(email); s != "" {
+			person.Telecom = append(person.Telecom, ContactPoint{System: "email", Value: s, Rank: i + 1})
+		}
+	}
+	phones := [][2]*string{{p.PrimaryPhoneNumber, p.PrimaryPhoneType}, {p.SecondaryPhoneNumber, p.SecondaryPhoneType}}
+	for i, phone := range phones {
+		number, phoneType := deref(phone[0]), strings.ToLower(deref(phone[1]))
+		if number == "" {
+			continue
+		}
+		cp := ContactPoint{System: "phone", Value: number, Rank: i + 1}
+		if use, ok := phoneUses[phoneType]; ok {
+			cp.Use = use
+		} else if phoneType != "" {
+			cp.Extension = []Extension{{URL: ExtensionPhoneType, ValueCode: phoneType}}
+		}
+		person.Telecom = append(person.Telecom, cp)
+	}
+
+	if p.Gender != nil && *p.Gender != "" {
+		gender, ok := genderToFHIR[*p.Gender]
+		if !ok {
+			gender = "unknown"
+		}
+		person.Gender = gender
+		if genderFromFHIR[gender] != *p.Gender {
+			person.Extension = append(person.Extension, Extension{URL: ExtensionGender, ValueString: string(*p.Gender)})
+		}
+	}
+	if p.Birthday != nil && !p.Birthday.IsZero() {
+		person.BirthDate = p.Birthday.Format(dateLayout)
+	}
+
+	address := Address{City: deref(p.City), State: deref(p.State), PostalCode: deref(p.ZipCode), Country: deref(p.Country)}
+	for _, line := range []*string{p.AddressLine1, p.AddressLine2} {
+		if s := deref(line); s != "" {
+			address.Line = append(address.Line, s)
+		}
+	}
+	if len(address.Line) > 0 || address.City != "" || address.State != "" || address.PostalCode != "" || address.Country != "" {
+		person.Address = []Address{address}
+	}
+
+	person.Extension = append(person.Extension, profileExtensions(p)...)
+	return person
+}
+
+func profileExtensions(p *client.Profile) []Extension {
+	extensions := []Extension{}
+	if s := deref(p.TimeZone); s != "" {
+		extensions = append(extensions, Extension{URL: ExtensionTimeZone, ValueCode: s})
+	}
+	if p.UserTypeID != nil {
+		extensions = append(extensions, Extension{URL: ExtensionUserType, ValueInteger: intPtr(*p.UserTypeID)})
+	}
+	if p.OrganizationID != nil {
+		extensions = append(extensions, Extension{URL: ExtensionOrganization, ValueInteger: intPtr(*p.OrganizationID)})
+	}
+	if p.NeedsOnboarding {
+		needs := true
+		extensions = append(extensions, Extension{URL: ExtensionNeedsOnboarding, ValueBoolean: &needs})
+	}
+	if p.Landing != "" {
+		extensions = append(extensions, Extension{URL: ExtensionLanding, ValueString: p.Landing})
+	}
+	if p.Program != "" {
+		extensions = append(extensions, Extension{URL: ExtensionProgram, ValueString: p.Program})
+	}
+	// Sorted, so the same profile always makes the same resource
+	keys := make([]string, 0, len(p.ExtendedProperties))
+	for k := range p.ExtendedProperties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		extensions = append(extensions, Extension{URL: ExtensionExtendedProperty, Extension: []Extension{
+			{URL: "key", ValueString: k},
+			{URL: "value", ValueString: p.ExtendedProperties[k]},
+		}})
+	}
+	return extensions
+}
+
+// profile is the reverse of newPerson.
+func (person Person) profile() (client.Profile, error) {
+	p := client.Profile{ID: person.ID}
+	for _, id := range person.Identifier {
+		switch id.System {
+		case SystemProfileID:
+			if p.ID == "" {
+				p.ID = id.Value
+			}
+		case SystemUsername:
+			p.Username = strPtr(id.Value)
+		}
+	}
+
+	if name, ok := officialName(person.Name); ok {
+		p.LastName = strPtr(name.Family)
+		if len(name.Given) > 0 {
+			p.FirstName = strPtr(name.Given[0])
+		}
+		if len(name.Given) > 1 {
+			p.MiddleName = strPtr(strings.Join(name.Given[1:], " "))
+		}
+	}
+
+	emails, phones := byRank(person.Telecom, "email"), byRank(person.Telecom, "phone")
+	for i, dst := range []**string{&p.Email, &p.SecondEmail} {
+		if i < len(emails) {
+			*dst = strPtr(emails[i].Value)
+		}
+	}
+	for i, dst := range [][2]**string{{&p.PrimaryPhoneNumber, &p.PrimaryPhoneType}, {&p.SecondaryPhoneNumber, &p.SecondaryPhoneType}} {
+		if i >= len(phones) {
+			break
+		}
+		*dst[0] = strPtr(phones[i].Value)
+		phoneType := phones[i].Use
+		if e, ok := findExtension(phones[i].Extension, ExtensionPhoneType); ok {
+			phoneType = e.ValueCode
+		}
+		if _, ok := phoneUses[phoneType]; ok || phoneType == "tablet" || phoneType == "other" {
+			*dst[1] = strPtr(phoneType)
+		}
+	}
+
+	if person.Gender != "" {
+		gender, ok := genderFromFHIR[person.Gender]
+		if e, found := findExtension(person.Extension, ExtensionGender); found {
+			gender, ok = client.GenderOption(e.ValueString), true
+		}
+		if ok {
+			p.Gender = &gender
+		}
+	}
+	if person.BirthDate != "" {
+		birthday, err := time.Parse(dateLayout, person.BirthDate)
+		if err != nil {
+			return client.Profile{}, fmt.Errorf("fhir: invalid birthDate %q", person.BirthDate)
+		}
+		p.Birthday = &birthday
+	}
+
+	if len(person.Address) > 0 {
+		address := person.Address[0]
+		if len(address.Line) > 0 {
+			p.AddressLine1 = strPtr(address.Line[0])
+		}
+		if len(address.Line) > 1 {
+			p.AddressLine2 = strPtr(strings.Join(address.Line[1:], ", "))
+		}
+		p.City, p.State, p.ZipCode, p.Country = strPtr(address.City), strPtr(address.State), strPtr(address.PostalCode), strPtr(address.Country)
+	}
+
+	for _, e := range person.Extension {
+		switch e.URL {
+		case ExtensionTimeZone:
+			p.TimeZone = strPtr(e.ValueCode)
+		case ExtensionUserType:
+			p.UserTypeID = e.ValueInteger
+		case ExtensionOrganization:
+			p.OrganizationID = e.ValueInteger
+		case ExtensionNeedsOnboarding:
+			p.NeedsOnboarding = e.ValueBoolean != nil && *e.ValueBoolean
+		case ExtensionLanding:
+			p.Landing = e.ValueString
+		case ExtensionProgram:
+			p.Program = e.ValueString
+		case ExtensionExtendedProperty:
+			key, hasKey := findExtension(e.Extension, "key")
+			value, _ := findExtension(e.Extension, "value")
+			if hasKey {
+				if p.ExtendedProperties == nil {
+					p.ExtendedProperties = map[string]string{}
+				}
+				p.ExtendedProperties[key.ValueString] = value.ValueString
+			}
+		}
+	}
+	return p, nil
+}
+
+// officialName returns the official name, or the first when none is marked
+// official.
+func officialName(names []HumanName) (HumanName, bool) {
+	for _, name := range names {
+		if name.Use == "official" {
+			return name, true
+		}
+	}
+	if len(names) > 0 {
+		return names[0], true
+	}
+	return HumanName{}, false
+}
+
+// byRank returns the contact points of the system, best first.  Unranked
+// ones come last, in the order they're listed.
+func byRank(telecom []ContactPoint, system string) []ContactPoint {
+	matches := []ContactPoint{}
+	for _, cp := range telecom {
+		if cp.System == system && cp.Value != "" {
+			matches = append(matches, cp)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		ri, rj := matches[i].Rank, matches[j].Rank
+		return ri != 0 && (rj == 0 || ri < rj)
+	})
+	return matches
+}
+
+func communication(p *client.Profile) []Communication {
+	if p.Locale == nil || *p.Locale == "" {
+		return nil
+	}
+	return []Communication{{Language: language(*p.Locale), Preferred: true}}
+}
+
+func language(locale string) CodeableConcept {
+	return CodeableConcept{Coding: []Coding{{System: SystemLanguage, Code: locale}}}
+}
+
+// preferredLanguage returns the preferred language, or the first.
+func preferredLanguage(communication []Communication) *string {
+	var first *string
+	for _, c := range communication {
+		locale, ok := c.Language.code(SystemLanguage)
+		if !ok {
+			continue
+		}
+		if c.Preferred {
+			return &locale
+		}
+		if first == nil {
+			first = &locale
+		}
+	}
+	return first
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// strPtr returns nil for an empty string, which our profiles leave out.
+func strPtr(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func intPtr(i int) *int {
+	return &i
+}
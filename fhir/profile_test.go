@@ -0,0 +1,132 @@
+package fhir
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/seniorlink-vela/cs-common/client"
+)
+
+func str(s string) *string {
+	return &s
+}
+
+func testProfile() client.Profile {
+	birthday := time.Date(1942, time.December, 4, 0, 0, 0, 0, time.UTC)
+	gender := client.GenderMale
+	userType, org := 3, 17
+	return client.Profile{
+		ID:                   "p-1",
+		FirstName:            str("Jeffrey"),
+		MiddleName:           str("Lee"),
+		LastName:             str("Lebowski"),
+		Username:             str("thedude"),
+		Email:                str("dude@example.com"),
+		SecondEmail:          str("jlebowski@example.com"),
+		AddressLine1:         str("606 Venezia Ave"),
+		AddressLine2:         str("Apt 2"),
+		City:                 str("Venice"),
+		State:                str("CA"),
+		ZipCode:              str("90291"),
+		Country:              str("US"),
+		PrimaryPhoneNumber:   str("+13105550123"),
+		PrimaryPhoneType:     str("home"),
+		SecondaryPhoneNumber: str("+13105550199"),
+		SecondaryPhoneType:   str("tablet"),
+		Locale:               str("es-US"),
+		TimeZone:             str("America/Los_Angeles"),
+		Gender:               &gender,
+		Birthday:             &birthday,
+		NeedsOnboarding:      true,
+		UserTypeID:           &userType,
+		OrganizationID:       &org,
+		ExtendedProperties:   map[string]string{"bowling_team": "yes", "rug": "tied the room together"},
+		Landing:              "test-sample",
+		Program:              "test-program",
+	}
+}
+
+// roundTrip sends the resource through JSON, the way it would travel.
+func roundTrip[T any](t *testing.T, resource T) T {
+	data, err := json.Marshal(resource)
+	require.NoError(t, err)
+	var decoded T
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	return decoded
+}
+
+func TestPatientRoundTrip(t *testing.T) {
+	p := testProfile()
+	patient := roundTrip(t, NewPatient(&p))
+
+	assert.Equal(t, "Patient", patient.ResourceType)
+	assert.Equal(t, "male", patient.Gender)
+	assert.Equal(t, "1942-12-04", patient.BirthDate)
+	assert.Equal(t, []HumanName{{Use: "official", Family: "Lebowski", Given: []string{"Jeffrey", "Lee"}}}, patient.Name)
+	assert.Contains(t, patient.Extension, Extension{URL: ExtensionTimeZone, ValueCode: "America/Los_Angeles"})
+
+	back, err := ProfileFromPatient(patient)
+	require.NoError(t, err)
+	assert.Equal(t, p, back)
+}
+
+func TestRelatedPersonAndPractitionerRoundTrip(t *testing.T) {
+	p := testProfile()
+	rp := roundTrip(t, NewRelatedPerson(&p, "consumer-1"))
+	assert.Equal(t, Reference{Reference: "Patient/consumer-1"}, rp.Patient)
+	back, err := ProfileFromRelatedPerson(rp)
+	require.NoError(t, err)
+	assert.Equal(t, p, back)
+
+	pr := roundTrip(t, NewPractitioner(&p))
+	back, err = ProfileFromPractitioner(pr)
+	require.NoError(t, err)
+	assert.Equal(t, p, back)
+
+	_, err = ProfileFromPractitioner(Practitioner{ResourceType: "Patient"})
+	assert.ErrorIs(t, err, ErrWrongResource)
+}
+
+func TestGenders(t *testing.T) {
+	for _, gender := range []client.GenderOption{client.GenderFemale, client.GenderTransgender, client.GenderUnspecified, "Nonbinary"} {
+		p := client.Profile{ID: "p-1", Gender: &gender}
+		back, err := ProfileFromPatient(roundTrip(t, NewPatient(&p)))
+		require.NoError(t, err)
+		assert.Equal(t, gender, *back.Gender)
+	}
+
+	// Other systems' genders without our extension
+	p, err := ProfileFromPatient(Patient{ResourceType: "Patient", Person: Person{Gender: "other"}})
+	require.NoError(t, err)
+	assert.Nil(t, p.Gender)
+}
+
+func TestFromOtherSystems(t *testing.T) {
+	var patient Patient
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"resourceType": "Patient",
+		"id": "abc",
+		"name": [{"use": "nickname", "given": ["Dude"]}, {"use": "official", "family": "Lebowski", "given": ["Jeffrey"]}],
+		"telecom": [
+			{"system": "phone", "value": "+13105550199"},
+			{"system": "phone", "value": "+13105550123", "use": "mobile", "rank": 1}
+		],
+		"communication": [{"language": {"coding": [{"system": "urn:ietf:bcp:47", "code": "en-US"}]}}]
+	}`), &patient))
+	p, err := ProfileFromPatient(patient)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", p.ID)
+	assert.Equal(t, "Jeffrey", *p.FirstName)
+	assert.Equal(t, "+13105550123", *p.PrimaryPhoneNumber)
+	assert.Equal(t, "mobile", *p.PrimaryPhoneType)
+	assert.Equal(t, "+13105550199", *p.SecondaryPhoneNumber)
+	assert.Nil(t, p.SecondaryPhoneType)
+	assert.Equal(t, "en-US", *p.Locale)
+
+	_, err = ProfileFromPatient(Patient{ResourceType: "Patient", Person: Person{BirthDate: "1942"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,221 @@
+// Package fhir converts our profiles and care teams to and from FHIR R4
+// resources, for payer integrations.  Consumers are Patients, professionals
+// are Practitioners, and caregivers are RelatedPersons of the consumer.  A
+// care team goes as a Bundle with its CareTeam and the people on it:
+//
+//	bundle, err := fhir.NewBundle(team)
+//	...
+//	team, err := fhir.TeamFromBundle(bundle)
+//
+// Only the subset of R4 that our models fill in is here.  Fields FHIR has no
+// place for go in extensions under BaseURL, so they survive a round trip.
+package fhir
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// BaseURL names our extensions, code systems and identifier systems.  They
+// don't need to resolve.
+const BaseURL = "https://fhir.alwaysreach.net"
+
+// The identifier systems.
+const (
+	SystemProfileID = BaseURL + "/identifier/user-profile"
+	SystemUsername  = BaseURL + "/identifier/username"
+	SystemCareTeam  = BaseURL + "/identifier/care-team"
+)
+
+// SystemCareTeamRole is the code system for care team roles, whose codes are
+// the member owner types, like Caregiver.
+const SystemCareTeamRole = BaseURL + "/CodeSystem/care-team-role"
+
+// The extensions for our fields.
+const (
+	ExtensionGender           = BaseURL + "/StructureDefinition/gender"
+	ExtensionUserType         = BaseURL + "/StructureDefinition/user-type"
+	ExtensionOrganization     = BaseURL + "/StructureDefinition/organization"
+	ExtensionNeedsOnboarding  = BaseURL + "/StructureDefinition/needs-onboarding"
+	ExtensionLanding          = BaseURL + "/StructureDefinition/landing"
+	ExtensionProgram          = BaseURL + "/StructureDefinition/program"
+	ExtensionExtendedProperty = BaseURL + "/StructureDefinition/extended-property"
+	ExtensionPhoneType        = BaseURL + "/StructureDefinition/phone-type"
+	ExtensionPrimaryCaregiver = BaseURL + "/StructureDefinition/primary-caregiver"
+
+	// ExtensionTimeZone is HL7's, so other systems understand it.
+	ExtensionTimeZone = "http://hl7.org/fhir/StructureDefinition/tz-code"
+)
+
+// SystemLanguage is the code system for languages.
+const SystemLanguage = "urn:ietf:bcp:47"
+
+// ErrWrongResource is returned when JSON is a different resource type than
+// the one asked for.
+var ErrWrongResource = errors.New("fhir: wrong resource type")
+
+type Coding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+// code returns the first code from the system.
+func (c CodeableConcept) code(system string) (string, bool) {
+	for _, coding := range c.Coding {
+		if coding.System == system {
+			return coding.Code, true
+		}
+	}
+	return "", false
+}
+
+type Identifier struct {
+	System string `json:"system,omitempty"`
+	Value  string `json:"value,omitempty"`
+}
+
+type Reference struct {
+	Reference string `json:"reference,omitempty"`
+	Display   string `json:"display,omitempty"`
+}
+
+// NewReference returns a reference to the resource, like Patient/123.
+func NewReference(resourceType, id string) Reference {
+	return Reference{Reference: resourceType + "/" + id}
+}
+
+// Target returns the type and ID of a relative reference.
+func (r Reference) Target() (resourceType, id string, ok bool) {
+	resourceType, id, ok = strings.Cut(r.Reference, "/")
+	if !ok || resourceType == "" || id == "" || strings.Contains(id, "/") {
+		return "", "", false
+	}
+	return resourceType, id, true
+}
+
+// Extension has the value types we use.  Complex extensions only have
+// Extension.
+type Extension struct {
+	URL          string      `json:"url"`
+	ValueString  string      `json:"valueString,omitempty"`
+	ValueCode    string      `json:"valueCode,omitempty"`
+	ValueInteger *int        `json:"valueInteger,omitempty"`
+	ValueBoolean *bool       `json:"valueBoolean,omitempty"`
+	Extension    []Extension `json:"extension,omitempty"`
+}
+
+func findExtension(extensions []Extension, url string) (Extension, bool) {
+	for _, e := range extensions {
+		if e.URL == url {
+			return e, true
+		}
+	}
+	return Extension{}, false
+}
+
+type HumanName struct {
+	Use    string   `json:"use,omitempty"`
+	Family string   `json:"family,omitempty"`
+	Given  []string `json:"given,omitempty"`
+}
+
+type ContactPoint struct {
+	System    string      `json:"system,omitempty"`
+	Value     string      `json:"value,omitempty"`
+	Use       string      `json:"use,omitempty"`
+	Rank      int         `json:"rank,omitempty"`
+	Extension []Extension `json:"extension,omitempty"`
+}
+
+type Address struct {
+	Line       []string `json:"line,omitempty"`
+	City       string   `json:"city,omitempty"`
+	State      string   `json:"state,omitempty"`
+	PostalCode string   `json:"postalCode,omitempty"`
+	Country    string   `json:"country,omitempty"`
+}
+
+// Communication is a language the person speaks.
+type Communication struct {
+	Language  CodeableConcept `json:"language"`
+	Preferred bool            `json:"preferred,omitempty"`
+}
+
+// Person has the fields Patient, RelatedPerson and Practitioner share.
+type Person struct {
+	ID         string         `json:"id,omitempty"`
+	Extension  []Extension    `json:"extension,omitempty"`
+	Identifier []Identifier   `json:"identifier,omitempty"`
+	Active     *bool          `json:"active,omitempty"`
+	Name       []HumanName    `json:"name,omitempty"`
+	Telecom    []ContactPoint `json:"telecom,omitempty"`
+	Gender     string         `json:"gender,omitempty"`
+	BirthDate  string         `json:"birthDate,omitempty"`
+	Address    []Address      `json:"address,omitempty"`
+}
+
+type Patient struct {
+	ResourceType string `json:"resourceType"`
+	Person
+	Communication []Communication `json:"communication,omitempty"`
+}
+
+type RelatedPerson struct {
+	ResourceType string `json:"resourceType"`
+	Person
+	Patient       Reference         `json:"patient"`
+	Relationship  []CodeableConcept `json:"relationship,omitempty"`
+	Communication []Communication   `json:"communication,omitempty"`
+}
+
+type Practitioner struct {
+	ResourceType string `json:"resourceType"`
+	Person
+	// Practitioners' languages don't have a preferred flag
+	Communication []CodeableConcept `json:"communication,omitempty"`
+}
+
+type CareTeam struct {
+	ResourceType string                `json:"resourceType"`
+	ID           string                `json:"id,omitempty"`
+	Identifier   []Identifier          `json:"identifier,omitempty"`
+	Status       string                `json:"status,omitempty"`
+	Name         string                `json:"name,omitempty"`
+	Subject      Reference             `json:"subject"`
+	Participant  []CareTeamParticipant `json:"participant,omitempty"`
+}
+
+type CareTeamParticipant struct {
+	Extension []Extension       `json:"extension,omitempty"`
+	Role      []CodeableConcept `json:"role,omitempty"`
+	Member    Reference         `json:"member"`
+}
+
+// Bundle is a collection of resources, which stay JSON until they're read,
+// since each entry can be a different type.
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type"`
+	Entry        []BundleEntry `json:"entry,omitempty"`
+}
+
+type BundleEntry struct {
+	FullURL  string          `json:"fullUrl,omitempty"`
+	Resource json.RawMessage `json:"resource"`
+}
+
+// ResourceType returns the entry's resource type.
+func (e BundleEntry) ResourceType() string {
+	var r struct {
+		ResourceType string `json:"resourceType"`
+	}
+	_ = json.Unmarshal(e.Resource, &r)
+	return r.ResourceType
+}
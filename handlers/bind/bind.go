@@ -0,0 +1,206 @@
+// Package bind decodes JSON request bodies into structs and validates them,
+// returning errors that respond writes as the canonical Vela error, so a
+// handler only needs
+//
+//	var body CareTeamCreate
+//	if err := bind.JSON(r, &body); err != nil {
+//		respond.WriteError(r.Context(), w, err)
+//		return
+//	}
+package bind
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/handlers/respond"
+	"github.com/seniorlink-vela/cs-common/validation"
+)
+
+// DefaultMaxSize is the largest body accepted when Options doesn't say.  ALB
+// bodies can't be bigger than 1MB anyway.
+const DefaultMaxSize = 1 << 20
+
+// The error types for bodies that can't be decoded.
+const (
+	ErrorTypeInvalidJSON = "invalid_json"
+	ErrorTypeTooLarge    = "request_too_large"
+)
+
+// Options control decoding and validation.  The zero value uses the defaults.
+type Options struct {
+	// MaxSize defaults to DefaultMaxSize.
+	MaxSize int64
+	// AllowUnknownFields accepts fields the struct doesn't have, which are
+	// otherwise an error, so typos don't go unnoticed.
+	AllowUnknownFields bool
+	// Scenario limits the rules that run, like validation.ScenarioCreate.
+	Scenario string
+	// Language for the messages, which defaults to the context locale.
+	Language string
+}
+
+// Error is a body that couldn't be decoded, or failed validation.  It
+// implements respond.Responder.
+type Error struct {
+	Status int
+	Body   respond.ErrorBody
+}
+
+func (e *Error) Error() string {
+	if len(e.Body.Fields) == 0 {
+		return "bind: " + e.Body.Message
+	}
+	return fmt.Sprintf("bind: %s %v", e.Body.Message, e.Body.Fields)
+}
+
+// ErrorResponse returns the status and body to send.
+func (e *Error) ErrorResponse() (int, respond.ErrorBody) {
+	return e.Status, e.Body
+}
+
+// JSON decodes and validates a net/http request's body.
+func JSON(r *http.Request, dst interface{}) error {
+	return JSONWithOptions(r, dst, Options{})
+}
+
+// JSONWithOptions is JSON with settings.
+func JSONWithOptions(r *http.Request, dst interface{}, opts Options) error {
+	body, err := io.ReadAll(io.LimitReader(r.Body, opts.maxSize()+1))
+	if err != nil {
+		return err
+	}
+	return BytesWithOptions(r.Context(), body, dst, opts)
+}
+
+// ALB decodes and validates an ALB request's body, which may be base64
+// encoded.
+func ALB(ctx context.Context, req events.ALBTargetGroupRequest, dst interface{}) error {
+	return ALBWithOptions(ctx, req, dst, Options{})
+}
+
+// ALBWithOptions is ALB with settings.
+func ALBWithOptions(ctx context.Context, req events.ALBTargetGroupRequest, dst interface{}, opts Options) error {
+	body := []byte(req.Body)
+	if req.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(req.Body)
+		if err != nil {
+			return invalidJSON("Request body isn't valid base64")
+		}
+		body = decoded
+	}
+	return BytesWithOptions(ctx, body, dst, opts)
+}
+
+// Bytes decodes and validates a body that's already been read, like a
+// router.Request's.
+func Bytes(ctx context.Context, body []byte, dst interface{}) error {
+	return BytesWithOptions(ctx, body, dst, Options{})
+}
+
+// BytesWithOptions is Bytes with settings.  The struct is normalized, as its
+// `normalize` tags say, before it's validated.
+func BytesWithOptions(ctx context.Context, body []byte, dst interface{}, opts Options) error {
+	if int64(len(body)) > opts.maxSize() {
+		return &Error{
+			Status: http.StatusRequestEntityTooLarge,
+			Body:   respond.ErrorBody{Message: fmt.Sprintf("Request body is larger than %d bytes", opts.maxSize()), ErrorType: ErrorTypeTooLarge},
+		}
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return invalidJSON("Request body is required")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if !opts.AllowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	if decoder.More() {
+		return invalidJSON("Request body has data after the JSON")
+	}
+
+	if err := validation.NormalizeStruct(dst); err != nil {
+		// Not a struct, so there's nothing to validate
+		return nil
+	}
+	language := opts.Language
+	if language == "" {
+		language = velacontext.GetContextLocale(ctx)
+	}
+	fields := fieldMap{}
+	err := validation.ValidateStructWithOptions(dst, validation.Options{Scenario: opts.Scenario, Language: language}, fields)
+	if errors.Is(err, validation.ValidationError) {
+		return fields.error()
+	}
+	return err
+}
+
+func (o Options) maxSize() int64 {
+	if o.MaxSize > 0 {
+		return o.MaxSize
+	}
+	return DefaultMaxSize
+}
+
+// fieldMap collects validation failures.
+type fieldMap map[string]string
+
+func (f fieldMap) AppendErrorField(name, message string) {
+	f[name] = message
+}
+
+func (f fieldMap) error() *Error {
+	body := respond.ErrorBody{Message: validation.ValidationError.Error(), ErrorType: respond.ErrorTypeValidation}
+	for name, message := range f {
+		body.Fields = append(body.Fields, respond.Field{Name: name, Message: message})
+	}
+	sort.Slice(body.Fields, func(i, j int) bool { return body.Fields[i].Name < body.Fields[j].Name })
+	return &Error{Status: http.StatusBadRequest, Body: body}
+}
+
+func invalidJSON(message string) *Error {
+	return &Error{Status: http.StatusBadRequest, Body: respond.ErrorBody{Message: message, ErrorType: ErrorTypeInvalidJSON}}
+}
+
+// decodeError turns a decoding failure into a field error when it's about
+// one field, and an invalid JSON error otherwise.
+func decodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return fieldMap{typeErr.Field: fmt.Sprintf("This must be %s", jsonKind(typeErr.Type.Kind().String()))}.error()
+	}
+	// encoding/json doesn't have a type for unknown fields
+	var field string
+	if _, scanErr := fmt.Sscanf(err.Error(), "json: unknown field %q", &field); scanErr == nil {
+		return fieldMap{field: "This isn't a known field"}.error()
+	}
+	return invalidJSON("Request body isn't valid JSON")
+}
+
+func jsonKind(kind string) string {
+	switch kind {
+	case "string":
+		return "a string"
+	case "bool":
+		return "true or false"
+	case "slice", "array":
+		return "a list"
+	case "map", "struct":
+		return "an object"
+	default:
+		return "a number"
+	}
+}
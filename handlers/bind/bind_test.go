@@ -0,0 +1,104 @@
+package bind
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/seniorlink-vela/cs-common/handlers/respond"
+)
+
+type member struct {
+	Email string `json:"email" validation:"required,email"`
+	Role  string `json:"role" validation:"values:caregiver|coordinator"`
+	Age   int    `json:"age"`
+}
+
+func TestJSON(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/members", strings.NewReader(`{"email":"a@example.com","role":"caregiver","age":40}`))
+	var m member
+	require.NoError(t, JSON(r, &m))
+	assert.Equal(t, member{Email: "a@example.com", Role: "caregiver", Age: 40}, m)
+}
+
+func TestALBBase64(t *testing.T) {
+	req := events.ALBTargetGroupRequest{
+		Body:            base64.StdEncoding.EncodeToString([]byte(`{"email":"a@example.com"}`)),
+		IsBase64Encoded: true,
+	}
+	var m member
+	require.NoError(t, ALB(context.Background(), req, &m))
+	assert.Equal(t, "a@example.com", m.Email)
+
+	req.Body = "not base64!"
+	err := ALB(context.Background(), req, &m)
+	assertError(t, err, http.StatusBadRequest, ErrorTypeInvalidJSON)
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		status    int
+		errorType string
+		field     string
+	}{
+		{"empty", " ", http.StatusBadRequest, ErrorTypeInvalidJSON, ""},
+		{"syntax", `{"email":`, http.StatusBadRequest, ErrorTypeInvalidJSON, ""},
+		{"trailing", `{"email":"a@example.com"} {}`, http.StatusBadRequest, ErrorTypeInvalidJSON, ""},
+		{"unknown field", `{"email":"a@example.com","nmae":"x"}`, http.StatusBadRequest, respond.ErrorTypeValidation, "nmae"},
+		{"wrong type", `{"email":"a@example.com","age":"forty"}`, http.StatusBadRequest, respond.ErrorTypeValidation, "age"},
+		{"too large", `{"email":"` + strings.Repeat("a", DefaultMaxSize) + `"}`, http.StatusRequestEntityTooLarge, ErrorTypeTooLarge, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var m member
+			err := Bytes(context.Background(), []byte(test.body), &m)
+			body := assertError(t, err, test.status, test.errorType)
+			if test.field != "" {
+				require.Len(t, body.Fields, 1)
+				assert.Equal(t, test.field, body.Fields[0].Name)
+			}
+		})
+	}
+}
+
+func TestValidation(t *testing.T) {
+	var m member
+	err := Bytes(context.Background(), []byte(`{"email":"nope","role":"admin"}`), &m)
+	body := assertError(t, err, http.StatusBadRequest, respond.ErrorTypeValidation)
+	require.Len(t, body.Fields, 2)
+	assert.Equal(t, "email", body.Fields[0].Name)
+	assert.Equal(t, "role", body.Fields[1].Name)
+
+	// respond sends it as is
+	status, sent := respond.Error(context.Background(), err)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, body, sent)
+}
+
+func TestOptions(t *testing.T) {
+	var m member
+	err := BytesWithOptions(context.Background(), []byte(`{"email":"a@example.com","extra":1}`), &m, Options{AllowUnknownFields: true})
+	assert.NoError(t, err)
+
+	err = BytesWithOptions(context.Background(), []byte(`{"email":"a@example.com"}`), &m, Options{MaxSize: 10})
+	assertError(t, err, http.StatusRequestEntityTooLarge, ErrorTypeTooLarge)
+}
+
+func assertError(t *testing.T, err error, status int, errorType string) respond.ErrorBody {
+	t.Helper()
+	var bindErr *Error
+	require.ErrorAs(t, err, &bindErr)
+	gotStatus, body := bindErr.ErrorResponse()
+	assert.Equal(t, status, gotStatus)
+	assert.Equal(t, errorType, body.ErrorType)
+	return body
+}
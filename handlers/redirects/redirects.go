@@ -0,0 +1,245 @@
+// Package redirects turns the redirects in the common config into rules, and
+// serves them for ALB and net/http handlers.
+//
+// Each redirect maps a path pattern to a target, optionally followed by a
+// status code and `preserve-query`, separated by spaces:
+//
+//	"/old-home":        "https://app.alwaysreach.net/home 301"
+//	"/docs/*":          "https://docs.alwaysreach.net/* 308 preserve-query"
+//	"/users/*/profile": "/profiles/$1"
+//
+// Patterns without a `*` match the path exactly.  A pattern ending in `*`
+// matches any path starting with the rest of it, and a `*` at the end of its
+// target is replaced with the remainder of the path.  Anywhere else, a `*`
+// matches a single path segment, and the target can refer to the segments as
+// `$1`, `$2` and so on.  Exact matches take precedence, then the longest
+// prefix, then the longest wildcard pattern.  Redirects are temporary (302)
+// unless a status is given, and the query string is dropped unless
+// `preserve-query` is.  Targets can't be protocol relative (`//host/...`), and
+// leading slashes picked up from the path are collapsed, so a local target
+// can't be turned into a redirect to another site.
+package redirects
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/seniorlink-vela/cs-common/config"
+	"github.com/seniorlink-vela/cs-common/context/lambdamiddleware"
+)
+
+const preserveQueryOption = "preserve-query"
+
+type ruleKind int
+
+const (
+	exactRule ruleKind = iota
+	prefixRule
+	wildcardRule
+)
+
+// Rule is a single compiled redirect.
+type Rule struct {
+	Pattern       string
+	Target        string
+	Status        int
+	PreserveQuery bool
+
+	kind   ruleKind
+	prefix string
+	re     *regexp.Regexp
+}
+
+// Rules are the compiled redirects, ready for matching.
+type Rules struct {
+	exact     map[string]*Rule
+	prefixes  []*Rule
+	wildcards []*Rule
+}
+
+// FromConfig compiles the redirects in the common config.
+func FromConfig(c *config.Config) (*Rules, error) {
+	if c == nil {
+		return Compile(nil)
+	}
+	return Compile(c.Common.Redirects)
+}
+
+// Compile turns a map of path patterns to targets into rules.  Patterns that
+// don't start with a `/` have one added, since parameter store names can't
+// hold one.
+func Compile(redirects map[string]string) (*Rules, error) {
+	rules := &Rules{exact: map[string]*Rule{}}
+	for pattern, value := range redirects {
+		rule, err := compileRule(pattern, value)
+		if err != nil {
+			return nil, err
+		}
+		switch rule.kind {
+		case exactRule:
+			rules.exact[rule.Pattern] = rule
+		case prefixRule:
+			rules.prefixes = append(rules.prefixes, rule)
+		case wildcardRule:
+			rules.wildcards = append(rules.wildcards, rule)
+		}
+	}
+	byLength := func(rs []*Rule) func(i, j int) bool {
+		return func(i, j int) bool {
+			if len(rs[i].Pattern) != len(rs[j].Pattern) {
+				return len(rs[i].Pattern) > len(rs[j].Pattern)
+			}
+			return rs[i].Pattern < rs[j].Pattern
+		}
+	}
+	sort.Slice(rules.prefixes, byLength(rules.prefixes))
+	sort.Slice(rules.wildcards, byLength(rules.wildcards))
+	return rules, nil
+}
+
+func compileRule(pattern, value string) (*Rule, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		pattern = "/" + pattern
+	}
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("redirect %s has no target", pattern)
+	}
+	if strings.HasPrefix(fields[0], "//") || strings.HasPrefix(fields[0], "/\\") {
+		return nil, fmt.Errorf("redirect %s has a protocol relative target", pattern)
+	}
+	rule := &Rule{Pattern: pattern, Target: fields[0], Status: http.StatusFound}
+	for _, option := range fields[1:] {
+		if option == preserveQueryOption {
+			rule.PreserveQuery = true
+			continue
+		}
+		status, err := strconv.Atoi(option)
+		if err != nil || !isRedirectStatus(status) {
+			return nil, fmt.Errorf("redirect %s has an invalid option %q", pattern, option)
+		}
+		rule.Status = status
+	}
+
+	stars := strings.Count(pattern, "*")
+	switch {
+	case stars == 0:
+		rule.kind = exactRule
+	case stars == 1 && strings.HasSuffix(pattern, "*"):
+		rule.kind = prefixRule
+		rule.prefix = strings.TrimSuffix(pattern, "*")
+	default:
+		rule.kind = wildcardRule
+		parts := strings.Split(pattern, "*")
+		for i := range parts {
+			parts[i] = regexp.QuoteMeta(parts[i])
+		}
+		rule.re = regexp.MustCompile("^" + strings.Join(parts, "([^/]*)") + "$")
+	}
+	return rule, nil
+}
+
+func isRedirectStatus(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// Match finds the redirect for a path, and returns where to send it.  The raw
+// query is added to the location when the rule preserves it.
+func (r *Rules) Match(path, rawQuery string) (location string, status int, ok bool) {
+	if r == nil {
+		return "", 0, false
+	}
+	rule, target := r.match(path)
+	if rule == nil {
+		return "", 0, false
+	}
+	target = localPath(target)
+	if rule.PreserveQuery && rawQuery != "" {
+		if strings.Contains(target, "?") {
+			target += "&" + rawQuery
+		} else {
+			target += "?" + rawQuery
+		}
+	}
+	return target, rule.Status, true
+}
+
+func (r *Rules) match(path string) (*Rule, string) {
+	if rule, ok := r.exact[path]; ok {
+		return rule, rule.Target
+	}
+	for _, rule := range r.prefixes {
+		if strings.HasPrefix(path, rule.prefix) {
+			if strings.HasSuffix(rule.Target, "*") {
+				return rule, strings.TrimSuffix(rule.Target, "*") + strings.TrimPrefix(path, rule.prefix)
+			}
+			return rule, rule.Target
+		}
+	}
+	for _, rule := range r.wildcards {
+		if m := rule.re.FindStringSubmatch(path); m != nil {
+			target := rule.Target
+			// Highest first, so $1 doesn't replace the start of $10
+			for i := len(m) - 1; i > 0; i-- {
+				target = strings.ReplaceAll(target, "$"+strconv.Itoa(i), m[i])
+			}
+			return rule, target
+		}
+	}
+	return nil, ""
+}
+
+// A local target can pick up more slashes from the path it was matched against,
+// and "/docs//evil.com" would send browsers to another host, so the leading
+// ones are collapsed.  Browsers treat backslashes there the same way.
+func localPath(target string) string {
+	if !strings.HasPrefix(target, "/") {
+		return target
+	}
+	return "/" + strings.TrimLeft(target, "/\\")
+}
+
+// HandleALB redirects the request when a rule matches.  Like the static
+// handler, it returns a nil response when nothing matches, so the request can
+// be passed on to other handlers.
+func (r *Rules) HandleALB(ctx context.Context, req events.ALBTargetGroupRequest) (*events.ALBTargetGroupResponse, error) {
+	location, status, ok := r.Match(req.Path, lambdamiddleware.ALBRawQuery(req))
+	if !ok {
+		return nil, nil
+	}
+	return &events.ALBTargetGroupResponse{
+		StatusCode:        status,
+		StatusDescription: fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Headers: map[string]string{
+			"Location": location,
+		},
+	}, nil
+}
+
+// Middleware redirects requests that match a rule, and passes the rest on to
+// next.  When next is nil, unmatched requests get a 404.
+func (r *Rules) Middleware(next http.Handler) http.Handler {
+	if next == nil {
+		next = http.NotFoundHandler()
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		location, status, ok := r.Match(req.URL.Path, req.URL.RawQuery)
+		if !ok {
+			next.ServeHTTP(w, req)
+			return
+		}
+		http.Redirect(w, req, location, status)
+	})
+}
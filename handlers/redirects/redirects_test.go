@@ -0,0 +1,170 @@
+package redirects
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/seniorlink-vela/cs-common/config"
+)
+
+func testRules(t *testing.T) *Rules {
+	rules, err := Compile(map[string]string{
+		"/old-home":        "https://app.alwaysreach.net/home 301",
+		"old-login":        "/login",
+		"/docs/*":          "https://docs.alwaysreach.net/* 308 preserve-query",
+		"/docs/v1/*":       "https://docs.alwaysreach.net/archive",
+		"/users/*/profile": "/profiles/$1?tab=info preserve-query",
+		"/a/*/b/*":         "/b/$2/a/$1",
+	})
+	require.NoError(t, err)
+	return rules
+}
+
+func TestMatch(t *testing.T) {
+	rules := testRules(t)
+
+	tests := []struct {
+		name     string
+		path     string
+		query    string
+		location string
+		status   int
+	}{
+		{"exact with status", "/old-home", "x=1", "https://app.alwaysreach.net/home", http.StatusMovedPermanently},
+		{"exact without a leading slash", "/old-login", "", "/login", http.StatusFound},
+		{"prefix keeps the remainder and query", "/docs/guide/setup", "lang=en", "https://docs.alwaysreach.net/guide/setup?lang=en", http.StatusPermanentRedirect},
+		{"longest prefix wins", "/docs/v1/guide", "", "https://docs.alwaysreach.net/archive", http.StatusFound},
+		{"wildcard segment", "/users/42/profile", "", "/profiles/42?tab=info", http.StatusFound},
+		{"wildcard appends to the target query", "/users/42/profile", "x=1", "/profiles/42?tab=info&x=1", http.StatusFound},
+		{"wildcard segments out of order", "/a/1/b/2", "", "/b/2/a/1", http.StatusFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location, status, ok := rules.Match(tt.path, tt.query)
+			require.True(t, ok)
+			assert.Equal(t, tt.location, location)
+			assert.Equal(t, tt.status, status)
+		})
+	}
+
+	t.Run("no match", func(t *testing.T) {
+		for _, path := range []string{"/", "/old-home/", "/users/42/7/profile", "/doc"} {
+			_, _, ok := rules.Match(path, "")
+			assert.False(t, ok, path)
+		}
+	})
+	t.Run("local targets stay local", func(t *testing.T) {
+		rules, err := Compile(map[string]string{"/old/*": "/*", "/u/*/p": "/$1"})
+		require.NoError(t, err)
+		for path, want := range map[string]string{
+			"/old//evil.com":  "/evil.com",
+			"/old/\\evil.com": "/evil.com",
+			"/old/new":        "/new",
+			"/u/\\evil.com/p": "/evil.com",
+		} {
+			location, _, ok := rules.Match(path, "")
+			require.True(t, ok, path)
+			assert.Equal(t, want, location, path)
+		}
+	})
+	t.Run("nil rules never match", func(t *testing.T) {
+		var rules *Rules
+		_, _, ok := rules.Match("/old-home", "")
+		assert.False(t, ok)
+	})
+}
+
+func TestCompileErrors(t *testing.T) {
+	_, err := Compile(map[string]string{"/old": ""})
+	assert.EqualError(t, err, "redirect /old has no target")
+
+	_, err = Compile(map[string]string{"/old": "/new 200"})
+	assert.EqualError(t, err, `redirect /old has an invalid option "200"`)
+
+	_, err = Compile(map[string]string{"/old": "/new keep-query"})
+	assert.EqualError(t, err, `redirect /old has an invalid option "keep-query"`)
+
+	_, err = Compile(map[string]string{"/old": "//evil.com"})
+	assert.EqualError(t, err, "redirect /old has a protocol relative target")
+}
+
+func TestFromConfig(t *testing.T) {
+	rules, err := FromConfig(&config.Config{Common: config.CommonConfig{
+		Redirects: map[string]string{"/old": "/new"},
+	}})
+	require.NoError(t, err)
+	location, _, ok := rules.Match("/old", "")
+	assert.True(t, ok)
+	assert.Equal(t, "/new", location)
+
+	rules, err = FromConfig(nil)
+	require.NoError(t, err)
+	_, _, ok = rules.Match("/old", "")
+	assert.False(t, ok)
+}
+
+func TestHandleALB(t *testing.T) {
+	rules := testRules(t)
+
+	t.Run("redirects a matching path", func(t *testing.T) {
+		r, err := rules.HandleALB(context.Background(), events.ALBTargetGroupRequest{
+			Path:                  "/docs/guide",
+			HTTPMethod:            http.MethodGet,
+			QueryStringParameters: map[string]string{"lang": "en"},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		assert.Equal(t, http.StatusPermanentRedirect, r.StatusCode)
+		assert.Equal(t, "308 Permanent Redirect", r.StatusDescription)
+		assert.Equal(t, "https://docs.alwaysreach.net/guide?lang=en", r.Headers["Location"])
+	})
+	t.Run("uses multi value query parameters", func(t *testing.T) {
+		r, err := rules.HandleALB(context.Background(), events.ALBTargetGroupRequest{
+			Path:                            "/docs/guide",
+			MultiValueQueryStringParameters: map[string][]string{"tag": {"a", "b"}},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		assert.Equal(t, "https://docs.alwaysreach.net/guide?tag=a&tag=b", r.Headers["Location"])
+	})
+	t.Run("passes the query on as ALB sent it", func(t *testing.T) {
+		r, err := rules.HandleALB(context.Background(), events.ALBTargetGroupRequest{
+			Path:                  "/docs/guide",
+			QueryStringParameters: map[string]string{"q": "a%20b%26c"},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		assert.Equal(t, "https://docs.alwaysreach.net/guide?q=a%20b%26c", r.Headers["Location"])
+	})
+	t.Run("nil when nothing matches", func(t *testing.T) {
+		r, err := rules.HandleALB(context.Background(), events.ALBTargetGroupRequest{Path: "/index.html"})
+		assert.NoError(t, err)
+		assert.Nil(t, r)
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	rules := testRules(t)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	w := httptest.NewRecorder()
+	rules.Middleware(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/old-home?x=1", nil))
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "https://app.alwaysreach.net/home", w.Header().Get("Location"))
+
+	w = httptest.NewRecorder()
+	rules.Middleware(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/index.html", nil))
+	assert.Equal(t, http.StatusTeapot, w.Code)
+
+	w = httptest.NewRecorder()
+	rules.Middleware(nil).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/index.html", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
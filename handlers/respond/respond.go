@@ -0,0 +1,194 @@
+// Package respond writes JSON responses and errors in the shape every Vela
+// service uses, for net/http and ALB handlers alike.  Errors are written as
+//
+//	{"message": "...", "error_type": "...", "fields": [{"name": "...", "message": "..."}]}
+//
+// which is the shape client.HttpClientError decodes.
+package respond
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-lambda-go/events"
+	"go.uber.org/zap"
+
+	"github.com/seniorlink-vela/cs-common/apperr"
+	"github.com/seniorlink-vela/cs-common/client"
+	"github.com/seniorlink-vela/cs-common/config"
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/validation"
+)
+
+// The error types for errors that don't carry their own.
+const (
+	ErrorTypeValidation   = "validation_error"
+	ErrorTypeUnauthorized = "unauthorized"
+	ErrorTypeForbidden    = "forbidden"
+	ErrorTypeNotFound     = "not_found"
+	ErrorTypeUnavailable  = "service_unavailable"
+	ErrorTypeTimeout      = "timeout"
+	ErrorTypeUpstream     = "upstream_error"
+	ErrorTypeInternal     = "internal_error"
+)
+
+// Field is a problem with one field of a request.
+type Field struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+// ErrorBody is the canonical Vela error.
+type ErrorBody struct {
+	Message   string  `json:"message"`
+	ErrorType string  `json:"error_type"`
+	Fields    []Field `json:"fields,omitempty"`
+}
+
+// Responder is implemented by errors that know their own status and body,
+// like the ones from bind, which Error sends as they are.
+type Responder interface {
+	error
+	ErrorResponse() (int, ErrorBody)
+}
+
+type registered struct {
+	err       error
+	status    int
+	errorType string
+}
+
+var (
+	registry     []registered
+	registryLock sync.RWMutex
+)
+
+// RegisterError maps a service's sentinel error, and anything wrapping it, to
+// a status and error type.  The error's own message is sent, so it should be
+// fit for clients.  Call it at start up.
+func RegisterError(err error, status int, errorType string) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registry = append(registry, registered{err: err, status: status, errorType: errorType})
+}
+
+// Error maps an error to a status and the canonical body.  Application
+// errors from apperr send their code and safe message.  Validation errors
+// become a 400 with their fields, errors from other Vela APIs keep their
+// message and type, and the config and context sentinels get a fitting
+// status.  Anything else is a 500 whose message doesn't leak the error, which
+// is logged with the context logger instead.
+func Error(ctx context.Context, err error) (int, ErrorBody) {
+	var errorMap client.ErrorMap
+	var clientErr client.HttpClientError
+	var clientErrPtr *client.HttpClientError
+	var fieldErr validation.FieldError
+	var responder Responder
+	var appErr *apperr.Error
+	if errors.As(err, &responder) {
+		return responder.ErrorResponse()
+	}
+	if errors.As(err, &appErr) {
+		if appErr.Status() >= http.StatusInternalServerError {
+			velacontext.GetContextLogger(ctx).Error("Request failed", zap.Error(err))
+		}
+		return appErr.Status(), appBody(appErr)
+	}
+
+	registryLock.RLock()
+	for _, r := range registry {
+		if errors.Is(err, r.err) {
+			registryLock.RUnlock()
+			return r.status, ErrorBody{Message: err.Error(), ErrorType: r.errorType}
+		}
+	}
+	registryLock.RUnlock()
+
+	switch {
+	case errors.As(err, &errorMap):
+		return http.StatusBadRequest, validationBody(errorMap)
+	case errors.As(err, &fieldErr):
+		return http.StatusBadRequest, validationBody(map[string]string{fieldErr.Field: fieldErr.Message})
+	case errors.Is(err, validation.ValidationError):
+		return http.StatusBadRequest, ErrorBody{Message: err.Error(), ErrorType: ErrorTypeValidation}
+	case errors.As(err, &clientErrPtr) && clientErrPtr != nil:
+		appErr = apperr.FromClientError(*clientErrPtr)
+		return appErr.Status(), appBody(appErr)
+	case errors.As(err, &clientErr):
+		appErr = apperr.FromClientError(clientErr)
+		return appErr.Status(), appBody(appErr)
+	case errors.Is(err, config.ErrLandingNotFound), errors.Is(err, config.ErrProgramNotFound):
+		return http.StatusNotFound, ErrorBody{Message: err.Error(), ErrorType: ErrorTypeNotFound}
+	case errors.Is(err, config.ErrNotLoaded):
+		return http.StatusServiceUnavailable, ErrorBody{Message: http.StatusText(http.StatusServiceUnavailable), ErrorType: ErrorTypeUnavailable}
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, ErrorBody{Message: http.StatusText(http.StatusGatewayTimeout), ErrorType: ErrorTypeTimeout}
+	}
+	velacontext.GetContextLogger(ctx).Error("Request failed", zap.Error(err))
+	return http.StatusInternalServerError, ErrorBody{Message: http.StatusText(http.StatusInternalServerError), ErrorType: ErrorTypeInternal}
+}
+
+func validationBody(fields map[string]string) ErrorBody {
+	body := ErrorBody{Message: validation.ValidationError.Error(), ErrorType: ErrorTypeValidation}
+	for name, message := range fields {
+		body.Fields = append(body.Fields, Field{Name: name, Message: message})
+	}
+	sort.Slice(body.Fields, func(i, j int) bool { return body.Fields[i].Name < body.Fields[j].Name })
+	return body
+}
+
+// appBody is the canonical body for an application error, which only has
+// what's safe to send.
+func appBody(err *apperr.Error) ErrorBody {
+	body := ErrorBody{Message: err.SafeMessage(), ErrorType: string(err.Code)}
+	for _, f := range err.Fields {
+		body.Fields = append(body.Fields, Field{Name: f.Name, Message: f.Message})
+	}
+	return body
+}
+
+// JSON writes body as JSON with the status.
+func JSON(w http.ResponseWriter, status int, body interface{}) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
+
+// WriteError writes the canonical body for err, as Error maps it.
+func WriteError(ctx context.Context, w http.ResponseWriter, err error) {
+	status, body := Error(ctx, err)
+	JSON(w, status, body)
+}
+
+// ALB returns an ALB response with body as JSON.
+func ALB(status int, body interface{}) (*events.ALBTargetGroupResponse, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("respond: marshalling response: %w", err)
+	}
+	return &events.ALBTargetGroupResponse{
+		StatusCode:        status,
+		StatusDescription: fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Headers:           map[string]string{"Content-Type": "application/json"},
+		Body:              string(data),
+	}, nil
+}
+
+// ALBError returns an ALB response with the canonical body for err, as Error
+// maps it.
+func ALBError(ctx context.Context, err error) *events.ALBTargetGroupResponse {
+	status, body := Error(ctx, err)
+	// The body always marshals
+	resp, _ := ALB(status, body)
+	return resp
+}
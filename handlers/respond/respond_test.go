@@ -0,0 +1,149 @@
+package respond
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/seniorlink-vela/cs-common/apperr"
+	"github.com/seniorlink-vela/cs-common/client"
+	"github.com/seniorlink-vela/cs-common/config"
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/validation"
+)
+
+var errCareTeamFull = errors.New("the care team is full")
+
+func TestError(t *testing.T) {
+	RegisterError(errCareTeamFull, http.StatusConflict, "care_team_full")
+	core, logs := observer.New(zapcore.InfoLevel)
+	ctx := velacontext.ContextWithLogger(context.Background(), zap.New(core))
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		body   ErrorBody
+	}{
+		{
+			name:   "error map",
+			err:    fmt.Errorf("creating profile: %w", client.ErrorMap{"last_name": "This is required", "email": "This must be an email"}),
+			status: http.StatusBadRequest,
+			body: ErrorBody{Message: "Validation failed.", ErrorType: ErrorTypeValidation, Fields: []Field{
+				{Name: "email", Message: "This must be an email"},
+				{Name: "last_name", Message: "This is required"},
+			}},
+		},
+		{
+			name:   "field error",
+			err:    validation.FieldError{Field: "email", Message: "This must be an email"},
+			status: http.StatusBadRequest,
+			body:   ErrorBody{Message: "Validation failed.", ErrorType: ErrorTypeValidation, Fields: []Field{{Name: "email", Message: "This must be an email"}}},
+		},
+		{
+			name:   "validation sentinel",
+			err:    validation.ValidationError,
+			status: http.StatusBadRequest,
+			body:   ErrorBody{Message: "Validation failed.", ErrorType: ErrorTypeValidation},
+		},
+		{
+			name:   "client error",
+			err:    client.HttpClientError{StatusCode: 409, Message: "Username taken", ErrorType: "conflict", Fields: []client.HttpErrorField{{Name: "username", Message: "taken"}}},
+			status: http.StatusConflict,
+			body:   ErrorBody{Message: "Username taken", ErrorType: "conflict", Fields: []Field{{Name: "username", Message: "taken"}}},
+		},
+		{
+			name:   "client server error",
+			err:    &client.HttpClientError{StatusCode: 500, Message: "boom"},
+			status: http.StatusBadGateway,
+			body:   ErrorBody{Message: "boom", ErrorType: ErrorTypeUpstream},
+		},
+		{
+			name:   "landing not found",
+			err:    fmt.Errorf("landing foo: %w", config.ErrLandingNotFound),
+			status: http.StatusNotFound,
+			body:   ErrorBody{Message: "landing foo: landing not found", ErrorType: ErrorTypeNotFound},
+		},
+		{
+			name:   "config not loaded",
+			err:    config.ErrNotLoaded,
+			status: http.StatusServiceUnavailable,
+			body:   ErrorBody{Message: "Service Unavailable", ErrorType: ErrorTypeUnavailable},
+		},
+		{
+			name:   "deadline",
+			err:    context.DeadlineExceeded,
+			status: http.StatusGatewayTimeout,
+			body:   ErrorBody{Message: "Gateway Timeout", ErrorType: ErrorTypeTimeout},
+		},
+		{
+			name:   "registered",
+			err:    fmt.Errorf("adding member: %w", errCareTeamFull),
+			status: http.StatusConflict,
+			body:   ErrorBody{Message: "adding member: the care team is full", ErrorType: "care_team_full"},
+		},
+		{
+			name:   "application error",
+			err:    fmt.Errorf("inviting: %w", apperr.New(apperr.CodeConflict, "Already invited").WithField("email", "This is already invited").WithDetail("invite 12")),
+			status: http.StatusConflict,
+			body:   ErrorBody{Message: "Already invited", ErrorType: "conflict", Fields: []Field{{Name: "email", Message: "This is already invited"}}},
+		},
+		{
+			name:   "internal application error",
+			err:    apperr.Wrap(errors.New("pq: connection refused"), apperr.CodeUnavailable, ""),
+			status: http.StatusServiceUnavailable,
+			body:   ErrorBody{Message: "Service Unavailable", ErrorType: ErrorTypeUnavailable},
+		},
+		{
+			name:   "anything else",
+			err:    errors.New("pq: password authentication failed"),
+			status: http.StatusInternalServerError,
+			body:   ErrorBody{Message: "Internal Server Error", ErrorType: ErrorTypeInternal},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := Error(ctx, tt.err)
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, tt.body, body)
+		})
+	}
+	assert.Equal(t, 2, logs.FilterMessage("Request failed").Len(), "only unexpected and server errors are logged")
+}
+
+func TestWriteError(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteError(context.Background(), w, client.ErrorMap{"email": "This must be an email"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	// Vela clients can read it back
+	var decoded client.HttpClientError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &decoded))
+	assert.Equal(t, ErrorTypeValidation, decoded.ErrorType)
+	assert.Equal(t, []client.HttpErrorField{{Name: "email", Message: "This must be an email"}}, decoded.Fields)
+}
+
+func TestALB(t *testing.T) {
+	resp, err := ALB(http.StatusCreated, map[string]string{"id": "42"})
+	require.NoError(t, err)
+	assert.Equal(t, "201 Created", resp.StatusDescription)
+	assert.JSONEq(t, `{"id": "42"}`, resp.Body)
+
+	_, err = ALB(http.StatusOK, make(chan int))
+	assert.Error(t, err)
+
+	resp = ALBError(context.Background(), config.ErrProgramNotFound)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.JSONEq(t, `{"message": "program not found", "error_type": "not_found"}`, resp.Body)
+}
@@ -0,0 +1,146 @@
+package router
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/seniorlink-vela/cs-common/context/lambdamiddleware"
+)
+
+// ALB returns a handler for ALB target group requests, which can be wrapped
+// with the lambdamiddleware ALB middleware like any other.
+func (rt *Router) ALB() lambdamiddleware.ALBHandler {
+	return func(ctx context.Context, event events.ALBTargetGroupRequest) (*events.ALBTargetGroupResponse, error) {
+		body, err := eventBody(event.Body, event.IsBase64Encoded)
+		if err != nil {
+			return nil, err
+		}
+		headers := httpHeaders(event.Headers, event.MultiValueHeaders)
+		req := &Request{
+			Method:   event.HTTPMethod,
+			Path:     event.Path,
+			Query:    lambdamiddleware.ALBQuery(event),
+			Headers:  headers,
+			Body:     body,
+			SourceIP: lambdamiddleware.ForwardedFor(strings.Join(headers.Values("X-Forwarded-For"), ",")),
+		}
+		resp, err := rt.Serve(ctx, req)
+		if resp == nil {
+			return nil, err
+		}
+		out := &events.ALBTargetGroupResponse{
+			StatusCode:        resp.Status,
+			StatusDescription: fmt.Sprintf("%d %s", resp.Status, http.StatusText(resp.Status)),
+			Headers:           resp.Headers,
+			Body:              resp.Body,
+			IsBase64Encoded:   resp.IsBase64Encoded,
+		}
+		if len(event.MultiValueHeaders) > 0 {
+			// ALB ignores Headers when multi-value headers are on
+			out.MultiValueHeaders = make(map[string][]string, len(resp.Headers))
+			for k, v := range resp.Headers {
+				out.MultiValueHeaders[k] = []string{v}
+			}
+			out.Headers = nil
+		}
+		return out, err
+	}
+}
+
+// APIGateway returns a handler for API Gateway REST API (v1) proxy requests.
+func (rt *Router) APIGateway() lambdamiddleware.APIGatewayHandler {
+	return func(ctx context.Context, event events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		body, err := eventBody(event.Body, event.IsBase64Encoded)
+		if err != nil {
+			return nil, err
+		}
+		query := url.Values(event.MultiValueQueryStringParameters)
+		if len(query) == 0 {
+			query = url.Values{}
+			for k, v := range event.QueryStringParameters {
+				query.Set(k, v)
+			}
+		}
+		req := &Request{
+			Method:   event.HTTPMethod,
+			Path:     event.Path,
+			Query:    query,
+			Headers:  httpHeaders(event.Headers, event.MultiValueHeaders),
+			Body:     body,
+			SourceIP: event.RequestContext.Identity.SourceIP,
+		}
+		resp, err := rt.Serve(ctx, req)
+		if resp == nil {
+			return nil, err
+		}
+		return &events.APIGatewayProxyResponse{
+			StatusCode:      resp.Status,
+			Headers:         resp.Headers,
+			Body:            resp.Body,
+			IsBase64Encoded: resp.IsBase64Encoded,
+		}, err
+	}
+}
+
+// APIGatewayV2 returns a handler for API Gateway HTTP API (v2) requests.
+func (rt *Router) APIGatewayV2() lambdamiddleware.APIGatewayV2Handler {
+	return func(ctx context.Context, event events.APIGatewayV2HTTPRequest) (*events.APIGatewayV2HTTPResponse, error) {
+		body, err := eventBody(event.Body, event.IsBase64Encoded)
+		if err != nil {
+			return nil, err
+		}
+		query, err := url.ParseQuery(event.RawQueryString)
+		if err != nil {
+			query = url.Values{}
+		}
+		req := &Request{
+			Method:   event.RequestContext.HTTP.Method,
+			Path:     event.RawPath,
+			Query:    query,
+			Headers:  httpHeaders(event.Headers, nil),
+			Body:     body,
+			SourceIP: event.RequestContext.HTTP.SourceIP,
+		}
+		resp, err := rt.Serve(ctx, req)
+		if resp == nil {
+			return nil, err
+		}
+		return &events.APIGatewayV2HTTPResponse{
+			StatusCode:      resp.Status,
+			Headers:         resp.Headers,
+			Body:            resp.Body,
+			IsBase64Encoded: resp.IsBase64Encoded,
+		}, err
+	}
+}
+
+func eventBody(body string, isBase64Encoded bool) ([]byte, error) {
+	if !isBase64Encoded {
+		return []byte(body), nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return nil, fmt.Errorf("router: decoding request body: %w", err)
+	}
+	return decoded, nil
+}
+
+func httpHeaders(headers map[string]string, multiValueHeaders map[string][]string) http.Header {
+	h := make(http.Header, len(headers)+len(multiValueHeaders))
+	for k, v := range headers {
+		h.Set(k, v)
+	}
+	for k, values := range multiValueHeaders {
+		h.Del(k)
+		for _, v := range values {
+			h.Add(k, v)
+		}
+	}
+	return h
+}
@@ -0,0 +1,87 @@
+package router
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func captureRouter(captured **Request) *Router {
+	rt := New()
+	rt.Post("/care-teams/{id}", func(ctx context.Context, req *Request) (*Response, error) {
+		*captured = req
+		return Text(http.StatusCreated, "created"), nil
+	})
+	return rt
+}
+
+func TestALB(t *testing.T) {
+	var req *Request
+	handler := captureRouter(&req).ALB()
+
+	resp, err := handler(context.Background(), events.ALBTargetGroupRequest{
+		HTTPMethod:            http.MethodPost,
+		Path:                  "/care-teams/42",
+		Headers:               map[string]string{"x-forwarded-for": "10.0.0.1, 10.0.0.2", "content-type": "application/json"},
+		QueryStringParameters: map[string]string{"name": "Ada%20Lovelace"},
+		Body:                  base64.StdEncoding.EncodeToString([]byte(`{"a":1}`)),
+		IsBase64Encoded:       true,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, req)
+	assert.Equal(t, "42", req.Param("id"))
+	assert.Equal(t, "Ada Lovelace", req.Query.Get("name"))
+	assert.Equal(t, `{"a":1}`, string(req.Body))
+	assert.Equal(t, "application/json", req.Headers.Get("Content-Type"))
+	assert.Equal(t, "10.0.0.2", req.SourceIP)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "201 Created", resp.StatusDescription)
+	assert.Equal(t, "created", resp.Body)
+
+	resp, err = handler(context.Background(), events.ALBTargetGroupRequest{
+		HTTPMethod:        http.MethodPost,
+		Path:              "/care-teams/42",
+		MultiValueHeaders: map[string][]string{"accept": {"application/json"}},
+	})
+	require.NoError(t, err)
+	assert.Nil(t, resp.Headers)
+	assert.Equal(t, []string{"text/plain; charset=utf-8"}, resp.MultiValueHeaders["Content-Type"])
+
+	_, err = handler(context.Background(), events.ALBTargetGroupRequest{HTTPMethod: http.MethodPost, Path: "/care-teams/42", Body: "%%%", IsBase64Encoded: true})
+	assert.Error(t, err)
+}
+
+func TestAPIGateway(t *testing.T) {
+	var req *Request
+	rt := captureRouter(&req)
+
+	resp, err := rt.APIGateway()(context.Background(), events.APIGatewayProxyRequest{
+		HTTPMethod:            http.MethodPost,
+		Path:                  "/care-teams/7",
+		QueryStringParameters: map[string]string{"name": "Ada Lovelace"},
+		RequestContext:        events.APIGatewayProxyRequestContext{Identity: events.APIGatewayRequestIdentity{SourceIP: "10.0.0.3"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "7", req.Param("id"))
+	assert.Equal(t, "Ada Lovelace", req.Query.Get("name"))
+	assert.Equal(t, "10.0.0.3", req.SourceIP)
+
+	v2, err := rt.APIGatewayV2()(context.Background(), events.APIGatewayV2HTTPRequest{
+		RawPath:        "/care-teams/8",
+		RawQueryString: "name=Ada+Lovelace",
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodPost, SourceIP: "10.0.0.4"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, v2.StatusCode)
+	assert.Equal(t, "8", req.Param("id"))
+	assert.Equal(t, "Ada Lovelace", req.Query.Get("name"))
+	assert.Equal(t, "10.0.0.4", req.SourceIP)
+}
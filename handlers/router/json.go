@@ -0,0 +1,50 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// MaxBodySize is the largest body DecodeJSON accepts.  ALB bodies can't be
+// bigger than 1MB anyway.
+const MaxBodySize = 1 << 20
+
+// JSON returns a response with the body marshalled as JSON.
+func JSON(status int, body interface{}) (*Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("router: marshalling response: %w", err)
+	}
+	return &Response{
+		Status:  status,
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    string(data),
+	}, nil
+}
+
+// Text returns a plain text response.
+func Text(status int, body string) *Response {
+	return &Response{
+		Status:  status,
+		Headers: map[string]string{"Content-Type": "text/plain; charset=utf-8"},
+		Body:    body,
+	}
+}
+
+// NoContent returns an empty 204 response.
+func NoContent() *Response {
+	return &Response{Status: http.StatusNoContent}
+}
+
+// DecodeJSON unmarshals the request's JSON body into v.
+func (r *Request) DecodeJSON(v interface{}) error {
+	if len(r.Body) > MaxBodySize {
+		return errors.New("router: request body is too large")
+	}
+	if len(r.Body) == 0 {
+		return errors.New("router: request body is empty")
+	}
+	return json.Unmarshal(r.Body, v)
+}
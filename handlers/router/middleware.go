@@ -0,0 +1,120 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/context/httpmiddleware"
+	"github.com/seniorlink-vela/cs-common/context/lambdamiddleware"
+	"github.com/seniorlink-vela/cs-common/handlers/respond"
+)
+
+// RequestContext returns middleware that adds the request ID, AWS trace ID,
+// source IP, baggage and a logger to each request's context, the same way
+// the httpmiddleware and lambdamiddleware versions do, and echoes the request
+// ID on the response.  The logger is the given one named name, or the
+// fallback logger when it's nil.
+func RequestContext(logger *zap.Logger, name string) Middleware {
+	if logger == nil {
+		logger = velacontext.FallbackLogger()
+	}
+	if name != "" {
+		logger = logger.Named(name)
+	}
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) (*Response, error) {
+			requestID := httpmiddleware.RequestIDFromHeader(req.Headers.Get(httpmiddleware.RequestIDHeader))
+			ctx = velacontext.ContextWithRequestID(ctx, requestID)
+			ctx = velacontext.ContextWithLogger(ctx, logger)
+			fields := []zap.Field{
+				zap.String("method", req.Method),
+				zap.String("path", req.Path),
+			}
+			if traceID := req.Headers.Get(lambdamiddleware.TraceIDHeader); traceID != "" {
+				ctx = velacontext.ContextWithAmznTraceID(ctx, traceID)
+				fields = append(fields, zap.String("amzn_trace_id", traceID))
+			}
+			if req.SourceIP != "" {
+				ctx = velacontext.ContextWithSourceIP(ctx, req.SourceIP)
+				fields = append(fields, zap.String("source_ip", req.SourceIP))
+			}
+			ctx = httpmiddleware.BaggageFromHeader(ctx, req.Headers)
+			ctx = velacontext.WithLoggerFields(ctx, fields...)
+
+			resp, err := next(ctx, req)
+			if resp != nil {
+				resp.SetHeader(httpmiddleware.RequestIDHeader, requestID)
+			}
+			return resp, err
+		}
+	}
+}
+
+// Logging returns middleware that logs each request once it's handled, with
+// its status and how long it took, using the context logger.  Server errors
+// are logged as errors.  Put it inside RequestContext, so the log includes the
+// request ID.
+func Logging() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) (*Response, error) {
+			start := time.Now()
+			resp, err := next(ctx, req)
+			status := http.StatusInternalServerError
+			if resp != nil && err == nil {
+				status = resp.Status
+			}
+			fields := []zap.Field{
+				zap.Int("status", status),
+				zap.Duration("latency", time.Since(start)),
+			}
+			logger := velacontext.GetContextLogger(ctx)
+			switch {
+			case err != nil:
+				logger.Error("Request failed", append(fields, zap.Error(err))...)
+			case status >= http.StatusInternalServerError:
+				logger.Error("Request handled", fields...)
+			default:
+				logger.Info("Request handled", fields...)
+			}
+			return resp, err
+		}
+	}
+}
+
+// Recover returns middleware that recovers panics in handlers, logs them with
+// velacontext.Recover, and responds with a 500.  Put it inside
+// RequestContext, so the log includes the request ID.
+func Recover() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) (resp *Response, err error) {
+			defer velacontext.Recover(ctx, func(error) {
+				resp, err = JSON(http.StatusInternalServerError, respond.ErrorBody{
+					Message:   http.StatusText(http.StatusInternalServerError),
+					ErrorType: respond.ErrorTypeInternal,
+				})
+			})
+			return next(ctx, req)
+		}
+	}
+}
+
+// Errors returns middleware that turns errors from handlers into responses
+// with the canonical Vela error body, as respond.Error maps them, rather than
+// failing the Lambda invocation.  Put it inside Logging, so the log has the
+// status that was sent.
+func Errors() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) (*Response, error) {
+			resp, err := next(ctx, req)
+			if err == nil {
+				return resp, nil
+			}
+			status, body := respond.Error(ctx, err)
+			return JSON(status, body)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/seniorlink-vela/cs-common/config"
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/context/httpmiddleware"
+)
+
+func TestMiddleware(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	rt := New()
+	rt.Use(RequestContext(zap.New(core), "api"), Logging(), Recover())
+	rt.Get("/ok", func(ctx context.Context, req *Request) (*Response, error) {
+		assert.Equal(t, "incoming-id", velacontext.GetContextRequestID(ctx))
+		assert.Equal(t, "10.0.0.1", velacontext.GetContextSourceIP(ctx))
+		assert.Equal(t, "web", velacontext.GetBaggage(ctx, "client"))
+		assert.Empty(t, velacontext.GetBaggage(ctx, "notes"))
+		return NoContent(), nil
+	})
+	rt.Get("/panic", func(ctx context.Context, req *Request) (*Response, error) {
+		panic("boom")
+	})
+	rt.Get("/error", func(ctx context.Context, req *Request) (*Response, error) {
+		return nil, errors.New("database is down")
+	})
+
+	headers := http.Header{}
+	headers.Set(httpmiddleware.RequestIDHeader, "incoming-id")
+	headers.Set(velacontext.BaggageHeaderPrefix+"Client", "web")
+	headers.Set(velacontext.BaggageHeaderPrefix+"Notes", strings.Repeat("x", 1000))
+	resp, err := rt.Serve(context.Background(), &Request{Method: http.MethodGet, Path: "/ok", Headers: headers, SourceIP: "10.0.0.1"})
+	require.NoError(t, err)
+	assert.Equal(t, "incoming-id", resp.Headers[httpmiddleware.RequestIDHeader])
+
+	handled := logs.FilterMessage("Request handled").AllUntimed()
+	require.Len(t, handled, 1)
+	fields := handled[0].ContextMap()
+	assert.Equal(t, "incoming-id", fields["request_id"])
+	assert.Equal(t, int64(http.StatusNoContent), fields["status"])
+	assert.Equal(t, "/ok", fields["path"])
+	assert.Equal(t, "api", handled[0].LoggerName)
+
+	resp, err = rt.Serve(context.Background(), &Request{Method: http.MethodGet, Path: "/panic", Headers: http.Header{}})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.Status)
+	assert.NotEmpty(t, resp.Headers[httpmiddleware.RequestIDHeader])
+	assert.Equal(t, 1, logs.FilterMessage("Recovered from panic").Len())
+
+	_, err = rt.Serve(context.Background(), &Request{Method: http.MethodGet, Path: "/error", Headers: http.Header{}})
+	assert.Error(t, err)
+	assert.Equal(t, 1, logs.FilterMessage("Request failed").Len())
+}
+
+func TestErrors(t *testing.T) {
+	rt := New()
+	rt.Use(Errors())
+	rt.Get("/error", func(ctx context.Context, req *Request) (*Response, error) {
+		return nil, config.ErrLandingNotFound
+	})
+
+	resp, err := rt.Serve(context.Background(), &Request{Method: http.MethodGet, Path: "/error", Headers: http.Header{}})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.Status)
+	assert.JSONEq(t, `{"message": "landing not found", "error_type": "not_found"}`, resp.Body)
+}
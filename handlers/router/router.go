@@ -0,0 +1,295 @@
+// Package router routes ALB and API Gateway requests to handlers by method and
+// path, so Lambda services don't need if/else chains in front of their API.
+//
+// Patterns are paths whose segments can be parameters:
+//
+//	/api/v1/care-teams/{care_team_id}/members/{member_id}
+//	/files/{path...}
+//
+// A `{name}` segment matches any one segment, and a final `{name...}` segment
+// matches the rest of the path, however many segments that is.  Handlers get
+// the matched values from Request.Param.  When several patterns match, the one
+// with the most literal segments wins, so /users/me can sit beside
+// /users/{user_id}.  Trailing slashes are ignored.
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/seniorlink-vela/cs-common/handlers/respond"
+)
+
+// Request is an incoming request, whichever kind of event it came in.
+type Request struct {
+	Method  string
+	Path    string
+	Query   url.Values
+	Headers http.Header
+	// Body is decoded when the event's was base64 encoded.
+	Body []byte
+	// SourceIP is the client's address, as the front door reports it.
+	SourceIP string
+
+	params map[string]string
+}
+
+// Param returns a path parameter, or "" when the route doesn't have it.
+func (r *Request) Param(name string) string {
+	return r.params[name]
+}
+
+// Response is what a handler sends back.  Binary bodies are base64 encoded,
+// with IsBase64Encoded set.
+type Response struct {
+	Status          int
+	Headers         map[string]string
+	Body            string
+	IsBase64Encoded bool
+}
+
+// SetHeader sets a response header, making the map if need be.
+func (r *Response) SetHeader(name, value string) {
+	if r.Headers == nil {
+		r.Headers = map[string]string{}
+	}
+	r.Headers[name] = value
+}
+
+// Handler handles a routed request.
+type Handler func(ctx context.Context, req *Request) (*Response, error)
+
+// Middleware wraps a handler, to do something before or after it.
+type Middleware func(Handler) Handler
+
+type segment struct {
+	literal string
+	param   string
+	rest    bool
+}
+
+type route struct {
+	method   string
+	pattern  string
+	segments []segment
+	handler  Handler
+}
+
+// Router sends requests to the handler for the first route matching them.
+// Routes and middleware should be added at start up, before it serves
+// requests.
+type Router struct {
+	routes     []*route
+	middleware []Middleware
+	// NotFound handles requests no route matches.  By default they get a 404
+	// with a JSON error.
+	NotFound Handler
+}
+
+// New returns an empty router.
+func New() *Router {
+	return &Router{}
+}
+
+// Use adds middleware, which wraps every route, in the order added, so the
+// first is the outermost.  It also wraps NotFound and the 405 responses.
+func (rt *Router) Use(middleware ...Middleware) {
+	rt.middleware = append(rt.middleware, middleware...)
+}
+
+// Handle adds a route.  It panics when the pattern is malformed, since that's
+// a mistake in the code.
+func (rt *Router) Handle(method, pattern string, handler Handler) {
+	segments, err := parsePattern(pattern)
+	if err != nil {
+		panic(err)
+	}
+	rt.routes = append(rt.routes, &route{
+		method:   strings.ToUpper(method),
+		pattern:  pattern,
+		segments: segments,
+		handler:  handler,
+	})
+}
+
+// Get adds a route for GET requests.  HEAD requests are routed to it too,
+// when there isn't a HEAD route, and get its headers without the body.
+func (rt *Router) Get(pattern string, handler Handler) { rt.Handle(http.MethodGet, pattern, handler) }
+
+// Post adds a route for POST requests.
+func (rt *Router) Post(pattern string, handler Handler) { rt.Handle(http.MethodPost, pattern, handler) }
+
+// Put adds a route for PUT requests.
+func (rt *Router) Put(pattern string, handler Handler) { rt.Handle(http.MethodPut, pattern, handler) }
+
+// Patch adds a route for PATCH requests.
+func (rt *Router) Patch(pattern string, handler Handler) {
+	rt.Handle(http.MethodPatch, pattern, handler)
+}
+
+// Delete adds a route for DELETE requests.
+func (rt *Router) Delete(pattern string, handler Handler) {
+	rt.Handle(http.MethodDelete, pattern, handler)
+}
+
+func parsePattern(pattern string) ([]segment, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return nil, fmt.Errorf("router: pattern %q must start with /", pattern)
+	}
+	parts := splitPath(pattern)
+	segments := make([]segment, 0, len(parts))
+	for i, part := range parts {
+		if !strings.HasPrefix(part, "{") || !strings.HasSuffix(part, "}") {
+			if strings.ContainsAny(part, "{}") {
+				return nil, fmt.Errorf("router: pattern %q has a malformed parameter %q", pattern, part)
+			}
+			segments = append(segments, segment{literal: part})
+			continue
+		}
+		name := part[1 : len(part)-1]
+		rest := strings.HasSuffix(name, "...")
+		name = strings.TrimSuffix(name, "...")
+		if name == "" {
+			return nil, fmt.Errorf("router: pattern %q has an unnamed parameter", pattern)
+		}
+		if rest && i != len(parts)-1 {
+			return nil, fmt.Errorf("router: pattern %q has %q before its end", pattern, part)
+		}
+		segments = append(segments, segment{param: name, rest: rest})
+	}
+	return segments, nil
+}
+
+func splitPath(p string) []string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+// match returns the route's parameters for a path, and the number of literal
+// segments matched, which ranks the routes that match.
+func (r *route) match(parts []string) (map[string]string, int, bool) {
+	params := map[string]string{}
+	literals := 0
+	for i, seg := range r.segments {
+		if seg.rest {
+			params[seg.param] = strings.Join(parts[i:], "/")
+			return params, literals, true
+		}
+		if i >= len(parts) {
+			return nil, 0, false
+		}
+		if seg.param != "" {
+			value, err := url.PathUnescape(parts[i])
+			if err != nil {
+				return nil, 0, false
+			}
+			params[seg.param] = value
+			continue
+		}
+		if seg.literal != parts[i] {
+			return nil, 0, false
+		}
+		literals++
+	}
+	if len(parts) != len(r.segments) {
+		return nil, 0, false
+	}
+	return params, literals, true
+}
+
+// find returns the best route for the request, and the methods allowed for
+// the path when none of its routes are for the request's.
+func (rt *Router) find(method, path string) (*route, map[string]string, []string) {
+	parts := splitPath(path)
+	var best *route
+	var bestParams map[string]string
+	bestLiterals := -1
+	var allowed []string
+	for _, candidates := range []string{method, headFallback(method)} {
+		if candidates == "" {
+			continue
+		}
+		for _, r := range rt.routes {
+			params, literals, ok := r.match(parts)
+			if !ok {
+				continue
+			}
+			if r.method != candidates {
+				if !contains(allowed, r.method) {
+					allowed = append(allowed, r.method)
+				}
+				continue
+			}
+			if literals > bestLiterals {
+				best, bestParams, bestLiterals = r, params, literals
+			}
+		}
+		if best != nil {
+			return best, bestParams, nil
+		}
+	}
+	return nil, nil, allowed
+}
+
+func headFallback(method string) string {
+	if method == http.MethodHead {
+		return http.MethodGet
+	}
+	return ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Serve routes a request through the middleware to its handler.  The event
+// adapters call it, and it's exported for tests and other front doors.
+func (rt *Router) Serve(ctx context.Context, req *Request) (*Response, error) {
+	route, params, allowed := rt.find(req.Method, req.Path)
+	var handler Handler
+	switch {
+	case route != nil:
+		req.params = params
+		handler = route.handler
+	case len(allowed) > 0:
+		handler = methodNotAllowed(allowed)
+	case rt.NotFound != nil:
+		handler = rt.NotFound
+	default:
+		handler = notFound
+	}
+	for i := len(rt.middleware) - 1; i >= 0; i-- {
+		handler = rt.middleware[i](handler)
+	}
+	resp, err := handler(ctx, req)
+	if resp != nil && req.Method == http.MethodHead {
+		resp.Body = ""
+		resp.IsBase64Encoded = false
+	}
+	return resp, err
+}
+
+func notFound(ctx context.Context, req *Request) (*Response, error) {
+	return JSON(http.StatusNotFound, respond.ErrorBody{Message: "No route for " + req.Path, ErrorType: respond.ErrorTypeNotFound})
+}
+
+func methodNotAllowed(allowed []string) Handler {
+	return func(ctx context.Context, req *Request) (*Response, error) {
+		resp, err := JSON(http.StatusMethodNotAllowed, respond.ErrorBody{Message: req.Method + " isn't allowed for " + req.Path, ErrorType: "method_not_allowed"})
+		if resp != nil {
+			resp.SetHeader("Allow", strings.Join(allowed, ", "))
+		}
+		return resp, err
+	}
+}
@@ -0,0 +1,124 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func echoRoute(name string) Handler {
+	return func(ctx context.Context, req *Request) (*Response, error) {
+		return JSON(http.StatusOK, map[string]string{
+			"route":   name,
+			"id":      req.Param("id"),
+			"note_id": req.Param("note_id"),
+			"path":    req.Param("path"),
+		})
+	}
+}
+
+func serve(t *testing.T, rt *Router, method, path string) (*Response, map[string]string) {
+	resp, err := rt.Serve(context.Background(), &Request{Method: method, Path: path, Headers: http.Header{}})
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	body := map[string]string{}
+	if resp.Body != "" {
+		require.NoError(t, json.Unmarshal([]byte(resp.Body), &body))
+	}
+	return resp, body
+}
+
+func TestRouter(t *testing.T) {
+	rt := New()
+	rt.Get("/users/{id}", echoRoute("user"))
+	rt.Get("/users/me", echoRoute("me"))
+	rt.Put("/users/{id}", echoRoute("update"))
+	rt.Get("/users/{id}/notes/{note_id}", echoRoute("note"))
+	rt.Get("/files/{path...}", echoRoute("files"))
+	rt.Get("/", echoRoute("root"))
+
+	_, body := serve(t, rt, http.MethodGet, "/users/123")
+	assert.Equal(t, "user", body["route"])
+	assert.Equal(t, "123", body["id"])
+
+	_, body = serve(t, rt, http.MethodGet, "/users/me/")
+	assert.Equal(t, "me", body["route"], "literal segments win")
+
+	_, body = serve(t, rt, http.MethodPut, "/users/123")
+	assert.Equal(t, "update", body["route"])
+
+	_, body = serve(t, rt, http.MethodGet, "/users/123/notes/a%20b")
+	assert.Equal(t, "note", body["route"])
+	assert.Equal(t, "a b", body["note_id"])
+
+	_, body = serve(t, rt, http.MethodGet, "/files/care-plans/2026/plan.pdf")
+	assert.Equal(t, "care-plans/2026/plan.pdf", body["path"])
+
+	_, body = serve(t, rt, http.MethodGet, "/")
+	assert.Equal(t, "root", body["route"])
+
+	resp, body := serve(t, rt, http.MethodGet, "/missing")
+	assert.Equal(t, http.StatusNotFound, resp.Status)
+	assert.Equal(t, "No route for /missing", body["message"])
+
+	resp, _ = serve(t, rt, http.MethodDelete, "/users/123")
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.Status)
+	assert.Equal(t, "GET, PUT", resp.Headers["Allow"])
+
+	resp, _ = serve(t, rt, http.MethodHead, "/users/123")
+	assert.Equal(t, http.StatusOK, resp.Status)
+	assert.Empty(t, resp.Body)
+
+	rt.NotFound = func(ctx context.Context, req *Request) (*Response, error) {
+		return Text(http.StatusTeapot, "custom"), nil
+	}
+	resp, _ = rt.Serve(context.Background(), &Request{Method: http.MethodGet, Path: "/missing"})
+	assert.Equal(t, http.StatusTeapot, resp.Status)
+}
+
+func TestRouterMiddleware(t *testing.T) {
+	var order []string
+	mark := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, req *Request) (*Response, error) {
+				order = append(order, name)
+				return next(ctx, req)
+			}
+		}
+	}
+	rt := New()
+	rt.Use(mark("outer"), mark("inner"))
+	rt.Get("/", func(ctx context.Context, req *Request) (*Response, error) {
+		order = append(order, "handler")
+		return NoContent(), nil
+	})
+
+	serve(t, rt, http.MethodGet, "/")
+	assert.Equal(t, []string{"outer", "inner", "handler"}, order)
+
+	order = nil
+	serve(t, rt, http.MethodGet, "/missing")
+	assert.Equal(t, []string{"outer", "inner"}, order, "middleware wraps not found too")
+}
+
+func TestParsePattern(t *testing.T) {
+	for _, pattern := range []string{"users", "/users/{}", "/files/{path...}/more", "/users/{id"} {
+		_, err := parsePattern(pattern)
+		assert.Error(t, err, pattern)
+	}
+	assert.Panics(t, func() { New().Get("users", echoRoute("bad")) })
+}
+
+func TestDecodeJSON(t *testing.T) {
+	var v struct {
+		Name string `json:"name"`
+	}
+	req := &Request{Body: []byte(`{"name": "Ada"}`)}
+	require.NoError(t, req.DecodeJSON(&v))
+	assert.Equal(t, "Ada", v.Name)
+	assert.Error(t, (&Request{}).DecodeJSON(&v))
+}
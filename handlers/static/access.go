@@ -0,0 +1,86 @@
+package static
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/metrics"
+)
+
+// AccessRecord describes a request the handlers answered.
+type AccessRecord struct {
+	Method string
+	Path   string
+	Status int
+	// Bytes is the size of the body sent, after any compression.
+	Bytes    int
+	Duration time.Duration
+	// CacheHit is false when the file had to be fetched from S3.
+	CacheHit bool
+	// Encoding is the Content-Encoding sent, if any.
+	Encoding string
+}
+
+// MetricsHook receives a record of every request the handlers answer, to
+// count requests and bytes sent, and time them.  It's called on the request
+// path, so it shouldn't block.
+type MetricsHook func(ctx context.Context, record AccessRecord)
+
+func recordAccess(ctx context.Context, req request, resp *response, duration time.Duration) {
+	if !options.AccessLog && options.Metrics == nil {
+		return
+	}
+	record := AccessRecord{
+		Method:   req.method,
+		Path:     req.path,
+		Status:   resp.statusCode,
+		Bytes:    resp.contentLength(),
+		Duration: duration,
+		CacheHit: resp.cacheHit,
+		Encoding: resp.headers["Content-Encoding"],
+	}
+	if options.AccessLog {
+		velacontext.GetContextLogger(ctx).Info("Static request",
+			zap.String("method", record.Method),
+			zap.String("path", record.Path),
+			zap.Int("status", record.Status),
+			zap.Int("bytes", record.Bytes),
+			zap.Duration("latency", record.Duration),
+			zap.Bool("cache_hit", record.CacheHit),
+			zap.String("encoding", record.Encoding),
+		)
+	}
+	if options.Metrics != nil {
+		options.Metrics(ctx, record)
+	}
+}
+
+// StandardMetrics returns a hook that records requests, bytes sent and
+// latency with the provider, tagged by status and whether the cache was hit.
+func StandardMetrics(provider metrics.Provider) MetricsHook {
+	requests := provider.Counter(metrics.Definition{
+		Name: "static_requests",
+		Help: "Requests the static handlers answered.",
+		Tags: []string{"status", "cache_hit"},
+	})
+	bytesSent := provider.Counter(metrics.Definition{
+		Name: "static_bytes_sent",
+		Help: "Bytes of body the static handlers sent.",
+		Unit: metrics.UnitBytes,
+	})
+	latency := provider.Histogram(metrics.Definition{
+		Name:    "static_request_duration_ms",
+		Help:    "How long the static handlers took to answer.",
+		Unit:    metrics.UnitMilliseconds,
+		Buckets: []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000},
+	})
+	return func(ctx context.Context, record AccessRecord) {
+		requests.Inc(metrics.Tags{"status": strconv.Itoa(record.Status), "cache_hit": strconv.FormatBool(record.CacheHit)})
+		bytesSent.Add(float64(record.Bytes), nil)
+		latency.Observe(float64(record.Duration)/float64(time.Millisecond), nil)
+	}
+}
@@ -0,0 +1,94 @@
+package static
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/metrics"
+)
+
+func TestAccessLog(t *testing.T) {
+	LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	defer Configure(Options{})
+	core, logs := observer.New(zapcore.InfoLevel)
+	ctx := velacontext.ContextWithLogger(context.Background(), zap.New(core))
+
+	var records []AccessRecord
+	Configure(Options{AccessLog: true, Metrics: func(ctx context.Context, record AccessRecord) {
+		records = append(records, record)
+	}})
+
+	r, err := HandleStaticALB(ctx, events.ALBTargetGroupRequest{Path: "/css/test.css", HTTPMethod: http.MethodGet})
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	r, err = HandleStaticALB(ctx, events.ALBTargetGroupRequest{Path: "/missing.css", HTTPMethod: http.MethodGet})
+	require.NoError(t, err)
+	require.Nil(t, r)
+
+	require.Len(t, records, 1, "requests that fall through aren't ours to record")
+	assert.Equal(t, http.MethodGet, records[0].Method)
+	assert.Equal(t, "/css/test.css", records[0].Path)
+	assert.Equal(t, http.StatusOK, records[0].Status)
+	assert.Equal(t, len(staticURLs["/css/test.css"].Contents), records[0].Bytes)
+	assert.True(t, records[0].CacheHit)
+
+	entries := logs.FilterMessage("Static request").AllUntimed()
+	require.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "/css/test.css", fields["path"])
+	assert.Equal(t, int64(http.StatusOK), fields["status"])
+	assert.Equal(t, true, fields["cache_hit"])
+	assert.Contains(t, fields, "latency")
+}
+
+func TestAccessRecordCacheHit(t *testing.T) {
+	client := &mockS3{objects: map[string]s3Object{"web/css/app.css": {body: "body{}"}}}
+	require.NoError(t, LoadS3Bucket(context.Background(), S3Options{Client: client, Bucket: "assets", Prefix: "web/"}))
+	defer LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	defer Configure(Options{})
+
+	var records []AccessRecord
+	Configure(Options{Metrics: func(ctx context.Context, record AccessRecord) {
+		records = append(records, record)
+	}})
+	for i := 0; i < 2; i++ {
+		_, err := HandleStaticALB(context.Background(), events.ALBTargetGroupRequest{Path: "/css/app.css", HTTPMethod: http.MethodGet})
+		require.NoError(t, err)
+	}
+	require.Len(t, records, 2)
+	assert.False(t, records[0].CacheHit)
+	assert.True(t, records[1].CacheHit)
+}
+
+func TestStandardMetrics(t *testing.T) {
+	var out bytes.Buffer
+	provider := metrics.NewEMF(metrics.EMFOptions{Namespace: "Vela/Static", Writer: &out})
+	hook := StandardMetrics(provider)
+	hook(context.Background(), AccessRecord{Status: http.StatusOK, Bytes: 120, Duration: 3 * time.Millisecond, CacheHit: true})
+	hook(context.Background(), AccessRecord{Status: http.StatusOK, Bytes: 80, Duration: 5 * time.Millisecond, CacheHit: true})
+	require.NoError(t, provider.Flush())
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	var tagged, untagged map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &tagged))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &untagged))
+	assert.Equal(t, float64(2), tagged["static_requests"])
+	assert.Equal(t, "200", tagged["status"])
+	assert.Equal(t, "true", tagged["cache_hit"])
+	assert.Equal(t, float64(200), untagged["static_bytes_sent"])
+	assert.Equal(t, []interface{}{float64(3), float64(5)}, untagged["static_request_duration_ms"])
+}
@@ -0,0 +1,160 @@
+package static
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultCacheSize is how many bytes of file contents are kept in memory by
+// lazy mode and LoadS3Bucket, when they aren't told.
+const DefaultCacheSize = 64 << 20
+
+// The files whose contents are loaded when they're requested, shared by all
+// the handlers.  It's nil when the whole tree is in memory.
+var lazyFiles *fileCache
+
+// CacheStats are counts of how the lazily loaded files have been served since
+// the tree was loaded.
+type CacheStats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64
+	// Entries and Bytes are what's in the cache now.
+	Entries int
+	Bytes   int64
+	// MaxBytes is the cache's size.
+	MaxBytes int64
+}
+
+// GetCacheStats returns the stats for the lazily loaded files, or zeros when
+// everything is in memory.
+func GetCacheStats() CacheStats {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	if lazyFiles == nil {
+		return CacheStats{}
+	}
+	return lazyFiles.stats()
+}
+
+// fileCache keeps the most recently used files in memory, up to a total
+// size, fetching the rest when they're requested.
+type fileCache struct {
+	maxBytes int64
+	fetch    func(ctx context.Context, fd FileDef) (FileDef, error)
+	// bucket is set for files from S3.
+	bucket string
+
+	hits, misses, evictions atomic.Int64
+
+	mu    sync.Mutex
+	bytes int64
+	order *list.List
+	items map[string]*list.Element
+}
+
+type cachedFile struct {
+	fd   FileDef
+	size int64
+}
+
+func newFileCache(maxBytes int64, fetch func(ctx context.Context, fd FileDef) (FileDef, error)) *fileCache {
+	return &fileCache{
+		maxBytes: maxBytes,
+		fetch:    fetch,
+		order:    list.New(),
+		items:    map[string]*list.Element{},
+	}
+}
+
+// lazy reports whether the file's contents are loaded when it's requested.
+func (fd FileDef) lazy() bool {
+	return fd.S3Key != "" || fd.Source != ""
+}
+
+// cacheKey identifies the file's contents, which index pages share between
+// their paths.
+func (fd FileDef) cacheKey() string {
+	if fd.S3Key != "" {
+		return fd.S3Key
+	}
+	return fd.Source
+}
+
+// fetches reports whether serving the file means fetching it, as it isn't
+// cached.
+func (c *fileCache) fetches(fd FileDef) bool {
+	if c == nil || !fd.lazy() {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.items[fd.cacheKey()]
+	return !ok
+}
+
+// load returns the file with its contents, from the cache or fetched.  The
+// path is kept from fd, since index pages are registered under several.
+func (c *fileCache) load(ctx context.Context, fd FileDef) (FileDef, error) {
+	c.mu.Lock()
+	if el, ok := c.items[fd.cacheKey()]; ok {
+		c.order.MoveToFront(el)
+		loaded := el.Value.(*cachedFile).fd
+		c.mu.Unlock()
+		c.hits.Add(1)
+		loaded.Path = fd.Path
+		return loaded, nil
+	}
+	c.mu.Unlock()
+
+	c.misses.Add(1)
+	loaded, err := c.fetch(ctx, fd)
+	if err != nil {
+		return fd, err
+	}
+	c.add(loaded)
+	return loaded, nil
+}
+
+func (c *fileCache) add(fd FileDef) {
+	size := int64(len(fd.Contents))
+	for _, encoded := range fd.Encodings {
+		size += int64(len(encoded))
+	}
+	if size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := fd.cacheKey()
+	if el, ok := c.items[key]; ok {
+		c.bytes -= el.Value.(*cachedFile).size
+		c.order.Remove(el)
+	}
+	c.items[key] = c.order.PushFront(&cachedFile{fd: fd, size: size})
+	c.bytes += size
+	for c.bytes > c.maxBytes {
+		oldest := c.order.Back()
+		cached := oldest.Value.(*cachedFile)
+		c.order.Remove(oldest)
+		delete(c.items, cached.fd.cacheKey())
+		c.bytes -= cached.size
+		c.evictions.Add(1)
+	}
+}
+
+func (c *fileCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Entries:   len(c.items),
+		Bytes:     c.bytes,
+		MaxBytes:  c.maxBytes,
+	}
+}
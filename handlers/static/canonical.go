@@ -0,0 +1,87 @@
+package static
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// TrailingSlashMode is how directory paths, which are registered with and
+// without a trailing slash, are canonicalized.
+type TrailingSlashMode int
+
+const (
+	// TrailingSlashAny serves directories with or without the slash.
+	TrailingSlashAny TrailingSlashMode = iota
+	// TrailingSlashAdd redirects /nested to /nested/.
+	TrailingSlashAdd
+	// TrailingSlashRemove redirects /nested/ to /nested.
+	TrailingSlashRemove
+)
+
+// foldedURLs maps lower cased paths to the registered ones, for
+// case-insensitive lookups.
+var foldedURLs map[string]string
+
+// foldPaths indexes the registry by lower cased path.  When paths only differ
+// by case, the first in sort order wins, so the choice is stable.
+func foldPaths(files map[string]FileDef) map[string]string {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	folded := make(map[string]string, len(paths))
+	for _, p := range paths {
+		if _, ok := folded[strings.ToLower(p)]; !ok {
+			folded[strings.ToLower(p)] = p
+		}
+	}
+	return folded
+}
+
+// canonicalPath returns the path to redirect a request to, when its path
+// isn't the canonical one.
+func canonicalPath(urlPath string) (string, bool) {
+	if _, ok := staticURLs[urlPath]; !ok && options.CaseInsensitive {
+		if registered, ok := foldedURLs[strings.ToLower(urlPath)]; ok {
+			canonical, _ := trailingSlash(registered)
+			return canonical, true
+		}
+	}
+	return trailingSlash(urlPath)
+}
+
+// trailingSlash returns the canonical form of a registered path, and whether
+// it differs.
+func trailingSlash(urlPath string) (string, bool) {
+	if options.TrailingSlash == TrailingSlashAny || urlPath == "/" || urlPath == "" {
+		return urlPath, false
+	}
+	trimmed := strings.TrimSuffix(urlPath, "/")
+	if _, ok := staticURLs[trimmed+"/"+indexPage]; ok {
+		// A directory
+		canonical := trimmed + "/"
+		if options.TrailingSlash == TrailingSlashRemove {
+			canonical = trimmed
+		}
+		return canonical, canonical != urlPath
+	}
+	// Files never have one
+	if trimmed != urlPath {
+		if _, ok := staticURLs[trimmed]; ok {
+			return trimmed, true
+		}
+	}
+	return urlPath, false
+}
+
+func redirectResponse(location, rawQuery string) *response {
+	if rawQuery != "" {
+		location += "?" + rawQuery
+	}
+	return &response{
+		statusCode: http.StatusMovedPermanently,
+		headers:    map[string]string{"Location": location},
+	}
+}
@@ -0,0 +1,57 @@
+package static
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalRedirects(t *testing.T) {
+	LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	defer Configure(Options{})
+	ctx := context.Background()
+
+	get := func(path string, query map[string]string) *events.ALBTargetGroupResponse {
+		r, err := HandleStaticALB(ctx, events.ALBTargetGroupRequest{Path: path, HTTPMethod: http.MethodGet, QueryStringParameters: query})
+		require.NoError(t, err)
+		require.NotNil(t, r, path)
+		return r
+	}
+	redirect := func(path string) string {
+		r := get(path, nil)
+		if r.StatusCode != http.StatusMovedPermanently {
+			return ""
+		}
+		return r.Headers["Location"]
+	}
+
+	// By default both forms are served
+	assert.Equal(t, http.StatusOK, get("/nested", nil).StatusCode)
+	assert.Equal(t, http.StatusOK, get("/nested/", nil).StatusCode)
+
+	Configure(Options{TrailingSlash: TrailingSlashAdd})
+	assert.Equal(t, "/nested/", redirect("/nested"))
+	assert.Equal(t, "", redirect("/nested/"))
+	assert.Equal(t, "", redirect("/"))
+	assert.Equal(t, "/css/test.css", redirect("/css/test.css/"))
+	r := get("/nested", map[string]string{"utm_source": "email"})
+	assert.Equal(t, "/nested/?utm_source=email", r.Headers["Location"])
+
+	Configure(Options{TrailingSlash: TrailingSlashRemove})
+	assert.Equal(t, "/nested", redirect("/nested/"))
+	assert.Equal(t, "", redirect("/nested"))
+
+	Configure(Options{CaseInsensitive: true, TrailingSlash: TrailingSlashAdd})
+	assert.Equal(t, "/css/test.css", redirect("/CSS/Test.css"))
+	assert.Equal(t, "/nested/", redirect("/Nested"))
+	assert.Equal(t, "", redirect("/css/test.css"))
+
+	Configure(Options{})
+	r, err := HandleStaticALB(ctx, events.ALBTargetGroupRequest{Path: "/CSS/Test.css", HTTPMethod: http.MethodGet})
+	require.NoError(t, err)
+	assert.Nil(t, r, "case matters by default")
+}
@@ -0,0 +1,135 @@
+package static
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Files smaller than this aren't worth compressing.
+const minCompressSize = 1024
+
+// Content encodings, in order of preference.
+var encodingPreference = []string{"br", "gzip"}
+
+// The extensions of precompressed files, by their encoding.
+var precompressedExtensions = map[string]string{
+	".br": "br",
+	".gz": "gzip",
+}
+
+// Whether files of the MIME type get smaller when compressed.  Images, fonts
+// and the like are compressed already.
+func compressible(mimeType string) bool {
+	return textual(mimeType) || strings.HasPrefix(mimeType, "application/wasm")
+}
+
+// compress stores gzip and brotli encoded copies of the file's contents, for
+// the ones that come out smaller.
+func (fd *FileDef) compress(contents []byte) {
+	if len(contents) < minCompressSize || !compressible(fd.MimeType) {
+		return
+	}
+	var gz bytes.Buffer
+	gw, _ := gzip.NewWriterLevel(&gz, gzip.BestCompression)
+	_, _ = gw.Write(contents)
+	_ = gw.Close()
+	fd.addEncoding("gzip", gz.Bytes(), len(contents))
+
+	var br bytes.Buffer
+	bw := brotli.NewWriterLevel(&br, brotli.BestCompression)
+	_, _ = bw.Write(contents)
+	_ = bw.Close()
+	fd.addEncoding("br", br.Bytes(), len(contents))
+}
+
+func (fd *FileDef) addEncoding(encoding string, encoded []byte, size int) {
+	if len(encoded) >= size {
+		return
+	}
+	if fd.Encodings == nil {
+		fd.Encodings = map[string]string{}
+	}
+	fd.Encodings[encoding] = base64.StdEncoding.EncodeToString(encoded)
+}
+
+// negotiateEncoding picks the preferred encoding the client accepts, going by
+// its Accept-Encoding header, from the ones available.  It returns an empty
+// string when the file should be sent as is.
+func negotiateEncoding(acceptEncoding string, available map[string]string) string {
+	if acceptEncoding == "" || len(available) == 0 {
+		return ""
+	}
+	accepted := map[string]bool{}
+	wildcard := false
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		ok := true
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				ok = err == nil && q > 0
+			}
+		}
+		if name == "*" {
+			wildcard = ok
+			continue
+		}
+		accepted[name] = ok
+	}
+	for _, encoding := range encodingPreference {
+		if _, has := available[encoding]; !has {
+			continue
+		}
+		if ok, listed := accepted[encoding]; ok || (!listed && wildcard) {
+			return encoding
+		}
+	}
+	return ""
+}
+
+// The ETag for an encoded copy, which has to differ from the original's since
+// the bytes do.
+func encodedETag(etag, encoding string) string {
+	return strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+}
+
+// attachPrecompressed turns the compressed siblings the build left next to
+// files, like app.js.gz and app.js.br next to app.js, into encodings of those
+// files, in place of any made at load time.  The siblings are removed from the
+// registry, so they're only served as encodings.  Siblings without an
+// uncompressed file are left alone.
+func attachPrecompressed(files map[string]FileDef) {
+	for path, compressed := range files {
+		encoding, ok := precompressedExtensions[filepath.Ext(path)]
+		if !ok {
+			continue
+		}
+		original, ok := files[strings.TrimSuffix(path, filepath.Ext(path))]
+		if !ok {
+			continue
+		}
+		// Index pages are registered under several paths, and they all share
+		// the file's ETag
+		for aliasPath, alias := range files {
+			if alias.ETag != original.ETag {
+				continue
+			}
+			encodings := make(map[string]string, len(alias.Encodings)+1)
+			for k, v := range alias.Encodings {
+				encodings[k] = v
+			}
+			encodings[encoding] = compressed.Contents
+			alias.Encodings = encodings
+			files[aliasPath] = alias
+		}
+		delete(files, path)
+	}
+}
@@ -0,0 +1,161 @@
+package static
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	both := map[string]string{"gzip": "", "br": ""}
+	tests := []struct {
+		accept    string
+		available map[string]string
+		expected  string
+	}{
+		{"", both, ""},
+		{"gzip, deflate, br", both, "br"},
+		{"gzip", both, "gzip"},
+		{"br;q=0, gzip;q=0.5", both, "gzip"},
+		{"*", both, "br"},
+		{"*, br;q=0", both, "gzip"},
+		{"identity", both, ""},
+		{"br", map[string]string{"gzip": ""}, ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, negotiateEncoding(tt.accept, tt.available), tt.accept)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	dir := t.TempDir()
+	script := strings.Repeat("console.log('hello, world');\n", 200)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js"), []byte(script), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "small.css"), []byte("body{}"), 0o644))
+
+	Configure(Options{Compress: true})
+	defer Configure(Options{})
+	require.NoError(t, LoadDirectoryTree(dir, dir, "index.html"))
+	defer LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+
+	get := func(path, accept string) *events.ALBTargetGroupResponse {
+		r, err := HandleStaticALB(context.Background(), events.ALBTargetGroupRequest{
+			Path:       path,
+			HTTPMethod: http.MethodGet,
+			Headers:    map[string]string{"accept-encoding": accept},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		return r
+	}
+
+	r := get("/app.js", "gzip, br")
+	assert.Equal(t, "br", r.Headers["Content-Encoding"])
+	assert.Equal(t, "Accept-Encoding", r.Headers["Vary"])
+	assert.True(t, r.IsBase64Encoded)
+	body, _ := base64.StdEncoding.DecodeString(r.Body)
+	decoded, err := ioutil.ReadAll(brotli.NewReader(bytes.NewReader(body)))
+	require.NoError(t, err)
+	assert.Equal(t, script, string(decoded))
+	brETag := r.Headers["ETag"]
+	assert.NotEqual(t, staticURLs["/app.js"].ETag, brETag)
+
+	r = get("/app.js", "gzip")
+	assert.Equal(t, "gzip", r.Headers["Content-Encoding"])
+	body, _ = base64.StdEncoding.DecodeString(r.Body)
+	gr, err := gzip.NewReader(bytes.NewReader(body))
+	require.NoError(t, err)
+	decoded, err = ioutil.ReadAll(gr)
+	require.NoError(t, err)
+	assert.Equal(t, script, string(decoded))
+
+	r = get("/app.js", "")
+	assert.Empty(t, r.Headers["Content-Encoding"])
+	assert.Equal(t, script, r.Body)
+
+	// The encoded copy's ETag revalidates it
+	r, err = HandleStaticALB(context.Background(), events.ALBTargetGroupRequest{
+		Path:       "/app.js",
+		HTTPMethod: http.MethodGet,
+		Headers:    map[string]string{"accept-encoding": "br", "if-none-match": brETag},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotModified, r.StatusCode)
+
+	// Small files aren't compressed
+	r = get("/small.css", "br")
+	assert.Empty(t, r.Headers["Content-Encoding"])
+	assert.Empty(t, r.Headers["Vary"])
+}
+
+func TestPrecompressedSiblings(t *testing.T) {
+	dir := t.TempDir()
+	compress := func(name string, contents []byte) {
+		var gz bytes.Buffer
+		gw := gzip.NewWriter(&gz)
+		_, _ = gw.Write(contents)
+		_ = gw.Close()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name+".gz"), gz.Bytes(), 0o644))
+		var br bytes.Buffer
+		bw := brotli.NewWriter(&br)
+		_, _ = bw.Write(contents)
+		_ = bw.Close()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name+".br"), br.Bytes(), 0o644))
+	}
+	script := []byte("console.log('hello');\n")
+	page := []byte("<html><body>hello</body></html>\n")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js"), script, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), page, 0o644))
+	compress("app.js", script)
+	compress("index.html", page)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "orphan.css.gz"), []byte("x"), 0o644))
+
+	require.NoError(t, LoadDirectoryTree(dir, dir, "index.html"))
+	defer LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+
+	assert.NotContains(t, staticURLs, "/app.js.gz")
+	assert.NotContains(t, staticURLs, "/app.js.br")
+	assert.Contains(t, staticURLs, "/orphan.css.gz")
+
+	get := func(path, accept string) *events.ALBTargetGroupResponse {
+		r, err := HandleStaticALB(context.Background(), events.ALBTargetGroupRequest{
+			Path:       path,
+			HTTPMethod: http.MethodGet,
+			Headers:    map[string]string{"Accept-Encoding": accept},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		return r
+	}
+
+	r := get("/app.js", "gzip")
+	assert.Equal(t, "gzip", r.Headers["Content-Encoding"])
+	assert.Equal(t, staticURLs["/app.js"].MimeType, r.Headers["Content-Type"])
+	body, _ := base64.StdEncoding.DecodeString(r.Body)
+	gr, err := gzip.NewReader(bytes.NewReader(body))
+	require.NoError(t, err)
+	decoded, _ := ioutil.ReadAll(gr)
+	assert.Equal(t, script, decoded)
+
+	r = get("/app.js", "")
+	assert.Empty(t, r.Headers["Content-Encoding"])
+	assert.Equal(t, string(script), r.Body)
+
+	// The index aliases get the siblings too
+	for _, path := range []string{"/index.html", "/", ""} {
+		r = get(path, "br")
+		assert.Equal(t, "br", r.Headers["Content-Encoding"], path)
+	}
+}
@@ -0,0 +1,109 @@
+package static
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS lets pages on other origins use the static assets, like fonts and JSON
+// files, which browsers otherwise block.
+type CORS struct {
+	// AllowedOrigins are the origins allowed to read assets, like
+	// `https://app.vela.care`.  `*` allows any origin, and path.Match
+	// patterns like `https://*.vela.care` allow subdomains.
+	AllowedOrigins []string
+	// AllowedMethods default to GET, HEAD and OPTIONS.
+	AllowedMethods []string
+	// AllowedHeaders are the request headers preflights may ask for.
+	AllowedHeaders []string
+	// MaxAge is how long browsers may cache a preflight response.
+	MaxAge time.Duration
+	// AllowCredentials lets the requests include cookies.  The origin is
+	// always echoed back with it, even when any origin is allowed.
+	AllowCredentials bool
+}
+
+var defaultCORSMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for an origin, or
+// "" when it isn't allowed.
+func (c *CORS) allowOrigin(origin string) string {
+	if origin == "" {
+		return ""
+	}
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			if c.AllowCredentials {
+				return origin
+			}
+			return "*"
+		}
+		if ok, _ := path.Match(allowed, origin); ok {
+			return origin
+		}
+	}
+	return ""
+}
+
+func (c *CORS) methods() []string {
+	if len(c.AllowedMethods) > 0 {
+		return c.AllowedMethods
+	}
+	return defaultCORSMethods
+}
+
+// addHeaders adds the CORS headers to a response to an allowed origin.  The
+// response varies by Origin unless every origin gets the same answer, so
+// caches don't hand one origin's response to another.
+func (c *CORS) addHeaders(req request, r *response) bool {
+	if r.headers == nil {
+		r.headers = map[string]string{}
+	}
+	allowed := c.allowOrigin(req.header("Origin"))
+	if allowed != "*" {
+		addVary(r.headers, "Origin")
+	}
+	if allowed == "" {
+		return false
+	}
+	r.headers["Access-Control-Allow-Origin"] = allowed
+	if c.AllowCredentials {
+		r.headers["Access-Control-Allow-Credentials"] = "true"
+	}
+	return true
+}
+
+// preflight answers an OPTIONS request for a static asset.  Preflights from
+// origins that aren't allowed get no CORS headers, so the browser blocks the
+// request.
+func (c *CORS) preflight(req request) *response {
+	resp := &response{statusCode: http.StatusNoContent, headers: map[string]string{}}
+	if !c.addHeaders(req, resp) || req.header("Access-Control-Request-Method") == "" {
+		return resp
+	}
+	resp.headers["Access-Control-Allow-Methods"] = strings.Join(c.methods(), ", ")
+	if len(c.AllowedHeaders) > 0 {
+		resp.headers["Access-Control-Allow-Headers"] = strings.Join(c.AllowedHeaders, ", ")
+	}
+	if c.MaxAge > 0 {
+		resp.headers["Access-Control-Max-Age"] = strconv.Itoa(int(c.MaxAge.Seconds()))
+	}
+	return resp
+}
+
+func addVary(headers map[string]string, name string) {
+	vary := headers["Vary"]
+	for _, v := range strings.Split(vary, ",") {
+		if strings.EqualFold(strings.TrimSpace(v), name) {
+			return
+		}
+	}
+	if vary == "" {
+		headers["Vary"] = name
+		return
+	}
+	headers["Vary"] = vary + ", " + name
+}
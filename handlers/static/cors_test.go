@@ -0,0 +1,95 @@
+package static
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORS(t *testing.T) {
+	LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	defer Configure(Options{})
+	ctx := context.Background()
+
+	alb := func(method, path string, headers map[string]string) *events.ALBTargetGroupResponse {
+		r, err := HandleStaticALB(ctx, events.ALBTargetGroupRequest{Path: path, HTTPMethod: method, Headers: headers})
+		require.NoError(t, err)
+		return r
+	}
+	preflight := map[string]string{
+		"Origin":                        "https://app.vela.care",
+		"Access-Control-Request-Method": http.MethodGet,
+	}
+
+	// Without CORS options, OPTIONS isn't ours and there are no headers
+	assert.Nil(t, alb(http.MethodOptions, "/css/test.css", preflight))
+	assert.NotContains(t, alb(http.MethodGet, "/css/test.css", preflight).Headers, "Access-Control-Allow-Origin")
+
+	Configure(Options{CORS: &CORS{
+		AllowedOrigins: []string{"https://*.vela.care"},
+		AllowedHeaders: []string{"Authorization"},
+		MaxAge:         10 * time.Minute,
+	}})
+
+	r := alb(http.MethodOptions, "/css/test.css", preflight)
+	require.NotNil(t, r)
+	assert.Equal(t, http.StatusNoContent, r.StatusCode)
+	assert.Equal(t, "https://app.vela.care", r.Headers["Access-Control-Allow-Origin"])
+	assert.Equal(t, "GET, HEAD, OPTIONS", r.Headers["Access-Control-Allow-Methods"])
+	assert.Equal(t, "Authorization", r.Headers["Access-Control-Allow-Headers"])
+	assert.Equal(t, "600", r.Headers["Access-Control-Max-Age"])
+	assert.Equal(t, "Origin", r.Headers["Vary"])
+
+	// Unknown paths fall through to the next handler
+	assert.Nil(t, alb(http.MethodOptions, "/api/users", preflight))
+
+	r = alb(http.MethodOptions, "/css/test.css", map[string]string{"Origin": "https://evil.example.com", "Access-Control-Request-Method": http.MethodGet})
+	require.NotNil(t, r)
+	assert.NotContains(t, r.Headers, "Access-Control-Allow-Origin")
+	assert.NotContains(t, r.Headers, "Access-Control-Allow-Methods")
+
+	r = alb(http.MethodGet, "/css/test.css", map[string]string{"Origin": "https://app.vela.care"})
+	require.NotNil(t, r)
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+	assert.Equal(t, "https://app.vela.care", r.Headers["Access-Control-Allow-Origin"])
+	assert.NotContains(t, r.Headers, "Access-Control-Allow-Credentials")
+
+	// The other front doors answer preflights too
+	v2, err := HandleStaticAPIGWV2(ctx, events.APIGatewayV2HTTPRequest{
+		RawPath: "/css/test.css",
+		Headers: map[string]string{"origin": "https://app.vela.care", "access-control-request-method": "GET"},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodOptions},
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, v2)
+	assert.Equal(t, "https://app.vela.care", v2.Headers["Access-Control-Allow-Origin"])
+
+	req := httptest.NewRequest(http.MethodOptions, "/css/test.css", nil)
+	req.Header.Set("Origin", "https://app.vela.care")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://app.vela.care", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSAllowOrigin(t *testing.T) {
+	wildcard := &CORS{AllowedOrigins: []string{"*"}}
+	assert.Equal(t, "*", wildcard.allowOrigin("https://app.vela.care"))
+	assert.Equal(t, "", wildcard.allowOrigin(""))
+
+	wildcard.AllowCredentials = true
+	assert.Equal(t, "https://app.vela.care", wildcard.allowOrigin("https://app.vela.care"))
+
+	exact := &CORS{AllowedOrigins: []string{"https://app.vela.care"}}
+	assert.Equal(t, "https://app.vela.care", exact.allowOrigin("https://app.vela.care"))
+	assert.Equal(t, "", exact.allowOrigin("https://app.vela.care.evil.com"))
+}
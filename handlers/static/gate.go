@@ -0,0 +1,96 @@
+package static
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+// TokenValidator checks a bearer token, returning an error when it isn't
+// valid.
+type TokenValidator func(ctx context.Context, token string) error
+
+// Gate protects the bundles of internal tools, which shouldn't be served to
+// just anyone.  Requests need either basic auth credentials that match
+// Credentials, or a bearer token Validator accepts.  It's meant as a simple
+// gate in front of tools, not as the auth for patient-facing apps.
+type Gate struct {
+	// Credentials are the passwords for basic auth, by user name.
+	Credentials map[string]string
+	// Validator checks bearer tokens, when it's set.
+	Validator TokenValidator
+	// Realm is sent with basic auth challenges.
+	Realm string
+	// Public are patterns for paths served without credentials, like
+	// `/favicon.ico` or `/login/**`.  They use the same syntax as
+	// CachePolicy patterns.
+	Public []string
+}
+
+// allows reports whether a request may be served.  A nil gate allows all of
+// them.
+func (g *Gate) allows(ctx context.Context, req request) bool {
+	if g == nil {
+		return true
+	}
+	for _, pattern := range g.Public {
+		if matchPattern(pattern, req.path) {
+			return true
+		}
+	}
+	scheme, credentials, _ := strings.Cut(req.header("Authorization"), " ")
+	credentials = strings.TrimSpace(credentials)
+	switch {
+	case strings.EqualFold(scheme, "Basic") && len(g.Credentials) > 0:
+		return g.basic(credentials)
+	case strings.EqualFold(scheme, "Bearer") && g.Validator != nil && credentials != "":
+		if err := g.Validator(ctx, credentials); err != nil {
+			velacontext.GetContextLogger(ctx).Info("Static gate rejected a token", zap.String("path", req.path), zap.Error(err))
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+func (g *Gate) basic(credentials string) bool {
+	decoded, err := base64.StdEncoding.DecodeString(credentials)
+	if err != nil {
+		return false
+	}
+	user, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return false
+	}
+	expected, ok := g.Credentials[user]
+	if !ok {
+		return false
+	}
+	// Comparing hashes keeps the comparison constant time whatever the
+	// lengths
+	got, want := sha256.Sum256([]byte(password)), sha256.Sum256([]byte(expected))
+	return subtle.ConstantTimeCompare(got[:], want[:]) == 1
+}
+
+func (g *Gate) unauthorized() *response {
+	resp := errorResponse(http.StatusUnauthorized)
+	resp.headers["Cache-Control"] = "no-store"
+	if len(g.Credentials) > 0 {
+		realm := g.Realm
+		if realm == "" {
+			realm = "Restricted"
+		}
+		resp.headers["WWW-Authenticate"] = fmt.Sprintf(`Basic realm=%q, charset="UTF-8"`, realm)
+	} else {
+		resp.headers["WWW-Authenticate"] = "Bearer"
+	}
+	return resp
+}
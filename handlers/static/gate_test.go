@@ -0,0 +1,64 @@
+package static
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGate(t *testing.T) {
+	LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	defer Configure(Options{})
+	ctx := context.Background()
+
+	get := func(path, authorization string) *events.ALBTargetGroupResponse {
+		headers := map[string]string{}
+		if authorization != "" {
+			headers["authorization"] = authorization
+		}
+		r, err := HandleStaticALB(ctx, events.ALBTargetGroupRequest{Path: path, HTTPMethod: http.MethodGet, Headers: headers})
+		require.NoError(t, err)
+		return r
+	}
+	basic := func(user, password string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+	}
+
+	Configure(Options{Gate: &Gate{
+		Credentials: map[string]string{"ops": "therug"},
+		Realm:       "Vela Tools",
+		Public:      []string{"/css/**"},
+	}})
+	r := get("/", "")
+	require.NotNil(t, r)
+	assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
+	assert.Equal(t, `Basic realm="Vela Tools", charset="UTF-8"`, r.Headers["WWW-Authenticate"])
+	assert.Equal(t, "no-store", r.Headers["Cache-Control"])
+
+	assert.Equal(t, http.StatusUnauthorized, get("/", basic("ops", "wrong")).StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, get("/", basic("other", "therug")).StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, get("/", "Basic not-base64!").StatusCode)
+	assert.Equal(t, http.StatusOK, get("/", basic("ops", "therug")).StatusCode)
+	assert.Equal(t, http.StatusOK, get("/css/test.css", "").StatusCode)
+
+	// Paths that aren't ours still fall through
+	assert.Nil(t, get("/api/users", ""))
+
+	Configure(Options{Gate: &Gate{Validator: func(ctx context.Context, token string) error {
+		if token != "good" {
+			return errors.New("unknown token")
+		}
+		return nil
+	}}})
+	r = get("/index.html", "Bearer bad")
+	assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
+	assert.Equal(t, "Bearer", r.Headers["WWW-Authenticate"])
+	assert.Equal(t, http.StatusOK, get("/index.html", "Bearer good").StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, get("/index.html", basic("ops", "therug")).StatusCode)
+}
@@ -0,0 +1,42 @@
+package static
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// Handler serves the loaded directory tree for services running as regular
+// HTTP servers, using the same registry as the Lambda handlers.  Paths that
+// aren't static assets get a 404.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers := make(map[string]string, len(r.Header))
+		for k, v := range r.Header {
+			headers[k] = strings.Join(v, ",")
+		}
+		resp := serve(r.Context(), request{method: r.Method, path: r.URL.Path, rawQuery: r.URL.RawQuery, headers: headers})
+		if resp == nil {
+			http.NotFound(w, r)
+			return
+		}
+		writeResponse(w, resp)
+	})
+}
+
+func writeResponse(w http.ResponseWriter, resp *response) {
+	body := []byte(resp.body)
+	if resp.isBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(resp.body)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		body = decoded
+	}
+	for k, v := range resp.headers {
+		w.Header().Set(k, v)
+	}
+	w.WriteHeader(resp.statusCode)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,52 @@
+package static
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	handler := Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nested", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, staticURLs["/nested/index.html"].Contents, rec.Body.String())
+
+	// Binary files are decoded
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/img/theodolite.jpg", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	expected, err := ioutil.ReadFile(filepath.Join(testDataDir, "img", "theodolite.jpg"))
+	require.NoError(t, err)
+	assert.Equal(t, expected, rec.Body.Bytes())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing.css", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/index.html", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHandlerHead(t *testing.T) {
+	LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/img/theodolite.jpg", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Body.Bytes())
+	expected, err := ioutil.ReadFile(filepath.Join(testDataDir, "img", "theodolite.jpg"))
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(len(expected)), rec.Header().Get("Content-Length"))
+}
@@ -0,0 +1,36 @@
+package static
+
+import (
+	"regexp"
+	"strings"
+)
+
+var variablePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// interpolates reports whether Variables should be substituted in a file.
+func (o Options) interpolates(fd FileDef) bool {
+	if len(o.Variables) == 0 {
+		return false
+	}
+	if len(o.InterpolatePatterns) == 0 {
+		return strings.HasPrefix(fd.MimeType, "text/html")
+	}
+	for _, pattern := range o.InterpolatePatterns {
+		if matchPattern(pattern, fd.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// interpolate substitutes variables for `${NAME}`.  Names without a value are
+// left as they are, rather than blanked, so a missing variable shows up in the
+// page instead of quietly breaking it.
+func interpolate(contents []byte, variables map[string]string) []byte {
+	return variablePattern.ReplaceAllFunc(contents, func(match []byte) []byte {
+		if value, ok := variables[string(match[2:len(match)-1])]; ok {
+			return []byte(value)
+		}
+		return match
+	})
+}
@@ -0,0 +1,43 @@
+package static
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterpolate(t *testing.T) {
+	variables := map[string]string{"API_BASE_URL": "https://api.vela.care", "CLIENT_ID": "web"}
+	assert.Equal(t,
+		`<script>window.config = {api: "https://api.vela.care", client: "web", other: "${OTHER}", $API_BASE_URL}</script>`,
+		string(interpolate([]byte(`<script>window.config = {api: "${API_BASE_URL}", client: "${CLIENT_ID}", other: "${OTHER}", $API_BASE_URL}</script>`), variables)),
+	)
+}
+
+func TestLoadDirectoryTreeInterpolates(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte(`<meta name="api" content="${API_BASE_URL}">`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js"), []byte("const url = `${API_BASE_URL}`"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"api": "${API_BASE_URL}"}`), 0o644))
+	defer LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	defer Configure(Options{})
+
+	Configure(Options{Variables: map[string]string{"API_BASE_URL": "https://api.vela.care"}})
+	require.NoError(t, LoadDirectoryTree(dir, dir, "index.html"))
+	assert.Equal(t, `<meta name="api" content="https://api.vela.care">`, staticURLs["/index.html"].Contents)
+	assert.Equal(t, staticURLs["/index.html"].Contents, staticURLs["/"].Contents)
+	assert.Equal(t, "const url = `${API_BASE_URL}`", staticURLs["/app.js"].Contents)
+	interpolatedETag := staticURLs["/index.html"].ETag
+
+	Configure(Options{
+		Variables:           map[string]string{"API_BASE_URL": "https://api.vela.care"},
+		InterpolatePatterns: []string{"*.json"},
+	})
+	require.NoError(t, LoadDirectoryTree(dir, dir, "index.html"))
+	assert.Equal(t, `{"api": "https://api.vela.care"}`, staticURLs["/config.json"].Contents)
+	assert.Equal(t, `<meta name="api" content="${API_BASE_URL}">`, staticURLs["/index.html"].Contents)
+	assert.NotEqual(t, interpolatedETag, staticURLs["/index.html"].ETag)
+}
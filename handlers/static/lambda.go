@@ -0,0 +1,69 @@
+package static
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// The handlers for the other AWS front doors share the registry with
+// HandleStaticALB, so the same bundle is served however the Lambda is
+// invoked.  Like it, they return a nil response when the path isn't a static
+// asset, so the request can be passed on to other handlers.
+
+// HandleStaticAPIGW serves static assets for API Gateway REST API (v1) proxy
+// requests.
+func HandleStaticAPIGW(ctx context.Context, req events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+	headers := req.Headers
+	if len(headers) == 0 && len(req.MultiValueHeaders) > 0 {
+		headers = albHeaders(events.ALBTargetGroupRequest{MultiValueHeaders: req.MultiValueHeaders})
+	}
+	query := url.Values(req.MultiValueQueryStringParameters)
+	if len(query) == 0 {
+		query = url.Values{}
+		for k, v := range req.QueryStringParameters {
+			query.Set(k, v)
+		}
+	}
+	resp := serve(ctx, request{method: req.HTTPMethod, path: req.Path, rawQuery: query.Encode(), headers: headers})
+	if resp == nil {
+		return nil, nil
+	}
+	return &events.APIGatewayProxyResponse{
+		StatusCode:      resp.statusCode,
+		Headers:         resp.headers,
+		Body:            resp.body,
+		IsBase64Encoded: resp.isBase64Encoded,
+	}, nil
+}
+
+// HandleStaticAPIGWV2 serves static assets for API Gateway HTTP API requests
+// using the 2.0 payload format.
+func HandleStaticAPIGWV2(ctx context.Context, req events.APIGatewayV2HTTPRequest) (*events.APIGatewayV2HTTPResponse, error) {
+	resp := serve(ctx, request{method: req.RequestContext.HTTP.Method, path: req.RawPath, rawQuery: req.RawQueryString, headers: req.Headers})
+	if resp == nil {
+		return nil, nil
+	}
+	return &events.APIGatewayV2HTTPResponse{
+		StatusCode:      resp.statusCode,
+		Headers:         resp.headers,
+		Body:            resp.body,
+		IsBase64Encoded: resp.isBase64Encoded,
+	}, nil
+}
+
+// HandleStaticFunctionURL serves static assets for Lambda Function URL
+// requests.
+func HandleStaticFunctionURL(ctx context.Context, req events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLResponse, error) {
+	resp := serve(ctx, request{method: req.RequestContext.HTTP.Method, path: req.RawPath, rawQuery: req.RawQueryString, headers: req.Headers})
+	if resp == nil {
+		return nil, nil
+	}
+	return &events.LambdaFunctionURLResponse{
+		StatusCode:      resp.statusCode,
+		Headers:         resp.headers,
+		Body:            resp.body,
+		IsBase64Encoded: resp.isBase64Encoded,
+	}, nil
+}
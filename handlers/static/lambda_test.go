@@ -0,0 +1,57 @@
+package static
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleStaticAPIGW(t *testing.T) {
+	LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	ctx := context.Background()
+
+	r, err := HandleStaticAPIGW(ctx, events.APIGatewayProxyRequest{Path: "/css/test.css", HTTPMethod: http.MethodGet})
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+	assert.Equal(t, "text/css; charset=utf-8", r.Headers["Content-Type"])
+	assert.Equal(t, staticURLs["/css/test.css"].Contents, r.Body)
+
+	r, err = HandleStaticAPIGW(ctx, events.APIGatewayProxyRequest{Path: "/missing", HTTPMethod: http.MethodGet})
+	assert.NoError(t, err)
+	assert.Nil(t, r)
+}
+
+func TestHandleStaticAPIGWV2(t *testing.T) {
+	LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	ctx := context.Background()
+
+	req := events.APIGatewayV2HTTPRequest{RawPath: "/img/theodolite.jpg"}
+	req.RequestContext.HTTP.Method = http.MethodGet
+	r, err := HandleStaticAPIGWV2(ctx, req)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.True(t, r.IsBase64Encoded)
+	assert.Equal(t, "image/jpeg", r.Headers["Content-Type"])
+
+	req.RequestContext.HTTP.Method = http.MethodPost
+	r, err = HandleStaticAPIGWV2(ctx, req)
+	assert.NoError(t, err)
+	assert.Nil(t, r)
+}
+
+func TestHandleStaticFunctionURL(t *testing.T) {
+	LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	ctx := context.Background()
+
+	req := events.LambdaFunctionURLRequest{RawPath: "/nested/"}
+	req.RequestContext.HTTP.Method = http.MethodGet
+	r, err := HandleStaticFunctionURL(ctx, req)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, staticURLs["/nested/index.html"].Contents, r.Body)
+}
@@ -0,0 +1,55 @@
+package static
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// loadMetadata fills in everything but the contents for a file found by the
+// walk, for lazy mode.  The file is hashed for its ETag as it's read, so it
+// never has to be in memory, unless it's interpolated.
+func (fd *FileDef) loadMetadata() error {
+	fd.Source = fd.Path
+	fd.Path = urlPath(fd.Path)
+	info, err := os.Stat(fd.Source)
+	if err != nil {
+		return err
+	}
+	fd.ModTime = info.ModTime().UTC().Truncate(time.Second)
+	if options.interpolates(*fd) {
+		contents, err := os.ReadFile(fd.Source)
+		if err != nil {
+			return err
+		}
+		fd.setETag(interpolate(contents, options.Variables))
+		return nil
+	}
+	f, err := os.Open(fd.Source)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return err
+	}
+	fd.ETag = fmt.Sprintf(`"%x"`, hash.Sum(nil)[:16])
+	return nil
+}
+
+// loadSource reads a lazily loaded file's contents from disk.
+func loadSource(ctx context.Context, fd FileDef) (FileDef, error) {
+	contents, err := os.ReadFile(fd.Source)
+	if err != nil {
+		return fd, err
+	}
+	if options.interpolates(fd) {
+		contents = interpolate(contents, options.Variables)
+	}
+	fd.setContents(contents)
+	return fd, nil
+}
@@ -0,0 +1,75 @@
+package static
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLazy(t *testing.T) {
+	defer LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	defer Configure(Options{})
+	ctx := context.Background()
+
+	eager := map[string]FileDef{}
+	require.NoError(t, LoadDirectoryTree(testDataDir, testDataDir, "index.html"))
+	for path, fd := range staticURLs {
+		eager[path] = fd
+	}
+	assert.Equal(t, CacheStats{}, GetCacheStats())
+
+	Configure(Options{Lazy: true, CacheSize: 1024})
+	require.NoError(t, LoadDirectoryTree(testDataDir, testDataDir, "index.html"))
+	for path, fd := range staticURLs {
+		assert.Empty(t, fd.Contents, path)
+		assert.Equal(t, eager[path].ETag, fd.ETag, path)
+		assert.Equal(t, eager[path].ModTime, fd.ModTime, path)
+	}
+
+	get := func(path string) *events.ALBTargetGroupResponse {
+		r, err := HandleStaticALB(ctx, events.ALBTargetGroupRequest{Path: path, HTTPMethod: http.MethodGet})
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		return r
+	}
+	assert.Equal(t, eager["/css/test.css"].Contents, get("/css/test.css").Body)
+	assert.Equal(t, eager["/index.html"].Contents, get("/").Body)
+	assert.Equal(t, eager["/index.html"].Contents, get("/index.html").Body, "index aliases share the cached file")
+	stats := GetCacheStats()
+	assert.Equal(t, int64(2), stats.Misses)
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(1024), stats.MaxBytes)
+
+	// The image is bigger than the cache, so it's never kept
+	assert.Equal(t, eager["/img/theodolite.jpg"].Contents, get("/img/theodolite.jpg").Body)
+	get("/img/theodolite.jpg")
+	assert.Equal(t, int64(4), GetCacheStats().Misses)
+}
+
+func TestLazyEvictions(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.css", "b.css", "c.css"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(strings.Repeat("x", 40)), 0o644))
+	}
+	defer LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	defer Configure(Options{})
+	Configure(Options{Lazy: true, CacheSize: 100})
+	require.NoError(t, LoadDirectoryTree(dir, dir, "index.html"))
+
+	for _, path := range []string{"/a.css", "/b.css", "/c.css"} {
+		_, ok, err := lookupFile(context.Background(), path)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+	stats := GetCacheStats()
+	assert.Equal(t, int64(1), stats.Evictions)
+	assert.Equal(t, 2, stats.Entries)
+	assert.Equal(t, int64(80), stats.Bytes)
+}
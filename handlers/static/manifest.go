@@ -0,0 +1,75 @@
+package static
+
+import (
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DefaultManifestPath is where the manifest is served when
+// Options.ManifestPath isn't set.
+const DefaultManifestPath = "/asset-manifest.json"
+
+// hashLength is how many hex digits of the content hash go in a hashed path.
+const hashLength = 10
+
+// manifest maps the paths of assets to their hashed paths.
+var manifest map[string]string
+
+// hashedPath puts part of the file's content hash before its extension, so
+// `/js/app.js` becomes `/js/app.3f9ab2c1d0.js`.
+func hashedPath(fd FileDef) string {
+	hash := strings.Trim(fd.ETag, `"`)
+	if len(hash) > hashLength {
+		hash = hash[:hashLength]
+	}
+	ext := path.Ext(fd.Path)
+	return strings.TrimSuffix(fd.Path, ext) + "." + hash + ext
+}
+
+// buildManifest registers a hashed alias for every asset that isn't a page,
+// and the manifest listing them, which it returns.  Pages are left out, since
+// their URLs are the ones people visit and bookmark.
+func buildManifest(files map[string]FileDef) map[string]string {
+	manifest := map[string]string{}
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		fd := files[p]
+		if fd.ETag == "" || strings.HasPrefix(fd.MimeType, "text/html") {
+			continue
+		}
+		alias := fd
+		alias.Path = hashedPath(fd)
+		files[alias.Path] = alias
+		manifest[fd.Path] = alias.Path
+	}
+
+	manifestPath := options.ManifestPath
+	if manifestPath == "" {
+		manifestPath = DefaultManifestPath
+	}
+	// Maps are marshalled with sorted keys, so the manifest, and its ETag,
+	// only change when an asset does
+	contents, _ := json.MarshalIndent(manifest, "", "  ")
+	fd := FileDef{MimeType: withCharset("application/json"), Path: manifestPath, CacheControl: "no-cache"}
+	fd.setETag(contents)
+	fd.setContents(contents)
+	files[manifestPath] = fd
+	return manifest
+}
+
+// AssetURL returns the hashed path of an asset, for pages rendered on the
+// server, or the path itself when it isn't in the manifest.
+func AssetURL(assetPath string) string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	if hashed, ok := manifest[assetPath]; ok {
+		return hashed
+	}
+	return assetPath
+}
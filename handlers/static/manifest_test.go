@@ -0,0 +1,52 @@
+package static
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifest(t *testing.T) {
+	defer LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	defer Configure(Options{})
+	ctx := context.Background()
+
+	Configure(Options{Manifest: true})
+	require.NoError(t, LoadDirectoryTree(testDataDir, testDataDir, "index.html"))
+
+	hashed := AssetURL("/css/test.css")
+	assert.Regexp(t, `^/css/test\.[0-9a-f]{10}\.css$`, hashed)
+	assert.Equal(t, "/index.html", AssetURL("/index.html"), "pages aren't hashed")
+	assert.Equal(t, "/missing.js", AssetURL("/missing.js"))
+
+	r, err := HandleStaticALB(ctx, events.ALBTargetGroupRequest{Path: hashed, HTTPMethod: http.MethodGet})
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, staticURLs["/css/test.css"].Contents, r.Body)
+	assert.Equal(t, DefaultCacheControl, r.Headers["Cache-Control"])
+
+	r, err = HandleStaticALB(ctx, events.ALBTargetGroupRequest{Path: DefaultManifestPath, HTTPMethod: http.MethodGet})
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, "application/json; charset=utf-8", r.Headers["Content-Type"])
+	assert.Equal(t, "no-cache", r.Headers["Cache-Control"])
+	var m map[string]string
+	require.NoError(t, json.Unmarshal([]byte(r.Body), &m))
+	assert.Equal(t, hashed, m["/css/test.css"])
+	assert.NotContains(t, m, "/index.html")
+
+	Configure(Options{Manifest: true, ManifestPath: "/manifest.json"})
+	require.NoError(t, LoadDirectoryTree(testDataDir, testDataDir, "index.html"))
+	assert.Contains(t, staticURLs, "/manifest.json")
+	assert.Equal(t, hashed, AssetURL("/css/test.css"), "hashes are stable")
+
+	Configure(Options{})
+	require.NoError(t, LoadDirectoryTree(testDataDir, testDataDir, "index.html"))
+	assert.NotContains(t, staticURLs, hashed)
+	assert.Equal(t, "/css/test.css", AssetURL("/css/test.css"))
+}
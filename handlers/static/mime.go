@@ -0,0 +1,85 @@
+package static
+
+import (
+	"mime"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultCharset is added to text types without one when Options.Charset
+// isn't set.
+const DefaultCharset = "utf-8"
+
+// builtinTypes are used ahead of mime.TypeByExtension, whose answers depend
+// on the mime.types files in the runtime image, and which doesn't know some
+// of the newer web types at all.
+var builtinTypes = map[string]string{
+	".css":         "text/css",
+	".csv":         "text/csv",
+	".gif":         "image/gif",
+	".htm":         "text/html",
+	".html":        "text/html",
+	".ico":         "image/x-icon",
+	".jpeg":        "image/jpeg",
+	".jpg":         "image/jpeg",
+	".js":          "text/javascript",
+	".json":        "application/json",
+	".map":         "application/json",
+	".mjs":         "text/javascript",
+	".otf":         "font/otf",
+	".pdf":         "application/pdf",
+	".png":         "image/png",
+	".svg":         "image/svg+xml",
+	".ttf":         "font/ttf",
+	".txt":         "text/plain",
+	".wasm":        "application/wasm",
+	".webmanifest": "application/manifest+json",
+	".webp":        "image/webp",
+	".woff":        "font/woff",
+	".woff2":       "font/woff2",
+	".xml":         "application/xml",
+}
+
+// mimeType is the Content-Type for a file, going by Options.MimeTypes, then
+// the built in types, then the runtime's.
+func mimeType(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	mimeType, ok := options.MimeTypes[ext]
+	if !ok {
+		mimeType, ok = builtinTypes[ext]
+	}
+	if !ok {
+		mimeType = mime.TypeByExtension(ext)
+	}
+	return withCharset(mimeType)
+}
+
+// withCharset adds the charset to text types without one, or browsers guess,
+// and often mangle accented names.
+func withCharset(mimeType string) string {
+	if !textual(mimeType) || strings.Contains(strings.ToLower(mimeType), "charset=") {
+		return mimeType
+	}
+	charset := options.Charset
+	if charset == "" {
+		charset = DefaultCharset
+	}
+	return mimeType + "; charset=" + charset
+}
+
+// textual reports whether files of the MIME type are text, which are stored
+// and sent as they are rather than base64 encoded.
+func textual(mimeType string) bool {
+	mimeType = strings.ToLower(strings.TrimSpace(strings.SplitN(mimeType, ";", 2)[0]))
+	switch {
+	case strings.HasPrefix(mimeType, "text/"),
+		strings.HasSuffix(mimeType, "+json"),
+		strings.HasSuffix(mimeType, "+xml"):
+		return true
+	}
+	switch mimeType {
+	case "application/javascript", "application/json", "application/xml":
+		return true
+	}
+	return false
+}
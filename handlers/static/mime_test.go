@@ -0,0 +1,34 @@
+package static
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMimeType(t *testing.T) {
+	defer Configure(Options{})
+
+	assert.Equal(t, "text/html; charset=utf-8", mimeType("/index.html"))
+	assert.Equal(t, "text/javascript; charset=utf-8", mimeType("/js/app.mjs"))
+	assert.Equal(t, "application/json; charset=utf-8", mimeType("/js/app.js.map"))
+	assert.Equal(t, "application/manifest+json; charset=utf-8", mimeType("/site.webmanifest"))
+	assert.Equal(t, "font/woff2", mimeType("/fonts/inter.woff2"))
+	assert.Equal(t, "image/jpeg", mimeType("/img/PHOTO.JPG"))
+
+	Configure(Options{
+		MimeTypes: map[string]string{".ics": "text/calendar", ".js": "application/javascript; charset=iso-8859-1"},
+		Charset:   "utf-16",
+	})
+	assert.Equal(t, "text/calendar; charset=utf-16", mimeType("/visits.ics"))
+	assert.Equal(t, "application/javascript; charset=iso-8859-1", mimeType("/js/app.js"))
+}
+
+func TestTextual(t *testing.T) {
+	for _, mimeType := range []string{"text/css", "application/json; charset=utf-8", "image/svg+xml", "application/ld+json"} {
+		assert.True(t, textual(mimeType), mimeType)
+	}
+	for _, mimeType := range []string{"image/png", "font/woff2", "application/wasm", ""} {
+		assert.False(t, textual(mimeType), mimeType)
+	}
+}
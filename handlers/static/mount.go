@@ -0,0 +1,149 @@
+package static
+
+import (
+	"errors"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MountOptions are the settings for a tree mounted at a URL prefix, which are
+// independent of the root tree's.
+type MountOptions struct {
+	// Index is the file served for the tree's directories.
+	Index string
+	// CachePolicies, CacheControl and HTMLCacheControl work like Options',
+	// with patterns matched against paths within the tree, so `/**` is
+	// everything in it.  When none are set, Options' apply.
+	CachePolicies    []CachePolicy
+	CacheControl     string
+	HTMLCacheControl string
+	// SPA serves the tree's index page for unknown paths under the prefix
+	// without a file extension.
+	SPA bool
+}
+
+type mountedTree struct {
+	prefix string
+	opts   MountOptions
+	files  map[string]FileDef
+}
+
+var (
+	// rootFiles are the files loaded by LoadDirectoryTree or LoadS3Bucket,
+	// which staticURLs has along with the mounted ones.
+	rootFiles map[string]FileDef
+	mounts    = map[string]mountedTree{}
+)
+
+// MountDirectoryTree serves a directory tree under a URL prefix, like /docs,
+// alongside the root tree and any other mounts, so several bundles can be
+// served by one handler.  Mounting at a prefix again replaces that tree, and
+// its files take the place of any root files under the prefix.  Mounted
+// trees are always held in memory, even in lazy mode.
+func MountDirectoryTree(urlPrefix, basePath string, opts MountOptions) error {
+	prefix := path.Clean("/" + urlPrefix)
+	if prefix == "/" {
+		return errors.New("static: mount prefix can't be the root, use LoadDirectoryTree")
+	}
+
+	loadLock.Lock()
+	defer loadLock.Unlock()
+	root := pathPrefix
+	pathPrefix = basePath
+	files := map[string]FileDef{}
+	err := filepath.Walk(basePath, walkDirectory(files, basePath, opts.Index, false))
+	pathPrefix = root
+	if err != nil {
+		return err
+	}
+	attachPrecompressed(files)
+
+	policies := Options{
+		CachePolicies:    opts.CachePolicies,
+		CacheControl:     opts.CacheControl,
+		HTMLCacheControl: opts.HTMLCacheControl,
+	}
+	ownPolicies := len(opts.CachePolicies) > 0 || opts.CacheControl != "" || opts.HTMLCacheControl != ""
+	mounted := make(map[string]FileDef, len(files))
+	for p, fd := range files {
+		if ownPolicies && fd.CacheControl == "" {
+			fd.CacheControl = policies.cacheControl(fd)
+		}
+		fd.FilePath = prefix + fd.FilePath
+		fd.Path = strings.TrimSuffix(prefix+p, "/")
+		if strings.HasSuffix(p, "/") {
+			fd.Path += "/"
+		}
+		mounted[fd.Path] = fd
+	}
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	mounts[prefix] = mountedTree{prefix: prefix, opts: opts, files: mounted}
+	staticURLs = withMounts(rootFiles)
+	foldedURLs = foldPaths(staticURLs)
+	return nil
+}
+
+// Unmount stops serving the tree mounted at a URL prefix.
+func Unmount(urlPrefix string) {
+	loadLock.Lock()
+	defer loadLock.Unlock()
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	delete(mounts, path.Clean("/"+urlPrefix))
+	staticURLs = withMounts(rootFiles)
+	foldedURLs = foldPaths(staticURLs)
+}
+
+// withMounts merges the mounted trees into the root files.  Mounts are merged
+// shortest prefix first, so nested mounts win.
+func withMounts(files map[string]FileDef) map[string]FileDef {
+	if len(mounts) == 0 {
+		return files
+	}
+	merged := make(map[string]FileDef, len(files))
+	for p, fd := range files {
+		merged[p] = fd
+	}
+	for _, m := range sortedMounts() {
+		for p := range merged {
+			if p == m.prefix || strings.HasPrefix(p, m.prefix+"/") {
+				delete(merged, p)
+			}
+		}
+		for p, fd := range m.files {
+			merged[p] = fd
+		}
+	}
+	return merged
+}
+
+func sortedMounts() []mountedTree {
+	sorted := make([]mountedTree, 0, len(mounts))
+	for _, m := range mounts {
+		sorted = append(sorted, m)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i].prefix) < len(sorted[j].prefix) })
+	return sorted
+}
+
+// mountSPARoute returns the index page for an unknown path under a mount that
+// serves a single-page app.  The longest matching prefix wins.
+func mountSPARoute(urlPath string) (string, bool) {
+	var found *mountedTree
+	for _, m := range mounts {
+		if !m.opts.SPA || !strings.HasPrefix(urlPath, m.prefix+"/") || path.Ext(urlPath) != "" {
+			continue
+		}
+		if found == nil || len(m.prefix) > len(found.prefix) {
+			found = &m
+		}
+	}
+	if found == nil {
+		return "", false
+	}
+	return found.prefix + "/" + found.opts.Index, true
+}
@@ -0,0 +1,64 @@
+package static
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMountDirectoryTree(t *testing.T) {
+	docs := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(docs, "home.html"), []byte("<html>docs</html>"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(docs, "guides"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(docs, "guides", "home.html"), []byte("<html>guides</html>"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(docs, "docs.css"), []byte("body{}"), 0o644))
+	app := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(app, "index.html"), []byte("<html>app</html>"), 0o644))
+
+	LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	defer LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	defer Unmount("/app")
+	defer Unmount("/docs")
+	ctx := context.Background()
+
+	require.NoError(t, MountDirectoryTree("/docs/", docs, MountOptions{
+		Index:         "home.html",
+		CachePolicies: []CachePolicy{{Pattern: "*.css", CacheControl: "public, max-age=60"}},
+	}))
+	require.NoError(t, MountDirectoryTree("app", app, MountOptions{Index: "index.html", SPA: true}))
+	assert.Error(t, MountDirectoryTree("/", app, MountOptions{}))
+
+	get := func(path string) *events.ALBTargetGroupResponse {
+		r, err := HandleStaticALB(ctx, events.ALBTargetGroupRequest{Path: path, HTTPMethod: http.MethodGet})
+		require.NoError(t, err)
+		return r
+	}
+	body := func(path string) string {
+		r := get(path)
+		require.NotNil(t, r, path)
+		return r.Body
+	}
+
+	assert.Equal(t, "<html>docs</html>", body("/docs"))
+	assert.Equal(t, "<html>docs</html>", body("/docs/"))
+	assert.Equal(t, "<html>guides</html>", body("/docs/guides/"))
+	assert.Equal(t, "public, max-age=60", get("/docs/docs.css").Headers["Cache-Control"])
+	assert.Equal(t, "<html>app</html>", body("/app/care-team/123"))
+	assert.Nil(t, get("/docs/care-team/123"), "only SPA mounts fall back to their index")
+
+	// The root tree is still served, and survives being reloaded
+	assert.Equal(t, DefaultCacheControl, get("/css/test.css").Headers["Cache-Control"])
+	require.NoError(t, LoadDirectoryTree(testDataDir, testDataDir, "index.html"))
+	assert.Equal(t, "<html>docs</html>", body("/docs/"))
+	assert.NotNil(t, get("/css/test.css"))
+
+	Unmount("/docs")
+	assert.Nil(t, get("/docs/"))
+	assert.NotContains(t, staticURLs, "/docs/docs.css")
+}
@@ -0,0 +1,75 @@
+package static
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// NotFoundMode is what the handlers do with requests for unknown paths.
+type NotFoundMode int
+
+const (
+	// NotFoundPassThrough returns a nil response, so the request can be
+	// handed to another handler.
+	NotFoundPassThrough NotFoundMode = iota
+	// NotFoundPage answers with Options.NotFoundPage, or a plain text 404
+	// when it isn't in the tree.
+	NotFoundPage
+	// NotFoundJSON answers with a JSON error body.
+	NotFoundJSON
+)
+
+// DefaultNotFoundPage is served by NotFoundPage when Options.NotFoundPage
+// isn't set.
+const DefaultNotFoundPage = "/404.html"
+
+type notFoundBody struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// notFoundResponse answers a GET or HEAD request for a path that isn't
+// registered, or returns nil to let it fall through.
+func notFoundResponse(ctx context.Context, req request) *response {
+	var resp *response
+	switch options.NotFound {
+	case NotFoundPage:
+		page := options.NotFoundPage
+		if page == "" {
+			page = DefaultNotFoundPage
+		}
+		fd, ok, err := lookupFile(ctx, page)
+		if err != nil {
+			return errorResponse(http.StatusBadGateway)
+		}
+		if !ok {
+			resp = errorResponse(http.StatusNotFound)
+			break
+		}
+		resp = fileResponse(fd)
+		resp.statusCode = http.StatusNotFound
+		// The page is the same for every missing path, so it can't be
+		// validated or cached like the file itself
+		delete(resp.headers, "ETag")
+		delete(resp.headers, "Last-Modified")
+		resp.headers["Cache-Control"] = "no-store"
+	case NotFoundJSON:
+		body, _ := json.Marshal(notFoundBody{Error: "not_found", Message: "No such file: " + req.path})
+		resp = &response{
+			statusCode: http.StatusNotFound,
+			body:       string(body),
+			headers: map[string]string{
+				"Content-Type":  "application/json",
+				"Cache-Control": "no-store",
+			},
+		}
+	default:
+		return nil
+	}
+	if req.method == http.MethodHead {
+		return resp.head()
+	}
+	resp.setContentLength()
+	return resp
+}
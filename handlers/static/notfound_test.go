@@ -0,0 +1,72 @@
+package static
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotFound(t *testing.T) {
+	dir := t.TempDir()
+	page := "<html><body>We couldn't find that page.</body></html>"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html></html>"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "404.html"), []byte(page), 0o644))
+	require.NoError(t, LoadDirectoryTree(dir, dir, "index.html"))
+	defer LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	defer Configure(Options{})
+	ctx := context.Background()
+
+	get := func(method, path string) *events.ALBTargetGroupResponse {
+		r, err := HandleStaticALB(ctx, events.ALBTargetGroupRequest{Path: path, HTTPMethod: method})
+		require.NoError(t, err)
+		return r
+	}
+
+	assert.Nil(t, get(http.MethodGet, "/missing.css"))
+
+	Configure(Options{NotFound: NotFoundPage})
+	r := get(http.MethodGet, "/missing.css")
+	require.NotNil(t, r)
+	assert.Equal(t, http.StatusNotFound, r.StatusCode)
+	assert.Equal(t, page, r.Body)
+	assert.Equal(t, "no-store", r.Headers["Cache-Control"])
+	assert.NotContains(t, r.Headers, "ETag")
+
+	r = get(http.MethodHead, "/missing.css")
+	require.NotNil(t, r)
+	assert.Equal(t, http.StatusNotFound, r.StatusCode)
+	assert.Empty(t, r.Body)
+	assert.Equal(t, strconv.Itoa(len(page)), r.Headers["Content-Length"])
+
+	// Other methods still aren't ours
+	assert.Nil(t, get(http.MethodPost, "/missing.css"))
+
+	Configure(Options{NotFound: NotFoundPage, NotFoundPage: "/errors/404.html"})
+	r = get(http.MethodGet, "/missing.css")
+	require.NotNil(t, r)
+	assert.Equal(t, http.StatusNotFound, r.StatusCode)
+	assert.Equal(t, http.StatusText(http.StatusNotFound), r.Body)
+
+	Configure(Options{NotFound: NotFoundJSON})
+	r = get(http.MethodGet, "/missing.css")
+	require.NotNil(t, r)
+	assert.Equal(t, http.StatusNotFound, r.StatusCode)
+	assert.Equal(t, "application/json", r.Headers["Content-Type"])
+	var body map[string]string
+	require.NoError(t, json.Unmarshal([]byte(r.Body), &body))
+	assert.Equal(t, "not_found", body["error"])
+
+	// SPA routes still get the index page
+	Configure(Options{NotFound: NotFoundJSON, SPA: true})
+	r = get(http.MethodGet, "/care-team/123")
+	require.NotNil(t, r)
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+}
@@ -0,0 +1,176 @@
+package static
+
+import (
+	"path"
+	"strings"
+)
+
+// Options controls how the static handlers build their responses.  The zero
+// value uses the defaults for everything.
+type Options struct {
+	// CachePolicies set the Cache-Control header for the files they match.
+	// The first match wins.
+	CachePolicies []CachePolicy
+	// CacheControl is used for files without a policy, and defaults to
+	// DefaultCacheControl.
+	CacheControl string
+	// HTMLCacheControl is used for HTML files without a policy, including the
+	// index pages, and defaults to DefaultHTMLCacheControl.  Pages have to be
+	// revalidated, or browsers keep using old bundles after a deploy.
+	HTMLCacheControl string
+	// Compress keeps gzip and brotli compressed copies of text files, made
+	// when the tree is loaded, to send to clients that accept them.  Set it
+	// before calling LoadDirectoryTree.
+	Compress bool
+	// SPA serves the index page for GET requests to unknown paths, so a
+	// single-page app's client-side routes, like /care-team/123, load the app
+	// instead of falling through to the next handler.  Paths with a file
+	// extension, and ones matching SPAExclude, still fall through.
+	SPA bool
+	// SPAExclude are patterns for paths that aren't app routes, like
+	// `/api/**`.  They use the same syntax as CachePolicy patterns.
+	SPAExclude []string
+	// Oversize serves files too large for an ALB response from S3 instead.
+	// Without it, they're logged when they're loaded and requested.
+	Oversize *OversizeFallback
+	// SecurityHeaders override DefaultSecurityHeaders for every response.
+	// An empty value leaves the header out.
+	SecurityHeaders map[string]string
+	// SecurityPolicies override security headers for the paths they match,
+	// after SecurityHeaders.  Later matches win.
+	SecurityPolicies []SecurityPolicy
+	// CORS answers preflights and adds CORS headers to responses for the
+	// origins it allows.  Without it, no CORS headers are sent.
+	CORS *CORS
+	// NotFound is what to do with GET and HEAD requests for unknown paths.
+	// The default passes them through, which standalone static Lambdas,
+	// with no other handler, can't do.
+	NotFound NotFoundMode
+	// NotFoundPage is the path of the page NotFoundPage serves, and defaults
+	// to DefaultNotFoundPage.
+	NotFoundPage string
+	// Variables are substituted for `${NAME}` in files as the tree is
+	// loaded, so one build can be configured per environment, e.g. with the
+	// API base URL.  Values are inserted as they are, unescaped.  Set it
+	// before calling LoadDirectoryTree.
+	Variables map[string]string
+	// InterpolatePatterns choose the files Variables are substituted in, and
+	// default to HTML files.  Bundles are left alone unless they match, since
+	// JavaScript template literals use the same syntax.
+	InterpolatePatterns []string
+	// Manifest registers a cache-busting alias for every asset other than
+	// pages, with a hash of its contents in the name, and serves a JSON
+	// manifest of them at ManifestPath.  Set it before calling
+	// LoadDirectoryTree.
+	Manifest bool
+	// ManifestPath defaults to DefaultManifestPath.
+	ManifestPath string
+	// Dotfiles loads files and directories whose names start with a dot,
+	// which are skipped by default, other than .well-known.
+	Dotfiles bool
+	// Exclude are patterns for paths to leave out when loading the tree,
+	// like `*.map` or `/drafts/**`.  They use the same syntax as CachePolicy
+	// patterns.  Set it before calling LoadDirectoryTree.
+	Exclude []string
+	// MimeTypes override the Content-Type for files by extension, like
+	// `".ics": "text/calendar"`.  Set it before loading the tree.
+	MimeTypes map[string]string
+	// Charset is added to text types without one, and defaults to
+	// DefaultCharset.
+	Charset string
+	// Gate requires credentials for everything but its public paths.
+	Gate *Gate
+	// AccessLog logs every request the handlers answer with the context
+	// logger.
+	AccessLog bool
+	// Metrics is called with every request the handlers answer.
+	Metrics MetricsHook
+	// TrailingSlash picks the canonical form of directory paths, and
+	// defaults to serving both.
+	TrailingSlash TrailingSlashMode
+	// CaseInsensitive redirects paths that only match a file when case is
+	// ignored, like /Nested/Index.HTML, to the file's path.  S3 website
+	// hosting is forgiving this way, and old links depend on it.
+	CaseInsensitive bool
+	// Lazy only reads files' metadata when the tree is loaded, and their
+	// contents when they're first requested, keeping the most recently used
+	// ones up to CacheSize bytes.  It's for bundles too large to hold in the
+	// Lambda's memory.  Precompressed siblings are served as files of their
+	// own in lazy mode.  Set it before calling LoadDirectoryTree.
+	Lazy bool
+	// CacheSize defaults to DefaultCacheSize.
+	CacheSize int64
+}
+
+const (
+	DefaultCacheControl     = "public, max-age=604800, immutable"
+	DefaultHTMLCacheControl = "no-cache"
+)
+
+// CachePolicy sets the Cache-Control header for the paths matching Pattern.
+// A pattern ending in `/**` matches everything under it, one with any other
+// `/` is matched against the whole path, and one without is matched against
+// the file name, so `*.json` matches JSON files anywhere.  Patterns otherwise
+// use path.Match syntax.
+type CachePolicy struct {
+	Pattern      string
+	CacheControl string
+}
+
+func (p CachePolicy) matches(urlPath string) bool {
+	return matchPattern(p.Pattern, urlPath)
+}
+
+func matchPattern(pattern, urlPath string) bool {
+	if prefix := strings.TrimSuffix(pattern, "**"); prefix != pattern && strings.HasSuffix(prefix, "/") {
+		return strings.HasPrefix(urlPath, prefix)
+	}
+	if strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, urlPath)
+		return ok
+	}
+	ok, _ := path.Match(pattern, path.Base(urlPath))
+	return ok
+}
+
+var options Options
+
+// Configure sets the options for all the static handlers.  Call it at start
+// up, before serving any requests.
+func Configure(opts Options) {
+	options = opts
+}
+
+func (o Options) cacheControl(fd FileDef) string {
+	if fd.CacheControl != "" {
+		return fd.CacheControl
+	}
+	for _, policy := range o.CachePolicies {
+		if policy.matches(fd.Path) {
+			return policy.CacheControl
+		}
+	}
+	if strings.HasPrefix(fd.MimeType, "text/html") {
+		if o.HTMLCacheControl != "" {
+			return o.HTMLCacheControl
+		}
+		return DefaultHTMLCacheControl
+	}
+	if o.CacheControl != "" {
+		return o.CacheControl
+	}
+	return DefaultCacheControl
+}
+
+// spaRoute reports whether an unknown path should get the index page.
+func (o Options) spaRoute(urlPath string) bool {
+	if !o.SPA || path.Ext(urlPath) != "" {
+		return false
+	}
+	for _, pattern := range o.SPAExclude {
+		if matchPattern(pattern, urlPath) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package static
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachePolicyMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		matches bool
+	}{
+		{"*.css", "/css/test.css", true},
+		{"*.css", "/css/test.js", false},
+		{"/css/*", "/css/test.css", true},
+		{"/css/*", "/css/deep/test.css", false},
+		{"/css/**", "/css/deep/test.css", true},
+		{"/css/**", "/js/test.js", false},
+		{"/index.html", "/index.html", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.matches, CachePolicy{Pattern: tt.pattern}.matches(tt.path), "%s %s", tt.pattern, tt.path)
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	defer Configure(Options{})
+	ctx := context.Background()
+
+	cacheControl := func(path string) string {
+		r, err := HandleStaticALB(ctx, events.ALBTargetGroupRequest{Path: path, HTTPMethod: http.MethodGet})
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		return r.Headers["Cache-Control"]
+	}
+
+	assert.Equal(t, DefaultCacheControl, cacheControl("/css/test.css"))
+	assert.Equal(t, DefaultHTMLCacheControl, cacheControl("/index.html"))
+	assert.Equal(t, DefaultHTMLCacheControl, cacheControl("/"))
+
+	Configure(Options{
+		CachePolicies: []CachePolicy{
+			{Pattern: "/nested/**", CacheControl: "private, max-age=60"},
+			{Pattern: "*.css", CacheControl: "public, max-age=3600"},
+		},
+		CacheControl:     "public, max-age=86400",
+		HTMLCacheControl: "no-store",
+	})
+	assert.Equal(t, "public, max-age=3600", cacheControl("/css/test.css"))
+	assert.Equal(t, "public, max-age=86400", cacheControl("/img/theodolite.jpg"))
+	assert.Equal(t, "no-store", cacheControl("/"))
+	assert.Equal(t, "private, max-age=60", cacheControl("/nested/index.html"))
+}
+
+func TestSPA(t *testing.T) {
+	LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	defer Configure(Options{})
+	ctx := context.Background()
+
+	get := func(path string) *events.ALBTargetGroupResponse {
+		r, err := HandleStaticALB(ctx, events.ALBTargetGroupRequest{Path: path, HTTPMethod: http.MethodGet})
+		require.NoError(t, err)
+		return r
+	}
+
+	assert.Nil(t, get("/care-team/123"), "only in SPA mode")
+
+	Configure(Options{SPA: true, SPAExclude: []string{"/api/**"}})
+	r := get("/care-team/123")
+	require.NotNil(t, r)
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+	assert.Equal(t, staticURLs["/index.html"].Contents, r.Body)
+
+	// Real files are still served
+	r = get("/nested/")
+	require.NotNil(t, r)
+	assert.Equal(t, staticURLs["/nested/index.html"].Contents, r.Body)
+
+	assert.Nil(t, get("/api/profiles"))
+	assert.Nil(t, get("/missing.js"))
+
+	r, err := HandleStaticALB(ctx, events.ALBTargetGroupRequest{Path: "/care-team/123", HTTPMethod: http.MethodPost})
+	assert.NoError(t, err)
+	assert.Nil(t, r)
+}
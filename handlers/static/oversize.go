@@ -0,0 +1,114 @@
+package static
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+// ALBResponseLimit is the most an ALB accepts from a Lambda target, counting
+// the body after base64 encoding, which makes binary files a third larger.
+const ALBResponseLimit = 1 << 20
+
+// DefaultPresignExpires is how long presigned URLs for oversize files last
+// when OversizeFallback doesn't say.
+const DefaultPresignExpires = 15 * time.Minute
+
+// PresignAPI is the part of the S3 presign client used to redirect to
+// oversize files.  *s3.PresignClient implements it.
+type PresignAPI interface {
+	PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+}
+
+var _ PresignAPI = (*s3.PresignClient)(nil)
+
+// OversizeFallback redirects requests for files too large for an ALB response
+// to a presigned S3 URL, for a copy of the tree uploaded to the bucket.
+type OversizeFallback struct {
+	Presigner PresignAPI
+	// Bucket and Prefix say where the copy is, so /img/map.png is at
+	// Prefix + "img/map.png".  Files served by LoadS3Bucket use their own key
+	// when Bucket is empty.
+	Bucket string
+	Prefix string
+	// Expires defaults to DefaultPresignExpires.
+	Expires time.Duration
+}
+
+// warnOversize logs files too large to be sent through an ALB as they're
+// loaded, rather than leaving requests for them to fail.
+func warnOversize(fd FileDef) {
+	if len(fd.Contents) <= ALBResponseLimit {
+		return
+	}
+	fitsEncoded := false
+	for _, encoded := range fd.Encodings {
+		if len(encoded) <= ALBResponseLimit {
+			fitsEncoded = true
+		}
+	}
+	velacontext.FallbackLogger().Warn("Static file is too large for an ALB response",
+		zap.String("path", fd.Path),
+		zap.Int("size", len(fd.Contents)),
+		zap.Bool("fits_compressed", fitsEncoded),
+		zap.Bool("s3_fallback", options.Oversize != nil),
+	)
+}
+
+// oversizeResponse replaces a response that's too large to send with a
+// redirect to S3 when there's a fallback, and otherwise logs it.
+func oversizeResponse(ctx context.Context, fd FileDef, cache *fileCache, resp *response, limit int) *response {
+	if limit <= 0 || len(resp.body) <= limit {
+		return resp
+	}
+	fallback := options.Oversize
+	if fallback == nil {
+		velacontext.GetContextLogger(ctx).Error("Static response is too large and there's no S3 fallback",
+			zap.String("path", fd.Path),
+			zap.Int("size", len(resp.body)),
+		)
+		return resp
+	}
+
+	// Index pages are registered at their directory's paths too, so the key
+	// comes from the file's own path
+	filePath := fd.FilePath
+	if filePath == "" {
+		filePath = fd.Path
+	}
+	bucket, key := fallback.Bucket, fallback.Prefix+strings.TrimPrefix(filePath, "/")
+	if bucket == "" && fd.S3Key != "" && cache != nil {
+		bucket, key = cache.bucket, fd.S3Key
+	}
+	expires := fallback.Expires
+	if expires <= 0 {
+		expires = DefaultPresignExpires
+	}
+	presigned, err := fallback.Presigner.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		velacontext.GetContextLogger(ctx).Error("Can't presign oversize static file",
+			zap.String("path", fd.Path),
+			zap.Error(err),
+		)
+		return errorResponse(http.StatusBadGateway)
+	}
+	return &response{
+		statusCode: http.StatusFound,
+		headers: map[string]string{
+			"Location": presigned.URL,
+			// The URL expires, so the redirect can't be cached for long
+			"Cache-Control": "no-store",
+		},
+	}
+}
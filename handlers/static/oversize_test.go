@@ -0,0 +1,105 @@
+package static
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+type mockPresigner struct {
+	inputs []*s3.GetObjectInput
+	err    error
+}
+
+func (m *mockPresigner) PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error) {
+	m.inputs = append(m.inputs, params)
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &v4.PresignedHTTPRequest{URL: "https://assets.s3.amazonaws.com/" + aws.ToString(params.Key) + "?X-Amz-Signature=abc"}, nil
+}
+
+func TestOversize(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	velacontext.SetFallbackLogger(zap.New(core))
+	defer velacontext.SetFallbackLogger(nil)
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "img"), 0o755))
+	// 800KB of binary is over the limit once it's base64 encoded
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "img", "map.png"), make([]byte, 800<<10), 0o644))
+	require.NoError(t, LoadDirectoryTree(dir, dir, "index.html"))
+	defer LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	assert.Equal(t, 1, logs.FilterMessage("Static file is too large for an ALB response").Len())
+
+	get := func(handle func(context.Context, events.ALBTargetGroupRequest) (*events.ALBTargetGroupResponse, error)) *events.ALBTargetGroupResponse {
+		r, err := handle(context.Background(), events.ALBTargetGroupRequest{Path: "/img/map.png", HTTPMethod: http.MethodGet})
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		return r
+	}
+
+	// Without a fallback it's sent anyway, and logged
+	r := get(HandleStaticALB)
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+	assert.Equal(t, 1, logs.FilterMessage("Static response is too large and there's no S3 fallback").Len())
+
+	presigner := &mockPresigner{}
+	Configure(Options{Oversize: &OversizeFallback{Presigner: presigner, Bucket: "assets", Prefix: "web/"}})
+	defer Configure(Options{})
+
+	r = get(HandleStaticALB)
+	assert.Equal(t, http.StatusFound, r.StatusCode)
+	assert.Equal(t, "https://assets.s3.amazonaws.com/web/img/map.png?X-Amz-Signature=abc", r.Headers["Location"])
+	assert.Empty(t, r.Body)
+	require.Len(t, presigner.inputs, 1)
+	assert.Equal(t, "assets", aws.ToString(presigner.inputs[0].Bucket))
+
+	// Other front doors don't have the ALB's limit
+	r2, err := HandleStaticAPIGW(context.Background(), events.APIGatewayProxyRequest{Path: "/img/map.png", HTTPMethod: http.MethodGet})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, r2.StatusCode)
+
+	presigner.err = errors.New("no credentials")
+	r = get(HandleStaticALB)
+	assert.Equal(t, http.StatusBadGateway, r.StatusCode)
+}
+
+func TestOversizeIndex(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "nested"), 0o755))
+	page := []byte("<html>" + strings.Repeat("a", ALBResponseLimit) + "</html>")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), page, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "nested", "index.html"), page, 0o644))
+	require.NoError(t, LoadDirectoryTree(dir, dir, "index.html"))
+	defer LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+
+	presigner := &mockPresigner{}
+	Configure(Options{Oversize: &OversizeFallback{Presigner: presigner, Bucket: "assets", Prefix: "web/"}})
+	defer Configure(Options{})
+
+	// Index pages are presigned as the page, not the directory
+	for path, key := range map[string]string{"/": "web/index.html", "/nested/": "web/nested/index.html", "/nested": "web/nested/index.html"} {
+		r, err := HandleStaticALB(context.Background(), events.ALBTargetGroupRequest{Path: path, HTTPMethod: http.MethodGet})
+		require.NoError(t, err, path)
+		require.NotNil(t, r, path)
+		assert.Equal(t, http.StatusFound, r.StatusCode, path)
+		assert.Equal(t, key, aws.ToString(presigner.inputs[len(presigner.inputs)-1].Key), path)
+	}
+}
@@ -0,0 +1,138 @@
+package static
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/seniorlink-vela/cs-common/retry"
+)
+
+// DefaultS3CacheSize is how many bytes of S3 files are kept in memory when
+// S3Options doesn't say.
+const DefaultS3CacheSize = DefaultCacheSize
+
+// S3API is the part of the S3 client used to serve static files from a
+// bucket.  *s3.Client implements it.
+type S3API interface {
+	s3.ListObjectsV2APIClient
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+var _ S3API = (*s3.Client)(nil)
+
+// S3Options controls where static files are served from in S3.
+type S3Options struct {
+	Client S3API
+	Bucket string
+	// Prefix is the part of the keys before the URL path, like "web/" for
+	// "web/css/app.css" to be served as /css/app.css.
+	Prefix string
+	// Index is the file served for directories, like "index.html".
+	Index string
+	// CacheSize bounds the bytes of file contents kept in memory, and
+	// defaults to DefaultS3CacheSize.  The least recently used files are
+	// dropped first.
+	CacheSize int64
+}
+
+// LoadS3Bucket registers the files in an S3 bucket, for asset sets too large
+// to keep in the Lambda's memory.  It lists the bucket up front, but only
+// fetches a file when it's requested, keeping the most recently used ones
+// cached.  Content-Type and Cache-Control set on an object are used in place
+// of the ones worked out from its name.  Like LoadDirectoryTree, it replaces
+// whatever was registered before.
+func LoadS3Bucket(ctx context.Context, opts S3Options) error {
+	files := map[string]FileDef{}
+	paginator := s3.NewListObjectsV2Paginator(opts.Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(opts.Bucket),
+		Prefix: aws.String(opts.Prefix),
+	})
+	for paginator.HasMorePages() {
+		var page *s3.ListObjectsV2Output
+		err := retry.Do(ctx, s3RetryPolicy, func(ctx context.Context) error {
+			var err error
+			page, err = paginator.NextPage(ctx, singleAttempt)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("static: listing s3://%s/%s: %w", opts.Bucket, opts.Prefix, err)
+		}
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+			if strings.HasSuffix(key, "/") {
+				continue
+			}
+			fd := FileDef{
+				MimeType: mimeType(key),
+				Path:     "/" + strings.TrimPrefix(strings.TrimPrefix(key, opts.Prefix), "/"),
+				ETag:     aws.ToString(object.ETag),
+				ModTime:  aws.ToTime(object.LastModified).UTC(),
+				S3Key:    key,
+			}
+			fd.FilePath = fd.Path
+			files[fd.Path] = fd
+			if opts.Index != "" && strings.HasSuffix(fd.Path, opts.Index) {
+				index := fd
+				index.Path = strings.TrimSuffix(fd.Path, opts.Index)
+				files[index.Path] = index
+				index2 := fd
+				index2.Path = strings.TrimSuffix(fd.Path, "/"+opts.Index)
+				files[index2.Path] = index2
+			}
+		}
+	}
+
+	size := opts.CacheSize
+	if size <= 0 {
+		size = DefaultS3CacheSize
+	}
+	cache := newFileCache(size, s3Fetcher(opts.Client, opts.Bucket))
+	cache.bucket = opts.Bucket
+	swapRegistry(files, opts.Index, nil, cache)
+	return nil
+}
+
+// Throttling and S3's server errors are retried, briefly, since a request is
+// waiting.
+var s3RetryPolicy = retry.Policy{MaxAttempts: 3, InitialDelay: 50 * time.Millisecond, Retryable: retry.AWSRetryable}
+
+// The SDK's own retries are turned off where s3RetryPolicy retries, so the
+// attempts don't multiply.
+func singleAttempt(o *s3.Options) {
+	o.RetryMaxAttempts = 1
+}
+
+// s3Fetcher fetches files' contents from the bucket.
+func s3Fetcher(client S3API, bucket string) func(context.Context, FileDef) (FileDef, error) {
+	return func(ctx context.Context, fd FileDef) (FileDef, error) {
+		var out *s3.GetObjectOutput
+		err := retry.Do(ctx, s3RetryPolicy, func(ctx context.Context) error {
+			var err error
+			out, err = client.GetObject(ctx, &s3.GetObjectInput{
+				Bucket: aws.String(bucket),
+				Key:    aws.String(fd.S3Key),
+			}, singleAttempt)
+			return err
+		})
+		if err != nil {
+			return fd, err
+		}
+		defer out.Body.Close()
+		contents, err := io.ReadAll(out.Body)
+		if err != nil {
+			return fd, err
+		}
+		if contentType := aws.ToString(out.ContentType); contentType != "" && contentType != "binary/octet-stream" {
+			fd.MimeType = withCharset(contentType)
+		}
+		fd.CacheControl = aws.ToString(out.CacheControl)
+		fd.setContents(contents)
+		return fd, nil
+	}
+}
@@ -0,0 +1,216 @@
+package static
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type s3Object struct {
+	body         string
+	contentType  string
+	cacheControl string
+}
+
+type mockS3 struct {
+	objects map[string]s3Object
+	gets    int
+	fail    bool
+	// GetObject is throttled this many times first
+	throttle int
+	// The options GetObject was last called with
+	options s3.Options
+}
+
+func (m *mockS3) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	out := &s3.ListObjectsV2Output{}
+	for key, object := range m.objects {
+		if !strings.HasPrefix(key, aws.ToString(params.Prefix)) {
+			continue
+		}
+		out.Contents = append(out.Contents, types.Object{
+			Key:          aws.String(key),
+			ETag:         aws.String(`"etag-` + key + `"`),
+			LastModified: aws.Time(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)),
+			Size:         aws.Int64(int64(len(object.body))),
+		})
+	}
+	return out, nil
+}
+
+func (m *mockS3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	m.gets++
+	for _, fn := range optFns {
+		fn(&m.options)
+	}
+	if m.throttle > 0 {
+		m.throttle--
+		return nil, &smithy.GenericAPIError{Code: "SlowDown", Message: "Please reduce your request rate."}
+	}
+	if m.fail {
+		return nil, errors.New("access denied")
+	}
+	object := m.objects[aws.ToString(params.Key)]
+	out := &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader([]byte(object.body)))}
+	if object.contentType != "" {
+		out.ContentType = aws.String(object.contentType)
+	}
+	if object.cacheControl != "" {
+		out.CacheControl = aws.String(object.cacheControl)
+	}
+	return out, nil
+}
+
+func TestLoadS3Bucket(t *testing.T) {
+	client := &mockS3{objects: map[string]s3Object{
+		"web/index.html":      {body: "<html></html>"},
+		"web/css/app.css":     {body: "body{}", cacheControl: "public, max-age=60"},
+		"web/data/feed":       {body: "{}", contentType: "application/json"},
+		"other/ignored.css":   {body: "x"},
+		"web/img/":            {},
+		"web/img/picture.jpg": {body: "\xff\xd8\xff"},
+		"web/js/app.js":       {body: "app()"},
+	}}
+	require.NoError(t, LoadS3Bucket(context.Background(), S3Options{Client: client, Bucket: "assets", Prefix: "web/", Index: "index.html"}))
+	defer LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+
+	assert.Contains(t, staticURLs, "/css/app.css")
+	assert.Contains(t, staticURLs, "/")
+	assert.NotContains(t, staticURLs, "/ignored.css")
+	assert.NotContains(t, staticURLs, "/img/")
+	assert.Zero(t, client.gets, "nothing's fetched until it's requested")
+
+	get := func(path string) *events.ALBTargetGroupResponse {
+		r, err := HandleStaticALB(context.Background(), events.ALBTargetGroupRequest{Path: path, HTTPMethod: http.MethodGet})
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		return r
+	}
+
+	r := get("/css/app.css")
+	assert.Equal(t, "body{}", r.Body)
+	assert.Equal(t, "public, max-age=60", r.Headers["Cache-Control"])
+	assert.Equal(t, `"etag-web/css/app.css"`, r.Headers["ETag"])
+	assert.Equal(t, "Fri, 02 Jan 2026 03:04:05 GMT", r.Headers["Last-Modified"])
+	get("/css/app.css")
+	assert.Equal(t, 1, client.gets, "the second request is served from the cache")
+
+	r = get("/data/feed")
+	assert.Equal(t, "application/json; charset=utf-8", r.Headers["Content-Type"])
+
+	r = get("/")
+	assert.Equal(t, "<html></html>", r.Body)
+	assert.Equal(t, DefaultHTMLCacheControl, r.Headers["Cache-Control"])
+
+	r = get("/img/picture.jpg")
+	assert.True(t, r.IsBase64Encoded)
+
+	client.fail = true
+	r = get("/js/app.js")
+	assert.Equal(t, http.StatusBadGateway, r.StatusCode)
+}
+
+func TestS3CacheEvicts(t *testing.T) {
+	client := &mockS3{objects: map[string]s3Object{
+		"a.css": {body: strings.Repeat("a", 40)},
+		"b.css": {body: strings.Repeat("b", 40)},
+		"c.css": {body: strings.Repeat("c", 40)},
+		"d.css": {body: strings.Repeat("d", 200)},
+	}}
+	require.NoError(t, LoadS3Bucket(context.Background(), S3Options{Client: client, Bucket: "assets", CacheSize: 100}))
+	defer LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+
+	get := func(path string) {
+		_, ok, err := lookupFile(context.Background(), path)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	get("/a.css")
+	get("/b.css")
+	get("/a.css")
+	get("/c.css") // evicts b, the least recently used
+	assert.Equal(t, 3, client.gets)
+	assert.Equal(t, int64(80), lazyFiles.bytes)
+	get("/a.css")
+	assert.Equal(t, 3, client.gets)
+	get("/b.css")
+	assert.Equal(t, 4, client.gets)
+
+	// Too big to cache at all
+	get("/d.css")
+	get("/d.css")
+	assert.Equal(t, 6, client.gets)
+}
+
+func TestS3Retry(t *testing.T) {
+	client := &mockS3{objects: map[string]s3Object{"web/app.js": {body: "app()"}}, throttle: 2}
+	require.NoError(t, LoadS3Bucket(context.Background(), S3Options{Client: client, Bucket: "assets", Prefix: "web/"}))
+	defer LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+
+	r, err := HandleStaticALB(context.Background(), events.ALBTargetGroupRequest{Path: "/app.js", HTTPMethod: http.MethodGet})
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, "app()", r.Body)
+	assert.Equal(t, 3, client.gets, "throttled fetches are retried")
+	assert.Equal(t, 1, client.options.RetryMaxAttempts, "the SDK doesn't retry as well")
+}
+
+// blockingS3 holds GetObject until it's released.
+type blockingS3 struct {
+	*mockS3
+	started, release chan struct{}
+}
+
+func (b blockingS3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	close(b.started)
+	<-b.release
+	return b.mockS3.GetObject(ctx, params, optFns...)
+}
+
+func TestS3FetchDoesNotBlockLoads(t *testing.T) {
+	client := blockingS3{
+		mockS3:  &mockS3{objects: map[string]s3Object{"app.js": {body: "app()"}}},
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	require.NoError(t, LoadS3Bucket(context.Background(), S3Options{Client: client, Bucket: "assets"}))
+	defer LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+
+	served := make(chan *events.ALBTargetGroupResponse)
+	go func() {
+		r, _ := HandleStaticALB(context.Background(), events.ALBTargetGroupRequest{Path: "/app.js", HTTPMethod: http.MethodGet})
+		served <- r
+	}()
+	<-client.started
+
+	loaded := make(chan error)
+	go func() {
+		loaded <- LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	}()
+	select {
+	case err := <-loaded:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		close(client.release)
+		t.Fatal("the load waited for the fetch")
+	}
+
+	close(client.release)
+	r := <-served
+	require.NotNil(t, r)
+	assert.Equal(t, "app()", r.Body, "the request is served from the files it started with")
+}
@@ -0,0 +1,50 @@
+package static
+
+// DefaultSecurityHeaders are added to every static response.  They're strict,
+// since the apps we serve show PHI: nothing can frame them, scripts and styles
+// only come from our own origin, and no referrer leaks a URL with an ID in it.
+var DefaultSecurityHeaders = map[string]string{
+	"Content-Security-Policy":   "default-src 'self'; img-src 'self' data:; object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'none'",
+	"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+	"X-Content-Type-Options":    "nosniff",
+	"X-Frame-Options":           "DENY",
+	"Referrer-Policy":           "no-referrer",
+}
+
+// SecurityPolicy overrides security headers for the paths matching Pattern,
+// which uses the same syntax as CachePolicy patterns.  An empty value leaves
+// the header out.
+type SecurityPolicy struct {
+	Pattern string
+	Headers map[string]string
+}
+
+// securityHeaders are the headers for a path: the defaults, then
+// Options.SecurityHeaders, then every matching policy, in order.
+func (o Options) securityHeaders(urlPath string) map[string]string {
+	headers := make(map[string]string, len(DefaultSecurityHeaders))
+	merge := func(from map[string]string) {
+		for name, value := range from {
+			headers[name] = value
+		}
+	}
+	merge(DefaultSecurityHeaders)
+	merge(o.SecurityHeaders)
+	for _, policy := range o.SecurityPolicies {
+		if matchPattern(policy.Pattern, urlPath) {
+			merge(policy.Headers)
+		}
+	}
+	return headers
+}
+
+func (r *response) addSecurityHeaders(urlPath string) {
+	if r.headers == nil {
+		r.headers = map[string]string{}
+	}
+	for name, value := range options.securityHeaders(urlPath) {
+		if value != "" {
+			r.headers[name] = value
+		}
+	}
+}
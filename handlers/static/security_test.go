@@ -0,0 +1,52 @@
+package static
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	defer Configure(Options{})
+	ctx := context.Background()
+
+	headers := func(path string) map[string]string {
+		r, err := HandleStaticALB(ctx, events.ALBTargetGroupRequest{Path: path, HTTPMethod: http.MethodGet})
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		return r.Headers
+	}
+
+	for name, value := range DefaultSecurityHeaders {
+		assert.Equal(t, value, headers("/")[name], name)
+		assert.Equal(t, value, headers("/css/test.css")[name], name)
+	}
+
+	Configure(Options{
+		SecurityHeaders: map[string]string{
+			"Content-Security-Policy": "default-src 'self' https://api.vela.care",
+			"X-Frame-Options":         "",
+		},
+		SecurityPolicies: []SecurityPolicy{
+			{Pattern: "/nested/**", Headers: map[string]string{"X-Frame-Options": "SAMEORIGIN"}},
+			{Pattern: "*.css", Headers: map[string]string{"Referrer-Policy": "same-origin"}},
+		},
+	})
+	h := headers("/")
+	assert.Equal(t, "default-src 'self' https://api.vela.care", h["Content-Security-Policy"])
+	assert.NotContains(t, h, "X-Frame-Options")
+	assert.Equal(t, "nosniff", h["X-Content-Type-Options"])
+	assert.Equal(t, "SAMEORIGIN", headers("/nested/index.html")["X-Frame-Options"])
+	assert.Equal(t, "same-origin", headers("/css/test.css")["Referrer-Policy"])
+
+	// Errors get them too
+	Configure(Options{})
+	resp := errorResponse(http.StatusBadGateway)
+	resp.addSecurityHeaders("/missing")
+	assert.Equal(t, "nosniff", resp.headers["X-Content-Type-Options"])
+}
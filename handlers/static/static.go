@@ -2,22 +2,35 @@ package static
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
 	"io/ioutil"
-	"mime"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/context/lambdamiddleware"
 )
 
 var (
 	staticURLs map[string]FileDef
 	pathPrefix string
 	indexPage  string
+
+	// registryLock guards the registry, which loads replace while requests
+	// are served from it.  loadLock keeps loads from running at once.
+	registryLock sync.RWMutex
+	loadLock     sync.Mutex
 )
 
 type FileDef struct {
@@ -25,106 +38,431 @@ type FileDef struct {
 	Contents string
 	Path     string
 	IsBinary bool
+	// ETag is a strong validator made from a hash of the contents, quoted as
+	// the header wants it.
+	ETag    string
+	ModTime time.Time
+	// Encodings are compressed copies of the contents, base64 encoded, by
+	// their Content-Encoding.
+	Encodings map[string]string
+	// S3Key is set for files served from S3 by LoadS3Bucket, whose contents
+	// are only loaded when they're requested.
+	S3Key string
+	// CacheControl overrides the cache policies, for files whose S3 object
+	// has its own.
+	CacheControl string
+	// Source is set for files loaded in lazy mode, to the file on disk their
+	// contents are read from when they're requested.
+	Source string
+	// FilePath is the path the file itself is served at, which stays the
+	// same for the index page aliases registered from it.
+	FilePath string
 }
 
 func (fd *FileDef) LoadContents() {
 	contents, _ := ioutil.ReadFile(fd.Path)
-	fd.Path = strings.TrimPrefix(fd.Path, pathPrefix)
+	if info, err := os.Stat(fd.Path); err == nil {
+		fd.ModTime = info.ModTime().UTC().Truncate(time.Second)
+	}
+	fd.Path = urlPath(fd.Path)
+	if options.interpolates(*fd) {
+		contents = interpolate(contents, options.Variables)
+	}
+	fd.setETag(contents)
+	fd.setContents(contents)
+}
+
+// urlPath is the path a file is served at: its path under the prefix, with
+// forward slashes, and cleaned.
+func urlPath(filePath string) string {
+	return path.Clean("/" + filepath.ToSlash(strings.TrimPrefix(filePath, pathPrefix)))
+}
 
-	if strings.HasPrefix(fd.MimeType, "text") {
+func (fd *FileDef) setETag(contents []byte) {
+	sum := sha256.Sum256(contents)
+	fd.ETag = fmt.Sprintf(`"%x"`, sum[:16])
+}
+
+// setContents stores the contents, as text or base64 encoded depending on the
+// MIME type, along with any compressed copies.
+func (fd *FileDef) setContents(contents []byte) {
+	if textual(fd.MimeType) {
 		fd.Contents = fmt.Sprintf("%s", contents)
 		fd.IsBinary = false
 	} else {
 		fd.Contents = base64.StdEncoding.EncodeToString(contents)
 		fd.IsBinary = true
 	}
+	if options.Compress {
+		fd.compress(contents)
+	}
 }
 
-func walkDirectory(path string, info os.FileInfo, err error) error {
-	if err != nil {
-		return err
-	}
-	// find out if it's a dir or file, if file, register for handler
-	if !info.IsDir() {
-		fd := &FileDef{
-			MimeType: mime.TypeByExtension(filepath.Ext(path)),
-			Path:     path,
+// walkDirectory returns the walk function registering the files it finds
+// under root in files, with aliases for the index pages.  Lazy files only have
+// their metadata loaded.
+func walkDirectory(files map[string]FileDef, root, index string, lazy bool) filepath.WalkFunc {
+	return func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
 		}
-		fd.LoadContents()
-		staticURLs[fd.Path] = *fd
-		if strings.HasSuffix(fd.Path, indexPage) {
-			index := &FileDef{
-				MimeType: fd.MimeType,
-				Path:     strings.TrimSuffix(fd.Path, indexPage),
-				Contents: fd.Contents,
-				IsBinary: fd.IsBinary,
+		if skip(root, path, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
 			}
-			staticURLs[index.Path] = *index
-			index2 := &FileDef{
-				MimeType: fd.MimeType,
-				Path:     strings.TrimSuffix(fd.Path, fmt.Sprintf("/%s", indexPage)),
-				Contents: fd.Contents,
-				IsBinary: fd.IsBinary,
+			return nil
+		}
+		// find out if it's a dir or file, if file, register for handler
+		if !info.IsDir() {
+			fd := &FileDef{
+				MimeType: mimeType(path),
+				Path:     path,
+			}
+			if lazy {
+				if err := fd.loadMetadata(); err != nil {
+					return err
+				}
+			} else {
+				fd.LoadContents()
+				warnOversize(*fd)
+			}
+			fd.FilePath = fd.Path
+			files[fd.Path] = *fd
+			if strings.HasSuffix(fd.Path, index) {
+				index1 := *fd
+				index1.Path = strings.TrimSuffix(fd.Path, index)
+				files[index1.Path] = index1
+				index2 := *fd
+				index2.Path = strings.TrimSuffix(fd.Path, fmt.Sprintf("/%s", index))
+				files[index2.Path] = index2
 			}
-			staticURLs[index2.Path] = *index2
 		}
+		return nil
 	}
-	return nil
 }
 
-// Walk through the static asset tree, and register any files found for the request list.
+// Walk through the static asset tree, and register any files found for the
+// request list.  The registry is only replaced once the whole tree has loaded,
+// so requests are served from the old one until then.
 func LoadDirectoryTree(basePath, prefix, index string) error {
+	loadLock.Lock()
+	defer loadLock.Unlock()
 	pathPrefix = prefix
+	files := map[string]FileDef{}
+	if err := filepath.Walk(basePath, walkDirectory(files, basePath, index, options.Lazy)); err != nil {
+		return err
+	}
+	var cache *fileCache
+	if options.Lazy {
+		size := options.CacheSize
+		if size <= 0 {
+			size = DefaultCacheSize
+		}
+		cache = newFileCache(size, loadSource)
+	} else {
+		attachPrecompressed(files)
+	}
+	var assets map[string]string
+	if options.Manifest {
+		assets = buildManifest(files)
+	}
+	swapRegistry(files, index, assets, cache)
+	return nil
+}
+
+// swapRegistry replaces everything the handlers serve from at once.
+func swapRegistry(files map[string]FileDef, index string, assets map[string]string, cache *fileCache) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	rootFiles = files
+	staticURLs = withMounts(files)
+	foldedURLs = foldPaths(staticURLs)
 	indexPage = index
-	staticURLs = map[string]FileDef{}
-	return filepath.Walk(basePath, walkDirectory)
+	manifest = assets
+	lazyFiles = cache
 }
 
-func HandleStaticALB(ctx context.Context, req events.ALBTargetGroupRequest) (*events.ALBTargetGroupResponse, error) {
+// The parts of a request the handlers look at, whichever front door it came
+// through.
+type request struct {
+	method string
+	path   string
+	// rawQuery is the encoded query string, without the `?`.
+	rawQuery string
+	headers  map[string]string
+	// limit is the largest body the front door can send, or 0 for no limit.
+	limit int
+}
 
-	// We deliberately only accept `GET` requests for static assets
-	if req.HTTPMethod == http.MethodGet {
-		fd, ok := staticURLs[req.Path]
-
-		if ok {
-			resp := &events.ALBTargetGroupResponse{
-				StatusCode:        http.StatusOK,
-				StatusDescription: http.StatusText(http.StatusOK),
-				Body:              fd.Contents,
-				IsBase64Encoded:   fd.IsBinary,
-				Headers: map[string]string{
-					"Content-Type":  fd.MimeType,
-					"Cache-Control": "public, max-age=604800, immutable",
-				},
-			}
-			return resp, nil
+// header looks a request header up without regard to case, since ALB lower
+// cases them and the others pass them on as sent.
+func (r request) header(name string) string {
+	if v, ok := r.headers[name]; ok {
+		return v
+	}
+	for k, v := range r.headers {
+		if strings.EqualFold(k, name) {
+			return v
 		}
 	}
-	// This returns a `nil` error when the path isn't found, as this is by design meant
-	// to be called before any other path handling.  The assumption is that any path not
-	// found here is being handled by another handler
-	return nil, nil
+	return ""
 }
 
-func GetResponseByPath(ctx context.Context, path string) (*events.ALBTargetGroupResponse, error) {
+// A response, before it's converted for the front door.
+type response struct {
+	statusCode      int
+	headers         map[string]string
+	body            string
+	isBase64Encoded bool
+	// cacheHit is set when a file was served without fetching it from S3.
+	cacheHit bool
+}
+
+// serve answers a request from the registry, or returns nil when it isn't for
+// a static asset.
+func serve(ctx context.Context, req request) *response {
+	start := time.Now()
+	var resp *response
+	if req.method == http.MethodOptions && options.CORS != nil {
+		if _, ok, _ := registeredFile(req.path); ok || options.spaRoute(req.path) {
+			resp = options.CORS.preflight(req)
+		}
+	} else {
+		resp = serveFile(ctx, req)
+		if resp != nil && options.CORS != nil {
+			options.CORS.addHeaders(req, resp)
+		}
+	}
+	if resp != nil {
+		resp.addSecurityHeaders(req.path)
+		recordAccess(ctx, req, resp, time.Since(start))
+	}
+	return resp
+}
+
+func serveFile(ctx context.Context, req request) *response {
+	// We deliberately only accept `GET` and `HEAD` requests for static assets
+	if req.method != http.MethodGet && req.method != http.MethodHead {
+		return nil
+	}
+	filePath, redirect := route(req)
+	if redirect != nil {
+		return redirect
+	}
+	fd, ok, cache := registeredFile(filePath)
+	if ok || options.NotFound != NotFoundPassThrough {
+		if !options.Gate.allows(ctx, req) {
+			return options.Gate.unauthorized()
+		}
+	}
+	if !ok {
+		return notFoundResponse(ctx, req)
+	}
+	cacheHit := !cache.fetches(fd)
+	fd, err := loadFile(ctx, fd, cache)
+	if err != nil {
+		return errorResponse(http.StatusBadGateway)
+	}
+	resp := fileResponse(fd)
+	resp.cacheHit = cacheHit
+	if len(fd.Encodings) > 0 {
+		resp.headers["Vary"] = "Accept-Encoding"
+		if encoding := negotiateEncoding(req.header("Accept-Encoding"), fd.Encodings); encoding != "" {
+			resp.body = fd.Encodings[encoding]
+			resp.isBase64Encoded = true
+			resp.headers["Content-Encoding"] = encoding
+			resp.headers["ETag"] = encodedETag(fd.ETag, encoding)
+		}
+	}
+	if notModified(req, resp.headers["ETag"], fd.ModTime) {
+		resp.statusCode = http.StatusNotModified
+		resp.body = ""
+		resp.isBase64Encoded = false
+		delete(resp.headers, "Content-Type")
+	}
+	if req.method == http.MethodHead {
+		return resp.head()
+	}
+	resp.setContentLength()
+	return oversizeResponse(ctx, fd, cache, resp, req.limit)
+}
 
+// route returns the path a request is for, which is the index page for SPA
+// routes, or a redirect when the request's path isn't the canonical one.
+func route(req request) (string, *response) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	if location, ok := canonicalPath(req.path); ok {
+		return "", redirectResponse(location, req.rawQuery)
+	}
+	filePath := req.path
+	if _, ok := staticURLs[filePath]; !ok {
+		if index, ok := mountSPARoute(filePath); ok {
+			filePath = index
+		} else if options.spaRoute(filePath) {
+			filePath = "/" + indexPage
+		}
+	}
+	return filePath, nil
+}
+
+// registeredFile returns the file registered for a path, and the cache lazy
+// files are loaded through.  They're copied under the lock, so files can be
+// fetched from S3 without holding up loads.
+func registeredFile(path string) (FileDef, bool, *fileCache) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
 	fd, ok := staticURLs[path]
+	return fd, ok, lazyFiles
+}
+
+// lookupFile finds the file registered for a path, with its contents loaded
+// when they're kept in S3, or on disk in lazy mode.
+func lookupFile(ctx context.Context, path string) (FileDef, bool, error) {
+	fd, ok, cache := registeredFile(path)
+	if !ok {
+		return fd, false, nil
+	}
+	fd, err := loadFile(ctx, fd, cache)
+	return fd, err == nil, err
+}
+
+// loadFile loads a lazy file's contents through the cache.
+func loadFile(ctx context.Context, fd FileDef, cache *fileCache) (FileDef, error) {
+	if !fd.lazy() || cache == nil {
+		return fd, nil
+	}
+	loaded, err := cache.load(ctx, fd)
+	if err != nil {
+		velacontext.GetContextLogger(ctx).Error("Can't load static file",
+			zap.String("key", fd.cacheKey()),
+			zap.Error(err),
+		)
+		return fd, err
+	}
+	return loaded, nil
+}
 
-	if ok {
-		resp := &events.ALBTargetGroupResponse{
-			StatusCode:        http.StatusOK,
-			StatusDescription: http.StatusText(http.StatusOK),
-			Body:              fd.Contents,
-			IsBase64Encoded:   fd.IsBinary,
-			Headers: map[string]string{
-				"Content-Type":  fd.MimeType,
-				"Cache-Control": "public, max-age=604800, immutable",
-			},
+func fileResponse(fd FileDef) *response {
+	headers := map[string]string{
+		"Content-Type":  fd.MimeType,
+		"Cache-Control": options.cacheControl(fd),
+		"ETag":          fd.ETag,
+	}
+	if !fd.ModTime.IsZero() {
+		headers["Last-Modified"] = fd.ModTime.Format(http.TimeFormat)
+	}
+	return &response{
+		statusCode:      http.StatusOK,
+		body:            fd.Contents,
+		isBase64Encoded: fd.IsBinary,
+		headers:         headers,
+	}
+}
+
+func (r *response) setContentLength() {
+	if r.statusCode != http.StatusNotModified {
+		r.headers["Content-Length"] = strconv.Itoa(r.contentLength())
+	}
+}
+
+// head turns the response into the one for a HEAD request, which has the
+// headers the body would have, without it.
+func (r *response) head() *response {
+	r.setContentLength()
+	r.body = ""
+	r.isBase64Encoded = false
+	return r
+}
+
+// contentLength is the size of the body once it's decoded.
+func (r *response) contentLength() int {
+	if !r.isBase64Encoded {
+		return len(r.body)
+	}
+	return base64.StdEncoding.DecodedLen(len(r.body)) - strings.Count(r.body[max(len(r.body)-2, 0):], "=")
+}
+
+func errorResponse(status int) *response {
+	return &response{
+		statusCode: status,
+		body:       http.StatusText(status),
+		headers:    map[string]string{"Content-Type": "text/plain; charset=utf-8"},
+	}
+}
+
+// notModified reports whether the browser's cached copy of the file is still
+// good, going by If-None-Match, or If-Modified-Since when that's missing.
+func notModified(req request, etag string, modTime time.Time) bool {
+	if match := req.header("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
 		}
-		return resp, nil
+		return false
+	}
+	if since := req.header("If-Modified-Since"); since != "" && !modTime.IsZero() {
+		t, err := http.ParseTime(since)
+		return err == nil && !modTime.After(t)
+	}
+	return false
+}
+
+func (r *response) alb() *events.ALBTargetGroupResponse {
+	return &events.ALBTargetGroupResponse{
+		StatusCode:        r.statusCode,
+		StatusDescription: http.StatusText(r.statusCode),
+		Body:              r.body,
+		IsBase64Encoded:   r.isBase64Encoded,
+		Headers:           r.headers,
+	}
+}
+
+// Flattens an ALB request's headers, which are in MultiValueHeaders instead
+// when the target group has multi-value headers turned on.
+func albHeaders(req events.ALBTargetGroupRequest) map[string]string {
+	if len(req.MultiValueHeaders) == 0 {
+		return req.Headers
+	}
+	headers := make(map[string]string, len(req.MultiValueHeaders))
+	for k, v := range req.MultiValueHeaders {
+		headers[k] = strings.Join(v, ",")
+	}
+	return headers
+}
+
+func HandleStaticALB(ctx context.Context, req events.ALBTargetGroupRequest) (*events.ALBTargetGroupResponse, error) {
+	resp := serve(ctx, request{method: req.HTTPMethod, path: req.Path, rawQuery: lambdamiddleware.ALBRawQuery(req), headers: albHeaders(req), limit: ALBResponseLimit})
+	if resp == nil {
+		// This returns a `nil` error when the path isn't found, as this is by design meant
+		// to be called before any other path handling.  The assumption is that any path not
+		// found here is being handled by another handler
+		return nil, nil
+	}
+	albResp := resp.alb()
+	if len(req.MultiValueHeaders) > 0 {
+		// ALB ignores Headers when multi-value headers are on
+		albResp.MultiValueHeaders = make(map[string][]string, len(albResp.Headers))
+		for k, v := range albResp.Headers {
+			albResp.MultiValueHeaders[k] = []string{v}
+		}
+		albResp.Headers = nil
+	}
+	return albResp, nil
+}
+
+func GetResponseByPath(ctx context.Context, path string) (*events.ALBTargetGroupResponse, error) {
+	fd, ok, err := lookupFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// This returns a `nil` error when the path isn't found, as this is by design meant
+		// to be called before any other path handling.  The assumption is that any path not
+		// found here is being handled by another handler
+		return nil, nil
 	}
-	// This returns a `nil` error when the path isn't found, as this is by design meant
-	// to be called before any other path handling.  The assumption is that any path not
-	// found here is being handled by another handler
-	return nil, nil
+	return fileResponse(fd).alb(), nil
 }
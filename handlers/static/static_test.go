@@ -2,11 +2,12 @@ package static
 
 import (
 	"context"
-	"mime"
+	"encoding/base64"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -43,7 +44,7 @@ func TestHandleStaticALB(t *testing.T) {
 		require.NotNil(t, r)
 
 		assert.False(t, r.IsBase64Encoded)
-		assert.Equal(t, mime.TypeByExtension(".html"), r.Headers["Content-Type"])
+		assert.Equal(t, "text/html; charset=utf-8", r.Headers["Content-Type"])
 	})
 	t.Run("css/test.css is returned properly", func(t *testing.T) {
 		req := events.ALBTargetGroupRequest{
@@ -57,7 +58,7 @@ func TestHandleStaticALB(t *testing.T) {
 		require.NotNil(t, r)
 
 		assert.False(t, r.IsBase64Encoded)
-		assert.Equal(t, mime.TypeByExtension(".css"), r.Headers["Content-Type"])
+		assert.Equal(t, "text/css; charset=utf-8", r.Headers["Content-Type"])
 	})
 	t.Run("js/test.js is returned properly", func(t *testing.T) {
 		req := events.ALBTargetGroupRequest{
@@ -72,7 +73,7 @@ func TestHandleStaticALB(t *testing.T) {
 
 		t.Skip("This seems to fail on github actions, we don't know why yet")
 		assert.True(t, r.IsBase64Encoded)
-		assert.Equal(t, mime.TypeByExtension(".js"), r.Headers["Content-Type"])
+		assert.Equal(t, "text/javascript; charset=utf-8", r.Headers["Content-Type"])
 	})
 	t.Run("img/theodolite.jpg is returned properly", func(t *testing.T) {
 		req := events.ALBTargetGroupRequest{
@@ -86,7 +87,7 @@ func TestHandleStaticALB(t *testing.T) {
 		require.NotNil(t, r)
 
 		assert.True(t, r.IsBase64Encoded)
-		assert.Equal(t, mime.TypeByExtension(".jpg"), r.Headers["Content-Type"])
+		assert.Equal(t, "image/jpeg", r.Headers["Content-Type"])
 	})
 	t.Run("/ returns the same page as /index.html", func(t *testing.T) {
 		req := events.ALBTargetGroupRequest{
@@ -100,7 +101,7 @@ func TestHandleStaticALB(t *testing.T) {
 		require.NotNil(t, r)
 
 		assert.False(t, r.IsBase64Encoded)
-		assert.Equal(t, mime.TypeByExtension(".html"), r.Headers["Content-Type"])
+		assert.Equal(t, "text/html; charset=utf-8", r.Headers["Content-Type"])
 		assert.Equal(t, staticURLs["/index.html"].Contents, r.Body)
 	})
 	t.Run(`"" returns the same page as /index.html"`, func(t *testing.T) {
@@ -115,7 +116,7 @@ func TestHandleStaticALB(t *testing.T) {
 		require.NotNil(t, r)
 
 		assert.False(t, r.IsBase64Encoded)
-		assert.Equal(t, mime.TypeByExtension(".html"), r.Headers["Content-Type"])
+		assert.Equal(t, "text/html; charset=utf-8", r.Headers["Content-Type"])
 		assert.Equal(t, staticURLs["/index.html"].Contents, r.Body)
 	})
 	t.Run("index is respected even on nested directory", func(t *testing.T) {
@@ -130,7 +131,7 @@ func TestHandleStaticALB(t *testing.T) {
 		require.NotNil(t, r)
 
 		assert.False(t, r.IsBase64Encoded)
-		assert.Equal(t, mime.TypeByExtension(".html"), r.Headers["Content-Type"])
+		assert.Equal(t, "text/html; charset=utf-8", r.Headers["Content-Type"])
 		assert.Equal(t, staticURLs["/nested/index.html"].Contents, r.Body)
 
 		req = events.ALBTargetGroupRequest{
@@ -143,7 +144,90 @@ func TestHandleStaticALB(t *testing.T) {
 		require.NotNil(t, r)
 
 		assert.False(t, r.IsBase64Encoded)
-		assert.Equal(t, mime.TypeByExtension(".html"), r.Headers["Content-Type"])
+		assert.Equal(t, "text/html; charset=utf-8", r.Headers["Content-Type"])
 		assert.Equal(t, staticURLs["/nested/index.html"].Contents, r.Body)
 	})
 }
+
+func TestHandleStaticALBMultiValueHeaders(t *testing.T) {
+	LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+
+	r, err := HandleStaticALB(context.Background(), events.ALBTargetGroupRequest{
+		Path:              "/css/test.css",
+		HTTPMethod:        http.MethodGet,
+		MultiValueHeaders: map[string][]string{"accept": {"text/css"}},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Nil(t, r.Headers)
+	assert.Equal(t, []string{"text/css; charset=utf-8"}, r.MultiValueHeaders["Content-Type"])
+}
+
+func TestHandleStaticALBConditional(t *testing.T) {
+	LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	ctx := context.Background()
+
+	r, err := HandleStaticALB(ctx, events.ALBTargetGroupRequest{Path: "/css/test.css", HTTPMethod: http.MethodGet})
+	require.NoError(t, err)
+	etag, lastModified := r.Headers["ETag"], r.Headers["Last-Modified"]
+	require.NotEmpty(t, etag)
+	require.NotEmpty(t, lastModified)
+	assert.Equal(t, staticURLs["/css/test.css"].ETag, etag)
+
+	conditional := func(headers map[string]string) *events.ALBTargetGroupResponse {
+		r, err := HandleStaticALB(ctx, events.ALBTargetGroupRequest{Path: "/css/test.css", HTTPMethod: http.MethodGet, Headers: headers})
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		return r
+	}
+
+	r = conditional(map[string]string{"if-none-match": `"other", ` + etag})
+	assert.Equal(t, http.StatusNotModified, r.StatusCode)
+	assert.Empty(t, r.Body)
+	assert.Equal(t, etag, r.Headers["ETag"])
+
+	r = conditional(map[string]string{"if-none-match": "W/" + etag})
+	assert.Equal(t, http.StatusNotModified, r.StatusCode)
+
+	r = conditional(map[string]string{"if-none-match": `"other"`, "if-modified-since": lastModified})
+	assert.Equal(t, http.StatusOK, r.StatusCode, "If-None-Match wins over If-Modified-Since")
+
+	r = conditional(map[string]string{"if-modified-since": lastModified})
+	assert.Equal(t, http.StatusNotModified, r.StatusCode)
+
+	r = conditional(map[string]string{"if-modified-since": "Mon, 01 Jan 2001 00:00:00 GMT"})
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+	assert.NotEmpty(t, r.Body)
+
+	// Index aliases share the file's validators
+	assert.Equal(t, staticURLs["/index.html"].ETag, staticURLs["/"].ETag)
+}
+
+func TestHandleStaticALBHead(t *testing.T) {
+	LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	ctx := context.Background()
+
+	for _, path := range []string{"/css/test.css", "/img/theodolite.jpg"} {
+		get, err := HandleStaticALB(ctx, events.ALBTargetGroupRequest{Path: path, HTTPMethod: http.MethodGet})
+		require.NoError(t, err)
+		head, err := HandleStaticALB(ctx, events.ALBTargetGroupRequest{Path: path, HTTPMethod: http.MethodHead})
+		require.NoError(t, err)
+		require.NotNil(t, head)
+
+		fi, err := os.Stat(filepath.Join(testDataDir, path))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, head.StatusCode)
+		assert.Empty(t, head.Body)
+		assert.False(t, head.IsBase64Encoded)
+		assert.Equal(t, strconv.FormatInt(fi.Size(), 10), head.Headers["Content-Length"], path)
+		assert.Equal(t, get.Headers, head.Headers, path)
+	}
+}
+
+func TestContentLength(t *testing.T) {
+	for _, body := range []string{"", "a", "ab", "abc", "abcd"} {
+		r := &response{body: base64.StdEncoding.EncodeToString([]byte(body)), isBase64Encoded: true}
+		assert.Equal(t, len(body), r.contentLength(), body)
+	}
+	assert.Equal(t, 5, (&response{body: "hello"}).contentLength())
+}
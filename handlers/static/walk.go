@@ -0,0 +1,64 @@
+package static
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+// allowedDotDirectory is served even though dotfiles aren't, since it's where
+// files like apple-app-site-association have to be.
+const allowedDotDirectory = ".well-known"
+
+// skip reports whether a file or directory found walking the tree at root
+// should be left out of the registry.  A bundle built in a messy workspace
+// can pick up .env files, .git directories, and symlinks to anywhere, none of
+// which should ever be served.
+func skip(root, filePath string, info os.FileInfo) bool {
+	if filePath == root {
+		return false
+	}
+	name := info.Name()
+	if strings.HasPrefix(name, ".") && name != allowedDotDirectory && !options.Dotfiles {
+		return true
+	}
+	servedAt := urlPath(filePath)
+	for _, pattern := range options.Exclude {
+		if matchPattern(pattern, servedAt) || (info.IsDir() && matchPattern(pattern, servedAt+"/")) {
+			return true
+		}
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return !safeSymlink(root, filePath)
+	}
+	return false
+}
+
+// safeSymlink reports whether a symlink points at a file inside root.
+// Symlinks to directories are skipped too, as the walk doesn't follow them.
+func safeSymlink(root, filePath string) bool {
+	logger := velacontext.FallbackLogger()
+	target, err := filepath.EvalSymlinks(filePath)
+	if err != nil {
+		logger.Warn("Skipping broken static symlink", zap.String("path", filePath), zap.Error(err))
+		return false
+	}
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(resolvedRoot, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		logger.Warn("Skipping static symlink outside the tree",
+			zap.String("path", filePath),
+			zap.String("target", target),
+		)
+		return false
+	}
+	info, err := os.Stat(target)
+	return err == nil && !info.IsDir()
+}
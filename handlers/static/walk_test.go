@@ -0,0 +1,58 @@
+package static
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDirectoryTreeSkips(t *testing.T) {
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "secret.txt")
+	require.NoError(t, os.WriteFile(secret, []byte("password"), 0o644))
+
+	dir := t.TempDir()
+	write := func(name, contents string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644))
+	}
+	write("index.html", "<html></html>")
+	write(".env", "DATABASE_URL=postgres://")
+	write(".git/config", "[core]")
+	write(".well-known/security.txt", "Contact: security@vela.care")
+	write("js/app.js", "app")
+	write("js/app.js.map", "{}")
+	write("drafts/page.html", "<html></html>")
+	require.NoError(t, os.Symlink(secret, filepath.Join(dir, "leak.txt")))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "js", "app.js"), filepath.Join(dir, "latest.js")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(dir, "outside")))
+	defer LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	defer Configure(Options{})
+
+	Configure(Options{Exclude: []string{"*.map", "/drafts/**"}})
+	require.NoError(t, LoadDirectoryTree(dir, dir, "index.html"))
+	assert.Contains(t, staticURLs, "/index.html")
+	assert.Contains(t, staticURLs, "/js/app.js")
+	assert.Contains(t, staticURLs, "/.well-known/security.txt")
+	assert.Equal(t, "app", staticURLs["/latest.js"].Contents, "symlinks inside the tree are followed")
+	for _, path := range []string{"/.env", "/.git/config", "/js/app.js.map", "/drafts/page.html", "/leak.txt", "/outside", "/outside/secret.txt"} {
+		assert.NotContains(t, staticURLs, path)
+	}
+
+	Configure(Options{Dotfiles: true})
+	require.NoError(t, LoadDirectoryTree(dir, dir, "index.html"))
+	assert.Contains(t, staticURLs, "/.env")
+	assert.Contains(t, staticURLs, "/js/app.js.map")
+	assert.NotContains(t, staticURLs, "/leak.txt")
+}
+
+func TestURLPath(t *testing.T) {
+	pathPrefix = "/srv/www/"
+	defer func() { pathPrefix = testDataDir }()
+	assert.Equal(t, "/css/test.css", urlPath("/srv/www/css/test.css"))
+	assert.Equal(t, "/css/test.css", urlPath("/srv/www/css/../css/./test.css"))
+	assert.Equal(t, "/", urlPath("/srv/www/"))
+}
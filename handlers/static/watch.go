@@ -0,0 +1,95 @@
+package static
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+// Frontend builds write lots of files, so reloads wait for them to settle.
+var treeReloadDelay = 250 * time.Millisecond
+
+// WatchDirectoryTree loads the tree like LoadDirectoryTree, then reloads it
+// whenever a file under basePath changes, so local development picks up each
+// frontend build without restarting.  Requests are served from the old
+// registry until a reload finishes, and a failed reload keeps it.  Watching
+// stops when the context is done, and failed reloads are logged with the
+// context logger.  It's meant for development; deployed Lambdas should load
+// the tree once.
+func WatchDirectoryTree(ctx context.Context, basePath, prefix, index string) error {
+	if err := LoadDirectoryTree(basePath, prefix, index); err != nil {
+		return err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watchDirectories(watcher, basePath); err != nil {
+		watcher.Close()
+		return err
+	}
+	delay := treeReloadDelay
+	go func() {
+		defer watcher.Close()
+		logger := velacontext.GetContextLogger(ctx)
+		reload := time.NewTimer(delay)
+		reload.Stop()
+		defer reload.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Error("Static directory watch failed", zap.Error(err))
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+				// Builds often replace whole directories, which need
+				// watching too
+				if event.Op&fsnotify.Create != 0 {
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						if err := watchDirectories(watcher, event.Name); err != nil {
+							logger.Error("Can't watch static directory", zap.String("path", event.Name), zap.Error(err))
+						}
+					}
+				}
+				reload.Reset(delay)
+			case <-reload.C:
+				if err := LoadDirectoryTree(basePath, prefix, index); err != nil {
+					logger.Error("Static directory reload failed", zap.String("path", basePath), zap.Error(err))
+					continue
+				}
+				logger.Info("Reloaded static directory", zap.String("path", basePath))
+			}
+		}
+	}()
+	return nil
+}
+
+// watchDirectories adds the directory and every one under it, since fsnotify
+// doesn't watch recursively.
+func watchDirectories(watcher *fsnotify.Watcher, root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+}
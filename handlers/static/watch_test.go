@@ -0,0 +1,54 @@
+package static
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchDirectoryTree(t *testing.T) {
+	treeReloadDelay = 10 * time.Millisecond
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html>v1</html>"), 0o644))
+	defer LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, WatchDirectoryTree(ctx, dir, dir, "index.html"))
+
+	body := func(path string) string {
+		r, err := HandleStaticALB(ctx, events.ALBTargetGroupRequest{Path: path, HTTPMethod: http.MethodGet})
+		require.NoError(t, err)
+		if r == nil {
+			return ""
+		}
+		return r.Body
+	}
+	assert.Equal(t, "<html>v1</html>", body("/"))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html>v2</html>"), 0o644))
+	assert.Eventually(t, func() bool { return body("/") == "<html>v2</html>" }, 2*time.Second, 10*time.Millisecond)
+
+	// New directories are watched too
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "js"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "js", "app.js"), []byte("v1"), 0o644))
+	assert.Eventually(t, func() bool { return body("/js/app.js") == "v1" }, 2*time.Second, 10*time.Millisecond)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "js", "app.js"), []byte("v2"), 0o644))
+	assert.Eventually(t, func() bool { return body("/js/app.js") == "v2" }, 2*time.Second, 10*time.Millisecond)
+
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+}
+
+func TestWatchDirectoryTreeMissingDirectory(t *testing.T) {
+	defer LoadDirectoryTree(testDataDir, testDataDir, "index.html")
+	missing := filepath.Join(t.TempDir(), "missing")
+	assert.Error(t, WatchDirectoryTree(context.Background(), missing, missing, "index.html"))
+}
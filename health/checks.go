@@ -0,0 +1,81 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+
+	"github.com/seniorlink-vela/cs-common/config"
+)
+
+// PublicAPI checks that the Vela public API, at the config's public base
+// URI, answers a GET of path without a server error.
+func PublicAPI(client *http.Client, path string) CheckFunc {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return func(ctx context.Context) error {
+		conf := config.Current()
+		if conf == nil {
+			return config.ErrNotLoaded
+		}
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, conf.Common.PublicBaseURI+path, nil)
+		if err != nil {
+			return err
+		}
+		response, err := client.Do(request)
+		if err != nil {
+			return err
+		}
+		response.Body.Close()
+		if response.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("public API responded %s", response.Status)
+		}
+		return nil
+	}
+}
+
+// ConfigFreshness checks that the config is loaded, and that it's been
+// loaded, or confirmed by a reload, within maxAge, so failing reloads are
+// noticed.
+func ConfigFreshness(maxAge time.Duration) CheckFunc {
+	return func(ctx context.Context) error {
+		if config.Current() == nil {
+			return config.ErrNotLoaded
+		}
+		if age := time.Since(config.LastChecked()); age > maxAge {
+			return fmt.Errorf("config last checked %s ago", age.Round(time.Second))
+		}
+		return nil
+	}
+}
+
+// SSMAccess checks that parameters under path can be read.
+func SSMAccess(client config.SSMAPI, path string) CheckFunc {
+	return func(ctx context.Context) error {
+		_, err := client.GetParametersByPath(ctx, &ssm.GetParametersByPathInput{
+			Path:       aws.String(path),
+			MaxResults: aws.Int32(1),
+		})
+		return err
+	}
+}
+
+// QueueLag checks that the lag, like the age of the oldest unread event,
+// isn't more than max.
+func QueueLag(lag func(ctx context.Context) (time.Duration, error), max time.Duration) CheckFunc {
+	return func(ctx context.Context) error {
+		current, err := lag(ctx)
+		if err != nil {
+			return err
+		}
+		if current > max {
+			return fmt.Errorf("queue is %s behind", current.Round(time.Second))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,67 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/seniorlink-vela/cs-common/config"
+)
+
+func TestPublicAPI(t *testing.T) {
+	defer config.Reset()
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/health", r.URL.Path)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	check := PublicAPI(nil, "/api/v1/health")
+
+	config.Reset()
+	assert.ErrorIs(t, check(context.Background()), config.ErrNotLoaded)
+
+	config.Set(&config.Config{Common: config.CommonConfig{PublicBaseURI: server.URL}})
+	assert.NoError(t, check(context.Background()))
+	status = http.StatusBadGateway
+	assert.Error(t, check(context.Background()))
+}
+
+func TestConfigFreshness(t *testing.T) {
+	defer config.Reset()
+	config.Reset()
+	assert.ErrorIs(t, ConfigFreshness(time.Minute)(context.Background()), config.ErrNotLoaded)
+
+	config.Set(&config.Config{})
+	assert.NoError(t, ConfigFreshness(time.Minute)(context.Background()))
+	time.Sleep(2 * time.Millisecond)
+	assert.Error(t, ConfigFreshness(time.Millisecond)(context.Background()))
+}
+
+type mockSSM struct {
+	config.SSMAPI
+	err error
+}
+
+func (m mockSSM) GetParametersByPath(ctx context.Context, in *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+	return &ssm.GetParametersByPathOutput{}, m.err
+}
+
+func TestSSMAccess(t *testing.T) {
+	assert.NoError(t, SSMAccess(mockSSM{}, "/vela/")(context.Background()))
+	assert.Error(t, SSMAccess(mockSSM{err: errors.New("AccessDenied")}, "/vela/")(context.Background()))
+}
+
+func TestQueueLag(t *testing.T) {
+	lag := time.Minute
+	check := QueueLag(func(context.Context) (time.Duration, error) { return lag, nil }, 5*time.Minute)
+	assert.NoError(t, check(context.Background()))
+	lag = 10 * time.Minute
+	assert.EqualError(t, check(context.Background()), "queue is 10m0s behind")
+}
@@ -0,0 +1,211 @@
+// Package health runs the checks components register, and reports them as
+// one status, for load balancer health checks and orchestrators.
+//
+//	health.Register(health.Check{Name: "config", Kind: health.Readiness, Func: health.ConfigFreshness(10 * time.Minute)})
+//	mux.Handle("/health/live", health.Handler(health.Liveness))
+//	mux.Handle("/health/ready", health.Handler(health.Readiness))
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/context/lambdamiddleware"
+)
+
+// Kind is when a check runs.
+type Kind int
+
+const (
+	// Liveness checks say whether the process is working at all, and should
+	// be restarted when it isn't.  Keep them cheap, and don't check
+	// dependencies, or an outage restarts everything.
+	Liveness Kind = iota
+	// Readiness checks say whether the service can handle requests, which
+	// depends on what it calls.
+	Readiness
+)
+
+// Status is the outcome of a check, or of all of them.
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	// StatusWarn is an optional check that failed.  The service is still
+	// reported healthy.
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// DefaultTimeout is how long a check gets when it doesn't say.
+const DefaultTimeout = 2 * time.Second
+
+// CheckFunc checks something, returning an error when it isn't healthy.
+type CheckFunc func(ctx context.Context) error
+
+// Check is a registered check.
+type Check struct {
+	Name string
+	Kind Kind
+	Func CheckFunc
+	// Timeout defaults to DefaultTimeout.
+	Timeout time.Duration
+	// Optional checks only warn when they fail, for things the service
+	// can do without for a while.
+	Optional bool
+}
+
+var (
+	checks     = map[string]Check{}
+	checksLock sync.RWMutex
+)
+
+// Register adds the check, replacing any with the same name, and returns a
+// function that removes it.
+func Register(check Check) func() {
+	checksLock.Lock()
+	defer checksLock.Unlock()
+	checks[check.Name] = check
+	return func() {
+		checksLock.Lock()
+		defer checksLock.Unlock()
+		delete(checks, check.Name)
+	}
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Status    Status  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of all the checks that ran.
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Run runs the checks for kind at the same time, and reports them.
+// Readiness runs the liveness checks too, since a service that isn't live
+// isn't ready either.
+func Run(ctx context.Context, kind Kind) Report {
+	checksLock.RLock()
+	var selected []Check
+	for _, check := range checks {
+		if check.Kind <= kind {
+			selected = append(selected, check)
+		}
+	}
+	checksLock.RUnlock()
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Name < selected[j].Name })
+
+	results := make([]Result, len(selected))
+	var wg sync.WaitGroup
+	for i, check := range selected {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusPass, Checks: make(map[string]Result, len(selected))}
+	for i, check := range selected {
+		report.Checks[check.Name] = results[i]
+		switch results[i].Status {
+		case StatusFail:
+			report.Status = StatusFail
+		case StatusWarn:
+			if report.Status == StatusPass {
+				report.Status = StatusWarn
+			}
+		}
+	}
+	return report
+}
+
+func run(ctx context.Context, check Check) (result Result) {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- check.Func(ctx)
+	}()
+	// Don't wait on a check that ignores its context
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result = Result{Status: StatusPass, LatencyMs: float64(time.Since(start)) / float64(time.Millisecond)}
+	if err != nil {
+		result.Status, result.Error = StatusFail, err.Error()
+		if check.Optional {
+			result.Status = StatusWarn
+		}
+		velacontext.GetContextLogger(ctx).Warn("Health check failed", zap.String("check", check.Name), zap.Bool("optional", check.Optional), zap.Error(err))
+	}
+	return result
+}
+
+// StatusCode is 503 when the report failed, and 200 otherwise.
+func (r Report) StatusCode() int {
+	if r.Status == StatusFail {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
+
+// Handler returns a handler that runs the checks for kind, and responds with
+// the report.
+func Handler(kind Kind) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := Run(r.Context(), kind)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(report.StatusCode())
+		json.NewEncoder(w).Encode(report)
+	})
+}
+
+// ALBHandler is Handler for ALB target groups.
+func ALBHandler(kind Kind) lambdamiddleware.ALBHandler {
+	return func(ctx context.Context, req events.ALBTargetGroupRequest) (*events.ALBTargetGroupResponse, error) {
+		report := Run(ctx, kind)
+		body, err := json.Marshal(report)
+		if err != nil {
+			return nil, err
+		}
+		status := report.StatusCode()
+		return &events.ALBTargetGroupResponse{
+			StatusCode:        status,
+			StatusDescription: fmt.Sprintf("%d %s", status, http.StatusText(status)),
+			Headers:           map[string]string{"Content-Type": "application/json", "Cache-Control": "no-store"},
+			Body:              string(body),
+		}, nil
+	}
+}
@@ -0,0 +1,83 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pass(context.Context) error { return nil }
+
+func TestRun(t *testing.T) {
+	defer Register(Check{Name: "process", Kind: Liveness, Func: pass})()
+	defer Register(Check{Name: "api", Kind: Readiness, Func: pass})()
+	defer Register(Check{Name: "cache", Kind: Readiness, Optional: true, Func: func(context.Context) error {
+		return errors.New("redis is down")
+	}})()
+
+	report := Run(context.Background(), Liveness)
+	assert.Equal(t, StatusPass, report.Status)
+	assert.Len(t, report.Checks, 1)
+
+	report = Run(context.Background(), Readiness)
+	assert.Equal(t, StatusWarn, report.Status)
+	assert.Equal(t, http.StatusOK, report.StatusCode())
+	require.Len(t, report.Checks, 3)
+	assert.Equal(t, StatusWarn, report.Checks["cache"].Status)
+	assert.Equal(t, "redis is down", report.Checks["cache"].Error)
+
+	defer Register(Check{Name: "db", Kind: Readiness, Func: func(context.Context) error {
+		panic("no connection")
+	}})()
+	report = Run(context.Background(), Readiness)
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, http.StatusServiceUnavailable, report.StatusCode())
+	assert.Equal(t, "panic: no connection", report.Checks["db"].Error)
+}
+
+func TestTimeout(t *testing.T) {
+	defer Register(Check{Name: "slow", Kind: Liveness, Timeout: 10 * time.Millisecond, Func: func(context.Context) error {
+		// Ignores its context
+		time.Sleep(time.Second)
+		return nil
+	}})()
+
+	start := time.Now()
+	report := Run(context.Background(), Liveness)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, StatusFail, report.Checks["slow"].Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["slow"].Error)
+}
+
+func TestHandler(t *testing.T) {
+	defer Register(Check{Name: "process", Kind: Liveness, Func: pass})()
+
+	w := httptest.NewRecorder()
+	Handler(Readiness).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	var report Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, StatusPass, report.Status)
+	assert.Contains(t, report.Checks, "process")
+}
+
+func TestALBHandler(t *testing.T) {
+	defer Register(Check{Name: "api", Kind: Readiness, Func: func(context.Context) error {
+		return errors.New("unreachable")
+	}})()
+
+	resp, err := ALBHandler(Readiness)(context.Background(), events.ALBTargetGroupRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "503 Service Unavailable", resp.StatusDescription)
+	assert.Contains(t, resp.Body, `"api":{"status":"fail"`)
+}
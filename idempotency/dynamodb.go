@@ -0,0 +1,137 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBAPI is the part of the DynamoDB client the store uses.
+// *dynamodb.Client implements it.
+type DynamoDBAPI interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+}
+
+var _ DynamoDBAPI = (*dynamodb.Client)(nil)
+
+// DynamoDBStore keeps records in a DynamoDB table.  The table's partition
+// key is the string "key", and expires_at holds the expiry in Unix seconds,
+// so it can be the table's TTL attribute.  token holds the claim's token.  DynamoDB deletes expired items
+// lazily, so the store checks expires_at itself.
+type DynamoDBStore struct {
+	client DynamoDBAPI
+	table  string
+	now    func() time.Time
+}
+
+var _ Store = (*DynamoDBStore)(nil)
+
+// NewDynamoDBStore returns a store for the table.
+func NewDynamoDBStore(client DynamoDBAPI, table string) *DynamoDBStore {
+	return &DynamoDBStore{client: client, table: table, now: time.Now}
+}
+
+func (s *DynamoDBStore) Claim(ctx context.Context, key string, expiresAt time.Time) (Record, bool, error) {
+	claim := Record{Key: key, Status: StatusInProgress, ExpiresAt: expiresAt, Token: newToken()}
+	_, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item: map[string]types.AttributeValue{
+			"key":        &types.AttributeValueMemberS{Value: key},
+			"status":     &types.AttributeValueMemberS{Value: string(StatusInProgress)},
+			"expires_at": unixSeconds(expiresAt),
+			"token":      &types.AttributeValueMemberS{Value: claim.Token},
+		},
+		ConditionExpression:       aws.String("attribute_not_exists(#key) OR expires_at <= :now"),
+		ExpressionAttributeNames:  map[string]string{"#key": "key"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{":now": unixSeconds(s.now())},
+	})
+	if err == nil {
+		return claim, true, nil
+	}
+	var conditionErr *types.ConditionalCheckFailedException
+	if !errors.As(err, &conditionErr) {
+		return Record{}, false, err
+	}
+
+	out, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.table),
+		Key:            map[string]types.AttributeValue{"key": &types.AttributeValueMemberS{Value: key}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return Record{}, false, err
+	}
+	return record(key, out.Item), false, nil
+}
+
+func (s *DynamoDBStore) Complete(ctx context.Context, key, token string, result []byte, expiresAt time.Time) error {
+	item := map[string]types.AttributeValue{
+		"key":        &types.AttributeValueMemberS{Value: key},
+		"status":     &types.AttributeValueMemberS{Value: string(StatusCompleted)},
+		"expires_at": unixSeconds(expiresAt),
+		"token":      &types.AttributeValueMemberS{Value: token},
+	}
+	if len(result) > 0 {
+		item["result"] = &types.AttributeValueMemberB{Value: result}
+	}
+	_, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                 aws.String(s.table),
+		Item:                      item,
+		ConditionExpression:       aws.String("#token = :mine"),
+		ExpressionAttributeNames:  map[string]string{"#token": "token"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{":mine": &types.AttributeValueMemberS{Value: token}},
+	})
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		return ErrClaimLost
+	}
+	return err
+}
+
+func (s *DynamoDBStore) Release(ctx context.Context, key, token string) error {
+	_, err := s.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:                aws.String(s.table),
+		Key:                      map[string]types.AttributeValue{"key": &types.AttributeValueMemberS{Value: key}},
+		ConditionExpression:      aws.String("#status = :in_progress AND #token = :mine"),
+		ExpressionAttributeNames: map[string]string{"#status": "status", "#token": "token"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":in_progress": &types.AttributeValueMemberS{Value: string(StatusInProgress)},
+			":mine":        &types.AttributeValueMemberS{Value: token},
+		},
+	})
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		return nil
+	}
+	return err
+}
+
+func record(key string, item map[string]types.AttributeValue) Record {
+	r := Record{Key: key}
+	if v, ok := item["status"].(*types.AttributeValueMemberS); ok {
+		r.Status = Status(v.Value)
+	}
+	if v, ok := item["result"].(*types.AttributeValueMemberB); ok {
+		r.Result = v.Value
+	}
+	if v, ok := item["token"].(*types.AttributeValueMemberS); ok {
+		r.Token = v.Value
+	}
+	if v, ok := item["expires_at"].(*types.AttributeValueMemberN); ok {
+		if seconds, err := strconv.ParseInt(v.Value, 10, 64); err == nil {
+			r.ExpiresAt = time.Unix(seconds, 0)
+		}
+	}
+	return r
+}
+
+func unixSeconds(t time.Time) *types.AttributeValueMemberN {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(t.Unix(), 10)}
+}
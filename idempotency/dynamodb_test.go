@@ -0,0 +1,113 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockDynamoDB evaluates the store's condition expressions.
+type mockDynamoDB struct {
+	items map[string]map[string]types.AttributeValue
+}
+
+func (m *mockDynamoDB) PutItem(ctx context.Context, in *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	key := in.Item["key"].(*types.AttributeValueMemberS).Value
+	if mine, ok := in.ExpressionAttributeValues[":mine"]; ok {
+		if !sameToken(m.items[key], mine) {
+			return nil, &types.ConditionalCheckFailedException{Message: aws.String("not mine")}
+		}
+	} else if in.ConditionExpression != nil {
+		if existing, ok := m.items[key]; ok {
+			now, _ := strconv.ParseInt(in.ExpressionAttributeValues[":now"].(*types.AttributeValueMemberN).Value, 10, 64)
+			expires, _ := strconv.ParseInt(existing["expires_at"].(*types.AttributeValueMemberN).Value, 10, 64)
+			if expires > now {
+				return nil, &types.ConditionalCheckFailedException{Message: aws.String("exists")}
+			}
+		}
+	}
+	m.items[key] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (m *mockDynamoDB) GetItem(ctx context.Context, in *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: m.items[in.Key["key"].(*types.AttributeValueMemberS).Value]}, nil
+}
+
+func (m *mockDynamoDB) DeleteItem(ctx context.Context, in *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	key := in.Key["key"].(*types.AttributeValueMemberS).Value
+	if m.items[key]["status"].(*types.AttributeValueMemberS).Value != string(StatusInProgress) {
+		return nil, &types.ConditionalCheckFailedException{Message: aws.String("completed")}
+	}
+	if !sameToken(m.items[key], in.ExpressionAttributeValues[":mine"]) {
+		return nil, &types.ConditionalCheckFailedException{Message: aws.String("not mine")}
+	}
+	delete(m.items, key)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func sameToken(item map[string]types.AttributeValue, mine types.AttributeValue) bool {
+	token, ok := item["token"].(*types.AttributeValueMemberS)
+	return ok && token.Value == mine.(*types.AttributeValueMemberS).Value
+}
+
+func TestDynamoDBStore(t *testing.T) {
+	ctx := context.Background()
+	client := &mockDynamoDB{items: map[string]map[string]types.AttributeValue{}}
+	store := NewDynamoDBStore(client, "idempotency")
+	now := time.Now()
+
+	claim, claimed, err := store.Claim(ctx, "key", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	assert.NotEmpty(t, claim.Token)
+	existing, claimed, err := store.Claim(ctx, "key", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	assert.Equal(t, StatusInProgress, existing.Status)
+
+	require.NoError(t, store.Complete(ctx, "key", claim.Token, []byte("done"), now.Add(time.Hour)))
+	assert.NoError(t, store.Release(ctx, "key", claim.Token))
+	existing, claimed, err = store.Claim(ctx, "key", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	assert.Equal(t, Record{Key: "key", Status: StatusCompleted, Result: []byte("done"), ExpiresAt: time.Unix(now.Add(time.Hour).Unix(), 0), Token: claim.Token}, existing)
+
+	// Expired items can be claimed again, and the old claim can't touch
+	// the new one
+	store.now = func() time.Time { return now.Add(2 * time.Hour) }
+	takeover, claimed, err := store.Claim(ctx, "key", now.Add(3*time.Hour))
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	assert.NotEqual(t, claim.Token, takeover.Token)
+	assert.ErrorIs(t, store.Complete(ctx, "key", claim.Token, nil, now.Add(4*time.Hour)), ErrClaimLost)
+	require.NoError(t, store.Release(ctx, "key", claim.Token))
+	assert.Len(t, client.items, 1)
+	require.NoError(t, store.Release(ctx, "key", takeover.Token))
+	assert.Empty(t, client.items)
+}
+
+type failingDynamoDB struct {
+	mockDynamoDB
+}
+
+func (*failingDynamoDB) PutItem(context.Context, *dynamodb.PutItemInput, ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	return nil, errors.New("throttled")
+}
+
+func TestDynamoDBStoreError(t *testing.T) {
+	processor := New(NewDynamoDBStore(&failingDynamoDB{}, "idempotency"), Options{})
+	err := processor.Process(context.Background(), "key", func(context.Context) error {
+		t.Error("ran without a claim")
+		return nil
+	})
+	assert.EqualError(t, err, "idempotency: unable to claim key: throttled")
+}
@@ -0,0 +1,168 @@
+// Package idempotency makes sure an event or webhook's side effects happen
+// once, however many times it's delivered or replayed.
+//
+//	processor := idempotency.New(idempotency.NewDynamoDBStore(client, "idempotency"), idempotency.Options{})
+//	err := processor.Process(ctx, idempotency.EventKey(event), func(ctx context.Context) error {
+//		return sendInvite(ctx, event)
+//	})
+//
+// Process claims the key in the store before running the function, and
+// marks it completed afterwards, so later deliveries are skipped.  When the
+// function fails the claim is released, so the next delivery tries again.
+package idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/seniorlink-vela/cs-common/client"
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+// The defaults for Options' zero fields.
+const (
+	DefaultTTL         = 24 * time.Hour
+	DefaultLockTimeout = 5 * time.Minute
+)
+
+// ErrInProgress is returned when another delivery of the same key is being
+// processed.  Retry later.
+var ErrInProgress = errors.New("idempotency: already being processed")
+
+// ErrClaimLost is returned by Store.Complete when the claim expired and was
+// taken over by another delivery before the key was completed.
+var ErrClaimLost = errors.New("idempotency: the claim was taken over")
+
+// Status is how far a key's processing got.
+type Status string
+
+const (
+	StatusInProgress Status = "in_progress"
+	StatusCompleted  Status = "completed"
+)
+
+// Record is a key's processing.  It's forgotten after ExpiresAt.  Token
+// identifies the claim that made it, so a claim that expired and was taken
+// over can't complete or release its successor's.
+type Record struct {
+	Key       string
+	Status    Status
+	Result    []byte
+	ExpiresAt time.Time
+	Token     string
+}
+
+// Store keeps the records.  Claim has to be atomic, so only one caller
+// claims a key.
+type Store interface {
+	// Claim records the key as in progress until expiresAt, under a new
+	// random token, and returns the record.  When the key has an unexpired
+	// record, it returns that instead, with claimed false.
+	Claim(ctx context.Context, key string, expiresAt time.Time) (record Record, claimed bool, err error)
+	// Complete records the key as completed, with the result, until
+	// expiresAt, as long as it's still claimed with the token.  When it
+	// isn't, it returns ErrClaimLost.
+	Complete(ctx context.Context, key, token string, result []byte, expiresAt time.Time) error
+	// Release forgets an in-progress key, as long as it's still claimed
+	// with the token.
+	Release(ctx context.Context, key, token string) error
+}
+
+// Options configure a Processor.
+type Options struct {
+	// TTL is how long a completed key is remembered.  Deliveries after
+	// that are processed again, so it has to be longer than the source
+	// retries or replays for.
+	TTL time.Duration
+	// LockTimeout is how long a claim lasts, after which another delivery
+	// can take it over, in case the process running it died.  It has to be
+	// longer than the function takes.
+	LockTimeout time.Duration
+}
+
+// Processor runs functions once per key.
+type Processor struct {
+	store   Store
+	options Options
+}
+
+// New returns a processor that keeps its records in the store.
+func New(store Store, options Options) *Processor {
+	if options.TTL <= 0 {
+		options.TTL = DefaultTTL
+	}
+	if options.LockTimeout <= 0 {
+		options.LockTimeout = DefaultLockTimeout
+	}
+	return &Processor{store: store, options: options}
+}
+
+// EventKey is the key for an event from the event queue: its message UUID,
+// which stays the same when it's redelivered or replayed.
+func EventKey(event client.Event) string {
+	if event.MessageUUID != "" {
+		return "event:" + event.MessageUUID
+	}
+	return fmt.Sprintf("event-id:%d", event.ID)
+}
+
+// Process runs fn unless the key has already been processed, when it
+// returns nil, or is being processed, when it returns ErrInProgress.
+func (p *Processor) Process(ctx context.Context, key string, fn func(ctx context.Context) error) error {
+	_, err := p.ProcessResult(ctx, key, func(ctx context.Context) ([]byte, error) {
+		return nil, fn(ctx)
+	})
+	return err
+}
+
+// ProcessResult is Process for functions with a result, like a webhook's
+// response, which is kept and returned again for later deliveries.
+func (p *Processor) ProcessResult(ctx context.Context, key string, fn func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	logger := velacontext.GetContextLogger(ctx)
+	record, claimed, err := p.store.Claim(ctx, key, time.Now().Add(p.options.LockTimeout))
+	if err != nil {
+		return nil, fmt.Errorf("idempotency: unable to claim %s: %w", key, err)
+	}
+	if !claimed {
+		if record.Status == StatusCompleted {
+			logger.Info("Skipping already processed key", zap.String("idempotency_key", key))
+			return record.Result, nil
+		}
+		return nil, ErrInProgress
+	}
+
+	result, err := p.run(ctx, fn)
+	if err != nil {
+		// Let the next delivery try again
+		if releaseErr := p.store.Release(velacontext.Detach(ctx), key, record.Token); releaseErr != nil {
+			logger.Warn("Unable to release idempotency key", zap.String("idempotency_key", key), zap.Error(releaseErr))
+		}
+		return nil, err
+	}
+	if err := p.store.Complete(velacontext.Detach(ctx), key, record.Token, result, time.Now().Add(p.options.TTL)); err != nil {
+		// The side effects happened, so don't fail; the claim expiring
+		// lets a redelivery run them again
+		logger.Error("Unable to complete idempotency key", zap.String("idempotency_key", key), zap.Error(err))
+	}
+	return result, nil
+}
+
+// A random claim token.
+func newToken() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("idempotency: can't read random bytes for a claim token: %v", err))
+	}
+	return hex.EncodeToString(b[:])
+}
+
+func (p *Processor) run(ctx context.Context, fn func(ctx context.Context) ([]byte, error)) (result []byte, err error) {
+	defer velacontext.Recover(ctx, func(panicErr error) { err = panicErr })
+	return fn(ctx)
+}
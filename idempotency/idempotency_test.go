@@ -0,0 +1,115 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/seniorlink-vela/cs-common/client"
+)
+
+func TestProcess(t *testing.T) {
+	ctx := context.Background()
+	processor := New(NewMemoryStore(), Options{})
+	calls := 0
+	fn := func(context.Context) error {
+		calls++
+		return nil
+	}
+	require.NoError(t, processor.Process(ctx, "event:1", fn))
+	require.NoError(t, processor.Process(ctx, "event:1", fn))
+	require.NoError(t, processor.Process(ctx, "event:2", fn))
+	assert.Equal(t, 2, calls)
+}
+
+func TestProcessFailure(t *testing.T) {
+	ctx := context.Background()
+	processor := New(NewMemoryStore(), Options{})
+	errFailed := errors.New("failed")
+	err := processor.Process(ctx, "key", func(context.Context) error { return errFailed })
+	assert.Equal(t, errFailed, err)
+
+	// Released, so it runs again
+	err = processor.Process(ctx, "key", func(context.Context) error { panic("boom") })
+	assert.ErrorContains(t, err, "boom")
+	calls := 0
+	require.NoError(t, processor.Process(ctx, "key", func(context.Context) error {
+		calls++
+		return nil
+	}))
+	assert.Equal(t, 1, calls)
+}
+
+func TestProcessInProgress(t *testing.T) {
+	ctx := context.Background()
+	processor := New(NewMemoryStore(), Options{})
+	err := processor.Process(ctx, "key", func(ctx context.Context) error {
+		return processor.Process(ctx, "key", func(context.Context) error {
+			t.Error("ran twice")
+			return nil
+		})
+	})
+	assert.Equal(t, ErrInProgress, err)
+}
+
+func TestProcessExpiry(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	processor := New(store, Options{TTL: time.Hour, LockTimeout: time.Minute})
+
+	// A claim that's outlived its lock timeout is taken over
+	stale, claimed, err := store.Claim(ctx, "stuck", now.Add(time.Minute))
+	require.NoError(t, err)
+	require.True(t, claimed)
+	now = now.Add(2 * time.Minute)
+	ran := false
+	require.NoError(t, processor.Process(ctx, "stuck", func(context.Context) error {
+		ran = true
+		return nil
+	}))
+	assert.True(t, ran)
+
+	// The stale claim can't overwrite or release the new record
+	assert.ErrorIs(t, store.Complete(ctx, "stuck", stale.Token, nil, now.Add(time.Hour)), ErrClaimLost)
+	require.NoError(t, store.Release(ctx, "stuck", stale.Token))
+	record, claimed, err := store.Claim(ctx, "stuck", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	assert.Equal(t, StatusCompleted, record.Status)
+
+	// Completed keys are forgotten after the TTL
+	now = now.Add(2 * time.Hour)
+	ran = false
+	require.NoError(t, processor.Process(ctx, "stuck", func(context.Context) error {
+		ran = true
+		return nil
+	}))
+	assert.True(t, ran)
+}
+
+func TestProcessResult(t *testing.T) {
+	ctx := context.Background()
+	processor := New(NewMemoryStore(), Options{})
+	result, err := processor.ProcessResult(ctx, "webhook:1", func(context.Context) ([]byte, error) {
+		return []byte(`{"ok":true}`), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `{"ok":true}`, string(result))
+
+	result, err = processor.ProcessResult(ctx, "webhook:1", func(context.Context) ([]byte, error) {
+		return []byte(`{"ok":false}`), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `{"ok":true}`, string(result))
+}
+
+func TestEventKey(t *testing.T) {
+	assert.Equal(t, "event:abc", EventKey(client.Event{ID: 3, MessageUUID: "abc"}))
+	assert.Equal(t, "event-id:3", EventKey(client.Event{ID: 3}))
+}
@@ -0,0 +1,58 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps records in memory, for tests and single-instance
+// services.  Expired records are dropped as keys are claimed.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+	now     func() time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore returns an empty store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: map[string]Record{}, now: time.Now}
+}
+
+func (s *MemoryStore) Claim(ctx context.Context, key string, expiresAt time.Time) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for k, record := range s.records {
+		if !record.ExpiresAt.After(now) {
+			delete(s.records, k)
+		}
+	}
+	if record, ok := s.records[key]; ok {
+		return record, false, nil
+	}
+	record := Record{Key: key, Status: StatusInProgress, ExpiresAt: expiresAt, Token: newToken()}
+	s.records[key] = record
+	return record, true, nil
+}
+
+func (s *MemoryStore) Complete(ctx context.Context, key, token string, result []byte, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if record, ok := s.records[key]; !ok || record.Token != token {
+		return ErrClaimLost
+	}
+	s.records[key] = Record{Key: key, Status: StatusCompleted, Result: result, ExpiresAt: expiresAt, Token: token}
+	return nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if record := s.records[key]; record.Status == StatusInProgress && record.Token == token {
+		delete(s.records, key)
+	}
+	return nil
+}
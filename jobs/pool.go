@@ -0,0 +1,189 @@
+// Package jobs runs work in the background: a bounded pool of workers for
+// work handed off from requests or events, and a scheduler for periodic
+// tasks like reporting queue lag or refreshing config.
+//
+//	pool := jobs.NewPool(jobs.PoolOptions{Workers: 5})
+//	defer pool.Close(shutdownCtx)
+//	err := pool.Submit(ctx, func(ctx context.Context) error { return sendReminder(ctx, visit) })
+//
+//	scheduler := jobs.NewScheduler(jobs.SchedulerOptions{})
+//	scheduler.Add(jobs.Job{Name: "queue_lag", Schedule: jobs.Every(time.Minute), Jitter: 10 * time.Second, Func: reportLag})
+//	go scheduler.Run(ctx)
+//
+// Jobs keep the values of the context they're submitted with, so they log
+// with the request's logger and request ID, but not its cancellation: they
+// run after the request is done.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/metrics"
+)
+
+// DefaultWorkers is how many jobs a pool runs at once when the options
+// don't say.
+const DefaultWorkers = 10
+
+// ErrClosed is returned when submitting to a closed pool.
+var ErrClosed = errors.New("jobs: pool is closed")
+
+// Func is a job.  Errors are logged, and panics recovered and logged.
+type Func func(ctx context.Context) error
+
+var (
+	jobsRunMetric = metrics.Definition{
+		Name: "jobs_run",
+		Help: "Background jobs run, by job and status.",
+		Tags: []string{"job", "status"},
+	}
+	jobDurationMetric = metrics.Definition{
+		Name:    "job_duration_ms",
+		Help:    "How long background jobs took.",
+		Unit:    metrics.UnitMilliseconds,
+		Tags:    []string{"job"},
+		Buckets: []float64{10, 50, 100, 500, 1000, 5000, 10000, 30000, 60000},
+	}
+)
+
+// PoolOptions configures a pool.
+type PoolOptions struct {
+	// Name tags the pool's metrics and logs.
+	Name string
+	// Workers defaults to DefaultWorkers.
+	Workers int
+	// QueueSize is how many jobs can wait for a worker before Submit
+	// blocks.  It defaults to Workers.
+	QueueSize int
+}
+
+type task struct {
+	ctx context.Context
+	fn  Func
+}
+
+// Pool runs jobs on a fixed number of workers.
+type Pool struct {
+	name  string
+	tasks chan task
+
+	// ctx is cancelled when Close gives up waiting, to cancel the jobs
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// closing is closed by Close, to turn away Submits still waiting for
+	// room, which are counted by submitting so the tasks channel is only
+	// closed once they're gone
+	mu         sync.Mutex
+	closed     bool
+	closing    chan struct{}
+	submitting sync.WaitGroup
+	closeTasks sync.Once
+	workers    sync.WaitGroup
+}
+
+// NewPool starts the pool's workers.
+func NewPool(options PoolOptions) *Pool {
+	if options.Name == "" {
+		options.Name = "pool"
+	}
+	if options.Workers <= 0 {
+		options.Workers = DefaultWorkers
+	}
+	if options.QueueSize <= 0 {
+		options.QueueSize = options.Workers
+	}
+	p := &Pool{name: options.Name, tasks: make(chan task, options.QueueSize), closing: make(chan struct{})}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	p.workers.Add(options.Workers)
+	for range options.Workers {
+		go p.work()
+	}
+	return p
+}
+
+func (p *Pool) work() {
+	defer p.workers.Done()
+	for t := range p.tasks {
+		ctx, cancel := context.WithCancel(velacontext.Detach(t.ctx))
+		stop := context.AfterFunc(p.ctx, cancel)
+		run(ctx, p.name, t.fn)
+		stop()
+		cancel()
+	}
+}
+
+// Submit queues the job, waiting for room when the queue is full, until
+// the context is done or the pool is closed.
+func (p *Pool) Submit(ctx context.Context, fn Func) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrClosed
+	}
+	p.submitting.Add(1)
+	p.mu.Unlock()
+	defer p.submitting.Done()
+
+	select {
+	case p.tasks <- task{ctx: ctx, fn: fn}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.closing:
+		return ErrClosed
+	}
+}
+
+// Close stops taking jobs and waits for the queued and running ones to
+// finish.  When the context is done first, it cancels the running jobs'
+// contexts and returns the context's error; queued jobs then run with
+// cancelled contexts.
+func (p *Pool) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.closing)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.closeTasks.Do(func() {
+			p.submitting.Wait()
+			close(p.tasks)
+		})
+		p.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		return ctx.Err()
+	}
+}
+
+// run runs the job, logging its error or panic, and records it.
+func run(ctx context.Context, name string, fn Func) {
+	start := time.Now()
+	status := "ok"
+	defer func() {
+		metrics.Default().Counter(jobsRunMetric).Inc(metrics.Tags{"job": name, "status": status})
+		metrics.Default().Histogram(jobDurationMetric).Observe(metrics.Milliseconds(start), metrics.Tags{"job": name})
+	}()
+	defer velacontext.Recover(ctx, func(error) { status = "panic" })
+
+	if err := fn(ctx); err != nil {
+		status = "error"
+		velacontext.GetContextLogger(ctx).Error("Job failed", zap.String("job", name), zap.Error(err))
+	}
+}
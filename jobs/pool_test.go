@@ -0,0 +1,136 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+func TestPool(t *testing.T) {
+	pool := NewPool(PoolOptions{Workers: 2})
+	var running, most, total atomic.Int32
+	for range 10 {
+		require.NoError(t, pool.Submit(context.Background(), func(ctx context.Context) error {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				m := most.Load()
+				if n <= m || most.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			total.Add(1)
+			return nil
+		}))
+	}
+	require.NoError(t, pool.Close(context.Background()))
+	assert.Equal(t, int32(10), total.Load())
+	assert.Equal(t, int32(2), most.Load())
+	assert.ErrorIs(t, pool.Submit(context.Background(), func(context.Context) error { return nil }), ErrClosed)
+}
+
+func TestPoolContext(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	ctx := velacontext.ContextWithRequestID(context.Background(), "req-1")
+	ctx = velacontext.ContextWithLogger(ctx, zap.New(core))
+	ctx, cancel := context.WithCancel(ctx)
+
+	pool := NewPool(PoolOptions{Name: "reminders", Workers: 1})
+	requestID := make(chan string, 1)
+	require.NoError(t, pool.Submit(ctx, func(ctx context.Context) error {
+		requestID <- velacontext.GetContextRequestID(ctx)
+		return errors.New("no phone number")
+	}))
+	// The job outlives the submitter
+	cancel()
+	require.NoError(t, pool.Submit(context.Background(), func(ctx context.Context) error {
+		panic("boom")
+	}))
+	require.NoError(t, pool.Close(context.Background()))
+
+	assert.Equal(t, "req-1", <-requestID)
+	failed := logs.FilterMessage("Job failed").All()
+	require.Len(t, failed, 1)
+	assert.Equal(t, "reminders", failed[0].ContextMap()["job"])
+	assert.Equal(t, "no phone number", failed[0].ContextMap()["error"])
+}
+
+func TestPoolSubmitFull(t *testing.T) {
+	pool := NewPool(PoolOptions{Workers: 1, QueueSize: 1})
+	release := make(chan struct{})
+	block := func(context.Context) error { <-release; return nil }
+	require.NoError(t, pool.Submit(context.Background(), block))
+	require.NoError(t, pool.Submit(context.Background(), block))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	// The worker may not have taken the first job yet, so one more may fit
+	var err error
+	for err == nil {
+		err = pool.Submit(ctx, block)
+	}
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	close(release)
+	require.NoError(t, pool.Close(context.Background()))
+}
+
+func TestPoolCloseTimeout(t *testing.T) {
+	pool := NewPool(PoolOptions{Workers: 1})
+	cancelled := make(chan struct{})
+	require.NoError(t, pool.Submit(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, pool.Close(ctx), context.DeadlineExceeded)
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("the running job wasn't cancelled")
+	}
+}
+
+func TestPoolCloseWhileSubmitting(t *testing.T) {
+	pool := NewPool(PoolOptions{Workers: 1, QueueSize: 1})
+	release := make(chan struct{})
+	block := func(context.Context) error { <-release; return nil }
+	require.NoError(t, pool.Submit(context.Background(), block))
+
+	// Fill the queue, then leave a Submit waiting for room
+	submitted := make(chan error)
+	go func() {
+		var err error
+		for err == nil {
+			err = pool.Submit(context.Background(), block)
+		}
+		submitted <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// Close doesn't wait on it, and turns it away
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, pool.Close(ctx), context.DeadlineExceeded)
+	select {
+	case err := <-submitted:
+		assert.ErrorIs(t, err, ErrClosed)
+	case <-time.After(time.Second):
+		t.Fatal("Submit is still waiting")
+	}
+	close(release)
+	assert.NoError(t, pool.Close(context.Background()))
+}
@@ -0,0 +1,178 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+// Schedule says when a job next runs after a time.
+type Schedule interface {
+	Next(time.Time) time.Time
+}
+
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// Every runs a job at an interval, measured from when its last run
+// finished, so a slow run pushes the next one back.
+func Every(interval time.Duration) Schedule {
+	return every(interval)
+}
+
+// Cron parses a standard five field cron spec, like "*/15 * * * *", or a
+// descriptor like "@hourly".  Times are in UTC unless the spec starts with
+// CRON_TZ=.
+func Cron(spec string) (Schedule, error) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("jobs: invalid schedule %q: %w", spec, err)
+	}
+	return schedule, nil
+}
+
+// MustCron is Cron for specs known to be valid, panicking when they aren't.
+func MustCron(spec string) Schedule {
+	schedule, err := Cron(spec)
+	if err != nil {
+		panic(err)
+	}
+	return schedule
+}
+
+// Job is a periodic task.
+type Job struct {
+	Name     string
+	Schedule Schedule
+	Func     Func
+	// Timeout cancels a run's context when it's taken that long.  Zero
+	// means no timeout.
+	Timeout time.Duration
+	// Jitter delays each run by up to that long, at random, so instances
+	// of a service don't all run a job at once.
+	Jitter time.Duration
+	// RunOnStart runs the job as soon as the scheduler starts, as well as
+	// on its schedule.
+	RunOnStart bool
+}
+
+// SchedulerOptions configures a scheduler.
+type SchedulerOptions struct {
+	// Logger defaults to the context's logger in Run.
+	Logger *zap.Logger
+	// Now defaults to time.Now.  Tests set it.
+	Now func() time.Time
+}
+
+// Scheduler runs jobs on their schedules.  A job's runs don't overlap: when
+// one runs long, the runs it overlaps are skipped.
+type Scheduler struct {
+	options SchedulerOptions
+
+	mu      sync.Mutex
+	jobs    []Job
+	running bool
+}
+
+// NewScheduler returns a scheduler without jobs.
+func NewScheduler(options SchedulerOptions) *Scheduler {
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+	return &Scheduler{options: options}
+}
+
+// Add adds a job.  Jobs have to be added before Run.
+func (s *Scheduler) Add(job Job) error {
+	if job.Name == "" || job.Schedule == nil || job.Func == nil {
+		return errors.New("jobs: a job needs a name, schedule and func")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return errors.New("jobs: the scheduler is already running")
+	}
+	for _, other := range s.jobs {
+		if other.Name == job.Name {
+			return fmt.Errorf("jobs: there's already a job called %s", job.Name)
+		}
+	}
+	s.jobs = append(s.jobs, job)
+	return nil
+}
+
+// Run runs the jobs until the context is done, then waits for running jobs
+// to finish, and returns the context's error.  Cancelling the context
+// cancels the runs' contexts too.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return errors.New("jobs: the scheduler is already running")
+	}
+	s.running = true
+	jobs := append([]Job(nil), s.jobs...)
+	s.mu.Unlock()
+
+	if s.options.Logger != nil {
+		ctx = velacontext.ContextWithLogger(ctx, s.options.Logger)
+	}
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx, job)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	if job.RunOnStart {
+		s.run(ctx, job)
+	}
+	for {
+		now := s.options.Now()
+		next := job.Schedule.Next(now)
+		if next.IsZero() {
+			// The schedule never fires again
+			return
+		}
+		wait := next.Sub(now)
+		if job.Jitter > 0 {
+			wait += rand.N(job.Jitter)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.run(ctx, job)
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	// Each run gets its own request ID, to find its logs
+	ctx = velacontext.WithLoggerFields(velacontext.ContextWithRequestID(ctx, velacontext.NewRequestID()))
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+	run(ctx, job.Name, job.Func)
+}
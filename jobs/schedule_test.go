@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+func TestCron(t *testing.T) {
+	schedule, err := Cron("*/15 * * * *")
+	require.NoError(t, err)
+	from := time.Date(2024, 3, 1, 9, 7, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 3, 1, 9, 15, 0, 0, time.UTC), schedule.Next(from))
+
+	_, err = Cron("every tuesday")
+	assert.ErrorContains(t, err, `invalid schedule "every tuesday"`)
+	assert.Panics(t, func() { MustCron("nope") })
+}
+
+func TestEvery(t *testing.T) {
+	from := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t, from.Add(time.Minute), Every(time.Minute).Next(from))
+}
+
+func TestSchedulerAdd(t *testing.T) {
+	s := NewScheduler(SchedulerOptions{})
+	noop := func(context.Context) error { return nil }
+	require.NoError(t, s.Add(Job{Name: "lag", Schedule: Every(time.Second), Func: noop}))
+	assert.ErrorContains(t, s.Add(Job{Name: "lag", Schedule: Every(time.Second), Func: noop}), "already a job")
+	assert.Error(t, s.Add(Job{Name: "refresh", Func: noop}))
+}
+
+func TestSchedulerRun(t *testing.T) {
+	s := NewScheduler(SchedulerOptions{})
+	var runs atomic.Int32
+	requestIDs := make(chan string, 100)
+	require.NoError(t, s.Add(Job{
+		Name:       "lag",
+		Schedule:   Every(5 * time.Millisecond),
+		Jitter:     time.Millisecond,
+		RunOnStart: true,
+		Func: func(ctx context.Context) error {
+			runs.Add(1)
+			requestIDs <- velacontext.GetContextRequestID(ctx)
+			return nil
+		},
+	}))
+	timedOut := make(chan error, 1)
+	require.NoError(t, s.Add(Job{
+		Name:     "refresh",
+		Schedule: Every(5 * time.Millisecond),
+		Timeout:  time.Millisecond,
+		Func: func(ctx context.Context) error {
+			<-ctx.Done()
+			select {
+			case timedOut <- ctx.Err():
+			default:
+			}
+			return nil
+		},
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, s.Run(ctx), context.DeadlineExceeded)
+
+	assert.GreaterOrEqual(t, runs.Load(), int32(3))
+	first, second := <-requestIDs, <-requestIDs
+	assert.NotEmpty(t, first)
+	assert.NotEqual(t, first, second)
+	assert.ErrorIs(t, <-timedOut, context.DeadlineExceeded)
+	assert.Error(t, s.Add(Job{Name: "late", Schedule: Every(time.Second), Func: func(context.Context) error { return nil }}))
+}
@@ -0,0 +1,264 @@
+// Package lifecycle starts a service's components in order, waits for
+// SIGTERM or SIGINT, and stops them in reverse order, each with a timeout,
+// so a deploy drains requests and messages instead of dropping them.
+//
+//	l := lifecycle.New(lifecycle.Options{})
+//	l.Append(lifecycle.CloseHook("postgres", pool.Close))
+//	l.Go("sqs", consumer.Run)
+//	l.Go("relay", relay.Run)
+//	l.AppendHTTPServer("http", server)
+//	if err := l.Run(ctx); err != nil {
+//		logger.Fatal("Service failed", zap.Error(err))
+//	}
+//
+// The HTTP server stops first, then the consumers, then the pool they use.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+// DefaultStopTimeout is how long a component gets to stop when neither it
+// nor the options say.
+const DefaultStopTimeout = 30 * time.Second
+
+// Hook is a component's start and stop.  Either can be nil.
+type Hook struct {
+	Name string
+	// Start shouldn't block.  Components that run until stopped use Go.
+	Start func(ctx context.Context) error
+	// Stop gets a context that's done when the stop timeout passes.
+	Stop func(ctx context.Context) error
+	// StopTimeout defaults to the options' StopTimeout.
+	StopTimeout time.Duration
+}
+
+// CloseHook is a hook that calls close when stopping, for things like
+// connection pools.
+func CloseHook(name string, close func()) Hook {
+	return Hook{Name: name, Stop: func(context.Context) error {
+		close()
+		return nil
+	}}
+}
+
+// Options configures a lifecycle.
+type Options struct {
+	// StopTimeout defaults to DefaultStopTimeout.
+	StopTimeout time.Duration
+	// Signals default to SIGTERM and SIGINT.
+	Signals []os.Signal
+	// Logger defaults to the context's logger.
+	Logger *zap.Logger
+}
+
+// Lifecycle is the components of a service.
+type Lifecycle struct {
+	options Options
+
+	mu      sync.Mutex
+	hooks   []Hook
+	started int
+	// failed gets the first component that stops by itself
+	failed chan error
+}
+
+// New returns a lifecycle without components.
+func New(options Options) *Lifecycle {
+	if options.StopTimeout <= 0 {
+		options.StopTimeout = DefaultStopTimeout
+	}
+	if len(options.Signals) == 0 {
+		options.Signals = []os.Signal{syscall.SIGTERM, os.Interrupt}
+	}
+	return &Lifecycle{options: options, failed: make(chan error, 1)}
+}
+
+// Append adds a component, which starts after the ones before it and stops
+// before them.
+func (l *Lifecycle) Append(hook Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, hook)
+}
+
+// Go adds a component that runs until its context is done, like an SQS
+// consumer or the relay.  Stopping cancels the context and waits for run to
+// return.  When run returns before then, the service shuts down.
+func (l *Lifecycle) Go(name string, run func(ctx context.Context) error) {
+	var (
+		cancel context.CancelFunc
+		done   chan struct{}
+	)
+	l.Append(Hook{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			ctx, cancel = context.WithCancel(velacontext.Detach(ctx))
+			done = make(chan struct{})
+			go func() {
+				defer close(done)
+				err := run(ctx)
+				if ctx.Err() == nil {
+					if err == nil {
+						err = errors.New("stopped unexpectedly")
+					}
+					l.fail(name, err)
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+}
+
+// AppendHTTPServer adds a server, listening on its address when starting,
+// so a port in use fails the start, and shutting it down gracefully when
+// stopping.
+func (l *Lifecycle) AppendHTTPServer(name string, server *http.Server) {
+	l.Append(Hook{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			addr := server.Addr
+			if addr == "" {
+				addr = ":http"
+			}
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			go func() {
+				if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+					l.fail(name, err)
+				}
+			}()
+			return nil
+		},
+		Stop: server.Shutdown,
+	})
+}
+
+func (l *Lifecycle) fail(name string, err error) {
+	select {
+	case l.failed <- fmt.Errorf("lifecycle: %s: %w", name, err):
+	default:
+	}
+}
+
+// Run starts the components, waits for a signal, for the context to be
+// done, or for a component to fail, then stops them.  It returns the start
+// or component failure, and any errors stopping.
+func (l *Lifecycle) Run(ctx context.Context) error {
+	if l.options.Logger != nil {
+		ctx = velacontext.ContextWithLogger(ctx, l.options.Logger)
+	}
+	logger := velacontext.GetContextLogger(ctx)
+	// Before starting, so a signal while starting still stops things
+	signalled := make(chan os.Signal, 1)
+	signal.Notify(signalled, l.options.Signals...)
+	defer signal.Stop(signalled)
+
+	if err := l.Start(ctx); err != nil {
+		return errors.Join(err, l.Stop(ctx))
+	}
+	logger.Info("Started")
+
+	var failure error
+	select {
+	case sig := <-signalled:
+		logger.Info("Shutting down", zap.String("signal", sig.String()))
+	case <-ctx.Done():
+		logger.Info("Shutting down", zap.NamedError("reason", ctx.Err()))
+	case failure = <-l.failed:
+		logger.Error("Shutting down after a component failed", zap.Error(failure))
+	}
+	return errors.Join(failure, l.Stop(ctx))
+}
+
+// Start starts the components in order.  When one fails, it returns its
+// error, and Stop stops the ones that started.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	logger := velacontext.GetContextLogger(ctx)
+	for l.started < len(l.hooks) {
+		hook := l.hooks[l.started]
+		if hook.Start != nil {
+			logger.Debug("Starting", zap.String("component", hook.Name))
+			if err := hook.Start(ctx); err != nil {
+				return fmt.Errorf("lifecycle: unable to start %s: %w", hook.Name, err)
+			}
+		}
+		l.started++
+	}
+	return nil
+}
+
+// Stop stops the started components in reverse order, each within its stop
+// timeout, carrying on when one fails, and returns their errors.  The
+// context's values are used, but not its cancellation: shutting down
+// happens after it's done.
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ctx = velacontext.Detach(ctx)
+	logger := velacontext.GetContextLogger(ctx)
+	var errs []error
+	for ; l.started > 0; l.started-- {
+		hook := l.hooks[l.started-1]
+		if hook.Stop == nil {
+			continue
+		}
+		timeout := hook.StopTimeout
+		if timeout <= 0 {
+			timeout = l.options.StopTimeout
+		}
+		start := time.Now()
+		logger.Info("Stopping", zap.String("component", hook.Name))
+		if err := stop(ctx, hook, timeout); err != nil {
+			logger.Error("Unable to stop", zap.String("component", hook.Name), zap.Duration("duration", time.Since(start)), zap.Error(err))
+			errs = append(errs, fmt.Errorf("lifecycle: unable to stop %s: %w", hook.Name, err))
+			continue
+		}
+		logger.Info("Stopped", zap.String("component", hook.Name), zap.Duration("duration", time.Since(start)))
+	}
+	return errors.Join(errs...)
+}
+
+// stop runs the hook's stop, not waiting past the timeout when it ignores
+// its context.
+func stop(ctx context.Context, hook Hook, timeout time.Duration) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		defer velacontext.Recover(ctx, func(err error) { done <- err })
+		done <- hook.Stop(ctx)
+	}()
+	select {
+	case err = <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
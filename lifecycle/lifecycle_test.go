@@ -0,0 +1,135 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// recorder records the order components start and stop.
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) hook(name string, startErr, stopErr error) Hook {
+	return Hook{
+		Name: name,
+		Start: func(context.Context) error {
+			r.add("start " + name)
+			return startErr
+		},
+		Stop: func(context.Context) error {
+			r.add("stop " + name)
+			return stopErr
+		},
+	}
+}
+
+func TestRunSignal(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	r := &recorder{}
+	l := New(Options{Signals: []os.Signal{syscall.SIGUSR1}, Logger: zap.New(core)})
+	l.Append(r.hook("db", nil, nil))
+	l.Append(r.hook("http", nil, nil))
+	l.Append(Hook{Name: "started", Start: func(context.Context) error {
+		go syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+		return nil
+	}})
+
+	require.NoError(t, l.Run(context.Background()))
+	assert.Equal(t, []string{"start db", "start http", "stop http", "stop db"}, r.events)
+	shutdown := logs.FilterMessage("Shutting down").All()
+	require.Len(t, shutdown, 1)
+	assert.Equal(t, "user defined signal 1", shutdown[0].ContextMap()["signal"])
+}
+
+func TestStartFailure(t *testing.T) {
+	r := &recorder{}
+	l := New(Options{})
+	l.Append(r.hook("db", nil, nil))
+	l.Append(r.hook("http", errors.New("address in use"), nil))
+	l.Append(r.hook("sqs", nil, nil))
+
+	err := l.Run(context.Background())
+	assert.EqualError(t, err, "lifecycle: unable to start http: address in use")
+	assert.Equal(t, []string{"start db", "start http", "stop db"}, r.events)
+}
+
+func TestStopTimeout(t *testing.T) {
+	r := &recorder{}
+	l := New(Options{StopTimeout: time.Second})
+	l.Append(r.hook("db", nil, errors.New("already closed")))
+	l.Append(Hook{
+		Name:        "stuck",
+		StopTimeout: 10 * time.Millisecond,
+		Stop: func(context.Context) error {
+			select {}
+		},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := l.Run(ctx)
+	assert.ErrorContains(t, err, "unable to stop stuck: context deadline exceeded")
+	assert.ErrorContains(t, err, "unable to stop db: already closed")
+	assert.Equal(t, []string{"start db", "stop db"}, r.events)
+}
+
+func TestGo(t *testing.T) {
+	l := New(Options{})
+	stopped := make(chan struct{})
+	l.Go("consumer", func(ctx context.Context) error {
+		<-ctx.Done()
+		close(stopped)
+		return nil
+	})
+	l.Go("relay", func(ctx context.Context) error {
+		return errors.New("queue not found")
+	})
+
+	err := l.Run(context.Background())
+	assert.EqualError(t, err, "lifecycle: relay: queue not found")
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("the consumer wasn't stopped")
+	}
+}
+
+func TestHTTPServer(t *testing.T) {
+	server := &http.Server{Addr: "127.0.0.1:0", Handler: http.NotFoundHandler()}
+	l := New(Options{})
+	l.AppendHTTPServer("http", server)
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, l.Start(ctx))
+	cancel()
+	require.NoError(t, l.Stop(ctx))
+	assert.ErrorIs(t, server.ListenAndServe(), http.ErrServerClosed)
+
+	// A port in use fails the start
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	l = New(Options{})
+	l.AppendHTTPServer("http", &http.Server{Addr: listener.Addr().String()})
+	assert.ErrorContains(t, l.Start(context.Background()), "unable to start http")
+}
@@ -0,0 +1,151 @@
+// Package logging builds the zap loggers services use, so they're all
+// configured the same way: JSON on stdout, which is what Lambda ships to
+// CloudWatch, ISO8601 timestamps, sampling of repeated messages, and a level
+// that can be changed while the service runs.  PHI and PII are redacted
+// from every entry.
+//
+//	logger, err := logging.New("care-team-api", os.Getenv("LOG_LEVEL"), logging.EncodingJSON)
+//	if err != nil {
+//		panic(err)
+//	}
+//	velacontext.SetFallbackLogger(logger)
+package logging
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/seniorlink-vela/cs-common/redact"
+)
+
+// The encodings New accepts.
+const (
+	EncodingJSON    = "json"
+	EncodingConsole = "console"
+)
+
+// DefaultLevel is used when New is given an empty level.
+const DefaultLevel = "info"
+
+// The default sampling, per message per second, which is zap's production
+// setting.
+const (
+	DefaultSamplingInitial    = 100
+	DefaultSamplingThereafter = 100
+)
+
+// The level every logger from New shares, so SetLevel changes them all.
+var level = zap.NewAtomicLevelAt(zap.InfoLevel)
+
+type options struct {
+	output     io.Writer
+	initial    int
+	thereafter int
+	fields     []zap.Field
+}
+
+// Option changes how New builds the logger.
+type Option func(*options)
+
+// WithSampling logs the first initial entries with the same message and
+// level each second, then every thereafter-th one, or none of the rest when
+// thereafter is 0.
+func WithSampling(initial, thereafter int) Option {
+	return func(o *options) {
+		o.initial, o.thereafter = initial, thereafter
+	}
+}
+
+// WithoutSampling logs every entry.
+func WithoutSampling() Option {
+	return WithSampling(0, 0)
+}
+
+// WithOutput writes to w rather than stdout.
+func WithOutput(w io.Writer) Option {
+	return func(o *options) {
+		o.output = w
+	}
+}
+
+// WithFields adds fields to every entry.
+func WithFields(fields ...zap.Field) Option {
+	return func(o *options) {
+		o.fields = append(o.fields, fields...)
+	}
+}
+
+// New returns a logger for the service.  The level is a zap level, like
+// "debug" or "warn", and defaults to DefaultLevel.  It's shared by every
+// logger New returns, and SetLevel changes it.  Entries include the service
+// name, and when running in Lambda, the function name and version.
+func New(serviceName, levelName, encoding string, opts ...Option) (*zap.Logger, error) {
+	o := options{output: os.Stdout, initial: DefaultSamplingInitial, thereafter: DefaultSamplingThereafter}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := SetLevel(levelName); err != nil {
+		return nil, err
+	}
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	var encoder zapcore.Encoder
+	switch encoding {
+	case EncodingJSON, "":
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	case EncodingConsole:
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	default:
+		return nil, fmt.Errorf("logging: unknown encoding %q", encoding)
+	}
+
+	core := zapcore.NewCore(encoder, zapcore.Lock(zapcore.AddSync(o.output)), level)
+	core = redact.NewCore(core)
+	if o.initial > 0 {
+		if o.thereafter <= 0 {
+			// zap divides by it
+			o.thereafter = math.MaxInt32
+		}
+		core = zapcore.NewSamplerWithOptions(core, time.Second, o.initial, o.thereafter)
+	}
+
+	fields := []zap.Field{zap.String("service", serviceName)}
+	if function := os.Getenv("AWS_LAMBDA_FUNCTION_NAME"); function != "" {
+		fields = append(fields, zap.String("function", function), zap.String("function_version", os.Getenv("AWS_LAMBDA_FUNCTION_VERSION")))
+	}
+	fields = append(fields, o.fields...)
+	return zap.New(core, zap.AddCaller(), zap.AddStacktrace(zap.ErrorLevel), zap.ErrorOutput(zapcore.Lock(os.Stderr))).With(fields...), nil
+}
+
+// SetLevel changes the level of every logger from New.  An empty level sets
+// DefaultLevel.
+func SetLevel(levelName string) error {
+	if levelName == "" {
+		levelName = DefaultLevel
+	}
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(levelName)); err != nil {
+		return fmt.Errorf("logging: %w", err)
+	}
+	level.SetLevel(l)
+	return nil
+}
+
+// Level returns the current level.
+func Level() string {
+	return level.String()
+}
+
+// LevelHandler returns a handler that reports the level for GET, and sets it
+// for PUT with a body like {"level":"debug"}.  Keep it off the public routes.
+func LevelHandler() http.Handler {
+	return level
+}
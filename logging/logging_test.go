@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNew(t *testing.T) {
+	defer SetLevel(DefaultLevel)
+	t.Setenv("AWS_LAMBDA_FUNCTION_NAME", "care-team")
+	t.Setenv("AWS_LAMBDA_FUNCTION_VERSION", "12")
+
+	var out bytes.Buffer
+	logger, err := New("care-team-api", "info", EncodingJSON, WithOutput(&out), WithFields(zap.String("env", "test")))
+	require.NoError(t, err)
+	logger.Debug("Hidden")
+	logger.Info("Shown", zap.Int("count", 2), zap.String("email", "a@example.com"))
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, "Shown", entry["msg"])
+	assert.Equal(t, "info", entry["level"])
+	assert.Equal(t, "care-team-api", entry["service"])
+	assert.Equal(t, "care-team", entry["function"])
+	assert.Equal(t, "12", entry["function_version"])
+	assert.Equal(t, "test", entry["env"])
+	assert.Equal(t, float64(2), entry["count"])
+	assert.Equal(t, "[REDACTED]", entry["email"])
+	assert.Regexp(t, `^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{3}`, entry["ts"])
+}
+
+func TestNewErrors(t *testing.T) {
+	defer SetLevel(DefaultLevel)
+	_, err := New("svc", "loud", EncodingJSON)
+	assert.Error(t, err)
+	_, err = New("svc", "info", "xml")
+	assert.Error(t, err)
+}
+
+func TestSetLevel(t *testing.T) {
+	defer SetLevel(DefaultLevel)
+	var out bytes.Buffer
+	logger, err := New("svc", "", EncodingConsole, WithOutput(&out))
+	require.NoError(t, err)
+	assert.Equal(t, "info", Level())
+
+	logger.Debug("first")
+	require.NoError(t, SetLevel("debug"))
+	logger.Debug("second")
+	assert.NotContains(t, out.String(), "first")
+	assert.Contains(t, out.String(), "second")
+
+	assert.Error(t, SetLevel("loud"))
+	assert.Equal(t, "debug", Level())
+}
+
+func TestSampling(t *testing.T) {
+	defer SetLevel(DefaultLevel)
+	var out bytes.Buffer
+	logger, err := New("svc", "info", EncodingJSON, WithOutput(&out), WithSampling(2, 0))
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		logger.Info("Repeated")
+	}
+	assert.Equal(t, 2, strings.Count(out.String(), "Repeated"))
+
+	out.Reset()
+	logger, err = New("svc", "info", EncodingJSON, WithOutput(&out), WithoutSampling())
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		logger.Info("Repeated")
+	}
+	assert.Equal(t, 5, strings.Count(out.String(), "Repeated"))
+}
+
+func TestLevelHandler(t *testing.T) {
+	defer SetLevel(DefaultLevel)
+	w := httptest.NewRecorder()
+	LevelHandler().ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/log-level", strings.NewReader(`{"level":"warn"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "warn", Level())
+}
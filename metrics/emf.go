@@ -0,0 +1,227 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// EMF documents can't have more than 100 metrics, or 100 values per metric.
+const maxEMFValues = 100
+
+// EMFOptions configure an EMF provider.
+type EMFOptions struct {
+	// Namespace is the CloudWatch namespace, like "Vela/CareTeam".
+	Namespace string
+	// Tags are added to every recording, like the service or environment.
+	Tags Tags
+	// Writer defaults to stdout, which Lambda sends to CloudWatch Logs.
+	Writer io.Writer
+}
+
+// EMF is a provider that writes CloudWatch Embedded Metric Format.
+// Recordings are aggregated until Flush, with counters summed and gauges
+// keeping their last value, and written as one document per set of tags.
+type EMF struct {
+	options EMFOptions
+	now     func() time.Time
+
+	lock   sync.Mutex
+	groups map[string]*emfGroup
+	order  []string
+}
+
+type emfGroup struct {
+	tags    Tags
+	metrics map[string]*emfMetric
+	names   []string
+}
+
+type emfMetric struct {
+	unit   Unit
+	values []float64
+	// Counters and gauges have a single value
+	single bool
+}
+
+// NewEMF returns an EMF provider.
+func NewEMF(options EMFOptions) *EMF {
+	if options.Writer == nil {
+		options.Writer = os.Stdout
+	}
+	return &EMF{options: options, now: time.Now, groups: map[string]*emfGroup{}}
+}
+
+// Counter returns a counter.
+func (e *EMF) Counter(def Definition) Counter {
+	if def.Unit == "" {
+		def.Unit = UnitCount
+	}
+	return emfCounter{e, def}
+}
+
+// Gauge returns a gauge.
+func (e *EMF) Gauge(def Definition) Gauge {
+	return emfGauge{e, def}
+}
+
+// Histogram returns a histogram.  Each value is kept, and CloudWatch works
+// out the percentiles.
+func (e *EMF) Histogram(def Definition) Histogram {
+	return emfHistogram{e, def}
+}
+
+type (
+	emfCounter struct {
+		*EMF
+		def Definition
+	}
+	emfGauge struct {
+		*EMF
+		def Definition
+	}
+	emfHistogram struct {
+		*EMF
+		def Definition
+	}
+)
+
+func (c emfCounter) Inc(tags Tags) { c.Add(1, tags) }
+
+func (c emfCounter) Add(delta float64, tags Tags) {
+	c.record(c.def, tags, func(m *emfMetric) { m.single = true; m.add(delta) })
+}
+
+func (g emfGauge) Set(value float64, tags Tags) {
+	g.record(g.def, tags, func(m *emfMetric) { m.single = true; m.values = []float64{value} })
+}
+
+func (g emfGauge) Add(delta float64, tags Tags) {
+	g.record(g.def, tags, func(m *emfMetric) { m.single = true; m.add(delta) })
+}
+
+func (h emfHistogram) Observe(value float64, tags Tags) {
+	h.record(h.def, tags, func(m *emfMetric) { m.values = append(m.values, value) })
+}
+
+func (m *emfMetric) add(delta float64) {
+	if len(m.values) == 0 {
+		m.values = []float64{0}
+	}
+	m.values[0] += delta
+}
+
+func (e *EMF) record(def Definition, tags Tags, update func(*emfMetric)) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	values := def.values(tags)
+	k := key(def.Tags, values)
+	group, ok := e.groups[k]
+	if !ok {
+		group = &emfGroup{tags: Tags{}, metrics: map[string]*emfMetric{}}
+		for i, name := range def.Tags {
+			group.tags[name] = values[i]
+		}
+		e.groups[k] = group
+		e.order = append(e.order, k)
+	}
+	metric, ok := group.metrics[def.Name]
+	if !ok {
+		metric = &emfMetric{unit: def.Unit}
+		if metric.unit == "" {
+			metric.unit = UnitNone
+		}
+		group.metrics[def.Name] = metric
+		group.names = append(group.names, def.Name)
+	}
+	update(metric)
+
+	if len(metric.values) >= maxEMFValues || len(group.metrics) >= maxEMFValues {
+		// Best effort, recording doesn't return errors
+		_ = e.write(group)
+		delete(e.groups, k)
+		e.order = remove(e.order, k)
+	}
+}
+
+// Flush writes what's been recorded since the last flush.
+func (e *EMF) Flush() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	var err error
+	for _, k := range e.order {
+		if writeErr := e.write(e.groups[k]); writeErr != nil && err == nil {
+			err = writeErr
+		}
+	}
+	e.groups, e.order = map[string]*emfGroup{}, nil
+	return err
+}
+
+type emfDocument struct {
+	Timestamp int64          `json:"Timestamp"`
+	Metrics   []emfDirective `json:"CloudWatchMetrics"`
+}
+
+type emfDirective struct {
+	Namespace  string                `json:"Namespace"`
+	Dimensions [][]string            `json:"Dimensions"`
+	Metrics    []emfMetricDefinition `json:"Metrics"`
+}
+
+type emfMetricDefinition struct {
+	Name string `json:"Name"`
+	Unit Unit   `json:"Unit"`
+}
+
+func (e *EMF) write(group *emfGroup) error {
+	doc := map[string]interface{}{}
+	dimensions := []string{}
+	for name, value := range e.options.Tags {
+		doc[name] = value
+		dimensions = append(dimensions, name)
+	}
+	for name, value := range group.tags {
+		doc[name] = value
+		if _, ok := e.options.Tags[name]; !ok {
+			dimensions = append(dimensions, name)
+		}
+	}
+	sort.Strings(dimensions)
+
+	directive := emfDirective{Namespace: e.options.Namespace, Dimensions: [][]string{dimensions}}
+	for _, name := range group.names {
+		metric := group.metrics[name]
+		directive.Metrics = append(directive.Metrics, emfMetricDefinition{Name: name, Unit: metric.unit})
+		if metric.single {
+			doc[name] = metric.values[0]
+		} else {
+			doc[name] = metric.values
+		}
+	}
+	doc["_aws"] = emfDocument{Timestamp: e.now().UnixMilli(), Metrics: []emfDirective{directive}}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("metrics: marshalling EMF: %w", err)
+	}
+	if _, err := e.options.Writer.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("metrics: writing EMF: %w", err)
+	}
+	return nil
+}
+
+func remove(keys []string, k string) []string {
+	for i, key := range keys {
+		if key == k {
+			return append(keys[:i], keys[i+1:]...)
+		}
+	}
+	return keys
+}
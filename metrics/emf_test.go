@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEMF(t *testing.T) {
+	var out bytes.Buffer
+	e := NewEMF(EMFOptions{Namespace: "Vela/Test", Tags: Tags{"service": "care-team"}, Writer: &out})
+	e.now = func() time.Time { return time.UnixMilli(1700000000000) }
+
+	invites := e.Counter(Definition{Name: "invites_sent", Tags: []string{"channel"}})
+	invites.Inc(Tags{"channel": "sms", "user": "dropped"})
+	invites.Add(2, Tags{"channel": "sms"})
+	invites.Inc(Tags{"channel": "email"})
+	depth := e.Gauge(Definition{Name: "queue_depth"})
+	depth.Set(4, nil)
+	depth.Set(7, nil)
+	latency := e.Histogram(Definition{Name: "latency", Unit: UnitMilliseconds})
+	latency.Observe(12, nil)
+	latency.Observe(30, nil)
+	require.NoError(t, e.Flush())
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.JSONEq(t, `{
+		"_aws": {"Timestamp": 1700000000000, "CloudWatchMetrics": [{
+			"Namespace": "Vela/Test",
+			"Dimensions": [["channel", "service"]],
+			"Metrics": [{"Name": "invites_sent", "Unit": "Count"}]
+		}]},
+		"service": "care-team",
+		"channel": "sms",
+		"invites_sent": 3
+	}`, lines[0])
+	assert.Contains(t, lines[1], `"channel":"email"`)
+	assert.JSONEq(t, `{
+		"_aws": {"Timestamp": 1700000000000, "CloudWatchMetrics": [{
+			"Namespace": "Vela/Test",
+			"Dimensions": [["service"]],
+			"Metrics": [{"Name": "queue_depth", "Unit": "None"}, {"Name": "latency", "Unit": "Milliseconds"}]
+		}]},
+		"service": "care-team",
+		"queue_depth": 7,
+		"latency": [12, 30]
+	}`, lines[2])
+
+	// Flushing again has nothing to write
+	out.Reset()
+	require.NoError(t, e.Flush())
+	assert.Empty(t, out.String())
+}
+
+func TestEMFValueLimit(t *testing.T) {
+	var out bytes.Buffer
+	e := NewEMF(EMFOptions{Namespace: "Vela/Test", Writer: &out})
+	latency := e.Histogram(Definition{Name: "latency"})
+	for i := 0; i < maxEMFValues+1; i++ {
+		latency.Observe(float64(i), nil)
+	}
+	// The first 100 were written as soon as there were that many
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &doc))
+	assert.Len(t, doc["latency"], maxEMFValues)
+
+	out.Reset()
+	require.NoError(t, e.Flush())
+	require.NoError(t, json.Unmarshal(out.Bytes(), &doc))
+	assert.Equal(t, []interface{}{float64(maxEMFValues)}, doc["latency"])
+}
+
+func TestDefault(t *testing.T) {
+	defer SetDefault(nil)
+	assert.Equal(t, Nop{}, Default())
+	assert.NoError(t, Flush())
+
+	var out bytes.Buffer
+	SetDefault(NewEMF(EMFOptions{Namespace: "Vela/Test", Writer: &out}))
+	Default().Counter(Definition{Name: "calls"}).Inc(nil)
+	require.NoError(t, Flush())
+	assert.Contains(t, out.String(), `"calls":1`)
+}
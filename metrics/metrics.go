@@ -0,0 +1,152 @@
+// Package metrics records counters, gauges and histograms without tying the
+// code that records them to a backend.  Lambdas use EMF, which writes
+// CloudWatch Embedded Metric Format to stdout, and ECS services use
+// Prometheus, which serves them for scraping.
+//
+//	metrics.SetDefault(metrics.NewEMF(metrics.EMFOptions{Namespace: "Vela/CareTeam"}))
+//	defer metrics.Flush()
+//
+//	requests := metrics.Default().Counter(metrics.Definition{Name: "invites_sent", Tags: []string{"channel"}})
+//	requests.Inc(metrics.Tags{"channel": "sms"})
+package metrics
+
+import (
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Unit is the unit of a metric, as CloudWatch names it.
+type Unit string
+
+// The units metrics are recorded in.
+const (
+	UnitNone         Unit = "None"
+	UnitCount        Unit = "Count"
+	UnitBytes        Unit = "Bytes"
+	UnitMilliseconds Unit = "Milliseconds"
+	UnitSeconds      Unit = "Seconds"
+	UnitPercent      Unit = "Percent"
+)
+
+// Tags are the dimensions, or labels, of a recording.
+type Tags map[string]string
+
+// Definition describes a metric.  Tags lists the tag names recordings may
+// use, and others are dropped, since Prometheus needs them up front.  Keep
+// their values to a small set, like a status rather than a path.
+type Definition struct {
+	Name string
+	Help string
+	// Unit defaults to UnitCount for counters and UnitNone otherwise.
+	Unit Unit
+	Tags []string
+	// Buckets are a Prometheus histogram's upper bounds, which default to
+	// prometheus.DefBuckets.
+	Buckets []float64
+}
+
+// Counter counts things that only go up.
+type Counter interface {
+	Inc(tags Tags)
+	Add(delta float64, tags Tags)
+}
+
+// Gauge records a value that goes up and down.
+type Gauge interface {
+	Set(value float64, tags Tags)
+	Add(delta float64, tags Tags)
+}
+
+// Histogram records the distribution of values, like latencies.
+type Histogram interface {
+	Observe(value float64, tags Tags)
+}
+
+// Provider creates metrics for a backend.  Asking for the same name again
+// returns the same metric, so code can ask where it records.
+type Provider interface {
+	Counter(def Definition) Counter
+	Gauge(def Definition) Gauge
+	Histogram(def Definition) Histogram
+}
+
+// Flusher is implemented by providers that buffer, like EMF.
+type Flusher interface {
+	Flush() error
+}
+
+type holder struct {
+	Provider
+}
+
+// The provider Default returns, a no-op one until SetDefault installs
+// another.
+var defaultProvider atomic.Pointer[holder]
+
+func init() {
+	defaultProvider.Store(&holder{Nop{}})
+}
+
+// SetDefault installs the provider the common packages record with.  Passing
+// nil restores the no-op provider.
+func SetDefault(p Provider) {
+	if p == nil {
+		p = Nop{}
+	}
+	defaultProvider.Store(&holder{p})
+}
+
+// Default returns the provider installed by SetDefault.
+func Default() Provider {
+	return defaultProvider.Load().Provider
+}
+
+// Flush flushes the default provider, when it buffers.  Lambdas should call
+// it before each invocation returns.
+func Flush() error {
+	if f, ok := Default().(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Milliseconds returns the time since start, in milliseconds.
+func Milliseconds(start time.Time) float64 {
+	return float64(time.Since(start)) / float64(time.Millisecond)
+}
+
+// Nop is a provider that records nothing.
+type Nop struct{}
+
+func (Nop) Counter(Definition) Counter     { return nop{} }
+func (Nop) Gauge(Definition) Gauge         { return nop{} }
+func (Nop) Histogram(Definition) Histogram { return nop{} }
+
+type nop struct{}
+
+func (nop) Inc(Tags)              {}
+func (nop) Add(float64, Tags)     {}
+func (nop) Set(float64, Tags)     {}
+func (nop) Observe(float64, Tags) {}
+
+// values returns the values of the definition's tags, in its order, with
+// missing ones empty.
+func (d Definition) values(tags Tags) []string {
+	values := make([]string, len(d.Tags))
+	for i, name := range d.Tags {
+		values[i] = tags[name]
+	}
+	return values
+}
+
+// key identifies a set of tags.
+func key(names, values []string) string {
+	pairs := make([]string, len(names))
+	for i := range names {
+		pairs[i] = names[i] + "\x00" + values[i]
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "\x01")
+}
@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Prometheus is a provider backed by a Prometheus registry, which Handler
+// serves for scraping.  The registry also has the Go runtime and process
+// collectors.
+type Prometheus struct {
+	namespace string
+	registry  *prometheus.Registry
+
+	lock       sync.Mutex
+	collectors map[string]prometheus.Collector
+}
+
+// NewPrometheus returns a provider whose metric names are prefixed with the
+// namespace, like "care_team".
+func NewPrometheus(namespace string) *Prometheus {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	return &Prometheus{namespace: namespace, registry: registry, collectors: map[string]prometheus.Collector{}}
+}
+
+// Registry returns the registry, to register other collectors with.
+func (p *Prometheus) Registry() *prometheus.Registry {
+	return p.registry
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (p *Prometheus) Handler() http.Handler {
+	return promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{})
+}
+
+// Counter returns a counter.
+func (p *Prometheus) Counter(def Definition) Counter {
+	vec := p.collector(def, func() prometheus.Collector {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: p.namespace, Name: def.Name, Help: help(def)}, def.Tags)
+	}).(*prometheus.CounterVec)
+	return promCounter{vec, def}
+}
+
+// Gauge returns a gauge.
+func (p *Prometheus) Gauge(def Definition) Gauge {
+	vec := p.collector(def, func() prometheus.Collector {
+		return prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: p.namespace, Name: def.Name, Help: help(def)}, def.Tags)
+	}).(*prometheus.GaugeVec)
+	return promGauge{vec, def}
+}
+
+// Histogram returns a histogram.
+func (p *Prometheus) Histogram(def Definition) Histogram {
+	vec := p.collector(def, func() prometheus.Collector {
+		return prometheus.NewHistogramVec(prometheus.HistogramOpts{Namespace: p.namespace, Name: def.Name, Help: help(def), Buckets: def.Buckets}, def.Tags)
+	}).(*prometheus.HistogramVec)
+	return promHistogram{vec, def}
+}
+
+// collector returns the collector for the metric, creating and registering it
+// the first time.  Asking for a name with a different kind panics, as
+// registering it twice would.
+func (p *Prometheus) collector(def Definition, create func() prometheus.Collector) prometheus.Collector {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if c, ok := p.collectors[def.Name]; ok {
+		return c
+	}
+	c := create()
+	p.registry.MustRegister(c)
+	p.collectors[def.Name] = c
+	return c
+}
+
+// Prometheus requires help text
+func help(def Definition) string {
+	if def.Help != "" {
+		return def.Help
+	}
+	return def.Name
+}
+
+type (
+	promCounter struct {
+		vec *prometheus.CounterVec
+		def Definition
+	}
+	promGauge struct {
+		vec *prometheus.GaugeVec
+		def Definition
+	}
+	promHistogram struct {
+		vec *prometheus.HistogramVec
+		def Definition
+	}
+)
+
+func (c promCounter) Inc(tags Tags) { c.Add(1, tags) }
+
+func (c promCounter) Add(delta float64, tags Tags) {
+	c.vec.WithLabelValues(c.def.values(tags)...).Add(delta)
+}
+
+func (g promGauge) Set(value float64, tags Tags) {
+	g.vec.WithLabelValues(g.def.values(tags)...).Set(value)
+}
+
+func (g promGauge) Add(delta float64, tags Tags) {
+	g.vec.WithLabelValues(g.def.values(tags)...).Add(delta)
+}
+
+func (h promHistogram) Observe(value float64, tags Tags) {
+	h.vec.WithLabelValues(h.def.values(tags)...).Observe(value)
+}
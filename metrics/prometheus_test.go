@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheus(t *testing.T) {
+	p := NewPrometheus("care_team")
+	invites := Definition{Name: "invites_sent", Help: "Invites sent.", Tags: []string{"channel"}}
+	p.Counter(invites).Inc(Tags{"channel": "sms"})
+	// Asking again returns the same counter
+	p.Counter(invites).Add(2, Tags{"channel": "sms"})
+	p.Gauge(Definition{Name: "queue_depth"}).Set(4, nil)
+	p.Histogram(Definition{Name: "latency_seconds", Buckets: []float64{0.1, 1}}).Observe(0.5, nil)
+
+	w := httptest.NewRecorder()
+	p.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(w.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "# HELP care_team_invites_sent Invites sent.")
+	assert.Contains(t, string(body), `care_team_invites_sent{channel="sms"} 3`)
+	assert.Contains(t, string(body), "care_team_queue_depth 4")
+	assert.Contains(t, string(body), `care_team_latency_seconds_bucket{le="1"} 1`)
+	assert.Contains(t, string(body), "go_goroutines")
+}
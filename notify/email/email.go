@@ -0,0 +1,243 @@
+// Package email sends email through SES, rendered from templates in the
+// recipient's language.
+//
+//	//go:embed templates
+//	var files embed.FS
+//
+//	sub, _ := fs.Sub(files, "templates")
+//	templates, err := email.LoadTemplates(sub, "en")
+//	sender, err := email.New(email.Options{Client: sesv2.NewFromConfig(cfg), From: "Vela <no-reply@example.com>", Templates: templates})
+//	_, err = sender.Send(ctx, email.Message{To: []string{address}, Template: "invitation", Locale: "es", Data: invite})
+//
+// Addresses on the account's suppression list, after bouncing or
+// complaining, aren't sent to.
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/retry"
+)
+
+// DefaultRate is how many messages a second a sender sends when the options
+// don't say, below SES' starting production quota of 14.
+const DefaultRate = 10
+
+// ErrSuppressed is returned when every recipient is on the suppression list.
+var ErrSuppressed = errors.New("email: the recipients are suppressed")
+
+// API is the part of the SES v2 client used to send email.  *sesv2.Client
+// implements it.
+type API interface {
+	SendEmail(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error)
+	GetSuppressedDestination(ctx context.Context, params *sesv2.GetSuppressedDestinationInput, optFns ...func(*sesv2.Options)) (*sesv2.GetSuppressedDestinationOutput, error)
+	PutSuppressedDestination(ctx context.Context, params *sesv2.PutSuppressedDestinationInput, optFns ...func(*sesv2.Options)) (*sesv2.PutSuppressedDestinationOutput, error)
+}
+
+var _ API = (*sesv2.Client)(nil)
+
+// Options configures a sender.
+type Options struct {
+	Client    API
+	Templates *Templates
+	// From is the sender, like "Vela <no-reply@example.com>".
+	From    string
+	ReplyTo []string
+	// ConfigurationSet is the SES configuration set, for bounce and
+	// delivery events.
+	ConfigurationSet string
+	// Rate is the most messages sent a second, across the sender's
+	// goroutines, and defaults to DefaultRate.  Send waits for its turn.
+	Rate float64
+	// SkipSuppressionCheck sends without checking the suppression list,
+	// leaving SES to drop suppressed recipients without saying.
+	SkipSuppressionCheck bool
+	// DryRun logs messages rather than sending them, for development and
+	// test environments.
+	DryRun bool
+}
+
+// Sender sends email.
+type Sender struct {
+	options Options
+	limiter *rate.Limiter
+}
+
+// New returns a sender.  The client isn't needed for a dry run.
+func New(options Options) (*Sender, error) {
+	if options.Templates == nil || options.From == "" {
+		return nil, errors.New("email: templates and a from address are required")
+	}
+	if options.Client == nil && !options.DryRun {
+		return nil, errors.New("email: a client is required")
+	}
+	if options.Rate <= 0 {
+		options.Rate = DefaultRate
+	}
+	return &Sender{options: options, limiter: rate.NewLimiter(rate.Limit(options.Rate), 1)}, nil
+}
+
+// Message is an email to send.
+type Message struct {
+	To []string
+	Cc []string
+	// Template is rendered in Locale, or the one it falls back to, with
+	// Data.
+	Template string
+	Locale   string
+	Data     any
+	// Tags are SES message tags, which are in its events.
+	Tags map[string]string
+}
+
+// Only throttling is retried, since SES didn't take the email then.  After a
+// timeout or server error it may have, and retrying would send it twice, so
+// the SDK's own retries are turned off for SendEmail too.
+var sendPolicy = retry.Policy{MaxAttempts: 3, Retryable: retry.AWSThrottle}
+
+func singleAttempt(o *sesv2.Options) {
+	o.RetryMaxAttempts = 1
+}
+
+// Send renders and sends the message, returning SES' message ID.
+// Recipients on the suppression list are left out, and when that's all of
+// them, it returns ErrSuppressed.
+func (s *Sender) Send(ctx context.Context, msg Message) (string, error) {
+	rendered, err := s.options.Templates.Render(msg.Template, msg.Locale, msg.Data)
+	if err != nil {
+		return "", err
+	}
+	logger := velacontext.GetContextLogger(ctx).With(zap.String("template", msg.Template), zap.String("locale", rendered.Locale))
+	if s.options.DryRun {
+		logger.Info("Not sending email in a dry run",
+			zap.Strings("to", msg.To),
+			zap.Strings("cc", msg.Cc),
+			zap.String("subject", rendered.Subject),
+			zap.String("text", rendered.Text),
+			zap.String("html", rendered.HTML),
+		)
+		return "", nil
+	}
+
+	to, err := s.unsuppressed(ctx, msg.To)
+	if err != nil {
+		return "", err
+	}
+	cc, err := s.unsuppressed(ctx, msg.Cc)
+	if err != nil {
+		return "", err
+	}
+	if len(to) == 0 && len(cc) == 0 {
+		return "", ErrSuppressed
+	}
+
+	input := &sesv2.SendEmailInput{
+		FromEmailAddress: aws.String(s.options.From),
+		ReplyToAddresses: s.options.ReplyTo,
+		Destination:      &types.Destination{ToAddresses: to, CcAddresses: cc},
+		Content: &types.EmailContent{Simple: &types.Message{
+			Subject: content(rendered.Subject),
+			Body:    &types.Body{Text: content(rendered.Text)},
+		}},
+	}
+	if rendered.HTML != "" {
+		input.Content.Simple.Body.Html = content(rendered.HTML)
+	}
+	if s.options.ConfigurationSet != "" {
+		input.ConfigurationSetName = aws.String(s.options.ConfigurationSet)
+	}
+	for name, value := range msg.Tags {
+		input.EmailTags = append(input.EmailTags, types.MessageTag{Name: aws.String(name), Value: aws.String(value)})
+	}
+
+	var messageID string
+	err = retry.Do(ctx, sendPolicy, func(ctx context.Context) error {
+		if err := s.limiter.Wait(ctx); err != nil {
+			return retry.Permanent(err)
+		}
+		out, err := s.options.Client.SendEmail(ctx, input, singleAttempt)
+		if err != nil {
+			return err
+		}
+		messageID = aws.ToString(out.MessageId)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("email: unable to send %s: %w", msg.Template, err)
+	}
+	logger.Info("Sent email", zap.String("message_id", messageID), zap.Int("recipients", len(to)+len(cc)))
+	return messageID, nil
+}
+
+func content(s string) *types.Content {
+	return &types.Content{Data: aws.String(s), Charset: aws.String("UTF-8")}
+}
+
+// unsuppressed returns the addresses that aren't on the suppression list.
+func (s *Sender) unsuppressed(ctx context.Context, addresses []string) ([]string, error) {
+	if s.options.SkipSuppressionCheck {
+		return addresses, nil
+	}
+	var allowed []string
+	for _, address := range addresses {
+		suppressed, err := s.Suppressed(ctx, address)
+		if err != nil {
+			return nil, err
+		}
+		if suppressed {
+			velacontext.GetContextLogger(ctx).Info("Not emailing a suppressed address", zap.String("email", address))
+			continue
+		}
+		allowed = append(allowed, address)
+	}
+	return allowed, nil
+}
+
+// Suppressed reports whether the address is on the account's suppression
+// list.
+func (s *Sender) Suppressed(ctx context.Context, address string) (bool, error) {
+	_, err := s.options.Client.GetSuppressedDestination(ctx, &sesv2.GetSuppressedDestinationInput{
+		EmailAddress: aws.String(bareAddress(address)),
+	})
+	var notFound *types.NotFoundException
+	if errors.As(err, &notFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("email: unable to check the suppression list: %w", err)
+	}
+	return true, nil
+}
+
+// Suppress adds the address to the suppression list, for addresses that
+// bounce or complain outside SES' own tracking, like someone asking not to
+// be emailed.
+func (s *Sender) Suppress(ctx context.Context, address string, reason types.SuppressionListReason) error {
+	_, err := s.options.Client.PutSuppressedDestination(ctx, &sesv2.PutSuppressedDestinationInput{
+		EmailAddress: aws.String(bareAddress(address)),
+		Reason:       reason,
+	})
+	if err != nil {
+		return fmt.Errorf("email: unable to suppress an address: %w", err)
+	}
+	return nil
+}
+
+// bareAddress strips the name from "Name <address>".
+func bareAddress(address string) string {
+	if parsed, err := mail.ParseAddress(address); err == nil {
+		return parsed.Address
+	}
+	return strings.TrimSpace(address)
+}
@@ -0,0 +1,158 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+type fakeSES struct {
+	mu         sync.Mutex
+	sent       []*sesv2.SendEmailInput
+	suppressed map[string]bool
+	sendErr    error
+	attempts   int
+	options    sesv2.Options
+}
+
+func (f *fakeSES) SendEmail(_ context.Context, in *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts++
+	for _, fn := range optFns {
+		fn(&f.options)
+	}
+	if f.sendErr != nil {
+		return nil, f.sendErr
+	}
+	f.sent = append(f.sent, in)
+	return &sesv2.SendEmailOutput{MessageId: aws.String("msg-1")}, nil
+}
+
+func (f *fakeSES) GetSuppressedDestination(_ context.Context, in *sesv2.GetSuppressedDestinationInput, _ ...func(*sesv2.Options)) (*sesv2.GetSuppressedDestinationOutput, error) {
+	if !f.suppressed[*in.EmailAddress] {
+		return nil, &types.NotFoundException{}
+	}
+	return &sesv2.GetSuppressedDestinationOutput{}, nil
+}
+
+func (f *fakeSES) PutSuppressedDestination(_ context.Context, in *sesv2.PutSuppressedDestinationInput, _ ...func(*sesv2.Options)) (*sesv2.PutSuppressedDestinationOutput, error) {
+	f.suppressed[*in.EmailAddress] = true
+	return &sesv2.PutSuppressedDestinationOutput{}, nil
+}
+
+func newSender(t *testing.T, client API, options Options) *Sender {
+	templates, err := LoadTemplates(templateFiles, "en")
+	require.NoError(t, err)
+	options.Client, options.Templates, options.From = client, templates, "Vela <no-reply@example.com>"
+	sender, err := New(options)
+	require.NoError(t, err)
+	return sender
+}
+
+var data = invite{Name: "Ana", Inviter: "Dr. Ruiz", Link: "https://example.com/i/1"}
+
+func TestSend(t *testing.T) {
+	client := &fakeSES{suppressed: map[string]bool{"bounced@example.com": true}}
+	sender := newSender(t, client, Options{ConfigurationSet: "transactional"})
+
+	id, err := sender.Send(context.Background(), Message{
+		To:       []string{"Ana <ana@example.com>", "Bounced <bounced@example.com>"},
+		Template: "invitation",
+		Data:     data,
+		Tags:     map[string]string{"flow": "invitation"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "msg-1", id)
+
+	require.Len(t, client.sent, 1)
+	sent := client.sent[0]
+	assert.Equal(t, []string{"Ana <ana@example.com>"}, sent.Destination.ToAddresses)
+	assert.Equal(t, "Vela <no-reply@example.com>", *sent.FromEmailAddress)
+	assert.Equal(t, "transactional", *sent.ConfigurationSetName)
+	assert.Equal(t, "Dr. Ruiz invited you to Vela", *sent.Content.Simple.Subject.Data)
+	assert.Contains(t, *sent.Content.Simple.Body.Html.Data, "<a href")
+	assert.Equal(t, "flow", *sent.EmailTags[0].Name)
+}
+
+func TestSendSuppressed(t *testing.T) {
+	client := &fakeSES{suppressed: map[string]bool{}}
+	sender := newSender(t, client, Options{})
+	require.NoError(t, sender.Suppress(context.Background(), "Ana <ana@example.com>", types.SuppressionListReasonComplaint))
+
+	_, err := sender.Send(context.Background(), Message{To: []string{"ana@example.com"}, Template: "welcome", Data: data})
+	assert.ErrorIs(t, err, ErrSuppressed)
+	assert.Empty(t, client.sent)
+}
+
+func TestSendFailure(t *testing.T) {
+	client := &fakeSES{suppressed: map[string]bool{}, sendErr: &types.MessageRejected{Message: aws.String("Email address is not verified.")}}
+	sender := newSender(t, client, Options{})
+	_, err := sender.Send(context.Background(), Message{To: []string{"ana@example.com"}, Template: "welcome", Data: data})
+	var rejected *types.MessageRejected
+	assert.ErrorAs(t, err, &rejected)
+	assert.Equal(t, 1, client.attempts)
+	assert.Equal(t, 1, client.options.RetryMaxAttempts)
+
+	// Timeouts aren't retried either, since the email may have gone
+	client = &fakeSES{suppressed: map[string]bool{}, sendErr: &net.DNSError{Err: "timeout", IsTimeout: true}}
+	_, err = newSender(t, client, Options{}).Send(context.Background(), Message{To: []string{"ana@example.com"}, Template: "welcome", Data: data})
+	assert.Error(t, err)
+	assert.Equal(t, 1, client.attempts)
+
+	// Throttling is
+	client = &fakeSES{suppressed: map[string]bool{}, sendErr: &types.TooManyRequestsException{Message: aws.String("slow down")}}
+	_, err = newSender(t, client, Options{Rate: 1000}).Send(context.Background(), Message{To: []string{"ana@example.com"}, Template: "welcome", Data: data})
+	assert.Error(t, err)
+	assert.Equal(t, 3, client.attempts)
+
+	_, err = sender.Send(context.Background(), Message{To: []string{"ana@example.com"}, Template: "missing"})
+	assert.ErrorIs(t, err, ErrNoTemplate)
+}
+
+func TestSendDryRun(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	ctx := velacontext.ContextWithLogger(context.Background(), zap.New(core))
+	templates, err := LoadTemplates(templateFiles, "en")
+	require.NoError(t, err)
+	sender, err := New(Options{Templates: templates, From: "no-reply@example.com", DryRun: true})
+	require.NoError(t, err)
+
+	_, err = sender.Send(ctx, Message{To: []string{"ana@example.com"}, Template: "invitation", Locale: "es", Data: data})
+	require.NoError(t, err)
+	entries := logs.FilterMessage("Not sending email in a dry run").All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "Dr. Ruiz te invitó a Vela", entries[0].ContextMap()["subject"])
+	assert.Equal(t, "es", entries[0].ContextMap()["locale"])
+}
+
+func TestSendRate(t *testing.T) {
+	client := &fakeSES{suppressed: map[string]bool{}}
+	sender := newSender(t, client, Options{Rate: 50, SkipSuppressionCheck: true})
+	start := time.Now()
+	for range 5 {
+		_, err := sender.Send(context.Background(), Message{To: []string{"ana@example.com"}, Template: "welcome", Data: data})
+		require.NoError(t, err)
+	}
+	// The first is immediate, then one every 20ms
+	assert.GreaterOrEqual(t, time.Since(start), 75*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := sender.Send(ctx, Message{To: []string{"ana@example.com"}, Template: "welcome", Data: data})
+	assert.True(t, errors.Is(err, context.Canceled))
+}
@@ -0,0 +1,150 @@
+package email
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/seniorlink-vela/cs-common/notify/internal/locale"
+)
+
+// DefaultLocale is the locale templates fall back to when LoadTemplates
+// isn't given one.
+const DefaultLocale = "en"
+
+// ErrNoTemplate is returned for templates that don't exist in the locale or
+// any it falls back to.
+var ErrNoTemplate = errors.New("email: no such template")
+
+// Templates are messages' subjects and bodies, by name and locale.
+type Templates struct {
+	templates *locale.Templates[*template]
+}
+
+type template struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// LoadTemplates loads the templates in fsys, which has a directory for each
+// locale, holding a message's subject, text body and, optionally, HTML body:
+//
+//	en/invitation.subject.txt
+//	en/invitation.txt
+//	en/invitation.html
+//	es/invitation.subject.txt
+//	...
+//
+// Subjects and text bodies are text/template, and HTML bodies html/template,
+// which escapes the data.  Templates missing from a locale fall back to the
+// language, so "es-MX" uses "es", then to the default locale.
+func LoadTemplates(fsys fs.FS, defaultLocale string) (*Templates, error) {
+	if defaultLocale == "" {
+		defaultLocale = DefaultLocale
+	}
+	templates, err := locale.Load(fsys, defaultLocale, loadLocale)
+	if err != nil {
+		return nil, fmt.Errorf("email: %w", err)
+	}
+	return &Templates{templates: templates}, nil
+}
+
+// loadLocale loads a locale's directory, checking each message has a subject
+// and a text body.
+func loadLocale(fsys fs.FS, dir string) (map[string]*template, error) {
+	files, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read templates: %w", err)
+	}
+	named := map[string]*template{}
+	for _, file := range files {
+		name, kind := splitName(file.Name())
+		if file.IsDir() || kind == "" {
+			continue
+		}
+		contents, err := fs.ReadFile(fsys, path.Join(dir, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s/%s: %w", dir, file.Name(), err)
+		}
+		tmpl := named[name]
+		if tmpl == nil {
+			tmpl = &template{}
+			named[name] = tmpl
+		}
+		id := dir + "/" + file.Name()
+		switch kind {
+		case "subject":
+			// Subjects are one line
+			tmpl.subject, err = texttemplate.New(id).Option("missingkey=error").Parse(strings.TrimSpace(string(contents)))
+		case "text":
+			tmpl.text, err = texttemplate.New(id).Option("missingkey=error").Parse(string(contents))
+		case "html":
+			tmpl.html, err = htmltemplate.New(id).Option("missingkey=error").Parse(string(contents))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+	}
+	for name, tmpl := range named {
+		if tmpl.subject == nil || tmpl.text == nil {
+			return nil, fmt.Errorf("%s/%s needs a subject and a text body", dir, name)
+		}
+	}
+	return named, nil
+}
+
+// splitName splits a file name into the template's name and what part of it
+// the file is, which is empty for files that aren't templates.
+func splitName(file string) (name, kind string) {
+	switch {
+	case strings.HasSuffix(file, ".subject.txt"):
+		return strings.TrimSuffix(file, ".subject.txt"), "subject"
+	case strings.HasSuffix(file, ".txt"):
+		return strings.TrimSuffix(file, ".txt"), "text"
+	case strings.HasSuffix(file, ".html"):
+		return strings.TrimSuffix(file, ".html"), "html"
+	}
+	return "", ""
+}
+
+// Rendered is a rendered message.  HTML is empty for templates without an
+// HTML body.
+type Rendered struct {
+	Locale  string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Render renders the template for the locale, or the one it falls back to.
+func (t *Templates) Render(name, locale string, data any) (Rendered, error) {
+	tmpl, found, ok := t.templates.Find(name, locale)
+	if !ok {
+		return Rendered{}, fmt.Errorf("%w: %s", ErrNoTemplate, name)
+	}
+	rendered := Rendered{Locale: found}
+	var buf bytes.Buffer
+	if err := tmpl.subject.Execute(&buf, data); err != nil {
+		return Rendered{}, fmt.Errorf("email: rendering %s: %w", name, err)
+	}
+	rendered.Subject = buf.String()
+	buf.Reset()
+	if err := tmpl.text.Execute(&buf, data); err != nil {
+		return Rendered{}, fmt.Errorf("email: rendering %s: %w", name, err)
+	}
+	rendered.Text = buf.String()
+	if tmpl.html != nil {
+		buf.Reset()
+		if err := tmpl.html.Execute(&buf, data); err != nil {
+			return Rendered{}, fmt.Errorf("email: rendering %s: %w", name, err)
+		}
+		rendered.HTML = buf.String()
+	}
+	return rendered, nil
+}
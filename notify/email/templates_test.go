@@ -0,0 +1,58 @@
+package email
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var templateFiles = fstest.MapFS{
+	"en/invitation.subject.txt": {Data: []byte("{{.Inviter}} invited you to Vela\n")},
+	"en/invitation.txt":         {Data: []byte("Hi {{.Name}}, join here: {{.Link}}")},
+	"en/invitation.html":        {Data: []byte(`<p>Hi {{.Name}}, <a href="{{.Link}}">join here</a></p>`)},
+	"en/welcome.subject.txt":    {Data: []byte("Welcome")},
+	"en/welcome.txt":            {Data: []byte("Welcome, {{.Name}}")},
+	"es/invitation.subject.txt": {Data: []byte("{{.Inviter}} te invitó a Vela")},
+	"es/invitation.txt":         {Data: []byte("Hola {{.Name}}")},
+	"README.md":                 {Data: []byte("templates")},
+}
+
+type invite struct {
+	Name, Inviter, Link string
+}
+
+func TestRender(t *testing.T) {
+	templates, err := LoadTemplates(templateFiles, "")
+	require.NoError(t, err)
+	data := invite{Name: "Ana <script>", Inviter: "Dr. Ruiz", Link: "https://example.com/i/1"}
+
+	rendered, err := templates.Render("invitation", "en", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Dr. Ruiz invited you to Vela", rendered.Subject)
+	assert.Equal(t, "Hi Ana <script>, join here: https://example.com/i/1", rendered.Text)
+	assert.Equal(t, `<p>Hi Ana &lt;script&gt;, <a href="https://example.com/i/1">join here</a></p>`, rendered.HTML)
+
+	rendered, err = templates.Render("invitation", "es_MX", data)
+	require.NoError(t, err)
+	assert.Equal(t, "es", rendered.Locale)
+	assert.Equal(t, "Dr. Ruiz te invitó a Vela", rendered.Subject)
+	assert.Empty(t, rendered.HTML)
+
+	rendered, err = templates.Render("welcome", "es", data)
+	require.NoError(t, err)
+	assert.Equal(t, "en", rendered.Locale)
+
+	_, err = templates.Render("goodbye", "en", data)
+	assert.ErrorIs(t, err, ErrNoTemplate)
+	_, err = templates.Render("invitation", "en", map[string]string{"Name": "Ana"})
+	assert.ErrorContains(t, err, "rendering invitation")
+}
+
+func TestLoadTemplatesIncomplete(t *testing.T) {
+	_, err := LoadTemplates(fstest.MapFS{"en/reminder.html": {Data: []byte("<p>Reminder</p>")}}, "en")
+	assert.ErrorContains(t, err, "en/reminder needs a subject and a text body")
+	_, err = LoadTemplates(fstest.MapFS{"en/reminder.subject.txt": {Data: []byte("{{.Broken")}}, "en")
+	assert.ErrorContains(t, err, "invalid template")
+}
@@ -0,0 +1,71 @@
+// Package locale holds notify's templates by locale, for the email and SMS
+// senders, which both keep a directory of templates per locale and fall back
+// from "es-MX" to "es", then to a default.
+package locale
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// Templates are a sender's templates, by locale and name.
+type Templates[T any] struct {
+	defaultLocale string
+	// by locale, then name
+	templates map[string]map[string]T
+}
+
+// Load calls load for each directory in fsys, which is named for its locale,
+// and keeps the templates it returns, by name.  Files at the top of fsys,
+// like a README, are skipped.
+func Load[T any](fsys fs.FS, defaultLocale string, load func(fsys fs.FS, dir string) (map[string]T, error)) (*Templates[T], error) {
+	t := &Templates[T]{defaultLocale: Normalize(defaultLocale), templates: map[string]map[string]T{}}
+	dirs, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("unable to read templates: %w", err)
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		named, err := load(fsys, dir.Name())
+		if err != nil {
+			return nil, err
+		}
+		t.templates[Normalize(dir.Name())] = named
+	}
+	return t, nil
+}
+
+// Find returns the template for the locale, or the one it falls back to,
+// and the locale it was found in.
+func (t *Templates[T]) Find(name, locale string) (T, string, bool) {
+	for _, candidate := range Fallbacks(locale, t.defaultLocale) {
+		if tmpl, ok := t.templates[candidate][name]; ok {
+			return tmpl, candidate, true
+		}
+	}
+	var zero T
+	return zero, "", false
+}
+
+// Normalize lower cases a locale and uses "-" between its parts, so "es_MX"
+// and "es-mx" are the same.
+func Normalize(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+}
+
+// Fallbacks is the locales to try for locale, in order: the locale, its
+// language, then the default.
+func Fallbacks(locale, defaultLocale string) []string {
+	locale = Normalize(locale)
+	var locales []string
+	if locale != "" {
+		locales = append(locales, locale)
+		if language, _, ok := strings.Cut(locale, "-"); ok {
+			locales = append(locales, language)
+		}
+	}
+	return append(locales, Normalize(defaultLocale))
+}
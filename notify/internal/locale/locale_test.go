@@ -0,0 +1,63 @@
+package locale
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFallbacks(t *testing.T) {
+	assert.Equal(t, []string{"es-mx", "es", "en"}, Fallbacks("es_MX", "EN"))
+	assert.Equal(t, []string{"fr", "en"}, Fallbacks("fr", "en"))
+	assert.Equal(t, []string{"en"}, Fallbacks("", "en"))
+}
+
+func TestFind(t *testing.T) {
+	files := fstest.MapFS{
+		"en/a.txt":    {Data: []byte("en a")},
+		"en/b.txt":    {Data: []byte("en b")},
+		"es_MX/a.txt": {Data: []byte("es-mx a")},
+		"README.md":   {Data: []byte("templates")},
+	}
+	templates, err := Load(files, "en", func(fsys fs.FS, dir string) (map[string]string, error) {
+		entries, err := fs.ReadDir(fsys, dir)
+		if err != nil {
+			return nil, err
+		}
+		named := map[string]string{}
+		for _, entry := range entries {
+			contents, err := fs.ReadFile(fsys, dir+"/"+entry.Name())
+			if err != nil {
+				return nil, err
+			}
+			named[entry.Name()] = string(contents)
+		}
+		return named, nil
+	})
+	require.NoError(t, err)
+
+	tmpl, locale, ok := templates.Find("a.txt", "es-mx")
+	assert.True(t, ok)
+	assert.Equal(t, "es-mx a", tmpl)
+	assert.Equal(t, "es-mx", locale)
+
+	tmpl, locale, ok = templates.Find("b.txt", "es-MX")
+	assert.True(t, ok)
+	assert.Equal(t, "en b", tmpl)
+	assert.Equal(t, "en", locale)
+
+	_, _, ok = templates.Find("c.txt", "en")
+	assert.False(t, ok)
+}
+
+func TestLoadError(t *testing.T) {
+	failed := errors.New("failed")
+	_, err := Load(fstest.MapFS{"en/a.txt": {}}, "en", func(fs.FS, string) (map[string]int, error) {
+		return nil, failed
+	})
+	assert.ErrorIs(t, err, failed)
+}
@@ -0,0 +1,134 @@
+package sms
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSNS struct {
+	published []*sns.PublishInput
+	optedOut  map[string]bool
+	// errs are returned by the first publishes
+	errs    []error
+	options sns.Options
+}
+
+func (f *fakeSNS) Publish(_ context.Context, in *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	f.published = append(f.published, in)
+	for _, fn := range optFns {
+		fn(&f.options)
+	}
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	return &sns.PublishOutput{MessageId: aws.String("sns-1")}, nil
+}
+
+func (f *fakeSNS) CheckIfPhoneNumberIsOptedOut(_ context.Context, in *sns.CheckIfPhoneNumberIsOptedOutInput, _ ...func(*sns.Options)) (*sns.CheckIfPhoneNumberIsOptedOutOutput, error) {
+	return &sns.CheckIfPhoneNumberIsOptedOutOutput{IsOptedOut: f.optedOut[*in.PhoneNumber]}, nil
+}
+
+func (f *fakeSNS) OptInPhoneNumber(_ context.Context, in *sns.OptInPhoneNumberInput, _ ...func(*sns.Options)) (*sns.OptInPhoneNumberOutput, error) {
+	delete(f.optedOut, *in.PhoneNumber)
+	return &sns.OptInPhoneNumberOutput{}, nil
+}
+
+func TestSNS(t *testing.T) {
+	client := &fakeSNS{optedOut: map[string]bool{"+16175550199": true}}
+	id, err := NewSNS(client, SNSOptions{OriginationNumber: "+18005550100"}).Send(context.Background(), "+16175550123", "Hi")
+	require.NoError(t, err)
+	assert.Equal(t, "sns-1", id)
+	in := client.published[0]
+	assert.Equal(t, "+16175550123", *in.PhoneNumber)
+	assert.Equal(t, "Transactional", *in.MessageAttributes["AWS.SNS.SMS.SMSType"].StringValue)
+	assert.Equal(t, "+18005550100", *in.MessageAttributes["AWS.MM.SMS.OriginationNumber"].StringValue)
+	assert.NotContains(t, in.MessageAttributes, "AWS.SNS.SMS.SenderID")
+	assert.Equal(t, 1, client.options.RetryMaxAttempts)
+
+	optOuts := SNSOptOuts{Client: client}
+	optedOut, err := optOuts.OptedOut(context.Background(), "+16175550199")
+	require.NoError(t, err)
+	assert.True(t, optedOut)
+	require.NoError(t, optOuts.OptIn(context.Background(), "+16175550199"))
+	optedOut, _ = optOuts.OptedOut(context.Background(), "+16175550199")
+	assert.False(t, optedOut)
+}
+
+func TestSNSRetries(t *testing.T) {
+	// Throttling is retried
+	client := &fakeSNS{errs: []error{&smithy.GenericAPIError{Code: "Throttling"}}}
+	_, err := NewSNS(client, SNSOptions{}).Send(context.Background(), "+16175550123", "Hi")
+	require.NoError(t, err)
+	assert.Len(t, client.published, 2)
+
+	// Timeouts and server errors aren't, since the text may have gone
+	for _, sendErr := range []error{
+		&net.DNSError{Err: "timeout", IsTimeout: true},
+		&smithy.GenericAPIError{Code: "InternalError", Fault: smithy.FaultServer},
+	} {
+		client = &fakeSNS{errs: []error{sendErr}}
+		_, err = NewSNS(client, SNSOptions{}).Send(context.Background(), "+16175550123", "Hi")
+		assert.Error(t, err)
+		assert.Len(t, client.published, 1)
+	}
+}
+
+func TestTwilio(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		user, pass, _ := r.BasicAuth()
+		assert.Equal(t, "AC123", user)
+		assert.Equal(t, "token", pass)
+		assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages.json", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		switch r.PostForm.Get("To") {
+		case "+16175550199":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status": 400, "code": 21610, "message": "Attempt to send to unsubscribed recipient"}`))
+		case "+16175550142":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "+16175550143":
+			w.WriteHeader(http.StatusGatewayTimeout)
+		default:
+			assert.Equal(t, "MG1", r.PostForm.Get("MessagingServiceSid"))
+			assert.Equal(t, "Hi", r.PostForm.Get("Body"))
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"sid": "SM1", "status": "queued"}`))
+		}
+	}))
+	defer server.Close()
+	twilio := NewTwilio(TwilioOptions{AccountSID: "AC123", AuthToken: "token", MessagingServiceSID: "MG1", BaseURL: server.URL})
+
+	id, err := twilio.Send(context.Background(), "+16175550123", "Hi")
+	require.NoError(t, err)
+	assert.Equal(t, "SM1", id)
+
+	_, err = twilio.Send(context.Background(), "+16175550199", "Hi")
+	assert.ErrorIs(t, err, ErrOptedOut)
+
+	calls = 0
+	_, err = twilio.Send(context.Background(), "+16175550142", "Hi")
+	var twilioErr *TwilioError
+	require.ErrorAs(t, err, &twilioErr)
+	assert.Equal(t, http.StatusServiceUnavailable, twilioErr.Status)
+	assert.Equal(t, 3, calls)
+
+	// A gateway timeout may have sent the text
+	calls = 0
+	_, err = twilio.Send(context.Background(), "+16175550143", "Hi")
+	require.ErrorAs(t, err, &twilioErr)
+	assert.Equal(t, http.StatusGatewayTimeout, twilioErr.Status)
+	assert.Equal(t, 1, calls)
+}
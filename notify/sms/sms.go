@@ -0,0 +1,157 @@
+// Package sms sends text messages, like appointment reminders, rendered from
+// templates in the recipient's language, through SNS or Twilio.
+//
+//	templates, err := sms.LoadTemplates(sub, "en")
+//	sender, err := sms.New(sms.Options{Provider: sms.NewSNS(snsClient, sms.SNSOptions{}), Templates: templates, OptOuts: optOuts})
+//	_, err = sender.Send(ctx, sms.Message{To: member.Phone, Template: "visit_reminder", Locale: member.Language, Data: visit})
+//
+// Numbers are normalized to E.164 with validation.NormalizePhone, and
+// numbers that opted out aren't texted.
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/validation"
+)
+
+var (
+	// ErrInvalidNumber is returned for numbers NormalizePhone rejects.
+	ErrInvalidNumber = errors.New("sms: invalid phone number")
+	// ErrOptedOut is returned for numbers that opted out, by texting STOP
+	// or otherwise.  Providers return it when they refuse a number for
+	// that.
+	ErrOptedOut = errors.New("sms: the number opted out")
+)
+
+// Provider sends a text to an E.164 number, returning the provider's
+// message ID.
+type Provider interface {
+	Send(ctx context.Context, to, body string) (string, error)
+}
+
+// OptOuts tracks which numbers opted out of texts, so services can share one
+// list, and stop texting before the provider refuses to.
+type OptOuts interface {
+	OptedOut(ctx context.Context, phone string) (bool, error)
+	OptOut(ctx context.Context, phone string) error
+	OptIn(ctx context.Context, phone string) error
+}
+
+// Options configures a sender.
+type Options struct {
+	Provider  Provider
+	Templates *Templates
+	// OptOuts is checked before sending, and told about numbers the
+	// provider says opted out.  Without it, only the provider's list
+	// applies.
+	OptOuts OptOuts
+	// DryRun logs messages rather than sending them, for development and
+	// test environments.
+	DryRun bool
+}
+
+// Sender sends texts.
+type Sender struct {
+	options Options
+}
+
+// New returns a sender.  The provider isn't needed for a dry run.
+func New(options Options) (*Sender, error) {
+	if options.Templates == nil {
+		return nil, errors.New("sms: templates are required")
+	}
+	if options.Provider == nil && !options.DryRun {
+		return nil, errors.New("sms: a provider is required")
+	}
+	return &Sender{options: options}, nil
+}
+
+// Message is a text to send.
+type Message struct {
+	To string
+	// Template is rendered in Locale, or the one it falls back to, with
+	// Data.
+	Template string
+	Locale   string
+	Data     any
+}
+
+// Send renders and sends the message, returning the provider's message ID.
+func (s *Sender) Send(ctx context.Context, msg Message) (string, error) {
+	to, ok := validation.NormalizePhone(msg.To)
+	if !ok {
+		return "", ErrInvalidNumber
+	}
+	body, locale, err := s.options.Templates.Render(msg.Template, msg.Locale, msg.Data)
+	if err != nil {
+		return "", err
+	}
+	logger := velacontext.GetContextLogger(ctx).With(zap.String("template", msg.Template), zap.String("locale", locale))
+	if s.options.DryRun {
+		logger.Info("Not sending a text in a dry run", zap.String("phone", to), zap.String("body", body))
+		return "", nil
+	}
+
+	if s.options.OptOuts != nil {
+		optedOut, err := s.options.OptOuts.OptedOut(ctx, to)
+		if err != nil {
+			return "", fmt.Errorf("sms: unable to check opt outs: %w", err)
+		}
+		if optedOut {
+			return "", ErrOptedOut
+		}
+	}
+	id, err := s.options.Provider.Send(ctx, to, body)
+	if errors.Is(err, ErrOptedOut) && s.options.OptOuts != nil {
+		if err := s.options.OptOuts.OptOut(ctx, to); err != nil {
+			logger.Warn("Unable to record an opt out", zap.Error(err))
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("sms: unable to send %s: %w", msg.Template, err)
+	}
+	logger.Info("Sent text", zap.String("message_id", id))
+	return id, nil
+}
+
+// The keywords carriers and providers treat as opting out and back in.
+var (
+	optOutKeywords = []string{"STOP", "STOPALL", "UNSUBSCRIBE", "CANCEL", "END", "QUIT", "OPTOUT", "REVOKE"}
+	optInKeywords  = []string{"START", "UNSTOP", "YES", "OPTIN"}
+)
+
+// HandleReply records opt outs and opt ins from a reply, for providers'
+// inbound message webhooks.  It reports whether the reply was one.
+func (s *Sender) HandleReply(ctx context.Context, from, body string) (bool, error) {
+	if s.options.OptOuts == nil {
+		return false, nil
+	}
+	phone, ok := validation.NormalizePhone(from)
+	if !ok {
+		return false, ErrInvalidNumber
+	}
+	keyword := strings.ToUpper(strings.TrimSpace(body))
+	switch {
+	case contains(optOutKeywords, keyword):
+		return true, s.options.OptOuts.OptOut(ctx, phone)
+	case contains(optInKeywords, keyword):
+		return true, s.options.OptOuts.OptIn(ctx, phone)
+	}
+	return false, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
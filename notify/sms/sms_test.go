@@ -0,0 +1,129 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+var templateFiles = fstest.MapFS{
+	"en/visit_reminder.txt": {Data: []byte("Reminder: {{.Caregiver}} visits at {{.Time}}. Reply STOP to opt out.\n")},
+	"es/visit_reminder.txt": {Data: []byte("Recordatorio: {{.Caregiver}} visita a las {{.Time}}.")},
+	"en/welcome.txt":        {Data: []byte("Welcome to Vela")},
+}
+
+type visit struct {
+	Caregiver, Time string
+}
+
+type fakeProvider struct {
+	mu   sync.Mutex
+	sent map[string]string
+	err  error
+}
+
+func (p *fakeProvider) Send(_ context.Context, to, body string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return "", p.err
+	}
+	p.sent[to] = body
+	return "SM1", nil
+}
+
+type memoryOptOuts map[string]bool
+
+func (m memoryOptOuts) OptedOut(_ context.Context, phone string) (bool, error) { return m[phone], nil }
+func (m memoryOptOuts) OptOut(_ context.Context, phone string) error           { m[phone] = true; return nil }
+func (m memoryOptOuts) OptIn(_ context.Context, phone string) error            { delete(m, phone); return nil }
+
+func newSender(t *testing.T, provider Provider, optOuts OptOuts) *Sender {
+	templates, err := LoadTemplates(templateFiles, "en")
+	require.NoError(t, err)
+	sender, err := New(Options{Provider: provider, Templates: templates, OptOuts: optOuts})
+	require.NoError(t, err)
+	return sender
+}
+
+func TestSend(t *testing.T) {
+	provider := &fakeProvider{sent: map[string]string{}}
+	sender := newSender(t, provider, memoryOptOuts{})
+
+	id, err := sender.Send(context.Background(), Message{To: "(617) 555-0123", Template: "visit_reminder", Locale: "es-US", Data: visit{"Maria", "3pm"}})
+	require.NoError(t, err)
+	assert.Equal(t, "SM1", id)
+	assert.Equal(t, "Recordatorio: Maria visita a las 3pm.", provider.sent["+16175550123"])
+
+	_, err = sender.Send(context.Background(), Message{To: "555-0123", Template: "welcome"})
+	assert.ErrorIs(t, err, ErrInvalidNumber)
+	_, err = sender.Send(context.Background(), Message{To: "6175550123", Template: "goodbye"})
+	assert.ErrorIs(t, err, ErrNoTemplate)
+	_, err = sender.Send(context.Background(), Message{To: "6175550123", Template: "visit_reminder", Data: map[string]string{}})
+	assert.ErrorContains(t, err, "rendering visit_reminder")
+}
+
+func TestSendOptedOut(t *testing.T) {
+	provider := &fakeProvider{sent: map[string]string{}}
+	optOuts := memoryOptOuts{"+16175550123": true}
+	sender := newSender(t, provider, optOuts)
+
+	_, err := sender.Send(context.Background(), Message{To: "617-555-0123", Template: "welcome"})
+	assert.ErrorIs(t, err, ErrOptedOut)
+	assert.Empty(t, provider.sent)
+
+	// The provider knowing first is recorded
+	provider.err = &TwilioError{Status: 400, Code: twilioOptedOut, Message: "Attempt to send to unsubscribed recipient"}
+	_, err = sender.Send(context.Background(), Message{To: "617-555-0199", Template: "welcome"})
+	assert.ErrorIs(t, err, ErrOptedOut)
+	assert.True(t, optOuts["+16175550199"])
+
+	provider.err = errors.New("unreachable")
+	_, err = sender.Send(context.Background(), Message{To: "617-555-0142", Template: "welcome"})
+	assert.Error(t, err)
+	assert.False(t, optOuts["+16175550142"])
+}
+
+func TestHandleReply(t *testing.T) {
+	optOuts := memoryOptOuts{}
+	sender := newSender(t, &fakeProvider{}, optOuts)
+
+	handled, err := sender.HandleReply(context.Background(), "+16175550123", " stop ")
+	require.NoError(t, err)
+	assert.True(t, handled)
+	assert.True(t, optOuts["+16175550123"])
+
+	handled, err = sender.HandleReply(context.Background(), "+16175550123", "Start")
+	require.NoError(t, err)
+	assert.True(t, handled)
+	assert.False(t, optOuts["+16175550123"])
+
+	handled, err = sender.HandleReply(context.Background(), "+16175550123", "See you at 3")
+	require.NoError(t, err)
+	assert.False(t, handled)
+}
+
+func TestSendDryRun(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	ctx := velacontext.ContextWithLogger(context.Background(), zap.New(core))
+	templates, err := LoadTemplates(templateFiles, "")
+	require.NoError(t, err)
+	sender, err := New(Options{Templates: templates, DryRun: true})
+	require.NoError(t, err)
+
+	_, err = sender.Send(ctx, Message{To: "6175550123", Template: "visit_reminder", Data: visit{"Maria", "3pm"}})
+	require.NoError(t, err)
+	entries := logs.FilterMessage("Not sending a text in a dry run").All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "Reminder: Maria visits at 3pm. Reply STOP to opt out.", entries[0].ContextMap()["body"])
+}
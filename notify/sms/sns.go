@@ -0,0 +1,114 @@
+package sms
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+
+	"github.com/seniorlink-vela/cs-common/retry"
+)
+
+// SNSAPI is the part of the SNS client used for texts.  *sns.Client
+// implements it.
+type SNSAPI interface {
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+	CheckIfPhoneNumberIsOptedOut(ctx context.Context, params *sns.CheckIfPhoneNumberIsOptedOutInput, optFns ...func(*sns.Options)) (*sns.CheckIfPhoneNumberIsOptedOutOutput, error)
+	OptInPhoneNumber(ctx context.Context, params *sns.OptInPhoneNumberInput, optFns ...func(*sns.Options)) (*sns.OptInPhoneNumberOutput, error)
+}
+
+var _ SNSAPI = (*sns.Client)(nil)
+
+// SNSOptions configures texts sent with SNS.
+type SNSOptions struct {
+	// OriginationNumber is the number texts come from, which US carriers
+	// require to be registered.
+	OriginationNumber string
+	// SenderID is shown in place of a number, in countries that allow it.
+	SenderID string
+	// Promotional sends texts as marketing, which is cheaper and less
+	// reliable, rather than transactional.
+	Promotional bool
+}
+
+// SNS sends texts with SNS.
+type SNS struct {
+	client  SNSAPI
+	options SNSOptions
+}
+
+// NewSNS returns a provider sending texts with SNS.
+func NewSNS(client SNSAPI, options SNSOptions) *SNS {
+	return &SNS{client: client, options: options}
+}
+
+// Only throttling is retried, since SNS didn't take the text then.  After a
+// timeout or server error it may have, and retrying would text the member
+// twice, so the SDK's own retries are turned off for Publish too.
+var snsPolicy = retry.Policy{MaxAttempts: 3, Retryable: retry.AWSThrottle}
+
+func singleAttempt(o *sns.Options) {
+	o.RetryMaxAttempts = 1
+}
+
+func (s *SNS) Send(ctx context.Context, to, body string) (string, error) {
+	smsType := "Transactional"
+	if s.options.Promotional {
+		smsType = "Promotional"
+	}
+	attributes := map[string]types.MessageAttributeValue{
+		"AWS.SNS.SMS.SMSType": stringAttribute(smsType),
+	}
+	if s.options.OriginationNumber != "" {
+		attributes["AWS.MM.SMS.OriginationNumber"] = stringAttribute(s.options.OriginationNumber)
+	}
+	if s.options.SenderID != "" {
+		attributes["AWS.SNS.SMS.SenderID"] = stringAttribute(s.options.SenderID)
+	}
+
+	var id string
+	err := retry.Do(ctx, snsPolicy, func(ctx context.Context) error {
+		out, err := s.client.Publish(ctx, &sns.PublishInput{
+			PhoneNumber:       aws.String(to),
+			Message:           aws.String(body),
+			MessageAttributes: attributes,
+		}, singleAttempt)
+		if err != nil {
+			return err
+		}
+		id = aws.ToString(out.MessageId)
+		return nil
+	})
+	return id, err
+}
+
+func stringAttribute(value string) types.MessageAttributeValue {
+	return types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(value)}
+}
+
+// SNSOptOuts is SNS' own opt out list, which numbers join by replying STOP.
+// SNS doesn't let numbers be added, so OptOut does nothing, and a number can
+// only be opted back in once every 30 days.
+type SNSOptOuts struct {
+	Client SNSAPI
+}
+
+var _ OptOuts = SNSOptOuts{}
+
+func (o SNSOptOuts) OptedOut(ctx context.Context, phone string) (bool, error) {
+	out, err := o.Client.CheckIfPhoneNumberIsOptedOut(ctx, &sns.CheckIfPhoneNumberIsOptedOutInput{PhoneNumber: aws.String(phone)})
+	if err != nil {
+		return false, err
+	}
+	return out.IsOptedOut, nil
+}
+
+func (o SNSOptOuts) OptOut(context.Context, string) error {
+	return nil
+}
+
+func (o SNSOptOuts) OptIn(ctx context.Context, phone string) error {
+	_, err := o.Client.OptInPhoneNumber(ctx, &sns.OptInPhoneNumberInput{PhoneNumber: aws.String(phone)})
+	return err
+}
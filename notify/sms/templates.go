@@ -0,0 +1,83 @@
+package sms
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/seniorlink-vela/cs-common/notify/internal/locale"
+)
+
+// DefaultLocale is the locale templates fall back to when LoadTemplates
+// isn't given one.
+const DefaultLocale = "en"
+
+// ErrNoTemplate is returned for templates that don't exist in the locale or
+// any it falls back to.
+var ErrNoTemplate = errors.New("sms: no such template")
+
+// Templates are texts' bodies, by name and locale.
+type Templates struct {
+	templates *locale.Templates[*template.Template]
+}
+
+// LoadTemplates loads the templates in fsys, which has a directory for each
+// locale, holding a text/template for each message:
+//
+//	en/visit_reminder.txt
+//	es/visit_reminder.txt
+//
+// Templates missing from a locale fall back to the language, so "es-MX"
+// uses "es", then to the default locale.
+func LoadTemplates(fsys fs.FS, defaultLocale string) (*Templates, error) {
+	if defaultLocale == "" {
+		defaultLocale = DefaultLocale
+	}
+	templates, err := locale.Load(fsys, defaultLocale, loadLocale)
+	if err != nil {
+		return nil, fmt.Errorf("sms: %w", err)
+	}
+	return &Templates{templates: templates}, nil
+}
+
+// loadLocale loads the .txt templates in a locale's directory.
+func loadLocale(fsys fs.FS, dir string) (map[string]*template.Template, error) {
+	files, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read templates: %w", err)
+	}
+	named := map[string]*template.Template{}
+	for _, file := range files {
+		name, ok := strings.CutSuffix(file.Name(), ".txt")
+		if file.IsDir() || !ok {
+			continue
+		}
+		contents, err := fs.ReadFile(fsys, path.Join(dir, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s/%s: %w", dir, file.Name(), err)
+		}
+		// Trailing newlines would be sent, and can cost a segment
+		named[name], err = template.New(dir + "/" + file.Name()).Option("missingkey=error").Parse(strings.TrimSpace(string(contents)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+	}
+	return named, nil
+}
+
+// Render renders the template for the locale, or the one it falls back to,
+// returning the text and the locale used.
+func (t *Templates) Render(name, locale string, data any) (string, string, error) {
+	tmpl, found, ok := t.templates.Find(name, locale)
+	if !ok {
+		return "", "", fmt.Errorf("%w: %s", ErrNoTemplate, name)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", "", fmt.Errorf("sms: rendering %s: %w", name, err)
+	}
+	return b.String(), found, nil
+}
@@ -0,0 +1,123 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/seniorlink-vela/cs-common/retry"
+)
+
+// DefaultTwilioURL is Twilio's API.
+const DefaultTwilioURL = "https://api.twilio.com"
+
+// twilioOptedOut is Twilio's error code for texting a number that replied
+// STOP.
+const twilioOptedOut = 21610
+
+// TwilioOptions configures texts sent with Twilio.
+type TwilioOptions struct {
+	AccountSID string
+	AuthToken  string
+	// Either From, the number texts come from, or MessagingServiceSID,
+	// which picks one from a pool.
+	From                string
+	MessagingServiceSID string
+	// HTTPClient defaults to a client with a 10 second timeout.
+	HTTPClient *http.Client
+	// BaseURL defaults to DefaultTwilioURL.  Tests set it.
+	BaseURL string
+}
+
+// Twilio sends texts with Twilio's API.
+type Twilio struct {
+	options TwilioOptions
+}
+
+// NewTwilio returns a provider sending texts with Twilio.
+func NewTwilio(options TwilioOptions) *Twilio {
+	if options.HTTPClient == nil {
+		options.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if options.BaseURL == "" {
+		options.BaseURL = DefaultTwilioURL
+	}
+	return &Twilio{options: options}
+}
+
+// TwilioError is an error response from Twilio.
+type TwilioError struct {
+	Status  int    `json:"status"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *TwilioError) Error() string {
+	return fmt.Sprintf("twilio: %d %s (%d)", e.Code, e.Message, e.Status)
+}
+
+// Is makes opted out numbers match ErrOptedOut.
+func (e *TwilioError) Is(target error) bool {
+	return target == ErrOptedOut && e.Code == twilioOptedOut
+}
+
+// Only responses saying the text wasn't taken, too many requests and service
+// unavailable, are retried.  A timeout or bad gateway may have sent it, and
+// retrying would text the member twice.
+var twilioPolicy = retry.Policy{
+	MaxAttempts: 3,
+	Retryable: func(err error) bool {
+		twilioErr, ok := err.(*TwilioError)
+		return ok && (twilioErr.Status == http.StatusTooManyRequests || twilioErr.Status == http.StatusServiceUnavailable)
+	},
+}
+
+func (t *Twilio) Send(ctx context.Context, to, body string) (string, error) {
+	form := url.Values{"To": {to}, "Body": {body}}
+	if t.options.MessagingServiceSID != "" {
+		form.Set("MessagingServiceSid", t.options.MessagingServiceSID)
+	} else {
+		form.Set("From", t.options.From)
+	}
+	endpoint := t.options.BaseURL + "/2010-04-01/Accounts/" + url.PathEscape(t.options.AccountSID) + "/Messages.json"
+
+	var sid string
+	err := retry.Do(ctx, twilioPolicy, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(t.options.AccountSID, t.options.AuthToken)
+		resp, err := t.options.HTTPClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= 300 {
+			twilioErr := &TwilioError{Status: resp.StatusCode}
+			if json.Unmarshal(data, twilioErr) != nil || twilioErr.Message == "" {
+				twilioErr.Message = http.StatusText(resp.StatusCode)
+			}
+			return twilioErr
+		}
+		var message struct {
+			SID string `json:"sid"`
+		}
+		if err := json.Unmarshal(data, &message); err != nil {
+			return retry.Permanent(fmt.Errorf("twilio: invalid response: %w", err))
+		}
+		sid = message.SID
+		return nil
+	})
+	return sid, err
+}
@@ -0,0 +1,155 @@
+// Package optional has Optional, a field that knows whether it was sent, so
+// PATCH bodies can tell "leave it alone" from "clear it":
+//
+//	type ProfilePatch struct {
+//		MiddleName optional.Optional[string] `json:"middle_name,omitzero"`
+//	}
+//
+// {} leaves the middle name alone, {"middle_name": null} clears it, and
+// {"middle_name": "Lee"} sets it.  The validation package checks the value
+// when there is one, and treats the rest like a nil pointer, so `required`
+// fails for an absent or null field.
+package optional
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Optional is a value that may be absent, null, or set.  The zero value is
+// absent.
+type Optional[T any] struct {
+	value T
+	set   bool
+	null  bool
+}
+
+// Of returns a set Optional.
+func Of[T any](v T) Optional[T] {
+	return Optional[T]{value: v, set: true}
+}
+
+// Null returns an Optional that was sent as null.
+func Null[T any]() Optional[T] {
+	return Optional[T]{set: true, null: true}
+}
+
+// FromPtr returns an Optional set to what p points to, or null when p is nil.
+func FromPtr[T any](p *T) Optional[T] {
+	if p == nil {
+		return Null[T]()
+	}
+	return Of(*p)
+}
+
+// IsSet reports whether the field was sent at all, including as null.
+func (o Optional[T]) IsSet() bool {
+	return o.set
+}
+
+// IsNull reports whether the field was sent as null.
+func (o Optional[T]) IsNull() bool {
+	return o.set && o.null
+}
+
+// Get returns the value, and whether there is one.
+func (o Optional[T]) Get() (T, bool) {
+	return o.value, o.set && !o.null
+}
+
+// Or returns the value, or def when there isn't one.
+func (o Optional[T]) Or(def T) T {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return def
+}
+
+// Ptr returns a pointer to a copy of the value, or nil when there isn't one.
+func (o Optional[T]) Ptr() *T {
+	if v, ok := o.Get(); ok {
+		return &v
+	}
+	return nil
+}
+
+// ApplyTo updates dst the way a PATCH would: an absent field leaves it alone,
+// null clears it, and a value replaces it.
+func (o Optional[T]) ApplyTo(dst **T) {
+	if o.set {
+		*dst = o.Ptr()
+	}
+}
+
+// ApplyToValue is ApplyTo for fields that aren't pointers, which null sets to
+// the zero value.
+func (o Optional[T]) ApplyToValue(dst *T) {
+	if o.set {
+		var zero T
+		*dst = o.Or(zero)
+	}
+}
+
+// IsZero reports whether the field is absent, so `omitzero` leaves it out
+// of the JSON.
+func (o Optional[T]) IsZero() bool {
+	return !o.set
+}
+
+// MarshalJSON writes null for absent and null fields.  Tag them `omitzero` to
+// leave absent ones out.
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if v, ok := o.Get(); ok {
+		return json.Marshal(v)
+	}
+	return []byte("null"), nil
+}
+
+// UnmarshalJSON is only called for fields that are in the JSON, so they're
+// set.
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	var value T
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		*o = Optional[T]{value: value, set: true, null: true}
+		return nil
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*o = Of(value)
+	return nil
+}
+
+// String is for logging, and formats the value, or <absent> or <null>.
+func (o Optional[T]) String() string {
+	switch {
+	case !o.set:
+		return "<absent>"
+	case o.null:
+		return "<null>"
+	default:
+		return fmt.Sprintf("%v", o.value)
+	}
+}
+
+// OptionalValue returns the value boxed, and whether there is one.  It's the
+// zero value when there isn't, so the type can still be seen.  It's for code
+// that handles Optionals without knowing T, like the validation package.
+func (o Optional[T]) OptionalValue() (interface{}, bool) {
+	return o.Get()
+}
+
+// SetOptionalValue replaces the value, keeping it set, and is false when v
+// isn't a T.  Absent and null fields are left alone.  It's how the validation
+// package normalizes Optionals.
+func (o *Optional[T]) SetOptionalValue(v interface{}) bool {
+	value, ok := v.(T)
+	if !ok {
+		return false
+	}
+	if o.set && !o.null {
+		o.value = value
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package optional
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type patch struct {
+	Name     Optional[string] `json:"name,omitzero"`
+	Nickname Optional[string] `json:"nickname,omitzero"`
+	Age      Optional[int]    `json:"age,omitzero"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	var p patch
+	require.NoError(t, json.Unmarshal([]byte(`{"name": "", "nickname": null}`), &p))
+
+	name, ok := p.Name.Get()
+	assert.True(t, ok)
+	assert.Equal(t, "", name)
+	assert.True(t, p.Nickname.IsSet())
+	assert.True(t, p.Nickname.IsNull())
+	assert.False(t, p.Age.IsSet())
+	assert.Equal(t, 42, p.Age.Or(42))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"age": "old"}`), &p))
+}
+
+func TestMarshal(t *testing.T) {
+	data, err := json.Marshal(patch{Name: Of("Walter"), Nickname: Null[string]()})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name": "Walter", "nickname": null}`, string(data))
+
+	// Round trips keep the three states apart
+	var p patch
+	require.NoError(t, json.Unmarshal(data, &p))
+	assert.Equal(t, patch{Name: Of("Walter"), Nickname: Null[string]()}, p)
+}
+
+func TestApply(t *testing.T) {
+	walter, dude := "Walter", "The Dude"
+	name, nickname := &walter, &dude
+	age := 45
+
+	Optional[string]{}.ApplyTo(&name)
+	Null[string]().ApplyTo(&nickname)
+	Null[int]().ApplyToValue(&age)
+	assert.Equal(t, "Walter", *name)
+	assert.Nil(t, nickname)
+	assert.Equal(t, 0, age)
+
+	Of("Donny").ApplyTo(&name)
+	assert.Equal(t, "Donny", *name)
+	assert.Equal(t, Of("Donny"), FromPtr(name))
+	assert.Equal(t, Null[string](), FromPtr[string](nil))
+	assert.Nil(t, Null[string]().Ptr())
+}
+
+func TestString(t *testing.T) {
+	assert.Equal(t, "<absent>", Optional[int]{}.String())
+	assert.Equal(t, "<null>", Null[int]().String())
+	assert.Equal(t, "7", Of(7).String())
+}
+
+func TestSetOptionalValue(t *testing.T) {
+	o := Of("  x ")
+	assert.True(t, o.SetOptionalValue("x"))
+	assert.Equal(t, Of("x"), o)
+	assert.False(t, o.SetOptionalValue(1))
+
+	null := Null[string]()
+	null.SetOptionalValue("x")
+	assert.True(t, null.IsNull())
+}
@@ -0,0 +1,32 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// EncodeCursor returns v as an opaque cursor: URL-safe base64 JSON.  It's
+// opaque, not secret or tamper-proof, so only put in it what the client could
+// send as a filter anyway, like the sort key and ID of the last item.
+func EncodeCursor(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCursor decodes a cursor from EncodeCursor into dst.  A cursor that
+// doesn't decode is an apperr validation error, like the other parameters.
+func DecodeCursor(cursor string, dst interface{}) error {
+	// Clients sometimes add the padding EncodeCursor leaves off
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(cursor, "="))
+	if err == nil {
+		err = json.Unmarshal(data, dst)
+	}
+	if err != nil {
+		return invalid(ParamCursor, "is not a valid cursor")
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+// Package pagination is the standard request parameters and response
+// envelopes for list endpoints, so every service pages the same way.  Offset
+// lists take ?page=2&limit=25:
+//
+//	req, err := pagination.PageFromRequest(r, pagination.Options{})
+//	if err != nil {
+//		respond.WriteError(r.Context(), w, err)
+//		return
+//	}
+//	members, total, err := store.Members(ctx, req.Limit, req.Offset())
+//	...
+//	page := pagination.NewPage(members, req, total)
+//	w.Header().Set("Link", page.Link(r.URL))
+//	respond.JSON(w, http.StatusOK, page)
+//
+// and lists that change too much for offsets take ?cursor=...&limit=25, where
+// the cursor is the opaque token from the previous response's next_cursor.
+package pagination
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/seniorlink-vela/cs-common/apperr"
+	"github.com/seniorlink-vela/cs-common/context/lambdamiddleware"
+)
+
+// The query parameters.
+const (
+	ParamPage   = "page"
+	ParamLimit  = "limit"
+	ParamCursor = "cursor"
+)
+
+// The limits when Options doesn't say.
+const (
+	DefaultLimit    = 25
+	DefaultMaxLimit = 100
+)
+
+// MaxPage is the highest page a request can ask for, which keeps Offset well
+// clear of overflowing.
+const MaxPage = 1000000
+
+// Options control parsing.  The zero value uses the defaults.
+type Options struct {
+	// Limit is used when the request doesn't have one, and defaults to
+	// DefaultLimit.
+	Limit int
+	// MaxLimit caps the limit a request can ask for, and defaults to
+	// DefaultMaxLimit.  Bigger limits are lowered to it rather than refused.
+	MaxLimit int
+}
+
+// PageRequest is an offset page.  Page starts at 1.
+type PageRequest struct {
+	Page  int
+	Limit int
+}
+
+// Offset returns how many items come before the page.
+func (p PageRequest) Offset() int {
+	return (p.Page - 1) * p.Limit
+}
+
+// CursorRequest is a cursor page.  Cursor is empty for the first page.
+type CursorRequest struct {
+	Cursor string
+	Limit  int
+}
+
+// ParsePage reads the page and limit parameters.  Bad values are an
+// apperr validation error naming the parameter, which respond sends as a 400.
+func ParsePage(query url.Values, opts Options) (PageRequest, error) {
+	limit, err := parseLimit(query, opts)
+	if err != nil {
+		return PageRequest{}, err
+	}
+	page := 1
+	if s := query.Get(ParamPage); s != "" {
+		page, err = strconv.Atoi(s)
+		if err != nil || page < 1 {
+			return PageRequest{}, invalid(ParamPage, "must be a whole number of at least 1")
+		}
+		if page > MaxPage {
+			return PageRequest{}, invalid(ParamPage, fmt.Sprintf("must be at most %d", MaxPage))
+		}
+	}
+	return PageRequest{Page: page, Limit: limit}, nil
+}
+
+// ParseCursor reads the cursor and limit parameters.  The cursor isn't
+// decoded here, since only the endpoint knows what's in it; use DecodeCursor.
+func ParseCursor(query url.Values, opts Options) (CursorRequest, error) {
+	limit, err := parseLimit(query, opts)
+	if err != nil {
+		return CursorRequest{}, err
+	}
+	return CursorRequest{Cursor: query.Get(ParamCursor), Limit: limit}, nil
+}
+
+// PageFromRequest is ParsePage for a net/http request.
+func PageFromRequest(r *http.Request, opts Options) (PageRequest, error) {
+	return ParsePage(r.URL.Query(), opts)
+}
+
+// CursorFromRequest is ParseCursor for a net/http request.
+func CursorFromRequest(r *http.Request, opts Options) (CursorRequest, error) {
+	return ParseCursor(r.URL.Query(), opts)
+}
+
+// PageFromALB is ParsePage for an ALB request.
+func PageFromALB(req events.ALBTargetGroupRequest, opts Options) (PageRequest, error) {
+	return ParsePage(lambdamiddleware.ALBQuery(req), opts)
+}
+
+// CursorFromALB is ParseCursor for an ALB request.
+func CursorFromALB(req events.ALBTargetGroupRequest, opts Options) (CursorRequest, error) {
+	return ParseCursor(lambdamiddleware.ALBQuery(req), opts)
+}
+
+// ALBURL returns the ALB request's path and raw query, to pass to Link.
+func ALBURL(req events.ALBTargetGroupRequest) *url.URL {
+	return &url.URL{Path: req.Path, RawQuery: lambdamiddleware.ALBRawQuery(req)}
+}
+
+func parseLimit(query url.Values, opts Options) (int, error) {
+	max := opts.MaxLimit
+	if max <= 0 {
+		max = DefaultMaxLimit
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if s := query.Get(ParamLimit); s != "" {
+		var err error
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit < 1 {
+			return 0, invalid(ParamLimit, "must be a whole number of at least 1")
+		}
+	}
+	if limit > max {
+		limit = max
+	}
+	return limit, nil
+}
+
+func invalid(param, message string) error {
+	return apperr.New(apperr.CodeValidation, "Invalid pagination parameters").WithField(param, message)
+}
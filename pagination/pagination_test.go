@@ -0,0 +1,86 @@
+package pagination
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/seniorlink-vela/cs-common/apperr"
+)
+
+func TestParsePage(t *testing.T) {
+	req, err := ParsePage(url.Values{}, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, PageRequest{Page: 1, Limit: DefaultLimit}, req)
+	assert.Equal(t, 0, req.Offset())
+
+	req, err = ParsePage(url.Values{"page": {"3"}, "limit": {"10"}}, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, 20, req.Offset())
+
+	// Too big is lowered rather than refused
+	req, err = ParsePage(url.Values{"limit": {"500"}}, Options{Limit: 50, MaxLimit: 200})
+	require.NoError(t, err)
+	assert.Equal(t, 200, req.Limit)
+	req, _ = ParsePage(url.Values{}, Options{Limit: 50})
+	assert.Equal(t, 50, req.Limit)
+
+	_, err = ParsePage(url.Values{"page": {"9223372036854775807"}}, Options{})
+	assert.True(t, apperr.IsCode(err, apperr.CodeValidation))
+	req, err = ParsePage(url.Values{"page": {"1000000"}, "limit": {"100"}}, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, 99999900, req.Offset())
+
+	for param, value := range map[string]string{"page": "0", "limit": "-1"} {
+		_, err = ParsePage(url.Values{param: {value}}, Options{})
+		var appErr *apperr.Error
+		require.ErrorAs(t, err, &appErr, param)
+		assert.Equal(t, apperr.CodeValidation, appErr.Code)
+		assert.Equal(t, param, appErr.Fields[0].Name)
+	}
+	_, err = ParsePage(url.Values{"page": {"two"}}, Options{})
+	assert.True(t, apperr.IsCode(err, apperr.CodeValidation))
+}
+
+func TestFromRequests(t *testing.T) {
+	r := httptest.NewRequest("GET", "/members?page=2&limit=5", nil)
+	page, err := PageFromRequest(r, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, PageRequest{Page: 2, Limit: 5}, page)
+
+	// The ALB doesn't decode the query
+	alb := events.ALBTargetGroupRequest{
+		Path:                  "/members",
+		QueryStringParameters: map[string]string{"cursor": "abc%3D", "limit": "5", "name": "de%20la%20Cruz"},
+	}
+	cursor, err := CursorFromALB(alb, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, CursorRequest{Cursor: "abc=", Limit: 5}, cursor)
+	assert.Equal(t, "/members?cursor=abc%3D&limit=5&name=de%20la%20Cruz", ALBURL(alb).String())
+
+	alb = events.ALBTargetGroupRequest{MultiValueQueryStringParameters: map[string][]string{"page": {"4"}}}
+	page, err = PageFromALB(alb, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, 4, page.Page)
+}
+
+func TestCursor(t *testing.T) {
+	type after struct {
+		CreatedAt string `json:"c"`
+		ID        string `json:"i"`
+	}
+	cursor, err := EncodeCursor(after{CreatedAt: "2024-03-04T15:30:00Z", ID: "m-1"})
+	require.NoError(t, err)
+	assert.Equal(t, cursor, url.QueryEscape(cursor))
+
+	var decoded after
+	require.NoError(t, DecodeCursor(cursor+"==", &decoded))
+	assert.Equal(t, "m-1", decoded.ID)
+
+	err = DecodeCursor("not a cursor", &decoded)
+	assert.True(t, apperr.IsCode(err, apperr.CodeValidation))
+}
@@ -0,0 +1,116 @@
+package pagination
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Page is the response envelope for an offset page.
+type Page[T any] struct {
+	Items      []T `json:"items"`
+	Page       int `json:"page"`
+	Limit      int `json:"limit"`
+	Total      int `json:"total"`
+	TotalPages int `json:"total_pages"`
+}
+
+// NewPage returns the envelope for one page of items, out of total.  Items
+// is never null in the JSON, even when there aren't any.
+func NewPage[T any](items []T, req PageRequest, total int) Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+	pages := 0
+	if req.Limit > 0 {
+		pages = (total + req.Limit - 1) / req.Limit
+	}
+	return Page[T]{Items: items, Page: req.Page, Limit: req.Limit, Total: total, TotalPages: pages}
+}
+
+// HasNext reports whether there's a page after this one.
+func (p Page[T]) HasNext() bool {
+	return p.Page < p.TotalPages
+}
+
+// Link returns the Link header for the page, with first, prev, next and last
+// links that are base with the page parameter changed.  Pass the request's
+// URL, or ALBURL for an ALB request; relative links are fine.
+func (p Page[T]) Link(base *url.URL) string {
+	withPage := func(page int) string {
+		return withParams(base, map[string]string{ParamPage: strconv.Itoa(page), ParamLimit: strconv.Itoa(p.Limit)})
+	}
+	last := p.TotalPages
+	if last < 1 {
+		last = 1
+	}
+	links := []string{link(withPage(1), "first")}
+	if p.Page > 1 {
+		prev := p.Page - 1
+		if prev > last {
+			prev = last
+		}
+		links = append(links, link(withPage(prev), "prev"))
+	}
+	if p.HasNext() {
+		links = append(links, link(withPage(p.Page+1), "next"))
+	}
+	links = append(links, link(withPage(last), "last"))
+	return strings.Join(links, ", ")
+}
+
+// CursorPage is the response envelope for a cursor page.  NextCursor is left
+// out on the last page.  Total is optional, since counting is often what
+// cursors are avoiding.
+type CursorPage[T any] struct {
+	Items      []T    `json:"items"`
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      *int   `json:"total,omitempty"`
+}
+
+// NewCursorPage returns the envelope for one page of items.  next is the
+// cursor for the page after, or empty when this is the last.
+func NewCursorPage[T any](items []T, req CursorRequest, next string) CursorPage[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return CursorPage[T]{Items: items, Limit: req.Limit, NextCursor: next}
+}
+
+// WithTotal sets the total, and returns the page.
+func (p CursorPage[T]) WithTotal(total int) CursorPage[T] {
+	p.Total = &total
+	return p
+}
+
+// HasNext reports whether there's a page after this one.
+func (p CursorPage[T]) HasNext() bool {
+	return p.NextCursor != ""
+}
+
+// Link returns the Link header for the page, which only has a next link,
+// and is empty on the last page.
+func (p CursorPage[T]) Link(base *url.URL) string {
+	if !p.HasNext() {
+		return ""
+	}
+	return link(withParams(base, map[string]string{ParamCursor: p.NextCursor, ParamLimit: strconv.Itoa(p.Limit)}), "next")
+}
+
+// withParams returns base with the params set, keeping its other parameters,
+// like filters.
+func withParams(base *url.URL, params map[string]string) string {
+	u := *base
+	q := u.Query()
+	for k, v := range params {
+		q.Set(k, v)
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func link(target, rel string) string {
+	return fmt.Sprintf(`<%s>; rel="%s"`, target, rel)
+}
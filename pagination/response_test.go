@@ -0,0 +1,45 @@
+package pagination
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPage(t *testing.T) {
+	page := NewPage([]string{"a", "b"}, PageRequest{Page: 2, Limit: 2}, 5)
+	assert.Equal(t, 3, page.TotalPages)
+	assert.True(t, page.HasNext())
+
+	data, err := json.Marshal(page)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"items": ["a", "b"], "page": 2, "limit": 2, "total": 5, "total_pages": 3}`, string(data))
+
+	base, _ := url.Parse("/members?status=active&page=2")
+	assert.Equal(t, `</members?limit=2&page=1&status=active>; rel="first", `+
+		`</members?limit=2&page=1&status=active>; rel="prev", `+
+		`</members?limit=2&page=3&status=active>; rel="next", `+
+		`</members?limit=2&page=3&status=active>; rel="last"`, page.Link(base))
+
+	empty := NewPage[string](nil, PageRequest{Page: 1, Limit: 25}, 0)
+	data, _ = json.Marshal(empty)
+	assert.JSONEq(t, `{"items": [], "page": 1, "limit": 25, "total": 0, "total_pages": 0}`, string(data))
+	assert.Equal(t, `</members?limit=25&page=1>; rel="first", </members?limit=25&page=1>; rel="last"`, empty.Link(&url.URL{Path: "/members"}))
+}
+
+func TestCursorPage(t *testing.T) {
+	page := NewCursorPage([]int{1, 2}, CursorRequest{Limit: 2}, "eyJpIjoyfQ")
+	data, err := json.Marshal(page)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"items": [1, 2], "limit": 2, "next_cursor": "eyJpIjoyfQ"}`, string(data))
+	assert.Equal(t, `</members?cursor=eyJpIjoyfQ&limit=2>; rel="next"`, page.Link(&url.URL{Path: "/members"}))
+
+	last := NewCursorPage([]int{3}, CursorRequest{Cursor: "eyJpIjoyfQ", Limit: 2}, "").WithTotal(3)
+	assert.False(t, last.HasNext())
+	assert.Empty(t, last.Link(&url.URL{Path: "/members"}))
+	data, _ = json.Marshal(last)
+	assert.JSONEq(t, `{"items": [3], "limit": 2, "total": 3}`, string(data))
+}
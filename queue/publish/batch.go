@@ -0,0 +1,150 @@
+package publish
+
+import (
+	"context"
+	"errors"
+
+	"github.com/seniorlink-vela/cs-common/context/httpmiddleware"
+	"github.com/seniorlink-vela/cs-common/retry"
+)
+
+// The message attributes set on SNS and SQS messages, besides the request
+// ID, so subscriptions can filter on them.
+const (
+	TypeAttribute          = "X-Vela-Event-Type"
+	SchemaVersionAttribute = "X-Vela-Schema-Version"
+)
+
+// SNS and SQS won't take more than 10 messages, or 256 KiB, in one batch.
+const (
+	maxBatch     = 10
+	maxBatchSize = 256 * 1024
+)
+
+// message is an envelope encoded for SNS or SQS.
+type message struct {
+	envelope   Envelope
+	body       string
+	attributes map[string]string
+	size       int
+}
+
+func newMessage(envelope Envelope) (message, error) {
+	body, err := jsonString(envelope)
+	if err != nil {
+		return message{}, err
+	}
+	attributes := map[string]string{
+		TypeAttribute:          envelope.Type,
+		SchemaVersionAttribute: envelope.SchemaVersion,
+	}
+	if envelope.RequestID != "" {
+		attributes[httpmiddleware.RequestIDHeader] = envelope.RequestID
+	}
+	size := len(body)
+	for name, value := range attributes {
+		// Each attribute's data type is "String"
+		size += len(name) + len("String") + len(value)
+	}
+	return message{envelope: envelope, body: body, attributes: attributes, size: size}, nil
+}
+
+// batches encodes the envelopes, in as few batches as fit the limits.
+func batches(envelopes []Envelope) ([][]message, error) {
+	var batches [][]message
+	var batch []message
+	size := 0
+	for _, envelope := range envelopes {
+		m, err := newMessage(envelope)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == maxBatch || (len(batch) > 0 && size+m.size > maxBatchSize) {
+			batches = append(batches, batch)
+			batch, size = nil, 0
+		}
+		batch = append(batch, m)
+		size += m.size
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches, nil
+}
+
+// rejection is a message a batch call didn't send.  SenderFault is set when
+// the message is to blame, so there's no point retrying it.
+type rejection struct {
+	Failure
+	SenderFault bool
+}
+
+// sendFunc sends a batch, returning the messages it didn't.
+type sendFunc func(ctx context.Context, batch []message) ([]rejection, error)
+
+// publishBatches sends the envelopes in batches, retrying the messages that
+// weren't sent for reasons other than the message, and returns an *Error
+// listing the ones that never were.
+func publishBatches(ctx context.Context, policy retry.Policy, envelopes []Envelope, send sendFunc) error {
+	batches, err := batches(envelopes)
+	if err != nil {
+		return err
+	}
+	var failed []Failure
+	for _, batch := range batches {
+		batchFailed, err := publishBatch(ctx, policy, batch, send)
+		if err != nil {
+			return err
+		}
+		failed = append(failed, batchFailed...)
+	}
+	if len(failed) > 0 {
+		return &Error{Failed: failed}
+	}
+	return nil
+}
+
+func publishBatch(ctx context.Context, policy retry.Policy, batch []message, send sendFunc) ([]Failure, error) {
+	// Messages that are rejected are failures straight away; the rest are
+	// retried
+	var rejected []Failure
+	err := retry.Do(ctx, policy, func(ctx context.Context) error {
+		rejections, err := send(ctx, batch)
+		if err != nil {
+			return err
+		}
+		byID := map[string]message{}
+		for _, m := range batch {
+			byID[m.envelope.ID] = m
+		}
+		batch = nil
+		var failed []Failure
+		for _, r := range rejections {
+			if r.SenderFault {
+				rejected = append(rejected, r.Failure)
+				continue
+			}
+			failed = append(failed, r.Failure)
+			batch = append(batch, byID[r.ID])
+		}
+		if len(failed) > 0 {
+			return &Error{Failed: failed}
+		}
+		return nil
+	})
+	var publishErr *Error
+	switch {
+	case errors.As(err, &publishErr):
+		rejected = append(rejected, publishErr.Failed...)
+	case err != nil:
+		return nil, err
+	}
+	return rejected, nil
+}
+
+// retryable retries the messages that weren't sent, and the batches the
+// client couldn't send.
+func retryable(err error) bool {
+	var publishErr *Error
+	return errors.As(err, &publishErr) || retry.AWSRetryable(err)
+}
@@ -0,0 +1,166 @@
+package publish
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+// The defaults for BatcherOptions' zero fields.
+const (
+	DefaultBatchSize = 100
+	DefaultBatchWait = time.Second
+)
+
+// BatcherOptions configure a Batcher.
+type BatcherOptions struct {
+	// Size is how many envelopes are buffered before they're published.
+	Size int
+	// Wait is the longest an envelope is buffered for.
+	Wait time.Duration
+	// OnError is called with the envelopes that couldn't be published in
+	// the background, so they can be added again or kept somewhere else.
+	// It defaults to logging them.
+	OnError func(err error, envelopes []Envelope)
+	// Logger logs the envelopes that couldn't be published in the
+	// background, when there's no OnError.  It defaults to the fallback
+	// logger.
+	Logger *zap.Logger
+}
+
+// Batcher buffers envelopes and publishes them together, when there are
+// enough of them or the oldest has waited long enough.  Close it to publish
+// the rest before exiting.
+type Batcher struct {
+	publisher Publisher
+	options   BatcherOptions
+
+	mu       sync.Mutex
+	buffered []Envelope
+	// The last error publishing in the background, which Close returns.
+	backgroundErr error
+
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// NewBatcher returns a batcher that publishes with the publisher.
+func NewBatcher(publisher Publisher, options BatcherOptions) *Batcher {
+	if options.Size <= 0 {
+		options.Size = DefaultBatchSize
+	}
+	if options.Wait <= 0 {
+		options.Wait = DefaultBatchWait
+	}
+	if options.Logger == nil {
+		options.Logger = velacontext.FallbackLogger()
+	}
+	if options.OnError == nil {
+		logger := options.Logger
+		options.OnError = func(err error, envelopes []Envelope) {
+			ids := make([]string, len(envelopes))
+			for i, envelope := range envelopes {
+				ids[i] = envelope.ID
+			}
+			logger.Error("Unable to publish events", zap.Strings("ids", ids), zap.Error(err))
+		}
+	}
+	b := &Batcher{
+		publisher: publisher,
+		options:   options,
+		stop:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Add buffers the envelopes, publishing the buffer when it's full.  Then it
+// returns the error publishing, which can be about envelopes other callers
+// added.
+func (b *Batcher) Add(ctx context.Context, envelopes ...Envelope) error {
+	b.mu.Lock()
+	b.buffered = append(b.buffered, envelopes...)
+	full := len(b.buffered) >= b.options.Size
+	b.mu.Unlock()
+	if full {
+		return b.Flush(ctx)
+	}
+	return nil
+}
+
+// Flush publishes the buffered envelopes.
+func (b *Batcher) Flush(ctx context.Context) error {
+	_, err := b.flush(ctx)
+	return err
+}
+
+func (b *Batcher) flush(ctx context.Context) ([]Envelope, error) {
+	b.mu.Lock()
+	envelopes := b.buffered
+	b.buffered = nil
+	b.mu.Unlock()
+	if len(envelopes) == 0 {
+		return nil, nil
+	}
+	return envelopes, b.publisher.Publish(ctx, envelopes...)
+}
+
+// Close stops publishing in the background and publishes the buffered
+// envelopes.  It also returns the last error publishing in the background,
+// so it isn't only logged.
+func (b *Batcher) Close(ctx context.Context) error {
+	b.once.Do(func() { close(b.stop) })
+	<-b.stopped
+	b.mu.Lock()
+	backgroundErr := b.backgroundErr
+	b.backgroundErr = nil
+	b.mu.Unlock()
+	return errors.Join(backgroundErr, b.Flush(ctx))
+}
+
+func (b *Batcher) run() {
+	defer close(b.stopped)
+	ticker := time.NewTicker(b.options.Wait)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+			envelopes, err := b.flush(context.Background())
+			if err != nil {
+				b.mu.Lock()
+				b.backgroundErr = err
+				b.mu.Unlock()
+				b.options.OnError(err, unpublished(envelopes, err))
+			}
+		}
+	}
+}
+
+// unpublished returns the envelopes publishing them failed for: the ones an
+// *Error lists, or all of them.
+func unpublished(envelopes []Envelope, err error) []Envelope {
+	var publishErr *Error
+	if !errors.As(err, &publishErr) {
+		return envelopes
+	}
+	failed := make(map[string]bool, len(publishErr.Failed))
+	for _, failure := range publishErr.Failed {
+		failed[failure.ID] = true
+	}
+	var result []Envelope
+	for _, envelope := range envelopes {
+		if failed[envelope.ID] {
+			result = append(result, envelope)
+		}
+	}
+	return result
+}
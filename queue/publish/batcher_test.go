@@ -0,0 +1,99 @@
+package publish
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]Envelope
+}
+
+func (r *recorder) Publish(ctx context.Context, envelopes ...Envelope) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, envelopes)
+	return nil
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.batches)
+}
+
+func TestBatcher(t *testing.T) {
+	ctx := context.Background()
+	publisher := &recorder{}
+	batcher := NewBatcher(publisher, BatcherOptions{Size: 3, Wait: time.Hour})
+	sent := envelopes(t, ctx, 4)
+
+	require.NoError(t, batcher.Add(ctx, sent[0], sent[1]))
+	assert.Equal(t, 0, publisher.count())
+	require.NoError(t, batcher.Add(ctx, sent[2]))
+	assert.Equal(t, [][]Envelope{sent[:3]}, publisher.batches)
+
+	require.NoError(t, batcher.Add(ctx, sent[3]))
+	require.NoError(t, batcher.Close(ctx))
+	assert.Equal(t, [][]Envelope{sent[:3], sent[3:]}, publisher.batches)
+	require.NoError(t, batcher.Close(ctx))
+}
+
+func TestBatcherWait(t *testing.T) {
+	ctx := context.Background()
+	publisher := &recorder{}
+	batcher := NewBatcher(publisher, BatcherOptions{Wait: 10 * time.Millisecond})
+	defer batcher.Close(ctx)
+
+	require.NoError(t, batcher.Add(ctx, envelopes(t, ctx, 1)...))
+	assert.Eventually(t, func() bool { return publisher.count() == 1 }, time.Second, 5*time.Millisecond)
+}
+
+type failing struct {
+	err error
+}
+
+func (f failing) Publish(ctx context.Context, envelopes ...Envelope) error {
+	return f.err
+}
+
+func TestBatcherBackgroundErrors(t *testing.T) {
+	ctx := context.Background()
+	sent := envelopes(t, ctx, 2)
+	publishErr := &Error{Failed: []Failure{{ID: sent[1].ID, Code: "InternalFailure"}}}
+	var mu sync.Mutex
+	var failed []Envelope
+	batcher := NewBatcher(failing{err: publishErr}, BatcherOptions{
+		Wait: 10 * time.Millisecond,
+		OnError: func(err error, envelopes []Envelope) {
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, publishErr, err)
+			failed = append(failed, envelopes...)
+		},
+	})
+
+	// Only the envelope that failed is handed back, and Close reports it
+	require.NoError(t, batcher.Add(ctx, sent...))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(failed) > 0
+	}, time.Second, 5*time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, sent[1:], failed)
+	mu.Unlock()
+	assert.ErrorIs(t, batcher.Close(ctx), publishErr)
+	require.NoError(t, batcher.Close(ctx))
+
+	// Other errors hand back everything
+	unavailable := errors.New("unavailable")
+	assert.Equal(t, sent, unpublished(sent, unavailable))
+}
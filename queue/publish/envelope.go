@@ -0,0 +1,93 @@
+// Package publish publishes events to SNS topics, SQS queues and EventBridge
+// buses in the standard envelope, so consumers can tell where an event came
+// from, which organization and request it belongs to, and which version of
+// its schema the data follows.
+//
+//	envelope, err := publish.NewEnvelope(ctx, "care-plans", "care_plan.updated", "1", plan)
+//	...
+//	err = publisher.Publish(ctx, envelope)
+//
+// High-volume emitters can buffer envelopes with a Batcher, which publishes
+// them in batches.
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+// Envelope is the standard event message.  Data is the event itself.
+type Envelope struct {
+	ID             string          `json:"id"`
+	Source         string          `json:"source"`
+	Type           string          `json:"type"`
+	Time           time.Time       `json:"time"`
+	OrganizationID int64           `json:"organization_id,omitempty"`
+	SchemaVersion  string          `json:"schema_version"`
+	RequestID      string          `json:"request_id,omitempty"`
+	Data           json.RawMessage `json:"data"`
+}
+
+// NewEnvelope returns an envelope for the event, with a new ID, the current
+// time, and the context's organization and request IDs.
+func NewEnvelope(ctx context.Context, source, eventType, schemaVersion string, data interface{}) (Envelope, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("unable to encode %s event: %w", eventType, err)
+	}
+	requestID, _ := velacontext.LookupRequestID(ctx)
+	return Envelope{
+		ID:             velacontext.NewRequestID(),
+		Source:         source,
+		Type:           eventType,
+		Time:           time.Now().UTC(),
+		OrganizationID: velacontext.GetContextOrganizationID(ctx),
+		SchemaVersion:  schemaVersion,
+		RequestID:      requestID,
+		Data:           body,
+	}, nil
+}
+
+// Decode decodes the envelope's data into dst.
+func (e Envelope) Decode(dst interface{}) error {
+	return json.Unmarshal(e.Data, dst)
+}
+
+// Publisher publishes envelopes.
+type Publisher interface {
+	Publish(ctx context.Context, envelopes ...Envelope) error
+}
+
+// Failure is an envelope that couldn't be published.
+type Failure struct {
+	ID      string
+	Code    string
+	Message string
+}
+
+// Error is returned when some of the envelopes couldn't be published.  The
+// rest were.
+type Error struct {
+	Failed []Failure
+}
+
+func (e *Error) Error() string {
+	ids := make([]string, len(e.Failed))
+	for i, failure := range e.Failed {
+		ids[i] = failure.ID
+	}
+	return fmt.Sprintf("unable to publish %d events (%s): %s: %s", len(e.Failed), strings.Join(ids, ", "), e.Failed[0].Code, e.Failed[0].Message)
+}
+
+func jsonString(envelope Envelope) (string, error) {
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return "", fmt.Errorf("unable to encode %s event %s: %w", envelope.Type, envelope.ID, err)
+	}
+	return string(body), nil
+}
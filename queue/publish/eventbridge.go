@@ -0,0 +1,114 @@
+package publish
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/retry"
+)
+
+// EventBridgeEntry is an entry for EventBridge's PutEvents.  Detail is the
+// JSON encoded envelope.
+type EventBridgeEntry struct {
+	EventBusName string
+	Source       string
+	DetailType   string
+	Detail       string
+	Time         time.Time
+	TraceHeader  string
+}
+
+// EventBridgeResult is what happened to an entry.  ErrorCode is empty when
+// it was put.
+type EventBridgeResult struct {
+	ErrorCode    string
+	ErrorMessage string
+}
+
+// EventBridgeAPI puts entries on an event bus, returning a result for each
+// entry in order.  This module doesn't depend on the EventBridge SDK, so
+// services adapt their client with EventBridgeFunc, copying the entries to
+// PutEventsRequestEntry and the result entries back.  Turn the SDK's own
+// retries off there, with RetryMaxAttempts 1, since the publisher retries.
+type EventBridgeAPI interface {
+	PutEvents(ctx context.Context, entries []EventBridgeEntry) ([]EventBridgeResult, error)
+}
+
+// EventBridgeFunc adapts a function to EventBridgeAPI.
+type EventBridgeFunc func(ctx context.Context, entries []EventBridgeEntry) ([]EventBridgeResult, error)
+
+// PutEvents calls f.
+func (f EventBridgeFunc) PutEvents(ctx context.Context, entries []EventBridgeEntry) ([]EventBridgeResult, error) {
+	return f(ctx, entries)
+}
+
+// The entry error codes worth retrying.  The rest are about the entry.
+var eventBridgeRetryableCodes = map[string]bool{
+	"InternalFailure":     true,
+	"ThrottlingException": true,
+}
+
+// EventBridgeOptions configure an EventBridge publisher.
+type EventBridgeOptions struct {
+	Client  EventBridgeAPI
+	BusName string
+	// Retry is the policy for entries EventBridge fails to put, other than
+	// because of the entry.
+	Retry retry.Policy
+}
+
+// EventBridge publishes envelopes to an event bus.  The envelope's source
+// and type are the event's source and detail type, so rules can match on
+// them.
+type EventBridge struct {
+	options EventBridgeOptions
+}
+
+var _ Publisher = (*EventBridge)(nil)
+
+// NewEventBridge returns a publisher for the event bus.
+func NewEventBridge(options EventBridgeOptions) *EventBridge {
+	if options.Retry.Retryable == nil {
+		options.Retry.Retryable = retryable
+	}
+	return &EventBridge{options: options}
+}
+
+// Publish puts the envelopes on the bus in as few batches as it can, which
+// have the same limits as SNS and SQS, retrying the ones that fail for
+// reasons other than the entry.  It returns an *Error listing the ones
+// EventBridge didn't accept.
+func (p *EventBridge) Publish(ctx context.Context, envelopes ...Envelope) error {
+	return publishBatches(ctx, p.options.Retry, envelopes, p.send)
+}
+
+func (p *EventBridge) send(ctx context.Context, batch []message) ([]rejection, error) {
+	traceHeader := velacontext.GetContextAmznTraceID(ctx)
+	entries := make([]EventBridgeEntry, len(batch))
+	for i, m := range batch {
+		entries[i] = EventBridgeEntry{
+			EventBusName: p.options.BusName,
+			Source:       m.envelope.Source,
+			DetailType:   m.envelope.Type,
+			Detail:       m.body,
+			Time:         m.envelope.Time,
+			TraceHeader:  traceHeader,
+		}
+	}
+	results, err := p.options.Client.PutEvents(ctx, entries)
+	if err != nil {
+		return nil, fmt.Errorf("unable to put events on %s: %w", p.options.BusName, err)
+	}
+	var rejections []rejection
+	for i, result := range results {
+		if result.ErrorCode != "" && i < len(batch) {
+			rejections = append(rejections, rejection{
+				Failure:     Failure{ID: batch[i].envelope.ID, Code: result.ErrorCode, Message: result.ErrorMessage},
+				SenderFault: !eventBridgeRetryableCodes[result.ErrorCode],
+			})
+		}
+	}
+	return rejections, nil
+}
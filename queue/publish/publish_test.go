@@ -0,0 +1,210 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	awssqs "github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/retry"
+)
+
+type plan struct {
+	ID int `json:"id"`
+}
+
+func envelopes(t *testing.T, ctx context.Context, n int) []Envelope {
+	var out []Envelope
+	for i := 0; i < n; i++ {
+		envelope, err := NewEnvelope(ctx, "care-plans", "care_plan.updated", "1", plan{ID: i})
+		require.NoError(t, err)
+		out = append(out, envelope)
+	}
+	return out
+}
+
+func TestNewEnvelope(t *testing.T) {
+	ctx := velacontext.ContextWithRequestID(context.Background(), "req-1")
+	ctx = velacontext.ContextWithIdentity(ctx, velacontext.Identity{OrganizationID: 42})
+	envelope, err := NewEnvelope(ctx, "care-plans", "care_plan.updated", "2", plan{ID: 7})
+	require.NoError(t, err)
+
+	assert.Len(t, envelope.ID, 36)
+	assert.Equal(t, "care-plans", envelope.Source)
+	assert.Equal(t, "care_plan.updated", envelope.Type)
+	assert.Equal(t, "2", envelope.SchemaVersion)
+	assert.Equal(t, int64(42), envelope.OrganizationID)
+	assert.Equal(t, "req-1", envelope.RequestID)
+	assert.WithinDuration(t, time.Now(), envelope.Time, time.Second)
+	var decoded plan
+	require.NoError(t, envelope.Decode(&decoded))
+	assert.Equal(t, 7, decoded.ID)
+
+	_, err = NewEnvelope(ctx, "care-plans", "bad", "1", func() {})
+	assert.Error(t, err)
+}
+
+type mockSNS struct {
+	batches [][]types.PublishBatchRequestEntry
+	// fail is called for each entry, and returns its failure
+	fail func(attempt int, id string) *types.BatchResultErrorEntry
+}
+
+func (m *mockSNS) PublishBatch(ctx context.Context, in *sns.PublishBatchInput, _ ...func(*sns.Options)) (*sns.PublishBatchOutput, error) {
+	m.batches = append(m.batches, in.PublishBatchRequestEntries)
+	out := &sns.PublishBatchOutput{}
+	for _, entry := range in.PublishBatchRequestEntries {
+		if m.fail != nil {
+			if failure := m.fail(len(m.batches), aws.ToString(entry.Id)); failure != nil {
+				failure.Id = entry.Id
+				out.Failed = append(out.Failed, *failure)
+				continue
+			}
+		}
+		out.Successful = append(out.Successful, types.PublishBatchResultEntry{Id: entry.Id})
+	}
+	return out, nil
+}
+
+func TestSNS(t *testing.T) {
+	ctx := velacontext.ContextWithRequestID(context.Background(), "req-1")
+	client := &mockSNS{}
+	publisher := NewSNS(SNSOptions{Client: client, TopicARN: "arn:aws:sns:us-east-1:1:events"})
+	require.NoError(t, publisher.Publish(ctx, envelopes(t, ctx, 23)...))
+
+	require.Len(t, client.batches, 3)
+	assert.Len(t, client.batches[0], 10)
+	assert.Len(t, client.batches[2], 3)
+	entry := client.batches[0][0]
+	assert.Equal(t, "req-1", aws.ToString(entry.MessageAttributes["X-Vela-Request-Id"].StringValue))
+	assert.Equal(t, "care_plan.updated", aws.ToString(entry.MessageAttributes[TypeAttribute].StringValue))
+	assert.Nil(t, entry.MessageGroupId)
+	var envelope Envelope
+	require.NoError(t, json.Unmarshal([]byte(aws.ToString(entry.Message)), &envelope))
+	assert.Equal(t, aws.ToString(entry.Id), envelope.ID)
+	assert.JSONEq(t, `{"id":0}`, string(envelope.Data))
+}
+
+func TestSNSBatchSize(t *testing.T) {
+	ctx := context.Background()
+	client := &mockSNS{}
+	publisher := NewSNS(SNSOptions{Client: client, TopicARN: "arn", MessageGroupID: func(e Envelope) string { return e.Source }})
+	var large []Envelope
+	for i := 0; i < 3; i++ {
+		envelope, err := NewEnvelope(ctx, "notes", "note.created", "1", strings.Repeat("x", 100*1024))
+		require.NoError(t, err)
+		large = append(large, envelope)
+	}
+	require.NoError(t, publisher.Publish(ctx, large...))
+
+	require.Len(t, client.batches, 2)
+	assert.Len(t, client.batches[0], 2)
+	assert.Equal(t, "notes", aws.ToString(client.batches[0][0].MessageGroupId))
+	assert.Equal(t, aws.ToString(client.batches[0][0].Id), aws.ToString(client.batches[0][0].MessageDeduplicationId))
+}
+
+func TestSNSFailures(t *testing.T) {
+	ctx := context.Background()
+	sent := envelopes(t, ctx, 3)
+	client := &mockSNS{fail: func(attempt int, id string) *types.BatchResultErrorEntry {
+		switch {
+		case id == sent[0].ID:
+			return &types.BatchResultErrorEntry{Code: aws.String("InvalidParameter"), Message: aws.String("bad"), SenderFault: true}
+		case id == sent[1].ID && attempt == 1:
+			return &types.BatchResultErrorEntry{Code: aws.String("InternalError"), Message: aws.String("try again")}
+		}
+		return nil
+	}}
+	publisher := NewSNS(SNSOptions{Client: client, TopicARN: "arn", Retry: retry.Policy{InitialDelay: time.Millisecond}})
+	err := publisher.Publish(ctx, sent...)
+
+	var publishErr *Error
+	require.True(t, errors.As(err, &publishErr))
+	assert.Equal(t, []Failure{{ID: sent[0].ID, Code: "InvalidParameter", Message: "bad"}}, publishErr.Failed)
+	require.Len(t, client.batches, 2)
+	assert.Len(t, client.batches[1], 1)
+	assert.Equal(t, sent[1].ID, aws.ToString(client.batches[1][0].Id))
+}
+
+func TestEventBridge(t *testing.T) {
+	ctx := velacontext.ContextWithAmznTraceID(context.Background(), "Root=1-abc")
+	sent := envelopes(t, ctx, 12)
+	var calls [][]EventBridgeEntry
+	publisher := NewEventBridge(EventBridgeOptions{
+		Client: EventBridgeFunc(func(ctx context.Context, entries []EventBridgeEntry) ([]EventBridgeResult, error) {
+			calls = append(calls, entries)
+			results := make([]EventBridgeResult, len(entries))
+			switch len(calls) {
+			case 1:
+				results[0] = EventBridgeResult{ErrorCode: "MalformedDetail", ErrorMessage: "bad"}
+				results[1] = EventBridgeResult{ErrorCode: "ThrottlingException", ErrorMessage: "slow down"}
+			}
+			return results, nil
+		}),
+		BusName: "vela",
+		Retry:   retry.Policy{InitialDelay: time.Millisecond},
+	})
+	err := publisher.Publish(ctx, sent...)
+
+	// The throttled entry is retried, and the malformed one isn't
+	var publishErr *Error
+	require.True(t, errors.As(err, &publishErr))
+	assert.Equal(t, []Failure{{ID: sent[0].ID, Code: "MalformedDetail", Message: "bad"}}, publishErr.Failed)
+	require.Len(t, calls, 3)
+	assert.Len(t, calls[0], 10)
+	require.Len(t, calls[1], 1)
+	assert.Contains(t, calls[1][0].Detail, sent[1].ID)
+	assert.Len(t, calls[2], 2)
+	entry := calls[0][0]
+	assert.Equal(t, "vela", entry.EventBusName)
+	assert.Equal(t, "care-plans", entry.Source)
+	assert.Equal(t, "care_plan.updated", entry.DetailType)
+	assert.Equal(t, "Root=1-abc", entry.TraceHeader)
+	assert.Contains(t, entry.Detail, sent[0].ID)
+
+	publisher = NewEventBridge(EventBridgeOptions{
+		Client: EventBridgeFunc(func(context.Context, []EventBridgeEntry) ([]EventBridgeResult, error) {
+			return nil, errors.New("unavailable")
+		}),
+		BusName: "vela",
+	})
+	assert.EqualError(t, publisher.Publish(ctx, sent[0]), "unable to put events on vela: unavailable")
+}
+
+type mockSQS struct {
+	batches [][]sqstypes.SendMessageBatchRequestEntry
+}
+
+func (m *mockSQS) SendMessageBatch(ctx context.Context, in *awssqs.SendMessageBatchInput, _ ...func(*awssqs.Options)) (*awssqs.SendMessageBatchOutput, error) {
+	m.batches = append(m.batches, in.Entries)
+	return &awssqs.SendMessageBatchOutput{Failed: []sqstypes.BatchResultErrorEntry{
+		{Id: in.Entries[0].Id, Code: aws.String("InvalidMessageContents"), Message: aws.String("bad"), SenderFault: true},
+	}}, nil
+}
+
+func TestSQS(t *testing.T) {
+	ctx := velacontext.ContextWithRequestID(context.Background(), "req-1")
+	client := &mockSQS{}
+	publisher := NewSQS(SQSOptions{Client: client, QueueURL: "https://sqs/queue"})
+	sent := envelopes(t, ctx, 2)
+	err := publisher.Publish(ctx, sent...)
+
+	var publishErr *Error
+	require.True(t, errors.As(err, &publishErr))
+	assert.Equal(t, []Failure{{ID: sent[0].ID, Code: "InvalidMessageContents", Message: "bad"}}, publishErr.Failed)
+	require.Len(t, client.batches, 1)
+	entry := client.batches[0][1]
+	assert.Equal(t, "req-1", aws.ToString(entry.MessageAttributes["X-Vela-Request-Id"].StringValue))
+	assert.Contains(t, aws.ToString(entry.MessageBody), sent[1].ID)
+}
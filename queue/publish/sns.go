@@ -0,0 +1,93 @@
+package publish
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+
+	"github.com/seniorlink-vela/cs-common/retry"
+)
+
+// SNSAPI is the part of the SNS client the publisher uses.  *sns.Client
+// implements it.
+type SNSAPI interface {
+	PublishBatch(ctx context.Context, params *sns.PublishBatchInput, optFns ...func(*sns.Options)) (*sns.PublishBatchOutput, error)
+}
+
+var _ SNSAPI = (*sns.Client)(nil)
+
+// SNSOptions configure an SNS publisher.
+type SNSOptions struct {
+	Client   SNSAPI
+	TopicARN string
+	// MessageGroupID picks the message group for FIFO topics.  The
+	// envelope ID is used for deduplication.
+	MessageGroupID func(Envelope) string
+	// Retry is the policy for messages SNS fails to publish, other than
+	// because of the message.
+	Retry retry.Policy
+}
+
+// SNS publishes envelopes to an SNS topic.  Each envelope is a message,
+// with its request ID, type and schema version as message attributes, so
+// the SQS consumer picks up the request ID when raw message delivery is on.
+type SNS struct {
+	options SNSOptions
+}
+
+var _ Publisher = (*SNS)(nil)
+
+// NewSNS returns a publisher for the topic.
+func NewSNS(options SNSOptions) *SNS {
+	if options.Retry.Retryable == nil {
+		options.Retry.Retryable = retryable
+	}
+	return &SNS{options: options}
+}
+
+// Publish publishes the envelopes in as few batches as it can, retrying the
+// ones that fail to publish for reasons other than the message.  It returns
+// an *Error listing the ones that didn't publish.
+func (p *SNS) Publish(ctx context.Context, envelopes ...Envelope) error {
+	return publishBatches(ctx, p.options.Retry, envelopes, p.send)
+}
+
+// The publisher's retry policy does the retrying, so the SDK doesn't.
+func singleSNSAttempt(o *sns.Options) {
+	o.RetryMaxAttempts = 1
+}
+
+func (p *SNS) send(ctx context.Context, batch []message) ([]rejection, error) {
+	entries := make([]types.PublishBatchRequestEntry, len(batch))
+	for i, m := range batch {
+		entries[i] = types.PublishBatchRequestEntry{
+			Id:                aws.String(m.envelope.ID),
+			Message:           aws.String(m.body),
+			MessageAttributes: map[string]types.MessageAttributeValue{},
+		}
+		for name, value := range m.attributes {
+			entries[i].MessageAttributes[name] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(value)}
+		}
+		if p.options.MessageGroupID != nil {
+			entries[i].MessageGroupId = aws.String(p.options.MessageGroupID(m.envelope))
+			entries[i].MessageDeduplicationId = aws.String(m.envelope.ID)
+		}
+	}
+	out, err := p.options.Client.PublishBatch(ctx, &sns.PublishBatchInput{
+		TopicArn:                   aws.String(p.options.TopicARN),
+		PublishBatchRequestEntries: entries,
+	}, singleSNSAttempt)
+	if err != nil {
+		return nil, err
+	}
+	rejections := make([]rejection, len(out.Failed))
+	for i, entry := range out.Failed {
+		rejections[i] = rejection{
+			Failure:     Failure{ID: aws.ToString(entry.Id), Code: aws.ToString(entry.Code), Message: aws.ToString(entry.Message)},
+			SenderFault: entry.SenderFault,
+		}
+	}
+	return rejections, nil
+}
@@ -0,0 +1,92 @@
+package publish
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+
+	"github.com/seniorlink-vela/cs-common/retry"
+)
+
+// SQSAPI is the part of the SQS client the publisher uses.  *sqs.Client
+// implements it.
+type SQSAPI interface {
+	SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error)
+}
+
+var _ SQSAPI = (*sqs.Client)(nil)
+
+// SQSOptions configure an SQS publisher.
+type SQSOptions struct {
+	Client   SQSAPI
+	QueueURL string
+	// MessageGroupID picks the message group for FIFO queues.  The
+	// envelope ID is used for deduplication.
+	MessageGroupID func(Envelope) string
+	// Retry is the policy for messages SQS fails to send, other than
+	// because of the message.
+	Retry retry.Policy
+}
+
+// SQS sends envelopes straight to an SQS queue, with the same message
+// attributes as SNS.
+type SQS struct {
+	options SQSOptions
+}
+
+var _ Publisher = (*SQS)(nil)
+
+// NewSQS returns a publisher for the queue.
+func NewSQS(options SQSOptions) *SQS {
+	if options.Retry.Retryable == nil {
+		options.Retry.Retryable = retryable
+	}
+	return &SQS{options: options}
+}
+
+// Publish sends the envelopes in as few batches as it can, retrying the ones
+// that fail to send for reasons other than the message.  It returns an
+// *Error listing the ones that didn't send.
+func (p *SQS) Publish(ctx context.Context, envelopes ...Envelope) error {
+	return publishBatches(ctx, p.options.Retry, envelopes, p.send)
+}
+
+// The publisher's retry policy does the retrying, so the SDK doesn't.
+func singleSQSAttempt(o *sqs.Options) {
+	o.RetryMaxAttempts = 1
+}
+
+func (p *SQS) send(ctx context.Context, batch []message) ([]rejection, error) {
+	entries := make([]types.SendMessageBatchRequestEntry, len(batch))
+	for i, m := range batch {
+		entries[i] = types.SendMessageBatchRequestEntry{
+			Id:                aws.String(m.envelope.ID),
+			MessageBody:       aws.String(m.body),
+			MessageAttributes: map[string]types.MessageAttributeValue{},
+		}
+		for name, value := range m.attributes {
+			entries[i].MessageAttributes[name] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(value)}
+		}
+		if p.options.MessageGroupID != nil {
+			entries[i].MessageGroupId = aws.String(p.options.MessageGroupID(m.envelope))
+			entries[i].MessageDeduplicationId = aws.String(m.envelope.ID)
+		}
+	}
+	out, err := p.options.Client.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+		QueueUrl: aws.String(p.options.QueueURL),
+		Entries:  entries,
+	}, singleSQSAttempt)
+	if err != nil {
+		return nil, err
+	}
+	rejections := make([]rejection, len(out.Failed))
+	for i, entry := range out.Failed {
+		rejections[i] = rejection{
+			Failure:     Failure{ID: aws.ToString(entry.Id), Code: aws.ToString(entry.Code), Message: aws.ToString(entry.Message)},
+			SenderFault: entry.SenderFault,
+		}
+	}
+	return rejections, nil
+}
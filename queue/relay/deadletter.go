@@ -0,0 +1,204 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+
+	"github.com/seniorlink-vela/cs-common/client"
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+// The stages an event can fail at.
+const (
+	StageTransform = "transform"
+	StagePublish   = "publish"
+)
+
+// SQS accepts at most this many messages in a batch.
+const maxSQSBatch = 10
+
+// DeadLetter is an event that couldn't be forwarded, and why.
+type DeadLetter struct {
+	Event     client.Event `json:"event"`
+	Route     string       `json:"route,omitempty"`
+	Stage     string       `json:"stage"`
+	Error     string       `json:"error"`
+	Attempts  int          `json:"attempts"`
+	FailedAt  time.Time    `json:"failed_at"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+func newDeadLetter(ctx context.Context, event client.Event, route Route, stage string, err error, attempts int) DeadLetter {
+	requestID, _ := velacontext.LookupRequestID(ctx)
+	return DeadLetter{
+		Event:     event,
+		Route:     route.Name,
+		Stage:     stage,
+		Error:     err.Error(),
+		Attempts:  attempts,
+		FailedAt:  time.Now().UTC(),
+		RequestID: requestID,
+	}
+}
+
+// DeadLetterSink keeps the events that couldn't be forwarded, so they can be
+// looked at and replayed.
+type DeadLetterSink interface {
+	WriteDeadLetters(ctx context.Context, letters []DeadLetter) error
+}
+
+// SQSAPI is the part of the SQS client used to dead letter events.
+// *sqs.Client implements it.
+type SQSAPI interface {
+	SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error)
+}
+
+var _ SQSAPI = (*sqs.Client)(nil)
+
+// SQSSink sends each dead letter to a queue as a JSON message.
+type SQSSink struct {
+	Client   SQSAPI
+	QueueURL string
+}
+
+func (s *SQSSink) WriteDeadLetters(ctx context.Context, letters []DeadLetter) error {
+	for start := 0; start < len(letters); start += maxSQSBatch {
+		end := min(start+maxSQSBatch, len(letters))
+		entries := make([]sqstypes.SendMessageBatchRequestEntry, 0, end-start)
+		for i, letter := range letters[start:end] {
+			body, err := json.Marshal(letter)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, sqstypes.SendMessageBatchRequestEntry{
+				Id:          aws.String(strconv.Itoa(start + i)),
+				MessageBody: aws.String(string(body)),
+			})
+		}
+		out, err := s.Client.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+			QueueUrl: aws.String(s.QueueURL),
+			Entries:  entries,
+		})
+		if err != nil {
+			return err
+		}
+		if len(out.Failed) > 0 {
+			return fmt.Errorf("relay: %d of %d dead letters weren't queued: %s", len(out.Failed), len(entries), aws.ToString(out.Failed[0].Message))
+		}
+	}
+	return nil
+}
+
+// S3API is the part of the S3 client used to dead letter events.
+// *s3.Client implements it.
+type S3API interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+var _ S3API = (*s3.Client)(nil)
+
+// S3Sink writes each dead letter to a bucket as a JSON object, under
+// Prefix/<date>/<event ID>-<time>.json, encrypted with KMS since events
+// can be about care recipients.
+type S3Sink struct {
+	Client S3API
+	Bucket string
+	Prefix string
+	// KMSKeyID is the key dead letters are encrypted with.  It defaults to
+	// the account's AWS managed key for S3.
+	KMSKeyID string
+}
+
+func (s *S3Sink) WriteDeadLetters(ctx context.Context, letters []DeadLetter) error {
+	for _, letter := range letters {
+		body, err := json.Marshal(letter)
+		if err != nil {
+			return err
+		}
+		key := path.Join(s.Prefix, letter.FailedAt.Format("2006/01/02"), fmt.Sprintf("%d-%d.json", letter.Event.ID, letter.FailedAt.UnixNano()))
+		_, err = s.Client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(s.Bucket),
+			Key:         aws.String(key),
+			Body:        bytes.NewReader(body),
+			ContentType: aws.String("application/json"),
+
+			ServerSideEncryption: s3types.ServerSideEncryptionAwsKms,
+			SSEKMSKeyId:          optional(s.KMSKeyID),
+			// Saves a KMS call per object
+			BucketKeyEnabled: aws.Bool(true),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DynamoDBAPI is the part of the DynamoDB client used to dead letter events.
+// *dynamodb.Client implements it.
+type DynamoDBAPI interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+}
+
+var _ DynamoDBAPI = (*dynamodb.Client)(nil)
+
+// DynamoDBSink writes each dead letter to a table as an item.  The table's
+// key is event_id (a number) and failed_at (a string), and the event is
+// kept as JSON.
+type DynamoDBSink struct {
+	Client DynamoDBAPI
+	Table  string
+}
+
+func (s *DynamoDBSink) WriteDeadLetters(ctx context.Context, letters []DeadLetter) error {
+	for _, letter := range letters {
+		event, err := json.Marshal(letter.Event)
+		if err != nil {
+			return err
+		}
+		item := map[string]dynamodbtypes.AttributeValue{
+			"event_id":     &dynamodbtypes.AttributeValueMemberN{Value: strconv.FormatInt(letter.Event.ID, 10)},
+			"failed_at":    &dynamodbtypes.AttributeValueMemberS{Value: letter.FailedAt.Format(time.RFC3339Nano)},
+			"message_uuid": &dynamodbtypes.AttributeValueMemberS{Value: letter.Event.MessageUUID},
+			"slug":         &dynamodbtypes.AttributeValueMemberS{Value: letter.Event.EventType},
+			"stage":        &dynamodbtypes.AttributeValueMemberS{Value: letter.Stage},
+			"error":        &dynamodbtypes.AttributeValueMemberS{Value: letter.Error},
+			"attempts":     &dynamodbtypes.AttributeValueMemberN{Value: strconv.Itoa(letter.Attempts)},
+			"event":        &dynamodbtypes.AttributeValueMemberS{Value: string(event)},
+		}
+		if letter.Route != "" {
+			item["route"] = &dynamodbtypes.AttributeValueMemberS{Value: letter.Route}
+		}
+		if letter.RequestID != "" {
+			item["request_id"] = &dynamodbtypes.AttributeValueMemberS{Value: letter.RequestID}
+		}
+		_, err = s.Client.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(s.Table),
+			Item:      item,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
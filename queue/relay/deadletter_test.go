@@ -0,0 +1,180 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/seniorlink-vela/cs-common/client"
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/queue/publish"
+)
+
+type letterSink struct {
+	letters []DeadLetter
+	err     error
+}
+
+func (s *letterSink) WriteDeadLetters(ctx context.Context, letters []DeadLetter) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.letters = append(s.letters, letters...)
+	return nil
+}
+
+// flaky rejects the envelopes with the IDs every time, and publishes the
+// rest.
+type flaky struct {
+	reject    map[string]bool
+	calls     int
+	published []string
+}
+
+func (f *flaky) Publish(ctx context.Context, envelopes ...publish.Envelope) error {
+	f.calls++
+	var failed []publish.Failure
+	for _, envelope := range envelopes {
+		if f.reject[envelope.ID] {
+			failed = append(failed, publish.Failure{ID: envelope.ID, Code: "InvalidParameter", Message: "too big"})
+			continue
+		}
+		f.published = append(f.published, envelope.ID)
+	}
+	if len(failed) > 0 {
+		return &publish.Error{Failed: failed}
+	}
+	return nil
+}
+
+func TestDeadLetter(t *testing.T) {
+	ctx := velacontext.ContextWithRequestID(context.Background(), "req-1")
+	source := &mockSource{events: []client.Event{event(1, "a"), event(2, "b"), event(3, "c")}}
+	publisher := &flaky{reject: map[string]bool{"uuid-b": true}}
+	sink := &letterSink{}
+	r := New(Options{Source: source, Retry: fast, DeadLetter: sink, Routes: []Route{{
+		Name:      "topic",
+		Publisher: publisher,
+		Transform: func(ctx context.Context, event client.Event) (publish.Envelope, bool, error) {
+			if event.EventType == "c" {
+				return publish.Envelope{}, false, errors.New("bad payload")
+			}
+			return Envelope(ctx, event)
+		},
+	}}})
+
+	forwarded, err := r.Poll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, forwarded)
+	assert.Equal(t, int64(3), source.watermark)
+	assert.Equal(t, []string{"uuid-a"}, publisher.published)
+	// Only the failed envelope is retried
+	assert.Equal(t, 3, publisher.calls)
+
+	require.Len(t, sink.letters, 2)
+	transform, publishing := sink.letters[0], sink.letters[1]
+	assert.Equal(t, int64(3), transform.Event.ID)
+	assert.Equal(t, StageTransform, transform.Stage)
+	assert.Equal(t, "bad payload", transform.Error)
+	assert.Equal(t, int64(2), publishing.Event.ID)
+	assert.Equal(t, StagePublish, publishing.Stage)
+	assert.Equal(t, "topic", publishing.Route)
+	assert.Equal(t, "InvalidParameter: too big", publishing.Error)
+	assert.Equal(t, 3, publishing.Attempts)
+	assert.Equal(t, "req-1", publishing.RequestID)
+	assert.False(t, publishing.FailedAt.IsZero())
+}
+
+func TestDeadLetterSinkFails(t *testing.T) {
+	source := &mockSource{events: []client.Event{event(1, "a")}}
+	r := New(Options{
+		Source:     source,
+		Retry:      fast,
+		DeadLetter: &letterSink{err: errors.New("unavailable")},
+		Routes:     []Route{{Publisher: &recorder{err: errors.New("down")}}},
+	})
+	_, err := r.Poll(context.Background())
+	assert.EqualError(t, err, "unable to dead letter 1 events: unavailable")
+	assert.Zero(t, source.watermark)
+}
+
+var letter = DeadLetter{Event: event(7, "a"), Stage: StagePublish, Error: "down", Attempts: 3, RequestID: "req-1"}
+
+type mockSQS struct {
+	entries int
+}
+
+func (m *mockSQS) SendMessageBatch(ctx context.Context, in *sqs.SendMessageBatchInput, _ ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+	m.entries += len(in.Entries)
+	var decoded DeadLetter
+	if err := json.Unmarshal([]byte(aws.ToString(in.Entries[0].MessageBody)), &decoded); err != nil {
+		return nil, err
+	}
+	return &sqs.SendMessageBatchOutput{}, nil
+}
+
+func TestSQSSink(t *testing.T) {
+	client := &mockSQS{}
+	letters := make([]DeadLetter, 12)
+	for i := range letters {
+		letters[i] = letter
+	}
+	require.NoError(t, (&SQSSink{Client: client, QueueURL: "https://sqs/dlq"}).WriteDeadLetters(context.Background(), letters))
+	assert.Equal(t, 12, client.entries)
+}
+
+type mockS3 struct {
+	key  string
+	body []byte
+	in   *s3.PutObjectInput
+}
+
+func (m *mockS3) PutObject(ctx context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	m.in = in
+	m.key = aws.ToString(in.Key)
+	m.body, _ = io.ReadAll(in.Body)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func TestS3Sink(t *testing.T) {
+	client := &mockS3{}
+	require.NoError(t, (&S3Sink{Client: client, Bucket: "dlq", Prefix: "relay"}).WriteDeadLetters(context.Background(), []DeadLetter{letter}))
+	assert.Regexp(t, `^relay/0001/01/01/7-[-0-9]+\.json$`, client.key)
+	var decoded DeadLetter
+	require.NoError(t, json.Unmarshal(client.body, &decoded))
+	assert.Equal(t, letter.Event.MessageUUID, decoded.Event.MessageUUID)
+	assert.Equal(t, s3types.ServerSideEncryptionAwsKms, client.in.ServerSideEncryption)
+	assert.Nil(t, client.in.SSEKMSKeyId)
+
+	require.NoError(t, (&S3Sink{Client: client, Bucket: "dlq", KMSKeyID: "key-1"}).WriteDeadLetters(context.Background(), []DeadLetter{letter}))
+	assert.Equal(t, "key-1", aws.ToString(client.in.SSEKMSKeyId))
+}
+
+type mockDynamoDB struct {
+	item map[string]dynamodbtypes.AttributeValue
+}
+
+func (m *mockDynamoDB) PutItem(ctx context.Context, in *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	m.item = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func TestDynamoDBSink(t *testing.T) {
+	client := &mockDynamoDB{}
+	require.NoError(t, (&DynamoDBSink{Client: client, Table: "dlq"}).WriteDeadLetters(context.Background(), []DeadLetter{letter}))
+	assert.Equal(t, &dynamodbtypes.AttributeValueMemberN{Value: "7"}, client.item["event_id"])
+	assert.Equal(t, &dynamodbtypes.AttributeValueMemberS{Value: "down"}, client.item["error"])
+	assert.Equal(t, &dynamodbtypes.AttributeValueMemberS{Value: "req-1"}, client.item["request_id"])
+	assert.NotContains(t, client.item, "route")
+}
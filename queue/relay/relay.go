@@ -0,0 +1,423 @@
+// Package relay forwards events from a partner's Vela event queue to AWS
+// messaging: SNS topics, SQS queues and EventBridge buses.
+//
+//	r := relay.New(relay.Options{
+//		Source: relay.NewClientSource(token),
+//		Routes: []relay.Route{
+//			{Slugs: []string{"care_plan.updated"}, Publisher: topic},
+//			{Publisher: bus},
+//		},
+//	})
+//	err := r.Run(ctx)
+//
+// Delivery is at least once: the queue's watermark only moves past events
+// once every route they match has published them, so events are forwarded
+// again after a failure, and consumers should use the envelope ID, which is
+// the event's message UUID, to ignore duplicates.
+//
+// With a dead letter sink, events that still fail to publish after the
+// retry policy's attempts, or can't be transformed, are written to the sink
+// instead, and the watermark moves on, so one bad event doesn't stall the
+// queue.
+package relay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/seniorlink-vela/cs-common/client"
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/metrics"
+	"github.com/seniorlink-vela/cs-common/queue/publish"
+	"github.com/seniorlink-vela/cs-common/retry"
+)
+
+// The defaults for Options' zero fields.
+const (
+	DefaultBatchSize = 100
+	DefaultInterval  = 10 * time.Second
+	// DefaultSchemaVersion is the schema version of envelopes made from
+	// events, which don't have one.
+	DefaultSchemaVersion = "1"
+)
+
+// The metrics the relay records with metrics.Default.
+var (
+	forwardedMetric = metrics.Definition{
+		Name: "relay_events_forwarded",
+		Help: "Events forwarded from the event queue.",
+		Tags: []string{"slug"},
+	}
+	skippedMetric = metrics.Definition{
+		Name: "relay_events_skipped",
+		Help: "Events not forwarded because they couldn't be transformed.",
+		Tags: []string{"slug"},
+	}
+	deadLetteredMetric = metrics.Definition{
+		Name: "relay_events_dead_lettered",
+		Help: "Events written to the dead letter sink.",
+		Tags: []string{"slug", "stage"},
+	}
+)
+
+// Source is the event queue.
+type Source interface {
+	// Events returns up to max events after the watermark with the slugs,
+	// or any slug when there aren't any, and the index of the last one.
+	Events(ctx context.Context, max int64, slugs []string) ([]client.Event, int64, error)
+	// SetWatermark marks the events up to the index as read.
+	SetWatermark(ctx context.Context, index int64) error
+}
+
+// Transform turns an event into the envelope to publish.  It returns false
+// to leave the event out, and an error when the event can't be forwarded,
+// which isn't retried: the event is dead lettered, or logged and skipped
+// when there's no sink.
+type Transform func(ctx context.Context, event client.Event) (publish.Envelope, bool, error)
+
+// Route forwards events to a publisher.
+type Route struct {
+	// Name identifies the route in logs and dead letters.
+	Name string
+	// Slugs are the event types forwarded.  None forwards all of them.
+	Slugs     []string
+	Publisher publish.Publisher
+	// Transform defaults to Envelope.
+	Transform Transform
+}
+
+func (r Route) matches(slug string) bool {
+	if len(r.Slugs) == 0 {
+		return true
+	}
+	for _, s := range r.Slugs {
+		if s == slug {
+			return true
+		}
+	}
+	return false
+}
+
+// Options configure a relay.
+type Options struct {
+	Source Source
+	Routes []Route
+	// BatchSize is the most events read from the queue at once.
+	BatchSize int64
+	// Interval is how long Run waits to poll again when the queue is
+	// empty, or polling failed.
+	Interval time.Duration
+	// Retry is the policy for publishing to each route.  The events that
+	// still fail are dead lettered.
+	Retry retry.Policy
+	// DeadLetter is where events that can't be forwarded are written.
+	// Without one, the watermark stays put until events that fail to
+	// publish are forwarded, and events that can't be transformed are
+	// logged and skipped.
+	DeadLetter DeadLetterSink
+	// Logger defaults to the fallback logger.
+	Logger *zap.Logger
+}
+
+// Relay forwards events from the queue according to its routes.
+type Relay struct {
+	options Options
+	slugs   []string
+}
+
+// New returns a relay.
+func New(options Options) *Relay {
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultBatchSize
+	}
+	if options.Interval <= 0 {
+		options.Interval = DefaultInterval
+	}
+	if options.Logger == nil {
+		options.Logger = velacontext.FallbackLogger()
+	}
+	for i := range options.Routes {
+		if options.Routes[i].Transform == nil {
+			options.Routes[i].Transform = Envelope
+		}
+	}
+	return &Relay{options: options, slugs: slugs(options.Routes)}
+}
+
+// slugs returns the slugs to read from the queue, or nil for all of them
+// when a route takes any.
+func slugs(routes []Route) []string {
+	var all []string
+	seen := map[string]bool{}
+	for _, route := range routes {
+		if len(route.Slugs) == 0 {
+			return nil
+		}
+		for _, slug := range route.Slugs {
+			if !seen[slug] {
+				seen[slug] = true
+				all = append(all, slug)
+			}
+		}
+	}
+	return all
+}
+
+// Envelope is the default transform.  It keeps the event's message UUID,
+// source, slug, timestamp, and organization, and the payload is the data.
+func Envelope(ctx context.Context, event client.Event) (publish.Envelope, bool, error) {
+	envelope, err := publish.NewEnvelope(ctx, event.MessageSource, event.EventType, DefaultSchemaVersion, event.Payload)
+	if err != nil {
+		return publish.Envelope{}, false, err
+	}
+	if event.MessageUUID != "" {
+		envelope.ID = event.MessageUUID
+	}
+	if !event.MessageTimestamp.IsZero() {
+		envelope.Time = event.MessageTimestamp.UTC()
+	}
+	envelope.OrganizationID = event.OrganizationID
+	return envelope, true, nil
+}
+
+// Run forwards events until the context is done, polling again straight
+// away while there are more, and after the interval when the queue is
+// empty or polling failed.  It returns nil when the context is done.
+func (r *Relay) Run(ctx context.Context) error {
+	for ctx.Err() == nil {
+		forwarded, err := r.Poll(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.options.Logger.Error("Unable to forward events", zap.Error(err))
+		}
+		if err == nil && forwarded > 0 {
+			continue
+		}
+		timer := time.NewTimer(r.options.Interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+	return nil
+}
+
+// Poll reads a batch of events, publishes them, and moves the watermark
+// past them.  It returns how many events were read.  When publishing fails
+// the watermark stays put, so the batch is read again next time.
+func (r *Relay) Poll(ctx context.Context) (int, error) {
+	ctx, _ = velacontext.EnsureRequestID(ctx)
+	events, lastIndex, err := r.options.Source.Events(ctx, r.options.BatchSize, r.slugs)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read events: %w", err)
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	if err := r.forward(ctx, events); err != nil {
+		return 0, err
+	}
+	if err := r.options.Source.SetWatermark(ctx, lastIndex); err != nil {
+		return 0, fmt.Errorf("unable to set the watermark to %d: %w", lastIndex, err)
+	}
+	return len(events), nil
+}
+
+// pending is an event and its envelope for a route.
+type pending struct {
+	event    client.Event
+	envelope publish.Envelope
+}
+
+// forward publishes the events to each route they match, in order, and dead
+// letters the ones that can't be.
+func (r *Relay) forward(ctx context.Context, events []client.Event) error {
+	logger := velacontext.GetContextLogger(ctx)
+	provider := metrics.Default()
+	var letters []DeadLetter
+	routed := make([][]pending, len(r.options.Routes))
+	for _, event := range events {
+		for i, route := range r.options.Routes {
+			if !route.matches(event.EventType) {
+				continue
+			}
+			envelope, ok, err := route.Transform(ctx, event)
+			if err != nil {
+				if r.options.DeadLetter != nil {
+					letters = append(letters, newDeadLetter(ctx, event, route, StageTransform, err, 1))
+					continue
+				}
+				logger.Error("Unable to transform event, skipping it", zap.Int64("event_id", event.ID), zap.String("slug", event.EventType), zap.Error(err))
+				provider.Counter(skippedMetric).Inc(metrics.Tags{"slug": event.EventType})
+				continue
+			}
+			if ok {
+				routed[i] = append(routed[i], pending{event: event, envelope: envelope})
+			}
+		}
+	}
+
+	var errs []error
+	for i, batch := range routed {
+		if len(batch) == 0 {
+			continue
+		}
+		route := r.options.Routes[i]
+		failed, attempts, err := r.publish(ctx, route, batch)
+		var failedIDs []string
+		for _, p := range failed {
+			failedIDs = append(failedIDs, p.envelope.ID)
+		}
+		for _, p := range filter(batch, failedIDs, false) {
+			provider.Counter(forwardedMetric).Inc(metrics.Tags{"slug": p.envelope.Type})
+		}
+		if err == nil {
+			continue
+		}
+		if r.options.DeadLetter == nil || ctx.Err() != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, p := range failed {
+			letters = append(letters, newDeadLetter(ctx, p.event, route, StagePublish, failure(err, p.envelope.ID), attempts))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("unable to publish events: %w", errors.Join(errs...))
+	}
+	return r.deadLetter(ctx, letters)
+}
+
+// publish publishes the batch to the route, retrying the envelopes that
+// fail.  It returns the ones that never published, and how many attempts
+// were made.
+func (r *Relay) publish(ctx context.Context, route Route, batch []pending) ([]pending, int, error) {
+	remaining := batch
+	attempts := 0
+	err := retry.Do(ctx, r.options.Retry, func(ctx context.Context) error {
+		attempts++
+		envelopes := make([]publish.Envelope, len(remaining))
+		for i, p := range remaining {
+			envelopes[i] = p.envelope
+		}
+		err := route.Publisher.Publish(ctx, envelopes...)
+		var publishErr *publish.Error
+		if errors.As(err, &publishErr) {
+			var failed []string
+			for _, f := range publishErr.Failed {
+				failed = append(failed, f.ID)
+			}
+			remaining = filter(remaining, failed, true)
+		}
+		return err
+	})
+	if err == nil {
+		return nil, attempts, nil
+	}
+	return remaining, attempts, err
+}
+
+// filter returns the batch's envelopes with the IDs, or without them.
+func filter(batch []pending, ids []string, with bool) []pending {
+	set := map[string]bool{}
+	for _, id := range ids {
+		set[id] = true
+	}
+	var out []pending
+	for _, p := range batch {
+		if set[p.envelope.ID] == with {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// failure returns the reason the envelope failed to publish.
+func failure(err error, id string) error {
+	var publishErr *publish.Error
+	if errors.As(err, &publishErr) {
+		for _, f := range publishErr.Failed {
+			if f.ID == id {
+				return fmt.Errorf("%s: %s", f.Code, f.Message)
+			}
+		}
+	}
+	return err
+}
+
+// deadLetter writes the letters to the sink.  The watermark mustn't move
+// past them when it can't.
+func (r *Relay) deadLetter(ctx context.Context, letters []DeadLetter) error {
+	if len(letters) == 0 {
+		return nil
+	}
+	logger := velacontext.GetContextLogger(ctx)
+	for _, letter := range letters {
+		logger.Error("Dead lettering event",
+			zap.Int64("event_id", letter.Event.ID),
+			zap.String("slug", letter.Event.EventType),
+			zap.String("route", letter.Route),
+			zap.String("stage", letter.Stage),
+			zap.String("error", letter.Error),
+		)
+	}
+	if err := r.options.DeadLetter.WriteDeadLetters(ctx, letters); err != nil {
+		return fmt.Errorf("unable to dead letter %d events: %w", len(letters), err)
+	}
+	provider := metrics.Default()
+	for _, letter := range letters {
+		provider.Counter(deadLetteredMetric).Inc(metrics.Tags{"slug": letter.Event.EventType, "stage": letter.Stage})
+	}
+	return nil
+}
+
+// Replay moves the watermark back to the index, so the events after it are
+// read again, and forwards them until it's caught up.  It returns how many
+// events were forwarded.
+func (r *Relay) Replay(ctx context.Context, from int64) (int, error) {
+	if err := r.options.Source.SetWatermark(ctx, from); err != nil {
+		return 0, fmt.Errorf("unable to set the watermark to %d: %w", from, err)
+	}
+	r.options.Logger.Info("Replaying events", zap.Int64("from", from))
+	total := 0
+	for {
+		forwarded, err := r.Poll(ctx)
+		total += forwarded
+		if err != nil || forwarded == 0 {
+			return total, err
+		}
+	}
+}
+
+// clientSource reads the queue with the client package.
+type clientSource struct {
+	token func(ctx context.Context) (string, error)
+}
+
+// NewClientSource returns a source that reads the queue with the client
+// package, authenticating with the token the function returns, so it can be
+// refreshed.
+func NewClientSource(token func(ctx context.Context) (string, error)) Source {
+	return clientSource{token: token}
+}
+
+func (s clientSource) Events(ctx context.Context, max int64, slugs []string) ([]client.Event, int64, error) {
+	token, err := s.token(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return client.GetEventsForQueue(ctx, token, &max, slugs)
+}
+
+func (s clientSource) SetWatermark(ctx context.Context, index int64) error {
+	token, err := s.token(ctx)
+	if err != nil {
+		return err
+	}
+	return client.SetWatermarkForQueue(ctx, token, index)
+}
@@ -0,0 +1,198 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/seniorlink-vela/cs-common/client"
+	"github.com/seniorlink-vela/cs-common/queue/publish"
+	"github.com/seniorlink-vela/cs-common/retry"
+)
+
+// mockSource is a queue of events, indexed by their IDs.
+type mockSource struct {
+	mu        sync.Mutex
+	events    []client.Event
+	watermark int64
+	slugs     []string
+	err       error
+}
+
+func (s *mockSource) Events(ctx context.Context, max int64, slugs []string) ([]client.Event, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.slugs = slugs
+	if s.err != nil {
+		return nil, 0, s.err
+	}
+	var out []client.Event
+	for _, event := range s.events {
+		if event.ID > s.watermark && int64(len(out)) < max {
+			out = append(out, event)
+		}
+	}
+	if len(out) == 0 {
+		return nil, s.watermark, nil
+	}
+	return out, out[len(out)-1].ID, nil
+}
+
+func (s *mockSource) SetWatermark(ctx context.Context, index int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.watermark = index
+	return nil
+}
+
+func (s *mockSource) read() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.watermark
+}
+
+var fast = retry.Policy{InitialDelay: time.Millisecond, Jitter: -1}
+
+type recorder struct {
+	envelopes []publish.Envelope
+	err       error
+}
+
+func (r *recorder) Publish(ctx context.Context, envelopes ...publish.Envelope) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.envelopes = append(r.envelopes, envelopes...)
+	return nil
+}
+
+func event(id int64, slug string) client.Event {
+	return client.Event{
+		ID:               id,
+		EventType:        slug,
+		MessageSource:    "vela",
+		MessageUUID:      "uuid-" + slug,
+		MessageTimestamp: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		OrganizationID:   42,
+		Payload:          map[string]interface{}{"id": id},
+	}
+}
+
+func ids(envelopes []publish.Envelope) []string {
+	var out []string
+	for _, envelope := range envelopes {
+		out = append(out, envelope.ID)
+	}
+	return out
+}
+
+func TestPoll(t *testing.T) {
+	ctx := context.Background()
+	source := &mockSource{events: []client.Event{event(1, "a"), event(2, "b"), event(3, "c")}}
+	routeA, routeAll := &recorder{}, &recorder{}
+	r := New(Options{Source: source, BatchSize: 2, Routes: []Route{
+		{Slugs: []string{"a", "c"}, Publisher: routeA},
+		{Publisher: routeAll, Transform: func(ctx context.Context, event client.Event) (publish.Envelope, bool, error) {
+			if event.EventType == "b" {
+				return publish.Envelope{}, false, nil
+			}
+			return Envelope(ctx, event)
+		}},
+	}})
+
+	forwarded, err := r.Poll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, forwarded)
+	assert.Nil(t, source.slugs)
+	assert.Equal(t, int64(2), source.watermark)
+	assert.Equal(t, []string{"uuid-a"}, ids(routeA.envelopes))
+	assert.Equal(t, []string{"uuid-a"}, ids(routeAll.envelopes))
+
+	envelope := routeA.envelopes[0]
+	assert.Equal(t, "vela", envelope.Source)
+	assert.Equal(t, "a", envelope.Type)
+	assert.Equal(t, int64(42), envelope.OrganizationID)
+	assert.Equal(t, "1", envelope.SchemaVersion)
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), envelope.Time)
+	assert.JSONEq(t, `{"id":1}`, string(envelope.Data))
+
+	forwarded, err = r.Poll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, forwarded)
+	assert.Equal(t, []string{"uuid-a", "uuid-c"}, ids(routeA.envelopes))
+
+	forwarded, err = r.Poll(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, forwarded)
+}
+
+func TestPollSlugs(t *testing.T) {
+	source := &mockSource{}
+	r := New(Options{Source: source, Routes: []Route{
+		{Slugs: []string{"a", "b"}, Publisher: &recorder{}},
+		{Slugs: []string{"b", "c"}, Publisher: &recorder{}},
+	}})
+	_, err := r.Poll(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, source.slugs)
+}
+
+func TestPollFailures(t *testing.T) {
+	ctx := context.Background()
+	source := &mockSource{events: []client.Event{event(1, "a"), event(2, "b")}}
+	failing := &recorder{err: errors.New("unavailable")}
+	r := New(Options{Source: source, Retry: fast, Routes: []Route{{Publisher: failing}}})
+
+	// The watermark doesn't move, so the events are read again
+	_, err := r.Poll(ctx)
+	assert.EqualError(t, err, "unable to publish events: unavailable")
+	assert.Zero(t, source.watermark)
+
+	// Events that can't be transformed are skipped
+	failing.err = nil
+	r.options.Routes[0].Transform = func(ctx context.Context, event client.Event) (publish.Envelope, bool, error) {
+		if event.EventType == "a" {
+			return publish.Envelope{}, false, errors.New("bad payload")
+		}
+		return Envelope(ctx, event)
+	}
+	forwarded, err := r.Poll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, forwarded)
+	assert.Equal(t, int64(2), source.watermark)
+	assert.Equal(t, []string{"uuid-b"}, ids(failing.envelopes))
+
+	source.err = errors.New("down")
+	_, err = r.Poll(ctx)
+	assert.EqualError(t, err, "unable to read events: down")
+}
+
+func TestReplay(t *testing.T) {
+	source := &mockSource{events: []client.Event{event(1, "a"), event(2, "b"), event(3, "c")}, watermark: 3}
+	publisher := &recorder{}
+	r := New(Options{Source: source, BatchSize: 1, Routes: []Route{{Publisher: publisher}}})
+
+	forwarded, err := r.Replay(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, forwarded)
+	assert.Equal(t, []string{"uuid-b", "uuid-c"}, ids(publisher.envelopes))
+	assert.Equal(t, int64(3), source.watermark)
+}
+
+func TestRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	source := &mockSource{events: []client.Event{event(1, "a"), event(2, "b")}}
+	publisher := &recorder{}
+	r := New(Options{Source: source, BatchSize: 1, Interval: time.Millisecond, Routes: []Route{{Publisher: publisher}}})
+
+	done := make(chan error)
+	go func() { done <- r.Run(ctx) }()
+	require.Eventually(t, func() bool { return source.read() == 2 }, time.Second, time.Millisecond)
+	cancel()
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,365 @@
+// Package sqs consumes SQS queues, handing each message to a handler with a
+// context set up the way the HTTP middleware sets up requests: a request ID,
+// taken from the message when the sender set one, a logger, and baggage.
+//
+//	consumer := sqs.NewConsumer(sqs.Options{Client: client, QueueURL: url, Logger: logger, Name: "invites"},
+//		func(ctx context.Context, msg sqs.Message) error {
+//			return sendInvite(ctx, msg.Body)
+//		})
+//	err := consumer.Run(ctx)
+//
+// Messages are deleted when the handler succeeds.  When it fails, the message
+// is made visible again after a backoff, and the queue's redrive policy moves
+// it to the dead letter queue after its maximum receives.  Messages that fail
+// permanently are sent straight to Options.DeadLetterQueueURL when there is
+// one, and otherwise left for the redrive policy too.
+package sqs
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awssqs "github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/context/httpmiddleware"
+	"github.com/seniorlink-vela/cs-common/retry"
+)
+
+// The defaults for Options' zero fields.
+const (
+	DefaultConcurrency       = 10
+	DefaultWaitTime          = 20 * time.Second
+	DefaultVisibilityTimeout = 30 * time.Second
+	DefaultShutdownTimeout   = 30 * time.Second
+)
+
+// SQS won't return more than 10 messages at once, or hide one for more than
+// 12 hours.
+const (
+	maxBatch      = 10
+	maxVisibility = 12 * time.Hour
+)
+
+// API is the part of the SQS client the consumer uses.  *sqs.Client
+// implements it.
+type API interface {
+	ReceiveMessage(ctx context.Context, params *awssqs.ReceiveMessageInput, optFns ...func(*awssqs.Options)) (*awssqs.ReceiveMessageOutput, error)
+	DeleteMessage(ctx context.Context, params *awssqs.DeleteMessageInput, optFns ...func(*awssqs.Options)) (*awssqs.DeleteMessageOutput, error)
+	ChangeMessageVisibility(ctx context.Context, params *awssqs.ChangeMessageVisibilityInput, optFns ...func(*awssqs.Options)) (*awssqs.ChangeMessageVisibilityOutput, error)
+	SendMessage(ctx context.Context, params *awssqs.SendMessageInput, optFns ...func(*awssqs.Options)) (*awssqs.SendMessageOutput, error)
+}
+
+var _ API = (*awssqs.Client)(nil)
+
+// Message is a message received from the queue.
+type Message struct {
+	ID            string
+	Body          string
+	ReceiptHandle string
+	// Attributes are the string message attributes.
+	Attributes map[string]string
+	// ReceiveCount is how many times the message has been received,
+	// including this time.
+	ReceiveCount int
+}
+
+// Handler handles a message.  Returning an error leaves the message on the
+// queue to be retried, unless it's marked retry.Permanent, when the message
+// is moved to the dead letter queue.
+type Handler func(ctx context.Context, msg Message) error
+
+// Options configure a consumer.
+type Options struct {
+	Client   API
+	QueueURL string
+	// DeadLetterQueueURL is where messages that fail permanently are sent
+	// before they're deleted.  Without one, they're left on the queue until
+	// its redrive policy moves them.
+	DeadLetterQueueURL string
+	// Concurrency is how many messages are handled at once.
+	Concurrency int
+	// WaitTime is how long each receive waits for messages, up to 20
+	// seconds.
+	WaitTime time.Duration
+	// VisibilityTimeout is how long a received message is hidden from other
+	// consumers.  It's extended while the handler is still running.
+	VisibilityTimeout time.Duration
+	// Backoff decides how long a failed message waits before it's retried,
+	// by its receive count.  Its MaxAttempts isn't used; the queue's redrive
+	// policy decides when a message has failed too often.
+	Backoff retry.Policy
+	// ShutdownTimeout is how long Run waits for handlers to finish after its
+	// context is done, before cancelling theirs.
+	ShutdownTimeout time.Duration
+	// Logger is named Name, and used for each message's context.  It
+	// defaults to the fallback logger.
+	Logger *zap.Logger
+	Name   string
+}
+
+// Consumer receives messages and hands them to a handler.
+type Consumer struct {
+	options Options
+	handler Handler
+	logger  *zap.Logger
+}
+
+// NewConsumer returns a consumer for the queue.
+func NewConsumer(options Options, handler Handler) *Consumer {
+	if options.Concurrency <= 0 {
+		options.Concurrency = DefaultConcurrency
+	}
+	if options.WaitTime <= 0 {
+		options.WaitTime = DefaultWaitTime
+	}
+	if options.VisibilityTimeout <= 0 {
+		options.VisibilityTimeout = DefaultVisibilityTimeout
+	}
+	if options.ShutdownTimeout <= 0 {
+		options.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if options.Backoff.InitialDelay <= 0 {
+		options.Backoff.InitialDelay = time.Second
+	}
+	if options.Backoff.MaxDelay <= 0 {
+		options.Backoff.MaxDelay = 15 * time.Minute
+	}
+	logger := options.Logger
+	if logger == nil {
+		logger = velacontext.FallbackLogger()
+	}
+	if options.Name != "" {
+		logger = logger.Named(options.Name)
+	}
+	return &Consumer{options: options, handler: handler, logger: logger.With(zap.String("queue_url", options.QueueURL))}
+}
+
+// Run receives and handles messages until the context is done, then waits
+// for the handlers that are running to finish, for up to the shutdown
+// timeout, and returns nil.
+func (c *Consumer) Run(ctx context.Context) error {
+	// Handlers keep going when receiving stops, until the shutdown timeout
+	handlerCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelHandlers()
+
+	slots := make(chan struct{}, c.options.Concurrency)
+	var wg sync.WaitGroup
+	failures := 0
+	for ctx.Err() == nil {
+		// Only ask for as many messages as can be handled now
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			continue
+		}
+		free := 1
+		for free < maxBatch && len(slots) < cap(slots) {
+			slots <- struct{}{}
+			free++
+		}
+
+		messages, err := c.receive(ctx, free)
+		for i := len(messages); i < free; i++ {
+			<-slots
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			failures++
+			c.logger.Error("Unable to receive messages", zap.Error(err))
+			sleep(ctx, c.options.Backoff.Delay(failures))
+			continue
+		}
+		failures = 0
+
+		for _, message := range messages {
+			wg.Add(1)
+			go func(message types.Message) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				c.handle(handlerCtx, message)
+			}(message)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(c.options.ShutdownTimeout):
+		c.logger.Warn("Cancelling message handlers that didn't finish", zap.Duration("shutdown_timeout", c.options.ShutdownTimeout))
+		cancelHandlers()
+		<-done
+	}
+	return nil
+}
+
+func (c *Consumer) receive(ctx context.Context, count int) ([]types.Message, error) {
+	out, err := c.options.Client.ReceiveMessage(ctx, &awssqs.ReceiveMessageInput{
+		QueueUrl:                    aws.String(c.options.QueueURL),
+		MaxNumberOfMessages:         int32(count),
+		WaitTimeSeconds:             int32(c.options.WaitTime / time.Second),
+		VisibilityTimeout:           seconds(c.options.VisibilityTimeout),
+		MessageAttributeNames:       []string{"All"},
+		MessageSystemAttributeNames: []types.MessageSystemAttributeName{types.MessageSystemAttributeNameApproximateReceiveCount, types.MessageSystemAttributeNameAWSTraceHeader},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Messages, nil
+}
+
+// handle runs the handler, keeping the message hidden while it does, and
+// deletes the message or schedules its retry.
+func (c *Consumer) handle(ctx context.Context, raw types.Message) {
+	message := newMessage(raw)
+	ctx = c.messageContext(ctx, message, raw)
+	logger := velacontext.GetContextLogger(ctx)
+
+	stopExtending := c.extendVisibility(ctx, message)
+	err := c.run(ctx, message)
+	stopExtending()
+
+	switch {
+	case err == nil:
+		c.delete(ctx, message)
+	case retry.IsPermanent(err):
+		c.deadLetter(ctx, message, raw, err)
+	default:
+		delay := c.options.Backoff.Delay(message.ReceiveCount)
+		logger.Warn("Message failed, it will be retried", zap.Int("receive_count", message.ReceiveCount), zap.Duration("retry_in", delay), zap.Error(err))
+		c.changeVisibility(ctx, message, delay)
+	}
+}
+
+// deadLetter moves a message that failed permanently to the dead letter
+// queue.  When there isn't one, or the message can't be sent to it, it's left
+// to become visible again, so it isn't lost.
+func (c *Consumer) deadLetter(ctx context.Context, message Message, raw types.Message, err error) {
+	logger := velacontext.GetContextLogger(ctx).With(zap.Int("receive_count", message.ReceiveCount), zap.Error(err))
+	if c.options.DeadLetterQueueURL == "" {
+		logger.Error("Message failed permanently, leaving it for the redrive policy")
+		return
+	}
+	_, sendErr := c.options.Client.SendMessage(ctx, &awssqs.SendMessageInput{
+		QueueUrl:          aws.String(c.options.DeadLetterQueueURL),
+		MessageBody:       raw.Body,
+		MessageAttributes: raw.MessageAttributes,
+	})
+	if sendErr != nil {
+		logger.Error("Message failed permanently, and can't be sent to the dead letter queue", zap.NamedError("send_error", sendErr))
+		return
+	}
+	logger.Error("Message failed permanently, moved it to the dead letter queue")
+	c.delete(ctx, message)
+}
+
+func (c *Consumer) run(ctx context.Context, message Message) (err error) {
+	defer velacontext.Recover(ctx, func(panicErr error) { err = panicErr })
+	return c.handler(ctx, message)
+}
+
+// extendVisibility keeps the message hidden, extending its timeout halfway
+// through each period, until the returned function is called.
+func (c *Consumer) extendVisibility(ctx context.Context, message Message) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(c.options.VisibilityTimeout / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.changeVisibility(ctx, message, c.options.VisibilityTimeout)
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func (c *Consumer) delete(ctx context.Context, message Message) {
+	_, err := c.options.Client.DeleteMessage(ctx, &awssqs.DeleteMessageInput{
+		QueueUrl:      aws.String(c.options.QueueURL),
+		ReceiptHandle: aws.String(message.ReceiptHandle),
+	})
+	if err != nil {
+		velacontext.GetContextLogger(ctx).Error("Unable to delete message", zap.Error(err))
+	}
+}
+
+func (c *Consumer) changeVisibility(ctx context.Context, message Message, timeout time.Duration) {
+	_, err := c.options.Client.ChangeMessageVisibility(ctx, &awssqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(c.options.QueueURL),
+		ReceiptHandle:     aws.String(message.ReceiptHandle),
+		VisibilityTimeout: seconds(timeout),
+	})
+	if err != nil && ctx.Err() == nil {
+		velacontext.GetContextLogger(ctx).Warn("Unable to change message visibility", zap.Error(err))
+	}
+}
+
+// messageContext sets up the context like httpmiddleware.RequestContext, from
+// the message's attributes.
+func (c *Consumer) messageContext(ctx context.Context, message Message, raw types.Message) context.Context {
+	requestID := httpmiddleware.RequestIDFromHeader(message.Attributes[httpmiddleware.RequestIDHeader])
+	ctx = velacontext.ContextWithRequestID(ctx, requestID)
+	ctx = velacontext.ContextWithLogger(ctx, c.logger)
+	if traceID := raw.Attributes[string(types.MessageSystemAttributeNameAWSTraceHeader)]; traceID != "" {
+		ctx = velacontext.ContextWithAmznTraceID(ctx, traceID)
+	}
+	for name, value := range message.Attributes {
+		if key, ok := strings.CutPrefix(name, velacontext.BaggageHeaderPrefix); ok && key != "" {
+			ctx = velacontext.SetBaggage(ctx, key, value)
+		}
+	}
+	return velacontext.WithLoggerFields(ctx, zap.String("message_id", message.ID))
+}
+
+func newMessage(raw types.Message) Message {
+	message := Message{
+		ID:            aws.ToString(raw.MessageId),
+		Body:          aws.ToString(raw.Body),
+		ReceiptHandle: aws.ToString(raw.ReceiptHandle),
+		Attributes:    map[string]string{},
+	}
+	for name, value := range raw.MessageAttributes {
+		if value.StringValue != nil {
+			message.Attributes[name] = *value.StringValue
+		}
+	}
+	message.ReceiveCount, _ = strconv.Atoi(raw.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)])
+	return message
+}
+
+func seconds(d time.Duration) int32 {
+	if d > maxVisibility {
+		d = maxVisibility
+	}
+	return int32(d / time.Second)
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
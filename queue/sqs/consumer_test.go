@@ -0,0 +1,226 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awssqs "github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/retry"
+)
+
+type mockSQS struct {
+	mu         sync.Mutex
+	messages   []types.Message
+	deleted    []string
+	sent       map[string][]string
+	sendErr    error
+	visibility map[string][]int32
+	receiveErr error
+	// cancel is called when the queue is empty
+	cancel func()
+}
+
+func (m *mockSQS) ReceiveMessage(ctx context.Context, in *awssqs.ReceiveMessageInput, _ ...func(*awssqs.Options)) (*awssqs.ReceiveMessageOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.receiveErr != nil {
+		err := m.receiveErr
+		m.receiveErr = nil
+		return nil, err
+	}
+	n := min(int(in.MaxNumberOfMessages), len(m.messages))
+	out := &awssqs.ReceiveMessageOutput{Messages: m.messages[:n]}
+	m.messages = m.messages[n:]
+	if n == 0 && m.cancel != nil {
+		m.cancel()
+	}
+	return out, nil
+}
+
+func (m *mockSQS) DeleteMessage(ctx context.Context, in *awssqs.DeleteMessageInput, _ ...func(*awssqs.Options)) (*awssqs.DeleteMessageOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleted = append(m.deleted, aws.ToString(in.ReceiptHandle))
+	return &awssqs.DeleteMessageOutput{}, nil
+}
+
+func (m *mockSQS) ChangeMessageVisibility(ctx context.Context, in *awssqs.ChangeMessageVisibilityInput, _ ...func(*awssqs.Options)) (*awssqs.ChangeMessageVisibilityOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.visibility == nil {
+		m.visibility = map[string][]int32{}
+	}
+	handle := aws.ToString(in.ReceiptHandle)
+	m.visibility[handle] = append(m.visibility[handle], in.VisibilityTimeout)
+	return &awssqs.ChangeMessageVisibilityOutput{}, nil
+}
+
+func (m *mockSQS) SendMessage(ctx context.Context, in *awssqs.SendMessageInput, _ ...func(*awssqs.Options)) (*awssqs.SendMessageOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sendErr != nil {
+		return nil, m.sendErr
+	}
+	if m.sent == nil {
+		m.sent = map[string][]string{}
+	}
+	url := aws.ToString(in.QueueUrl)
+	m.sent[url] = append(m.sent[url], aws.ToString(in.MessageBody))
+	return &awssqs.SendMessageOutput{}, nil
+}
+
+func message(id string, attributes map[string]string) types.Message {
+	msg := types.Message{
+		MessageId:         aws.String(id),
+		Body:              aws.String("body " + id),
+		ReceiptHandle:     aws.String("handle " + id),
+		Attributes:        map[string]string{"ApproximateReceiveCount": "2"},
+		MessageAttributes: map[string]types.MessageAttributeValue{},
+	}
+	for name, value := range attributes {
+		msg.MessageAttributes[name] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(value)}
+	}
+	return msg
+}
+
+func run(t *testing.T, client *mockSQS, options Options, handler Handler) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client.cancel = cancel
+	options.Client = client
+	options.QueueURL = "https://sqs.us-east-1.amazonaws.com/1/test"
+	options.Backoff = retry.Policy{InitialDelay: time.Second, Jitter: -1}
+	done := make(chan error)
+	go func() { done <- NewConsumer(options, handler).Run(ctx) }()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("consumer didn't stop")
+	}
+}
+
+func TestConsumer(t *testing.T) {
+	client := &mockSQS{messages: []types.Message{
+		message("ok", map[string]string{"X-Vela-Request-Id": "req-1", "X-Vela-Baggage-Tenant": "acme"}),
+		message("flaky", nil),
+		message("bad", nil),
+		message("panic", nil),
+	}}
+	var mu sync.Mutex
+	seen := map[string]Message{}
+	requestIDs := map[string]string{}
+	var tenant string
+	run(t, client, Options{}, func(ctx context.Context, msg Message) error {
+		mu.Lock()
+		seen[msg.ID] = msg
+		requestIDs[msg.ID] = velacontext.GetContextRequestID(ctx)
+		if msg.ID == "ok" {
+			tenant = velacontext.GetBaggage(ctx, "Tenant")
+		}
+		mu.Unlock()
+		switch msg.ID {
+		case "flaky":
+			return errors.New("flaky")
+		case "bad":
+			return retry.Permanent(errors.New("bad"))
+		case "panic":
+			panic("boom")
+		}
+		return nil
+	})
+
+	assert.Len(t, seen, 4)
+	assert.Equal(t, "body ok", seen["ok"].Body)
+	assert.Equal(t, 2, seen["ok"].ReceiveCount)
+	assert.Equal(t, "req-1", requestIDs["ok"])
+	assert.NotEmpty(t, requestIDs["flaky"])
+	assert.Equal(t, "acme", tenant)
+	// Permanent failures are left for the redrive policy without a dead
+	// letter queue
+	assert.Equal(t, []string{"handle ok"}, client.deleted)
+	assert.Empty(t, client.sent)
+	// Retried after the backoff for the second receive
+	assert.Equal(t, map[string][]int32{"handle flaky": {2}, "handle panic": {2}}, client.visibility)
+}
+
+func TestConsumerDeadLetterQueue(t *testing.T) {
+	const dlq = "https://sqs.us-east-1.amazonaws.com/1/test-dlq"
+	handler := func(ctx context.Context, msg Message) error {
+		return retry.Permanent(errors.New("bad"))
+	}
+
+	client := &mockSQS{messages: []types.Message{message("bad", nil)}}
+	run(t, client, Options{DeadLetterQueueURL: dlq}, handler)
+	assert.Equal(t, map[string][]string{dlq: {"body bad"}}, client.sent)
+	assert.Equal(t, []string{"handle bad"}, client.deleted)
+
+	// Not deleted when it can't be sent
+	client = &mockSQS{messages: []types.Message{message("bad", nil)}, sendErr: errors.New("throttled")}
+	run(t, client, Options{DeadLetterQueueURL: dlq}, handler)
+	assert.Empty(t, client.deleted)
+}
+
+func TestConsumerExtendsVisibility(t *testing.T) {
+	client := &mockSQS{messages: []types.Message{message("slow", nil)}}
+	run(t, client, Options{VisibilityTimeout: 20 * time.Millisecond}, func(ctx context.Context, msg Message) error {
+		time.Sleep(55 * time.Millisecond)
+		return nil
+	})
+	assert.GreaterOrEqual(t, len(client.visibility["handle slow"]), 2)
+	assert.Equal(t, []string{"handle slow"}, client.deleted)
+}
+
+func TestConsumerConcurrency(t *testing.T) {
+	var messages []types.Message
+	for _, id := range []string{"a", "b", "c", "d", "e", "f"} {
+		messages = append(messages, message(id, nil))
+	}
+	client := &mockSQS{messages: messages}
+	var mu sync.Mutex
+	running, most := 0, 0
+	run(t, client, Options{Concurrency: 2}, func(ctx context.Context, msg Message) error {
+		mu.Lock()
+		running++
+		most = max(most, running)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+	assert.Equal(t, 2, most)
+	assert.Len(t, client.deleted, 6)
+}
+
+func TestConsumerReceiveError(t *testing.T) {
+	client := &mockSQS{receiveErr: errors.New("unavailable"), messages: []types.Message{message("ok", nil)}}
+	handled := false
+	run(t, client, Options{}, func(ctx context.Context, msg Message) error {
+		handled = true
+		return nil
+	})
+	assert.True(t, handled)
+}
+
+func TestConsumerShutdownTimeout(t *testing.T) {
+	client := &mockSQS{messages: []types.Message{message("stuck", nil)}}
+	var cancelled bool
+	run(t, client, Options{ShutdownTimeout: 10 * time.Millisecond}, func(ctx context.Context, msg Message) error {
+		<-ctx.Done()
+		cancelled = true
+		return ctx.Err()
+	})
+	assert.True(t, cancelled)
+}
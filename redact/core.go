@@ -0,0 +1,68 @@
+package redact
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// NewCore wraps a zap core, so entries have their message scrubbed, and
+// their fields redacted by name and by Value, before they're written.
+func NewCore(core zapcore.Core) zapcore.Core {
+	return &redactingCore{Core: core}
+}
+
+type redactingCore struct {
+	zapcore.Core
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(Fields(fields))}
+}
+
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = String(entry.Message)
+	return c.Core.Write(entry, Fields(fields))
+}
+
+// Fields returns copies of the fields with sensitive values redacted.
+// Numbers, times and the like are left alone, unless the field's name is
+// sensitive.
+func Fields(fields []zapcore.Field) []zapcore.Field {
+	redacted := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		redacted[i] = redactField(f)
+	}
+	return redacted
+}
+
+func redactField(f zapcore.Field) zapcore.Field {
+	switch f.Type {
+	case zapcore.SkipType, zapcore.NamespaceType:
+		return f
+	}
+	if SensitiveField(f.Key) {
+		return zap.String(f.Key, Redacted)
+	}
+	switch f.Type {
+	case zapcore.StringType:
+		return zap.String(f.Key, String(f.String))
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok {
+			return zap.String(f.Key, Error(err))
+		}
+	case zapcore.StringerType, zapcore.ByteStringType, zapcore.BinaryType,
+		zapcore.ReflectType, zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType:
+		// Encode it as it would be logged, then redact that
+		encoder := zapcore.NewMapObjectEncoder()
+		f.AddTo(encoder)
+		return zap.Any(f.Key, Value(encoder.Fields[f.Key]))
+	}
+	return f
+}
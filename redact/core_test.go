@@ -0,0 +1,51 @@
+package redact
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type member struct {
+	Name  string
+	Email string
+}
+
+func (m member) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", m.Name)
+	enc.AddString("email", m.Email)
+	return nil
+}
+
+func TestCore(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	logger := zap.New(NewCore(core)).With(zap.String("email", "a@example.com"))
+
+	logger.Info("Sent to a@example.com",
+		zap.String("note", "SSN 123-45-6789"),
+		zap.Int("profile_id", 7),
+		zap.String("dob", "not a date"),
+		zap.Duration("latency", time.Second),
+		zap.Error(errors.New("no profile for 617-555-0134")),
+		zap.Any("response", map[string]interface{}{"phone": "x", "status": "ok"}),
+		zap.Object("member", member{Name: "Ada", Email: "ada@example.com"}),
+	)
+
+	entry := logs.All()[0]
+	assert.Equal(t, "Sent to [REDACTED]", entry.Message)
+	assert.Equal(t, map[string]interface{}{
+		"email":      Redacted,
+		"note":       "SSN [REDACTED]",
+		"profile_id": int64(7),
+		"dob":        Redacted,
+		"latency":    time.Second,
+		"error":      "no profile for [REDACTED]",
+		"response":   map[string]interface{}{"phone": Redacted, "status": "ok"},
+		"member":     map[string]interface{}{"name": "Ada", "email": Redacted},
+	}, entry.ContextMap())
+}
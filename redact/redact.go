@@ -0,0 +1,156 @@
+// Package redact scrubs PHI and PII from what's logged and from error
+// messages.  Values of fields with sensitive names, like "email" or "dob",
+// are replaced outright, and anything that looks like an email address, phone
+// number, SSN or date of birth is replaced wherever it appears.
+//
+// Loggers from the logging package are already wrapped with NewCore.  Code
+// logging through other loggers should pass values through Value first.
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces what's scrubbed.
+const Redacted = "[REDACTED]"
+
+// The field names whose values are always redacted, normalized by
+// normalizeName.
+var (
+	sensitiveNames = map[string]bool{
+		"address": true, "addressline1": true, "addressline2": true, "street": true,
+		"postalcode": true, "zip": true, "zipcode": true,
+		"email": true, "emailaddress": true,
+		"phone": true, "phonenumber": true, "mobile": true, "mobilephone": true,
+		"ssn": true, "socialsecuritynumber": true,
+		"dob": true, "dateofbirth": true, "birthdate": true, "birthday": true,
+		"firstname": true, "lastname": true, "middlename": true, "fullname": true,
+		"medicaidid": true, "medicareid": true, "mrn": true, "medicalrecordnumber": true,
+		"password": true, "secret": true, "clientsecret": true, "apikey": true,
+		"authorization": true, "token": true, "accesstoken": true, "refreshtoken": true, "idtoken": true,
+	}
+	namesLock sync.RWMutex
+)
+
+// AddFields adds field names whose values are always redacted.  Names match
+// ignoring case, underscores and dashes, so "DateOfBirth" matches
+// "date_of_birth".  Call it at start up.
+func AddFields(names ...string) {
+	namesLock.Lock()
+	defer namesLock.Unlock()
+	for _, name := range names {
+		sensitiveNames[normalizeName(name)] = true
+	}
+}
+
+// SensitiveField reports whether values of the field are always redacted.
+func SensitiveField(name string) bool {
+	namesLock.RLock()
+	defer namesLock.RUnlock()
+	return sensitiveNames[normalizeName(name)]
+}
+
+func normalizeName(name string) string {
+	return strings.NewReplacer("_", "", "-", "", " ", "").Replace(strings.ToLower(name))
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	ssnPattern   = regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)
+	phonePattern = regexp.MustCompile(`(?:\+?1[\s.\-]?)?(?:\(\d{3}\)\s?|\b\d{3}[\s.\-])\d{3}[\s.\-]\d{4}\b`)
+	// US style dates, and ISO dates without a time, which a timestamp isn't
+	datePattern = regexp.MustCompile(`\b(?:\d{1,2}/\d{1,2}/\d{4}|\d{4}-\d{2}-\d{2})\b(?:[T ]\d)?`)
+)
+
+// String replaces anything in s that looks like an email address, phone
+// number, SSN or date of birth.
+func String(s string) string {
+	s = emailPattern.ReplaceAllString(s, Redacted)
+	s = ssnPattern.ReplaceAllString(s, Redacted)
+	s = phonePattern.ReplaceAllString(s, Redacted)
+	return datePattern.ReplaceAllStringFunc(s, func(date string) string {
+		if strings.ContainsAny(date[len(date)-2:], "T ") {
+			return date
+		}
+		return Redacted
+	})
+}
+
+// Value returns a copy of v that's safe to log.  Maps and structs have the
+// values of sensitive fields replaced, using their JSON names, and every
+// string is scrubbed by String.  Bytes are treated as JSON when they are,
+// and as a string otherwise.
+func Value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, json.Number:
+		return v
+	case string:
+		return String(v)
+	case []byte:
+		var decoded interface{}
+		if err := json.Unmarshal(v, &decoded); err == nil {
+			return Value(decoded)
+		}
+		return String(string(v))
+	case error:
+		return String(v.Error())
+	case fmt.Stringer:
+		return String(v.String())
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			redacted[key] = field(key, value)
+		}
+		return redacted
+	case map[string]string:
+		redacted := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			redacted[key] = field(key, value)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, value := range v {
+			redacted[i] = Value(value)
+		}
+		return redacted
+	}
+
+	switch reflect.Indirect(reflect.ValueOf(v)).Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		// Go through JSON, so fields have the names clients know them by
+		data, err := json.Marshal(v)
+		if err != nil {
+			return Redacted
+		}
+		var decoded interface{}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return Redacted
+		}
+		return Value(decoded)
+	case reflect.String:
+		return String(reflect.Indirect(reflect.ValueOf(v)).String())
+	}
+	return v
+}
+
+// field redacts the value of a named field.
+func field(name string, value interface{}) interface{} {
+	if value != nil && SensitiveField(name) {
+		return Redacted
+	}
+	return Value(value)
+}
+
+// Error returns err's message, scrubbed by String.
+func Error(err error) string {
+	if err == nil {
+		return ""
+	}
+	return String(err.Error())
+}
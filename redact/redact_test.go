@@ -0,0 +1,65 @@
+package redact
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestString(t *testing.T) {
+	tests := map[string]string{
+		"Contact jane.doe+care@example.com today": "Contact [REDACTED] today",
+		"Call (617) 555-0134 or 617.555.0199":     "Call [REDACTED] or [REDACTED]",
+		"Call +1 617-555-0134":                    "Call [REDACTED]",
+		"SSN 123-45-6789 on file":                 "SSN [REDACTED] on file",
+		"Born 04/12/1941":                         "Born [REDACTED]",
+		"Born 1941-04-12":                         "Born [REDACTED]",
+		"Updated 2026-03-01T12:00:00Z":            "Updated 2026-03-01T12:00:00Z",
+		"Profile 12345 has 3 caregivers":          "Profile 12345 has 3 caregivers",
+	}
+	for input, expected := range tests {
+		assert.Equal(t, expected, String(input), input)
+	}
+}
+
+func TestSensitiveField(t *testing.T) {
+	assert.True(t, SensitiveField("date_of_birth"))
+	assert.True(t, SensitiveField("DateOfBirth"))
+	assert.True(t, SensitiveField("first-name"))
+	assert.False(t, SensitiveField("profile_id"))
+
+	AddFields("Diagnosis_Code")
+	assert.True(t, SensitiveField("diagnosiscode"))
+}
+
+type profile struct {
+	ID        int    `json:"id"`
+	FirstName string `json:"first_name"`
+	Notes     string `json:"notes"`
+}
+
+func TestValue(t *testing.T) {
+	assert.Equal(t, map[string]interface{}{
+		"id":    float64(7),
+		"email": Redacted,
+		"notes": "Call [REDACTED]",
+		"caregivers": []interface{}{
+			map[string]interface{}{"id": "c1", "phone": Redacted},
+		},
+	}, Value(map[string]interface{}{
+		"id":    float64(7),
+		"email": "a@example.com",
+		"notes": "Call 617-555-0134",
+		"caregivers": []interface{}{
+			map[string]interface{}{"id": "c1", "phone": "anything"},
+		},
+	}))
+
+	assert.Equal(t, map[string]interface{}{"id": float64(3), "first_name": Redacted, "notes": "ok"},
+		Value(&profile{ID: 3, FirstName: "Ada", Notes: "ok"}))
+	assert.Equal(t, map[string]interface{}{"ssn": Redacted}, Value([]byte(`{"ssn":"123-45-6789"}`)))
+	assert.Equal(t, "not json [REDACTED]", Value([]byte("not json a@example.com")))
+	assert.Equal(t, 42, Value(42))
+	assert.Equal(t, "[REDACTED] not found", Error(errors.New("a@example.com not found")))
+}
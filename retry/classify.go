@@ -0,0 +1,62 @@
+package retry
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// The error codes AWS services use when requests are throttled.
+var throttleCodes = map[string]bool{
+	"Throttling":                             true,
+	"ThrottlingException":                    true,
+	"ThrottledException":                     true,
+	"RequestThrottledException":              true,
+	"TooManyRequestsException":               true,
+	"ProvisionedThroughputExceededException": true,
+	"RequestLimitExceeded":                   true,
+	"SlowDown":                               true,
+}
+
+// AWSThrottle reports whether err is an AWS service throttling the caller.
+func AWSThrottle(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && throttleCodes[apiErr.ErrorCode()]
+}
+
+// AWSRetryable reports whether an error from an AWS client is worth
+// retrying: throttling, server errors and network errors.
+func AWSRetryable(err error) bool {
+	if AWSThrottle(err) {
+		return true
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorFault() == smithy.FaultServer {
+		return true
+	}
+	var respErr *smithyhttp.ResponseError
+	if errors.As(err, &respErr) {
+		return RetryableStatus(respErr.HTTPStatusCode())
+	}
+	return Network(err)
+}
+
+// RetryableStatus reports whether a response with the status is worth
+// retrying: too many requests, and the gateway and availability errors.
+func RetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Network reports whether err is from the network rather than the server,
+// like a refused connection or a timeout.
+func Network(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,153 @@
+// Package retry runs calls again when they fail, backing off exponentially
+// with jitter, and giving up early rather than sleeping past the context's
+// deadline.
+//
+//	err := retry.Do(ctx, retry.Policy{Retryable: retry.AWSRetryable}, func(ctx context.Context) error {
+//		out, err = client.GetObject(ctx, in)
+//		return err
+//	})
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// The defaults for a Policy's zero fields.
+const (
+	DefaultMaxAttempts  = 3
+	DefaultInitialDelay = 100 * time.Millisecond
+	DefaultMaxDelay     = 5 * time.Second
+	DefaultMultiplier   = 2
+	DefaultJitter       = 0.5
+)
+
+// Policy says how often, and how long apart, calls are tried.  The zero value
+// uses the defaults.
+type Policy struct {
+	// MaxAttempts counts the first call, so 3 is two retries, and 1 never
+	// retries.  Zero is DefaultMaxAttempts.
+	MaxAttempts int
+	// InitialDelay is the wait before the first retry.  It's multiplied by
+	// Multiplier for each retry after that, up to MaxDelay.
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+	// Jitter is the fraction of each delay that's random, so callers that
+	// failed together don't retry together.  Use a negative number for
+	// none.
+	Jitter float64
+	// Retryable decides which errors are worth retrying.  Nil retries all of
+	// them, except context errors and ones marked Permanent.
+	Retryable func(error) bool
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultMaxAttempts
+	}
+	if p.InitialDelay <= 0 {
+		p.InitialDelay = DefaultInitialDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultMaxDelay
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = DefaultMultiplier
+	}
+	if p.Jitter == 0 {
+		p.Jitter = DefaultJitter
+	}
+	if p.Jitter > 1 {
+		p.Jitter = 1
+	}
+	return p
+}
+
+// Delay returns the wait before the retry after attempt, counting from 1,
+// before jitter.
+func (p Policy) Delay(attempt int) time.Duration {
+	p = p.withDefaults()
+	delay := float64(p.InitialDelay)
+	for i := 1; i < attempt; i++ {
+		delay *= p.Multiplier
+		if delay >= float64(p.MaxDelay) {
+			return p.MaxDelay
+		}
+	}
+	return time.Duration(delay)
+}
+
+type permanent struct {
+	err error
+}
+
+func (p permanent) Error() string { return p.err.Error() }
+func (p permanent) Unwrap() error { return p.err }
+
+// Permanent marks an error that mustn't be retried, whatever the policy
+// thinks.  Do returns the error inside it.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanent{err}
+}
+
+// IsPermanent reports whether err, or an error it wraps, was marked
+// Permanent.
+func IsPermanent(err error) bool {
+	var p permanent
+	return errors.As(err, &p)
+}
+
+// Do calls fn until it succeeds, it returns an error that isn't retryable, or
+// the policy's attempts run out, and returns its last error.  It returns
+// the context's error when it's done while waiting, and gives up early,
+// returning the last error, when the deadline would pass before the next
+// attempt.
+func Do(ctx context.Context, policy Policy, fn func(ctx context.Context) error) error {
+	policy = policy.withDefaults()
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		var p permanent
+		if errors.As(err, &p) {
+			return p.err
+		}
+		if attempt >= policy.MaxAttempts || !policy.retryable(err) {
+			return err
+		}
+
+		delay := policy.jitter(policy.Delay(attempt))
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			return err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (p Policy) retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// Takes up to the jitter fraction off the delay.
+func (p Policy) jitter(delay time.Duration) time.Duration {
+	if p.Jitter <= 0 {
+		return delay
+	}
+	return delay - time.Duration(rand.Float64()*p.Jitter*float64(delay))
+}
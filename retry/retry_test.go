@@ -0,0 +1,103 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+)
+
+var errFlaky = errors.New("flaky")
+
+var fast = Policy{InitialDelay: time.Millisecond, Jitter: -1}
+
+func TestDo(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), fast, func(context.Context) error {
+		if calls++; calls < 3 {
+			return errFlaky
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestDoGivesUp(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), fast, func(context.Context) error {
+		calls++
+		return errFlaky
+	})
+	assert.Equal(t, errFlaky, err)
+	assert.Equal(t, DefaultMaxAttempts, calls)
+
+	calls = 0
+	policy := fast
+	policy.Retryable = func(err error) bool { return !errors.Is(err, errFlaky) }
+	Do(context.Background(), policy, func(context.Context) error {
+		calls++
+		return errFlaky
+	})
+	assert.Equal(t, 1, calls, "errors that aren't retryable aren't retried")
+
+	calls = 0
+	err = Do(context.Background(), fast, func(context.Context) error {
+		calls++
+		return Permanent(errFlaky)
+	})
+	assert.Equal(t, errFlaky, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestDoDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	calls := 0
+	start := time.Now()
+	err := Do(ctx, Policy{InitialDelay: time.Second}, func(context.Context) error {
+		calls++
+		return errFlaky
+	})
+	assert.Equal(t, errFlaky, err, "the last error is more useful than the deadline")
+	assert.Equal(t, 1, calls)
+	assert.Less(t, time.Since(start), 40*time.Millisecond, "it doesn't wait for a retry it can't make")
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	err = Do(ctx, Policy{InitialDelay: time.Hour}, func(context.Context) error { return errFlaky })
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestDelay(t *testing.T) {
+	policy := Policy{InitialDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	assert.Equal(t, 100*time.Millisecond, policy.Delay(1))
+	assert.Equal(t, 200*time.Millisecond, policy.Delay(2))
+	assert.Equal(t, 800*time.Millisecond, policy.Delay(4))
+	assert.Equal(t, time.Second, policy.Delay(5))
+
+	jittered := policy.withDefaults().jitter(time.Second)
+	assert.GreaterOrEqual(t, jittered, 500*time.Millisecond)
+	assert.LessOrEqual(t, jittered, time.Second)
+}
+
+func TestClassify(t *testing.T) {
+	assert.True(t, AWSThrottle(&smithy.GenericAPIError{Code: "ThrottlingException"}))
+	assert.False(t, AWSThrottle(&smithy.GenericAPIError{Code: "AccessDenied"}))
+	assert.True(t, AWSRetryable(&smithy.GenericAPIError{Code: "InternalError", Fault: smithy.FaultServer}))
+	assert.False(t, AWSRetryable(&smithy.GenericAPIError{Code: "NoSuchKey", Fault: smithy.FaultClient}))
+	assert.True(t, AWSRetryable(&net.OpError{Op: "dial", Err: errors.New("connection refused")}))
+	assert.True(t, RetryableStatus(503))
+	assert.False(t, RetryableStatus(500))
+}
+
+func TestIsPermanent(t *testing.T) {
+	assert.True(t, IsPermanent(fmt.Errorf("sending: %w", Permanent(errFlaky))))
+	assert.False(t, IsPermanent(errFlaky))
+	assert.False(t, IsPermanent(nil))
+}
@@ -0,0 +1,50 @@
+package s3
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// documentTypes are used ahead of mime.TypeByExtension, whose answers depend
+// on the mime.types files in the runtime image.
+var documentTypes = map[string]string{
+	".csv":  "text/csv; charset=utf-8",
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".heic": "image/heic",
+	".jpeg": "image/jpeg",
+	".jpg":  "image/jpeg",
+	".json": "application/json",
+	".pdf":  "application/pdf",
+	".png":  "image/png",
+	".rtf":  "application/rtf",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+	".txt":  "text/plain; charset=utf-8",
+	".xls":  "application/vnd.ms-excel",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".xml":  "application/xml",
+}
+
+// DetectContentType works out a document's Content-Type from the start of
+// its contents, falling back to its name's extension when the contents are
+// too generic to say, like Office documents, which are zip files, or CSV,
+// which is text.
+func DetectContentType(name string, head []byte) string {
+	sniffed := http.DetectContentType(head)
+	switch strings.SplitN(sniffed, ";", 2)[0] {
+	case "application/octet-stream", "text/plain", "application/zip":
+	default:
+		return sniffed
+	}
+	ext := strings.ToLower(path.Ext(name))
+	if contentType, ok := documentTypes[ext]; ok {
+		return contentType
+	}
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+	return sniffed
+}
@@ -0,0 +1,380 @@
+// Package s3 stores documents in S3: uploads streamed in parts so large
+// files aren't held in memory, encrypted with KMS unless the options say
+// otherwise, downloads, and presigned URLs for browsers to fetch or upload
+// directly.
+//
+//	store, err := s3.New(s3.Options{Client: client, Presigner: awss3.NewPresignClient(client), Bucket: "care-documents", KMSKeyID: keyARN})
+//	obj, err := store.Upload(ctx, "careplans/"+id+"/consent.pdf", r, s3.UploadOptions{})
+//	url, err := store.PresignGet(ctx, obj.Key, 5*time.Minute)
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/seniorlink-vela/cs-common/retry"
+)
+
+// The defaults for Options' zero fields.
+const (
+	DefaultPartSize       = 8 << 20
+	DefaultPresignExpires = 15 * time.Minute
+)
+
+// MinPartSize is the smallest part S3 accepts, except for the last.
+const MinPartSize = 5 << 20
+
+// maxParts is the most parts S3 accepts in an upload.
+const maxParts = 10000
+
+// ErrNotFound is returned for keys that don't exist.
+var ErrNotFound = errors.New("s3: not found")
+
+// API is the part of the S3 client used to store documents.  *s3.Client
+// implements it.
+type API interface {
+	PutObject(ctx context.Context, params *awss3.PutObjectInput, optFns ...func(*awss3.Options)) (*awss3.PutObjectOutput, error)
+	GetObject(ctx context.Context, params *awss3.GetObjectInput, optFns ...func(*awss3.Options)) (*awss3.GetObjectOutput, error)
+	DeleteObject(ctx context.Context, params *awss3.DeleteObjectInput, optFns ...func(*awss3.Options)) (*awss3.DeleteObjectOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *awss3.CreateMultipartUploadInput, optFns ...func(*awss3.Options)) (*awss3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *awss3.UploadPartInput, optFns ...func(*awss3.Options)) (*awss3.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *awss3.CompleteMultipartUploadInput, optFns ...func(*awss3.Options)) (*awss3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *awss3.AbortMultipartUploadInput, optFns ...func(*awss3.Options)) (*awss3.AbortMultipartUploadOutput, error)
+}
+
+// PresignAPI is the part of the S3 presign client used for presigned URLs.
+// *s3.PresignClient implements it.
+type PresignAPI interface {
+	PresignGetObject(ctx context.Context, params *awss3.GetObjectInput, optFns ...func(*awss3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+	PresignPutObject(ctx context.Context, params *awss3.PutObjectInput, optFns ...func(*awss3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+}
+
+var (
+	_ API        = (*awss3.Client)(nil)
+	_ PresignAPI = (*awss3.PresignClient)(nil)
+)
+
+// Options says where documents are stored.
+type Options struct {
+	Client API
+	// Presigner is only needed for presigned URLs.
+	Presigner PresignAPI
+	Bucket    string
+	// Prefix is added to keys, like "documents/".
+	Prefix string
+	// KMSKeyID is the key documents are encrypted with.  It defaults to
+	// the account's AWS managed key for S3.
+	KMSKeyID string
+	// Unencrypted stores documents with S3's own keys rather than KMS, for
+	// buckets of things that aren't sensitive.
+	Unencrypted bool
+	// PartSize is the size of the parts large uploads are sent in, which
+	// is also how much of an upload is held in memory.  It defaults to
+	// DefaultPartSize, and can't be less than MinPartSize.
+	PartSize int64
+	// PresignExpires is how long presigned URLs last when the call doesn't
+	// say, and defaults to DefaultPresignExpires.
+	PresignExpires time.Duration
+}
+
+// Store stores documents in a bucket.
+type Store struct {
+	options Options
+}
+
+// New returns a store for the bucket.
+func New(options Options) (*Store, error) {
+	if options.Client == nil || options.Bucket == "" {
+		return nil, errors.New("s3: a client and bucket are required")
+	}
+	if options.PartSize == 0 {
+		options.PartSize = DefaultPartSize
+	}
+	if options.PartSize < MinPartSize {
+		return nil, fmt.Errorf("s3: parts can't be smaller than %d bytes", MinPartSize)
+	}
+	if options.PresignExpires <= 0 {
+		options.PresignExpires = DefaultPresignExpires
+	}
+	return &Store{options: options}, nil
+}
+
+// Object describes a stored document.
+type Object struct {
+	// Key is without the prefix.
+	Key          string
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+	Metadata     map[string]string
+}
+
+// UploadOptions are an upload's object settings.
+type UploadOptions struct {
+	// ContentType defaults to what DetectContentType makes of the key and
+	// the start of the body.
+	ContentType        string
+	ContentDisposition string
+	CacheControl       string
+	Metadata           map[string]string
+}
+
+// Throttling and S3's server errors are retried, since parts are buffered
+// and can be sent again.
+var retryPolicy = retry.Policy{MaxAttempts: 3, InitialDelay: 100 * time.Millisecond, Retryable: retry.AWSRetryable}
+
+// The SDK's own retries are turned off where retryPolicy retries, so the
+// attempts don't multiply.
+func singleAttempt(o *awss3.Options) {
+	o.RetryMaxAttempts = 1
+}
+
+// Upload stores the body at the key.  Bodies that fit in one part are put in
+// one request, and larger ones are sent a part at a time, so only a part is
+// in memory.  A failed upload's parts are deleted.
+func (s *Store) Upload(ctx context.Context, key string, body io.Reader, options UploadOptions) (Object, error) {
+	buf := make([]byte, s.options.PartSize)
+	n, err := io.ReadFull(body, buf)
+	last := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+	if err != nil && !last {
+		return Object{}, fmt.Errorf("s3: reading %s: %w", key, err)
+	}
+	if options.ContentType == "" {
+		options.ContentType = DetectContentType(key, buf[:n])
+	}
+	obj := Object{Key: key, Size: int64(n), ContentType: options.ContentType, Metadata: options.Metadata}
+
+	if last {
+		input := s.putInput(key, options)
+		err := retry.Do(ctx, retryPolicy, func(ctx context.Context) error {
+			input.Body = bytes.NewReader(buf[:n])
+			input.ContentLength = aws.Int64(int64(n))
+			out, err := s.options.Client.PutObject(ctx, input, singleAttempt)
+			if err == nil {
+				obj.ETag = aws.ToString(out.ETag)
+			}
+			return err
+		})
+		if err != nil {
+			return Object{}, fmt.Errorf("s3: uploading %s: %w", key, err)
+		}
+		return obj, nil
+	}
+	return s.uploadParts(ctx, obj, body, buf, options)
+}
+
+func (s *Store) uploadParts(ctx context.Context, obj Object, body io.Reader, buf []byte, options UploadOptions) (Object, error) {
+	put := s.putInput(obj.Key, options)
+	created, err := s.options.Client.CreateMultipartUpload(ctx, &awss3.CreateMultipartUploadInput{
+		Bucket:               put.Bucket,
+		Key:                  put.Key,
+		ContentType:          put.ContentType,
+		ContentDisposition:   put.ContentDisposition,
+		CacheControl:         put.CacheControl,
+		Metadata:             put.Metadata,
+		ServerSideEncryption: put.ServerSideEncryption,
+		SSEKMSKeyId:          put.SSEKMSKeyId,
+		BucketKeyEnabled:     put.BucketKeyEnabled,
+	})
+	if err != nil {
+		return Object{}, fmt.Errorf("s3: uploading %s: %w", obj.Key, err)
+	}
+	uploadID := created.UploadId
+
+	var parts []types.CompletedPart
+	n := len(buf)
+	for number := int32(1); n > 0; number++ {
+		if number > maxParts {
+			err = fmt.Errorf("more than %d parts", maxParts)
+			break
+		}
+		part := buf[:n]
+		err = retry.Do(ctx, retryPolicy, func(ctx context.Context) error {
+			out, err := s.options.Client.UploadPart(ctx, &awss3.UploadPartInput{
+				Bucket:        put.Bucket,
+				Key:           put.Key,
+				UploadId:      uploadID,
+				PartNumber:    aws.Int32(number),
+				Body:          bytes.NewReader(part),
+				ContentLength: aws.Int64(int64(len(part))),
+			}, singleAttempt)
+			if err == nil {
+				parts = append(parts, types.CompletedPart{ETag: out.ETag, PartNumber: aws.Int32(number)})
+			}
+			return err
+		})
+		if err != nil {
+			break
+		}
+		n, err = io.ReadFull(body, buf)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			err = nil
+		} else if err != nil {
+			break
+		}
+		obj.Size += int64(n)
+	}
+
+	if err == nil {
+		var out *awss3.CompleteMultipartUploadOutput
+		out, err = s.options.Client.CompleteMultipartUpload(ctx, &awss3.CompleteMultipartUploadInput{
+			Bucket:          put.Bucket,
+			Key:             put.Key,
+			UploadId:        uploadID,
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+		})
+		if err == nil {
+			obj.ETag = aws.ToString(out.ETag)
+			return obj, nil
+		}
+	}
+	// Parts of an abandoned upload are stored, and charged for, until
+	// they're aborted
+	_, abortErr := s.options.Client.AbortMultipartUpload(context.WithoutCancel(ctx), &awss3.AbortMultipartUploadInput{
+		Bucket:   put.Bucket,
+		Key:      put.Key,
+		UploadId: uploadID,
+	})
+	return Object{}, errors.Join(fmt.Errorf("s3: uploading %s: %w", obj.Key, err), abortErr)
+}
+
+// putInput is a PutObject for the key, with the encryption settings.
+func (s *Store) putInput(key string, options UploadOptions) *awss3.PutObjectInput {
+	input := &awss3.PutObjectInput{
+		Bucket:             aws.String(s.options.Bucket),
+		Key:                aws.String(s.options.Prefix + key),
+		ContentType:        aws.String(options.ContentType),
+		ContentDisposition: optional(options.ContentDisposition),
+		CacheControl:       optional(options.CacheControl),
+		Metadata:           options.Metadata,
+	}
+	if s.options.Unencrypted {
+		input.ServerSideEncryption = types.ServerSideEncryptionAes256
+	} else {
+		input.ServerSideEncryption = types.ServerSideEncryptionAwsKms
+		input.SSEKMSKeyId = optional(s.options.KMSKeyID)
+		// Saves a KMS call per request
+		input.BucketKeyEnabled = aws.Bool(true)
+	}
+	return input
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
+
+// Download returns the document at the key, which the caller has to close,
+// or ErrNotFound.
+func (s *Store) Download(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	var out *awss3.GetObjectOutput
+	err := retry.Do(ctx, retryPolicy, func(ctx context.Context) error {
+		var err error
+		out, err = s.options.Client.GetObject(ctx, &awss3.GetObjectInput{
+			Bucket: aws.String(s.options.Bucket),
+			Key:    aws.String(s.options.Prefix + key),
+		}, singleAttempt)
+		return err
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, Object{}, fmt.Errorf("s3: downloading %s: %w", key, ErrNotFound)
+		}
+		return nil, Object{}, fmt.Errorf("s3: downloading %s: %w", key, err)
+	}
+	return out.Body, Object{
+		Key:          key,
+		Size:         aws.ToInt64(out.ContentLength),
+		ContentType:  aws.ToString(out.ContentType),
+		ETag:         aws.ToString(out.ETag),
+		LastModified: aws.ToTime(out.LastModified),
+		Metadata:     out.Metadata,
+	}, nil
+}
+
+// Delete deletes the document at the key.  Deleting a key that doesn't exist
+// isn't an error.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	err := retry.Do(ctx, retryPolicy, func(ctx context.Context) error {
+		_, err := s.options.Client.DeleteObject(ctx, &awss3.DeleteObjectInput{
+			Bucket: aws.String(s.options.Bucket),
+			Key:    aws.String(s.options.Prefix + key),
+		}, singleAttempt)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("s3: deleting %s: %w", key, err)
+	}
+	return nil
+}
+
+// Presigned is a presigned request.  Header is the headers the request has
+// to be sent with, like the encryption settings for uploads.
+type Presigned struct {
+	URL     string
+	Method  string
+	Header  http.Header
+	Expires time.Time
+}
+
+// PresignGet returns a URL that downloads the document until it expires.
+// Zero expires uses the options' PresignExpires.
+func (s *Store) PresignGet(ctx context.Context, key string, expires time.Duration) (Presigned, error) {
+	expires = s.expires(expires)
+	if s.options.Presigner == nil {
+		return Presigned{}, errNoPresigner
+	}
+	req, err := s.options.Presigner.PresignGetObject(ctx, &awss3.GetObjectInput{
+		Bucket: aws.String(s.options.Bucket),
+		Key:    aws.String(s.options.Prefix + key),
+	}, awss3.WithPresignExpires(expires))
+	return presigned(req, expires, key, err)
+}
+
+// PresignPut returns a URL that uploads a document to the key until it
+// expires, with the content type and the store's encryption.
+func (s *Store) PresignPut(ctx context.Context, key, contentType string, expires time.Duration) (Presigned, error) {
+	expires = s.expires(expires)
+	input := s.putInput(key, UploadOptions{ContentType: contentType})
+	if contentType == "" {
+		input.ContentType = nil
+	}
+	if s.options.Presigner == nil {
+		return Presigned{}, errNoPresigner
+	}
+	req, err := s.options.Presigner.PresignPutObject(ctx, input, awss3.WithPresignExpires(expires))
+	return presigned(req, expires, key, err)
+}
+
+var errNoPresigner = errors.New("s3: presigning needs Options.Presigner")
+
+func (s *Store) expires(expires time.Duration) time.Duration {
+	if expires <= 0 {
+		return s.options.PresignExpires
+	}
+	return expires
+}
+
+func presigned(req *v4.PresignedHTTPRequest, expires time.Duration, key string, err error) (Presigned, error) {
+	if err != nil {
+		return Presigned{}, fmt.Errorf("s3: presigning %s: %w", key, err)
+	}
+	header := req.SignedHeader.Clone()
+	// The client sets Host from the URL
+	header.Del("Host")
+	return Presigned{URL: req.URL, Method: req.Method, Header: header, Expires: time.Now().Add(expires)}, nil
+}
@@ -0,0 +1,224 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 keeps objects in memory.
+type fakeS3 struct {
+	API
+	mu         sync.Mutex
+	objects    map[string][]byte
+	puts       []*awss3.PutObjectInput
+	putOptions awss3.Options
+	creates    []*awss3.CreateMultipartUploadInput
+	parts      map[int32][]byte
+	aborted    int
+	failPart   int32
+	completeOK bool
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: map[string][]byte{}, parts: map[int32][]byte{}}
+}
+
+func (f *fakeS3) PutObject(_ context.Context, in *awss3.PutObjectInput, optFns ...func(*awss3.Options)) (*awss3.PutObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	body, _ := io.ReadAll(in.Body)
+	f.objects[*in.Key] = body
+	f.puts = append(f.puts, in)
+	for _, fn := range optFns {
+		fn(&f.putOptions)
+	}
+	return &awss3.PutObjectOutput{ETag: aws.String(`"put"`)}, nil
+}
+
+func (f *fakeS3) GetObject(_ context.Context, in *awss3.GetObjectInput, _ ...func(*awss3.Options)) (*awss3.GetObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	body, ok := f.objects[*in.Key]
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	return &awss3.GetObjectOutput{
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: aws.Int64(int64(len(body))),
+		ContentType:   aws.String("application/pdf"),
+	}, nil
+}
+
+func (f *fakeS3) DeleteObject(_ context.Context, in *awss3.DeleteObjectInput, _ ...func(*awss3.Options)) (*awss3.DeleteObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.objects, *in.Key)
+	return &awss3.DeleteObjectOutput{}, nil
+}
+
+func (f *fakeS3) CreateMultipartUpload(_ context.Context, in *awss3.CreateMultipartUploadInput, _ ...func(*awss3.Options)) (*awss3.CreateMultipartUploadOutput, error) {
+	f.creates = append(f.creates, in)
+	return &awss3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil
+}
+
+func (f *fakeS3) UploadPart(_ context.Context, in *awss3.UploadPartInput, _ ...func(*awss3.Options)) (*awss3.UploadPartOutput, error) {
+	if *in.PartNumber == f.failPart {
+		return nil, errors.New("access denied")
+	}
+	body, _ := io.ReadAll(in.Body)
+	f.parts[*in.PartNumber] = body
+	return &awss3.UploadPartOutput{ETag: aws.String("part")}, nil
+}
+
+func (f *fakeS3) CompleteMultipartUpload(_ context.Context, in *awss3.CompleteMultipartUploadInput, _ ...func(*awss3.Options)) (*awss3.CompleteMultipartUploadOutput, error) {
+	var body []byte
+	for _, part := range in.MultipartUpload.Parts {
+		body = append(body, f.parts[*part.PartNumber]...)
+	}
+	f.objects[*in.Key] = body
+	return &awss3.CompleteMultipartUploadOutput{ETag: aws.String(`"multipart"`)}, nil
+}
+
+func (f *fakeS3) AbortMultipartUpload(context.Context, *awss3.AbortMultipartUploadInput, ...func(*awss3.Options)) (*awss3.AbortMultipartUploadOutput, error) {
+	f.aborted++
+	return &awss3.AbortMultipartUploadOutput{}, nil
+}
+
+func TestUpload(t *testing.T) {
+	client := newFakeS3()
+	store, err := New(Options{Client: client, Bucket: "docs", Prefix: "careplans/", KMSKeyID: "key-1"})
+	require.NoError(t, err)
+
+	obj, err := store.Upload(context.Background(), "consent.pdf", strings.NewReader("%PDF-1.7 consent"), UploadOptions{Metadata: map[string]string{"uploaded-by": "42"}})
+	require.NoError(t, err)
+	assert.Equal(t, Object{Key: "consent.pdf", Size: 16, ContentType: "application/pdf", ETag: `"put"`, Metadata: map[string]string{"uploaded-by": "42"}}, obj)
+
+	require.Len(t, client.puts, 1)
+	put := client.puts[0]
+	assert.Equal(t, "careplans/consent.pdf", *put.Key)
+	assert.Equal(t, types.ServerSideEncryptionAwsKms, put.ServerSideEncryption)
+	assert.Equal(t, "key-1", *put.SSEKMSKeyId)
+	assert.True(t, *put.BucketKeyEnabled)
+	assert.Equal(t, 1, client.putOptions.RetryMaxAttempts, "the SDK doesn't retry as well")
+	assert.Empty(t, client.creates)
+}
+
+func TestUploadUnencrypted(t *testing.T) {
+	client := newFakeS3()
+	store, err := New(Options{Client: client, Bucket: "assets", Unencrypted: true})
+	require.NoError(t, err)
+	_, err = store.Upload(context.Background(), "logo.png", strings.NewReader("png"), UploadOptions{ContentType: "image/png"})
+	require.NoError(t, err)
+	assert.Equal(t, types.ServerSideEncryptionAes256, client.puts[0].ServerSideEncryption)
+	assert.Nil(t, client.puts[0].SSEKMSKeyId)
+	assert.Equal(t, "image/png", *client.puts[0].ContentType)
+}
+
+func TestUploadMultipart(t *testing.T) {
+	client := newFakeS3()
+	store, err := New(Options{Client: client, Bucket: "docs", PartSize: MinPartSize})
+	require.NoError(t, err)
+	body := bytes.Repeat([]byte("0123456789"), MinPartSize/4)
+
+	obj, err := store.Upload(context.Background(), "scan.tiff", bytes.NewReader(body), UploadOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(body)), obj.Size)
+	assert.Equal(t, `"multipart"`, obj.ETag)
+	assert.Equal(t, "image/tiff", obj.ContentType)
+	assert.Len(t, client.parts, 3)
+	assert.Equal(t, body, client.objects["scan.tiff"])
+	require.Len(t, client.creates, 1)
+	assert.Equal(t, types.ServerSideEncryptionAwsKms, client.creates[0].ServerSideEncryption)
+	assert.Empty(t, client.puts)
+}
+
+func TestUploadMultipartFailure(t *testing.T) {
+	client := newFakeS3()
+	client.failPart = 2
+	store, err := New(Options{Client: client, Bucket: "docs", PartSize: MinPartSize})
+	require.NoError(t, err)
+
+	_, err = store.Upload(context.Background(), "scan.tiff", bytes.NewReader(make([]byte, 2*MinPartSize+1)), UploadOptions{})
+	assert.ErrorContains(t, err, "s3: uploading scan.tiff: access denied")
+	assert.Equal(t, 1, client.aborted)
+	assert.NotContains(t, client.objects, "scan.tiff")
+}
+
+func TestDownloadAndDelete(t *testing.T) {
+	client := newFakeS3()
+	store, err := New(Options{Client: client, Bucket: "docs", Prefix: "p/"})
+	require.NoError(t, err)
+	client.objects["p/consent.pdf"] = []byte("%PDF")
+
+	body, obj, err := store.Download(context.Background(), "consent.pdf")
+	require.NoError(t, err)
+	defer body.Close()
+	contents, _ := io.ReadAll(body)
+	assert.Equal(t, "%PDF", string(contents))
+	assert.Equal(t, int64(4), obj.Size)
+	assert.Equal(t, "consent.pdf", obj.Key)
+
+	require.NoError(t, store.Delete(context.Background(), "consent.pdf"))
+	_, _, err = store.Download(context.Background(), "consent.pdf")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func presignClient() *awss3.PresignClient {
+	return awss3.NewPresignClient(awss3.New(awss3.Options{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+	}))
+}
+
+func TestPresign(t *testing.T) {
+	store, err := New(Options{Client: newFakeS3(), Presigner: presignClient(), Bucket: "docs", KMSKeyID: "key-1"})
+	require.NoError(t, err)
+
+	get, err := store.PresignGet(context.Background(), "consent.pdf", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "GET", get.Method)
+	assert.Contains(t, get.URL, "consent.pdf")
+	assert.Contains(t, get.URL, "X-Amz-Expires=900")
+	assert.WithinDuration(t, time.Now().Add(DefaultPresignExpires), get.Expires, time.Second)
+
+	put, err := store.PresignPut(context.Background(), "upload.pdf", "application/pdf", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "PUT", put.Method)
+	assert.Contains(t, put.URL, "X-Amz-Expires=60")
+	assert.Equal(t, "aws:kms", put.Header.Get("X-Amz-Server-Side-Encryption"))
+	assert.Equal(t, "key-1", put.Header.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"))
+	assert.Empty(t, put.Header.Get("Host"))
+
+	store, _ = New(Options{Client: newFakeS3(), Bucket: "docs"})
+	_, err = store.PresignGet(context.Background(), "consent.pdf", 0)
+	assert.Error(t, err)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Options{Client: newFakeS3()})
+	assert.Error(t, err)
+	_, err = New(Options{Client: newFakeS3(), Bucket: "docs", PartSize: 1 << 20})
+	assert.Error(t, err)
+}
+
+func TestDetectContentType(t *testing.T) {
+	assert.Equal(t, "application/pdf", DetectContentType("scan", []byte("%PDF-1.7")))
+	assert.Equal(t, "image/png", DetectContentType("photo.txt", []byte("\x89PNG\r\n\x1a\n")))
+	assert.Equal(t, "application/vnd.openxmlformats-officedocument.wordprocessingml.document", DetectContentType("plan.DOCX", []byte("PK\x03\x04")))
+	assert.Equal(t, "text/csv; charset=utf-8", DetectContentType("visits.csv", []byte("date,caregiver\n")))
+	assert.Equal(t, "text/plain; charset=utf-8", DetectContentType("notes", []byte("hello")))
+	assert.Equal(t, "application/octet-stream", DetectContentType("blob", []byte{0, 1, 2}))
+}
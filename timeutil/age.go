@@ -0,0 +1,15 @@
+package timeutil
+
+import "time"
+
+// Age is the whole years from birthday to now, counted in the birthday's
+// location, so a birthday stored as midnight UTC doesn't roll over early or
+// late.  Birthdays on February 29th roll over on March 1st in other years.
+func Age(birthday, now time.Time) int {
+	now = now.In(birthday.Location())
+	years := now.Year() - birthday.Year()
+	if now.Month() < birthday.Month() || (now.Month() == birthday.Month() && now.Day() < birthday.Day()) {
+		years--
+	}
+	return years
+}
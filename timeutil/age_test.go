@@ -0,0 +1,18 @@
+package timeutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAge(t *testing.T) {
+	birthday := time.Date(2000, time.February, 29, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 20, Age(birthday, time.Date(2021, time.February, 28, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 21, Age(birthday, time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)))
+	// Still the day before in New York, even though it's the birthday in UTC
+	ny, _ := time.LoadLocation("America/New_York")
+	birthday = time.Date(2000, time.March, 15, 0, 0, 0, 0, ny)
+	assert.Equal(t, 20, Age(birthday, time.Date(2021, time.March, 15, 3, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,61 @@
+package timeutil
+
+import "time"
+
+// Holidays are dates that aren't business days, as "2006-01-02".
+type Holidays map[string]bool
+
+// NewHolidays returns the dates as holidays.
+func NewHolidays(dates ...string) Holidays {
+	h := make(Holidays, len(dates))
+	for _, date := range dates {
+		h[date] = true
+	}
+	return h
+}
+
+// IsBusinessDay reports whether t's day, in t's location, is a weekday and
+// not a holiday.
+func IsBusinessDay(t time.Time, holidays Holidays) bool {
+	switch t.Weekday() {
+	case time.Saturday, time.Sunday:
+		return false
+	}
+	return !holidays[t.Format(time.DateOnly)]
+}
+
+// AddBusinessDays moves t by n business days, keeping its time of day.
+// Negative n moves back.  Starting on a day that isn't a business day, the
+// first step is to the next, or previous, business day.
+func AddBusinessDays(t time.Time, n int, holidays Holidays) time.Time {
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for n > 0 {
+		t = t.AddDate(0, 0, step)
+		if IsBusinessDay(t, holidays) {
+			n--
+		}
+	}
+	return t
+}
+
+// BusinessDaysBetween counts the business days after from's day, up to and
+// including to's day, so the days between Friday and Monday is 1.  It's
+// negative when to is before from.
+func BusinessDaysBetween(from, to time.Time, holidays Holidays) int {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	sign := 1
+	if to.Before(from) {
+		from, to, sign = to, from, -1
+	}
+	count := 0
+	for d := from.AddDate(0, 0, 1); !d.After(to); d = d.AddDate(0, 0, 1) {
+		if IsBusinessDay(d, holidays) {
+			count++
+		}
+	}
+	return sign * count
+}
@@ -0,0 +1,28 @@
+package timeutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBusinessDays(t *testing.T) {
+	holidays := NewHolidays("2024-12-25")
+	friday := time.Date(2024, time.December, 20, 10, 0, 0, 0, time.UTC)
+	assert.True(t, IsBusinessDay(friday, holidays))
+	assert.False(t, IsBusinessDay(friday.AddDate(0, 0, 1), holidays))
+	assert.False(t, IsBusinessDay(time.Date(2024, time.December, 25, 0, 0, 0, 0, time.UTC), holidays))
+
+	assert.Equal(t, time.Date(2024, time.December, 23, 10, 0, 0, 0, time.UTC), AddBusinessDays(friday, 1, holidays))
+	// Skipping the weekend and Christmas
+	assert.Equal(t, time.Date(2024, time.December, 26, 10, 0, 0, 0, time.UTC), AddBusinessDays(friday, 3, holidays))
+	assert.Equal(t, time.Date(2024, time.December, 18, 10, 0, 0, 0, time.UTC), AddBusinessDays(friday, -2, holidays))
+	assert.Equal(t, friday, AddBusinessDays(friday, 0, holidays))
+
+	monday := time.Date(2024, time.December, 23, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t, 1, BusinessDaysBetween(friday, monday, holidays))
+	assert.Equal(t, 3, BusinessDaysBetween(friday, time.Date(2024, time.December, 26, 0, 0, 0, 0, time.UTC), holidays))
+	assert.Equal(t, -1, BusinessDaysBetween(monday, friday, holidays))
+	assert.Equal(t, 0, BusinessDaysBetween(friday, friday, holidays))
+}
@@ -0,0 +1,75 @@
+package timeutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Time is a time.Time that unmarshals from the formats partners send: an
+// RFC 3339 string, a date, or a Unix epoch number, in seconds or
+// milliseconds.  It marshals as RFC 3339, and null is the zero time.
+type Time struct {
+	time.Time
+}
+
+// Milliseconds since the epoch are told from seconds by size: 1e11 seconds
+// is the year 5138, and 1e11 milliseconds is 1973.
+const epochMillisecondsFrom = 1e11
+
+func (t *Time) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		t.Time = time.Time{}
+		return nil
+	}
+	if len(data) > 0 && data[0] != '"' {
+		return t.unmarshalEpoch(string(data))
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		t.Time = time.Time{}
+		return nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, time.DateOnly} {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	// Some partners send epochs as strings
+	if err := t.unmarshalEpoch(s); err == nil {
+		return nil
+	}
+	return fmt.Errorf("timeutil: invalid time %q", s)
+}
+
+func (t *Time) unmarshalEpoch(s string) error {
+	epoch, err := strconv.ParseFloat(s, 64)
+	// NaN and infinities parse, but aren't times, and neither is anything
+	// past what an int64 holds
+	if err != nil || math.IsNaN(epoch) || math.IsInf(epoch, 0) || math.Abs(epoch) >= math.MaxInt64 {
+		return fmt.Errorf("timeutil: invalid time %s", s)
+	}
+	if epoch >= epochMillisecondsFrom || epoch <= -epochMillisecondsFrom {
+		t.Time = time.UnixMilli(int64(epoch)).UTC()
+		return nil
+	}
+	seconds := int64(epoch)
+	// Fractions of a second don't survive float64 exactly
+	t.Time = time.Unix(seconds, int64((epoch-float64(seconds))*1e9)).UTC().Round(time.Millisecond)
+	return nil
+}
+
+func (t Time) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.Time.Format(time.RFC3339Nano))
+}
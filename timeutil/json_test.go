@@ -0,0 +1,49 @@
+package timeutil
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeUnmarshal(t *testing.T) {
+	expected := time.Date(2024, time.March, 4, 15, 30, 0, 0, time.UTC)
+	inputs := map[string]time.Time{
+		`"2024-03-04T15:30:00Z"`:      expected,
+		`"2024-03-04T10:30:00-05:00"`: expected,
+		`1709566200`:                  expected,
+		`1709566200000`:               expected,
+		`"1709566200"`:                expected,
+		`1709566200.5`:                expected.Add(500 * time.Millisecond),
+		`"2024-03-04"`:                time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC),
+		`null`:                        {},
+		`""`:                          {},
+	}
+	for input, want := range inputs {
+		var v Time
+		require.NoError(t, json.Unmarshal([]byte(input), &v), input)
+		assert.True(t, want.Equal(v.Time), "%s: %s", input, v.Time)
+	}
+
+	for _, input := range []string{`"yesterday"`, `true`, `"2024-13-01"`, `"NaN"`, `"Inf"`, `"-Infinity"`, `"1e300"`} {
+		var v Time
+		assert.Error(t, json.Unmarshal([]byte(input), &v), input)
+	}
+}
+
+func TestTimeMarshal(t *testing.T) {
+	type visit struct {
+		At    Time  `json:"at"`
+		Ended *Time `json:"ended,omitempty"`
+	}
+	data, err := json.Marshal(visit{At: Time{time.Date(2024, time.March, 4, 15, 30, 0, 0, time.UTC)}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"at": "2024-03-04T15:30:00Z"}`, string(data))
+
+	data, err = json.Marshal(visit{})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"at": null}`, string(data))
+}
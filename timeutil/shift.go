@@ -0,0 +1,63 @@
+package timeutil
+
+import (
+	"fmt"
+	"time"
+)
+
+// Clock is a time of day, like a shift's start, in minutes after midnight.
+type Clock int
+
+// ParseClock parses "15:04".
+func ParseClock(s string) (Clock, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("timeutil: invalid time of day %q", s)
+	}
+	return Clock(t.Hour()*60 + t.Minute()), nil
+}
+
+func (c Clock) String() string {
+	return fmt.Sprintf("%02d:%02d", int(c)/60, int(c)%60)
+}
+
+// On is the time on the day of t, in loc.  Times skipped when daylight
+// saving starts are moved forward by the gap, as time.Date does.
+func (c Clock) On(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), int(c)/60, int(c)%60, 0, 0, loc)
+}
+
+// Window is the time from Start up to End.
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+// ShiftWindow is the shift from start to end on t's day in loc.  Shifts that
+// end at or before they start are overnight, and end the next day.
+func ShiftWindow(t time.Time, start, end Clock, loc *time.Location) Window {
+	w := Window{Start: start.On(t, loc), End: end.On(t, loc)}
+	if end <= start {
+		w.End = end.On(t.In(loc).AddDate(0, 0, 1), loc)
+	}
+	return w
+}
+
+// Duration is how long the window is, which for shifts over a daylight
+// saving change isn't the difference of the clocks.
+func (w Window) Duration() time.Duration {
+	return w.End.Sub(w.Start)
+}
+
+// Contains reports whether t is in the window, counting the start and not
+// the end.
+func (w Window) Contains(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+// Overlaps reports whether the windows share any time.  Windows that only
+// touch, with one ending as the other starts, don't.
+func (w Window) Overlaps(other Window) bool {
+	return w.Start.Before(other.End) && other.Start.Before(w.End)
+}
@@ -0,0 +1,40 @@
+package timeutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseClock(t *testing.T) {
+	c, err := ParseClock("07:30")
+	require.NoError(t, err)
+	assert.Equal(t, Clock(450), c)
+	assert.Equal(t, "07:30", c.String())
+	_, err = ParseClock("7.30pm")
+	assert.Error(t, err)
+}
+
+func TestShiftWindow(t *testing.T) {
+	ny, _ := LoadZone("America/New_York")
+	day := time.Date(2024, time.June, 3, 15, 0, 0, 0, time.UTC)
+	morning := ShiftWindow(day, 7*60, 15*60, ny)
+	assert.Equal(t, time.Date(2024, time.June, 3, 7, 0, 0, 0, ny), morning.Start)
+	assert.Equal(t, 8*time.Hour, morning.Duration())
+	assert.True(t, morning.Contains(morning.Start))
+	assert.False(t, morning.Contains(morning.End))
+
+	overnight := ShiftWindow(day, 23*60, 7*60, ny)
+	assert.Equal(t, time.Date(2024, time.June, 4, 7, 0, 0, 0, ny), overnight.End)
+	assert.Equal(t, 8*time.Hour, overnight.Duration())
+	assert.False(t, overnight.Overlaps(morning))
+	evening := ShiftWindow(day, 15*60, 23*60, ny)
+	assert.False(t, evening.Overlaps(morning))
+	assert.True(t, evening.Overlaps(ShiftWindow(day, 22*60, 2*60, ny)))
+
+	// The night daylight saving ends has an extra hour
+	fallBack := ShiftWindow(time.Date(2024, time.November, 2, 12, 0, 0, 0, ny), 23*60, 7*60, ny)
+	assert.Equal(t, 9*time.Hour, fallBack.Duration())
+}
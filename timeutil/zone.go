@@ -0,0 +1,77 @@
+// Package timeutil has the time helpers care scheduling needs: members' time
+// zones and days, shift windows, business days, ages, and a JSON time that
+// accepts the formats partners send.
+//
+// Days are a member's days, in their time zone, not the server's or UTC's:
+// a visit at 9pm in Los Angeles is on the 3rd there, though it's the 4th in
+// UTC.
+package timeutil
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	// Lambda runtimes don't reliably ship a zoneinfo database, so embed
+	// one.
+	_ "time/tzdata"
+)
+
+// ErrInvalidZone is returned for names that aren't IANA time zones.
+var ErrInvalidZone = errors.New("timeutil: invalid time zone")
+
+var zones sync.Map
+
+// LoadZone returns the IANA time zone, like "America/New_York", caching it,
+// since loading reads and parses the zone's data each time.  Empty and
+// "Local" aren't zones: the server's zone is never the member's.  Names
+// aren't trimmed, so a padded name isn't valid; trim it first to accept it.
+func LoadZone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, ErrInvalidZone
+	}
+	if loc, ok := zones.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidZone
+	}
+	zones.Store(name, loc)
+	return loc, nil
+}
+
+// ValidZone reports whether the name is an IANA time zone.
+func ValidZone(name string) bool {
+	_, err := LoadZone(name)
+	return err == nil
+}
+
+// In converts the time to the named zone.
+func In(t time.Time, zone string) (time.Time, error) {
+	loc, err := LoadZone(zone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.In(loc), nil
+}
+
+// StartOfDay is midnight at the start of t's day in loc.
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// DayBounds are the start of t's day in loc, and the start of the next day,
+// for queries like start <= visit_at < end.  Days aren't always 24 hours:
+// the days daylight saving starts and ends are 23 and 25.
+func DayBounds(t time.Time, loc *time.Location) (start, end time.Time) {
+	start = StartOfDay(t, loc)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// SameDay reports whether a and b are on the same day in loc.
+func SameDay(a, b time.Time, loc *time.Location) bool {
+	a, b = a.In(loc), b.In(loc)
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
@@ -0,0 +1,41 @@
+package timeutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadZone(t *testing.T) {
+	loc, err := LoadZone("America/New_York")
+	require.NoError(t, err)
+	assert.Equal(t, "America/New_York", loc.String())
+	again, _ := LoadZone("America/New_York")
+	assert.Same(t, loc, again)
+
+	for _, name := range []string{"", "Local", "America/Boston", "EST5EDT/x", " America/New_York "} {
+		assert.False(t, ValidZone(name), name)
+	}
+	_, err = In(time.Now(), "Mars/Olympus_Mons")
+	assert.ErrorIs(t, err, ErrInvalidZone)
+}
+
+func TestDayBounds(t *testing.T) {
+	la, _ := LoadZone("America/Los_Angeles")
+	// 9pm on the 3rd in Los Angeles is the 4th in UTC
+	visit := time.Date(2024, time.March, 4, 5, 0, 0, 0, time.UTC)
+	start, end := DayBounds(visit, la)
+	assert.Equal(t, time.Date(2024, time.March, 3, 0, 0, 0, 0, la), start)
+	assert.Equal(t, time.Date(2024, time.March, 4, 0, 0, 0, 0, la), end)
+
+	// Daylight saving starts on the 10th, which is 23 hours long
+	start, end = DayBounds(time.Date(2024, time.March, 10, 12, 0, 0, 0, la), la)
+	assert.Equal(t, 23*time.Hour, end.Sub(start))
+
+	in, _ := In(visit, "America/Los_Angeles")
+	assert.Equal(t, 21, in.Hour())
+	assert.True(t, SameDay(visit, time.Date(2024, time.March, 3, 9, 0, 0, 0, la), la))
+	assert.False(t, SameDay(visit, time.Date(2024, time.March, 3, 9, 0, 0, 0, la), time.UTC))
+}
@@ -0,0 +1,147 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/context/lambdamiddleware"
+)
+
+// Middleware returns middleware that continues the caller's trace, from its
+// W3C or X-Ray headers, and starts a server span for each request.  Put it
+// inside httpmiddleware.RequestContext, so spans have the request ID.
+func Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := startServerSpan(ctx, r.Method, r.URL.Path)
+			defer span.End()
+
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+			endServerSpan(span, recorder.status)
+		})
+	}
+}
+
+// ALBMiddleware continues the trace of ALB requests and starts a server span
+// for each.  A handler's error marks the span failed, as does a 5xx.  Put it
+// inside lambdamiddleware.ALBRequestContext, so spans have the request ID.
+func ALBMiddleware() func(lambdamiddleware.ALBHandler) lambdamiddleware.ALBHandler {
+	return func(next lambdamiddleware.ALBHandler) lambdamiddleware.ALBHandler {
+		return func(ctx context.Context, req events.ALBTargetGroupRequest) (*events.ALBTargetGroupResponse, error) {
+			ctx = otel.GetTextMapPropagator().Extract(ctx, albCarrier(req))
+			ctx, span := startServerSpan(ctx, req.HTTPMethod, req.Path)
+			defer span.End()
+
+			resp, err := next(ctx, req)
+			switch {
+			case err != nil:
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			case resp != nil:
+				endServerSpan(span, resp.StatusCode)
+			}
+			return resp, err
+		}
+	}
+}
+
+func startServerSpan(ctx context.Context, method, path string) (context.Context, trace.Span) {
+	return velacontext.StartSpan(ctx, fmt.Sprintf("%s %s", method, path),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(semconv.HTTPRequestMethodKey.String(method), semconv.URLPath(path)),
+	)
+}
+
+// Server spans are only errors for 5xx, a 4xx is the caller's problem.
+func endServerSpan(span trace.Span, status int) {
+	span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+}
+
+// albCarrier reads an ALB request's headers for the propagators, which look
+// them up by their canonical names while the ALB sends them lower case.
+type albCarrier events.ALBTargetGroupRequest
+
+func (c albCarrier) Get(key string) string {
+	return lambdamiddleware.ALBHeader(events.ALBTargetGroupRequest(c), key)
+}
+
+// Set does nothing, the carrier's only extracted from.
+func (c albCarrier) Set(string, string) {}
+
+func (c albCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.Headers)+len(c.MultiValueHeaders))
+	for key := range c.Headers {
+		keys = append(keys, key)
+	}
+	for key := range c.MultiValueHeaders {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Transport returns a round tripper that starts a client span for each
+// request, and adds the W3C and X-Ray headers, so the service it calls joins
+// the trace.  Requests need their context, from http.NewRequestWithContext,
+// for the span to be part of the caller's trace.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return transport{next: next}
+}
+
+type transport struct {
+	next http.RoundTripper
+}
+
+func (t transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	ctx, span := velacontext.StartSpan(request.Context(), "HTTP "+request.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(request.Method),
+			semconv.ServerAddress(request.URL.Hostname()),
+			semconv.URLPath(request.URL.Path),
+		),
+	)
+	defer span.End()
+
+	// Round trippers mustn't change the request they're given
+	request = request.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(request.Header))
+
+	response, err := t.next.RoundTrip(request)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return response, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(response.StatusCode))
+	if response.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, http.StatusText(response.StatusCode))
+	}
+	return response, nil
+}
@@ -0,0 +1,126 @@
+// Package tracing sets up OpenTelemetry for a service, so its spans reach
+// X-Ray or an OTLP collector, and traces follow requests across services
+// whether the headers are W3C or X-Ray's.
+//
+//	shutdown, err := tracing.Setup(ctx, tracing.Config{ServiceName: "care-team-api", Exporter: tracing.ExporterXRay})
+//	if err != nil {
+//		panic(err)
+//	}
+//	defer shutdown(context.Background())
+//
+// Spans are started with velacontext.StartSpan, and Middleware,
+// ALBMiddleware and Transport start them for requests in and out.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// The exporters Setup supports.
+const (
+	// ExporterOTLP sends spans to an OTLP/HTTP endpoint.
+	ExporterOTLP = "otlp"
+	// ExporterXRay sends spans to the ADOT collector, or Lambda's ADOT
+	// layer, which forwards them to X-Ray.  Trace IDs are generated the way
+	// X-Ray needs them.
+	ExporterXRay = "xray"
+	// ExporterNone doesn't send spans anywhere, but still propagates traces.
+	ExporterNone = "none"
+)
+
+// DefaultEndpoint is the collector's OTLP/HTTP endpoint, as the ADOT
+// collector and Lambda layer listen.
+const DefaultEndpoint = "localhost:4318"
+
+// Config says where spans go.
+type Config struct {
+	ServiceName    string
+	ServiceVersion string
+	// Exporter defaults to ExporterOTLP.
+	Exporter string
+	// Endpoint is the host and port of the collector, and defaults to
+	// DefaultEndpoint.
+	Endpoint string
+	// Insecure sends spans over HTTP rather than HTTPS, as a local
+	// collector expects.  DefaultEndpoint is always sent to over HTTP.
+	Insecure bool
+	// SampleRatio is the fraction of new traces that are sampled.  Traces
+	// started by a caller keep its decision.  Zero samples them all.
+	SampleRatio float64
+	// SpanExporter replaces the exporter, for tests.
+	SpanExporter sdktrace.SpanExporter
+}
+
+// Propagator returns the propagator Setup installs, which reads and writes
+// W3C trace context and baggage, and X-Ray's X-Amzn-Trace-Id.
+func Propagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}, xray.Propagator{})
+}
+
+// Setup installs a global tracer provider and propagator.  Call the returned
+// function before exiting, to send any spans that are still buffered.
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	if config.Exporter == "" {
+		config.Exporter = ExporterOTLP
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = DefaultEndpoint
+	}
+	if config.Endpoint == DefaultEndpoint {
+		// The local collector doesn't listen for HTTPS
+		config.Insecure = true
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName(config.ServiceName),
+		semconv.ServiceVersion(config.ServiceVersion),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("tracing: building resource: %w", err)
+	}
+	sampler := sdktrace.AlwaysSample()
+	if config.SampleRatio > 0 && config.SampleRatio < 1 {
+		sampler = sdktrace.TraceIDRatioBased(config.SampleRatio)
+	}
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+	}
+
+	exporter := config.SpanExporter
+	switch config.Exporter {
+	case ExporterOTLP, ExporterXRay:
+		if config.Exporter == ExporterXRay {
+			opts = append(opts, sdktrace.WithIDGenerator(xray.NewIDGenerator()))
+		}
+		if exporter == nil {
+			exporterOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(config.Endpoint)}
+			if config.Insecure {
+				exporterOpts = append(exporterOpts, otlptracehttp.WithInsecure())
+			}
+			if exporter, err = otlptracehttp.New(ctx, exporterOpts...); err != nil {
+				return nil, fmt.Errorf("tracing: creating exporter: %w", err)
+			}
+		}
+	case ExporterNone:
+	default:
+		return nil, fmt.Errorf("tracing: unknown exporter %q", config.Exporter)
+	}
+	if exporter != nil {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+
+	provider := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(Propagator())
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,140 @@
+package tracing
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+)
+
+// setup installs a provider that records spans in memory, and returns a
+// function that flushes and returns them.
+func setup(t *testing.T) func() tracetest.SpanStubs {
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown, err := Setup(context.Background(), Config{ServiceName: "test", Exporter: ExporterXRay, SpanExporter: exporter})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		shutdown(context.Background())
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+	return func() tracetest.SpanStubs {
+		require.NoError(t, otel.GetTracerProvider().(*sdktrace.TracerProvider).ForceFlush(context.Background()))
+		return exporter.GetSpans()
+	}
+}
+
+func TestSetupErrors(t *testing.T) {
+	_, err := Setup(context.Background(), Config{Exporter: "zipkin"})
+	assert.Error(t, err)
+}
+
+func TestSetupDefaultEndpointIsHTTP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:4318")
+	if err != nil {
+		t.Skipf("the collector's port is taken: %v", err)
+	}
+	received := make(chan string, 10)
+	collector := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.URL.Path
+	}))
+	collector.Listener.Close()
+	collector.Listener = listener
+	collector.Start()
+	defer collector.Close()
+
+	shutdown, err := Setup(context.Background(), Config{ServiceName: "test"})
+	require.NoError(t, err)
+	defer func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	}()
+	_, span := otel.Tracer("test").Start(context.Background(), "span")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+	select {
+	case path := <-received:
+		assert.Equal(t, "/v1/traces", path)
+	default:
+		t.Fatal("the collector didn't get the spans")
+	}
+}
+
+func TestMiddlewareXRay(t *testing.T) {
+	spans := setup(t)
+	handler := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "5759e988bd862e3fe1be46a994272793", velacontext.GetContextTraceID(r.Context()))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/care-teams", nil)
+	r.Header.Set("X-Amzn-Trace-Id", "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	recorded := spans()
+	require.Len(t, recorded, 1)
+	assert.Equal(t, "GET /care-teams", recorded[0].Name)
+	assert.Equal(t, trace.SpanKindServer, recorded[0].SpanKind)
+	assert.Equal(t, "53995c3f42cd8ad8", recorded[0].Parent.SpanID().String())
+	assert.Equal(t, codes.Error, recorded[0].Status.Code)
+}
+
+func TestALBMiddlewareW3C(t *testing.T) {
+	spans := setup(t)
+	handler := ALBMiddleware()(func(ctx context.Context, req events.ALBTargetGroupRequest) (*events.ALBTargetGroupResponse, error) {
+		return &events.ALBTargetGroupResponse{StatusCode: http.StatusNotFound}, nil
+	})
+
+	_, err := handler(context.Background(), events.ALBTargetGroupRequest{
+		HTTPMethod: http.MethodPost,
+		Path:       "/invites",
+		Headers:    map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+	})
+	require.NoError(t, err)
+
+	recorded := spans()
+	require.Len(t, recorded, 1)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", recorded[0].SpanContext.TraceID().String())
+	assert.Equal(t, codes.Unset, recorded[0].Status.Code, "a 4xx isn't the server's error")
+}
+
+func TestTransport(t *testing.T) {
+	spans := setup(t)
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+	}))
+	defer server.Close()
+
+	ctx, parent := velacontext.StartSpan(context.Background(), "parent")
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v1/profiles", nil)
+	require.NoError(t, err)
+	response, err := (&http.Client{Transport: Transport(nil)}).Do(request)
+	require.NoError(t, err)
+	response.Body.Close()
+	parent.End()
+
+	assert.Empty(t, request.Header.Get("traceparent"), "the caller's request isn't changed")
+	assert.Contains(t, headers.Get("Traceparent"), parent.SpanContext().TraceID().String())
+	assert.Contains(t, headers.Get("X-Amzn-Trace-Id"), "Root=1-")
+
+	recorded := spans()
+	require.Len(t, recorded, 2)
+	assert.Equal(t, "HTTP GET", recorded[0].Name)
+	assert.Equal(t, trace.SpanKindClient, recorded[0].SpanKind)
+	assert.Equal(t, parent.SpanContext().SpanID(), recorded[0].Parent.SpanID())
+}
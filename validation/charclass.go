@@ -0,0 +1,96 @@
+package validation
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Optional length coupling for the `numeric`, `alpha`, and `alphanumeric`
+// rules, e.g. `numeric:5` for exactly 5 digits, or `numeric:5-9` for
+// between 5 and 9.  A zero max means there is no length check.
+type charClassParams struct {
+	min int
+	max int
+}
+
+func parseCharClassParams(s string) charClassParams {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return charClassParams{}
+	}
+	// Being lazy about checks here, the same as the length rules
+	parts := strings.SplitN(s, "-", 2)
+	trimSliceValues(parts)
+	min, _ := strconv.Atoi(parts[0])
+	max := min
+	if len(parts) > 1 {
+		max, _ = strconv.Atoi(parts[1])
+	}
+	return charClassParams{min: min, max: max}
+}
+
+func (p charClassParams) hasLength() bool {
+	return p.max > 0
+}
+
+// Formats the length for messages, e.g. `5` or `5-9`.
+func (p charClassParams) String() string {
+	if p.min == p.max {
+		return strconv.Itoa(p.min)
+	}
+	return strconv.Itoa(p.min) + "-" + strconv.Itoa(p.max)
+}
+
+func (p charClassParams) details() []string {
+	if !p.hasLength() {
+		return nil
+	}
+	if p.min == p.max {
+		return []string{strconv.Itoa(p.min)}
+	}
+	return []string{strconv.Itoa(p.min), strconv.Itoa(p.max)}
+}
+
+// Only ASCII digits are accepted, since these are used for things like
+// zip codes and member numbers.
+func isASCIIDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isLetterOrDigit(r rune) bool {
+	return unicode.IsLetter(r) || isASCIIDigit(r)
+}
+
+func isNumeric(r *validationRule) bool {
+	return matchesCharClass(r, isASCIIDigit)
+}
+
+func isAlpha(r *validationRule) bool {
+	return matchesCharClass(r, unicode.IsLetter)
+}
+
+func isAlphanumeric(r *validationRule) bool {
+	return matchesCharClass(r, isLetterOrDigit)
+}
+
+func matchesCharClass(r *validationRule, allowed func(rune) bool) bool {
+	value := getFieldValue(r.value)
+	// We've already checked for required previously, so an empty
+	// string should not fail here
+	if value == "" {
+		return true
+	}
+	for _, c := range value {
+		if !allowed(c) {
+			return false
+		}
+	}
+	params := r.params.(charClassParams)
+	if params.hasLength() {
+		length := utf8.RuneCountInString(value)
+		return length >= params.min && length <= params.max
+	}
+	return true
+}
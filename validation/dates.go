@@ -0,0 +1,82 @@
+package validation
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/seniorlink-vela/cs-common/timeutil"
+)
+
+// Allows tests to pin the current time for the date rules.
+var nowFunc = time.Now
+
+// Params for the `before` and `after` rules.  The date is either `now`,
+// evaluated when validating, or a fixed date in `2006-01-02` or RFC 3339 format.
+type dateParams struct {
+	now  bool
+	date time.Time
+}
+
+func parseDateParams(s string) (dateParams, bool) {
+	s = strings.TrimSpace(s)
+	if s == "now" {
+		return dateParams{now: true}, true
+	}
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if d, err := time.Parse(layout, s); err == nil {
+			return dateParams{date: d}, true
+		}
+	}
+	return dateParams{}, false
+}
+
+func (p dateParams) resolve() time.Time {
+	if p.now {
+		return nowFunc()
+	}
+	return p.date
+}
+
+// Returns the time held by a time.Time or *time.Time field, and whether
+// there is one to check.  Zero and nil times are left to `required` or
+// `not-zero`.
+func getTimeValue(v reflect.Value) (time.Time, bool) {
+	if isPtrOrInterface(v) {
+		if v.IsNil() {
+			return time.Time{}, false
+		}
+		v = v.Elem()
+	}
+	t, ok := v.Interface().(time.Time)
+	if !ok || t.IsZero() {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func isBefore(r *validationRule) bool {
+	t, ok := getTimeValue(r.value)
+	if !ok {
+		return true
+	}
+	return t.Before(r.params.(dateParams).resolve())
+}
+
+func isAfter(r *validationRule) bool {
+	t, ok := getTimeValue(r.value)
+	if !ok {
+		return true
+	}
+	return t.After(r.params.(dateParams).resolve())
+}
+
+// Checks that at least the given number of years have passed since the date,
+// e.g. `min-age:18` on a birthday.
+func isMinimumAge(r *validationRule) bool {
+	t, ok := getTimeValue(r.value)
+	if !ok {
+		return true
+	}
+	return timeutil.Age(t, nowFunc()) >= r.params.(int)
+}
@@ -0,0 +1,90 @@
+package validation
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dateStruct struct {
+	Birthday    *time.Time `json:"birthday" validation:"before:now,min-age:18"`
+	Appointment time.Time  `json:"appointment" validation:"after:now"`
+	EnrolledAt  time.Time  `json:"enrolled_at" validation:"after:2020-01-01,before:2030-01-01T00:00:00Z"`
+}
+
+func withNow(t *testing.T, now time.Time) {
+	nowFunc = func() time.Time { return now }
+	t.Cleanup(func() { nowFunc = time.Now })
+}
+
+func TestStructsDateRules(t *testing.T) {
+	now := time.Date(2021, time.March, 15, 12, 0, 0, 0, time.UTC)
+	withNow(t, now)
+	toTimePtr := func(v time.Time) *time.Time { return &v }
+
+	t.Run("Passes with valid or zero dates", func(t *testing.T) {
+		valid := []dateStruct{
+			{
+				Birthday:    toTimePtr(time.Date(2003, time.March, 15, 0, 0, 0, 0, time.UTC)),
+				Appointment: now.Add(time.Hour),
+				EnrolledAt:  time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+			},
+			{},
+		}
+		for _, ts := range valid {
+			em := make(errorMap, 0)
+			err := ValidateStruct(ts, em)
+			require.NoError(t, err, "This struct should have passed validation, instead got: %#v", em)
+		}
+	})
+	t.Run("Fails with dates out of range", func(t *testing.T) {
+		ts := dateStruct{
+			Birthday:    toTimePtr(now.Add(24 * time.Hour)),
+			Appointment: now.Add(-time.Hour),
+			EnrolledAt:  time.Date(2019, time.December, 31, 0, 0, 0, 0, time.UTC),
+		}
+		fieldErrors, err := ValidateStructDetailed(ts)
+		require.Error(t, err)
+		require.Len(t, fieldErrors, 4)
+		assert.Equal(t, beforeNowMessage, fieldErrors[0].Message)
+		assert.Equal(t, fmt.Sprintf(minAgeMessage, 18), fieldErrors[1].Message)
+		assert.Equal(t, afterNowMessage, fieldErrors[2].Message)
+		assert.Equal(t, fmt.Sprintf(afterMessage, "2020-01-01"), fieldErrors[3].Message)
+		assert.Equal(t, []string{"2020-01-01"}, fieldErrors[3].Params)
+	})
+	t.Run("Underage by a day", func(t *testing.T) {
+		ts := dateStruct{
+			Birthday: toTimePtr(time.Date(2003, time.March, 16, 0, 0, 0, 0, time.UTC)),
+		}
+		em := make(errorMap, 0)
+		err := ValidateStruct(ts, em)
+		require.Error(t, err)
+		assert.Equal(t, fmt.Sprintf(minAgeMessage, 18), em["birthday"])
+	})
+}
+
+func TestStructsDateRulesBadParams(t *testing.T) {
+	assert.PanicsWithValue(t, `validation: invalid params "2020-13-01" for the after rule on enrolled_at`, func() {
+		_ = ValidateStruct(struct {
+			EnrolledAt time.Time `json:"enrolled_at" validation:"after:2020-13-01"`
+		}{}, errorMap{})
+	})
+	assert.Panics(t, func() {
+		_ = ValidateStruct(struct {
+			Birthday time.Time `validation:"before:yesterday"`
+		}{}, errorMap{})
+	})
+	assert.PanicsWithValue(t, `validation: invalid params "abc" for the min-age rule on birthday`, func() {
+		_ = ValidateStruct(struct {
+			Birthday time.Time `json:"birthday" validation:"min-age:abc"`
+		}{}, errorMap{})
+	})
+	assert.Panics(t, func() {
+		_ = ValidateStruct(struct {
+			Birthday time.Time `validation:"min-age:-5"`
+		}{}, errorMap{})
+	})
+}
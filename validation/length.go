@@ -0,0 +1,101 @@
+package validation
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Length modes for the `min-length` and `max-length` rules.  These are passed
+// as an optional suffix to the rule, e.g. `max-length:255:bytes`.
+const (
+	// Counts unicode code points.  This matches the character limits of our
+	// database columns, and is the default.
+	LengthModeChars = "chars"
+	// Counts raw bytes, for storage constrained fields.
+	LengthModeBytes = "bytes"
+	// Counts user perceived characters, so an accented letter built from a
+	// combining mark, or an emoji sequence, counts as a single character.
+	LengthModeGraphemes = "graphemes"
+)
+
+type lengthParams struct {
+	length int
+	mode   string
+}
+
+// Parses the `<length>[:<mode>]` portion of a length rule, and is false for
+// a length that isn't a number, or a mode we don't know.
+func parseLengthParams(s string) (lengthParams, bool) {
+	parts := strings.SplitN(s, ":", 2)
+	trimSliceValues(parts)
+	length, err := strconv.Atoi(parts[0])
+	if err != nil || length < 0 {
+		return lengthParams{}, false
+	}
+	lp := lengthParams{
+		length: length,
+		mode:   LengthModeChars,
+	}
+	if len(parts) > 1 && parts[1] != "" {
+		lp.mode = parts[1]
+	}
+	switch lp.mode {
+	case LengthModeChars, LengthModeBytes, LengthModeGraphemes:
+		return lp, true
+	default:
+		return lengthParams{}, false
+	}
+}
+
+func stringLength(s, mode string) int {
+	switch mode {
+	case LengthModeBytes:
+		return len(s)
+	case LengthModeGraphemes:
+		return graphemeCount(s)
+	default:
+		return utf8.RuneCountInString(s)
+	}
+}
+
+const (
+	zeroWidthJoiner    = '\u200d'
+	regionalIndicatorA = '\U0001F1E6'
+	regionalIndicatorZ = '\U0001F1FF'
+)
+
+// This is an approximation of the extended grapheme cluster rules from UAX #29,
+// which covers combining marks, variation selectors, emoji modifiers, zero width
+// joiner sequences and flags.  That is enough for names and free text, and saves
+// us pulling in a full segmentation library.
+func graphemeCount(s string) int {
+	count := 0
+	joined := false
+	regionalIndicators := 0
+	for _, r := range s {
+		switch {
+		case joined:
+			// The rune after a zero width joiner belongs to the previous cluster
+			joined = false
+		case r == zeroWidthJoiner:
+			joined = true
+		case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc, unicode.Variation_Selector):
+			// Combining marks and variation selectors extend the previous cluster
+		case r >= '\U0001F3FB' && r <= '\U0001F3FF':
+			// Emoji skin tone modifiers
+		case r >= regionalIndicatorA && r <= regionalIndicatorZ:
+			// Flags are made from pairs of regional indicators
+			if regionalIndicators%2 == 0 {
+				count++
+			}
+			regionalIndicators++
+			continue
+		default:
+			count++
+		}
+		regionalIndicators = 0
+	}
+	return count
+}
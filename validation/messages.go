@@ -0,0 +1,133 @@
+package validation
+
+import (
+	"strings"
+	"sync"
+)
+
+// The language used when a message has no translation.
+const DefaultLanguage = "en"
+
+// Catalog of error messages, keyed by language and then rule.  Messages for
+// rules with parameters are format strings, matching the English originals.
+var (
+	messageCatalog = map[string]map[string]string{
+		DefaultLanguage: {
+			"required":            requiredMessage,
+			"email":               emailMessage,
+			"min-length":          tooShortMessage,
+			"max-length":          tooLongMessage,
+			"values":              validValueMessage,
+			"values-insensitive":  validValueMessage,
+			"values-fn":           validValueMessage,
+			"not-null":            requiredMessage,
+			"not-zero":            requiredMessage,
+			"uuid":                uuidMessage,
+			"url":                 urlMessage,
+			"timezone":            timezoneMessage,
+			"phone":               phoneMessage,
+			"numeric":             numericMessage,
+			"numeric-length":      numericLengthMessage,
+			"alpha":               alphaMessage,
+			"alpha-length":        alphaLengthMessage,
+			"alphanumeric":        alphanumericMessage,
+			"alphanumeric-length": alphanumericLengthMessage,
+			"before":              beforeMessage,
+			"before-now":          beforeNowMessage,
+			"after":               afterMessage,
+			"after-now":           afterNowMessage,
+			"min-age":             minAgeMessage,
+		},
+		"es": {
+			"required":            "Este campo es obligatorio",
+			"email":               "Esta no es una dirección de correo electrónico válida",
+			"min-length":          "Debe tener al menos %d caracteres",
+			"max-length":          "No debe tener más de %d caracteres",
+			"values":              "Debe ser uno de los siguientes valores: %s",
+			"values-insensitive":  "Debe ser uno de los siguientes valores: %s",
+			"values-fn":           "Debe ser uno de los siguientes valores: %s",
+			"not-null":            "Este campo es obligatorio",
+			"not-zero":            "Este campo es obligatorio",
+			"uuid":                "Este no es un UUID válido",
+			"url":                 "Esta no es una URL válida",
+			"timezone":            "Esta no es una zona horaria válida",
+			"phone":               "Este no es un número de teléfono válido",
+			"numeric":             "Solo debe contener dígitos",
+			"numeric-length":      "Debe tener %s dígitos",
+			"alpha":               "Solo debe contener letras",
+			"alpha-length":        "Debe tener %s letras",
+			"alphanumeric":        "Solo debe contener letras y dígitos",
+			"alphanumeric-length": "Debe tener %s letras o dígitos",
+			"before":              "Debe ser anterior a %s",
+			"before-now":          "Debe estar en el pasado",
+			"after":               "Debe ser posterior a %s",
+			"after-now":           "Debe estar en el futuro",
+			"min-age":             "Esta persona debe tener al menos %d años",
+		},
+		"fr": {
+			"required":            "Ce champ est obligatoire",
+			"email":               "Cette adresse e-mail n'est pas valide",
+			"min-length":          "Ce champ doit contenir au moins %d caractères",
+			"max-length":          "Ce champ ne doit pas dépasser %d caractères",
+			"values":              "Ce champ doit avoir l'une des valeurs suivantes : %s",
+			"values-insensitive":  "Ce champ doit avoir l'une des valeurs suivantes : %s",
+			"values-fn":           "Ce champ doit avoir l'une des valeurs suivantes : %s",
+			"not-null":            "Ce champ est obligatoire",
+			"not-zero":            "Ce champ est obligatoire",
+			"uuid":                "Cet UUID n'est pas valide",
+			"url":                 "Cette URL n'est pas valide",
+			"timezone":            "Ce fuseau horaire n'est pas valide",
+			"phone":               "Ce numéro de téléphone n'est pas valide",
+			"numeric":             "Ce champ ne doit contenir que des chiffres",
+			"numeric-length":      "Ce champ doit contenir %s chiffres",
+			"alpha":               "Ce champ ne doit contenir que des lettres",
+			"alpha-length":        "Ce champ doit contenir %s lettres",
+			"alphanumeric":        "Ce champ ne doit contenir que des lettres et des chiffres",
+			"alphanumeric-length": "Ce champ doit contenir %s lettres ou chiffres",
+			"before":              "Ce champ doit être antérieur au %s",
+			"before-now":          "Ce champ doit être dans le passé",
+			"after":               "Ce champ doit être postérieur au %s",
+			"after-now":           "Ce champ doit être dans le futur",
+			"min-age":             "Cette personne doit avoir au moins %d ans",
+		},
+	}
+	catalogLock sync.RWMutex
+)
+
+// RegisterTranslations adds, or replaces, messages for a language.  Keys are
+// rule names, e.g. `max-length`, and values follow the same format as the
+// English messages.  This is meant to be called during service start up.
+func RegisterTranslations(lang string, messages map[string]string) {
+	lang = normalizeLanguage(lang)
+	catalogLock.Lock()
+	defer catalogLock.Unlock()
+	if _, ok := messageCatalog[lang]; !ok {
+		messageCatalog[lang] = map[string]string{}
+	}
+	for rule, message := range messages {
+		messageCatalog[lang][rule] = message
+	}
+}
+
+// Looks up the message for a rule, trying the full language tag first (`es-mx`),
+// then the base language (`es`), and finally English.
+func messageFor(lang, rule string) string {
+	catalogLock.RLock()
+	defer catalogLock.RUnlock()
+	lang = normalizeLanguage(lang)
+	candidates := []string{lang}
+	if i := strings.Index(lang, "-"); i > 0 {
+		candidates = append(candidates, lang[:i])
+	}
+	candidates = append(candidates, DefaultLanguage)
+	for _, l := range candidates {
+		if message, ok := messageCatalog[l][rule]; ok {
+			return message
+		}
+	}
+	return ""
+}
+
+func normalizeLanguage(lang string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(lang), "_", "-", -1))
+}
@@ -0,0 +1,59 @@
+package validation
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type localizedStruct struct {
+	Name  string `json:"name" validation:"required"`
+	Email string `json:"email" validation:"email"`
+	Note  string `json:"note" validation:"max-length:3"`
+	Title string `json:"title" validation:"values:Dr|Mr|Ms=Pick a title"`
+}
+
+func TestValidateStructLocalized(t *testing.T) {
+	ts := localizedStruct{Email: "bad-email", Note: "Careful", Title: "Dude"}
+	t.Run("Messages are translated", func(t *testing.T) {
+		em := make(errorMap, 0)
+		err := ValidateStructLocalized(ts, "es", em)
+		require.Error(t, err)
+		assert.Equal(t, "Este campo es obligatorio", em["name"])
+		assert.Equal(t, "Esta no es una dirección de correo electrónico válida", em["email"])
+		assert.Equal(t, "No debe tener más de 3 caracteres", em["note_too_long"])
+	})
+	t.Run("Regional languages fall back to the base language", func(t *testing.T) {
+		em := make(errorMap, 0)
+		err := ValidateStructLocalized(ts, "fr_CA", em)
+		require.Error(t, err)
+		assert.Equal(t, "Ce champ est obligatoire", em["name"])
+	})
+	t.Run("Unknown languages fall back to English", func(t *testing.T) {
+		em := make(errorMap, 0)
+		err := ValidateStructLocalized(ts, "xx", em)
+		require.Error(t, err)
+		assert.Equal(t, requiredMessage, em["name"])
+		assert.Equal(t, fmt.Sprintf(tooLongMessage, 3), em["note_too_long"])
+	})
+	t.Run("Tag overrides aren't translated", func(t *testing.T) {
+		em := make(errorMap, 0)
+		err := ValidateStructLocalized(ts, "es", em)
+		require.Error(t, err)
+		assert.Equal(t, "Pick a title", em["title"])
+	})
+}
+
+func TestRegisterTranslations(t *testing.T) {
+	RegisterTranslations("pt-BR", map[string]string{
+		"required": "Este campo é obrigatório",
+	})
+	em := make(errorMap, 0)
+	err := ValidateStructLocalized(localizedStruct{Email: "bad-email"}, "pt-br", em)
+	require.Error(t, err)
+	assert.Equal(t, "Este campo é obrigatório", em["name"])
+	// Missing translations fall back to English
+	assert.Equal(t, emailMessage, em["email"])
+}
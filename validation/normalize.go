@@ -0,0 +1,137 @@
+package validation
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+type normalizerFunc func(string) string
+
+// Normalizers for the `normalize` tag, e.g. `normalize:"trim,lower"`.  They
+// are applied in the order they're listed.
+var normalizerMap = map[string]normalizerFunc{
+	"trim":            strings.TrimSpace,
+	"lower":           strings.ToLower,
+	"upper":           strings.ToUpper,
+	"collapse-spaces": collapseSpaces,
+	"titlecase":       titleCase,
+	"e164":            e164,
+}
+
+// NormalizeStruct applies the `normalize` tags of a struct, updating string
+// and *string fields in place, so it must be passed a pointer.
+func NormalizeStruct(s interface{}) error {
+	valS := reflect.ValueOf(s)
+	if valS.Kind() != reflect.Ptr || valS.IsNil() || valS.Elem().Kind() != reflect.Struct {
+		return KindError
+	}
+	valS = valS.Elem()
+	plan := planFor(valS.Type())
+	for _, fp := range plan.fields {
+		if len(fp.normalizers) == 0 {
+			continue
+		}
+		fieldVal := valS.Field(fp.index)
+		if fp.optional {
+			normalizeOptional(fieldVal, fp.normalizers)
+			continue
+		}
+		if fieldVal.Kind() == reflect.Ptr {
+			if fieldVal.IsNil() {
+				continue
+			}
+			fieldVal = fieldVal.Elem()
+		}
+		if fieldVal.Kind() != reflect.String || !fieldVal.CanSet() {
+			continue
+		}
+		value := fieldVal.String()
+		for _, n := range fp.normalizers {
+			value = n(value)
+		}
+		fieldVal.SetString(value)
+	}
+	return nil
+}
+
+// Normalizes the string an Optional holds, if it holds one.
+func normalizeOptional(fieldVal reflect.Value, normalizers []normalizerFunc) {
+	fieldVal, ok := derefOptional(fieldVal)
+	if !ok || !fieldVal.CanAddr() {
+		return
+	}
+	value, ok := fieldVal.Interface().(Optional).OptionalValue()
+	setter, canSet := fieldVal.Addr().Interface().(optionalSetter)
+	if !ok || value == nil || !canSet {
+		return
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.String {
+		return
+	}
+	s := v.String()
+	for _, n := range normalizers {
+		s = n(s)
+	}
+	// Converted back, for named string types
+	setter.SetOptionalValue(reflect.ValueOf(s).Convert(v.Type()).Interface())
+}
+
+// NormalizeAndValidateStruct normalizes the struct the pointer refers to, and
+// then validates it.
+func NormalizeAndValidateStruct(s interface{}, ae AppendableError) error {
+	if err := NormalizeStruct(s); err != nil {
+		return err
+	}
+	return ValidateStruct(reflect.ValueOf(s).Elem().Interface(), ae)
+}
+
+func compileNormalizers(tag string) []normalizerFunc {
+	normalizers := []normalizerFunc{}
+	for _, name := range strings.Split(tag, ",") {
+		// Unknown normalizers are ignored, the same as unknown rules
+		if n, ok := normalizerMap[strings.TrimSpace(name)]; ok {
+			normalizers = append(normalizers, n)
+		}
+	}
+	return normalizers
+}
+
+// Replaces any run of whitespace with a single space.  Leading and trailing
+// whitespace is collapsed, not removed, so combine with `trim` as needed.
+func collapseSpaces(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	inSpace := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			if !inSpace {
+				b.WriteRune(' ')
+			}
+			inSpace = true
+			continue
+		}
+		inSpace = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Upper cases the first letter of each word, where words are separated by
+// whitespace or hyphens.  The rest of the word is left alone, so names like
+// `McDonald` keep their casing.
+func titleCase(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	startOfWord := true
+	for _, r := range s {
+		if startOfWord {
+			b.WriteRune(unicode.ToTitle(r))
+		} else {
+			b.WriteRune(r)
+		}
+		startOfWord = unicode.IsSpace(r) || r == '-'
+	}
+	return b.String()
+}
@@ -0,0 +1,55 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type normalizeStruct struct {
+	Name     string  `json:"name" normalize:"trim,collapse-spaces,titlecase" validation:"max-length:20"`
+	Email    *string `json:"email" normalize:"trim,lower" validation:"email"`
+	Code     string  `json:"code" normalize:"upper"`
+	Missing  *string `json:"missing" normalize:"trim"`
+	Untagged string  `json:"untagged"`
+}
+
+func TestNormalizeStruct(t *testing.T) {
+	email := "  Walter@Example.COM "
+	ts := normalizeStruct{
+		Name:     "  mary-jane   \t mcDonald ",
+		Email:    &email,
+		Code:     "ab12",
+		Untagged: "  left alone ",
+	}
+	err := NormalizeStruct(&ts)
+	require.NoError(t, err)
+	assert.Equal(t, "Mary-Jane McDonald", ts.Name)
+	assert.Equal(t, "walter@example.com", *ts.Email)
+	assert.Equal(t, "AB12", ts.Code)
+	assert.Nil(t, ts.Missing)
+	assert.Equal(t, "  left alone ", ts.Untagged)
+}
+
+func TestNormalizeStructRequiresPointer(t *testing.T) {
+	assert.Equal(t, KindError, NormalizeStruct(normalizeStruct{}))
+	var ts *normalizeStruct
+	assert.Equal(t, KindError, NormalizeStruct(ts))
+}
+
+func TestNormalizeAndValidateStruct(t *testing.T) {
+	// This would fail the email rule without trimming
+	email := " walter@example.com "
+	ts := normalizeStruct{Name: "walter   sobchak", Email: &email}
+	em := make(errorMap, 0)
+	err := NormalizeAndValidateStruct(&ts, em)
+	require.NoError(t, err, "This struct should have passed validation, instead got: %#v", em)
+	assert.Equal(t, "Walter Sobchak", ts.Name)
+
+	ts.Name = "walter sobchak the second"
+	em = make(errorMap, 0)
+	err = NormalizeAndValidateStruct(&ts, em)
+	require.Error(t, err)
+	assert.Len(t, em, 1)
+}
@@ -0,0 +1,278 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is an OpenAPI 3 schema object, covering the subset of the
+// specification that we can derive from struct types and their tags.
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+}
+
+// MediaType is an OpenAPI 3 media type object.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// RequestBody is an OpenAPI 3 request body object.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Parameter is an OpenAPI 3 parameter object.
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// SchemaFor builds the schema for a struct value or type, including every rule.
+func SchemaFor(v interface{}) *Schema {
+	return SchemaForScenario(v, "")
+}
+
+// SchemaForScenario builds the schema using only the rules that apply to the
+// scenario, so the same model can document both create and patch bodies.
+func SchemaForScenario(v interface{}, scenario string) *Schema {
+	t, ok := v.(reflect.Type)
+	if !ok {
+		t = reflect.TypeOf(v)
+	}
+	return schemaForType(t, scenario, map[reflect.Type]bool{})
+}
+
+// RequestBodyFor wraps the schema in a JSON request body.  If wrapper isn't
+// empty the schema is nested under that key, e.g. `user_profile`.
+func RequestBodyFor(v interface{}, scenario, wrapper string) *RequestBody {
+	schema := SchemaForScenario(v, scenario)
+	if wrapper != "" {
+		schema = &Schema{
+			Type:       "object",
+			Properties: map[string]*Schema{wrapper: schema},
+			Required:   []string{wrapper},
+		}
+	}
+	return &RequestBody{
+		Required: true,
+		Content: map[string]MediaType{
+			"application/json": {Schema: schema},
+		},
+	}
+}
+
+// ParametersFor describes each field of the struct as a parameter in the given
+// location, `query`, `path`, or `header`.
+func ParametersFor(v interface{}, in string) []Parameter {
+	schema := SchemaFor(v)
+	params := []Parameter{}
+	t := derefType(reflect.TypeOf(v))
+	for _, name := range jsonFieldOrder(t) {
+		prop, ok := schema.Properties[name]
+		if !ok {
+			continue
+		}
+		required, _ := contains(schema.Required, name)
+		params = append(params, Parameter{
+			Name: name,
+			In:   in,
+			// Path parameters are always required
+			Required: required || in == "path",
+			Schema:   prop,
+		})
+	}
+	return params
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func schemaForType(t reflect.Type, scenario string, visiting map[reflect.Type]bool) *Schema {
+	t = derefType(t)
+	// Optionals can be sent as null to clear them
+	if isOptional(t) {
+		schema := &Schema{}
+		if elem := optionalElem(t); elem != nil {
+			schema = schemaForType(elem, scenario, visiting)
+		}
+		schema.Nullable = true
+		return schema
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: schemaForType(t.Elem(), scenario, visiting)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaForType(t.Elem(), scenario, visiting)}
+	case reflect.Struct:
+		return structSchema(t, scenario, visiting)
+	default:
+		// interface{} and anything else we can't describe accepts any value
+		return &Schema{}
+	}
+}
+
+func structSchema(t reflect.Type, scenario string, visiting map[reflect.Type]bool) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	// Recursive types are described once, and left open below that
+	if visiting[t] {
+		return schema
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	rules := map[int][]compiledRule{}
+	for _, fp := range planFor(t).fields {
+		rules[fp.index] = fp.rules
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, ok := jsonName(f)
+		if !ok {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			// Embedded structs without a name are flattened, the same as encoding/json
+			embedded := schemaForType(f.Type, scenario, visiting)
+			for k, v := range embedded.Properties {
+				schema.Properties[k] = v
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		prop := schemaForType(f.Type, scenario, visiting)
+		required := false
+		for _, cr := range rules[i] {
+			if !cr.tag.appliesTo(scenario) {
+				continue
+			}
+			if cr.rule.ruleKey == "required" {
+				required = true
+				continue
+			}
+			applyRuleToSchema(prop, cr)
+		}
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = prop
+	}
+	return schema
+}
+
+func applyRuleToSchema(s *Schema, cr compiledRule) {
+	switch cr.rule.ruleKey {
+	case "not-null":
+		s.Nullable = false
+	case "email":
+		s.Format = "email"
+	case "uuid":
+		s.Format = "uuid"
+	case "url":
+		s.Format = "uri"
+	case "timezone":
+		s.Description = "An IANA time zone, e.g. America/New_York"
+	case "phone":
+		s.Description = "A phone number, e.g. +16175550123"
+	case "min-length", "max-length":
+		lp := cr.rule.params.(lengthParams)
+		// JSON schema lengths are in characters, so a byte limit can't be described
+		if lp.mode == LengthModeBytes {
+			return
+		}
+		length := lp.length
+		if cr.rule.ruleKey == "min-length" {
+			s.MinLength = &length
+		} else {
+			s.MaxLength = &length
+		}
+	case "values":
+		s.Enum = append([]string{}, cr.details...)
+	case "values-fn":
+		// Documents the values as they are when the schema is generated
+		if values, ok := resolveValues(cr.provider); ok {
+			s.Enum = append([]string{}, values...)
+		}
+	case "values-insensitive":
+		s.Description = fmt.Sprintf("One of (case insensitive): %s", strings.Join(cr.details, ", "))
+	case "numeric":
+		params := cr.rule.params.(charClassParams)
+		switch {
+		case !params.hasLength():
+			s.Pattern = "^[0-9]+$"
+		case params.min == params.max:
+			s.Pattern = fmt.Sprintf("^[0-9]{%d}$", params.min)
+		default:
+			s.Pattern = fmt.Sprintf("^[0-9]{%d,%d}$", params.min, params.max)
+		}
+	}
+}
+
+// Returns the json name of the field, and false if it isn't serialized.
+// The name is empty when the tag doesn't set one.
+func jsonName(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" && !f.Anonymous {
+		return "", false
+	}
+	name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return "", false
+	}
+	return name, true
+}
+
+// The serialized field names, in declaration order.
+func jsonFieldOrder(t reflect.Type) []string {
+	names := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, ok := jsonName(f)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,108 @@
+package validation
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type schemaAddress struct {
+	Line1   string `json:"line1" validation:"required,max-length:255"`
+	ZipCode string `json:"zip_code" validation:"numeric:5"`
+}
+
+type schemaBase struct {
+	ID string `json:"id" validation:"uuid"`
+}
+
+type schemaStruct struct {
+	schemaBase
+	FirstName *string           `json:"first_name,omitempty" validation:"required(create),min-length:1,max-length:255"`
+	Email     string            `json:"email" validation:"required,email"`
+	Website   string            `json:"website" validation:"url"`
+	Gender    string            `json:"gender" validation:"values:Female|Male"`
+	PhoneType string            `json:"phone_type" validation:"values-insensitive:mobile|home"`
+	Notes     string            `json:"notes" validation:"max-length:100:bytes"`
+	Age       int               `json:"age"`
+	Score     float64           `json:"score"`
+	Active    bool              `json:"active"`
+	Birthday  *time.Time        `json:"birthday"`
+	Addresses []schemaAddress   `json:"addresses"`
+	Extra     map[string]string `json:"extra"`
+	Payload   interface{}       `json:"payload"`
+	Secret    string            `json:"-"`
+	internal  string
+	Children  []*schemaNode `json:"children"`
+}
+
+type schemaNode struct {
+	Name     string        `json:"name"`
+	Children []*schemaNode `json:"children"`
+}
+
+func TestSchemaFor(t *testing.T) {
+	s := SchemaFor(schemaStruct{})
+
+	assert.Equal(t, "object", s.Type)
+	assert.Equal(t, []string{"first_name", "email"}, s.Required)
+	assert.Equal(t, &Schema{Type: "string", Format: "uuid"}, s.Properties["id"])
+
+	one, max := 1, 255
+	assert.Equal(t, &Schema{Type: "string", MinLength: &one, MaxLength: &max}, s.Properties["first_name"])
+	assert.Equal(t, "email", s.Properties["email"].Format)
+	assert.Equal(t, "uri", s.Properties["website"].Format)
+	assert.Equal(t, []string{"Female", "Male"}, s.Properties["gender"].Enum)
+	assert.Empty(t, s.Properties["phone_type"].Enum)
+	assert.Contains(t, s.Properties["phone_type"].Description, "mobile, home")
+	assert.Nil(t, s.Properties["notes"].MaxLength)
+	assert.Equal(t, &Schema{Type: "integer", Format: "int64"}, s.Properties["age"])
+	assert.Equal(t, &Schema{Type: "number", Format: "double"}, s.Properties["score"])
+	assert.Equal(t, &Schema{Type: "boolean"}, s.Properties["active"])
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, s.Properties["birthday"])
+	assert.Equal(t, &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}}, s.Properties["extra"])
+	assert.Equal(t, &Schema{}, s.Properties["payload"])
+	assert.NotContains(t, s.Properties, "Secret")
+	assert.NotContains(t, s.Properties, "internal")
+
+	addresses := s.Properties["addresses"]
+	require.Equal(t, "array", addresses.Type)
+	assert.Equal(t, []string{"line1"}, addresses.Items.Required)
+	assert.Equal(t, "^[0-9]{5}$", addresses.Items.Properties["zip_code"].Pattern)
+
+	// Recursive types stop rather than looping forever
+	node := s.Properties["children"].Items
+	assert.Equal(t, "object", node.Type)
+	assert.Equal(t, "object", node.Properties["children"].Items.Type)
+	assert.Empty(t, node.Properties["children"].Items.Properties)
+
+	_, err := json.Marshal(s)
+	require.NoError(t, err)
+}
+
+func TestSchemaForScenario(t *testing.T) {
+	s := SchemaForScenario(&schemaStruct{}, ScenarioPatch)
+	assert.Equal(t, []string{"email"}, s.Required)
+}
+
+func TestRequestBodyFor(t *testing.T) {
+	rb := RequestBodyFor(schemaAddress{}, "", "address")
+	require.True(t, rb.Required)
+	schema := rb.Content["application/json"].Schema
+	assert.Equal(t, []string{"address"}, schema.Required)
+	assert.Equal(t, []string{"line1"}, schema.Properties["address"].Required)
+
+	b, err := json.Marshal(rb)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"application/json":{"schema":{"type":"object"`)
+}
+
+func TestParametersFor(t *testing.T) {
+	params := ParametersFor(schemaAddress{}, "query")
+	require.Len(t, params, 2)
+	assert.Equal(t, Parameter{Name: "line1", In: "query", Required: true, Schema: &Schema{Type: "string", MaxLength: params[0].Schema.MaxLength}}, params[0])
+	assert.Equal(t, "zip_code", params[1].Name)
+	assert.False(t, params[1].Required)
+}
@@ -0,0 +1,83 @@
+package validation
+
+import "reflect"
+
+// Optional is implemented by fields that know whether they were sent, like
+// optional.Optional.  Rules check the value when there is one, and otherwise
+// see a nil, the same as a nil pointer, so only `required` fails.  Use
+// `not-null` instead for fields that can be left out but not cleared, like a
+// PATCH's name: it's only checked when the field was sent.  The value
+// is the zero value when there isn't one, which is how its type is found.
+// Pointers to Optionals are seen through, and a nil one is absent.
+type Optional interface {
+	OptionalValue() (interface{}, bool)
+}
+
+// Implemented by Optionals that can tell absent from null, like
+// optional.Optional, for `not-null`.
+type sentOptional interface {
+	IsSet() bool
+}
+
+// Implemented by pointers to Optionals, so normalizers can update them.
+type optionalSetter interface {
+	SetOptionalValue(interface{}) bool
+}
+
+var (
+	optionalType = reflect.TypeOf((*Optional)(nil)).Elem()
+	// What rules see for an Optional without a value
+	nilOptional = reflect.Zero(reflect.TypeOf((*interface{})(nil)).Elem())
+)
+
+// A pointer to an Optional implements it too, but calling it on the nil zero
+// value panics, so pointers are followed first.
+func isOptional(t reflect.Type) bool {
+	return derefType(t).Implements(optionalType)
+}
+
+// The type an Optional holds, or nil when it holds an interface, which can't
+// be seen from the zero value.
+func optionalElem(t reflect.Type) reflect.Type {
+	value, _ := reflect.Zero(derefType(t)).Interface().(Optional).OptionalValue()
+	return reflect.TypeOf(value)
+}
+
+// Follows pointers to an Optional, returning false for a nil one.
+func derefOptional(v reflect.Value) (reflect.Value, bool) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	return v, true
+}
+
+// Reports whether the Optional was sent, as null or a value.  Optionals that
+// can't tell count as sent when they have a value.
+func optionalSent(v reflect.Value) bool {
+	v, ok := derefOptional(v)
+	if !ok {
+		return false
+	}
+	if sent, ok := v.Interface().(sentOptional); ok {
+		return sent.IsSet()
+	}
+	_, ok = v.Interface().(Optional).OptionalValue()
+	return ok
+}
+
+// Returns the value the Optional holds, or a nil interface when it's absent
+// or null.
+func unwrapOptional(v reflect.Value) reflect.Value {
+	v, ok := derefOptional(v)
+	if !ok {
+		return nilOptional
+	}
+	value, ok := v.Interface().(Optional).OptionalValue()
+	if !ok || value == nil {
+		return nilOptional
+	}
+	return reflect.ValueOf(value)
+}
@@ -0,0 +1,96 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/seniorlink-vela/cs-common/optional"
+)
+
+type testAddress struct {
+	City string `json:"city" validation:"required"`
+}
+
+type testOptionalPatch struct {
+	Email    optional.Optional[string]      `json:"email,omitzero" validation:"required,email" normalize:"trim,lower"`
+	TimeZone optional.Optional[string]      `json:"time_zone,omitzero" validation:"timezone"`
+	Birthday optional.Optional[time.Time]   `json:"birthday,omitzero" validation:"before:now"`
+	Address  optional.Optional[testAddress] `json:"address,omitzero"`
+}
+
+func TestOptionalFields(t *testing.T) {
+	var patch testOptionalPatch
+	require.NoError(t, json.Unmarshal([]byte(`{"email": "  Walter@Example.com ", "time_zone": null, "address": {"city": ""}}`), &patch))
+	require.NoError(t, NormalizeStruct(&patch))
+	assert.Equal(t, "walter@example.com", patch.Email.Or(""))
+	assert.True(t, patch.TimeZone.IsNull())
+
+	em := errorMap{}
+	assert.Equal(t, ValidationError, ValidateStruct(patch, em))
+	assert.Equal(t, errorMap{"city": requiredMessage}, em)
+
+	// Absent and null fields are like nil pointers, so only required fails
+	em = errorMap{}
+	assert.Equal(t, ValidationError, ValidateStruct(testOptionalPatch{TimeZone: optional.Null[string]()}, em))
+	assert.Equal(t, errorMap{"email": requiredMessage}, em)
+
+	em = errorMap{}
+	patch = testOptionalPatch{
+		Email:    optional.Of("donny"),
+		TimeZone: optional.Of("Bowling/Alley"),
+		Birthday: optional.Of(time.Now().Add(time.Hour)),
+	}
+	assert.Equal(t, ValidationError, ValidateStruct(patch, em))
+	assert.Equal(t, errorMap{"email": emailMessage, "time_zone": timezoneMessage, "birthday": beforeNowMessage}, em)
+}
+
+func TestOptionalPointers(t *testing.T) {
+	type patch struct {
+		Name *optional.Optional[string] `json:"name" validation:"max-length:3" normalize:"trim"`
+	}
+	em := errorMap{}
+	assert.NoError(t, ValidateStruct(patch{}, em))
+	assert.Empty(t, em)
+
+	name := optional.Of(" Walter ")
+	p := patch{Name: &name}
+	require.NoError(t, NormalizeStruct(&p))
+	assert.Equal(t, "Walter", name.Or(""))
+	assert.Equal(t, ValidationError, ValidateStruct(p, em))
+	assert.Contains(t, em, "name_too_long")
+
+	assert.True(t, SchemaFor(patch{}).Properties["name"].Nullable)
+}
+
+func TestOptionalNotNull(t *testing.T) {
+	type patch struct {
+		Name optional.Optional[string] `json:"name,omitzero" validation:"not-null,max-length:3"`
+	}
+	// Absent is fine, but null and empty aren't
+	em := errorMap{}
+	assert.NoError(t, ValidateStruct(patch{}, em))
+	for _, name := range []optional.Optional[string]{optional.Null[string](), optional.Of("")} {
+		em = errorMap{}
+		assert.Equal(t, ValidationError, ValidateStruct(patch{Name: name}, em))
+		assert.Equal(t, errorMap{"name": requiredMessage}, em)
+	}
+	em = errorMap{}
+	assert.Equal(t, ValidationError, ValidateStruct(patch{Name: optional.Of("Walter")}, em))
+	assert.Equal(t, errorMap{"name_too_long": fmt.Sprintf(tooLongMessage, 3)}, em)
+
+	assert.False(t, SchemaFor(patch{}).Properties["name"].Nullable)
+}
+
+func TestOptionalSchema(t *testing.T) {
+	schema := SchemaFor(testOptionalPatch{})
+	assert.Equal(t, &Schema{Type: "string", Format: "email", Nullable: true}, schema.Properties["email"])
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time", Nullable: true}, schema.Properties["birthday"])
+	assert.Equal(t, "object", schema.Properties["address"].Type)
+	assert.True(t, schema.Properties["address"].Nullable)
+	assert.Equal(t, []string{"email"}, schema.Required)
+}
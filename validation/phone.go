@@ -0,0 +1,63 @@
+package validation
+
+import "strings"
+
+// NormalizePhone returns the phone number in E.164 form, e.g. `+16175550123`,
+// and false if it isn't one.  Spaces, dashes, dots and brackets are ignored.
+// Numbers without a leading `+` are taken to be North American, with or
+// without the leading 1, since that's where our members are; others have to
+// include their country code.
+func NormalizePhone(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	international := strings.HasPrefix(s, "+")
+	if international {
+		s = s[1:]
+	}
+	digits := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			digits = append(digits, c)
+		case c == ' ' || c == '-' || c == '.' || c == '(' || c == ')':
+		default:
+			return "", false
+		}
+	}
+	if !international {
+		if len(digits) == 10 {
+			digits = append([]byte{'1'}, digits...)
+		}
+		if len(digits) != 11 || digits[0] != '1' {
+			return "", false
+		}
+	}
+	if len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return "", false
+	}
+	// North American area codes and exchanges can't start with 0 or 1
+	if digits[0] == '1' && (len(digits) != 11 || digits[1] < '2' || digits[4] < '2') {
+		return "", false
+	}
+	return "+" + string(digits), true
+}
+
+// Validity check for phone numbers, see NormalizePhone for what's accepted.
+func isPhoneValid(r *validationRule) bool {
+	value := getFieldValue(r.value)
+	// We've already checked for required previously, so an empty
+	// string should not fail here
+	if strings.TrimSpace(value) == "" {
+		return true
+	}
+	_, ok := NormalizePhone(value)
+	return ok
+}
+
+// Normalizes phone numbers to E.164, leaving anything that isn't one alone
+// for the `phone` rule to reject.
+func e164(s string) string {
+	if normalized, ok := NormalizePhone(s); ok {
+		return normalized
+	}
+	return s
+}
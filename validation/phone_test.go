@@ -0,0 +1,61 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	valid := map[string]string{
+		"(617) 555-0123":    "+16175550123",
+		"617.555.0123":      "+16175550123",
+		"1-617-555-0123":    "+16175550123",
+		" +1 617 555 0123 ": "+16175550123",
+		"+44 20 7946 0958":  "+442079460958",
+		"+33 1 23 45 67 89": "+33123456789",
+	}
+	for input, expected := range valid {
+		normalized, ok := NormalizePhone(input)
+		assert.True(t, ok, input)
+		assert.Equal(t, expected, normalized, input)
+	}
+
+	invalid := []string{
+		"",
+		"555-0123",
+		"617-555-0123 x42",
+		"(017) 555-0123",
+		"617-155-0123",
+		"2-617-555-0123",
+		"44 20 7946 0958",
+		"+0 20 7946 0958",
+		"+1 617 555 012",
+		"+1234567890123456",
+		"call me",
+	}
+	for _, input := range invalid {
+		_, ok := NormalizePhone(input)
+		assert.False(t, ok, input)
+	}
+}
+
+func TestStructsPhoneRule(t *testing.T) {
+	type phoneStruct struct {
+		Mobile *string `json:"mobile" normalize:"e164" validation:"phone"`
+		Home   string  `json:"home" normalize:"e164" validation:"phone"`
+	}
+	mobile := "(617) 555-0123"
+	ts := phoneStruct{Mobile: &mobile, Home: "555-0123"}
+	em := make(errorMap, 0)
+	err := NormalizeAndValidateStruct(&ts, em)
+	require.Error(t, err)
+	assert.Equal(t, "+16175550123", *ts.Mobile)
+	assert.Equal(t, "555-0123", ts.Home)
+	assert.Len(t, em, 1)
+	assert.Equal(t, phoneMessage, em["home"])
+
+	em = make(errorMap, 0)
+	require.NoError(t, ValidateStruct(phoneStruct{}, em))
+}
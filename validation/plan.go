@@ -0,0 +1,239 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Parsing tags and looking up rules for every call adds up on hot request paths,
+// so the rules for each struct type are compiled once, and cached here.
+var planCache sync.Map // map[reflect.Type]*structPlan
+
+type structPlan struct {
+	fields []fieldPlan
+}
+
+type fieldPlan struct {
+	index       int
+	name        string
+	rules       []compiledRule
+	normalizers []normalizerFunc
+	// Whether the field may hold structs that need validating too
+	nested bool
+	// Whether the field is an Optional, which rules see through
+	optional bool
+}
+
+type compiledRule struct {
+	tag  ruleTag
+	rule validationRule
+	// Passed to the message format, for rules whose messages take a parameter
+	formatArg interface{}
+	// Reported as FieldError.Params
+	details []string
+	// The catalog key for the message, when it isn't the rule name
+	messageKey string
+	// The ValuesProvider for `values-fn`, whose values are only known
+	// at validation time
+	provider string
+}
+
+// Returns the cached plan for the type, compiling it on first use.
+func planFor(t reflect.Type) *structPlan {
+	if plan, ok := planCache.Load(t); ok {
+		return plan.(*structPlan)
+	}
+	plan, _ := planCache.LoadOrStore(t, compilePlan(t))
+	return plan.(*structPlan)
+}
+
+func compilePlan(t reflect.Type) *structPlan {
+	plan := &structPlan{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		validationRules := f.Tag.Get("validation")
+		normalizeRules := f.Tag.Get("normalize")
+		// We can't read unexported fields, so there's nothing to descend into
+		optional := f.PkgPath == "" && isOptional(f.Type)
+		nested := f.PkgPath == "" && mayHoldStruct(f.Type)
+		if validationRules == "" && normalizeRules == "" && !nested {
+			continue
+		}
+		fp := fieldPlan{
+			index:    i,
+			name:     fieldName(f),
+			nested:   nested,
+			optional: optional,
+		}
+		if normalizeRules != "" {
+			fp.normalizers = compileNormalizers(normalizeRules)
+		}
+		required := []compiledRule{}
+		others := []compiledRule{}
+		for _, r := range strings.Split(validationRules, ",") {
+			cr, ok := compileRule(parseRuleTag(r), fp.name)
+			if !ok {
+				// If there isn't a rule we can execute on, just move on to the next one.
+				continue
+			}
+			if cr.rule.ruleKey == "required" {
+				required = append(required, cr)
+			} else {
+				others = append(others, cr)
+			}
+		}
+		// Required rules always run first
+		fp.rules = append(required, others...)
+		if len(fp.rules) > 0 || len(fp.normalizers) > 0 || fp.nested {
+			plan.fields = append(plan.fields, fp)
+		}
+	}
+	return plan
+}
+
+// Whether values of the type could contain a struct to validate.  Maps aren't
+// followed, time.Time is treated as a plain value, and Optionals are seen
+// through.
+func mayHoldStruct(t reflect.Type) bool {
+	for {
+		if isOptional(t) {
+			if t = optionalElem(t); t == nil {
+				return true
+			}
+		}
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array:
+			t = t.Elem()
+		case reflect.Interface:
+			return true
+		case reflect.Struct:
+			return t != timeType
+		default:
+			return false
+		}
+	}
+}
+
+func compileRule(tag ruleTag, fName string) (compiledRule, bool) {
+	rule, ok := validationRuleMap[tag.name]
+	if !ok {
+		return compiledRule{}, false
+	}
+	cr := compiledRule{tag: tag}
+	switch rule.ruleKey {
+	case "required", "email", "not-null", "not-zero", "uuid", "url", "timezone", "phone":
+		rule.messageKey = fName
+	case "min-length":
+		lp, ok := parseLengthParams(tag.params)
+		if !ok {
+			invalidParams(tag, fName)
+		}
+		rule.messageKey = fName + "_too_short"
+		rule.params = lp
+		cr.formatArg = lp.length
+		cr.details = []string{strconv.Itoa(lp.length), lp.mode}
+	case "max-length":
+		lp, ok := parseLengthParams(tag.params)
+		if !ok {
+			invalidParams(tag, fName)
+		}
+		rule.messageKey = fName + "_too_long"
+		rule.params = lp
+		cr.formatArg = lp.length
+		cr.details = []string{strconv.Itoa(lp.length), lp.mode}
+	case "values":
+		validValues := strings.Split(tag.params, "|")
+		trimSliceValues(validValues)
+		rule.messageKey = fName
+		rule.params = validValues
+		cr.formatArg = strings.Join(validValues, ", ")
+		cr.details = validValues
+	case "values-insensitive":
+		validValues := strings.Split(tag.params, "|")
+		trimSliceValues(validValues)
+		allowed := append([]string{}, validValues...)
+		lowerCaseSliceValues(allowed)
+		rule.messageKey = fName
+		rule.params = allowed
+		cr.formatArg = strings.Join(validValues, ", ")
+		cr.details = validValues
+	case "numeric", "alpha", "alphanumeric":
+		params := parseCharClassParams(tag.params)
+		rule.messageKey = fName
+		rule.params = params
+		if params.hasLength() {
+			cr.messageKey = rule.ruleKey + "-length"
+			cr.formatArg = params.String()
+			cr.details = params.details()
+		}
+	case "before", "after":
+		params, ok := parseDateParams(tag.params)
+		if !ok {
+			invalidParams(tag, fName)
+		}
+		rule.messageKey = fName
+		rule.params = params
+		cr.details = []string{tag.params}
+		if params.now {
+			cr.messageKey = rule.ruleKey + "-now"
+		} else {
+			cr.formatArg = tag.params
+		}
+	case "values-fn":
+		rule.messageKey = fName
+		rule.params = tag.params
+		cr.provider = tag.params
+	case "min-age":
+		years, err := strconv.Atoi(tag.params)
+		if err != nil || years < 0 {
+			invalidParams(tag, fName)
+		}
+		rule.messageKey = fName
+		rule.params = years
+		cr.formatArg = years
+		cr.details = []string{strconv.Itoa(years)}
+	default:
+		return compiledRule{}, false
+	}
+	cr.rule = rule
+	return cr, true
+}
+
+// Bad params are a mistake in the struct's tags, so rather than quietly
+// turning the check off, they panic when the plan is compiled, the same as a
+// bad regexp would.
+func invalidParams(tag ruleTag, fName string) {
+	panic(fmt.Sprintf("validation: invalid params %q for the %s rule on %s", tag.params, tag.name, fName))
+}
+
+// Builds the error for a failed rule.  Overrides from the tag win,
+// otherwise the message comes from the catalog for the language.
+func (cr compiledRule) fieldError(field, lang string) FieldError {
+	details := cr.details
+	formatArg := cr.formatArg
+	if cr.provider != "" {
+		details, _ = resolveValues(cr.provider)
+		formatArg = strings.Join(details, ", ")
+	}
+	message := cr.tag.message
+	if message == "" {
+		key := cr.rule.ruleKey
+		if cr.messageKey != "" {
+			key = cr.messageKey
+		}
+		message = messageFor(lang, key)
+		if formatArg != nil {
+			message = fmt.Sprintf(message, formatArg)
+		}
+	}
+	return FieldError{
+		Field:   field,
+		Rule:    cr.rule.ruleKey,
+		Params:  details,
+		Message: message,
+		key:     cr.rule.messageKey,
+	}
+}
@@ -0,0 +1,52 @@
+package validation
+
+import (
+	"strings"
+	"sync"
+)
+
+// ValuesProvider returns the allowed values for a `values-fn` rule.  It's
+// called every time the rule is checked, so it should be cheap, e.g. reading
+// from the current config rather than calling an API.
+type ValuesProvider func() []string
+
+var (
+	valuesProviders     = map[string]ValuesProvider{}
+	valuesProvidersLock sync.RWMutex
+)
+
+// RegisterValuesProvider makes a provider available to the `values-fn` rule,
+// e.g. `values-fn:programs`.  Registering the same name again replaces it.
+func RegisterValuesProvider(name string, fn ValuesProvider) {
+	valuesProvidersLock.Lock()
+	defer valuesProvidersLock.Unlock()
+	valuesProviders[name] = fn
+}
+
+// Returns the values for a provider, and false if it isn't registered.
+func resolveValues(name string) ([]string, bool) {
+	valuesProvidersLock.RLock()
+	fn, ok := valuesProviders[name]
+	valuesProvidersLock.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return fn(), true
+}
+
+func isValueInProvider(r *validationRule) bool {
+	value := getFieldValue(r.value)
+	// We've already checked for required previously, so an empty
+	// string should not fail here
+	if strings.TrimSpace(value) == "" {
+		return true
+	}
+	allowed, ok := resolveValues(r.params.(string))
+	if !ok {
+		// An unknown provider is a programming error, but we'd rather
+		// reject the value than let it through unchecked.
+		return false
+	}
+	valid, _ := contains(allowed, value)
+	return valid
+}
@@ -0,0 +1,52 @@
+package validation
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type providerStruct struct {
+	Program string `json:"program" validation:"values-fn:test-programs"`
+	Locale  string `json:"locale" validation:"values-fn:not-registered"`
+}
+
+func TestValuesProvider(t *testing.T) {
+	programs := []string{"alpha", "beta"}
+	RegisterValuesProvider("test-programs", func() []string { return programs })
+
+	t.Run("Values come from the provider", func(t *testing.T) {
+		em := make(errorMap, 0)
+		err := ValidateStruct(providerStruct{Program: "beta"}, em)
+		require.NoError(t, err, "This struct should have passed validation, instead got: %#v", em)
+
+		em = make(errorMap, 0)
+		err = ValidateStruct(providerStruct{Program: "gamma"}, em)
+		require.Error(t, err)
+		assert.Equal(t, fmt.Sprintf(validValueMessage, "alpha, beta"), em["program"])
+	})
+	t.Run("Providers are resolved on every call", func(t *testing.T) {
+		programs = append(programs, "gamma")
+		fieldErrors, err := ValidateStructDetailed(providerStruct{Program: "gamma"})
+		require.NoError(t, err)
+		assert.Empty(t, fieldErrors)
+
+		fieldErrors, err = ValidateStructDetailed(providerStruct{Program: "delta"})
+		require.Error(t, err)
+		require.Len(t, fieldErrors, 1)
+		assert.Equal(t, []string{"alpha", "beta", "gamma"}, fieldErrors[0].Params)
+	})
+	t.Run("Unknown providers reject values", func(t *testing.T) {
+		em := make(errorMap, 0)
+		err := ValidateStruct(providerStruct{Locale: "en"}, em)
+		require.Error(t, err)
+		assert.Contains(t, em, "locale")
+	})
+	t.Run("Schemas list the current values", func(t *testing.T) {
+		s := SchemaFor(providerStruct{})
+		assert.Equal(t, []string{"alpha", "beta", "gamma"}, s.Properties["program"].Enum)
+		assert.Empty(t, s.Properties["locale"].Enum)
+	})
+}
@@ -4,17 +4,27 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"net/mail"
+	"net/url"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/seniorlink-vela/cs-common/timeutil"
 )
 
 type AppendableError interface {
 	AppendErrorField(name, message string)
 }
 
+// Common scenarios for ValidateStructForScenario
+const (
+	ScenarioCreate = "create"
+	ScenarioPatch  = "patch"
+)
+
 var (
 	KindError       = errors.New("Incorrect kind of argument. Must be struct.")
 	ValidationError = errors.New("Validation failed.")
@@ -63,11 +73,71 @@ var validationRuleMap = map[string]validationRule{
 		message:   validValueMessage,
 		validator: isValueValidInsensitive,
 	},
+	"values-fn": validationRule{
+		ruleKey:   "values-fn",
+		message:   validValueMessage,
+		validator: isValueInProvider,
+	},
+	"not-null": validationRule{
+		ruleKey:   "not-null",
+		message:   requiredMessage,
+		validator: requiredValuePresent,
+	},
 	"not-zero": validationRule{
 		ruleKey:   "not-zero",
 		message:   requiredMessage,
 		validator: isNotZero,
 	},
+	"uuid": validationRule{
+		ruleKey:   "uuid",
+		message:   uuidMessage,
+		validator: isUUIDValid,
+	},
+	"url": validationRule{
+		ruleKey:   "url",
+		message:   urlMessage,
+		validator: isURLValid,
+	},
+	"timezone": validationRule{
+		ruleKey:   "timezone",
+		message:   timezoneMessage,
+		validator: isTimezoneValid,
+	},
+	"phone": validationRule{
+		ruleKey:   "phone",
+		message:   phoneMessage,
+		validator: isPhoneValid,
+	},
+	"numeric": validationRule{
+		ruleKey:   "numeric",
+		message:   numericMessage,
+		validator: isNumeric,
+	},
+	"alpha": validationRule{
+		ruleKey:   "alpha",
+		message:   alphaMessage,
+		validator: isAlpha,
+	},
+	"alphanumeric": validationRule{
+		ruleKey:   "alphanumeric",
+		message:   alphanumericMessage,
+		validator: isAlphanumeric,
+	},
+	"before": validationRule{
+		ruleKey:   "before",
+		message:   beforeMessage,
+		validator: isBefore,
+	},
+	"after": validationRule{
+		ruleKey:   "after",
+		message:   afterMessage,
+		validator: isAfter,
+	},
+	"min-age": validationRule{
+		ruleKey:   "min-age",
+		message:   minAgeMessage,
+		validator: isMinimumAge,
+	},
 }
 
 // Error messages
@@ -77,87 +147,215 @@ const (
 	tooShortMessage   = "This must be at least %d characters"
 	tooLongMessage    = "This must not be longer than %d characters"
 	validValueMessage = "This must be one of the following values: %s"
+	uuidMessage       = "This is not a valid UUID"
+	urlMessage        = "This is not a valid URL"
+	timezoneMessage   = "This is not a valid time zone"
+	phoneMessage      = "This is not a valid phone number"
+
+	numericMessage            = "This must contain only digits"
+	numericLengthMessage      = "This must be %s digits"
+	alphaMessage              = "This must contain only letters"
+	alphaLengthMessage        = "This must be %s letters"
+	alphanumericMessage       = "This must contain only letters and digits"
+	alphanumericLengthMessage = "This must be %s letters or digits"
+
+	beforeMessage    = "This must be before %s"
+	beforeNowMessage = "This must be in the past"
+	afterMessage     = "This must be after %s"
+	afterNowMessage  = "This must be in the future"
+	minAgeMessage    = "This person must be at least %d years old"
 )
 
+var uuidRE = regexp.MustCompile("^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$")
+
+// FieldError describes a single failed rule, so APIs can return
+// machine readable error codes, and clients can localize the messages.
+type FieldError struct {
+	Field string `json:"field"`
+	// JSON Pointer to the field, including any nesting, e.g. `/extensions/0/name`
+	Path    string   `json:"path"`
+	Rule    string   `json:"rule"`
+	Params  []string `json:"params,omitempty"`
+	Message string   `json:"message"`
+	// The key used when reporting through an AppendableError, which may
+	// carry a suffix, e.g. `first_name_too_long`.
+	key string
+}
+
+func (fe FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", fe.Field, fe.Message)
+}
+
+// How errors are keyed when reported through an AppendableError
+type KeyStyle int
+
+const (
+	// The json name of the field, or the Go name without a json tag, with
+	// a suffix for length rules, e.g. `first_name_too_long`.
+	KeyStyleFieldName KeyStyle = iota
+	// A JSON Pointer built from json names, e.g. `/user_profile/first_name`,
+	// so nested fields can be told apart.
+	KeyStyleJSONPointer
+)
+
+// Options are the per call settings for ValidateStructWithOptions.  The zero
+// value behaves the same as ValidateStruct.
+type Options struct {
+	// See ValidateStructForScenario
+	Scenario string
+	// See ValidateStructLocalized
+	Language string
+	KeyStyle KeyStyle
+	// Prefixed to every path, for structs that are nested in the request
+	// body, e.g. `/user_profile`.
+	RootPath string
+}
+
 func ValidateStruct(s interface{}, ae AppendableError) error {
-	validStruct := true
+	return ValidateStructWithOptions(s, Options{}, ae)
+}
+
+// ValidateStructForScenario only runs the rules that apply to the given
+// scenario.  Rules can be limited to one or more scenarios by listing them
+// after the rule name, e.g. `required(create)` or `required(create|import)`.
+// Rules without a scenario always run, and an empty scenario runs everything.
+func ValidateStructForScenario(s interface{}, scenario string, ae AppendableError) error {
+	return ValidateStructWithOptions(s, Options{Scenario: scenario}, ae)
+}
+
+// ValidateStructLocalized reports errors in the given language, e.g. `es` or
+// `fr-CA`, falling back to English for anything that hasn't been translated.
+// Messages overridden in the tag are never translated.
+func ValidateStructLocalized(s interface{}, lang string, ae AppendableError) error {
+	return ValidateStructWithOptions(s, Options{Language: lang}, ae)
+}
+
+// ValidateStructWithOptions combines the scenario, language, and key settings
+// of the other entry points.
+func ValidateStructWithOptions(s interface{}, opts Options, ae AppendableError) error {
+	fieldErrors, err := validateStruct(s, opts)
+	if err != nil {
+		return err
+	}
+	for _, fe := range fieldErrors {
+		ae.AppendErrorField(fe.key, fe.Message)
+	}
+	if len(fieldErrors) > 0 {
+		return ValidationError
+	}
+	return nil
+}
+
+// ValidateStructDetailed runs the same rules as ValidateStruct, but returns
+// every failure with the rule and parameters that caused it.  The error is
+// `ValidationError` when any rule fails.
+func ValidateStructDetailed(s interface{}) ([]FieldError, error) {
+	return ValidateStructDetailedWithOptions(s, Options{})
+}
+
+// ValidateStructDetailedWithOptions is ValidateStructDetailed with per call
+// settings.  The key style has no effect here, since both the field name and
+// path are returned.
+func ValidateStructDetailedWithOptions(s interface{}, opts Options) ([]FieldError, error) {
+	fieldErrors, err := validateStruct(s, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(fieldErrors) > 0 {
+		return fieldErrors, ValidationError
+	}
+	return nil, nil
+}
+
+func validateStruct(s interface{}, opts Options) ([]FieldError, error) {
 	valS := reflect.ValueOf(s)
+	if valS.Kind() == reflect.Ptr && !valS.IsNil() {
+		valS = valS.Elem()
+	}
 	if valS.Kind() != reflect.Struct {
-		return KindError
-	}
-	typeS := valS.Type()
-
-	for i := 0; i < typeS.NumField(); i++ {
-		f := typeS.Field(i)
-		fName := fieldName(f)
-		validationRules := f.Tag.Get("validation")
-		if validationRules != "" {
-			rules := strings.Split(validationRules, ",")
-			trimSliceValues(rules)
-			required, j := contains(rules, "required")
-			fieldVal := valS.Field(i)
-			if required {
-				rules = remove(rules, j)
-				rule := validationRuleMap["required"]
-				rule.value = fieldVal
-				rule.messageKey = fName
-				if !rule.validator(&rule) {
-					validStruct = false
-					ae.AppendErrorField(fName, rule.message)
-				}
+		return nil, KindError
+	}
+	return validateValue(valS, opts, strings.TrimSuffix(opts.RootPath, "/")), nil
+}
+
+func validateValue(valS reflect.Value, opts Options, path string) []FieldError {
+	fieldErrors := []FieldError{}
+	plan := planFor(valS.Type())
+
+	for _, fp := range plan.fields {
+		fieldVal := valS.Field(fp.index)
+		fieldPath := path + "/" + escapeJSONPointer(fp.name)
+		// Rules apply to whatever an interface holds, rather than the interface itself
+		if fieldVal.Kind() == reflect.Interface && !fieldVal.IsNil() {
+			fieldVal = fieldVal.Elem()
+		}
+		sent := true
+		if fp.optional {
+			sent = optionalSent(fieldVal)
+			fieldVal = unwrapOptional(fieldVal)
+		}
+		requiredChecked := false
+		for _, cr := range fp.rules {
+			if !cr.tag.appliesTo(opts.Scenario) {
+				continue
 			}
-			for _, rule := range rules {
-				ruleType := strings.SplitN(rule, ":", 2)
-				rule := validationRuleMap[ruleType[0]]
-				rule.value = fieldVal
-				switch rule.ruleKey {
-				case "email":
-					rule.messageKey = fName
-				case "min-length":
-					// Being lazy about checks here, it should be safe to assume
-					// that we would know how to figure out why validation of
-					// our models isn't behaving as expected.
-					length, _ := strconv.Atoi(ruleType[1])
-					rule.messageKey = fmt.Sprintf("%s_too_short", fName)
-					rule.message = fmt.Sprintf(tooShortMessage, length)
-					rule.params = length
-				case "max-length":
-					// Being lazy about checks here, it should be safe to assume
-					// that we would know how to figure out why validation of
-					// our models isn't behaving as expected.
-					length, _ := strconv.Atoi(ruleType[1])
-					rule.messageKey = fmt.Sprintf("%s_too_long", fName)
-					rule.message = fmt.Sprintf(tooLongMessage, length)
-					rule.params = length
-				case "values":
-					validValues := strings.Split(ruleType[1], "|")
-					trimSliceValues(validValues)
-					rule.messageKey = fName
-					rule.message = fmt.Sprintf(validValueMessage, strings.Join(validValues, ", "))
-					rule.params = validValues
-				case "values-insensitive":
-					validValues := strings.Split(ruleType[1], "|")
-					trimSliceValues(validValues)
-					rule.messageKey = fName
-					rule.message = fmt.Sprintf(validValueMessage, strings.Join(validValues, ", "))
-					rule.params = validValues
-				case "not-zero":
-					rule.messageKey = fName
-				default:
-					// If there isn't a rule we can execute on, just move on to the next field.
+			if cr.rule.ruleKey == "not-null" && !sent {
+				continue
+			}
+			if cr.rule.ruleKey == "required" {
+				// Only the first applicable required rule is checked
+				if requiredChecked {
 					continue
 				}
-				if !rule.validator(&rule) {
-					validStruct = false
-					ae.AppendErrorField(rule.messageKey, rule.message)
+				requiredChecked = true
+			}
+			rule := cr.rule
+			rule.value = fieldVal
+			if !rule.validator(&rule) {
+				fe := cr.fieldError(fp.name, opts.Language)
+				fe.Path = fieldPath
+				if opts.KeyStyle == KeyStyleJSONPointer {
+					fe.key = fieldPath
 				}
+				fieldErrors = append(fieldErrors, fe)
 			}
 		}
+		if fp.nested {
+			fieldErrors = append(fieldErrors, validateNested(fieldVal, opts, fieldPath)...)
+		}
 	}
-	if !validStruct {
-		return ValidationError
+	return fieldErrors
+}
+
+// Validates any structs held by the value, following pointers, interfaces,
+// slices, and arrays.  With the default key style, errors from nested structs
+// use the nested field's key.
+func validateNested(v reflect.Value, opts Options, path string) []FieldError {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return validateNested(v.Elem(), opts, path)
+	case reflect.Slice, reflect.Array:
+		fieldErrors := []FieldError{}
+		for i := 0; i < v.Len(); i++ {
+			fieldErrors = append(fieldErrors, validateNested(v.Index(i), opts, fmt.Sprintf("%s/%d", path, i))...)
+		}
+		return fieldErrors
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return nil
+		}
+		return validateValue(v, opts, path)
+	default:
+		return nil
 	}
-	return nil
+}
+
+// Escapes a JSON Pointer reference token, per RFC 6901
+func escapeJSONPointer(s string) string {
+	return strings.Replace(strings.Replace(s, "~", "~0", -1), "/", "~1", -1)
 }
 
 // Basic check for required data being present.  For non-string data,
@@ -166,7 +364,7 @@ func requiredValuePresent(r *validationRule) bool {
 	fieldVal := r.value
 	// We follow a slightly different path here, since required
 	// fields may be values other than strings.
-	if fieldVal.Type().Kind() == reflect.Ptr {
+	if isPtrOrInterface(fieldVal) {
 		if fieldVal.IsNil() {
 			return false
 		} else {
@@ -184,9 +382,7 @@ func requiredValuePresent(r *validationRule) bool {
 	return true
 }
 
-// Basic validity check for email
-// it is a badly formatted email if it does not have exactly 1 @,
-// the last dot must be after the @, and the @ must not be the 1st character
+// Validity check for email, see isValidEmail for the details.
 func isEmailValid(r *validationRule) bool {
 	email := getFieldValue(r.value)
 	// We've already checked for required previously, so an empty
@@ -209,11 +405,11 @@ func isValueValid(r *validationRule) bool {
 	return valid
 }
 
+// The allowed values are lower cased when the rule is compiled.
 func isValueValidInsensitive(r *validationRule) bool {
 	value := getFieldValue(r.value)
 	value = strings.ToLower(value)
 	allowed := r.params.([]string)
-	lowerCaseSliceValues(allowed)
 	// We've already checked for required previously, so an empty
 	// string should not fail here
 	if strings.TrimSpace(value) == "" {
@@ -224,26 +420,26 @@ func isValueValidInsensitive(r *validationRule) bool {
 }
 
 func isBelowMaximumLength(r *validationRule) bool {
-	length := r.params.(int)
+	lp := r.params.(lengthParams)
 	value := getFieldValue(r.value)
 	value = strings.TrimSpace(value)
 	if len(value) == 0 {
 		// We've already checked for required, so there is no point in checking an empty string
 		return true
-	} else if len(value) > length {
+	} else if stringLength(value, lp.mode) > lp.length {
 		return false
 	}
 	return true
 }
 
 func isMinimumLength(r *validationRule) bool {
-	length := r.params.(int)
+	lp := r.params.(lengthParams)
 	value := getFieldValue(r.value)
 	value = strings.TrimSpace(value)
 	if len(value) == 0 {
 		// We've already checked for required, so there is no point in checking an empty string
 		return true
-	} else if len(value) < length {
+	} else if stringLength(value, lp.mode) < lp.length {
 		return false
 	}
 	return true
@@ -257,24 +453,30 @@ func fieldName(f reflect.StructField) string {
 	return name
 }
 
+// Interface fields only reach the validators when they're nil, otherwise
+// the value they hold is validated, so they're treated like pointers.
+func isPtrOrInterface(v reflect.Value) bool {
+	return v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface
+}
+
 func getFieldValue(valueField reflect.Value) string {
 	var value string
 
-	if valueField.Type().Kind() == reflect.Ptr {
+	if isPtrOrInterface(valueField) {
 		if !valueField.IsNil() {
-			value = fmt.Sprintf("%s", valueField.Elem().Interface())
+			value = fmt.Sprintf("%v", valueField.Elem().Interface())
 		} else {
 			value = ""
 		}
 	} else {
-		value = fmt.Sprintf("%s", valueField.Interface())
+		value = fmt.Sprintf("%v", valueField.Interface())
 	}
 	return value
 }
 
 func isNotZero(r *validationRule) bool {
 	v := r.value
-	if v.Type().Kind() == reflect.Ptr {
+	if isPtrOrInterface(v) {
 		if v.IsNil() {
 			return false
 		}
@@ -297,7 +499,85 @@ func isNotZero(r *validationRule) bool {
 	default:
 		return true
 	}
-	return true
+}
+
+// Checks for the canonical 8-4-4-4-12 hex representation of a UUID.
+// We don't care about the version or variant here.
+func isUUIDValid(r *validationRule) bool {
+	value := strings.TrimSpace(getFieldValue(r.value))
+	// We've already checked for required previously, so an empty
+	// string should not fail here
+	if value == "" {
+		return true
+	}
+	return uuidRE.MatchString(value)
+}
+
+// Only absolute URLs are accepted, i.e. they must have both a scheme and a host.
+func isURLValid(r *validationRule) bool {
+	value := strings.TrimSpace(getFieldValue(r.value))
+	// We've already checked for required previously, so an empty
+	// string should not fail here
+	if value == "" {
+		return true
+	}
+	u, err := url.ParseRequestURI(value)
+	if err != nil {
+		return false
+	}
+	return u.Scheme != "" && u.Host != ""
+}
+
+// Validates against the IANA tz database, e.g. `America/New_York`.
+func isTimezoneValid(r *validationRule) bool {
+	value := getFieldValue(r.value)
+	// We've already checked for required previously, so an empty
+	// string should not fail here
+	if strings.TrimSpace(value) == "" {
+		return true
+	}
+	// The value is stored as it is, so it's checked as it is: use the trim
+	// normalizer to accept padded zones
+	return timeutil.ValidZone(value)
+}
+
+// A single rule from a `validation` tag, in the form
+// `name[(scenario|...)][:params][=message]`, e.g. `max-length(create):255`.
+type ruleTag struct {
+	name      string
+	params    string
+	scenarios []string
+	message   string
+}
+
+// Rules may carry a custom error message after an `=`, e.g.
+// `email=Please enter a valid email`.  Since rules are comma separated,
+// the message itself can't contain a comma.
+func parseRuleTag(rule string) ruleTag {
+	rt := ruleTag{}
+	parts := strings.SplitN(rule, "=", 2)
+	if len(parts) > 1 {
+		rt.message = strings.TrimSpace(parts[1])
+	}
+	parts = strings.SplitN(strings.TrimSpace(parts[0]), ":", 2)
+	if len(parts) > 1 {
+		rt.params = strings.TrimSpace(parts[1])
+	}
+	rt.name = strings.TrimSpace(parts[0])
+	if open := strings.Index(rt.name, "("); open > 0 && strings.HasSuffix(rt.name, ")") {
+		rt.scenarios = strings.Split(rt.name[open+1:len(rt.name)-1], "|")
+		trimSliceValues(rt.scenarios)
+		rt.name = strings.TrimSpace(rt.name[:open])
+	}
+	return rt
+}
+
+func (rt ruleTag) appliesTo(scenario string) bool {
+	if scenario == "" || len(rt.scenarios) == 0 {
+		return true
+	}
+	found, _ := contains(rt.scenarios, scenario)
+	return found
 }
 
 // Searches a slice of strings for the passed value, and returns
@@ -312,13 +592,6 @@ func contains(list []string, item string) (bool, int) {
 	return false, -1
 }
 
-// This *might* be inefficient for really large slices, but the
-// likelihood of having more than 3 or 4 items in this use case
-// is *very* low, so we'll allow it.
-func remove(s []string, i int) []string {
-	return append(s[:i], s[i+1:]...)
-}
-
 func trimSliceValues(s []string) {
 	for i, value := range s {
 		s[i] = strings.TrimSpace(value)
@@ -331,10 +604,46 @@ func lowerCaseSliceValues(s []string) {
 	}
 }
 
-// IsValidEmail provides basic validity for email
+// isValidEmail parses the address per RFC 5322, and then applies some extra
+// sanity checks the upstream API enforces: no display names or comments, and
+// a fully qualified domain made of valid DNS labels.
 func isValidEmail(email string) bool {
-	validEmailRE := "^([^@\\s]+)@([^@\\s]+)\\.([^@\\s]+)$"
-	emailRE := regexp.MustCompile(validEmailRE)
-	matches := emailRE.FindAllString(email, -1)
-	return len(matches) > 0
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || addr.Address != email {
+		return false
+	}
+	at := strings.LastIndex(addr.Address, "@")
+	if at < 1 {
+		return false
+	}
+	return isValidEmailDomain(addr.Address[at+1:])
+}
+
+func isValidEmailDomain(domain string) bool {
+	if len(domain) > 253 {
+		return false
+	}
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if len(label) == 0 || len(label) > 63 {
+			return false
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	// Top level domains are never numeric, this also rejects bare IP addresses
+	tld := labels[len(labels)-1]
+	if _, err := strconv.Atoi(tld); err == nil {
+		return false
+	}
+	return true
 }
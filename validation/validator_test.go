@@ -2,6 +2,7 @@ package validation
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
@@ -327,3 +328,478 @@ func setupStructs(email, requiredValidValue, validValue, insensitiveValidValue,
 		},
 	}
 }
+
+func TestStructsFormatRules(t *testing.T) {
+	type formatStruct struct {
+		ID       string  `validation:"uuid"`
+		Website  *string `validation:"url"`
+		TimeZone string  `validation:"timezone"`
+	}
+	toStringPtr := func(v string) *string { return &v }
+	t.Run("Passes with valid or empty values", func(t *testing.T) {
+		valid := []formatStruct{
+			{
+				ID:       "123e4567-e89b-12d3-a456-426614174000",
+				Website:  toStringPtr("https://www.example.com/path?q=1"),
+				TimeZone: "America/New_York",
+			},
+			{
+				ID:       "123E4567-E89B-12D3-A456-426614174000",
+				TimeZone: "UTC",
+			},
+			{},
+		}
+		for _, ts := range valid {
+			em := make(errorMap, 0)
+			err := ValidateStruct(ts, em)
+			require.NoError(t, err, "This struct should have passed validation, instead got: %#v", em)
+		}
+	})
+	t.Run("Fails with badly formatted values", func(t *testing.T) {
+		ts := formatStruct{
+			ID:       "123e4567e89b12d3a456426614174000",
+			Website:  toStringPtr("www.example.com"),
+			TimeZone: "America/Boston",
+		}
+		em := make(errorMap, 0)
+		err := ValidateStruct(ts, em)
+		require.Error(t, err)
+		assert.Len(t, em, 3, "This struct should have 3 errors, instead got: %#v", em)
+		assert.Equal(t, uuidMessage, em["ID"])
+		assert.Equal(t, urlMessage, em["Website"])
+		assert.Equal(t, timezoneMessage, em["TimeZone"])
+	})
+	t.Run("Local is not a valid time zone", func(t *testing.T) {
+		em := make(errorMap, 0)
+		err := ValidateStruct(formatStruct{TimeZone: "Local"}, em)
+		require.Error(t, err)
+		assert.Equal(t, timezoneMessage, em["TimeZone"])
+	})
+	t.Run("Padded time zones are not valid", func(t *testing.T) {
+		em := make(errorMap, 0)
+		err := ValidateStruct(formatStruct{TimeZone: " America/New_York "}, em)
+		require.Error(t, err)
+		assert.Equal(t, timezoneMessage, em["TimeZone"])
+	})
+}
+
+func TestStructsLengthModes(t *testing.T) {
+	type lengthStruct struct {
+		Chars     string `validation:"max-length:5"`
+		Bytes     string `validation:"max-length:5:bytes"`
+		Graphemes string `validation:"max-length:2:graphemes"`
+		MinChars  string `validation:"min-length:4"`
+	}
+	t.Run("Multi-byte characters count once by default", func(t *testing.T) {
+		ts := lengthStruct{
+			Chars:     "Zoë 😀",
+			Graphemes: "é👍🏽",
+			MinChars:  "José",
+		}
+		em := make(errorMap, 0)
+		err := ValidateStruct(ts, em)
+		require.NoError(t, err, "This struct should have passed validation, instead got: %#v", em)
+	})
+	t.Run("Bytes mode counts the encoded length", func(t *testing.T) {
+		ts := lengthStruct{
+			Bytes: "Zoë 😀",
+		}
+		em := make(errorMap, 0)
+		err := ValidateStruct(ts, em)
+		require.Error(t, err)
+		assert.Len(t, em, 1, "This struct should have 1 error, instead got: %#v", em)
+		assert.Equal(t, fmt.Sprintf(tooLongMessage, 5), em["Bytes_too_long"])
+	})
+	t.Run("Graphemes mode counts user perceived characters", func(t *testing.T) {
+		ts := lengthStruct{
+			Graphemes: "🇺🇸👨‍👩‍👧a",
+		}
+		em := make(errorMap, 0)
+		err := ValidateStruct(ts, em)
+		require.Error(t, err)
+		assert.Equal(t, fmt.Sprintf(tooLongMessage, 2), em["Graphemes_too_long"])
+	})
+	t.Run("Unknown modes and lengths panic", func(t *testing.T) {
+		assert.PanicsWithValue(t, `validation: invalid params "10:runes" for the max-length rule on Name`, func() {
+			_ = ValidateStruct(struct {
+				Name string `validation:"max-length:10:runes"`
+			}{}, errorMap{})
+		})
+		assert.Panics(t, func() {
+			_ = ValidateStruct(struct {
+				Name string `validation:"min-length:ten"`
+			}{}, errorMap{})
+		})
+	})
+}
+
+func TestGraphemeCount(t *testing.T) {
+	assert.Equal(t, 0, graphemeCount(""))
+	assert.Equal(t, 4, graphemeCount("José"))
+	assert.Equal(t, 4, graphemeCount("Jose\u0301"))
+	assert.Equal(t, 1, graphemeCount("👍🏽"))
+	assert.Equal(t, 1, graphemeCount("👨‍👩‍👧"))
+	assert.Equal(t, 2, graphemeCount("🇺🇸🇨🇦"))
+	assert.Equal(t, 1, graphemeCount("❤️"))
+}
+
+func TestIsValidEmail(t *testing.T) {
+	valid := []string{
+		"test@example.com",
+		"first.last+tag@sub.example.co.uk",
+		"o'brien@example-domain.org",
+	}
+	for _, e := range valid {
+		assert.True(t, isValidEmail(e), "%s should be a valid email", e)
+	}
+	invalid := []string{
+		"bad-email",
+		"@example.com",
+		"test@",
+		"test@localhost",
+		"test@example..com",
+		"test@-example.com",
+		"test@example_domain.com",
+		"test@127.0.0.1",
+		"two@at@example.com",
+		"Jeff <jeff@example.com>",
+		"test@example.com (comment)",
+		" test@example.com",
+	}
+	for _, e := range invalid {
+		assert.False(t, isValidEmail(e), "%s should not be a valid email", e)
+	}
+}
+
+func TestStructsMessageOverride(t *testing.T) {
+	type overrideStruct struct {
+		Email string `validation:"required=Email is needed, email=Please enter a valid email"`
+		Name  string `validation:"max-length:3=Keep it short"`
+	}
+	em := make(errorMap, 0)
+	err := ValidateStruct(overrideStruct{}, em)
+	require.Error(t, err)
+	assert.Equal(t, "Email is needed", em["Email"])
+
+	em = make(errorMap, 0)
+	err = ValidateStruct(overrideStruct{Email: "bad-email", Name: "Walter"}, em)
+	require.Error(t, err)
+	assert.Len(t, em, 2, "This struct should have 2 errors, instead got: %#v", em)
+	assert.Equal(t, "Please enter a valid email", em["Email"])
+	assert.Equal(t, "Keep it short", em["Name_too_long"])
+}
+
+func TestValidateStructDetailed(t *testing.T) {
+	t.Run("Passing struct returns no field errors", func(t *testing.T) {
+		email := "test@example.com"
+		requiredValidValue := "one"
+		structs := setupStructs(&email, &requiredValidValue, nil, nil, nil, nil)
+		for _, ts := range structs {
+			fieldErrors, err := ValidateStructDetailed(ts)
+			require.NoError(t, err)
+			assert.Empty(t, fieldErrors)
+		}
+	})
+	t.Run("Failures carry the rule and params", func(t *testing.T) {
+		email := ""
+		requiredValidValue := "four"
+		validValue := "delta"
+		insensitiveValidValue := "DELTA"
+		tooShortValue := "f"
+		tooLongValue := "foo  foo  foo  foo  foo  foo  foo  "
+		structs := setupStructs(
+			&email,
+			&requiredValidValue,
+			&validValue,
+			&insensitiveValidValue,
+			&tooShortValue,
+			&tooLongValue,
+		)
+		for _, ts := range structs {
+			fieldErrors, err := ValidateStructDetailed(ts)
+			require.Equal(t, ValidationError, err)
+			require.Len(t, fieldErrors, 6)
+
+			assert.Equal(t, FieldError{Field: "RequiredEmail", Path: "/RequiredEmail", Rule: "required", Message: requiredMessage, key: "RequiredEmail"}, fieldErrors[0])
+			assert.Equal(t, "values", fieldErrors[1].Rule)
+			assert.Equal(t, []string{"one", "two", "three"}, fieldErrors[1].Params)
+			assert.Equal(t, "values-insensitive", fieldErrors[3].Rule)
+			assert.Equal(t, []string{"alpha", "beta", "gamma"}, fieldErrors[3].Params)
+			assert.Equal(t, "TooShortValue", fieldErrors[4].Field)
+			assert.Equal(t, "min-length", fieldErrors[4].Rule)
+			assert.Equal(t, []string{"3", LengthModeChars}, fieldErrors[4].Params)
+			assert.Equal(t, fmt.Sprintf(tooLongMessage, 30), fieldErrors[5].Message)
+		}
+	})
+	t.Run("Non struct values are rejected", func(t *testing.T) {
+		_, err := ValidateStructDetailed("not a struct")
+		assert.Equal(t, KindError, err)
+	})
+}
+
+func TestValidateStructForScenario(t *testing.T) {
+	type scenarioStruct struct {
+		FirstName *string `json:"first_name" validation:"required(create),max-length:5"`
+		Email     string  `json:"email" validation:"required(create|invite), email"`
+		Note      string  `json:"note" validation:"max-length(patch):3=Notes must be short when patching"`
+	}
+	toStringPtr := func(v string) *string { return &v }
+	t.Run("Scoped rules are skipped for other scenarios", func(t *testing.T) {
+		em := make(errorMap, 0)
+		err := ValidateStructForScenario(scenarioStruct{}, ScenarioPatch, em)
+		require.NoError(t, err, "This struct should have passed validation, instead got: %#v", em)
+	})
+	t.Run("Scoped rules run for their scenario", func(t *testing.T) {
+		em := make(errorMap, 0)
+		err := ValidateStructForScenario(scenarioStruct{}, ScenarioCreate, em)
+		require.Error(t, err)
+		assert.Len(t, em, 2, "This struct should have 2 errors, instead got: %#v", em)
+
+		em = make(errorMap, 0)
+		err = ValidateStructForScenario(scenarioStruct{}, "invite", em)
+		require.Error(t, err)
+		assert.Len(t, em, 1, "This struct should have 1 error, instead got: %#v", em)
+		assert.Equal(t, requiredMessage, em["email"])
+
+		em = make(errorMap, 0)
+		err = ValidateStructForScenario(scenarioStruct{Note: "Careful"}, ScenarioPatch, em)
+		require.Error(t, err)
+		assert.Equal(t, "Notes must be short when patching", em["note_too_long"])
+	})
+	t.Run("Unscoped rules always run", func(t *testing.T) {
+		em := make(errorMap, 0)
+		ts := scenarioStruct{FirstName: toStringPtr("Jeffrey"), Email: "bad-email"}
+		err := ValidateStructForScenario(ts, ScenarioPatch, em)
+		require.Error(t, err)
+		assert.Len(t, em, 2, "This struct should have 2 errors, instead got: %#v", em)
+		assert.Equal(t, fmt.Sprintf(tooLongMessage, 5), em["first_name_too_long"])
+		assert.Equal(t, emailMessage, em["email"])
+	})
+	t.Run("ValidateStruct runs every rule", func(t *testing.T) {
+		em := make(errorMap, 0)
+		err := ValidateStruct(scenarioStruct{Note: "Careful"}, em)
+		require.Error(t, err)
+		assert.Len(t, em, 3, "This struct should have 3 errors, instead got: %#v", em)
+	})
+}
+
+func benchmarkStruct() TestBasicStruct {
+	return TestBasicStruct{
+		RequiredEmail:         "test@example.local",
+		RequiredValidValue:    "three",
+		ValidValue:            "gamma",
+		InsensitiveValidValue: "BETA",
+		TooShortValue:         "foo",
+		TooLongValue:          "foo",
+	}
+}
+
+func BenchmarkValidateStruct(b *testing.B) {
+	ts := benchmarkStruct()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		em := make(errorMap, 0)
+		_ = ValidateStruct(ts, em)
+	}
+}
+
+// Compiles the rules on every call, which is how ValidateStruct used to behave
+func BenchmarkValidateStructUncached(b *testing.B) {
+	ts := benchmarkStruct()
+	t := reflect.TypeOf(ts)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		planCache.Delete(t)
+		em := make(errorMap, 0)
+		_ = ValidateStruct(ts, em)
+	}
+}
+
+func TestStructsCharacterClassRules(t *testing.T) {
+	type charClassStruct struct {
+		ZipCode      string  `json:"zip_code" validation:"numeric:5"`
+		MemberNumber *string `json:"member_number" validation:"numeric:6-8"`
+		Digits       string  `json:"digits" validation:"numeric"`
+		Initials     string  `json:"initials" validation:"alpha:2-3"`
+		Name         string  `json:"name" validation:"alpha"`
+		Code         string  `json:"code" validation:"alphanumeric"`
+	}
+	toStringPtr := func(v string) *string { return &v }
+	t.Run("Passes with valid or empty values", func(t *testing.T) {
+		valid := []charClassStruct{
+			{
+				ZipCode:      "02110",
+				MemberNumber: toStringPtr("1234567"),
+				Digits:       "0123456789",
+				Initials:     "JÉL",
+				Name:         "Zoë",
+				Code:         "Abc123",
+			},
+			{},
+		}
+		for _, ts := range valid {
+			em := make(errorMap, 0)
+			err := ValidateStruct(ts, em)
+			require.NoError(t, err, "This struct should have passed validation, instead got: %#v", em)
+		}
+	})
+	t.Run("Fails with invalid characters or lengths", func(t *testing.T) {
+		ts := charClassStruct{
+			ZipCode:      "0211",
+			MemberNumber: toStringPtr("12345678901"),
+			Digits:       "12.5",
+			Initials:     "J",
+			Name:         "Jeff Lebowski",
+			Code:         "abc-123",
+		}
+		em := make(errorMap, 0)
+		err := ValidateStruct(ts, em)
+		require.Error(t, err)
+		assert.Len(t, em, 6, "This struct should have 6 errors, instead got: %#v", em)
+		assert.Equal(t, fmt.Sprintf(numericLengthMessage, "5"), em["zip_code"])
+		assert.Equal(t, fmt.Sprintf(numericLengthMessage, "6-8"), em["member_number"])
+		assert.Equal(t, numericMessage, em["digits"])
+		assert.Equal(t, fmt.Sprintf(alphaLengthMessage, "2-3"), em["initials"])
+		assert.Equal(t, alphaMessage, em["name"])
+		assert.Equal(t, alphanumericMessage, em["code"])
+	})
+	t.Run("Length params are reported", func(t *testing.T) {
+		fieldErrors, err := ValidateStructDetailed(charClassStruct{ZipCode: "1", MemberNumber: toStringPtr("1")})
+		require.Error(t, err)
+		require.Len(t, fieldErrors, 2)
+		assert.Equal(t, []string{"5"}, fieldErrors[0].Params)
+		assert.Equal(t, []string{"6", "8"}, fieldErrors[1].Params)
+	})
+}
+
+// Mirrors the shape of client.Profile.Extensions
+type testExtensionValue struct {
+	FieldQualifiedName string      `json:"field_qualified_name" validation:"required"`
+	FieldValue         interface{} `json:"value" validation:"required,max-length:5"`
+}
+
+type testExtension struct {
+	ID     int64                 `json:"extension_id" validation:"not-zero"`
+	Values []*testExtensionValue `json:"values"`
+}
+
+type testExtendedProfile struct {
+	Name       string            `json:"name" validation:"required"`
+	Extensions *[]*testExtension `json:"extensions,omitempty"`
+	Primary    *testExtension    `json:"primary,omitempty"`
+	Any        interface{}       `json:"any,omitempty"`
+}
+
+func TestStructsPointerInput(t *testing.T) {
+	em := make(errorMap, 0)
+	err := ValidateStruct(&testExtendedProfile{Name: "Walter"}, em)
+	require.NoError(t, err, "This struct should have passed validation, instead got: %#v", em)
+
+	em = make(errorMap, 0)
+	err = ValidateStruct(&testExtendedProfile{}, em)
+	require.Error(t, err)
+	assert.Equal(t, requiredMessage, em["name"])
+
+	var nilProfile *testExtendedProfile
+	assert.Equal(t, KindError, ValidateStruct(nilProfile, em))
+}
+
+func TestStructsNestedAndInterfaceFields(t *testing.T) {
+	value := "short"
+	t.Run("Valid nested values pass", func(t *testing.T) {
+		extensions := []*testExtension{
+			{
+				ID: 1,
+				Values: []*testExtensionValue{
+					{FieldQualifiedName: "a.b", FieldValue: "abc"},
+					{FieldQualifiedName: "a.c", FieldValue: &value},
+					{FieldQualifiedName: "a.d", FieldValue: 42},
+				},
+			},
+			nil,
+		}
+		ts := testExtendedProfile{Name: "Walter", Extensions: &extensions}
+		em := make(errorMap, 0)
+		err := ValidateStruct(ts, em)
+		require.NoError(t, err, "This struct should have passed validation, instead got: %#v", em)
+	})
+	t.Run("Nested failures are reported", func(t *testing.T) {
+		extensions := []*testExtension{
+			{
+				Values: []*testExtensionValue{
+					{FieldQualifiedName: "a.b"},
+					{FieldQualifiedName: "a.c", FieldValue: "too long"},
+				},
+			},
+		}
+		ts := testExtendedProfile{Name: "Walter", Extensions: &extensions}
+		fieldErrors, err := ValidateStructDetailed(ts)
+		require.Error(t, err)
+		require.Len(t, fieldErrors, 3)
+		assert.Equal(t, "extension_id", fieldErrors[0].Field)
+		assert.Equal(t, "not-zero", fieldErrors[0].Rule)
+		assert.Equal(t, "value", fieldErrors[1].Field)
+		assert.Equal(t, "required", fieldErrors[1].Rule)
+		assert.Equal(t, "value", fieldErrors[2].Field)
+		assert.Equal(t, "max-length", fieldErrors[2].Rule)
+	})
+	t.Run("Structs held by interfaces and pointers are validated", func(t *testing.T) {
+		ts := testExtendedProfile{
+			Name:    "Walter",
+			Primary: &testExtension{ID: 1},
+			Any:     testExtension{},
+		}
+		fieldErrors, err := ValidateStructDetailed(ts)
+		require.Error(t, err)
+		require.Len(t, fieldErrors, 1)
+		assert.Equal(t, "extension_id", fieldErrors[0].Field)
+	})
+}
+
+func TestValidateStructWithOptions(t *testing.T) {
+	extensions := []*testExtension{
+		{ID: 1},
+		{
+			Values: []*testExtensionValue{
+				{FieldQualifiedName: "a.b", FieldValue: "too long"},
+			},
+		},
+	}
+	ts := testExtendedProfile{Extensions: &extensions}
+	t.Run("JSON Pointer keys include the full path", func(t *testing.T) {
+		em := make(errorMap, 0)
+		err := ValidateStructWithOptions(ts, Options{KeyStyle: KeyStyleJSONPointer, RootPath: "/user_profile"}, em)
+		require.Error(t, err)
+		assert.Equal(t, errorMap{
+			"/user_profile/name":                        requiredMessage,
+			"/user_profile/extensions/1/extension_id":   requiredMessage,
+			"/user_profile/extensions/1/values/0/value": fmt.Sprintf(tooLongMessage, 5),
+		}, em)
+	})
+	t.Run("Default keys are unchanged", func(t *testing.T) {
+		em := make(errorMap, 0)
+		err := ValidateStructWithOptions(ts, Options{}, em)
+		require.Error(t, err)
+		assert.Equal(t, errorMap{
+			"name":           requiredMessage,
+			"extension_id":   requiredMessage,
+			"value_too_long": fmt.Sprintf(tooLongMessage, 5),
+		}, em)
+	})
+	t.Run("Detailed errors always carry the path", func(t *testing.T) {
+		fieldErrors, err := ValidateStructDetailedWithOptions(ts, Options{Language: "es"})
+		require.Error(t, err)
+		require.Len(t, fieldErrors, 3)
+		assert.Equal(t, "/extensions/1/values/0/value", fieldErrors[2].Path)
+		assert.Equal(t, "value", fieldErrors[2].Field)
+		assert.Equal(t, "No debe tener más de 5 caracteres", fieldErrors[2].Message)
+	})
+	t.Run("Path tokens are escaped", func(t *testing.T) {
+		var escaped struct {
+			Odd string `json:"a/b~c" validation:"required"`
+		}
+		fieldErrors, err := ValidateStructDetailed(escaped)
+		require.Error(t, err)
+		assert.Equal(t, "/a~1b~0c", fieldErrors[0].Path)
+	})
+}
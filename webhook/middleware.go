@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"go.uber.org/zap"
+
+	velacontext "github.com/seniorlink-vela/cs-common/context"
+	"github.com/seniorlink-vela/cs-common/context/lambdamiddleware"
+	"github.com/seniorlink-vela/cs-common/handlers/bind"
+	"github.com/seniorlink-vela/cs-common/handlers/respond"
+)
+
+// MaxBodySize is the largest webhook body the middleware reads.
+const MaxBodySize = 1 << 20
+
+var errTooLarge = errors.New("webhook: body too large")
+
+// Middleware returns middleware that verifies each request's signature, and
+// responds with a 401 when it's missing or doesn't verify.  The handler can
+// still read the body.  Put it inside httpmiddleware.RequestContext, so
+// failures are logged with the request ID.
+func Middleware(v *Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodySize+1))
+			r.Body.Close()
+			if err == nil && len(body) > MaxBodySize {
+				err = errTooLarge
+			}
+			if err == nil {
+				err = v.Verify(r.Header.Get(SignatureHeader), body)
+			}
+			if err != nil {
+				status, errBody := failure(r.Context(), err)
+				respond.JSON(w, status, errBody)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ALBMiddleware verifies the signature of ALB requests, decoding the body
+// first when the ALB base64 encoded it.  Rejected requests get a 401 without
+// reaching the handler, so put it inside lambdamiddleware.ALBRequestContext
+// for the rejection to be logged with the request ID.
+func ALBMiddleware(v *Verifier) func(lambdamiddleware.ALBHandler) lambdamiddleware.ALBHandler {
+	return func(next lambdamiddleware.ALBHandler) lambdamiddleware.ALBHandler {
+		return func(ctx context.Context, req events.ALBTargetGroupRequest) (*events.ALBTargetGroupResponse, error) {
+			body := []byte(req.Body)
+			var err error
+			if req.IsBase64Encoded {
+				body, err = base64.StdEncoding.DecodeString(req.Body)
+			}
+			if err == nil {
+				err = v.Verify(lambdamiddleware.ALBHeader(req, SignatureHeader), body)
+			}
+			if err != nil {
+				// The body always marshals
+				resp, _ := respond.ALB(failure(ctx, err))
+				return resp, nil
+			}
+			return next(ctx, req)
+		}
+	}
+}
+
+// failure logs why the webhook was rejected, which isn't sent, so senders
+// can't probe the checks.
+func failure(ctx context.Context, err error) (int, respond.ErrorBody) {
+	velacontext.GetContextLogger(ctx).Info("Webhook rejected", zap.Error(err))
+	if errors.Is(err, errTooLarge) {
+		return http.StatusRequestEntityTooLarge, respond.ErrorBody{Message: fmt.Sprintf("Request body is larger than %d bytes", MaxBodySize), ErrorType: bind.ErrorTypeTooLarge}
+	}
+	return http.StatusUnauthorized, respond.ErrorBody{Message: http.StatusText(http.StatusUnauthorized), ErrorType: respond.ErrorTypeUnauthorized}
+}
@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	handler := Middleware(newVerifier(t, secret))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ := io.ReadAll(r.Body)
+		w.Write(received)
+	}))
+
+	tests := []struct {
+		name      string
+		body      []byte
+		signature string
+		status    int
+	}{
+		{"valid", body, sign(t, body, time.Now(), secret), http.StatusOK},
+		{"missing", body, "", http.StatusUnauthorized},
+		{"invalid", body, sign(t, body, time.Now(), []byte("other")), http.StatusUnauthorized},
+		{"too large", bytes.Repeat([]byte("x"), MaxBodySize+1), "", http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest("POST", "/hooks", bytes.NewReader(tt.body))
+			request.Header.Set(SignatureHeader, tt.signature)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			assert.Equal(t, tt.status, recorder.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, tt.body, recorder.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestALBMiddleware(t *testing.T) {
+	handler := ALBMiddleware(newVerifier(t, secret))(func(ctx context.Context, req events.ALBTargetGroupRequest) (*events.ALBTargetGroupResponse, error) {
+		return &events.ALBTargetGroupResponse{StatusCode: http.StatusAccepted}, nil
+	})
+	signature := sign(t, body, time.Now(), secret)
+
+	resp, err := handler(context.Background(), events.ALBTargetGroupRequest{
+		Headers:         map[string]string{"x-vela-signature": signature},
+		Body:            base64.StdEncoding.EncodeToString(body),
+		IsBase64Encoded: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	resp, err = handler(context.Background(), events.ALBTargetGroupRequest{
+		MultiValueHeaders: map[string][]string{"X-Vela-Signature": {signature}},
+		Body:              `{"event":"deleted"}`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.JSONEq(t, `{"message":"Unauthorized","error_type":"unauthorized"}`, resp.Body)
+}
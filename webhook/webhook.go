@@ -0,0 +1,180 @@
+// Package webhook signs the webhooks services send, and verifies the ones
+// they receive from Vela and partners.
+//
+// A signature is an HMAC-SHA256 of the timestamp and the body, sent as
+//
+//	X-Vela-Signature: t=1700000000,v1=5257a869...
+//
+// The timestamp stops a captured webhook being replayed later: Verify
+// rejects signatures older, or newer, than its tolerance.  While a secret is
+// rotated, the sender can sign with both secrets, giving a v1 for each, and
+// the receiver can accept either.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader is the header the signature is sent in.
+const SignatureHeader = "X-Vela-Signature"
+
+// DefaultTolerance is how far a signature's timestamp can be from now.
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	// ErrMissingSignature is returned when there's no signature, or it
+	// has no timestamp or v1.
+	ErrMissingSignature = errors.New("webhook: missing signature")
+	// ErrInvalidSignature is returned when no v1 matches.
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+	// ErrExpired is returned when the timestamp is outside the tolerance.
+	ErrExpired = errors.New("webhook: signature timestamp outside the tolerance")
+	// ErrEmptySecret is returned for an empty secret, usually a missing
+	// setting, which would let anyone sign.
+	ErrEmptySecret = errors.New("webhook: empty secret")
+)
+
+// Sign returns the signature header value for the body, signed at the time
+// with each of the secrets.  It returns ErrEmptySecret if one is empty.
+func Sign(body []byte, at time.Time, secrets ...[]byte) (string, error) {
+	if err := checkSecrets(secrets); err != nil {
+		return "", err
+	}
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	parts := []string{"t=" + timestamp}
+	for _, secret := range secrets {
+		parts = append(parts, "v1="+hex.EncodeToString(mac(secret, timestamp, body)))
+	}
+	return strings.Join(parts, ","), nil
+}
+
+func checkSecrets(secrets [][]byte) error {
+	for _, secret := range secrets {
+		if len(secret) == 0 {
+			return ErrEmptySecret
+		}
+	}
+	return nil
+}
+
+// SignRequest signs the request's body, now, and sets the signature header.
+// The body is read and replaced, so it can still be sent.
+func SignRequest(request *http.Request, secrets ...[]byte) error {
+	if err := checkSecrets(secrets); err != nil {
+		return err
+	}
+	var body []byte
+	if request.Body != nil {
+		var err error
+		body, err = io.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return fmt.Errorf("webhook: unable to read the body: %w", err)
+		}
+		request.Body = io.NopCloser(bytes.NewReader(body))
+		request.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	signature, err := Sign(body, time.Now(), secrets...)
+	if err != nil {
+		return err
+	}
+	request.Header.Set(SignatureHeader, signature)
+	return nil
+}
+
+func mac(secret []byte, timestamp string, body []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// Verifier checks webhook signatures.
+type Verifier struct {
+	secrets   [][]byte
+	tolerance time.Duration
+	now       func() time.Time
+}
+
+// NewVerifier returns a verifier that accepts signatures made with any of
+// the secrets within DefaultTolerance.  It returns ErrEmptySecret when there
+// are no secrets, or one is empty.
+func NewVerifier(secrets ...[]byte) (*Verifier, error) {
+	if len(secrets) == 0 {
+		return nil, ErrEmptySecret
+	}
+	if err := checkSecrets(secrets); err != nil {
+		return nil, err
+	}
+	return &Verifier{secrets: secrets, tolerance: DefaultTolerance, now: time.Now}, nil
+}
+
+// WithTolerance returns a copy of the verifier that accepts timestamps up to
+// the tolerance from now.
+func (v *Verifier) WithTolerance(tolerance time.Duration) *Verifier {
+	copied := *v
+	copied.tolerance = tolerance
+	return &copied
+}
+
+// Verify checks the signature header value for the body.
+func (v *Verifier) Verify(signature string, body []byte) error {
+	timestamp, macs, err := parse(signature)
+	if err != nil {
+		return err
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrMissingSignature
+	}
+	if age := v.now().Sub(time.Unix(seconds, 0)); age > v.tolerance || age < -v.tolerance {
+		return ErrExpired
+	}
+	for _, secret := range v.secrets {
+		expected := mac(secret, timestamp, body)
+		for _, m := range macs {
+			if hmac.Equal(m, expected) {
+				return nil
+			}
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// parse splits the header value into its timestamp and v1 MACs.  Other
+// schemes are ignored, so new ones can be added.
+func parse(signature string) (string, [][]byte, error) {
+	var timestamp string
+	var macs [][]byte
+	for _, part := range strings.Split(signature, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			if m, err := hex.DecodeString(value); err == nil {
+				macs = append(macs, m)
+			}
+		}
+	}
+	if timestamp == "" || len(macs) == 0 {
+		return "", nil, ErrMissingSignature
+	}
+	return timestamp, macs, nil
+}
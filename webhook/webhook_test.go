@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	secret = []byte("shh")
+	body   = []byte(`{"event":"care_plan.updated"}`)
+)
+
+func sign(t *testing.T, body []byte, at time.Time, secrets ...[]byte) string {
+	signature, err := Sign(body, at, secrets...)
+	require.NoError(t, err)
+	return signature
+}
+
+func newVerifier(t *testing.T, secrets ...[]byte) *Verifier {
+	v, err := NewVerifier(secrets...)
+	require.NoError(t, err)
+	return v
+}
+
+func TestSign(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	// Computed with: printf '1700000000.{"event":"care_plan.updated"}' | openssl dgst -sha256 -hmac shh
+	assert.Equal(t, "t=1700000000,v1=97bfe21695f4a8ac181dcdadd4dffbbffe0bd919ece3aa8889444b2095a86593", sign(t, body, at, secret))
+	assert.Equal(t, "t=1700000000", sign(t, body, at))
+	assert.Len(t, strings.Split(sign(t, body, at, secret, []byte("new")), ","), 3)
+
+	// An empty secret, like a missing setting, would let anyone sign
+	_, err := Sign(body, at, secret, nil)
+	assert.ErrorIs(t, err, ErrEmptySecret)
+	request, _ := http.NewRequest("POST", "https://partner.example.com/hooks", bytes.NewReader(body))
+	assert.ErrorIs(t, SignRequest(request, []byte("")), ErrEmptySecret)
+}
+
+func TestNewVerifierEmptySecrets(t *testing.T) {
+	for _, secrets := range [][][]byte{nil, {nil}, {secret, []byte("")}} {
+		_, err := NewVerifier(secrets...)
+		assert.ErrorIs(t, err, ErrEmptySecret)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	v := newVerifier(t, []byte("old"), secret)
+	v.now = func() time.Time { return now }
+
+	tests := []struct {
+		name      string
+		signature string
+		body      []byte
+		err       error
+	}{
+		{"valid", sign(t, body, now, secret), body, nil},
+		{"rotating", sign(t, body, now, []byte("new"), secret), body, nil},
+		{"within tolerance", sign(t, body, now.Add(-4*time.Minute), secret), body, nil},
+		{"old", sign(t, body, now.Add(-6*time.Minute), secret), body, ErrExpired},
+		{"future", sign(t, body, now.Add(6*time.Minute), secret), body, ErrExpired},
+		{"tampered", sign(t, body, now, secret), []byte(`{"event":"deleted"}`), ErrInvalidSignature},
+		{"other secret", sign(t, body, now, []byte("other")), body, ErrInvalidSignature},
+		{"missing", "", body, ErrMissingSignature},
+		{"no mac", "t=1700000000", body, ErrMissingSignature},
+		{"bad timestamp", "t=soon,v1=00", body, ErrMissingSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.err, v.Verify(tt.signature, tt.body))
+		})
+	}
+
+	assert.NoError(t, v.WithTolerance(time.Hour).Verify(sign(t, body, now.Add(-time.Minute*30), secret), body))
+}
+
+func TestSignRequest(t *testing.T) {
+	request, err := http.NewRequest("POST", "https://partner.example.com/hooks", bytes.NewReader(body))
+	require.NoError(t, err)
+	require.NoError(t, SignRequest(request, secret))
+
+	sent, err := io.ReadAll(request.Body)
+	require.NoError(t, err)
+	assert.Equal(t, body, sent)
+	assert.NoError(t, newVerifier(t, secret).Verify(request.Header.Get(SignatureHeader), sent))
+	again, err := request.GetBody()
+	require.NoError(t, err)
+	resent, _ := io.ReadAll(again)
+	assert.Equal(t, body, resent)
+}